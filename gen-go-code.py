@@ -742,9 +742,11 @@ def generate_readline_actions() -> str:
         ActionKillNextWord
         ActionKillPreviousWord
         ActionKillPreviousSpaceDelimitedWord
+        ActionKillSelection
         ActionEndKillActions
         ActionYank
         ActionPopYank
+        ActionCopySelection
 
         ActionNumericArgumentDigit0
         ActionNumericArgumentDigit1
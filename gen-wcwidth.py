@@ -21,6 +21,7 @@
     List,
     Optional,
     Set,
+    TextIO,
     Tuple,
     Union,
 )
@@ -272,13 +273,9 @@ def get_ranges(items: List[int]) -> Generator[Union[int, Tuple[int, int]], None,
             yield a, b
 
 
-def write_case(spec: Union[Tuple[int, ...], int], p: Callable[..., None], for_go: bool = False) -> None:
+def write_case(spec: Union[Tuple[int, ...], int], p: Callable[..., None]) -> None:
     if isinstance(spec, tuple):
-        if for_go:
-            v = ', '.join(f'0x{x:x}' for x in range(spec[0], spec[1] + 1))
-            p(f'\t\tcase {v}:')
-        else:
-            p('\t\tcase 0x{:x} ... 0x{:x}:'.format(*spec))
+        p('\t\tcase 0x{:x} ... 0x{:x}:'.format(*spec))
     else:
         p(f'\t\tcase 0x{spec:x}:')
 
@@ -470,70 +467,87 @@ def gen_names() -> None:
             print(cp, *words, end=end, file=f)
 
 
+def merged_ranges(items: Iterable[int]) -> Generator[Tuple[int, int], None, None]:
+    for spec in get_ranges(list(items)):
+        yield (spec, spec) if isinstance(spec, int) else (spec[0], spec[-1])
+
+
+def write_go_wcwidth_tables(gof: TextIO, widths: Dict[int, int], emoji_presentation_bases: Iterable[int], uv: Tuple[int, int, int]) -> None:
+    gop = partial(print, file=gof)
+    gop('// Code generated by gen-wcwidth.py, DO NOT EDIT.\n')
+    gop('package wcswidth\n')
+    # codepoints of width one are the default and so are not stored
+    ranges: List[Tuple[int, int, int]] = []
+    for cp in sorted(cp for cp, w in widths.items() if w != 1):
+        w = widths[cp]
+        if ranges and ranges[-1][1] == cp - 1 and ranges[-1][2] == w:
+            ranges[-1] = (ranges[-1][0], cp, w)
+        else:
+            ranges.append((cp, cp, w))
+    gop(f'// The widths of all codepoints whose width is not one, as {len(ranges)} sorted, non-overlapping ranges')
+    gop('var wcwidth_ranges = [...]wcwidth_range{ // {{''{')
+    for first, last, w in ranges:
+        gop(f'\t{{0x{first:x}, 0x{last:x}, {w}}},')
+    gop('} // }}''}\n')
+    gop('var emoji_presentation_base_ranges = [...]codepoint_range{ // {{''{')
+    for first, last in merged_ranges(emoji_presentation_bases):
+        gop(f'\t{{0x{first:x}, 0x{last:x}}},')
+    gop('} // }}''}\n')
+    gop('var UnicodeDatabaseVersion [3]int = [3]int{' f'{uv[0]}, {uv[1]}, {uv[2]}' + '}')
+
+
 def gen_wcwidth() -> None:
     seen: Set[int] = set()
     non_printing = class_maps['Cc'] | class_maps['Cf'] | class_maps['Cs']
+    widths: Dict[int, int] = {}
 
-    def add(p: Callable[..., None], comment: str, chars_: Union[Set[int], FrozenSet[int]], ret: int, for_go: bool = False) -> None:
+    def add(p: Callable[..., None], comment: str, chars_: Union[Set[int], FrozenSet[int]], ret: int) -> None:
         chars = chars_ - seen
         seen.update(chars)
+        for cp in chars:
+            widths[cp] = ret
         p(f'\t\t// {comment} ({len(chars)} codepoints)' + ' {{' '{')
         for spec in get_ranges(list(chars)):
-            write_case(spec, p, for_go)
+            write_case(spec, p)
             p(f'\t\t\treturn {ret};')
         p('\t\t// }}}\n')
 
-    def add_all(p: Callable[..., None], for_go: bool = False) -> None:
+    def add_all(p: Callable[..., None]) -> None:
         seen.clear()
-        add(p, 'Flags', flag_codepoints, 2, for_go)
-        add(p, 'Marks', marks | {0}, 0, for_go)
-        add(p, 'Non-printing characters', non_printing, -1, for_go)
-        add(p, 'Private use', class_maps['Co'], -3, for_go)
-        add(p, 'Text Presentation', narrow_emoji, 1, for_go)
-        add(p, 'East Asian ambiguous width', ambiguous, -2, for_go)
-        add(p, 'East Asian double width', doublewidth, 2, for_go)
-        add(p, 'Emoji Presentation', wide_emoji, 2, for_go)
+        add(p, 'Flags', flag_codepoints, 2)
+        add(p, 'Marks', marks | {0}, 0)
+        add(p, 'Non-printing characters', non_printing, -1)
+        add(p, 'Private use', class_maps['Co'], -3)
+        add(p, 'Text Presentation', narrow_emoji, 1)
+        add(p, 'East Asian ambiguous width', ambiguous, -2)
+        add(p, 'East Asian double width', doublewidth, 2)
+        add(p, 'Emoji Presentation', wide_emoji, 2)
 
-        add(p, 'Not assigned in the unicode character database', not_assigned, -4, for_go)
+        add(p, 'Not assigned in the unicode character database', not_assigned, -4)
 
         p('\t\tdefault:\n\t\t\treturn 1;')
         p('\t}')
-        if for_go:
-            p('\t}')
-        else:
-            p('\treturn 1;\n}')
+        p('\treturn 1;\n}')
 
     with create_header('kitty/wcwidth-std.h') as p, open('tools/wcswidth/std.go', 'w') as gof:
-        gop = partial(print, file=gof)
-        gop('package wcswidth\n\n')
-        gop('func Runewidth(code rune) int {')
         p('static inline int\nwcwidth_std(int32_t code) {')
         p('\tif (LIKELY(0x20 <= code && code <= 0x7e)) { return 1; }')
         p('\tswitch(code) {')
-        gop('\tswitch(code) {')
         add_all(p)
-        add_all(gop, True)
 
         p('static inline bool\nis_emoji_presentation_base(uint32_t code) {')
-        gop('func IsEmojiPresentationBase(code rune) bool {')
         p('\tswitch(code) {')
-        gop('\tswitch(code) {')
         for spec in get_ranges(list(emoji_presentation_bases)):
             write_case(spec, p)
-            write_case(spec, gop, for_go=True)
             p('\t\t\treturn true;')
-            gop('\t\t\treturn true;')
         p('\t\tdefault: return false;')
         p('\t}')
-        gop('\t\tdefault:\n\t\t\treturn false')
-        gop('\t}')
         p('\treturn true;\n}')
-        gop('\n}')
         uv = unicode_version()
         p(f'#define UNICODE_MAJOR_VERSION {uv[0]}')
         p(f'#define UNICODE_MINOR_VERSION {uv[1]}')
         p(f'#define UNICODE_PATCH_VERSION {uv[2]}')
-        gop('var UnicodeDatabaseVersion [3]int = [3]int{' f'{uv[0]}, {uv[1]}, {uv[2]}' + '}')
+        write_go_wcwidth_tables(gof, widths, emoji_presentation_bases, uv)
     subprocess.check_call(['gofmt', '-w', '-s', gof.name])
 
 
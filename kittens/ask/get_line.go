@@ -7,8 +7,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"kitty/tools/cli/markup"
 	"kitty/tools/tui/loop"
 	"kitty/tools/tui/readline"
 	"kitty/tools/utils"
@@ -17,10 +19,16 @@ import (
 var _ = fmt.Print
 
 func get_line(o *Options) (result string, err error) {
+	v, err := new_validator(o)
+	if err != nil {
+		return
+	}
 	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors)
 	if err != nil {
 		return
 	}
+	// allow moving the cursor and selecting text with the mouse
+	lp.MouseTrackingMode(loop.BUTTONS_AND_DRAG_MOUSE_TRACKING)
 	cwd, _ := os.Getwd()
 	ropts := readline.RlInit{Prompt: o.Prompt}
 	if o.Name != "" {
@@ -32,7 +40,21 @@ func get_line(o *Options) (result string, err error) {
 	if o.Default != "" {
 		rl.SetText(o.Default)
 	}
+	m := markup.New(true)
+	invalid_value, error_message := "", ""
+	// show the error for an invalid value only until the value is edited
+	update_footer := func() {
+		lines := []string{}
+		if error_message != "" && rl.AllText() == invalid_value {
+			lines = append(lines, m.Err(error_message))
+		}
+		if o.ValidationHint != "" {
+			lines = append(lines, m.Dim(o.ValidationHint))
+		}
+		rl.SetFooter(strings.Join(lines, "\n"))
+	}
 	lp.OnInitialize = func() (string, error) {
+		update_footer()
 		rl.Start()
 		return "", nil
 	}
@@ -44,6 +66,7 @@ func get_line(o *Options) (result string, err error) {
 	}
 
 	lp.OnResize = rl.OnResize
+	lp.OnMouseEvent = rl.OnMouseEvent
 
 	lp.OnKeyEvent = func(event *loop.KeyEvent) error {
 		if event.MatchesPressOrRepeat("ctrl+c") {
@@ -56,6 +79,13 @@ func get_line(o *Options) (result string, err error) {
 				return nil
 			}
 			if err == readline.ErrAcceptInput {
+				if msg := v.validate(rl.AllText()); msg != "" {
+					invalid_value, error_message = rl.AllText(), msg
+					update_footer()
+					rl.Redraw()
+					lp.Beep()
+					return nil
+				}
 				hi := readline.HistoryItem{Timestamp: time.Now(), Cmd: rl.AllText(), ExitCode: 0, Cwd: cwd}
 				rl.AddHistoryItem(hi)
 				result = rl.AllText()
@@ -65,6 +95,7 @@ func get_line(o *Options) (result string, err error) {
 			return err
 		}
 		if event.Handled {
+			update_footer()
 			rl.Redraw()
 			return nil
 		}
@@ -74,6 +105,7 @@ func get_line(o *Options) (result string, err error) {
 	lp.OnText = func(text string, from_key_event, in_bracketed_paste bool) error {
 		err := rl.OnText(text, from_key_event, in_bracketed_paste)
 		if err == nil {
+			update_footer()
 			rl.Redraw()
 		}
 		return err
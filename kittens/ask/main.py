@@ -58,6 +58,24 @@ def option_text() -> str:
 
 --hidden-text-placeholder
 The text in the message to be replaced by hidden text. The hidden text is read via STDIN.
+
+
+--validate-regex
+A regular expression that the entered text must match completely for it to be
+accepted. If it does not match, an error is shown and the user can edit the
+text and try again. Only used for the :code:`line` type.
+
+
+--validate-cmd
+A command to validate the entered text with. The text is passed to the command
+via STDIN and it is accepted only if the command exits successfully. Otherwise,
+the output of the command, if any, is shown as the error message and the user
+can edit the text and try again. Only used for the :code:`line` type.
+
+
+--validation-hint
+Hint text to show below the input, for example, describing the expected format
+of the text. Only used for the :code:`line` type.
 '''
 
 
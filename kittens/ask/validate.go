@@ -0,0 +1,59 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ask
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"kitty/tools/utils/shlex"
+)
+
+var _ = fmt.Print
+
+type validator struct {
+	pat *regexp.Regexp
+	cmd []string
+}
+
+func new_validator(o *Options) (ans *validator, err error) {
+	ans = &validator{}
+	if o.ValidateRegex != "" {
+		// the whole value must match, not just part of it
+		if ans.pat, err = regexp.Compile(`^(?:` + o.ValidateRegex + `)$`); err != nil {
+			return nil, fmt.Errorf("Invalid regular expression for --validate-regex: %s with error: %w", o.ValidateRegex, err)
+		}
+	}
+	if o.ValidateCmd != "" {
+		if ans.cmd, err = shlex.Split(o.ValidateCmd); err != nil {
+			return nil, fmt.Errorf("Invalid command for --validate-cmd: %s with error: %w", o.ValidateCmd, err)
+		}
+	}
+	return
+}
+
+// Returns an error message if the value is not valid, an empty string otherwise
+func (self *validator) validate(value string) string {
+	if self.pat != nil && !self.pat.MatchString(value) {
+		return "The entered value is not in the expected format"
+	}
+	if len(self.cmd) > 0 {
+		c := exec.Command(self.cmd[0], self.cmd[1:]...)
+		c.Stdin = strings.NewReader(value)
+		output := bytes.Buffer{}
+		c.Stdout, c.Stderr = &output, &output
+		if err := c.Run(); err != nil {
+			if msg := strings.TrimSpace(output.String()); msg != "" {
+				return msg
+			}
+			if _, ok := err.(*exec.ExitError); ok {
+				return "The entered value is not valid"
+			}
+			return fmt.Sprintf("Failed to run the validation command with error: %s", err)
+		}
+	}
+	return ""
+}
@@ -95,6 +95,33 @@ func preread_stdin() (data_src io.Reader, tempfile *os.File, err error) {
 	return
 }
 
+func size_of_piped_data(data_src io.Reader) int64 {
+	switch v := data_src.(type) {
+	case *bytes.Buffer:
+		return int64(v.Len())
+	case *os.File:
+		if st, err := v.Stat(); err == nil {
+			return st.Size()
+		}
+	}
+	return -1
+}
+
+// Print a warning to stderr, but still go ahead, if the terminal, as detected
+// from environment variables, does not support OSC 52 or limits its size to
+// less than the piped data needs
+func warn_if_terminal_lacks_osc52(data_src io.Reader) {
+	caps := loop.TerminalCapabilitiesFromEnvironment()
+	if !caps.OSC52 {
+		fmt.Fprintf(os.Stderr, "Warning: This terminal (%s) may not support accessing the clipboard via OSC 52\n", caps.Terminal)
+	}
+	if data_src != nil && caps.OSC52MaxSize > 0 {
+		if sz := size_of_piped_data(data_src); sz > 0 && int64(base64.StdEncoding.EncodedLen(int(sz)))+16 > int64(caps.OSC52MaxSize) {
+			fmt.Fprintf(os.Stderr, "Warning: Copying %d bytes to the clipboard may fail as %s limits clipboard escape codes to %d bytes\n", sz, caps.Terminal, caps.OSC52MaxSize)
+		}
+	}
+}
+
 func run_plain_text_loop(opts *Options) (err error) {
 	stdin_is_tty := tty.IsTerminal(os.Stdin.Fd())
 	var data_src io.Reader
@@ -110,6 +137,18 @@ func run_plain_text_loop(opts *Options) (err error) {
 			defer tempfile.Close()
 		}
 	}
+	if data_src != nil || opts.GetClipboard {
+		warn_if_terminal_lacks_osc52(data_src)
+	}
+	if data_src != nil {
+		// A single OSC 52 escape code can neither be aborted part way nor
+		// have progress drawn while it is being sent, so send large payloads
+		// in chunks using the clipboard protocol when the terminal is known
+		// to support it
+		if size_of_piped_data(data_src) >= PROGRESS_THRESHOLD && loop.TerminalCapabilitiesFromEnvironment().Terminal == loop.KITTY_TERMINAL {
+			return write_loop([]*Input{{src: data_src, arg: "/dev/stdin", is_stream: true, mime_type: "text/plain"}}, opts)
+		}
+	}
 	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors, loop.NoMouseTracking)
 	if err != nil {
 		return
@@ -76,6 +76,11 @@
 
     # List the formats available on the system clipboard
     kitty +kitten clipboard -g -m . /dev/stdout
+
+When copying large amounts of data to the clipboard, the progress of the copy is
+shown. Press :kbd:`Esc` or :kbd:`Ctrl+C` to pause it, then :kbd:`Enter` to
+resume it or :kbd:`Esc` or :kbd:`Ctrl+C` again to abort it, leaving the
+clipboard unchanged.
 '''
 
 usage = '[files to copy to/from]'
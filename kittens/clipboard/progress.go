@@ -0,0 +1,108 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package clipboard
+
+import (
+	"fmt"
+	"time"
+
+	"kitty/tools/tui"
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils/humanize"
+)
+
+var _ = fmt.Print
+
+// Payloads at least this large show the progress of their transmission
+const PROGRESS_THRESHOLD = 1024 * 1024
+
+type write_progress struct {
+	lp          *loop.Loop
+	total, sent int64
+	started_at  time.Time
+	last_frac   int
+	drawn       bool
+	paused      bool
+	paused_at   time.Time
+	// the time spent paused, excluded from the transfer rate
+	paused_for time.Duration
+}
+
+func new_write_progress(lp *loop.Loop, total int64) *write_progress {
+	return &write_progress{lp: lp, total: total, started_at: time.Now(), last_frac: -1}
+}
+
+func (self *write_progress) enabled() bool {
+	return self.total >= PROGRESS_THRESHOLD
+}
+
+// Pause or resume the transmission, showing how to resume it while paused
+func (self *write_progress) set_paused(paused bool) {
+	if paused == self.paused {
+		return
+	}
+	self.paused = paused
+	if paused {
+		self.paused_at = time.Now()
+	} else {
+		self.paused_for += time.Since(self.paused_at)
+	}
+	self.last_frac = -1
+	self.add(0)
+}
+
+func (self *write_progress) add(n int) {
+	self.sent += int64(n)
+	if !self.enabled() {
+		return
+	}
+	frac := int(1000 * self.sent / self.total)
+	if frac == self.last_frac {
+		return
+	}
+	self.last_frac = frac
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return
+	}
+	elapsed := time.Since(self.started_at) - self.paused_for
+	if self.paused {
+		elapsed -= time.Since(self.paused_at)
+	}
+	rate := float64(self.sent) / max(elapsed.Seconds(), 0.001)
+	prefix := "Copying to clipboard: "
+	if self.paused {
+		prefix = "Paused, Enter to resume, Esc to abort: "
+	}
+	suffix := fmt.Sprintf(" %3d%% %s/%s %s/s", frac/10, humanize.Bytes(uint64(self.sent)), humanize.Bytes(uint64(self.total)), humanize.Bytes(uint64(rate)))
+	w := int(sz.WidthCells) - len(prefix) - len(suffix) - 1
+	self.lp.AllowLineWrapping(false)
+	self.lp.QueueWriteString("\r" + prefix)
+	if w > 4 {
+		self.lp.QueueWriteString(tui.RenderProgressBar(float64(self.sent)/float64(self.total), w))
+	}
+	self.lp.QueueWriteString(suffix)
+	self.lp.ClearToEndOfLine()
+	self.lp.AllowLineWrapping(true)
+	if self.paused {
+		self.lp.SetProgress(loop.PROGRESS_PAUSED, frac/10)
+	} else {
+		self.lp.SetProgress(loop.PROGRESS_NORMAL, frac/10)
+	}
+	self.drawn = true
+}
+
+// Clear the progress line, when the transmission is complete or aborted
+func (self *write_progress) finish(failed bool) {
+	if !self.drawn {
+		return
+	}
+	self.lp.QueueWriteString("\r")
+	self.lp.ClearToEndOfLine()
+	if failed {
+		self.lp.SetProgress(loop.PROGRESS_ERROR, 100)
+	} else {
+		self.lp.ClearProgress()
+	}
+	self.drawn = false
+}
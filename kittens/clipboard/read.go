@@ -9,6 +9,7 @@ import (
 	"image"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -455,3 +456,96 @@ func run_get_loop(opts *Options, args []string) (err error) {
 
 	return
 }
+
+// Read the data for the first of the specified MIME types that is available
+// on the clipboard, for use by other kittens. The MIME types can be glob
+// patterns such as image/*.
+func ReadFromClipboard(mime_types []string, use_primary bool) (data []byte, mime_type string, err error) {
+	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors, loop.NoMouseTracking)
+	if err != nil {
+		return nil, "", err
+	}
+	var available_mimes []string
+	reading_available_mimes := true
+	basic_metadata := map[string]string{"type": "read"}
+	if use_primary {
+		basic_metadata["loc"] = "primary"
+	}
+
+	lp.OnInitialize = func() (string, error) {
+		lp.QueueWriteString(encode(basic_metadata, "."))
+		return "", nil
+	}
+
+	choose_mime := func() string {
+		for _, pat := range mime_types {
+			for _, m := range available_mimes {
+				if matched, _ := path.Match(pat, m); matched {
+					return m
+				}
+			}
+		}
+		return ""
+	}
+
+	lp.OnEscapeCode = func(etype loop.EscapeCodeType, raw []byte) error {
+		metadata, payload, err := parse_escape_code(etype, raw)
+		if err != nil {
+			return err
+		}
+		if metadata == nil {
+			return nil
+		}
+		if reading_available_mimes {
+			switch metadata["status"] {
+			case "DATA":
+				available_mimes = utils.Map(strings.TrimSpace, strings.Split(utils.UnsafeBytesToString(payload), " "))
+			case "OK":
+			case "DONE":
+				reading_available_mimes = false
+				if len(available_mimes) == 0 {
+					return fmt.Errorf("The clipboard is empty")
+				}
+				if mime_type = choose_mime(); mime_type == "" {
+					return fmt.Errorf("None of the MIME types %s are available on the clipboard, available MIME types: %s", strings.Join(mime_types, ", "), strings.Join(available_mimes, ", "))
+				}
+				lp.QueueWriteString(encode(basic_metadata, mime_type))
+			default:
+				return fmt.Errorf("Failed to read list of available data types in the clipboard with error: %w", error_from_status(metadata["status"]))
+			}
+		} else {
+			switch metadata["status"] {
+			case "DATA":
+				if metadata["mime"] == mime_type {
+					data = append(data, payload...)
+				}
+			case "OK":
+			case "DONE":
+				lp.Quit(0)
+			default:
+				return fmt.Errorf("Failed to read data from the clipboard with error: %w", error_from_status(metadata["status"]))
+			}
+		}
+		return nil
+	}
+
+	lp.OnKeyEvent = func(event *loop.KeyEvent) error {
+		if event.MatchesPressOrRepeat("ctrl+c") || event.MatchesPressOrRepeat("esc") {
+			event.Handled = true
+			return fmt.Errorf("Aborted by user!")
+		}
+		return nil
+	}
+
+	if err = lp.Run(); err != nil {
+		return nil, "", err
+	}
+	if ds := lp.DeathSignalName(); ds != "" {
+		lp.KillIfSignalled()
+		return nil, "", fmt.Errorf("Killed by signal: %s", ds)
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("No data for the MIME type %s on the clipboard", mime_type)
+	}
+	return data, mime_type, nil
+}
@@ -52,6 +52,18 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 	}
 	var waiting_for_write loop.IdType
 	var buf [4096]byte
+	total_size := int64(0)
+	for _, i := range inputs {
+		if sz := size_of_piped_data(i.src); sz > 0 && total_size > -1 {
+			total_size += sz
+		} else {
+			total_size = -1
+		}
+	}
+	progress := new_write_progress(lp, total_size)
+	transmitting, aborted := true, false
+	// stalled is set when a chunk was not sent because the transfer is paused
+	stalled := false
 	aliases, aerr := parse_aliases(opts.Alias)
 	if aerr != nil {
 		return aerr
@@ -96,10 +108,15 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 		if len(inputs) == 0 {
 			return nil
 		}
+		if progress.paused {
+			stalled = true
+			return nil
+		}
 		i := inputs[0]
 		n, err := i.src.Read(buf[:])
 		if n > 0 {
 			waiting_for_write = lp.QueueWriteString(encode_bytes(make_metadata("wdata", i.mime_type), buf[:n]))
+			progress.add(n)
 		}
 		if err != nil {
 			if errors.Is(err, io.EOF) {
@@ -108,6 +125,8 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 				}
 				inputs = inputs[1:]
 				if len(inputs) == 0 {
+					progress.finish(false)
+					transmitting = false
 					lp.QueueWriteString(encode(make_metadata("wdata", ""), ""))
 					waiting_for_write = 0
 				}
@@ -131,6 +150,9 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 			return err
 		}
 		if metadata != nil && metadata["type"] == "write" {
+			if metadata["status"] != "DONE" {
+				progress.finish(true)
+			}
 			switch metadata["status"] {
 			case "DONE":
 				lp.Quit(0)
@@ -155,6 +177,23 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 	lp.OnKeyEvent = func(event *loop.KeyEvent) error {
 		if event.MatchesPressOrRepeat("ctrl+c") || event.MatchesPressOrRepeat("esc") {
 			event.Handled = true
+			if transmitting && progress.enabled() && !progress.paused {
+				// the terminal waits for the rest of the data, so the
+				// transfer can be resumed later
+				progress.set_paused(true)
+				return nil
+			}
+			if transmitting {
+				// Stop sending data and tell the terminal to discard what it
+				// has received so far, so that the clipboard is left unchanged
+				// and no partial escape code is left behind
+				progress.finish(true)
+				inputs, transmitting, aborted = nil, false, true
+				waiting_for_write = 0
+				lp.QueueWriteString(encode(make_metadata("wabort", ""), ""))
+				lp.Quit(1)
+				return nil
+			}
 			esc_count++
 			if esc_count < 2 {
 				key := "Esc"
@@ -166,6 +205,14 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 				return fmt.Errorf("Aborted by user!")
 			}
 		}
+		if progress.paused && (event.MatchesPressOrRepeat("enter") || event.MatchesPressOrRepeat("space")) {
+			event.Handled = true
+			progress.set_paused(false)
+			if stalled {
+				stalled = false
+				return write_chunk()
+			}
+		}
 		return nil
 	}
 
@@ -179,7 +226,9 @@ func write_loop(inputs []*Input, opts *Options) (err error) {
 		lp.KillIfSignalled()
 		return
 	}
-
+	if aborted {
+		return fmt.Errorf("Aborted by user, the clipboard was not changed")
+	}
 	return
 }
 
@@ -0,0 +1,186 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kitty/tools/utils"
+	"kitty/tools/utils/humanize"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type blame_commit struct {
+	sha, author string
+	author_time time.Time
+}
+
+func (self *blame_commit) is_uncommitted() bool {
+	return strings.Trim(self.sha, "0") == ""
+}
+
+func (self *blame_commit) String() string {
+	if self.is_uncommitted() {
+		return "uncommitted"
+	}
+	return fmt.Sprintf("%s %s %s", self.sha[:utils.Min(7, len(self.sha))], self.author, humanize.Time(self.author_time))
+}
+
+type blame_file struct {
+	// the commit for each line, indexed by line number - 1
+	lines []*blame_commit
+	// whether the blame is for the left file, only used if the right file
+	// is not tracked by git
+	is_left bool
+}
+
+func (self *blame_file) commit_for_line(linenum int) *blame_commit {
+	if self == nil || linenum < 1 || linenum > len(self.lines) {
+		return nil
+	}
+	return self.lines[linenum-1]
+}
+
+// Parse the output of git blame --porcelain
+func parse_blame_porcelain(raw []byte) (ans []*blame_commit, err error) {
+	commits := make(map[string]*blame_commit)
+	var current *blame_commit
+	var final_line int
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if current == nil {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || len(fields[0]) < 40 {
+				return nil, fmt.Errorf("Invalid git blame header line: %#v", line)
+			}
+			if final_line, err = strconv.Atoi(fields[2]); err != nil || final_line < 1 {
+				return nil, fmt.Errorf("Invalid line number in git blame header line: %#v", line)
+			}
+			if current = commits[fields[0]]; current == nil {
+				current = &blame_commit{sha: fields[0]}
+				commits[fields[0]] = current
+			}
+			continue
+		}
+		key, val, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.author = val
+		case "author-time":
+			if t, err := strconv.ParseInt(val, 10, 64); err == nil {
+				current.author_time = time.Unix(t, 0)
+			}
+		default:
+			if strings.HasPrefix(line, "\t") {
+				// the contents of the line, which ends the entry
+				for len(ans) < final_line {
+					ans = append(ans, nil)
+				}
+				ans[final_line-1] = current
+				current = nil
+			}
+		}
+	}
+	return ans, scanner.Err()
+}
+
+func blame_path(path string) ([]*blame_commit, error) {
+	if path == "" {
+		return nil, fmt.Errorf("No file to blame")
+	}
+	if q, err := filepath.EvalSymlinks(path); err == nil {
+		path = q
+	}
+	c := exec.Command(GitExe(), "-C", filepath.Dir(path), "blame", "--porcelain", "--", filepath.Base(path))
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	c.Stdout, c.Stderr = &stdout, &stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("git blame failed for %s with error: %w and stderr: %s", path, err, stderr.String())
+	}
+	return parse_blame_porcelain(stdout.Bytes())
+}
+
+// Blame the right file, falling back to the left file, which is the case when
+// the right file is a temporary copy, for example. Files not tracked by git
+// have no blame lines.
+func blame_pair(left_path, right_path string) *blame_file {
+	if lines, err := blame_path(right_path); err == nil {
+		return &blame_file{lines: lines}
+	}
+	if lines, err := blame_path(left_path); err == nil {
+		return &blame_file{lines: lines, is_left: true}
+	}
+	return &blame_file{}
+}
+
+type blame_state struct {
+	shown bool
+	// keyed by the path of the right file, a nil value means the blame is
+	// still being fetched
+	files map[string]*blame_file
+}
+
+func (self *Handler) toggle_blame() {
+	self.blame.shown = !self.blame.shown
+	if self.blame.shown && GitExe() == "git" {
+		self.blame.shown = false
+		self.statusline_message = "git is not installed, cannot show blame annotations"
+		self.lp.Beep()
+	}
+	self.draw_screen()
+}
+
+// The blame annotation for a context line, fetching the blame data for its
+// file in the background if needed
+func (self *Handler) blame_annotation_for(ll *LogicalLine) string {
+	key := ll.right_reference.path
+	bf, found := self.blame.files[key]
+	if !found {
+		if self.blame.files == nil {
+			self.blame.files = make(map[string]*blame_file)
+		}
+		self.blame.files[key] = nil
+		left := ll.left_reference.path
+		self.workers.submit(func() {
+			self.send_async_result(AsyncResult{rtype: BLAME, path: key, blame: blame_pair(left, key)})
+		})
+		return ""
+	}
+	linenum := ll.right_reference.linenum
+	if bf != nil && bf.is_left {
+		linenum = ll.left_reference.linenum
+	}
+	if c := bf.commit_for_line(linenum); c != nil {
+		return c.String()
+	}
+	return ""
+}
+
+// Draw the annotation right aligned in the left half of the first screen line
+// of a context line, since the left half of a context line is the same as the
+// right half
+func (self *Handler) draw_blame_annotation(ll *LogicalLine) {
+	if ll.line_type != CONTEXT_LINE {
+		return
+	}
+	text := self.blame_annotation_for(ll)
+	available_cols := self.logical_lines.columns/2 - self.logical_lines.margin_size
+	if text == "" || available_cols < 4 {
+		return
+	}
+	text = fit_in(sanitize(text), available_cols-1)
+	self.lp.QueueWriteString("\r")
+	self.lp.MoveCursorHorizontally(self.logical_lines.margin_size + available_cols - wcswidth.Stringwidth(text) - 1)
+	self.lp.QueueWriteString(format_as_sgr.blame + " " + text + "\x1b[m")
+}
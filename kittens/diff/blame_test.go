@@ -0,0 +1,59 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestDiffBlameParsing(t *testing.T) {
+	a, b := strings.Repeat("a", 40), strings.Repeat("0", 40)
+	raw := strings.Join([]string{
+		a + " 1 1 2",
+		"author Some One",
+		"author-mail <one@example.com>",
+		"author-time 1700000000",
+		"summary first",
+		"filename x.txt",
+		"\tline one",
+		a + " 2 2",
+		"\tline two",
+		b + " 3 3 1",
+		"author Not Committed Yet",
+		"author-time 1700000100",
+		"filename x.txt",
+		"\tline three",
+	}, "\n") + "\n"
+	lines, err := parse_blame_porcelain([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(lines))
+	for i, c := range lines {
+		actual[i] = fmt.Sprintf("%s|%s|%d", c.sha, c.author, c.author_time.Unix())
+	}
+	if diff := cmp.Diff([]string{a + "|Some One|1700000000", a + "|Some One|1700000000", b + "|Not Committed Yet|1700000100"}, actual); diff != "" {
+		t.Fatalf("Failed to parse git blame output:\n%s", diff)
+	}
+	if lines[0] != lines[1] || !lines[2].is_uncommitted() || lines[2].String() != "uncommitted" {
+		t.Fatalf("Commits not shared or uncommitted lines not recognized: %v", lines)
+	}
+	c := blame_commit{sha: a, author: "X", author_time: time.Now().Add(-3 * 24 * time.Hour)}
+	if q := c.String(); q != "aaaaaaa X 3 days ago" {
+		t.Fatalf("Unexpected annotation: %#v", q)
+	}
+	bf := &blame_file{lines: lines}
+	if bf.commit_for_line(0) != nil || bf.commit_for_line(4) != nil || bf.commit_for_line(3) != lines[2] {
+		t.Fatalf("Incorrect commit for line")
+	}
+	if _, err = parse_blame_porcelain([]byte("garbage\n")); err == nil {
+		t.Fatalf("No error for invalid git blame output")
+	}
+}
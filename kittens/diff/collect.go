@@ -5,7 +5,6 @@ package diff
 import (
 	"crypto/md5"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -227,16 +226,6 @@ func (self *Collection) Apply(f func(path, typ, changed_path string) error) erro
 	return nil
 }
 
-func allowed(path string, patterns ...string) bool {
-	name := filepath.Base(path)
-	for _, pat := range patterns {
-		if matched, err := filepath.Match(pat, name); err == nil && matched {
-			return false
-		}
-	}
-	return true
-}
-
 func remote_hostname(path string) (string, string) {
 	for q, val := range remote_dirs {
 		if strings.HasPrefix(path, q) {
@@ -257,47 +246,35 @@ func resolve_remote_name(path, defval string) string {
 	return defval
 }
 
-func walk(base string, patterns []string, names *utils.Set[string], pmap, path_name_map map[string]string) error {
+func walk(base string, patterns []string, respect_ignore_files bool, names *utils.Set[string], pmap, path_name_map map[string]string) error {
 	base, err := filepath.Abs(base)
 	if err != nil {
 		return err
 	}
-	return filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
-		is_allowed := allowed(path, patterns...)
-		if !is_allowed {
-			if d.IsDir() {
-				return fs.SkipDir
-			}
-			return nil
-		}
-		if d.IsDir() {
-			return nil
-		}
-		path, err = filepath.Abs(path)
-		if err != nil {
-			return err
-		}
-		name, err := filepath.Rel(base, path)
-		if err != nil {
-			return err
-		}
-		if name != "." {
-			path_name_map[path] = name
-			names.Add(name)
-			pmap[name] = path
+	entries, err := utils.WalkFiltered(base, utils.WalkOptions{ExcludeNames: patterns, RespectIgnoreFiles: respect_ignore_files})
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
 		}
-		return nil
-	})
+		name := filepath.FromSlash(e.RelPath)
+		path_name_map[e.Path] = name
+		names.Add(name)
+		pmap[name] = e.Path
+	}
+	return nil
 }
 
 func (self *Collection) collect_files(left, right string) error {
 	left_names, right_names := utils.NewSet[string](16), utils.NewSet[string](16)
 	left_path_map, right_path_map := make(map[string]string, 16), make(map[string]string, 16)
-	err := walk(left, conf.Ignore_name, left_names, left_path_map, path_name_map)
+	err := walk(left, conf.Ignore_name, conf.Respect_ignore_files, left_names, left_path_map, path_name_map)
 	if err != nil {
 		return err
 	}
-	err = walk(right, conf.Ignore_name, right_names, right_path_map, path_name_map)
+	err = walk(right, conf.Ignore_name, conf.Respect_ignore_files, right_names, right_path_map, path_name_map)
 	common_names := left_names.Intersect(right_names)
 	changed_names := utils.NewSet[string](common_names.Len())
 	for n := range common_names.Iterable() {
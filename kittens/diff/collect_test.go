@@ -39,7 +39,7 @@ func TestDiffCollectWalk(t *testing.T) {
 	}
 	names := utils.NewSet[string](16)
 	pmap := make(map[string]string, 16)
-	if err := walk(tdir, []string{"*~", "#*#", "b"}, names, pmap, map[string]string{}); err != nil {
+	if err := walk(tdir, []string{"*~", "#*#", "b"}, false, names, pmap, map[string]string{}); err != nil {
 		t.Fatal(err)
 	}
 	if diff := cmp.Diff(
@@ -51,4 +51,13 @@ func TestDiffCollectWalk(t *testing.T) {
 	if diff := cmp.Diff(expected_pmap, pmap); diff != "" {
 		t.Fatal(diff)
 	}
+
+	os.WriteFile(j(".gitignore"), []byte("e\n/f/\n"), 0o600)
+	names = utils.NewSet[string](16)
+	if err := walk(tdir, []string{"*~", "#*#", "b"}, true, names, map[string]string{}, map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{".gitignore", "d", "h space"}, utils.Sort(names.AsSlice(), strings.Compare)); diff != "" {
+		t.Fatal(diff)
+	}
 }
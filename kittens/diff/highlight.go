@@ -12,7 +12,6 @@ import (
 	"sync"
 
 	"kitty/tools/utils"
-	"kitty/tools/utils/images"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/lexers"
@@ -194,15 +193,9 @@ func highlight_file(path string) (highlighted string, err error) {
 	return w.String(), err
 }
 
-func highlight_all(paths []string) {
-	ctx := images.Context{}
-	ctx.Parallel(0, len(paths), func(nums <-chan int) {
-		for i := range nums {
-			path := paths[i]
-			raw, err := highlight_file(path)
-			if err == nil {
-				highlighted_lines_cache.Set(path, text_to_lines(raw))
-			}
-		}
-	})
+func highlight_path(path string) {
+	raw, err := highlight_file(path)
+	if err == nil {
+		highlighted_lines_cache.Set(path, text_to_lines(raw))
+	}
 }
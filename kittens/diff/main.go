@@ -15,6 +15,7 @@ import (
 	"kitty/kittens/ssh"
 	"kitty/tools/cli"
 	"kitty/tools/config"
+	"kitty/tools/tty"
 	"kitty/tools/tui/loop"
 	"kitty/tools/utils"
 )
@@ -103,7 +104,17 @@ func main(_ *cli.Command, opts_ *Options, args []string) (rc int, err error) {
 	if err != nil {
 		return 1, err
 	}
-	if len(args) != 2 {
+	patch_file := ""
+	switch len(args) {
+	case 2:
+	case 1:
+		patch_file = args[0]
+	case 0:
+		if tty.IsTerminal(os.Stdin.Fd()) {
+			return 1, fmt.Errorf("You must specify exactly two files/directories to compare or a patch file")
+		}
+		patch_file = "-"
+	default:
 		return 1, fmt.Errorf("You must specify exactly two files/directories to compare")
 	}
 	if err = set_diff_command(conf.Diff_cmd); err != nil {
@@ -116,13 +127,28 @@ func main(_ *cli.Command, opts_ *Options, args []string) (rc int, err error) {
 			os.RemoveAll(tdir)
 		}
 	}()
-	left, err := get_remote_file(args[0])
-	if err != nil {
-		return 1, err
-	}
-	right, err := get_remote_file(args[1])
-	if err != nil {
-		return 1, err
+	var left, right, title string
+	if patch_file == "" {
+		if left, err = get_remote_file(args[0]); err != nil {
+			return 1, err
+		}
+		if right, err = get_remote_file(args[1]); err != nil {
+			return 1, err
+		}
+		title = fmt.Sprintf("%s vs. %s", left, right)
+	} else {
+		tdir, err := os.MkdirTemp("", "kitty-diff-patch-*")
+		if err != nil {
+			return 1, err
+		}
+		defer os.RemoveAll(tdir)
+		if left, right, err = sides_from_patch(patch_file, tdir); err != nil {
+			return 1, err
+		}
+		title = "Patch"
+		if patch_file != "-" {
+			title = "Patch: " + patch_file
+		}
 	}
 	if isdir(left) != isdir(right) {
 		return 1, fmt.Errorf("The items to be diffed should both be either directories or files. Comparing a directory to a file is not valid.'")
@@ -143,7 +169,7 @@ func main(_ *cli.Command, opts_ *Options, args []string) (rc int, err error) {
 		lp.SetCursorVisible(false)
 		lp.SetCursorShape(loop.BAR_CURSOR, true)
 		lp.AllowLineWrapping(false)
-		lp.SetWindowTitle(fmt.Sprintf("%s vs. %s", left, right))
+		lp.SetWindowTitle(title)
 		h.initialize()
 		return "", nil
 	}
@@ -53,6 +53,15 @@ def main(args: List[str]) -> None:
     long_text='The string to replace tabs with. Default is to use four spaces.'
     )
 
+opt('show_minimap', 'yes', option_type='to_bool',
+    long_text='''
+Show an overview of the whole diff in the rightmost column of the screen,
+marking the locations of added and removed lines, the start of each file and
+search matches, along with the currently visible region. Click or drag in it to
+jump to the corresponding part of the diff.
+'''
+    )
+
 opt('+ignore_name', '', ctype='string',
     add_to_default=False,
     long_text='''
@@ -66,6 +75,15 @@ def main(args: List[str]) -> None:
 ''',
     )
 
+opt('respect_ignore_files', 'no', option_type='to_bool',
+    long_text='''
+When scanning directories, also ignore the files and directories that are
+ignored by the rules in :file:`.gitignore` and :file:`.ignore` files, as well
+as :file:`.git` directories. The rules are applied separately to the two
+directories being compared.
+'''
+    )
+
 egr()  # }}}
 
 # colors {{{
@@ -267,6 +285,15 @@ def main(args: List[str]) -> None:
     'search_backward_simple b start_search substring backward',
     )
 
+map('Toggle git blame annotations',
+    'toggle_blame shift+b toggle_blame',
+    long_text='''
+Annotate context lines with the commit that last changed them, showing its
+abbreviated hash, author and age. The data is fetched from :program:`git blame`
+as files are displayed, so it is only available for files tracked by git.
+'''
+    )
+
 map('Copy selection to clipboard', 'copy_to_clipboard y copy_to_clipboard')
 map('Copy selection to clipboard or exit if no selection is present', 'copy_to_clipboard_or_exit ctrl+c copy_to_clipboard_or_exit')
 
@@ -292,8 +319,12 @@ def main(args: List[str]) -> None:
 Syntax: :italic:`name=value`. For example: :italic:`-o background=gray`
 
 '''.format, config_help=CONFIG_HELP.format(conf_name='diff', appname=appname))
-help_text = 'Show a side-by-side diff of the specified files/directories. You can also use :italic:`ssh:hostname:remote-file-path` to diff remote files.'
-usage = 'file_or_directory_left file_or_directory_right'
+help_text = (
+    'Show a side-by-side diff of the specified files/directories. You can also use :italic:`ssh:hostname:remote-file-path` to diff remote files.'
+    ' Alternately, specify a single patch file in unified diff format, or pipe one into STDIN, for example, from :code:`git diff`,'
+    ' to view it. The full files are used from the working tree when they match the patch, to show all the context around the changes.'
+)
+usage = 'file_or_directory_left file_or_directory_right | patch_file'
 
 
 
@@ -0,0 +1,172 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+type minimap_cell uint8
+
+const (
+	MINIMAP_ADDED minimap_cell = 1 << iota
+	MINIMAP_REMOVED
+	MINIMAP_MATCH
+	MINIMAP_FILE_START
+)
+
+// A one column overview of the whole diff drawn at the right edge of the
+// screen, each row of which summarizes a range of screen lines
+type minimap struct {
+	cells            []minimap_cell
+	num_screen_lines int
+	dragging         bool
+	// the inputs the cells were built from, used to invalidate the cache
+	lines  *LogicalLines
+	search *Search
+}
+
+func minimap_flags_for_line(ll *LogicalLine, sl *ScreenLine) (ans minimap_cell) {
+	switch ll.line_type {
+	case TITLE_LINE:
+		ans |= MINIMAP_FILE_START
+	case CHANGE_LINE:
+		if ll.is_full_width {
+			break
+		}
+		if !sl.left.is_filler {
+			ans |= MINIMAP_REMOVED
+		}
+		if !sl.right.is_filler {
+			ans |= MINIMAP_ADDED
+		}
+	}
+	return
+}
+
+// The minimap row that the specified screen line is summarized in
+func (self *minimap) row_for(screen_line int) int {
+	if self.num_screen_lines <= len(self.cells) {
+		return screen_line
+	}
+	return screen_line * len(self.cells) / self.num_screen_lines
+}
+
+// The first screen line summarized in the specified minimap row
+func (self *minimap) screen_line_for(row int) int {
+	if self.num_screen_lines <= len(self.cells) {
+		return row
+	}
+	return row * self.num_screen_lines / len(self.cells)
+}
+
+func (self *minimap) build(lines *LogicalLines, search *Search, num_rows int) {
+	self.lines, self.search = lines, search
+	self.cells = make([]minimap_cell, num_rows)
+	self.num_screen_lines = lines.num_of_screen_lines()
+	n := 0
+	for i, ll := range lines.lines {
+		for j, sl := range ll.screen_lines {
+			row := self.row_for(n)
+			self.cells[row] |= minimap_flags_for_line(ll, sl)
+			if search != nil && search.Has(ScrollPos{i, j}) {
+				self.cells[row] |= MINIMAP_MATCH
+			}
+			n++
+		}
+	}
+}
+
+func (self *minimap) update(lines *LogicalLines, search *Search, num_rows int) {
+	if self.lines != lines || self.search != search || len(self.cells) != num_rows {
+		self.build(lines, search, num_rows)
+	}
+}
+
+func (self minimap_cell) render(in_viewport bool) string {
+	ch := " "
+	if in_viewport {
+		ch = "┃"
+	}
+	switch {
+	case self&MINIMAP_MATCH != 0:
+		return format_as_sgr.search + ch
+	case self&(MINIMAP_ADDED|MINIMAP_REMOVED) == MINIMAP_ADDED|MINIMAP_REMOVED:
+		return format_as_sgr.minimap_modified + "▐"
+	case self&MINIMAP_ADDED != 0:
+		return format_as_sgr.minimap_added + ch
+	case self&MINIMAP_REMOVED != 0:
+		return format_as_sgr.minimap_removed + ch
+	case self&MINIMAP_FILE_START != 0 && !in_viewport:
+		return format_as_sgr.margin + "─"
+	}
+	return format_as_sgr.margin + ch
+}
+
+func (self *Handler) minimap_shown() bool {
+	return conf.Show_minimap && self.logical_lines != nil
+}
+
+func (self *Handler) draw_minimap() {
+	num_rows := self.screen_size.num_lines
+	self.minimap.update(self.logical_lines, self.current_search, num_rows)
+	top := self.logical_lines.NumScreenLinesTo(self.scroll_pos)
+	first, last := self.minimap.row_for(top), self.minimap.row_for(top+num_rows-1)
+	buf := strings.Builder{}
+	for y, cell := range self.minimap.cells {
+		buf.WriteString(fmt.Sprintf("\x1b[%d;%dH", y+1, self.screen_size.columns))
+		buf.WriteString(cell.render(first <= y && y <= last))
+	}
+	buf.WriteString("\x1b[m")
+	self.lp.QueueWriteString(buf.String())
+}
+
+// Scroll so that the lines summarized in the specified minimap row are in
+// the middle of the screen
+func (self *Handler) scroll_to_minimap_row(y int) {
+	y = utils.Max(0, utils.Min(y, len(self.minimap.cells)-1))
+	target := self.minimap.screen_line_for(y) - self.screen_size.num_lines/2
+	pos := ScrollPos{}
+	self.logical_lines.IncrementScrollPosBy(&pos, utils.Max(0, target))
+	if self.max_scroll_pos.Less(pos) {
+		pos = self.max_scroll_pos
+	}
+	if pos != self.scroll_pos {
+		self.scroll_pos = pos
+		self.draw_screen()
+	}
+}
+
+func (self *Handler) handle_minimap_mouse_event(ev *loop.MouseEvent) bool {
+	if !self.minimap_shown() {
+		return false
+	}
+	switch ev.Event_type {
+	case loop.MOUSE_PRESS:
+		if ev.Buttons&loop.LEFT_MOUSE_BUTTON == 0 || ev.Cell.X != self.screen_size.columns-1 || ev.Cell.Y >= self.screen_size.num_lines {
+			return false
+		}
+		self.minimap.dragging = true
+		self.scroll_to_minimap_row(ev.Cell.Y)
+		return true
+	case loop.MOUSE_MOVE:
+		if !self.minimap.dragging {
+			return false
+		}
+		self.scroll_to_minimap_row(ev.Cell.Y)
+		return true
+	case loop.MOUSE_RELEASE:
+		if !self.minimap.dragging {
+			return false
+		}
+		self.minimap.dragging = false
+		return true
+	}
+	return false
+}
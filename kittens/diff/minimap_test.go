@@ -0,0 +1,52 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestDiffMinimap(t *testing.T) {
+	screen_lines := func(n int, sl ScreenLine) (ans []*ScreenLine) {
+		for i := 0; i < n; i++ {
+			x := sl
+			ans = append(ans, &x)
+		}
+		return
+	}
+	filler := HalfScreenLine{is_filler: true}
+	lines := &LogicalLines{lines: []*LogicalLine{
+		{line_type: TITLE_LINE, is_full_width: true, screen_lines: screen_lines(2, ScreenLine{})},
+		{line_type: CONTEXT_LINE, screen_lines: screen_lines(4, ScreenLine{})},
+		{line_type: CHANGE_LINE, screen_lines: screen_lines(2, ScreenLine{right: filler})},
+		{line_type: CHANGE_LINE, screen_lines: screen_lines(2, ScreenLine{left: filler})},
+		{line_type: CHANGE_LINE, screen_lines: screen_lines(2, ScreenLine{})},
+	}}
+	search := &Search{matches: map[ScrollPos][]Span{{1, 3}: {{0, 1}}}}
+
+	m := minimap{}
+	m.update(lines, search, 6)
+	if diff := cmp.Diff([]minimap_cell{
+		MINIMAP_FILE_START, 0, MINIMAP_MATCH, MINIMAP_REMOVED, MINIMAP_ADDED, MINIMAP_ADDED | MINIMAP_REMOVED,
+	}, m.cells); diff != "" {
+		t.Fatalf("Unexpected minimap cells:\n%s", diff)
+	}
+	for row, line := range map[int]int{0: 0, 1: 2, 3: 6, 5: 10} {
+		if actual := m.screen_line_for(row); actual != line {
+			t.Fatalf("Row %d mapped to screen line %d instead of %d", row, actual, line)
+		}
+		if actual := m.row_for(line); actual != row {
+			t.Fatalf("Screen line %d mapped to row %d instead of %d", line, actual, row)
+		}
+	}
+
+	m.update(lines, nil, 20)
+	if len(m.cells) != 20 || m.row_for(11) != 11 || m.cells[5]&MINIMAP_MATCH != 0 {
+		t.Fatalf("Minimap not rebuilt with a one to one mapping: %v", m.cells)
+	}
+}
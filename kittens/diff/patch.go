@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"kitty/tools/utils"
-	"kitty/tools/utils/images"
 	"kitty/tools/utils/shlex"
 	"os/exec"
 	"path/filepath"
@@ -348,30 +347,3 @@ func do_diff(file1, file2 string, context_count int) (ans *Patch, err error) {
 }
 
 type diff_job struct{ file1, file2 string }
-
-func diff(jobs []diff_job, context_count int) (ans map[string]*Patch, err error) {
-	ans = make(map[string]*Patch)
-	ctx := images.Context{}
-	type result struct {
-		file1, file2 string
-		err          error
-		patch        *Patch
-	}
-	results := make(chan result, len(jobs))
-	ctx.Parallel(0, len(jobs), func(nums <-chan int) {
-		for i := range nums {
-			job := jobs[i]
-			r := result{file1: job.file1, file2: job.file2}
-			r.patch, r.err = do_diff(job.file1, job.file2, context_count)
-			results <- r
-		}
-	})
-	close(results)
-	for r := range results {
-		if r.err != nil {
-			return nil, r.err
-		}
-		ans[r.file1] = r.patch
-	}
-	return ans, nil
-}
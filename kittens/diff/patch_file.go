@@ -0,0 +1,231 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type patch_hunk struct {
+	// zero based line numbers of the first line of the hunk on each side
+	left_start, right_start int
+	left, right             []string
+}
+
+// A single file from a unified diff. The names are empty for added and
+// removed files.
+type patched_file struct {
+	left_name, right_name string
+	hunks                 []*patch_hunk
+}
+
+func patch_file_name(x string) string {
+	x, _, _ = strings.Cut(x, "\t")
+	x = strings.TrimSpace(x)
+	if x == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(x, `"`) {
+		if q, err := strconv.Unquote(x); err == nil {
+			x = q
+		}
+	}
+	return x
+}
+
+// Remove the a/ and b/ prefixes git uses for the two sides of the diff
+func (self *patched_file) strip_git_prefixes() {
+	if (self.left_name == "" || strings.HasPrefix(self.left_name, "a/")) && (self.right_name == "" || strings.HasPrefix(self.right_name, "b/")) {
+		self.left_name = strings.TrimPrefix(self.left_name, "a/")
+		self.right_name = strings.TrimPrefix(self.right_name, "b/")
+	}
+}
+
+// Parse the files and hunks from a unified diff, such as the output of
+// git diff or diff -u
+func parse_patch_file(raw string) (ans []*patched_file, err error) {
+	var current *patched_file
+	var hunk *patch_hunk
+	left_remaining, right_remaining := 0, 0
+	left_name, has_left_name := "", false
+	splitlines_like_git(raw, false, func(line string) {
+		if hunk != nil && (left_remaining > 0 || right_remaining > 0) {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				hunk.right = append(hunk.right, line[1:])
+				right_remaining--
+			case strings.HasPrefix(line, "-"):
+				hunk.left = append(hunk.left, line[1:])
+				left_remaining--
+			case strings.HasPrefix(line, `\`):
+			default:
+				// some tools strip the trailing space from empty context lines
+				if line != "" {
+					line = line[1:]
+				}
+				hunk.left = append(hunk.left, line)
+				hunk.right = append(hunk.right, line)
+				left_remaining--
+				right_remaining--
+			}
+			return
+		}
+		hunk = nil
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			left_name, has_left_name = patch_file_name(line[4:]), true
+		case strings.HasPrefix(line, "+++ ") && has_left_name:
+			current = &patched_file{left_name: left_name, right_name: patch_file_name(line[4:])}
+			current.strip_git_prefixes()
+			ans = append(ans, current)
+			has_left_name = false
+		case strings.HasPrefix(line, "@@ ") && current != nil:
+			h := parse_hunk_header(line)
+			hunk = &patch_hunk{left_start: h.left_start, right_start: h.right_start}
+			if h.left_count == 0 {
+				// an empty range refers to the line before the change
+				hunk.left_start++
+			}
+			if h.right_count == 0 {
+				hunk.right_start++
+			}
+			left_remaining, right_remaining = h.left_count, h.right_count
+			current.hunks = append(current.hunks, hunk)
+		}
+	})
+	if len(ans) == 0 {
+		err = fmt.Errorf("No changed files found in the patch")
+	}
+	return
+}
+
+// Replace the lines from one side of every hunk with the lines from the other
+// side, returning false if lines do not match the patch
+func (self *patched_file) transform(lines []string, reverse bool) ([]string, bool) {
+	ans := make([]string, 0, len(lines))
+	pos := 0
+	for _, h := range self.hunks {
+		start, from, to := h.left_start, h.left, h.right
+		if reverse {
+			start, from, to = h.right_start, h.right, h.left
+		}
+		if start < pos || start+len(from) > len(lines) || !slices.Equal(lines[start:start+len(from)], from) {
+			return nil, false
+		}
+		ans = append(ans, lines[pos:start]...)
+		ans = append(ans, to...)
+		pos = start + len(from)
+	}
+	return append(ans, lines[pos:]...), true
+}
+
+// The two sides of the file using only the lines in the patch, with blank
+// lines filling in the gaps between hunks so that line numbers are preserved
+func (self *patched_file) sides_from_hunks() (left, right []string) {
+	pad := func(lines []string, n int) []string {
+		for len(lines) < n {
+			lines = append(lines, "")
+		}
+		return lines
+	}
+	for _, h := range self.hunks {
+		left = append(pad(left, h.left_start), h.left...)
+		right = append(pad(right, h.right_start), h.right...)
+	}
+	return
+}
+
+// The contents of the two sides of the file. When a side of the file is
+// present in the working tree and matches the patch, the full file is used,
+// so that all the context around the changes is available.
+func (self *patched_file) sides() (left, right []string) {
+	read := func(name string) ([]string, bool) {
+		if name == "" {
+			return nil, false
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, false
+		}
+		return text_to_lines(string(data)), true
+	}
+	if r, ok := read(self.right_name); ok {
+		if l, ok := self.transform(r, true); ok {
+			return l, r
+		}
+	}
+	if l, ok := read(self.left_name); ok {
+		if r, ok := self.transform(l, false); ok {
+			return l, r
+		}
+	}
+	return self.sides_from_hunks()
+}
+
+// Convert a name from the patch into a path inside base, names that could
+// refer to locations outside base are reduced to their last component
+func path_in(base, name string) string {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		name = filepath.Base(name)
+	}
+	return filepath.Join(base, name)
+}
+
+func write_lines(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	text := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		text += "\n"
+	}
+	return os.WriteFile(path, []byte(text), 0o600)
+}
+
+// Read a patch from the specified file, or STDIN if the file is - and
+// reconstruct both sides of every file in it in directories inside tdir
+func sides_from_patch(patch_file, tdir string) (left, right string, err error) {
+	var raw []byte
+	if patch_file == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(patch_file)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to read the patch from %s with error: %w", patch_file, err)
+	}
+	files, err := parse_patch_file(string(raw))
+	if err != nil {
+		return
+	}
+	left, right = filepath.Join(tdir, "a"), filepath.Join(tdir, "b")
+	for _, d := range []string{left, right} {
+		if err = os.MkdirAll(d, 0o700); err != nil {
+			return
+		}
+	}
+	for _, f := range files {
+		l, r := f.sides()
+		if f.left_name != "" {
+			if err = write_lines(path_in(left, f.left_name), l); err != nil {
+				return
+			}
+		}
+		if f.right_name != "" {
+			if err = write_lines(path_in(right, f.right_name), r); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,115 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+const test_patch = `diff --git a/x.txt b/x.txt
+index 1111111..2222222 100644
+--- a/x.txt
++++ b/x.txt
+@@ -2,3 +2,3 @@ title
+ 2
+-3
++three
+ 4
+@@ -7,0 +8,2 @@
++-- not a header
+++++ not a header either
+diff --git a/gone b/gone
+deleted file mode 100644
+--- a/gone
++++ /dev/null
+@@ -1 +0,0 @@
+-x
+\ No newline at end of file
+--- /dev/null	2023-01-01 00:00:00
++++ "new name"	2023-01-01 00:00:00
+@@ -0,0 +1 @@
++y
+`
+
+func TestDiffParsePatchFile(t *testing.T) {
+	files, err := parse_patch_file(test_patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*patched_file{
+		{left_name: "x.txt", right_name: "x.txt", hunks: []*patch_hunk{
+			{left_start: 1, right_start: 1, left: []string{"2", "3", "4"}, right: []string{"2", "three", "4"}},
+			{left_start: 7, right_start: 7, right: []string{"-- not a header", "+++ not a header either"}},
+		}},
+		{left_name: "gone", hunks: []*patch_hunk{{left_start: 0, right_start: 0, left: []string{"x"}}}},
+		{right_name: "new name", hunks: []*patch_hunk{{left_start: 0, right_start: 0, right: []string{"y"}}}},
+	}, files, cmp.AllowUnexported(patched_file{}, patch_hunk{})); diff != "" {
+		t.Fatalf("Patch not parsed correctly:\n%s", diff)
+	}
+	if _, err = parse_patch_file("not a patch"); err == nil {
+		t.Fatalf("Parsing an invalid patch did not fail")
+	}
+
+	f := files[0]
+	l, r := f.sides_from_hunks()
+	if diff := cmp.Diff([]string{"", "2", "3", "4", "", "", ""}, l); diff != "" {
+		t.Fatalf("Left side from hunks not correct:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"", "2", "three", "4", "", "", "", "-- not a header", "+++ not a header either"}, r); diff != "" {
+		t.Fatalf("Right side from hunks not correct:\n%s", diff)
+	}
+
+	original := strings.Split("1 2 3 4 5 6 7 8", " ")
+	patched := []string{"1", "2", "three", "4", "5", "6", "7", "-- not a header", "+++ not a header either", "8"}
+	if q, ok := f.transform(original, false); !ok {
+		t.Fatalf("Failed to apply patch")
+	} else if diff := cmp.Diff(patched, q); diff != "" {
+		t.Fatalf("Patch not applied correctly:\n%s", diff)
+	}
+	if q, ok := f.transform(patched, true); !ok {
+		t.Fatalf("Failed to reverse patch")
+	} else if diff := cmp.Diff(original, q); diff != "" {
+		t.Fatalf("Patch not reversed correctly:\n%s", diff)
+	}
+	if _, ok := f.transform(patched, false); ok {
+		t.Fatalf("Applying patch to non-matching lines did not fail")
+	}
+
+	tdir := t.TempDir()
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	os.Chdir(tdir)
+	os.WriteFile("x.txt", []byte(strings.Join(patched, "\n")+"\n"), 0o600)
+	os.WriteFile("p.diff", []byte(test_patch), 0o600)
+	left, right, err := sides_from_patch("p.diff", filepath.Join(tdir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if diff := cmp.Diff(strings.Join(original, "\n")+"\n", read(filepath.Join(left, "x.txt"))); diff != "" {
+		t.Fatalf("Left side not reconstructed from working tree:\n%s", diff)
+	}
+	if diff := cmp.Diff("x\n", read(filepath.Join(left, "gone"))); diff != "" {
+		t.Fatalf("Removed file not correct:\n%s", diff)
+	}
+	if diff := cmp.Diff("y\n", read(filepath.Join(right, "new name"))); diff != "" {
+		t.Fatalf("Added file not correct:\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(right, "gone")); err == nil {
+		t.Fatalf("Removed file present on the right side")
+	}
+}
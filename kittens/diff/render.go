@@ -13,6 +13,7 @@ import (
 	"kitty/tools/tui/loop"
 	"kitty/tools/tui/sgr"
 	"kitty/tools/utils"
+	"kitty/tools/utils/humanize"
 	"kitty/tools/utils/style"
 	"kitty/tools/wcswidth"
 )
@@ -156,6 +157,7 @@ func place_in(text string, sz int) string {
 
 var format_as_sgr struct {
 	title, margin, added, removed, added_margin, removed_margin, filler, margin_filler, hunk_margin, hunk, selection, search string
+	minimap_added, minimap_removed, minimap_modified, blame                                                                  string
 }
 
 var statusline_format, added_count_format, removed_count_format, message_format, selection_format func(...any) string
@@ -182,6 +184,10 @@ func create_formatters() {
 	format_as_sgr.hunk = only_open(fmt.Sprintf("fg=%s bg=%s", conf.Margin_fg.AsRGBSharp(), conf.Hunk_bg.AsRGBSharp()))
 	format_as_sgr.hunk_margin = only_open(fmt.Sprintf("fg=%s bg=%s", conf.Margin_fg.AsRGBSharp(), conf.Hunk_margin_bg.AsRGBSharp()))
 	format_as_sgr.search = only_open(fmt.Sprintf("fg=%s bg=%s", conf.Search_fg.AsRGBSharp(), conf.Search_bg.AsRGBSharp()))
+	format_as_sgr.minimap_added = only_open(fmt.Sprintf("fg=%s bg=%s", conf.Margin_fg.AsRGBSharp(), conf.Highlight_added_bg.AsRGBSharp()))
+	format_as_sgr.minimap_removed = only_open(fmt.Sprintf("fg=%s bg=%s", conf.Margin_fg.AsRGBSharp(), conf.Highlight_removed_bg.AsRGBSharp()))
+	format_as_sgr.minimap_modified = only_open(fmt.Sprintf("fg=%s bg=%s", conf.Highlight_added_bg.AsRGBSharp(), conf.Highlight_removed_bg.AsRGBSharp()))
+	format_as_sgr.blame = only_open(fmt.Sprintf("fg=%s italic", conf.Margin_fg.AsRGBSharp()))
 	statusline_format = ctx.SprintFunc(fmt.Sprintf("fg=%s", conf.Margin_fg.AsRGBSharp()))
 	added_count_format = ctx.SprintFunc(fmt.Sprintf("fg=%s", conf.Highlight_added_bg.AsRGBSharp()))
 	removed_count_format = ctx.SprintFunc(fmt.Sprintf("fg=%s", conf.Highlight_removed_bg.AsRGBSharp()))
@@ -326,7 +332,8 @@ func human_readable(size int64) string {
 		idx := strings.IndexByte(s, '.')
 		s = s[:idx]
 	}
-	return s + " " + suffix
+	loc := humanize.CurrentLocale()
+	return strings.Replace(s, ".", loc.Decimal, 1) + loc.UnitSeparator + suffix
 }
 
 func image_lines(left_path, right_path string, screen_size screen_size, margin_size int, image_size graphics.Size, ans []*LogicalLine) ([]*LogicalLine, error) {
@@ -564,6 +571,21 @@ func lines_for_diff_chunk(data *DiffData, hunk_num int, chunk *Chunk, chunk_num
 	return ans
 }
 
+// Placeholder for a file whose diff is still being computed
+func pending_diff_lines(left_path, right_path string, columns, margin_size int, ans []*LogicalLine) []*LogicalLine {
+	ll := LogicalLine{
+		line_type:      EMPTY_LINE,
+		left_reference: Reference{path: left_path}, right_reference: Reference{path: right_path},
+		is_full_width: true,
+	}
+	for _, line := range splitlines("Calculating diff…", columns-margin_size) {
+		sl := ScreenLine{}
+		sl.left.marked_up_text = line
+		ll.screen_lines = append(ll.screen_lines, &sl)
+	}
+	return append(ans, &ll)
+}
+
 func lines_for_diff(left_path string, right_path string, patch *Patch, columns, margin_size int, ans []*LogicalLine) (result []*LogicalLine, err error) {
 	ht := LogicalLine{
 		line_type:      HUNK_TITLE_LINE,
@@ -710,8 +732,10 @@ func render(collection *Collection, diff_map map[string]*Patch, screen_size scre
 				} else {
 					ans, err = binary_lines(path, changed_path, columns, margin_size, ans)
 				}
+			} else if patch, found := diff_map[path]; found {
+				ans, err = lines_for_diff(path, changed_path, patch, columns, margin_size, ans)
 			} else {
-				ans, err = lines_for_diff(path, changed_path, diff_map[path], columns, margin_size, ans)
+				ans = pending_diff_lines(path, changed_path, columns, margin_size, ans)
 			}
 			if err != nil {
 				return err
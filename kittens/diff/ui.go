@@ -7,6 +7,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"kitty/tools/config"
 	"kitty/tools/tui"
@@ -27,6 +29,7 @@ const (
 	HIGHLIGHT
 	IMAGE_LOAD
 	IMAGE_RESIZE
+	BLAME
 )
 
 type ScrollPos struct {
@@ -45,8 +48,12 @@ type AsyncResult struct {
 	err        error
 	rtype      ResultType
 	collection *Collection
-	diff_map   map[string]*Patch
 	page_size  graphics.Size
+	// the result of diffing a single file
+	path       string
+	patch      *Patch
+	generation int64
+	blame      *blame_file
 }
 
 var image_collection *graphics.ImageCollection
@@ -74,6 +81,16 @@ type Handler struct {
 	current_search_is_regex, current_search_is_backward bool
 	largest_line_number                                 int
 	images_resized_to                                   graphics.Size
+	workers                                             *worker_pool
+	// incremented every time the diff is regenerated so that results from
+	// superseded diffs can be ignored
+	diff_generation                                atomic.Int64
+	pending_diffs, total_diffs, pending_highlights int
+	needs_rerender                                 bool
+	last_render_at                                 time.Time
+	rerender_timer                                 loop.IdType
+	minimap                                        minimap
+	blame                                          blame_state
 }
 
 func (self *Handler) calculate_statistics() {
@@ -130,6 +147,7 @@ func (self *Handler) initialize() {
 		self.lp.SetDefaultColor(loop.SELECTION_FG, conf.Select_fg.Color)
 	}
 	self.async_results = make(chan AsyncResult, 32)
+	self.workers = new_worker_pool()
 	go func() {
 		r := AsyncResult{}
 		r.collection, r.err = create_collection(self.left, self.right)
@@ -139,8 +157,14 @@ func (self *Handler) initialize() {
 	self.draw_screen()
 }
 
+func (self *Handler) send_async_result(r AsyncResult) {
+	self.async_results <- r
+	self.lp.WakeupMainThread()
+}
+
+// Diff all files on the worker pool, rendering the results as each file
+// completes
 func (self *Handler) generate_diff() {
-	self.diff_map = nil
 	jobs := make([]diff_job, 0, 32)
 	self.collection.Apply(func(path, typ, changed_path string) error {
 		if typ == "diff" {
@@ -150,12 +174,24 @@ func (self *Handler) generate_diff() {
 		}
 		return nil
 	})
-	go func() {
-		r := AsyncResult{rtype: DIFF}
-		r.diff_map, r.err = diff(jobs, self.current_context_count)
-		self.async_results <- r
-		self.lp.WakeupMainThread()
-	}()
+	generation := self.diff_generation.Add(1)
+	self.diff_map = make(map[string]*Patch, len(jobs))
+	self.pending_diffs, self.total_diffs = len(jobs), len(jobs)
+	self.needs_rerender = true
+	if len(jobs) == 0 {
+		return
+	}
+	context_count := self.current_context_count
+	self.workers.submit(utils.Map(func(j diff_job) func() {
+		return func() {
+			if self.diff_generation.Load() != generation {
+				return // superseded by a newer diff
+			}
+			r := AsyncResult{rtype: DIFF, path: j.file1, generation: generation}
+			r.patch, r.err = do_diff(j.file1, j.file2, context_count)
+			self.send_async_result(r)
+		}
+	}, jobs)...)
 }
 
 func (self *Handler) on_wakeup() error {
@@ -163,7 +199,7 @@ func (self *Handler) on_wakeup() error {
 	for {
 		select {
 		case r = <-self.async_results:
-			if r.err != nil {
+			if r.err != nil && r.rtype != DIFF {
 				return r.err
 			}
 			r.err = self.handle_async_result(r)
@@ -171,20 +207,55 @@ func (self *Handler) on_wakeup() error {
 				return r.err
 			}
 		default:
+			return self.rerender_if_needed()
+		}
+	}
+}
+
+// Re-render at most every 100ms while results are still arriving, as
+// rendering large diffs is expensive
+func (self *Handler) rerender_if_needed() error {
+	if !self.needs_rerender || self.collection == nil || self.rerender_timer != 0 {
+		return nil
+	}
+	const interval = 100 * time.Millisecond
+	if self.pending_diffs+self.pending_highlights > 0 {
+		if wait := interval - time.Since(self.last_render_at); wait > 0 {
+			self.rerender_timer, _ = self.lp.AddTimer(wait, false, func(loop.IdType) error {
+				self.rerender_timer = 0
+				return self.rerender_if_needed()
+			})
 			return nil
 		}
 	}
+	self.needs_rerender = false
+	self.last_render_at = time.Now()
+	self.calculate_statistics()
+	if err := self.render_diff(); err != nil {
+		return err
+	}
+	if self.restore_position != nil {
+		self.scroll_pos = *self.restore_position
+		if self.pending_diffs == 0 {
+			self.restore_position = nil
+		}
+	}
+	if self.max_scroll_pos.Less(self.scroll_pos) {
+		self.scroll_pos = self.max_scroll_pos
+	}
+	self.draw_screen()
+	return nil
 }
 
 func (self *Handler) highlight_all() {
 	text_files := utils.Filter(self.collection.paths_to_highlight.AsSlice(), is_path_text)
-	go func() {
-		r := AsyncResult{rtype: HIGHLIGHT}
-		highlight_all(text_files)
-		self.async_results <- r
-		self.lp.WakeupMainThread()
-	}()
-
+	self.pending_highlights = len(text_files)
+	self.workers.submit(utils.Map(func(path string) func() {
+		return func() {
+			highlight_path(path)
+			self.send_async_result(AsyncResult{rtype: HIGHLIGHT, path: path})
+		}
+	}, text_files)...)
 }
 
 func (self *Handler) load_all_images() {
@@ -250,27 +321,29 @@ func (self *Handler) handle_async_result(r AsyncResult) error {
 		self.highlight_all()
 		self.load_all_images()
 	case DIFF:
-		self.diff_map = r.diff_map
-		self.calculate_statistics()
-		self.clear_mouse_selection()
-		err := self.render_diff()
-		if err != nil {
-			return err
+		if r.generation != self.diff_generation.Load() {
+			return nil
 		}
-		self.scroll_pos = ScrollPos{}
-		if self.restore_position != nil {
-			self.scroll_pos = *self.restore_position
-			if self.max_scroll_pos.Less(self.scroll_pos) {
-				self.scroll_pos = self.max_scroll_pos
-			}
-			self.restore_position = nil
+		if r.err != nil {
+			return r.err
 		}
-		self.draw_screen()
+		self.diff_map[r.path] = r.patch
+		self.pending_diffs--
+		self.clear_mouse_selection()
+		self.needs_rerender = true
+	case HIGHLIGHT:
+		self.pending_highlights--
+		self.needs_rerender = true
 	case IMAGE_RESIZE:
 		self.images_resized_to = r.page_size
 		return self.rerender_diff()
-	case IMAGE_LOAD, HIGHLIGHT:
+	case IMAGE_LOAD:
 		return self.rerender_diff()
+	case BLAME:
+		self.blame.files[r.path] = r.blame
+		if self.blame.shown {
+			self.draw_screen()
+		}
 	}
 	return nil
 }
@@ -292,13 +365,17 @@ func (self *Handler) on_resize(old_size, new_size loop.ScreenSize) error {
 }
 
 func (self *Handler) render_diff() (err error) {
-	if self.screen_size.columns < 8 {
-		return fmt.Errorf("Screen too narrow, need at least 8 columns")
+	if self.screen_size.columns < 9 {
+		return fmt.Errorf("Screen too narrow, need at least 9 columns")
 	}
 	if self.screen_size.rows < 2 {
 		return fmt.Errorf("Screen too short, need at least 2 rows")
 	}
-	self.logical_lines, err = render(self.collection, self.diff_map, self.screen_size, self.largest_line_number, self.images_resized_to)
+	sz := self.screen_size
+	if conf.Show_minimap {
+		sz.columns--
+	}
+	self.logical_lines, err = render(self.collection, self.diff_map, sz, self.largest_line_number, self.images_resized_to)
 	if err != nil {
 		return err
 	}
@@ -359,6 +436,9 @@ func (self *Handler) draw_screen() {
 		} else {
 			is_image := ll.line_type == IMAGE_LINE
 			ll.render_screen_line(pos.screen_line, lp, self.logical_lines.margin_size, self.logical_lines.columns)
+			if self.blame.shown && pos.screen_line == 0 {
+				self.draw_blame_annotation(ll)
+			}
 			if is_image && !seen_images.Has(pos.logical_line) && pos.screen_line >= ll.image_lines_offset {
 				seen_images.Add(pos.logical_line)
 				self.draw_image_pair(ll, pos.screen_line-ll.image_lines_offset)
@@ -378,6 +458,9 @@ func (self *Handler) draw_screen() {
 			break
 		}
 	}
+	if self.minimap_shown() {
+		self.draw_minimap()
+	}
 	self.draw_status_line()
 }
 
@@ -400,6 +483,9 @@ func (self *Handler) draw_status_line() {
 			frac = int((float64(num) * 100.0) / float64(den))
 		}
 		sp := statusline_format(fmt.Sprintf("%d%%", frac))
+		if self.pending_diffs > 0 {
+			sp = statusline_format(fmt.Sprintf("diffed %d of %d files  ", self.total_diffs-self.pending_diffs, self.total_diffs)) + sp
+		}
 		var counts string
 		if self.current_search == nil {
 			counts = added_count_format(strconv.Itoa(self.added_count)) + statusline_format(`,`) + removed_count_format(strconv.Itoa(self.removed_count))
@@ -655,6 +741,10 @@ func (self *Handler) dispatch_action(name, args string) error {
 		if !self.change_context_count(new_ctx) {
 			self.lp.Beep()
 		}
+	case `toggle_blame`:
+		if self.logical_lines != nil {
+			self.toggle_blame()
+		}
 	case `start_search`:
 		if self.diff_map != nil && self.logical_lines != nil {
 			a, b, _ := strings.Cut(args, " ")
@@ -672,6 +762,9 @@ func (self *Handler) on_mouse_event(ev *loop.MouseEvent) error {
 		self.handle_wheel_event(ev.Buttons&(loop.MOUSE_WHEEL_UP) != 0)
 		return nil
 	}
+	if self.handle_minimap_mouse_event(ev) {
+		return nil
+	}
 	if ev.Event_type == loop.MOUSE_PRESS && ev.Buttons&loop.LEFT_MOUSE_BUTTON != 0 {
 		self.start_mouse_selection(ev)
 		return nil
@@ -0,0 +1,41 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package diff
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var _ = fmt.Print
+
+// A pool of GOMAXPROCS goroutines shared by the per-file diff and syntax
+// highlighting jobs, so that the two do not compete for CPUs with separate
+// pools of their own
+type worker_pool struct {
+	jobs chan func()
+}
+
+func new_worker_pool() *worker_pool {
+	n := runtime.GOMAXPROCS(0)
+	ans := &worker_pool{jobs: make(chan func(), 4*n)}
+	for i := 0; i < n; i++ {
+		go func() {
+			for job := range ans.jobs {
+				job()
+			}
+		}()
+	}
+	return ans
+}
+
+// Queue the jobs without blocking the caller
+func (self *worker_pool) submit(jobs ...func()) {
+	if len(jobs) > 0 {
+		go func() {
+			for _, job := range jobs {
+				self.jobs <- job
+			}
+		}()
+	}
+}
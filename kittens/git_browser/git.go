@@ -0,0 +1,202 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package git_browser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var _ = fmt.Print
+
+// Run git with the specified arguments in dir returning its output
+func run_git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", errors.New(msg)
+			}
+		}
+		return "", fmt.Errorf("Failed to run git %s with error: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+func repo_root(dir string) (string, error) {
+	out, err := run_git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(out)), nil
+}
+
+type StatusEntry struct {
+	// The two letter status code from git status --porcelain, the first
+	// letter is for the index and the second for the working tree
+	Code string
+	// Paths relative to the root of the repository, OrigPath is set only for
+	// renames and copies
+	Path, OrigPath string
+}
+
+func (self StatusEntry) IsUntracked() bool { return self.Code == "??" }
+func (self StatusEntry) IsStaged() bool    { return self.Code[0] != ' ' && self.Code[0] != '?' }
+func (self StatusEntry) IsConflicted() bool {
+	return strings.Contains(self.Code, "U") || self.Code == "AA" || self.Code == "DD"
+}
+
+type Status struct {
+	Branch, Upstream string
+	Ahead, Behind    int
+	Entries          []StatusEntry
+}
+
+// Parse the output of git status --porcelain=v1 --branch -z
+func ParseStatus(raw string) (ans Status, err error) {
+	records := strings.Split(raw, "\x00")
+	for i := 0; i < len(records); i++ {
+		r := records[i]
+		if r == "" {
+			continue
+		}
+		if strings.HasPrefix(r, "## ") {
+			ans.parse_branch(r[3:])
+			continue
+		}
+		if len(r) < 4 || r[2] != ' ' {
+			return ans, fmt.Errorf("Invalid status line in output from git: %#v", r)
+		}
+		e := StatusEntry{Code: r[:2], Path: r[3:]}
+		if e.Code[0] == 'R' || e.Code[0] == 'C' {
+			// the original path is the next record
+			if i+1 < len(records) {
+				i++
+				e.OrigPath = records[i]
+			}
+		}
+		ans.Entries = append(ans.Entries, e)
+	}
+	return
+}
+
+func (self *Status) parse_branch(x string) {
+	x, tracking, _ := strings.Cut(x, " [")
+	if tracking, found := strings.CutSuffix(tracking, "]"); found {
+		for _, part := range strings.Split(tracking, ", ") {
+			which, num, _ := strings.Cut(part, " ")
+			n, _ := strconv.Atoi(num)
+			switch which {
+			case "ahead":
+				self.Ahead = n
+			case "behind":
+				self.Behind = n
+			}
+		}
+	}
+	if rest, found := strings.CutPrefix(x, "No commits yet on "); found {
+		x = rest
+	}
+	self.Branch, self.Upstream, _ = strings.Cut(x, "...")
+}
+
+func get_status(root string) (Status, error) {
+	raw, err := run_git(root, "status", "--porcelain=v1", "--branch", "-z")
+	if err != nil {
+		return Status{}, err
+	}
+	return ParseStatus(raw)
+}
+
+type Commit struct {
+	Hash, ShortHash, Author, Date, Refs, Subject string
+}
+
+// A line from the output of git log --graph. Lines that only continue the
+// graph between commits have a nil Commit.
+type LogLine struct {
+	Graph  string
+	Commit *Commit
+}
+
+const log_format = "%x00%H%x00%h%x00%an%x00%ar%x00%D%x00%s"
+
+// Parse the output of git log --graph with log_format
+func ParseLog(raw string) (ans []LogLine) {
+	for _, line := range strings.Split(strings.TrimRight(raw, "\n"), "\n") {
+		graph, rest, found := strings.Cut(line, "\x00")
+		l := LogLine{Graph: strings.TrimRight(graph, " ")}
+		if found {
+			if fields := strings.Split(rest, "\x00"); len(fields) == 6 {
+				l.Commit = &Commit{Hash: fields[0], ShortHash: fields[1], Author: fields[2], Date: fields[3], Refs: fields[4], Subject: fields[5]}
+			}
+		}
+		if l.Graph != "" || l.Commit != nil {
+			ans = append(ans, l)
+		}
+	}
+	return
+}
+
+func get_log(root string, max_count int, all bool) ([]LogLine, error) {
+	args := []string{"log", "--graph", "--color=never", "--format=" + log_format}
+	if max_count > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(max_count))
+	}
+	if all {
+		args = append(args, "--all")
+	}
+	raw, err := run_git(root, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have any commits yet") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ParseLog(raw), nil
+}
+
+// The patch for a commit, in the unified diff format understood by the diff
+// kitten
+func commit_patch(root, hash string) (string, error) {
+	return run_git(root, "show", "--format=", "--patch", "--find-renames", "--no-color", "--no-ext-diff", hash)
+}
+
+// The patch for the uncommitted changes to a file
+func file_patch(root string, e StatusEntry) (string, error) {
+	if e.IsUntracked() {
+		if strings.HasSuffix(e.Path, "/") {
+			return "", fmt.Errorf("%s is an untracked directory, open it to see its contents", e.Path)
+		}
+		// git diff --no-index exits with 1 when there are differences
+		cmd := exec.Command("git", "diff", "--no-color", "--no-ext-diff", "--no-index", "--", "/dev/null", e.Path)
+		cmd.Dir = root
+		out, err := cmd.Output()
+		var ee *exec.ExitError
+		if err != nil && !(errors.As(err, &ee) && ee.ExitCode() == 1) {
+			return "", fmt.Errorf("Failed to run git diff with error: %w", err)
+		}
+		return string(out), nil
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames"}
+	if has_head, _ := run_git(root, "rev-parse", "--verify", "--quiet", "HEAD"); has_head != "" {
+		args = append(args, "HEAD")
+	} else {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--", e.Path)
+	if e.OrigPath != "" {
+		args = append(args, e.OrigPath)
+	}
+	return run_git(root, args...)
+}
@@ -0,0 +1,93 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package git_browser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestGitParseStatus(t *testing.T) {
+	raw := strings.Join([]string{"## main...origin/main [ahead 2, behind 1]", " M a.txt", "R  new name", "old name", "?? x/", "UU c", ""}, "\x00")
+	s, err := ParseStatus(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Status{Branch: "main", Upstream: "origin/main", Ahead: 2, Behind: 1, Entries: []StatusEntry{
+		{Code: " M", Path: "a.txt"}, {Code: "R ", Path: "new name", OrigPath: "old name"}, {Code: "??", Path: "x/"}, {Code: "UU", Path: "c"},
+	}}, s); diff != "" {
+		t.Fatalf("Status not parsed correctly:\n%s", diff)
+	}
+	e := s.Entries
+	if e[0].IsStaged() || !e[1].IsStaged() || !e[2].IsUntracked() || e[2].IsStaged() || !e[3].IsConflicted() {
+		t.Fatalf("Status entries not classified correctly: %v", e)
+	}
+	for raw, expected := range map[string]Status{
+		"## No commits yet on main\x00":        {Branch: "main"},
+		"## HEAD (no branch)\x00":              {Branch: "HEAD (no branch)"},
+		"## dev...up/dev [gone]\x00A  y z\x00": {Branch: "dev", Upstream: "up/dev", Entries: []StatusEntry{{Code: "A ", Path: "y z"}}},
+	} {
+		s, err := ParseStatus(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, s); diff != "" {
+			t.Fatalf("Status not parsed correctly from: %#v\n%s", raw, diff)
+		}
+	}
+	if _, err := ParseStatus("bad"); err == nil {
+		t.Fatalf("Parsing invalid status did not fail")
+	}
+}
+
+func TestGitParseLog(t *testing.T) {
+	c := func(hash, refs, subject string) string {
+		return strings.Join([]string{"", hash, hash[:2], "Author", "2 days ago", refs, subject}, "\x00")
+	}
+	raw := strings.Join([]string{
+		"*   " + c("aaaa", "HEAD -> main", "Merge branch"),
+		"|\\  ",
+		"| * " + c("bbbb", "", "Side"),
+		"* | " + c("cccc", "", "Main"),
+		"|/  ",
+		"* " + c("dddd", "tag: v1", "Root"),
+	}, "\n") + "\n"
+	lines := ParseLog(raw)
+	commit := func(hash, refs, subject string) *Commit {
+		return &Commit{Hash: hash, ShortHash: hash[:2], Author: "Author", Date: "2 days ago", Refs: refs, Subject: subject}
+	}
+	if diff := cmp.Diff([]LogLine{
+		{Graph: "*", Commit: commit("aaaa", "HEAD -> main", "Merge branch")},
+		{Graph: "|\\"},
+		{Graph: "| *", Commit: commit("bbbb", "", "Side")},
+		{Graph: "* |", Commit: commit("cccc", "", "Main")},
+		{Graph: "|/"},
+		{Graph: "*", Commit: commit("dddd", "tag: v1", "Root")},
+	}, lines); diff != "" {
+		t.Fatalf("Log not parsed correctly:\n%s", diff)
+	}
+
+	render := func(graph string) (ans string) {
+		for _, s := range graph_segments(graph) {
+			ans += s.text
+		}
+		return
+	}
+	if diff := cmp.Diff("● │ ╱╲─_", render("* | /\\-_")); diff != "" {
+		t.Fatalf("Graph not rendered correctly:\n%s", diff)
+	}
+	if s := graph_segments("| *"); s[0].style != "fg=red" || s[2].style != "fg=bright-green" {
+		t.Fatalf("Graph columns not colored correctly: %v", s)
+	}
+}
+
+func TestGitSanitizeControlCodes(t *testing.T) {
+	if diff := cmp.Diff("a░]0;title░b░c", sanitize_control_codes("a\x1b]0;title\x07b\nc")); diff != "" {
+		t.Fatalf("Control codes not sanitized:\n%s", diff)
+	}
+}
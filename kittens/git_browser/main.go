@@ -0,0 +1,34 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package git_browser
+
+import (
+	"fmt"
+	"os"
+
+	"kitty/tools/cli"
+)
+
+var _ = fmt.Print
+
+func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
+	if len(args) > 1 {
+		return 1, fmt.Errorf("Only a single repository can be browsed at a time")
+	}
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	if s, err := os.Stat(dir); err != nil || !s.IsDir() {
+		return 1, fmt.Errorf("%s is not a directory", dir)
+	}
+	root, err := repo_root(dir)
+	if err != nil {
+		return 1, err
+	}
+	return run_ui(root, opts)
+}
+
+func EntryPoint(parent *cli.Command) {
+	create_cmd(parent, main)
+}
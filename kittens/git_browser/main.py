@@ -0,0 +1,47 @@
+#!/usr/bin/env python
+# License: GPLv3 Copyright: 2023, Kovid Goyal <kovid at kovidgoyal.net>
+
+
+import sys
+from typing import List
+
+OPTIONS = r'''
+--log -l
+type=bool-set
+Start by showing the commit log rather than the status of the working tree.
+
+
+--max-count -n
+type=int
+default=1000
+The maximum number of commits to show in the log. Zero or a negative number
+means show all commits.
+
+
+--all -a
+type=bool-set
+Show the commits from all branches and tags in the log, not just the commits
+reachable from the current branch.
+'''.format
+help_text = '''\
+Browse the status and commit log of a git repository. The status shows the
+changed files, with hyperlinks to open them, and the log shows the history of
+commits with their graph. The changes to a file or made by a commit are shown
+using the diff kitten. If no directory is specified, the repository containing
+the current working directory is used.
+'''
+usage = '[path/to/repository]'
+
+
+def main(args: List[str]) -> None:
+    raise SystemExit('This should be run as kitten git_browser')
+
+
+if __name__ == '__main__':
+    main(sys.argv)
+elif __name__ == '__doc__':
+    cd = sys.cli_docs  # type: ignore
+    cd['usage'] = usage
+    cd['options'] = OPTIONS
+    cd['help_text'] = help_text
+    cd['short_desc'] = 'Browse the status and log of a git repository'
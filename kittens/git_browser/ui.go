@@ -0,0 +1,491 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package git_browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/tui"
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type View int
+
+const (
+	STATUS_VIEW View = iota
+	LOG_VIEW
+)
+
+// A piece of a row on the screen with a single style and optional hyperlink
+type segment struct {
+	text, style, url string
+}
+
+type row struct {
+	segments []segment
+	// the index of the status entry or log line this row is for, -1 for rows
+	// that cannot be selected
+	item int
+}
+
+var graph_colors = []string{"red", "green", "yellow", "blue", "magenta", "cyan"}
+
+var graph_chars = map[rune]string{'*': "●", '|': "│", '/': "╱", '\\': "╲", '-': "─"}
+
+// Render the graph drawn by git log --graph using box drawing characters,
+// coloring each column of the graph differently
+func graph_segments(graph string) (ans []segment) {
+	for i, ch := range graph {
+		if ch == ' ' {
+			ans = append(ans, segment{text: " "})
+			continue
+		}
+		text := graph_chars[ch]
+		if text == "" {
+			text = string(ch)
+		}
+		// every column of the graph is two cells wide, the lines between
+		// columns belong to the column on their left
+		color := graph_colors[(i/2)%len(graph_colors)]
+		if ch == '*' {
+			color = "bright-" + color
+		}
+		ans = append(ans, segment{text: text, style: "fg=" + color})
+	}
+	return
+}
+
+func status_code_style(e StatusEntry) string {
+	switch {
+	case e.IsConflicted():
+		return "fg=magenta bold"
+	case e.IsUntracked():
+		return "dim"
+	case e.IsStaged() && e.Code[1] != ' ':
+		return "fg=yellow"
+	case e.IsStaged():
+		return "fg=green"
+	}
+	return "fg=red"
+}
+
+type handler struct {
+	lp         *loop.Loop
+	hyperlinks *tui.Hyperlinks
+	opts       *Options
+	root       string
+
+	view         View
+	status       Status
+	log          []LogLine
+	selected     [2]int
+	top          [2]int
+	message      string
+	message_err  bool
+	rows_on_view []row
+}
+
+func (self *handler) set_message(err bool, format string, args ...any) {
+	self.message, self.message_err = fmt.Sprintf(format, args...), err
+}
+
+func (self *handler) screen_size() (width, height int) {
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return 80, 24
+	}
+	return int(sz.WidthCells), int(sz.HeightCells)
+}
+
+func (self *handler) num_rows() int {
+	_, height := self.screen_size()
+	return max(1, height-1)
+}
+
+func (self *handler) refresh() error {
+	var err error
+	if self.status, err = get_status(self.root); err != nil {
+		return err
+	}
+	if self.log, err = get_log(self.root, self.opts.MaxCount, self.opts.All); err != nil {
+		return err
+	}
+	self.rows_on_view = self.rows()
+	self.select_item(self.selected[self.view], 0)
+	return nil
+}
+
+func (self *handler) status_rows() (ans []row) {
+	s := self.status
+	header := []segment{{text: "On branch "}, {text: s.Branch, style: "bold fg=green"}}
+	if s.Upstream != "" {
+		header = append(header, segment{text: " tracking "}, segment{text: s.Upstream, style: "fg=cyan"})
+	}
+	if s.Ahead > 0 {
+		header = append(header, segment{text: fmt.Sprintf(" ↑%d", s.Ahead), style: "fg=green"})
+	}
+	if s.Behind > 0 {
+		header = append(header, segment{text: fmt.Sprintf(" ↓%d", s.Behind), style: "fg=red"})
+	}
+	ans = append(ans, row{segments: header, item: -1})
+	if len(s.Entries) == 0 {
+		ans = append(ans, row{segments: []segment{{text: "Nothing to commit, working tree clean", style: "dim"}}, item: -1})
+	}
+	for i, e := range s.Entries {
+		segs := []segment{{text: e.Code, style: status_code_style(e)}, {text: " "}}
+		if e.OrigPath != "" {
+			segs = append(segs, segment{text: e.OrigPath, url: tui.FileURL(filepath.Join(self.root, e.OrigPath), "")}, segment{text: " → "})
+		}
+		segs = append(segs, segment{text: e.Path, url: tui.FileURL(filepath.Join(self.root, e.Path), "")})
+		ans = append(ans, row{segments: segs, item: i})
+	}
+	return
+}
+
+func (self *handler) log_rows() (ans []row) {
+	if len(self.log) == 0 {
+		return []row{{segments: []segment{{text: "No commits yet", style: "dim"}}, item: -1}}
+	}
+	for i, l := range self.log {
+		segs := graph_segments(l.Graph)
+		r := row{item: -1}
+		if c := l.Commit; c != nil {
+			r.item = i
+			segs = append(segs, segment{text: " "}, segment{text: c.ShortHash, style: "fg=yellow"}, segment{text: " "})
+			if c.Refs != "" {
+				segs = append(segs, segment{text: "(" + c.Refs + ")", style: "bold fg=green"}, segment{text: " "})
+			}
+			segs = append(segs, segment{text: c.Subject}, segment{text: " " + c.Author + ", " + c.Date, style: "dim"})
+		}
+		r.segments = segs
+		ans = append(ans, r)
+	}
+	return
+}
+
+func (self *handler) rows() []row {
+	if self.view == LOG_VIEW {
+		return self.log_rows()
+	}
+	return self.status_rows()
+}
+
+// Move the selection to the selectable row at or after idx when delta >= 0
+// or at or before it otherwise, scrolling it into view
+func (self *handler) select_item(idx, delta int) {
+	rows := self.rows_on_view
+	if len(rows) == 0 {
+		return
+	}
+	idx = max(0, min(idx, len(rows)-1))
+	step := 1
+	if delta < 0 {
+		step = -1
+	}
+	found := -1
+	for i := idx; i >= 0 && i < len(rows); i += step {
+		if rows[i].item > -1 {
+			found = i
+			break
+		}
+	}
+	if found < 0 {
+		// nothing selectable in that direction, try the other one
+		for i := idx; i >= 0 && i < len(rows); i -= step {
+			if rows[i].item > -1 {
+				found = i
+				break
+			}
+		}
+	}
+	if found < 0 {
+		found = idx
+	}
+	self.selected[self.view] = found
+	num_rows := self.num_rows()
+	top := self.top[self.view]
+	if found < top {
+		top = found
+	} else if found >= top+num_rows {
+		top = found - num_rows + 1
+	}
+	if top > 0 && found < num_rows && rows[0].item < 0 {
+		// keep the header visible when possible
+		top = 0
+	}
+	self.top[self.view] = max(0, min(top, len(rows)-1))
+}
+
+func (self *handler) move_selection(delta int) {
+	self.select_item(self.selected[self.view]+delta, delta)
+}
+
+func (self *handler) switch_view(v View) {
+	if self.view != v {
+		self.view = v
+		self.rows_on_view = self.rows()
+		self.select_item(self.selected[v], 0)
+	}
+}
+
+func (self *handler) selected_entry() *StatusEntry {
+	if self.view != STATUS_VIEW || len(self.rows_on_view) == 0 {
+		return nil
+	}
+	if item := self.rows_on_view[self.selected[STATUS_VIEW]].item; item > -1 {
+		return &self.status.Entries[item]
+	}
+	return nil
+}
+
+func (self *handler) selected_commit() *Commit {
+	if self.view != LOG_VIEW || len(self.rows_on_view) == 0 {
+		return nil
+	}
+	if item := self.rows_on_view[self.selected[LOG_VIEW]].item; item > -1 {
+		return self.log[item].Commit
+	}
+	return nil
+}
+
+func (self *handler) open_entry(e *StatusEntry) {
+	path := filepath.Join(self.root, e.Path)
+	if err := utils.OpenWithRules(path); err != nil {
+		self.set_message(true, "Failed to open %s with error: %s", e.Path, err)
+	}
+}
+
+// Show the patch in the diff kitten, running it in place of this kitten
+// until it quits
+func (self *handler) show_patch(name, patch string) {
+	if strings.TrimSpace(patch) == "" {
+		self.set_message(false, "No changes to show for %s", name)
+		return
+	}
+	f, err := os.CreateTemp("", "kitty-git-*.patch")
+	if err != nil {
+		self.set_message(true, "Failed to create temporary file with error: %s", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(patch)
+	f.Close()
+	if err != nil {
+		self.set_message(true, "Failed to write to temporary file with error: %s", err)
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "kitten"
+	}
+	if _, err = self.lp.RunCommand([]string{exe, "diff", f.Name()}, loop.RunCommandOptions{Dir: self.root}); err != nil {
+		self.set_message(true, "Failed to run the diff kitten with error: %s", err)
+	}
+	self.lp.SetWindowTitle(self.title())
+}
+
+func (self *handler) show_diff() {
+	if e := self.selected_entry(); e != nil {
+		patch, err := file_patch(self.root, *e)
+		if err != nil {
+			self.set_message(true, "%s", err)
+			return
+		}
+		self.show_patch(e.Path, patch)
+	} else if c := self.selected_commit(); c != nil {
+		patch, err := commit_patch(self.root, c.Hash)
+		if err != nil {
+			self.set_message(true, "%s", err)
+			return
+		}
+		self.show_patch(c.ShortHash, patch)
+	}
+}
+
+func (self *handler) activate() {
+	if e := self.selected_entry(); e != nil {
+		self.open_entry(e)
+	} else {
+		self.show_diff()
+	}
+}
+
+func (self *handler) title() string {
+	return "git: " + filepath.Base(self.root)
+}
+
+// Remove all control codes, as file names, commit messages, etc. can contain
+// anything, including escape codes that would be interpreted by the terminal
+func sanitize_control_codes(x string) string {
+	pat := utils.MustCompile("[\x00-\x1f\x7f\u0080-\u009f]")
+	return pat.ReplaceAllLiteralString(x, "░")
+}
+
+// Render the segments truncated to width, padded to fill width when
+// highlighted
+func (self *handler) render_row(r row, width int, highlighted bool) string {
+	b := strings.Builder{}
+	for _, s := range r.segments {
+		if width <= 0 {
+			break
+		}
+		text, w := wcswidth.TruncateToVisualLengthWithWidth(sanitize_control_codes(s.text), width)
+		width -= w
+		sty := s.style
+		if highlighted {
+			sty = strings.TrimSpace(sty + " reverse")
+		}
+		if sty != "" {
+			text = self.lp.SprintStyled(sty, text)
+		}
+		b.WriteString(self.hyperlinks.Wrap(s.url, text))
+	}
+	if highlighted && width > 0 {
+		b.WriteString(self.lp.SprintStyled("reverse", strings.Repeat(" ", width)))
+	}
+	return b.String()
+}
+
+func (self *handler) draw_screen() error {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	self.lp.ClearMouseRegions()
+	width, height := self.screen_size()
+	num_rows := self.num_rows()
+	rows, top := self.rows_on_view, self.top[self.view]
+	for y := 0; y < num_rows && top+y < len(rows); y++ {
+		idx := top + y
+		r := rows[idx]
+		self.lp.MoveCursorTo(1, y+1)
+		self.lp.QueueWriteString(self.render_row(r, width, idx == self.selected[self.view] && r.item > -1))
+		if r.item > -1 {
+			self.lp.AddMouseRegion(loop.MouseRegion{
+				Left: 0, Top: y, Width: width, Height: 1,
+				OnClick: func(ev *loop.MouseEvent) error {
+					self.selected[self.view] = idx
+					self.activate()
+					return self.draw_screen()
+				},
+			})
+		}
+	}
+	self.lp.MoveCursorTo(1, height)
+	footer := self.message
+	if footer != "" {
+		if self.message_err {
+			footer = self.lp.SprintStyled("fg=red", wcswidth.TruncateToVisualLength(footer, width))
+		}
+	} else {
+		help := "[Tab] Log  [Enter] Open  [d] Diff  [r] Refresh  [q] Quit"
+		if self.view == LOG_VIEW {
+			help = "[Tab] Status  [Enter] Diff  [r] Refresh  [q] Quit"
+		}
+		footer = self.lp.SprintStyled("dim", wcswidth.TruncateToVisualLength(help, width))
+	}
+	self.lp.QueueWriteString(footer)
+	return nil
+}
+
+func (self *handler) on_key_event(ev *loop.KeyEvent) error {
+	ev.Handled = true
+	self.message = ""
+	num_rows := self.num_rows()
+	switch {
+	case ev.MatchesPressOrRepeat("q") || ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c"):
+		self.lp.Quit(0)
+		return nil
+	case ev.MatchesPressOrRepeat("tab") || ev.MatchesPressOrRepeat("shift+tab"):
+		self.switch_view(1 - self.view)
+	case ev.MatchesPressOrRepeat("s"):
+		self.switch_view(STATUS_VIEW)
+	case ev.MatchesPressOrRepeat("l"):
+		self.switch_view(LOG_VIEW)
+	case ev.MatchesPressOrRepeat("up") || ev.MatchesPressOrRepeat("k"):
+		self.move_selection(-1)
+	case ev.MatchesPressOrRepeat("down") || ev.MatchesPressOrRepeat("j"):
+		self.move_selection(1)
+	case ev.MatchesPressOrRepeat("page_up"):
+		self.move_selection(-num_rows)
+	case ev.MatchesPressOrRepeat("page_down"):
+		self.move_selection(num_rows)
+	case ev.MatchesPressOrRepeat("home") || ev.MatchesPressOrRepeat("g"):
+		self.select_item(0, 1)
+	case ev.MatchesPressOrRepeat("end") || ev.MatchesPressOrRepeat("shift+g"):
+		self.select_item(len(self.rows_on_view)-1, -1)
+	case ev.MatchesPressOrRepeat("enter"):
+		self.activate()
+	case ev.MatchesPressOrRepeat("d"):
+		self.show_diff()
+	case ev.MatchesPressOrRepeat("r"):
+		if err := self.refresh(); err != nil {
+			self.set_message(true, "%s", err)
+		}
+	default:
+		ev.Handled = false
+		return nil
+	}
+	return self.draw_screen()
+}
+
+func (self *handler) on_mouse_event(ev *loop.MouseEvent) error {
+	if ev.Event_type == loop.MOUSE_PRESS {
+		switch {
+		case ev.Buttons&loop.MOUSE_WHEEL_UP != 0:
+			self.move_selection(-3)
+		case ev.Buttons&loop.MOUSE_WHEEL_DOWN != 0:
+			self.move_selection(3)
+		default:
+			return nil
+		}
+		return self.draw_screen()
+	}
+	return nil
+}
+
+func run_ui(root string, opts *Options) (rc int, err error) {
+	lp, err := loop.New()
+	if err != nil {
+		return 1, err
+	}
+	h := &handler{lp: lp, hyperlinks: tui.NewHyperlinks(), opts: opts, root: root}
+	if opts.Log {
+		h.view = LOG_VIEW
+	}
+	lp.OnInitialize = func() (string, error) {
+		lp.AllowLineWrapping(false)
+		lp.SetCursorVisible(false)
+		lp.SetWindowTitle(h.title())
+		if err := h.refresh(); err != nil {
+			return "", err
+		}
+		return "", h.draw_screen()
+	}
+	lp.OnFinalize = func() string {
+		lp.SetCursorVisible(true)
+		return ""
+	}
+	lp.OnResize = func(_, _ loop.ScreenSize) error {
+		h.select_item(h.selected[h.view], 0)
+		return h.draw_screen()
+	}
+	lp.OnKeyEvent = h.on_key_event
+	lp.OnMouseEvent = h.on_mouse_event
+	if err = lp.Run(); err != nil {
+		return 1, err
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return 1, nil
+	}
+	return lp.ExitCode(), nil
+}
@@ -0,0 +1,69 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package hints
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"kitty/tools/tui/subseq"
+)
+
+var _ = fmt.Print
+
+type fuzzy_match struct {
+	mark  *Mark
+	score float64
+	// byte offsets of the matched characters into the text of the mark on screen
+	positions []int
+}
+
+// Rank marks by how well their text on screen matches query, best first,
+// dropping marks that do not match at all. Ties are broken in favor of marks
+// closer to the bottom of the screen, as that is usually the most recent
+// output.
+func rank_marks(query, text string, marks []*Mark) (ans []fuzzy_match) {
+	if query == "" || len(marks) == 0 {
+		return
+	}
+	items := make([]string, len(marks))
+	for i, m := range marks {
+		items[i] = text[m.Start:m.End]
+	}
+	for i, r := range subseq.ScoreItems(query, items, subseq.Options{}) {
+		if r.Score > 0 {
+			ans = append(ans, fuzzy_match{mark: marks[i], score: r.Score, positions: r.Positions})
+		}
+	}
+	sort.SliceStable(ans, func(i, j int) bool {
+		if ans[i].score != ans[j].score {
+			return ans[i].score > ans[j].score
+		}
+		return ans[i].mark.Start > ans[j].mark.Start
+	})
+	return
+}
+
+// Style the characters at the specified byte offsets in text with matched and
+// the rest with unmatched
+func highlight_positions(text string, positions []int, matched, unmatched func(...any) string) string {
+	b := strings.Builder{}
+	prev := 0
+	for _, p := range positions {
+		if p >= len(text) || p < prev {
+			continue
+		}
+		_, sz := utf8.DecodeRuneInString(text[p:])
+		if p > prev {
+			b.WriteString(unmatched(text[prev:p]))
+		}
+		b.WriteString(matched(text[p : p+sz]))
+		prev = p + sz
+	}
+	if prev < len(text) {
+		b.WriteString(unmatched(text[prev:]))
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package hints
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestHintsFuzzy(t *testing.T) {
+	text := "src/main.go README.md src/mark.go tests/main_test.go"
+	var marks []*Mark
+	start := 0
+	for i, x := range []string{"src/main.go", "README.md", "src/mark.go", "tests/main_test.go"} {
+		marks = append(marks, &Mark{Index: i, Start: start, End: start + len(x), Text: x})
+		start += len(x) + 1
+	}
+	r := func(query string, expected ...string) {
+		var actual []string
+		for _, m := range rank_marks(query, text, marks) {
+			actual = append(actual, m.mark.Text)
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Incorrect ranking for %#v:\n%s", query, diff)
+		}
+	}
+	r("")
+	r("readme", "README.md")
+	r("mrk", "src/mark.go")
+	r("xyz")
+	// ties are broken in favor of later marks
+	r("src", "src/mark.go", "src/main.go")
+
+	ranked := rank_marks("mg", text, marks)
+	wrap := func(a ...any) string { return "[" + fmt.Sprint(a...) + "]" }
+	plain := func(a ...any) string { return fmt.Sprint(a...) }
+	if ranked[0].mark.Text != "src/main.go" && ranked[0].mark.Text != "src/mark.go" {
+		t.Fatalf("Unexpected best match for mg: %s", ranked[0].mark.Text)
+	}
+	if diff := cmp.Diff("src/[m]ain.[g]o", highlight_positions("src/main.go", []int{4, 9}, wrap, plain)); diff != "" {
+		t.Fatalf("Incorrect highlighting:\n%s", diff)
+	}
+}
@@ -160,6 +160,18 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 		}
 	}
 	chosen := []*Mark{}
+	// when hinting over the scrollback, only a screenful of lines is shown at a time
+	var pg pager
+	var rendered_lines []string
+	var line_of_mark map[int]int
+	if o.Scrollback {
+		line_of_mark = mark_lines(text, all_marks)
+	}
+	// in fuzzy mode the typed text is matched against the text of the marks
+	// instead of against the hints
+	fuzzy_query, fuzzy_current := "", 0
+	var ranked []fuzzy_match
+	fuzzy_positions := make(map[int][]int)
 	lp, err := loop.New(loop.NoAlternateScreen) // no alternate screen reduces flicker on exit
 	if err != nil {
 		return
@@ -170,6 +182,19 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 	text_style := fctx.SprintFunc(fmt.Sprintf("fg=bright-%s bold", o.HintsTextColor))
 
 	highlight_mark := func(m *Mark, mark_text string) string {
+		if o.Fuzzy {
+			positions, found := fuzzy_positions[m.Index]
+			switch {
+			case fuzzy_query == "":
+				return text_style(mark_text)
+			case !found:
+				return faint(mark_text)
+			case m == ranked[fuzzy_current].mark:
+				return hint_style(mark_text)
+			default:
+				return highlight_positions(mark_text, positions, hint_style, text_style)
+			}
+		}
 		hint := encode_hint(m.Index, alphabet)
 		if current_input != "" && !strings.HasPrefix(hint, current_input) {
 			return faint(mark_text)
@@ -196,19 +221,78 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 		return strings.TrimRightFunc(strings.NewReplacer("\r", "\r\n", "\n", "\r\n").Replace(ans), unicode.IsSpace)
 	}
 
+	fuzzy_status := func() string {
+		ans := text_style("> ") + fuzzy_query
+		if fuzzy_query == "" {
+			ans += faint("  Type some characters from the text to select")
+		} else {
+			ans += faint(fmt.Sprintf("  %d matches, Tab for the next match", len(ranked)))
+		}
+		return ans
+	}
+
+	draw_page := func() {
+		sz, _ := lp.ScreenSize()
+		height := int(sz.HeightCells)
+		pg.set_page_size(height - 1)
+		lp.QueueWriteString(strings.Join(pg.visible(rendered_lines), "\r\n"))
+		lp.MoveCursorTo(1, height)
+		last := min(pg.top+pg.page_size, pg.num_lines)
+		status := faint(fmt.Sprintf("Lines %d-%d of %d, scroll with PgUp, PgDn, Up, Down, Home and End", pg.top+1, last, pg.num_lines))
+		if o.Fuzzy {
+			status = fuzzy_status() + "  " + status
+		}
+		lp.QueueWriteString(status)
+	}
+
 	draw_screen := func() {
 		lp.StartAtomicUpdate()
 		defer lp.EndAtomicUpdate()
 		if current_text == "" {
 			current_text = render()
+			if o.Scrollback {
+				first_render := rendered_lines == nil
+				rendered_lines = strings.Split(current_text, "\r\n")
+				pg.num_lines = len(rendered_lines)
+				if first_render {
+					// start with the screen, which is at the bottom of the scrollback
+					pg.scroll_to_end()
+				}
+			}
 		}
 		lp.ClearScreen()
-		lp.QueueWriteString(current_text)
+		if o.Scrollback {
+			draw_page()
+		} else {
+			lp.QueueWriteString(current_text)
+			if o.Fuzzy {
+				sz, _ := lp.ScreenSize()
+				lp.MoveCursorTo(1, int(sz.HeightCells))
+				lp.ClearToEndOfLine()
+				lp.QueueWriteString(fuzzy_status())
+			}
+		}
 	}
 	reset := func() {
 		current_input = ""
 		current_text = ""
 	}
+	update_fuzzy := func() {
+		candidates := make([]*Mark, 0, len(all_marks))
+		for i := range all_marks {
+			m := &all_marks[i]
+			// only visible marks are candidates
+			if !ignore_mark_indices.Has(m.Index) && (!o.Scrollback || pg.is_visible(line_of_mark[m.Index])) {
+				candidates = append(candidates, m)
+			}
+		}
+		ranked, fuzzy_current = rank_marks(fuzzy_query, text, candidates), 0
+		fuzzy_positions = make(map[int][]int, len(ranked))
+		for _, r := range ranked {
+			fuzzy_positions[r.mark.Index] = r.positions
+		}
+		current_text = ""
+	}
 
 	lp.OnInitialize = func() (string, error) {
 		lp.SendOverlayReady()
@@ -227,6 +311,12 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 		return nil
 	}
 	lp.OnText = func(text string, _, _ bool) error {
+		if o.Fuzzy {
+			fuzzy_query += text
+			update_fuzzy()
+			draw_screen()
+			return nil
+		}
 		changed := false
 		for _, ch := range text {
 			if strings.ContainsRune(alphabet, ch) {
@@ -241,6 +331,20 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 					matches = append(matches, m)
 				}
 			}
+			if o.Scrollback && len(matches) > 1 {
+				// show the matches closest to the bottom if none are visible
+				visible, last := false, -1
+				for _, m := range matches {
+					l := line_of_mark[m.Index]
+					if visible = pg.is_visible(l); visible {
+						break
+					}
+					last = max(last, l)
+				}
+				if !visible {
+					pg.ensure_visible(last)
+				}
+			}
 			if len(matches) == 1 {
 				chosen = append(chosen, matches[0])
 				if o.Multiple {
@@ -257,7 +361,72 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 		return nil
 	}
 
+	scroll_by := func(ev *loop.KeyEvent) bool {
+		switch {
+		case ev.MatchesPressOrRepeat("page_up"):
+			pg.scroll(-pg.page_size)
+		case ev.MatchesPressOrRepeat("page_down"):
+			pg.scroll(pg.page_size)
+		case ev.MatchesPressOrRepeat("up"):
+			pg.scroll(-1)
+		case ev.MatchesPressOrRepeat("down"):
+			pg.scroll(1)
+		case ev.MatchesPressOrRepeat("home"):
+			pg.scroll(-pg.num_lines)
+		case ev.MatchesPressOrRepeat("end"):
+			pg.scroll_to_end()
+		default:
+			return false
+		}
+		return true
+	}
+
+	on_fuzzy_key_event := func(ev *loop.KeyEvent) {
+		ev.Handled = true
+		switch {
+		case ev.MatchesPressOrRepeat("backspace"):
+			if r := []rune(fuzzy_query); len(r) > 0 {
+				fuzzy_query = string(r[:len(r)-1])
+				update_fuzzy()
+			}
+		case ev.MatchesPressOrRepeat("tab") || ev.MatchesPressOrRepeat("ctrl+n"):
+			if len(ranked) > 0 {
+				fuzzy_current = (fuzzy_current + 1) % len(ranked)
+				current_text = ""
+			}
+		case ev.MatchesPressOrRepeat("shift+tab") || ev.MatchesPressOrRepeat("ctrl+p"):
+			if len(ranked) > 0 {
+				fuzzy_current = (fuzzy_current - 1 + len(ranked)) % len(ranked)
+				current_text = ""
+			}
+		case ev.MatchesPressOrRepeat("enter") || ev.MatchesPressOrRepeat("space"):
+			if len(ranked) == 0 {
+				return
+			}
+			m := ranked[fuzzy_current].mark
+			chosen = append(chosen, m)
+			if !o.Multiple {
+				lp.Quit(0)
+				return
+			}
+			ignore_mark_indices.Add(m.Index)
+			fuzzy_query = ""
+			update_fuzzy()
+		case o.Scrollback && scroll_by(ev):
+			update_fuzzy()
+		default:
+			ev.Handled = false
+			return
+		}
+		draw_screen()
+	}
+
 	lp.OnKeyEvent = func(ev *loop.KeyEvent) error {
+		if o.Fuzzy {
+			if on_fuzzy_key_event(ev); ev.Handled {
+				return nil
+			}
+		}
 		if ev.MatchesPressOrRepeat("backspace") {
 			ev.Handled = true
 			r := []rune(current_input)
@@ -286,6 +455,9 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 					draw_screen()
 				}
 			}
+		} else if o.Scrollback && scroll_by(ev) {
+			ev.Handled = true
+			draw_screen()
 		} else if ev.MatchesPressOrRepeat("esc") {
 			if o.Multiple {
 				lp.Quit(0)
@@ -315,6 +487,15 @@ func main(_ *cli.Command, o *Options, args []string) (rc int, err error) {
 		result.Match[i] = m.Text + match_suffix
 		result.Groupdicts[i] = m.Groupdict
 	}
+	if !tui.RunningAsUI() && len(o.Program) == 1 && o.Program[0] == "default" && o.Type != "linenum" {
+		// not running inside kitty so open the matches ourselves
+		for _, m := range chosen {
+			if err = utils.OpenWithRules(m.Text); err != nil {
+				return 1, err
+			}
+		}
+		return
+	}
 	fmt.Println(output(result))
 	return
 }
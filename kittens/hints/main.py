@@ -92,6 +92,8 @@ def custom_marking() -> None:
 :code:`default`
     run the default open program. Note that when using the hyperlink :code:`--type`
     the default is to use the kitty :doc:`hyperlink handling </open_actions>` facilities.
+    When the kitten is run outside kitty, the rules from :ref:`open-with.conf <open_with>`
+    are used instead.
 
 :code:`launch`
     run :doc:`/launch` to open the program in a new kitty tab, window, overlay, etc.
@@ -197,6 +199,25 @@ def custom_marking() -> None:
 second character by default.
 
 
+--scrollback
+type=bool-set
+Find matches in the entire scrollback, not just the screen. One screenful of
+lines is shown at a time, use the :kbd:`PgUp`, :kbd:`PgDn`, :kbd:`Up`,
+:kbd:`Down`, :kbd:`Home` and :kbd:`End` keys to scroll. Hints are numbered over
+the entire scrollback, so they do not change when scrolling. Typing the start of
+a hint scrolls to the matching hints if none of them are visible.
+
+
+--fuzzy
+type=bool-set
+Select matches by typing some of the characters in them, instead of by typing
+hints. The visible matches are ranked by how well they match the typed
+characters, with the matched characters highlighted, and pressing :kbd:`Enter`
+selects the best match. Use :kbd:`Tab` and :kbd:`Shift+Tab` to select the next
+and previous matches. Faster than hints for picking out a specific word or
+filename from dense output, for example, with :code:`--type=path`.
+
+
 --ascending
 type=bool-set
 Make the hints increase from top to bottom, instead of decreasing from top to
@@ -294,7 +315,15 @@ def is_copy_action(s: str) -> bool:
             }[action])(*cmd)
 
 
-@result_handler(type_of_input='screen-ansi', has_ready_notification=True)
+def type_of_input(args: Sequence[str]) -> str:
+    try:
+        opts = parse_hints_args(list(args))[0]
+    except SystemExit:
+        return 'screen-ansi'
+    return 'screen-ansi-history' if opts.scrollback else 'screen-ansi'
+
+
+@result_handler(type_of_input=type_of_input, has_ready_notification=True)
 def handle_result(args: List[str], data: Dict[str, Any], target_window_id: int, boss: BossType) -> None:
     cp = data['customize_processing']
     if data['type'] == 'linenum':
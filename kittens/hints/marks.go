@@ -23,6 +23,7 @@ import (
 	"kitty"
 	"kitty/tools/config"
 	"kitty/tools/utils"
+	"kitty/tools/utils/style"
 )
 
 var _ = fmt.Print
@@ -51,47 +52,23 @@ type Mark struct {
 }
 
 func process_escape_codes(text string) (ans string, hyperlinks []Mark) {
-	removed_size, idx := 0, 0
-	active_hyperlink_url := ""
-	active_hyperlink_id := ""
-	active_hyperlink_start_offset := 0
-
-	add_hyperlink := func(end int) {
-		hyperlinks = append(hyperlinks, Mark{
-			Index: idx, Start: active_hyperlink_start_offset, End: end, Text: active_hyperlink_url, Is_hyperlink: true, Group_id: active_hyperlink_id})
-		active_hyperlink_url, active_hyperlink_id = "", ""
-		active_hyperlink_start_offset = 0
-		idx++
-	}
-
-	ans = utils.ReplaceAll(utils.MustCompile("\x1b(?:\\[[0-9;:]*?m|\\].*?\x1b\\\\)"), text, func(raw string, groupdict map[string]utils.SubMatch) string {
-		if !strings.HasPrefix(raw, "\x1b]8") {
-			removed_size += len(raw)
-			return ""
-		}
-		start := groupdict[""].Start - removed_size
-		removed_size += len(raw)
-		if active_hyperlink_url != "" {
-			add_hyperlink(start)
+	b := strings.Builder{}
+	b.Grow(len(text))
+	for _, s := range style.ParseANSI(text) {
+		start := b.Len()
+		b.WriteString(s.Text)
+		if s.URL == "" {
+			continue
 		}
-		raw = raw[4 : len(raw)-2]
-		if metadata, url, found := strings.Cut(raw, ";"); found && url != "" {
-			active_hyperlink_url = url
-			active_hyperlink_start_offset = start
-			if metadata != "" {
-				for _, entry := range strings.Split(metadata, ":") {
-					if strings.HasPrefix(entry, "id=") && len(entry) > 3 {
-						active_hyperlink_id = entry[3:]
-					}
-				}
-			}
+		// a hyperlink split into multiple spans by changes in formatting
+		if n := len(hyperlinks); n > 0 && hyperlinks[n-1].End == start && hyperlinks[n-1].Text == s.URL && hyperlinks[n-1].Group_id == s.HyperlinkID {
+			hyperlinks[n-1].End = b.Len()
+			continue
 		}
-		return ""
-	})
-	if active_hyperlink_url != "" {
-		add_hyperlink(len(ans))
+		hyperlinks = append(hyperlinks, Mark{
+			Index: len(hyperlinks), Start: start, End: b.Len(), Text: s.URL, Is_hyperlink: true, Group_id: s.HyperlinkID})
 	}
-	return
+	return b.String(), hyperlinks
 }
 
 type PostProcessorFunc = func(string, int, int) (int, int)
@@ -209,7 +186,7 @@ type KittyOpts struct {
 	Select_by_word_characters string
 }
 
-func read_relevant_kitty_opts(path string) KittyOpts {
+func read_relevant_kitty_opts(path, cache_dir string) KittyOpts {
 	ans := KittyOpts{Select_by_word_characters: kitty.KittyConfigDefaults.Select_by_word_characters}
 	handle_line := func(key, val string) error {
 		switch key {
@@ -221,7 +198,7 @@ func read_relevant_kitty_opts(path string) KittyOpts {
 		return nil
 	}
 	cp := config.ConfigParser{LineHandler: handle_line}
-	cp.ParseFiles(path)
+	cp.ParseFileWithCache(path, cache_dir)
 	if ans.Url_prefixes == nil {
 		ans.Url_prefixes = utils.NewSetWithItems(kitty.KittyConfigDefaults.Url_prefixes...)
 	}
@@ -229,7 +206,7 @@ func read_relevant_kitty_opts(path string) KittyOpts {
 }
 
 var RelevantKittyOpts = sync.OnceValue(func() KittyOpts {
-	return read_relevant_kitty_opts(filepath.Join(utils.ConfigDir(), "kitty.conf"))
+	return read_relevant_kitty_opts(filepath.Join(utils.ConfigDir(), "kitty.conf"), config.ParsedConfigCacheDir())
 })
 
 func functions_for(opts *Options) (pattern string, post_processors []PostProcessorFunc, group_processors []GroupProcessorFunc) {
@@ -141,3 +141,17 @@ def mark(text, args, Mark, extra_cli_args, *a):
 	os.WriteFile(simple, []byte(""), 0o600)
 	r("a b", `b`)
 }
+
+func TestHintsProcessEscapeCodes(t *testing.T) {
+	text, hyperlinks := process_escape_codes("a\x1b]8;id=1;http://x.com\x1b\\b\x1b[1mc\x1b]8;;\x1b\\\x1b[22md\x1b]8;;http://y.com\x1b\\e\x1b]8;;\x1b\\")
+	if text != "abcde" {
+		t.Fatalf("Escape codes not removed: %#v", text)
+	}
+	expected := []Mark{
+		{Index: 0, Start: 1, End: 3, Text: "http://x.com", Is_hyperlink: true, Group_id: "1"},
+		{Index: 1, Start: 4, End: 5, Text: "http://y.com", Is_hyperlink: true},
+	}
+	if diff := cmp.Diff(expected, hyperlinks); diff != "" {
+		t.Fatalf("Hyperlinks not correct:\n%s", diff)
+	}
+}
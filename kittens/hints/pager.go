@@ -0,0 +1,77 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package hints
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Print
+
+// Shows one screenful at a time of the lines of text being hinted, used when
+// hinting over the scrollback
+type pager struct {
+	num_lines, page_size int
+	// the first line shown on screen
+	top int
+}
+
+func (self *pager) clamp() {
+	self.top = max(0, min(self.top, self.num_lines-self.page_size))
+}
+
+func (self *pager) set_page_size(page_size int) {
+	at_end := self.top >= self.num_lines-self.page_size
+	self.page_size = max(1, page_size)
+	if at_end {
+		self.scroll_to_end()
+	}
+	self.clamp()
+}
+
+func (self *pager) scroll(delta int) {
+	self.top += delta
+	self.clamp()
+}
+
+func (self *pager) scroll_to_end() {
+	self.top = self.num_lines
+	self.clamp()
+}
+
+func (self *pager) is_visible(line int) bool {
+	return self.top <= line && line < self.top+self.page_size
+}
+
+// Scroll so that line is visible, leaving the view unchanged if it already is
+func (self *pager) ensure_visible(line int) {
+	if !self.is_visible(line) {
+		self.top = line - self.page_size/2
+		self.clamp()
+	}
+}
+
+// The lines currently visible
+func (self *pager) visible(lines []string) []string {
+	return lines[min(self.top, len(lines)):min(self.top+self.page_size, len(lines))]
+}
+
+// The line the start of each mark is on, as a map from mark index to line
+// number. Lines are terminated by either a newline or, for wrapped lines, a
+// carriage return.
+func mark_lines(text string, marks []Mark) map[int]int {
+	ans := make(map[int]int, len(marks))
+	pos, line := 0, 0
+	for _, m := range marks {
+		if m.Start < pos {
+			pos, line = 0, 0
+		}
+		for ; pos < m.Start && pos < len(text); pos++ {
+			if text[pos] == '\n' || text[pos] == '\r' {
+				line++
+			}
+		}
+		ans[m.Index] = line
+	}
+	return ans
+}
@@ -0,0 +1,54 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package hints
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestHintsPager(t *testing.T) {
+	lines := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	p := pager{num_lines: len(lines)}
+	p.set_page_size(4)
+	p.scroll_to_end()
+	if diff := cmp.Diff([]string{"6", "7", "8", "9"}, p.visible(lines)); diff != "" {
+		t.Fatalf("Incorrect lines at end:\n%s", diff)
+	}
+	p.scroll(-4)
+	if p.top != 2 {
+		t.Fatalf("Incorrect top after scrolling up: %d", p.top)
+	}
+	p.scroll(-100)
+	if p.top != 0 {
+		t.Fatalf("Scrolled past the start: %d", p.top)
+	}
+	p.ensure_visible(2)
+	if p.top != 0 {
+		t.Fatalf("Scrolled when the line was already visible: %d", p.top)
+	}
+	p.ensure_visible(8)
+	if !p.is_visible(8) {
+		t.Fatalf("Line not visible after ensure_visible(), top: %d", p.top)
+	}
+	p.scroll_to_end()
+	p.set_page_size(6)
+	if diff := cmp.Diff([]string{"4", "5", "6", "7", "8", "9"}, p.visible(lines)); diff != "" {
+		t.Fatalf("Did not stay at the end when resized:\n%s", diff)
+	}
+	p = pager{num_lines: 2}
+	p.set_page_size(5)
+	if diff := cmp.Diff([]string{"0", "1"}, p.visible(lines[:2])); diff != "" {
+		t.Fatalf("Incorrect lines when the page is larger than the text:\n%s", diff)
+	}
+
+	text := "a\rb\nc d\n\ne"
+	marks := []Mark{{Index: 3, Start: 0}, {Index: 2, Start: 2}, {Index: 1, Start: 6}, {Index: 0, Start: 9}}
+	if diff := cmp.Diff(map[int]int{3: 0, 2: 1, 1: 2, 0: 4}, mark_lines(text, marks)); diff != "" {
+		t.Fatalf("Incorrect mark lines:\n%s", diff)
+	}
+}
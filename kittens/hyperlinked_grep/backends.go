@@ -0,0 +1,220 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package hyperlinked_grep
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"kitty/tools/tty"
+	"kitty/tools/utils"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type grep_backend struct {
+	name, exe string
+	// long rg options translated to the equivalent option of this backend,
+	// an empty value means the option is dropped
+	translations map[string]string
+	options      func() (expecting_args map[string]bool, alias_map map[string]string, err error)
+}
+
+var UgrepExe = sync.OnceValue(func() string {
+	return utils.FindExe("ugrep")
+})
+
+var GrepExe = sync.OnceValue(func() string {
+	return utils.FindExe("grep")
+})
+
+// Options that control the output format of rg, that are always dropped as
+// the grep backends are run with a fixed output format
+var common_grep_translations = map[string]string{
+	"pretty": "", "heading": "", "no-heading": "", "no-line-number": "",
+	"context-separator": "group-separator", "no-context-separator": "no-group-separator",
+}
+
+func new_grep_backend(name string) *grep_backend {
+	ans := &grep_backend{name: name, translations: make(map[string]string, 16)}
+	for k, v := range common_grep_translations {
+		ans.translations[k] = v
+	}
+	switch name {
+	case "rg":
+		ans.exe = RgExe()
+		ans.translations = nil
+		ans.options = get_options_for_rg
+		return ans
+	case "ugrep":
+		ans.exe = UgrepExe()
+	case "grep":
+		ans.exe = GrepExe()
+		for k, v := range map[string]string{"glob": "include", "hidden": "", "no-ignore": "", "smart-case": "", "vimgrep": ""} {
+			ans.translations[k] = v
+		}
+	}
+	ans.options = func() (map[string]bool, map[string]string, error) {
+		return get_options_for_grep(ans.exe, ans.name)
+	}
+	return ans
+}
+
+func (self *grep_backend) is_rg() bool { return self.name == "rg" }
+
+// Use the backend requested via --kitten backend=name, defaulting to the
+// first of rg, ugrep and grep that is installed
+func find_backend(args []string) (*grep_backend, error) {
+	requested := "auto"
+	for i, x := range args {
+		if x == "--" {
+			break
+		}
+		val := ""
+		if x == "--kitten" && i+1 < len(args) {
+			val = args[i+1]
+		} else if q, found := strings.CutPrefix(x, "--kitten="); found {
+			val = q
+		}
+		if q, found := strings.CutPrefix(val, "backend="); found {
+			requested = q
+		}
+	}
+	switch requested {
+	case "rg", "ugrep", "grep":
+		return new_grep_backend(requested), nil
+	case "auto":
+		for _, x := range []struct {
+			name string
+			exe  func() string
+		}{{"rg", RgExe}, {"ugrep", UgrepExe}} {
+			if filepath.IsAbs(x.exe()) {
+				return new_grep_backend(x.name), nil
+			}
+		}
+		return new_grep_backend("grep"), nil
+	}
+	return nil, fmt.Errorf("Unknown grep backend: %s", requested)
+}
+
+// Parse the options from the --help output of GNU grep and ugrep which have
+// lines of the form:
+//
+//	-m, --max-count=NUM       stop after NUM selected lines
+//	-A NUM, --after-context=NUM
+func get_options_for_grep(exe, name string) (expecting_args map[string]bool, alias_map map[string]string, err error) {
+	raw, err := exec.Command(exe, "--help").Output()
+	if err != nil {
+		err = fmt.Errorf("Failed to execute %s: %w", name, err)
+		return
+	}
+	expecting_args = make(map[string]bool, 128)
+	alias_map = make(map[string]string, 64)
+	scanner := utils.NewLineScanner(utils.UnsafeBytesToString(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "-") {
+			continue
+		}
+		spec, _, _ := strings.Cut(line, "  ")
+		short_names := make([]string, 0, 1)
+		long_name, expecting_arg := "", false
+		for _, x := range strings.Split(spec, ", ") {
+			x = strings.TrimSpace(x)
+			if q, found := strings.CutPrefix(x, "--"); found {
+				n, _, _ := strings.Cut(q, "[")
+				n, _, has_arg := strings.Cut(n, "=")
+				if long_name == "" {
+					long_name = n
+				} else {
+					alias_map[n] = long_name
+				}
+				expecting_arg = expecting_arg || has_arg
+			} else if q, found := strings.CutPrefix(x, "-"); found && q != "" {
+				n, _, has_arg := strings.Cut(q, " ")
+				short_names = append(short_names, n)
+				expecting_arg = expecting_arg || has_arg
+			}
+		}
+		if long_name == "" {
+			continue
+		}
+		for _, x := range short_names {
+			alias_map[x] = long_name
+		}
+		expecting_args[long_name] = expecting_arg
+	}
+	return
+}
+
+// The command line used to run a grep backend so that its output can be parsed
+func (self *grep_backend) command_line(kitten_opts *kitten_options, args []string) []string {
+	ans := make([]string, 0, len(args)+4)
+	ans = append(ans, "--color=always", "--with-filename")
+	if kitten_opts.line_number {
+		ans = append(ans, "--line-number")
+	}
+	// behave like rg and search the current directory unless input is piped
+	if tty.IsTerminal(os.Stdin.Fd()) {
+		if files := self.files_to_search(kitten_opts, args); files != nil {
+			ans = append(ans, args...)
+			if !slices.Contains(args, "--") {
+				ans = append(ans, "--")
+			}
+			return append(ans, files...)
+		}
+		ans = append(ans, "--recursive")
+	}
+	return append(ans, args...)
+}
+
+// GNU grep does not support ignore files, so when searching the current
+// directory, list the files to search, skipping ignored and hidden files like
+// rg does. Returns nil if grep should search recursively itself, which
+// is also done when there are too many files to pass on the command line,
+// in which case the walk is stopped early, so huge trees are not walked.
+func (self *grep_backend) files_to_search(kitten_opts *kitten_options, args []string) (ans []string) {
+	num_paths := kitten_opts.num_operands
+	if !kitten_opts.pattern_given {
+		num_paths--
+	}
+	if self.name != "grep" || kitten_opts.no_ignore || num_paths > 0 {
+		return nil
+	}
+	const max_size = 128 * 1024
+	// every file needs at least two bytes on the command line
+	entries, err := utils.WalkFiltered(".", utils.WalkOptions{RespectIgnoreFiles: true, SkipHidden: !kitten_opts.hidden, MaxEntries: max_size / 2})
+	if err != nil {
+		return nil
+	}
+	size := 0
+	ans = make([]string, 0, len(entries)+1)
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			if size += len(e.Path) + 1; size > max_size {
+				return nil
+			}
+			ans = append(ans, e.Path)
+		}
+	}
+	if len(ans) == 0 {
+		// prevent grep from reading from STDIN
+		ans = append(ans, os.DevNull)
+	}
+	return
+}
+
+// Have GNU grep not emit erase to end of line escape codes after colored text
+func grep_colors_env() string {
+	val := os.Getenv("GREP_COLORS")
+	if val != "" {
+		val += ":"
+	}
+	return "GREP_COLORS=" + val + "ne"
+}
@@ -16,6 +16,7 @@ import (
 	"unicode"
 
 	"kitty/tools/cli"
+	"kitty/tools/tui"
 	"kitty/tools/utils"
 
 	"golang.org/x/sys/unix"
@@ -82,6 +83,44 @@ type kitten_options struct {
 	stats, count, count_matches                    bool
 	files, files_with_matches, files_without_match bool
 	vimgrep                                        bool
+	no_ignore, hidden                              bool
+	backend                                        *grep_backend
+	url_template                                   string
+	// the number of arguments that are not options and whether the pattern
+	// was specified using an option
+	num_operands  int
+	pattern_given bool
+}
+
+// Create the URL for a search result from the user specified template, with
+// placeholders: {path} the absolute path, {relpath} the path as output by the
+// search program, {line}, {col} and {hostname}. Paths are percent encoded. The
+// default is a file:// URL with the line number as the fragment.
+func url_for_result(url_template, path, line, col string) string {
+	if url_template == "" {
+		return tui.FileURL(path, line)
+	}
+	escape := func(p string) string {
+		return strings.Join(utils.Map(url.PathEscape, strings.Split(filepath.ToSlash(p), "/")), "/")
+	}
+	abspath := path
+	if q, err := filepath.Abs(path); err == nil {
+		abspath = q
+	}
+	if col == "" {
+		col = "1"
+	}
+	if line == "" {
+		line = "1"
+	}
+	return strings.NewReplacer(
+		"{path}", escape(abspath), "{relpath}", escape(path), "{line}", line, "{col}", col, "{hostname}", utils.Hostname(),
+	).Replace(url_template)
+}
+
+// Whether the URL template needs column numbers
+func (self *kitten_options) needs_column() bool {
+	return strings.Contains(self.url_template, "{col}")
 }
 
 func default_kitten_opts() *kitten_options {
@@ -93,12 +132,17 @@ func default_kitten_opts() *kitten_options {
 }
 
 func parse_args(args ...string) (delegate_to_rg bool, sanitized_args []string, kitten_opts *kitten_options, err error) {
-	options_that_expect_args, alias_map, err := get_options_for_rg()
+	backend, err := find_backend(args)
+	if err != nil {
+		return
+	}
+	options_that_expect_args, alias_map, err := backend.options()
 	if err != nil {
 		return
 	}
 	options_that_expect_args["kitten"] = true
 	kitten_opts = default_kitten_opts()
+	kitten_opts.backend = backend
 	sanitized_args = make([]string, 0, len(args))
 	expecting_option_arg := ""
 
@@ -119,14 +163,24 @@ func parse_args(args ...string) (delegate_to_rg bool, sanitized_args []string, k
 			if val != string(os.PathSeparator) {
 				delegate_to_rg = true
 			}
-		case "context-separator":
+		case "context-separator", "group-separator":
 			context_separator = val
 		case "field-context-separator":
 			field_context_separator = val
 		case "field-match-separator":
 			field_match_separator = val
+		case "regexp", "file":
+			kitten_opts.pattern_given = true
 		case "kitten":
 			k, v, found := strings.Cut(val, "=")
+			if found && k == "backend" {
+				// already handled by find_backend()
+				return nil
+			}
+			if found && k == "url" {
+				kitten_opts.url_template = v
+				return nil
+			}
 			if !found || k != "hyperlink" {
 				return fmt.Errorf("Unknown --kitten option: %s", val)
 			}
@@ -156,7 +210,7 @@ func parse_args(args ...string) (delegate_to_rg bool, sanitized_args []string, k
 
 	handle_bool_option := func(key string) {
 		switch key {
-		case "no-context-separator":
+		case "no-context-separator", "no-group-separator":
 			context_separator = ""
 		case "no-filename":
 			kitten_opts.with_filename = false
@@ -187,6 +241,10 @@ func parse_args(args ...string) (delegate_to_rg bool, sanitized_args []string, k
 			kitten_opts.files_without_match = true
 		case "vimgrep":
 			kitten_opts.vimgrep = true
+		case "no-ignore":
+			kitten_opts.no_ignore = true
+		case "hidden":
+			kitten_opts.hidden = true
 		case "null", "null-data", "type-list", "version", "help":
 			delegate_to_rg = true
 		}
@@ -201,11 +259,23 @@ func parse_args(args ...string) (delegate_to_rg bool, sanitized_args []string, k
 		} else {
 			if x == "--" {
 				sanitized_args = append(sanitized_args, args[i:]...)
+				kitten_opts.num_operands += len(args) - i - 1
 				break
 			}
 			if strings.HasPrefix(x, "--") {
 				a, b, found := strings.Cut(x, "=")
 				a = a[2:]
+				if t, is_translated := backend.translations[a]; is_translated {
+					if t == "" {
+						handle_bool_option(a)
+						continue
+					}
+					a = t
+					x = "--" + a
+					if found {
+						x += "=" + b
+					}
+				}
 				q := alias_map[a]
 				if q != "" {
 					a = q
@@ -250,9 +320,22 @@ func parse_args(args ...string) (delegate_to_rg bool, sanitized_args []string, k
 				}
 			} else {
 				sanitized_args = append(sanitized_args, x)
+				kitten_opts.num_operands++
 			}
 		}
 	}
+	if !backend.is_rg() {
+		// grep backends are always run without headings
+		kitten_opts.heading = false
+	}
+	if kitten_opts.needs_column() && !kitten_opts.vimgrep {
+		switch backend.name {
+		case "rg":
+			sanitized_args = append([]string{"--column"}, sanitized_args...)
+		case "ugrep":
+			sanitized_args = append([]string{"--column-number"}, sanitized_args...)
+		}
+	}
 	if !kitten_opts.with_filename || context_separator != "--" || field_context_separator != "-" || field_match_separator != "-" {
 		delegate_to_rg = true
 	}
@@ -286,41 +369,48 @@ func (self *stdout_filter) Write(p []byte) (n int, err error) {
 
 func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 	delegate_to_rg, sanitized_args, kitten_opts, err := parse_args(args...)
+	if err != nil {
+		return 1, err
+	}
+	backend := kitten_opts.backend
 	if delegate_to_rg {
-		sanitized_args = append([]string{"rg"}, sanitized_args...)
-		err = unix.Exec(RgExe(), sanitized_args, os.Environ())
+		sanitized_args = append([]string{backend.name}, sanitized_args...)
+		err = unix.Exec(backend.exe, sanitized_args, os.Environ())
 		if err != nil {
-			err = fmt.Errorf("Failed to execute rg: %w", err)
+			err = fmt.Errorf("Failed to execute %s: %w", backend.name, err)
 			rc = 1
 		}
 		return
 	}
-	cmdline := append([]string{"--pretty", "--with-filename"}, sanitized_args...)
-	cmd := exec.Command(RgExe(), cmdline...)
+	var cmd *exec.Cmd
+	if backend.is_rg() {
+		cmd = exec.Command(backend.exe, append([]string{"--pretty", "--with-filename"}, sanitized_args...)...)
+	} else {
+		cmd = exec.Command(backend.exe, backend.command_line(kitten_opts, sanitized_args)...)
+		cmd.Env = append(os.Environ(), grep_colors_env())
+	}
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
 	buf := stdout_filter{prefix: make([]byte, 0, 8*1024)}
 	cmd.Stdout = &buf
-	sgr_pat := regexp.MustCompile("\x1b\\[.*?m")
+	sgr_pat := regexp.MustCompile("\x1b\\[[0-9;:]*[mK]")
 	osc_pat := regexp.MustCompile("\x1b\\].*?\x1b\\\\")
-	num_pat := regexp.MustCompile(`^(\d+)([:-])`)
+	num_pat := regexp.MustCompile(`^(\d+)([:-])(?:(\d+):)?`)
 	path_with_count_pat := regexp.MustCompile(`^(.*?)(:\d+)`)
-	path_with_linenum_pat := regexp.MustCompile(`^(.*?):(\d+):`)
+	path_with_linenum_pat := regexp.MustCompile(`^(.*?):(\d+):(?:(\d+):)?`)
 	stats_pat := regexp.MustCompile(`^\d+ matches$`)
 	vimgrep_pat := regexp.MustCompile(`^(.*?):(\d+):(\d+):`)
 
 	in_stats := false
 	in_result := ""
-	hostname := utils.Hostname()
+	links := tui.NewHyperlinks()
 
-	get_quoted_url := func(file_path string) string {
-		q, err := filepath.Abs(file_path)
-		if err == nil {
-			file_path = q
+	needs_column := kitten_opts.needs_column()
+	column := func(m []string) string {
+		if needs_column && len(m) > 3 {
+			return m[3]
 		}
-		file_path = filepath.ToSlash(file_path)
-		file_path = strings.Join(utils.Map(url.PathEscape, strings.Split(file_path, "/")), "/")
-		return "file://" + hostname + file_path
+		return ""
 	}
 
 	write := func(items ...string) {
@@ -329,12 +419,9 @@ func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 		}
 	}
 
-	write_hyperlink := func(url, line, frag string) {
-		write("\033]8;;", url)
-		if frag != "" {
-			write("#", frag)
-		}
-		write("\033\\", line, "\n\033]8;;\033\\")
+	write_hyperlink := func(path, line, linenum, col string) {
+		url := url_for_result(kitten_opts.url_template, path, linenum, col)
+		write(links.Start(url), line, "\n", links.End())
 	}
 
 	buf.process_line = func(line string) {
@@ -352,7 +439,7 @@ func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 				if len(m) > 0 {
 					is_match_line := len(m) > 1 && m[2] == ":"
 					if (is_match_line && kitten_opts.matching_lines) || (!is_match_line && kitten_opts.context_lines) {
-						write_hyperlink(in_result, line, m[1])
+						write_hyperlink(in_result, line, m[1], column(m))
 						return
 					}
 				}
@@ -365,12 +452,12 @@ func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 					in_stats = true
 				} else if kitten_opts.count || kitten_opts.count_matches {
 					if m := path_with_count_pat.FindStringSubmatch(clean_line); len(m) > 0 && kitten_opts.file_headers {
-						write_hyperlink(get_quoted_url(m[1]), line, "")
+						write_hyperlink(m[1], line, "", "")
 						return
 					}
 				} else if kitten_opts.files || kitten_opts.files_with_matches || kitten_opts.files_without_match {
 					if kitten_opts.file_headers {
-						write_hyperlink(get_quoted_url(clean_line), line, "")
+						write_hyperlink(clean_line, line, "", "")
 						return
 					}
 				} else if kitten_opts.vimgrep || !kitten_opts.heading {
@@ -382,13 +469,13 @@ func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 						m = path_with_linenum_pat.FindStringSubmatch(clean_line)
 					}
 					if len(m) > 0 && (kitten_opts.file_headers || kitten_opts.matching_lines) {
-						write_hyperlink(get_quoted_url(m[1]), line, m[2])
+						write_hyperlink(m[1], line, m[2], column(m))
 						return
 					}
 				} else {
-					in_result = get_quoted_url(clean_line)
+					in_result = clean_line
 					if kitten_opts.file_headers {
-						write_hyperlink(in_result, line, "")
+						write_hyperlink(in_result, line, "", "")
 						return
 					}
 				}
@@ -403,7 +490,7 @@ func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 		if errors.As(err, &ee) {
 			return ee.ExitCode(), nil
 		}
-		return 1, fmt.Errorf("Failed to execute rg: %w", err)
+		return 1, fmt.Errorf("Failed to execute %s: %w", backend.name, err)
 	}
 
 	return
@@ -412,7 +499,7 @@ func main(_ *cli.Command, _ *Options, args []string) (rc int, err error) {
 func specialize_command(hg *cli.Command) {
 	hg.Usage = "arguments for the rg command"
 	hg.ShortDescription = "Add hyperlinks to the output of ripgrep"
-	hg.HelpText = "The hyperlinked_grep kitten is a thin wrapper around the rg command. It automatically adds hyperlinks to the output of rg allowing the user to click on search results to have them open directly in their editor. For details on its usage, see :doc:`/kittens/hyperlinked_grep`."
+	hg.HelpText = "The hyperlinked_grep kitten is a thin wrapper around the rg command. It automatically adds hyperlinks to the output of rg allowing the user to click on search results to have them open directly in their editor. If rg is not installed, ugrep or GNU grep are used instead, use :code:`--kitten backend=grep` to choose one explicitly. For details on its usage, see :doc:`/kittens/hyperlinked_grep`."
 	hg.IgnoreAllArgs = true
 	hg.OnlyArgsAllowed = true
 	hg.ArgCompleter = cli.CompletionForWrapper("rg")
@@ -5,6 +5,8 @@ package hyperlinked_grep
 import (
 	"fmt"
 	"kitty/tools/utils/shlex"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -91,3 +93,87 @@ func TestRgArgParsing(t *testing.T) {
 	check_args("-mn 10 abcd", "-n --max-count 10 abcd")
 
 }
+
+func TestGrepArgParsing(t *testing.T) {
+	if !filepath.IsAbs(GrepExe()) {
+		t.Skip("Skipping as grep not found in PATH")
+	}
+	check_args := func(args, expected string) {
+		t.Helper()
+		a, err := shlex.Split("--kitten backend=grep " + args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, actual, kitten_opts, err := parse_args(a...)
+		if err != nil {
+			t.Fatalf("error when parsing: %#v: %s", args, err)
+		}
+		if kitten_opts.backend.name != "grep" || kitten_opts.heading {
+			t.Fatalf("Incorrect backend options for: %#v", args)
+		}
+		ex, err := shlex.Split(expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(ex, actual); diff != "" {
+			t.Fatalf("args not correct for %s\n%s", args, diff)
+		}
+	}
+	check_args("-m 10 abcd", "--max-count 10 abcd")
+	check_args("-in abcd", "-i -n abcd")
+	check_args("--glob=*.go --smart-case --pretty abcd", "--include=*.go abcd")
+	check_args("--context-separator xx abcd", "--group-separator xx abcd")
+}
+
+func TestGrepFilesToSearch(t *testing.T) {
+	if !filepath.IsAbs(GrepExe()) {
+		t.Skip("Skipping as grep not found in PATH")
+	}
+	tdir := t.TempDir()
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	if err := os.Chdir(tdir); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(".gitignore", []byte("*.o\n"), 0o600)
+	os.WriteFile("a.txt", nil, 0o600)
+	os.WriteFile("a.o", nil, 0o600)
+	os.WriteFile(".hidden", nil, 0o600)
+	files := func(args string) []string {
+		t.Helper()
+		a, err := shlex.Split("--kitten backend=grep " + args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, sanitized_args, kitten_opts, err := parse_args(a...)
+		if err != nil {
+			t.Fatalf("error when parsing: %#v: %s", args, err)
+		}
+		return kitten_opts.backend.files_to_search(kitten_opts, sanitized_args)
+	}
+	for args, expected := range map[string][]string{
+		"abcd":            {"a.txt"},
+		"-m 10 abcd":      {"a.txt"},
+		"-e abcd":         {"a.txt"},
+		"--hidden abcd":   {".gitignore", ".hidden", "a.txt"},
+		"abcd a.o":        nil,
+		"-e abcd -- a.o":  nil,
+		"--no-ignore abc": nil,
+	} {
+		if diff := cmp.Diff(expected, files(args)); diff != "" {
+			t.Fatalf("Incorrect files to search for: %#v\n%s", args, diff)
+		}
+	}
+}
+
+func TestURLTemplates(t *testing.T) {
+	cwd, _ := os.Getwd()
+	for template, expected := range map[string]string{
+		"vscode://file{path}:{line}:{col}":              "vscode://file" + filepath.ToSlash(cwd) + "/a%20b.txt:3:1",
+		"https://github.com/x/y/blob/{relpath}#L{line}": "https://github.com/x/y/blob/a%20b.txt#L3",
+	} {
+		if actual := url_for_result(template, "a b.txt", "3", ""); actual != expected {
+			t.Fatalf("Incorrect URL for template: %s\n%#v != %#v", template, expected, actual)
+		}
+	}
+}
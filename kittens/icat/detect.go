@@ -17,7 +17,7 @@ import (
 
 var _ = fmt.Print
 
-func DetectSupport(timeout time.Duration) (memory, files, direct bool, err error) {
+func DetectSupport(timeout time.Duration) (memory, files, direct bool, terminal loop.TerminalCapabilities, err error) {
 	temp_files_to_delete := make([]string, 0, 8)
 	shm_files_to_delete := make([]shm.MMap, 0, 8)
 	var direct_query_id, file_query_id, memory_query_id uint32
@@ -80,18 +80,17 @@ func DetectSupport(timeout time.Duration) (memory, files, direct bool, err error
 				print_error("Failed to create SHM for data transfer, memory based transfer is disabled. Error: %v", err)
 			}
 		}
-		lp.QueueWriteString("\x1b[c")
-
-		return "", nil
+		// the terminal responds to the queries in order, so by the time it is
+		// identified all graphics query responses have been received
+		return "", lp.DetectTerminalCapabilities(func(c *loop.TerminalCapabilities) error {
+			terminal = *c
+			lp.Quit(0)
+			return nil
+		})
 	}
 
 	lp.OnEscapeCode = func(etype loop.EscapeCodeType, payload []byte) (err error) {
 		switch etype {
-		case loop.CSI:
-			if len(payload) > 3 && payload[0] == '?' && payload[len(payload)-1] == 'c' {
-				lp.Quit(0)
-				return nil
-			}
 		case loop.APC:
 			g := graphics.GraphicsCommandFromAPC(payload)
 			if g != nil {
@@ -0,0 +1,169 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package icat
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+const DEFAULT_GRID_CELL_WIDTH = 20
+
+// Lays out images as thumbnails in a grid, one row of the grid at a time,
+// with each thumbnail placed relative to the cursor at the start of the row
+type grid_layout struct {
+	columns, cell_width, thumbnail_height, rows_per_page int
+
+	// images are displayed in the order they were specified, not the order
+	// they finish processing in
+	pending    map[int]*image_data
+	next_index int
+
+	column, rows_on_page int
+	failed               []*image_data
+}
+
+func new_grid_layout(columns int) *grid_layout {
+	cw := int(screen_size.Xpixel) / int(screen_size.Col)
+	ch := int(screen_size.Ypixel) / int(screen_size.Row)
+	ans := &grid_layout{pending: make(map[int]*image_data)}
+	ans.columns = columns
+	if ans.columns < 1 {
+		ans.columns = int(screen_size.Col) / DEFAULT_GRID_CELL_WIDTH
+	}
+	ans.columns = utils.Max(1, utils.Min(ans.columns, int(screen_size.Col)/2))
+	ans.cell_width = utils.Max(2, int(screen_size.Col)/ans.columns)
+	// square thumbnails, leaving room for the caption and the paging prompt
+	ans.thumbnail_height = utils.Max(1, utils.Min((ans.cell_width-1)*cw/ch, int(screen_size.Row)-2))
+	ans.rows_per_page = utils.Max(1, (int(screen_size.Row)-1)/(ans.thumbnail_height+1))
+	return ans
+}
+
+// The area each thumbnail is scaled to fit into, leaving a blank column
+// between thumbnails
+func (self *grid_layout) thumbnail_place() *Place {
+	return &Place{width: self.cell_width - 1, height: self.thumbnail_height, relative_to_cursor: true}
+}
+
+func caption_for(imgd *image_data, width int) string {
+	name := "<stdin>"
+	if imgd.source_name != "" {
+		name = filepath.Base(imgd.source_name)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return '?'
+		}
+		return r
+	}, name)
+	if wcswidth.Stringwidth(name) > width {
+		name = wcswidth.TruncateToVisualLength(name, width-1) + "…"
+	}
+	return name
+}
+
+func (self *grid_layout) start_row() bool {
+	if self.rows_on_page >= self.rows_per_page {
+		if !wait_for_next_page() {
+			return false
+		}
+		self.rows_on_page = 0
+	}
+	// scroll the screen if needed to make room for the row
+	h := self.thumbnail_height + 1
+	fmt.Print("\r" + strings.Repeat("\n", h) + relative_cursor_movement(0, -h))
+	return true
+}
+
+func (self *grid_layout) finish_row() {
+	fmt.Print(relative_cursor_movement(0, self.thumbnail_height+1) + "\r")
+	self.column = 0
+	self.rows_on_page++
+}
+
+func (self *grid_layout) show(imgd *image_data) bool {
+	if imgd.err != nil {
+		self.failed = append(self.failed, imgd)
+		return true
+	}
+	if self.column == 0 && !self.start_row() {
+		return false
+	}
+	x := self.column * self.cell_width
+	imgd.grid_x_offset = x
+	transmit_image(imgd)
+	if imgd.err != nil {
+		self.failed = append(self.failed, imgd)
+		return true
+	}
+	fmt.Print(loop.SAVE_CURSOR + relative_cursor_movement(x, self.thumbnail_height) + caption_for(imgd, self.cell_width-1) + loop.RESTORE_CURSOR)
+	self.column++
+	if self.column >= self.columns {
+		self.finish_row()
+	}
+	return true
+}
+
+// Add a processed image, displaying it and any images after it that are
+// ready. Returns false if the user chose to stop paging.
+func (self *grid_layout) add(imgd *image_data) bool {
+	self.pending[imgd.index] = imgd
+	for {
+		imgd, found := self.pending[self.next_index]
+		if !found {
+			return true
+		}
+		delete(self.pending, self.next_index)
+		self.next_index++
+		if !self.show(imgd) {
+			return false
+		}
+	}
+}
+
+func (self *grid_layout) finish() {
+	if self.column > 0 {
+		self.finish_row()
+	}
+	for _, imgd := range self.failed {
+		print_error("Failed to display \x1b[31m%s\x1b[39m: %s\r\n", imgd.source_name, imgd.err)
+	}
+}
+
+// Wait for a key press before showing the next screenful of thumbnails,
+// returns false if the user wants to stop
+func wait_for_next_page() (ans bool) {
+	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors, loop.NoMouseTracking)
+	if err != nil {
+		return true
+	}
+	lp.OnInitialize = func() (string, error) {
+		lp.SetCursorVisible(false)
+		lp.QueueWriteString("\r\x1b[1;32mPress any key for the next page, q or Esc to quit\x1b[m")
+		return "", nil
+	}
+	lp.OnFinalize = func() string {
+		lp.SetCursorVisible(true)
+		return "\r\x1b[K"
+	}
+	lp.OnKeyEvent = func(event *loop.KeyEvent) error {
+		if event.Type == loop.RELEASE {
+			return nil
+		}
+		event.Handled = true
+		ans = !(event.MatchesPressOrRepeat("q") || event.MatchesPressOrRepeat("esc") || event.MatchesPressOrRepeat("ctrl+c"))
+		lp.Quit(0)
+		return nil
+	}
+	if err = lp.Run(); err != nil {
+		return true
+	}
+	return
+}
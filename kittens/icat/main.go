@@ -11,10 +11,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"kitty/kittens/clipboard"
 	"kitty/tools/cli"
 	"kitty/tools/tty"
 	"kitty/tools/tui"
 	"kitty/tools/tui/graphics"
+	"kitty/tools/tui/loop"
 	"kitty/tools/utils"
 	"kitty/tools/utils/images"
 	"kitty/tools/utils/style"
@@ -26,6 +28,9 @@ var _ = fmt.Print
 
 type Place struct {
 	width, height, left, top int
+	// left and top are offsets from the cursor position instead of the
+	// top left corner of the screen
+	relative_to_cursor bool
 }
 
 var opts *Options
@@ -87,40 +92,51 @@ func parse_z_index() (err error) {
 	return
 }
 
-func parse_place() (err error) {
-	if opts.Place == "" {
-		return nil
-	}
-	area, pos, found := strings.Cut(opts.Place, "@")
+func parse_place_spec(option_name, spec string) (ans *Place, err error) {
+	area, pos, found := strings.Cut(spec, "@")
 	if !found {
-		return fmt.Errorf("Invalid --place specification: %s", opts.Place)
+		return nil, fmt.Errorf("Invalid %s specification: %s", option_name, spec)
 	}
 	w, h, found := strings.Cut(area, "x")
 	if !found {
-		return fmt.Errorf("Invalid --place specification: %s", opts.Place)
+		return nil, fmt.Errorf("Invalid %s specification: %s", option_name, spec)
 	}
 	l, t, found := strings.Cut(pos, "x")
 	if !found {
-		return fmt.Errorf("Invalid --place specification: %s", opts.Place)
+		return nil, fmt.Errorf("Invalid %s specification: %s", option_name, spec)
 	}
-	place = &Place{}
-	place.width, err = strconv.Atoi(w)
+	ans = &Place{}
+	ans.width, err = strconv.Atoi(w)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	place.height, err = strconv.Atoi(h)
+	ans.height, err = strconv.Atoi(h)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	place.left, err = strconv.Atoi(l)
+	ans.left, err = strconv.Atoi(l)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	place.top, err = strconv.Atoi(t)
+	ans.top, err = strconv.Atoi(t)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return ans, nil
+}
+
+func parse_place() (err error) {
+	switch {
+	case opts.Place != "" && opts.PlaceRelativeToCursor != "":
+		return fmt.Errorf("The --place and --place-relative-to-cursor options cannot be used together")
+	case opts.Place != "":
+		place, err = parse_place_spec("--place", opts.Place)
+	case opts.PlaceRelativeToCursor != "":
+		if place, err = parse_place_spec("--place-relative-to-cursor", opts.PlaceRelativeToCursor); err == nil {
+			place.relative_to_cursor = true
+		}
 	}
-	return nil
+	return
 }
 
 func print_error(format string, args ...any) {
@@ -150,7 +166,8 @@ func main(cmd *cli.Command, o *Options, args []string) (rc int, err error) {
 	if err != nil {
 		return 1, fmt.Errorf("Failed to open controlling terminal with error: %w", err)
 	}
-	screen_size, err = t.GetSize()
+	screen_size, err = t.GetSizeWithPixels(2 * time.Second)
+	t.Close()
 	if err != nil {
 		return 1, fmt.Errorf("Failed to query terminal using TIOCGWINSZ with error: %w", err)
 	}
@@ -172,11 +189,36 @@ func main(cmd *cli.Command, o *Options, args []string) (rc int, err error) {
 	if err != nil {
 		return 1, err
 	}
+	if opts.FromClipboard {
+		data, _, err := clipboard.ReadFromClipboard([]string{"image/png", "image/*"}, false)
+		if err != nil {
+			return 1, fmt.Errorf("Failed to read an image from the clipboard with error: %w", err)
+		}
+		items = append([]input_arg{{arg: "<clipboard>", value: "<clipboard>", data: data}}, items...)
+	}
 	if opts.Place != "" && len(items) > 1 {
 		return 1, fmt.Errorf("The --place option can only be used with a single image, not %d", len(items))
 	}
+	if opts.PlaceRelativeToCursor != "" && len(items) > 1 {
+		return 1, fmt.Errorf("The --place-relative-to-cursor option can only be used with a single image, not %d", len(items))
+	}
+	if opts.Watch && (len(items) != 1 || items[0].value == "" || items[0].is_http_url || items[0].data != nil) {
+		return 1, fmt.Errorf("The --watch option can only be used with a single image file")
+	}
+	var grid *grid_layout
+	if opts.Grid {
+		if opts.Watch {
+			return 1, fmt.Errorf("The --grid option cannot be used together with --watch")
+		}
+		if place != nil {
+			return 1, fmt.Errorf("The --grid option cannot be used together with --place or --place-relative-to-cursor")
+		}
+		grid = new_grid_layout(opts.GridColumns)
+		place = grid.thumbnail_place()
+	}
 	files_channel = make(chan input_arg, len(items))
-	for _, ia := range items {
+	for i, ia := range items {
+		ia.index = i
 		files_channel <- ia
 	}
 	num_of_items = len(items)
@@ -190,24 +232,28 @@ func main(cmd *cli.Command, o *Options, args []string) (rc int, err error) {
 		}
 	}
 
-	passthrough_mode := no_passthrough
+	passthrough_mode := tui.NO_PASSTHROUGH
 	switch opts.Passthrough {
 	case "tmux":
-		passthrough_mode = tmux_passthrough
+		passthrough_mode = tui.TMUX_PASSTHROUGH
+	case "screen":
+		passthrough_mode = tui.SCREEN_PASSTHROUGH
 	case "detect":
-		if tui.TmuxSocketAddress() != "" {
-			passthrough_mode = tmux_passthrough
-		}
+		passthrough_mode = tui.DetectPassthrough()
 	}
 
-	if passthrough_mode == no_passthrough && (opts.TransferMode == "detect" || opts.DetectSupport) {
-		memory, files, direct, err := DetectSupport(time.Duration(opts.DetectionTimeout * float64(time.Second)))
+	if passthrough_mode == tui.NO_PASSTHROUGH && (opts.TransferMode == "detect" || opts.DetectSupport) {
+		memory, files, direct, terminal, err := DetectSupport(time.Duration(opts.DetectionTimeout * float64(time.Second)))
 		if err != nil {
 			return 1, err
 		}
 		if !direct {
 			keep_going.Store(false)
-			return 1, fmt.Errorf("This terminal does not support the graphics protocol use a terminal such as kitty, WezTerm or Konsole that does. If you are running inside a terminal multiplexer such as tmux or screen that might be interfering as well.")
+			which := "This terminal"
+			if terminal.Terminal != loop.UNKNOWN_TERMINAL {
+				which = fmt.Sprintf("This terminal (%s)", terminal.Terminal)
+			}
+			return 1, fmt.Errorf("%s does not support the graphics protocol use a terminal such as kitty, WezTerm or Konsole that does. If you are running inside a terminal multiplexer such as tmux or screen that might be interfering as well.", which)
 		}
 		if memory {
 			transfer_by_memory = supported
@@ -220,8 +266,8 @@ func main(cmd *cli.Command, o *Options, args []string) (rc int, err error) {
 			transfer_by_file = unsupported
 		}
 	}
-	if passthrough_mode != no_passthrough {
-		// tmux doesn't allow responses from the terminal so we can't detect if memory or file based transferring is supported
+	if passthrough_mode != tui.NO_PASSTHROUGH {
+		// multiplexers don't allow responses from the terminal so we can't detect if memory or file based transferring is supported
 		transfer_by_memory = unsupported
 		transfer_by_file = unsupported
 		transfer_by_stream = supported
@@ -237,10 +283,11 @@ func main(cmd *cli.Command, o *Options, args []string) (rc int, err error) {
 		return 0, nil
 	}
 	use_unicode_placeholder := opts.UnicodePlaceholder
-	if passthrough_mode != no_passthrough {
+	if passthrough_mode != tui.NO_PASSTHROUGH {
 		use_unicode_placeholder = true
 	}
 	base_id := uint32(opts.ImageId)
+	var watched *image_data
 	for num_of_items > 0 {
 		imgd := <-output_channel
 		if base_id != 0 {
@@ -253,16 +300,33 @@ func main(cmd *cli.Command, o *Options, args []string) (rc int, err error) {
 		imgd.use_unicode_placeholder = use_unicode_placeholder
 		imgd.passthrough_mode = passthrough_mode
 		num_of_items--
+		if grid != nil {
+			if !grid.add(imgd) {
+				break
+			}
+			continue
+		}
 		if imgd.err != nil {
 			print_error("Failed to process \x1b[31m%s\x1b[39m: %s\r\n", imgd.source_name, imgd.err)
 		} else {
+			if opts.Watch {
+				prepare_for_watch(imgd)
+			}
 			transmit_image(imgd)
 			if imgd.err != nil {
 				print_error("Failed to transmit \x1b[31m%s\x1b[39m: %s\r\n", imgd.source_name, imgd.err)
+			} else if opts.Watch {
+				watched = imgd
 			}
 		}
 	}
+	if watched != nil {
+		watch_for_changes(items[0], watched)
+	}
 	keep_going.Store(false)
+	if grid != nil {
+		grid.finish()
+	}
 	if opts.Hold {
 		fmt.Print("\r")
 		if opts.Place != "" {
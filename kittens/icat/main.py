@@ -20,9 +20,35 @@
 be positioned at the top left corner of the image, instead of on the line after the image.
 
 
+--place-relative-to-cursor
+Like :option:`--place` except that the position of the rectangle is relative to
+the cell the cursor is in, instead of the top-left corner of the screen, and
+can be negative. For example, :code:`10x5@0x0` displays the image in the ten
+by five cell area whose top-left corner is at the cursor. The cursor is left
+where it was, which allows overlaying images onto existing text without
+computing absolute coordinates. Combine with a negative :option:`--z-index` to
+display the image behind the text.
+
+
+--grid
+type=bool-set
+Display the images as thumbnails laid out in a grid sized to fit the window,
+with the file name of each image below its thumbnail, instead of one after the
+other at full size. When there are more thumbnails than fit on the screen,
+wait for a key press before displaying the next screenful.
+
+
+--grid-columns
+type=int
+default=0
+The number of columns of thumbnails to use with :option:`--grid`. The default
+is to use as many columns of about twenty cells each as fit in the window.
+
+
 --scale-up
 type=bool-set
-When used in combination with :option:`--place` it will cause images that are
+When used in combination with :option:`--place` or
+:option:`--place-relative-to-cursor` it will cause images that are
 smaller than the specified area to be scaled up to use as much of the specified
 area as possible.
 
@@ -40,6 +66,14 @@
 Mirror the image about a horizontal or vertical axis or both.
 
 
+--from-clipboard
+type=bool-set
+Display the image currently on the clipboard, for example, a screenshot that was
+just copied. The image is read from the terminal using the :doc:`clipboard
+protocol </clipboard>`, so the terminal must support it and allow reading from
+the clipboard.
+
+
 --clear
 type=bool-set
 Remove all images currently displayed on the screen.
@@ -98,7 +132,7 @@
 default=auto
 The engine used for decoding and processing of images. The default is to use
 the most appropriate engine.  The :code:`builtin` engine uses Go's native
-imaging libraries. The :code:`magick` engine uses ImageMagick which requires
+imaging libraries and can also decode sixel images. The :code:`magick` engine uses ImageMagick which requires
 it to be installed on the system.
 
 
@@ -136,14 +170,22 @@
 
 --passthrough
 type=choices
-choices=detect,tmux,none
+choices=detect,tmux,screen,none
 default=detect
 Whether to surround graphics commands with escape sequences that allow them to passthrough
-programs like tmux. The default is to detect when running inside tmux and automatically use
-the tmux passthrough escape codes. Note that when this option is enabled it implies
+programs like tmux and GNU screen. The default is to detect when running inside tmux or screen
+and automatically use the appropriate passthrough escape codes. With tmux, the
+:code:`allow-passthrough` option is turned on for the current pane if needed. Note that when this option is enabled it implies
 :option:`--unicode-placeholder` as well.
 
 
+--watch
+type=bool-set
+Keep running and re-display the image, in place, whenever the image file
+changes on disk. Useful when iterating on plots or design assets. Can only be
+used with a single image file. Press :kbd:`Ctrl+C` to quit.
+
+
 --image-id
 type=int
 default=0
@@ -0,0 +1,40 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package icat
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var _ = fmt.Print
+
+// Map the contents of the specified file into memory for reading, so that
+// large files are paged in as they are encoded rather than read into memory
+// all at once. Falls back to reading the file if it cannot be mapped.
+func map_file_for_reading(path string) (data []byte, release func(), err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	release = func() {}
+	if st.Size() == 0 {
+		return []byte{}, release, nil
+	}
+	if st.Mode().IsRegular() && int64(int(st.Size())) == st.Size() {
+		if data, err = unix.Mmap(int(f.Fd()), 0, int(st.Size()), unix.PROT_READ, unix.MAP_SHARED); err == nil {
+			// the data is read once from start to end
+			_ = unix.Madvise(data, unix.MADV_SEQUENTIAL)
+			return data, func() { unix.Munmap(data) }, nil
+		}
+	}
+	data, err = os.ReadFile(path)
+	return data, release, err
+}
@@ -21,7 +21,7 @@ func resize_frame(imgd *image_data, img image.Image) (image.Image, image.Rectang
 	left, top, width, height := b.Min.X, b.Min.Y, b.Dx(), b.Dy()
 	new_width := int(imgd.scaled_frac.x * float64(width))
 	new_height := int(imgd.scaled_frac.y * float64(height))
-	img = imaging.Resize(img, new_width, new_height, imaging.Lanczos)
+	img = images.Resize(img, new_width, new_height)
 	newleft := int(imgd.scaled_frac.x * float64(left))
 	newtop := int(imgd.scaled_frac.y * float64(top))
 	return img, image.Rect(newleft, newtop, newleft+new_width, newtop+new_height)
@@ -16,10 +16,13 @@ import (
 	"strings"
 
 	"kitty/tools/tty"
+	"kitty/tools/tui"
 	"kitty/tools/tui/graphics"
 	"kitty/tools/utils"
 	"kitty/tools/utils/images"
 	"kitty/tools/utils/shm"
+
+	"golang.org/x/exp/slices"
 )
 
 var _ = fmt.Print
@@ -65,6 +68,10 @@ type input_arg struct {
 	arg         string
 	value       string
 	is_http_url bool
+	// the position of this input in the list of inputs
+	index int
+	// image data read from somewhere other than a file, such as the clipboard
+	data []byte
 }
 
 func is_http_url(arg string) bool {
@@ -93,6 +100,7 @@ func process_dirs(args ...string) (results []input_arg, err error) {
 					return nil, &fs.PathError{Op: "Stat", Path: arg, Err: err}
 				}
 				if s.IsDir() {
+					first := len(results)
 					filepath.WalkDir(arg, func(path string, d fs.DirEntry, walk_err error) error {
 						if walk_err != nil {
 							if d == nil {
@@ -108,6 +116,8 @@ func process_dirs(args ...string) (results []input_arg, err error) {
 						}
 						return nil
 					})
+					// show image001.png, image2.png and image10.png in numeric order
+					slices.SortStableFunc(results[first:], func(a, b input_arg) int { return utils.NaturalCompare(a.value, b.value) })
 				} else {
 					results = append(results, input_arg{arg: arg, value: arg})
 				}
@@ -183,16 +193,20 @@ type image_data struct {
 	frames                            []*image_frame
 	image_number                      uint32
 	image_id                          uint32
+	placement_id                      uint32
 	cell_x_offset                     int
 	move_x_by                         int
 	move_to                           struct{ x, y int }
+	move_relative_by                  struct{ x, y int }
+	grid_x_offset                     int
 	width_cells, height_cells         int
 	use_unicode_placeholder           bool
-	passthrough_mode                  passthrough_type
+	passthrough_mode                  tui.PassthroughMode
 
 	// for error reporting
 	err         error
 	source_name string
+	index       int
 }
 
 func set_basic_metadata(imgd *image_data) {
@@ -209,8 +223,8 @@ func set_basic_metadata(imgd *image_data) {
 	imgd.needs_conversion = imgd.needs_scaling || remove_alpha != nil || flip || flop || imgd.format_uppercase != "PNG"
 }
 
-func report_error(source_name, msg string, err error) {
-	imgd := image_data{source_name: source_name, err: fmt.Errorf("%s: %w", msg, err)}
+func report_error(arg input_arg, source_name, msg string, err error) {
+	imgd := image_data{source_name: source_name, index: arg.index, err: fmt.Errorf("%s: %w", msg, err)}
 	send_output(&imgd)
 }
 
@@ -237,36 +251,38 @@ func make_output_from_input(imgd *image_data, f *opened_input) {
 
 func process_arg(arg input_arg) {
 	var f opened_input
-	if arg.is_http_url {
+	if arg.data != nil {
+		f.file = &BytesBuf{data: arg.data}
+	} else if arg.is_http_url {
 		resp, err := http.Get(arg.value)
 		if err != nil {
-			report_error(arg.value, "Could not get", err)
+			report_error(arg, arg.value, "Could not get", err)
 			return
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode != http.StatusOK {
-			report_error(arg.value, "Could not get", fmt.Errorf("bad status: %v", resp.Status))
+			report_error(arg, arg.value, "Could not get", fmt.Errorf("bad status: %v", resp.Status))
 			return
 		}
 		dest := bytes.Buffer{}
 		dest.Grow(64 * 1024)
 		_, err = io.Copy(&dest, resp.Body)
 		if err != nil {
-			report_error(arg.value, "Could not download", err)
+			report_error(arg, arg.value, "Could not download", err)
 			return
 		}
 		f.file = &BytesBuf{data: dest.Bytes()}
 	} else if arg.value == "" {
 		stdin, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			report_error("<stdin>", "Could not read from", err)
+			report_error(arg, "<stdin>", "Could not read from", err)
 			return
 		}
 		f.file = &BytesBuf{data: stdin}
 	} else {
 		q, err := os.Open(arg.value)
 		if err != nil {
-			report_error(arg.value, "Could not open", err)
+			report_error(arg, arg.value, "Could not open", err)
 			return
 		}
 		f.file = q
@@ -276,7 +292,7 @@ func process_arg(arg input_arg) {
 	var c image.Config
 	var format string
 	var err error
-	imgd := image_data{source_name: arg.value}
+	imgd := image_data{source_name: arg.value, index: arg.index}
 	if opts.Engine == "auto" || opts.Engine == "native" {
 		c, format, err = image.DecodeConfig(f.file)
 		f.Rewind()
@@ -297,13 +313,13 @@ func process_arg(arg input_arg) {
 		}
 		err = render_image_with_go(&imgd, &f)
 		if err != nil {
-			report_error(arg.value, "Could not render image to RGB", err)
+			report_error(arg, arg.value, "Could not render image to RGB", err)
 			return
 		}
 	} else {
 		err = render_image_with_magick(&imgd, &f)
 		if err != nil {
-			report_error(arg.value, "ImageMagick failed", err)
+			report_error(arg, arg.value, "ImageMagick failed", err)
 			return
 		}
 	}
@@ -3,6 +3,7 @@
 package icat
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
@@ -16,7 +17,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"kitty/tools/tui"
 	"kitty/tools/tui/graphics"
 	"kitty/tools/tui/loop"
 	"kitty/tools/utils"
@@ -26,22 +26,8 @@ import (
 
 var _ = fmt.Print
 
-type passthrough_type int
-
-const (
-	no_passthrough passthrough_type = iota
-	tmux_passthrough
-)
-
 func new_graphics_command(imgd *image_data) *graphics.GraphicsCommand {
-	gc := graphics.GraphicsCommand{}
-	switch imgd.passthrough_mode {
-	case tmux_passthrough:
-		gc.WrapPrefix = "\033Ptmux;"
-		gc.WrapSuffix = "\033\\"
-		gc.EncodeSerializedDataFunc = func(x string) string { return strings.ReplaceAll(x, "\033", "\033\033") }
-	}
-	return &gc
+	return &graphics.GraphicsCommand{Passthrough: imgd.passthrough_mode}
 }
 
 func gc_for_image(imgd *image_data, frame_num int, frame *image_frame) *graphics.GraphicsCommand {
@@ -57,6 +43,9 @@ func gc_for_image(imgd *image_data, frame_num int, frame *image_frame) *graphics
 	}
 	if frame_num == 0 {
 		gc.SetAction(graphics.GRT_action_transmit_and_display)
+		if imgd.placement_id != 0 {
+			gc.SetPlacementId(imgd.placement_id)
+		}
 		if imgd.use_unicode_placeholder {
 			gc.SetUnicodePlaceholder(graphics.GRT_create_unicode_placeholder)
 			gc.SetColumns(uint64(imgd.width_cells))
@@ -181,18 +170,23 @@ func transmit_file(imgd *image_data, frame_num int, frame *image_frame) (err err
 func transmit_stream(imgd *image_data, frame_num int, frame *image_frame) (err error) {
 	data := frame.in_memory_bytes
 	if data == nil {
-		f, err := os.Open(frame.filename)
+		var release func()
+		data, release, err = map_file_for_reading(frame.filename)
 		if err != nil {
-			return fmt.Errorf("Failed to open image data output file: %s with error: %w", frame.filename, err)
-		}
-		data, err = io.ReadAll(f)
-		f.Close()
-		if err != nil {
-			return fmt.Errorf("Failed to read data from image output data file: %w", err)
+			return fmt.Errorf("Failed to read data from image output data file: %s with error: %w", frame.filename, err)
 		}
+		defer release()
 	}
 	gc := gc_for_image(imgd, frame_num, frame)
-	gc.WriteWithPayloadTo(os.Stdout, data)
+	// encode and write the data in chunks so that the terminal can start
+	// receiving the image before all of it has been encoded
+	w := bufio.NewWriterSize(os.Stdout, 64*1024)
+	if err = gc.WriteStreamingPayloadTo(w, bytes.NewReader(data), true); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write image data with error: %w", err)
+	}
 	return nil
 }
 
@@ -225,17 +219,40 @@ func place_cursor(imgd *image_data) {
 			imgd.move_x_by = (int(screen_size.Col) - imgd.width_cells)
 		}
 	} else {
-		imgd.move_to.x = place.left + 1
-		imgd.move_to.y = place.top + 1
+		x := &imgd.move_to.x
+		if place.relative_to_cursor {
+			x = &imgd.move_relative_by.x
+			*x, imgd.move_relative_by.y = place.left+imgd.grid_x_offset, place.top
+		} else {
+			imgd.move_to.x = place.left + 1
+			imgd.move_to.y = place.top + 1
+		}
 		switch opts.Align {
 		case "center":
-			imgd.move_to.x += (place.width - imgd.width_cells) / 2
+			*x += (place.width - imgd.width_cells) / 2
 		case "right":
-			imgd.move_to.x += (place.width - imgd.width_cells)
+			*x += (place.width - imgd.width_cells)
 		}
 	}
 }
 
+func relative_cursor_movement(x, y int) string {
+	ans := ""
+	switch {
+	case x > 0:
+		ans += fmt.Sprintf("\x1b[%dC", x)
+	case x < 0:
+		ans += fmt.Sprintf("\x1b[%dD", -x)
+	}
+	switch {
+	case y > 0:
+		ans += fmt.Sprintf("\x1b[%dB", y)
+	case y < 0:
+		ans += fmt.Sprintf("\x1b[%dA", -y)
+	}
+	return ans
+}
+
 func next_random() (ans uint32) {
 	for ans == 0 {
 		b := make([]byte, 4)
@@ -265,6 +282,17 @@ func write_unicode_placeholder(imgd *image_data) {
 		fmt.Printf(loop.MoveCursorToTemplate, imgd.move_to.y, 0)
 	}
 	id_char := string(images.NumberToDiacritic[(imgd.image_id>>24)&255])
+	if place != nil && place.relative_to_cursor {
+		// stay in the same columns on every row, the cursor is restored by the caller
+		os.Stdout.WriteString(relative_cursor_movement(imgd.move_relative_by.x, imgd.move_relative_by.y))
+		for r := 0; r < imgd.height_cells; r++ {
+			for c := 0; c < imgd.width_cells; c++ {
+				os.Stdout.WriteString(string(kitty.ImagePlaceholderChar) + string(images.NumberToDiacritic[r]) + string(images.NumberToDiacritic[c]) + id_char)
+			}
+			os.Stdout.WriteString(relative_cursor_movement(-imgd.width_cells, 1))
+		}
+		return
+	}
 	for r := 0; r < imgd.height_cells; r++ {
 		if imgd.move_to.x > 0 {
 			fmt.Printf("\x1b[%dC", imgd.move_to.x-1)
@@ -342,15 +370,19 @@ func transmit_image(imgd *image_data) {
 		imgd.err = fmt.Errorf("Image too large to be displayed using Unicode placeholders. Maximum size is %dx%d cells", len(images.NumberToDiacritic), len(images.NumberToDiacritic))
 		return
 	}
-	switch imgd.passthrough_mode {
-	case tmux_passthrough:
-		imgd.err = tui.TmuxAllowPassthrough()
-		if imgd.err != nil {
-			return
+	if imgd.err = imgd.passthrough_mode.Enable(); imgd.err != nil {
+		return
+	}
+	relative_to_cursor := place != nil && place.relative_to_cursor
+	if relative_to_cursor {
+		os.Stdout.WriteString(loop.SAVE_CURSOR)
+		if !imgd.use_unicode_placeholder {
+			os.Stdout.WriteString(relative_cursor_movement(imgd.move_relative_by.x, imgd.move_relative_by.y))
 		}
+	} else {
+		fmt.Print("\r")
 	}
-	fmt.Print("\r")
-	if !imgd.use_unicode_placeholder {
+	if !imgd.use_unicode_placeholder && !relative_to_cursor {
 		if imgd.move_x_by > 0 {
 			fmt.Printf("\x1b[%dC", imgd.move_x_by)
 		}
@@ -402,7 +434,9 @@ func transmit_image(imgd *image_data) {
 		c.SetAnimationControl(3) // set animation to normal mode
 		c.WriteWithPayloadTo(os.Stdout, nil)
 	}
-	if imgd.move_to.x == 0 {
+	if relative_to_cursor {
+		os.Stdout.WriteString(loop.RESTORE_CURSOR)
+	} else if imgd.move_to.x == 0 {
 		fmt.Println() // ensure cursor is on new line
 	}
 }
@@ -0,0 +1,100 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package icat
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+var _ = fmt.Print
+
+const watch_poll_interval = 250 * time.Millisecond
+
+// The placement id used for watched images, so that re-displaying the image
+// replaces the existing placement
+const watch_placement_id = 1
+
+type file_state struct {
+	mtime time.Time
+	size  int64
+}
+
+func stat_file(path string) (ans file_state, err error) {
+	s, err := os.Stat(path)
+	if err == nil {
+		ans.mtime, ans.size = s.ModTime(), s.Size()
+	}
+	return
+}
+
+// Wait for the file at path to change from prev and then stop changing, so
+// that an image that is still being written is not displayed
+func wait_for_change(path string, prev file_state) file_state {
+	for {
+		time.Sleep(watch_poll_interval)
+		s, err := stat_file(path)
+		if err != nil || s == prev {
+			continue
+		}
+		for {
+			time.Sleep(watch_poll_interval)
+			q, err := stat_file(path)
+			if err == nil && q == s {
+				return s
+			}
+			s = q
+		}
+	}
+}
+
+// The number of lines the cursor moved down by when displaying the image, so
+// that it can be moved back to re-display the image in the same place
+func lines_advanced(imgd *image_data) int {
+	switch {
+	case place != nil || imgd.move_to.y > 0:
+		return 0
+	case imgd.use_unicode_placeholder:
+		return imgd.height_cells + 1
+	default:
+		return imgd.height_cells
+	}
+}
+
+func prepare_for_watch(imgd *image_data) {
+	if imgd.image_id == 0 && !imgd.use_unicode_placeholder {
+		// unicode placeholder image ids have constraints and are generated
+		// by transmit_image()
+		imgd.image_id = next_random()
+	}
+	imgd.placement_id = watch_placement_id
+}
+
+// Re-display the image, in place, whenever the file it was read from
+// changes, until interrupted
+func watch_for_changes(arg input_arg, imgd *image_data) {
+	state, _ := stat_file(arg.value)
+	advanced := lines_advanced(imgd)
+	for {
+		state = wait_for_change(arg.value, state)
+		process_arg(arg)
+		n := <-output_channel
+		n.image_id, n.placement_id = imgd.image_id, imgd.placement_id
+		n.use_unicode_placeholder, n.passthrough_mode = imgd.use_unicode_placeholder, imgd.passthrough_mode
+		if advanced > 0 {
+			os.Stdout.WriteString(relative_cursor_movement(0, -advanced) + "\r\x1b[J")
+		}
+		if n.err == nil {
+			transmit_image(n)
+		}
+		if n.err != nil {
+			fmt.Printf("Failed to display \x1b[31m%s\x1b[39m: %s\r\n", n.source_name, n.err)
+			if place == nil {
+				advanced = 1
+			}
+			continue
+		}
+		advanced = lines_advanced(n)
+	}
+}
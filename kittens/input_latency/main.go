@@ -0,0 +1,79 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package input_latency
+
+import (
+	"fmt"
+	"time"
+
+	"kitty/tools/cli"
+	"kitty/tools/tui/loop"
+)
+
+var _ = fmt.Print
+
+func print_report(r Results) {
+	caps := loop.TerminalCapabilitiesFromEnvironment()
+	term := caps.Terminal.String()
+	if caps.Version != "" {
+		term += " " + caps.Version
+	}
+	fmt.Println("Terminal:", term)
+	type column struct {
+		title string
+		stats Stats
+	}
+	cols := []column{}
+	if len(r.Input) > 0 {
+		total := make([]time.Duration, len(r.Input))
+		for i, x := range r.Input {
+			total[i] = x + r.Render[i]
+		}
+		cols = append(cols, column{"Input", Summarize(r.Input)}, column{"Render", Summarize(r.Render)}, column{"Total", Summarize(total)})
+	} else {
+		cols = append(cols, column{"Render", Summarize(r.Render)})
+	}
+	fmt.Printf("Samples: %d\n\n", len(r.Render))
+	fmt.Printf("%-8s", "")
+	for _, c := range cols {
+		fmt.Printf(" %12s", c.title)
+	}
+	fmt.Println()
+	row := func(name string, val func(*Stats) time.Duration) {
+		fmt.Printf("%-8s", name)
+		for _, c := range cols {
+			fmt.Printf(" %12s", val(&c.stats).Round(time.Microsecond))
+		}
+		fmt.Println()
+	}
+	row("min", func(s *Stats) time.Duration { return s.Min })
+	row("p50", func(s *Stats) time.Duration { return s.P50 })
+	row("p90", func(s *Stats) time.Duration { return s.P90 })
+	row("p95", func(s *Stats) time.Duration { return s.P95 })
+	row("p99", func(s *Stats) time.Duration { return s.P99 })
+	row("max", func(s *Stats) time.Duration { return s.Max })
+	row("mean", func(s *Stats) time.Duration { return s.Mean })
+	row("stddev", func(s *Stats) time.Duration { return s.StdDev })
+}
+
+func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
+	if opts.Samples < 1 {
+		return 1, fmt.Errorf("The number of samples must be at least one")
+	}
+	if opts.Interval < 0 {
+		return 1, fmt.Errorf("The interval between samples must not be negative")
+	}
+	results, err := run_loop(opts)
+	if err != nil {
+		return 1, err
+	}
+	if len(results.Render) == 0 {
+		return 1, fmt.Errorf("No samples were collected")
+	}
+	print_report(results)
+	return 0, nil
+}
+
+func EntryPoint(parent *cli.Command) {
+	create_cmd(parent, main)
+}
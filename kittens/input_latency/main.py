@@ -0,0 +1,52 @@
+#!/usr/bin/env python
+# License: GPLv3 Copyright: 2023, Kovid Goyal <kovid at kovidgoyal.net>
+
+
+import sys
+from typing import List
+
+OPTIONS = r'''
+--samples -n
+type=int
+default=100
+The number of samples to collect.
+
+
+--interval
+type=float
+default=0.05
+The time, in seconds, to wait between samples of synthesized input.
+
+
+--manual
+type=bool-set
+Instead of synthesizing input, measure the latency of keys pressed by you.
+Since the time at which a key was physically pressed is not known, only the
+time from the key being received to the terminal processing the response to it
+is measured.
+'''.format
+help_text = '''\
+Measure the latency of input in the terminal, reporting percentile statistics
+that can be used to compare terminals, compositors and remote connections. By
+default, input is synthesized by asking the terminal, via remote control, to
+send timestamped text as though it were typed, and two latencies are measured,
+the time for the text to be received (input) and the time from receiving it to
+the terminal having processed the text drawn in response (render), which is
+detected by querying the cursor position. The times do not include the time
+taken by the keyboard and the display hardware.
+'''
+usage = ''
+
+
+def main(args: List[str]) -> None:
+    raise SystemExit('This should be run as kitten input_latency')
+
+
+if __name__ == '__main__':
+    main(sys.argv)
+elif __name__ == '__doc__':
+    cd = sys.cli_docs  # type: ignore
+    cd['usage'] = usage
+    cd['options'] = OPTIONS
+    cd['help_text'] = help_text
+    cd['short_desc'] = 'Measure the input latency of the terminal'
@@ -0,0 +1,62 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package input_latency
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type Stats struct {
+	Samples            int
+	Min, Max, Mean     time.Duration
+	StdDev             time.Duration
+	P50, P90, P95, P99 time.Duration
+}
+
+// The pth percentile of the sorted samples, interpolating linearly between
+// the closest ranks
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	switch len(sorted) {
+	case 0:
+		return 0
+	case 1:
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + time.Duration(math.Round(frac*float64(sorted[lower+1]-sorted[lower])))
+}
+
+func Summarize(samples []time.Duration) (ans Stats) {
+	if len(samples) == 0 {
+		return
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	ans.Samples = len(sorted)
+	ans.Min, ans.Max = sorted[0], sorted[len(sorted)-1]
+	var total float64
+	for _, x := range sorted {
+		total += float64(x)
+	}
+	mean := total / float64(len(sorted))
+	var variance float64
+	for _, x := range sorted {
+		variance += (float64(x) - mean) * (float64(x) - mean)
+	}
+	ans.Mean = time.Duration(mean)
+	ans.StdDev = time.Duration(math.Sqrt(variance / float64(len(sorted))))
+	ans.P50, ans.P90 = Percentile(sorted, 50), Percentile(sorted, 90)
+	ans.P95, ans.P99 = Percentile(sorted, 95), Percentile(sorted, 99)
+	return
+}
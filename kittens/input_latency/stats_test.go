@@ -0,0 +1,47 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package input_latency
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestInputLatencyStats(t *testing.T) {
+	ms := time.Millisecond
+	s := Summarize([]time.Duration{4 * ms, 1 * ms, 3 * ms, 2 * ms, 5 * ms})
+	expected := Stats{
+		Samples: 5, Min: ms, Max: 5 * ms, Mean: 3 * ms, StdDev: 1414213,
+		P50: 3 * ms, P90: 4600 * time.Microsecond, P95: 4800 * time.Microsecond, P99: 4960 * time.Microsecond,
+	}
+	if diff := cmp.Diff(expected, s); diff != "" {
+		t.Fatalf("Incorrect stats:\n%s", diff)
+	}
+	if diff := cmp.Diff(Stats{}, Summarize(nil)); diff != "" {
+		t.Fatalf("Incorrect stats for no samples:\n%s", diff)
+	}
+	if p := Percentile([]time.Duration{ms}, 90); p != ms {
+		t.Fatalf("Incorrect percentile for a single sample: %s", p)
+	}
+}
+
+func TestInputLatencyTokens(t *testing.T) {
+	a, b := token{seq: 1, sent: 123456}, token{seq: 2, sent: 7890123}
+	p := token_parser{}
+	sa, sb := a.String(), b.String()
+	var actual []token
+	actual = append(actual, p.feed("x"+sa+sb[:5])...)
+	actual = append(actual, p.feed(sb[5:12])...)
+	actual = append(actual, p.feed(sb[12:]+"[y")...)
+	if diff := cmp.Diff([]token{a, b}, actual, cmp.AllowUnexported(token{})); diff != "" {
+		t.Fatalf("Incorrect tokens:\n%s", diff)
+	}
+	if p.pending != "[y" {
+		t.Fatalf("Incorrect pending text: %#v", p.pending)
+	}
+}
@@ -0,0 +1,89 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package input_latency
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kitty/tools/cmd/at"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Synthesized input is a token containing a sequence number and the time,
+// relative to the start of the measurement, at which it was sent
+type token struct {
+	seq  int
+	sent time.Duration
+}
+
+const token_prefix = "[input-latency:"
+
+func (self token) String() string {
+	return fmt.Sprintf("%s%d:%d]", token_prefix, self.seq, self.sent.Nanoseconds())
+}
+
+var token_pat = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`\[input-latency:(\d+):(\d+)\]`)
+})
+
+// Extract tokens from text received from the terminal, which can be split
+// over multiple reads
+type token_parser struct {
+	pending string
+}
+
+func (self *token_parser) feed(text string) (ans []token) {
+	self.pending += text
+	end := 0
+	for _, m := range token_pat().FindAllStringSubmatchIndex(self.pending, -1) {
+		seq, _ := strconv.Atoi(self.pending[m[2]:m[3]])
+		ns, _ := strconv.ParseInt(self.pending[m[4]:m[5]], 10, 64)
+		ans = append(ans, token{seq: seq, sent: time.Duration(ns)})
+		end = m[1]
+	}
+	self.pending = self.pending[end:]
+	// keep only what could be the start of an incomplete token
+	if idx := strings.LastIndexByte(self.pending, '['); idx > -1 {
+		self.pending = self.pending[idx:]
+		if len(self.pending) > len(token_prefix)+64 {
+			self.pending = ""
+		}
+	} else {
+		self.pending = ""
+	}
+	return
+}
+
+// The escape code that asks the terminal to send text to this window as
+// though it were typed, via remote control over the tty
+func send_text_escape_code(text string) (string, error) {
+	rc := utils.RemoteControlCmd{
+		Cmd: "send-text", Version: at.ProtocolVersion,
+		Payload: map[string]any{"match": "state:self", "data": "text:" + text},
+	}
+	b, err := json.Marshal(&rc)
+	if err != nil {
+		return "", err
+	}
+	return "\x1bP@kitty-cmd" + string(b) + "\x1b\\", nil
+}
+
+// The error from a response to a remote control command, if any
+func rc_response_error(raw []byte) error {
+	var r at.Response
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return fmt.Errorf("Received an invalid response to a remote control command: %w", err)
+	}
+	if !r.Ok {
+		return fmt.Errorf("Sending synthesized input failed with error: %s", r.Error)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package input_latency
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kitty/tools/tui/loop"
+)
+
+var _ = fmt.Print
+
+// How long to wait for synthesized input before giving up
+const input_timeout = 5 * time.Second
+
+type Results struct {
+	// The time from sending synthesized input to receiving it, only
+	// measured for synthesized input
+	Input []time.Duration
+	// The time from receiving input to the terminal having processed the
+	// output drawn in response to it
+	Render []time.Duration
+}
+
+type handler struct {
+	lp      *loop.Loop
+	opts    *Options
+	start   time.Time
+	parser  token_parser
+	results Results
+
+	seq           int
+	timeout_timer loop.IdType
+	waiting       bool
+	last          time.Duration
+}
+
+func (self *handler) done() bool { return len(self.results.Render) >= self.opts.Samples }
+
+func (self *handler) draw_screen() {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	if self.opts.Manual {
+		self.lp.Println("Press any key repeatedly to measure the latency, press", self.lp.SprintStyled("fg=green", "Esc"), "to stop early")
+	} else {
+		self.lp.Println("Measuring the latency of synthesized input, press", self.lp.SprintStyled("fg=green", "Esc"), "to stop early")
+	}
+	self.lp.Println()
+	n := len(self.results.Render)
+	self.lp.Printf("Sample %d of %d\n", n, self.opts.Samples)
+	if n > 0 {
+		self.lp.Printf("Last: %s\n", self.last.Round(time.Microsecond))
+	}
+	width := 40
+	filled := n * width / max(1, self.opts.Samples)
+	self.lp.Println()
+	self.lp.QueueWriteString(self.lp.SprintStyled("fg=green", strings.Repeat("█", filled)) + self.lp.SprintStyled("dim", strings.Repeat("░", width-filled)))
+}
+
+// Draw in response to input and wait for the terminal to report the cursor
+// position, which it does only after processing the drawing
+func (self *handler) respond_to_input(received time.Time, input_delay time.Duration) error {
+	self.waiting = true
+	self.draw_screen()
+	return self.lp.QueryTerminal(loop.TerminalQuery{CursorPosition: true}, func(*loop.TerminalQueryResults) error {
+		self.waiting = false
+		render := time.Since(received)
+		self.results.Render = append(self.results.Render, render)
+		self.last = render
+		if !self.opts.Manual {
+			self.results.Input = append(self.results.Input, input_delay)
+			self.last += input_delay
+		}
+		if self.done() {
+			self.lp.Quit(0)
+			return nil
+		}
+		self.draw_screen()
+		if !self.opts.Manual {
+			_, err := self.lp.AddTimer(time.Duration(self.opts.Interval*float64(time.Second)), false, self.send_input)
+			return err
+		}
+		return nil
+	})
+}
+
+func (self *handler) send_input(loop.IdType) error {
+	self.seq++
+	ec, err := send_text_escape_code(token{seq: self.seq, sent: time.Since(self.start)}.String())
+	if err != nil {
+		return err
+	}
+	self.lp.QueueWriteString(ec)
+	self.timeout_timer, err = self.lp.AddTimer(input_timeout, false, func(loop.IdType) error {
+		return fmt.Errorf("Timed out waiting for synthesized input. Make sure remote control is allowed for this window, for example, by running it with: launch --allow-remote-control kitten input_latency")
+	})
+	return err
+}
+
+func (self *handler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
+	if self.opts.Manual {
+		return nil
+	}
+	now := time.Now()
+	for _, t := range self.parser.feed(text) {
+		if t.seq != self.seq || self.waiting {
+			continue
+		}
+		self.lp.RemoveTimer(self.timeout_timer)
+		if err := self.respond_to_input(now, now.Sub(self.start)-t.sent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *handler) on_key_event(ev *loop.KeyEvent) error {
+	if ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c") {
+		ev.Handled = true
+		self.lp.Quit(1)
+		return nil
+	}
+	if self.opts.Manual && ev.Type == loop.PRESS && !self.waiting {
+		ev.Handled = true
+		return self.respond_to_input(time.Now(), 0)
+	}
+	return nil
+}
+
+func run_loop(opts *Options) (results Results, err error) {
+	lp, err := loop.New(loop.NoMouseTracking)
+	if err != nil {
+		return
+	}
+	h := &handler{lp: lp, opts: opts}
+	lp.OnInitialize = func() (string, error) {
+		lp.AllowLineWrapping(false)
+		lp.SetCursorVisible(false)
+		lp.SetWindowTitle("Input latency")
+		h.start = time.Now()
+		h.draw_screen()
+		if !opts.Manual {
+			if _, err := lp.CallSoon(h.send_input); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	}
+	lp.OnFinalize = func() string {
+		lp.SetCursorVisible(true)
+		return ""
+	}
+	lp.OnResize = func(_, _ loop.ScreenSize) error {
+		h.draw_screen()
+		return nil
+	}
+	lp.OnKeyEvent = h.on_key_event
+	lp.OnText = h.on_text
+	lp.OnRCResponse = func(raw []byte) error { return rc_response_error(raw) }
+	if err = lp.Run(); err != nil {
+		return
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return
+	}
+	return h.results, nil
+}
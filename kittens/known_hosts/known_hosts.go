@@ -0,0 +1,364 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package known_hosts
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// The prefix of hostnames hashed by ssh-keygen -H or HashKnownHosts
+const HASH_MAGIC = "|1|"
+
+type hashed_host struct {
+	salt, hash []byte
+}
+
+// A single host key from a known_hosts file
+type Entry struct {
+	Path string
+	// 1-based line number in the file
+	LineNumber int
+	// @cert-authority or @revoked, empty for plain host keys
+	Marker string
+	// The hostname field, comma separated patterns or a hashed hostname
+	HostField string
+	KeyType   string
+	Key       []byte
+	Comment   string
+
+	patterns []string
+	hashed   *hashed_host
+	removed  bool
+}
+
+func (self *Entry) IsHashed() bool { return self.hashed != nil }
+
+// The fingerprint of the key in the same format as ssh-keygen -l
+func (self *Entry) Fingerprint() string {
+	h := sha256.Sum256(self.Key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(h[:])
+}
+
+// The key algorithm in the same format as ssh-keygen -l
+func (self *Entry) Algorithm() string {
+	switch self.KeyType {
+	case "ssh-ed25519":
+		return "ED25519"
+	case "sk-ssh-ed25519@openssh.com":
+		return "ED25519-SK"
+	case "ssh-rsa":
+		return "RSA"
+	case "ssh-dss":
+		return "DSA"
+	case "sk-ecdsa-sha2-nistp256@openssh.com":
+		return "ECDSA-SK"
+	}
+	if strings.HasPrefix(self.KeyType, "ecdsa-sha2-") {
+		return "ECDSA"
+	}
+	return strings.ToUpper(self.KeyType)
+}
+
+// The key type as accepted by ssh-keyscan -t
+func (self *Entry) ScanType() string {
+	switch self.KeyType {
+	case "ssh-ed25519":
+		return "ed25519"
+	case "sk-ssh-ed25519@openssh.com":
+		return "ed25519-sk"
+	case "ssh-rsa":
+		return "rsa"
+	case "ssh-dss":
+		return "dsa"
+	case "sk-ecdsa-sha2-nistp256@openssh.com":
+		return "ecdsa-sk"
+	}
+	if strings.HasPrefix(self.KeyType, "ecdsa-sha2-") {
+		return "ecdsa"
+	}
+	return self.KeyType
+}
+
+// The hostname patterns, empty for hashed entries
+func (self *Entry) Patterns() []string { return self.patterns }
+
+// A hostname that can be connected to for this entry, empty if unknown,
+// as for hashed entries or entries with only wildcard patterns
+func (self *Entry) ConnectableHost() (host string, port int) {
+	for _, p := range self.patterns {
+		if !strings.HasPrefix(p, "!") && !strings.ContainsAny(p, "*?") {
+			return SplitHostPort(p)
+		}
+	}
+	return "", 0
+}
+
+// The format used for hosts with non-standard ports in known_hosts files
+func HostKey(host string, port int) string {
+	if port == 0 || port == 22 {
+		return host
+	}
+	return fmt.Sprintf("[%s]:%d", host, port)
+}
+
+// Split [host]:port into its parts, port is 22 if not specified
+func SplitHostPort(x string) (host string, port int) {
+	if rest, found := strings.CutPrefix(x, "["); found {
+		if h, p, found := strings.Cut(rest, "]:"); found {
+			if port, err := strconv.Atoi(p); err == nil {
+				return h, port
+			}
+		}
+	}
+	return x, 22
+}
+
+// Match the OpenSSH wildcards * and ?
+func wildcard_match(pattern, text string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for pattern = pattern[1:]; len(pattern) > 0 && pattern[0] == '*'; pattern = pattern[1:] {
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(text); i++ {
+				if wildcard_match(pattern, text[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(text) == 0 {
+				return false
+			}
+		default:
+			if len(text) == 0 || pattern[0] != text[0] {
+				return false
+			}
+		}
+		pattern, text = pattern[1:], text[1:]
+	}
+	return len(text) == 0
+}
+
+// Whether this entry applies to the specified host, with the same semantics
+// as ssh, negated patterns take precedence
+func (self *Entry) MatchesHost(host string, port int) bool {
+	key := strings.ToLower(HostKey(host, port))
+	if self.hashed != nil {
+		mac := hmac.New(sha1.New, self.hashed.salt)
+		mac.Write([]byte(key))
+		return hmac.Equal(mac.Sum(nil), self.hashed.hash)
+	}
+	matched := false
+	for _, p := range self.patterns {
+		negated := strings.HasPrefix(p, "!")
+		if wildcard_match(strings.ToLower(strings.TrimPrefix(p, "!")), key) {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+func parse_hashed_host(field string) (*hashed_host, error) {
+	salt, hash, found := strings.Cut(field[len(HASH_MAGIC):], "|")
+	if !found {
+		return nil, fmt.Errorf("Invalid hashed hostname: %s", field)
+	}
+	ans := hashed_host{}
+	var err error
+	if ans.salt, err = base64.StdEncoding.DecodeString(salt); err != nil {
+		return nil, fmt.Errorf("Invalid salt in hashed hostname: %s", field)
+	}
+	if ans.hash, err = base64.StdEncoding.DecodeString(hash); err != nil {
+		return nil, fmt.Errorf("Invalid hash in hashed hostname: %s", field)
+	}
+	return &ans, nil
+}
+
+// Parse a line from a known_hosts file, returning nil for blank lines and comments
+func ParseLine(line string) (*Entry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+	ans := Entry{}
+	fields := strings.Fields(line)
+	if strings.HasPrefix(fields[0], "@") {
+		ans.Marker, fields = fields[0], fields[1:]
+		if ans.Marker != "@cert-authority" && ans.Marker != "@revoked" {
+			return nil, fmt.Errorf("Unknown marker: %s", ans.Marker)
+		}
+	}
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("Too few fields")
+	}
+	ans.HostField, ans.KeyType = fields[0], fields[1]
+	var err error
+	if ans.Key, err = base64.StdEncoding.DecodeString(fields[2]); err != nil {
+		return nil, fmt.Errorf("Invalid key data: %w", err)
+	}
+	ans.Comment = strings.Join(fields[3:], " ")
+	if strings.HasPrefix(ans.HostField, HASH_MAGIC) {
+		if ans.hashed, err = parse_hashed_host(ans.HostField); err != nil {
+			return nil, err
+		}
+	} else {
+		ans.patterns = strings.Split(ans.HostField, ",")
+	}
+	return &ans, nil
+}
+
+// The line in known_hosts format for this entry
+func (self *Entry) String() string {
+	parts := make([]string, 0, 5)
+	if self.Marker != "" {
+		parts = append(parts, self.Marker)
+	}
+	parts = append(parts, self.HostField, self.KeyType, base64.StdEncoding.EncodeToString(self.Key))
+	if self.Comment != "" {
+		parts = append(parts, self.Comment)
+	}
+	return strings.Join(parts, " ")
+}
+
+// A known_hosts file, preserving comments and lines that could not be parsed
+type File struct {
+	Path    string
+	Entries []*Entry
+	// Lines that could not be parsed, as errors mentioning the line number
+	Errors []error
+
+	lines   []string
+	entries map[int]*Entry
+	dirty   bool
+}
+
+func LoadFile(path string) (*File, error) {
+	ans := &File{Path: path, entries: make(map[int]*Entry)}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ans, nil
+		}
+		return nil, err
+	}
+	ans.lines = utils.Splitlines(utils.UnsafeBytesToString(raw))
+	for i, line := range ans.lines {
+		e, err := ParseLine(line)
+		if err != nil {
+			ans.Errors = append(ans.Errors, fmt.Errorf("%s:%d: %w", path, i+1, err))
+			continue
+		}
+		if e != nil {
+			e.Path, e.LineNumber = path, i+1
+			ans.Entries = append(ans.Entries, e)
+			ans.entries[i] = e
+		}
+	}
+	return ans, nil
+}
+
+func (self *File) Remove(e *Entry) {
+	if !e.removed {
+		e.removed = true
+		self.dirty = true
+		self.Entries = utils.Filter(self.Entries, func(x *Entry) bool { return x != e })
+	}
+}
+
+// Replace the key of an entry, keeping its hostnames
+func (self *File) ReplaceKey(e *Entry, key_type string, key []byte) {
+	e.KeyType, e.Key = key_type, key
+	self.dirty = true
+}
+
+func (self *File) Save() error {
+	if !self.dirty {
+		return nil
+	}
+	buf := strings.Builder{}
+	for i, line := range self.lines {
+		if e := self.entries[i]; e != nil {
+			if e.removed {
+				continue
+			}
+			line = e.String()
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := utils.AtomicUpdateFile(self.Path, utils.UnsafeStringToBytes(buf.String()), 0o600); err != nil {
+		return err
+	}
+	self.dirty = false
+	return nil
+}
+
+// The known_hosts files used by ssh by default that belong to the user
+func DefaultFiles() []string {
+	return []string{filepath.Join(utils.Expanduser("~/.ssh"), "known_hosts"), filepath.Join(utils.Expanduser("~/.ssh"), "known_hosts2")}
+}
+
+// All entries matching the specified host in the specified files
+func FindHost(host string, port int, files ...*File) (ans []*Entry) {
+	for _, f := range files {
+		for _, e := range f.Entries {
+			if e.MatchesHost(host, port) {
+				ans = append(ans, e)
+			}
+		}
+	}
+	return
+}
+
+var authenticity_prompt_pat = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`authenticity of host '([^' ]+)`)
+})
+
+// Extra information for the prompt ssh shows when connecting to a host
+// whose key is not known, listing the keys already known for the host, if
+// any, since the prompt is also the result of a host changing its key type.
+// Returns the empty string if there is nothing to add.
+func HostKeyPromptNote(prompt string) string {
+	m := authenticity_prompt_pat().FindStringSubmatch(prompt)
+	if m == nil {
+		return ""
+	}
+	files, err := load_files(nil)
+	if err != nil {
+		return ""
+	}
+	host, port := SplitHostPort(m[1])
+	entries := FindHost(host, port, files...)
+	if len(entries) == 0 {
+		return ""
+	}
+	lines := []string{"", "Other keys are already known for this host:"}
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("  %s %s (%s:%d)", e.Algorithm(), e.Fingerprint(), e.Path, e.LineNumber))
+	}
+	lines = append(lines, fmt.Sprintf("Use: kitten known_hosts %s to review them.", m[1]))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,128 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package known_hosts
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func hashed_line(host, key string) string {
+	salt := []byte("0123456789abcdefghij")
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return fmt.Sprintf("|1|%s|%s ssh-ed25519 %s", base64.StdEncoding.EncodeToString(salt), base64.StdEncoding.EncodeToString(mac.Sum(nil)), key)
+}
+
+func TestKnownHosts(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("not really a key"))
+	lines := []string{
+		"# a comment",
+		"example.com,192.0.2.1 ssh-ed25519 " + key + " some comment",
+		"*.example.org,!bad.example.org ecdsa-sha2-nistp256 " + key,
+		"[alt.example.com]:2222 ssh-rsa " + key,
+		hashed_line("secret.example.com", key),
+		"@revoked old.example.com ssh-rsa " + key,
+		"garbage",
+	}
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	data := ""
+	for _, l := range lines {
+		data += l + "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Entries) != 5 || len(f.Errors) != 1 {
+		t.Fatalf("Incorrect number of entries: %d or errors: %v", len(f.Entries), f.Errors)
+	}
+	e := f.Entries[0]
+	if e.LineNumber != 2 || e.Comment != "some comment" || e.Algorithm() != "ED25519" || e.ScanType() != "ed25519" {
+		t.Fatalf("Incorrectly parsed entry: %#v", e)
+	}
+	if fp := e.Fingerprint(); len(fp) != len("SHA256:")+43 {
+		t.Fatalf("Incorrect fingerprint: %s", fp)
+	}
+	if h, p := f.Entries[2].ConnectableHost(); h != "alt.example.com" || p != 2222 {
+		t.Fatalf("Incorrect connectable host: %s %d", h, p)
+	}
+	if !f.Entries[3].IsHashed() || f.Entries[4].Marker != "@revoked" {
+		t.Fatalf("Incorrectly parsed entries: %#v", f.Entries[3:])
+	}
+
+	found := func(host string, port int) (ans []int) {
+		for _, e := range FindHost(host, port, f) {
+			ans = append(ans, e.LineNumber)
+		}
+		return
+	}
+	for _, x := range []struct {
+		host     string
+		port     int
+		expected []int
+	}{
+		{"example.com", 22, []int{2}},
+		{"EXAMPLE.com", 22, []int{2}},
+		{"192.0.2.1", 22, []int{2}},
+		{"example.com", 2222, nil},
+		{"a.example.org", 22, []int{3}},
+		{"bad.example.org", 22, nil},
+		{"alt.example.com", 2222, []int{4}},
+		{"alt.example.com", 22, nil},
+		{"secret.example.com", 22, []int{5}},
+		{"other.example.com", 22, nil},
+	} {
+		if diff := cmp.Diff(x.expected, found(x.host, x.port)); diff != "" {
+			t.Fatalf("Incorrect entries found for %s:%d\n%s", x.host, x.port, diff)
+		}
+	}
+
+	f.Remove(f.Entries[0])
+	f.ReplaceKey(f.Entries[0], "ssh-ed25519", []byte("new key"))
+	if err = f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# a comment\n*.example.org,!bad.example.org ssh-ed25519 " + base64.StdEncoding.EncodeToString([]byte("new key")) + "\n"
+	for _, l := range lines[3:] {
+		expected += l + "\n"
+	}
+	if diff := cmp.Diff(expected, string(raw)); diff != "" {
+		t.Fatalf("Incorrect file contents after saving:\n%s", diff)
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	for _, x := range []struct {
+		pattern, text string
+		expected      bool
+	}{
+		{"*", "anything", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbb", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[h]:22", "[h]:22", true},
+		{"**.x", "a.x", true},
+	} {
+		if actual := wildcard_match(x.pattern, x.text); actual != x.expected {
+			t.Fatalf("wildcard_match(%#v, %#v) != %v", x.pattern, x.text, x.expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package known_hosts
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/cli"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+func load_files(paths []string) (ans []*File, err error) {
+	if len(paths) == 0 {
+		paths = DefaultFiles()
+	}
+	for _, path := range paths {
+		f, err := LoadFile(utils.Expanduser(path))
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, f)
+	}
+	return
+}
+
+func remove_hosts(files []*File, hosts []string) error {
+	for _, x := range hosts {
+		host, port := SplitHostPort(x)
+		for _, f := range files {
+			entries := FindHost(host, port, f)
+			for _, e := range entries {
+				f.Remove(e)
+			}
+			if err := f.Save(); err != nil {
+				return err
+			}
+			if len(entries) > 0 {
+				fmt.Printf("Removed %d keys for %s from %s\n", len(entries), x, f.Path)
+			}
+		}
+	}
+	return nil
+}
+
+func rescan_hosts(files []*File, hosts []string) error {
+	for _, x := range hosts {
+		host, port := SplitHostPort(x)
+		for _, f := range files {
+			for _, e := range FindHost(host, port, f) {
+				old := e.Fingerprint()
+				changed, err := RescanEntry(f, e, host, port)
+				if err != nil {
+					return err
+				}
+				if changed {
+					fmt.Printf("Updated %s key for %s in %s: %s -> %s\n", e.Algorithm(), x, f.Path, old, e.Fingerprint())
+				} else {
+					fmt.Printf("The %s key for %s in %s is unchanged\n", e.Algorithm(), x, f.Path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
+	if (opts.Remove || opts.Rescan) && len(args) == 0 {
+		return 1, fmt.Errorf("No hosts specified")
+	}
+	files, err := load_files(opts.File)
+	if err != nil {
+		return 1, err
+	}
+	switch {
+	case opts.Remove:
+		err = remove_hosts(files, args)
+	case opts.Rescan:
+		err = rescan_hosts(files, args)
+	default:
+		return run_ui(files, strings.Join(args, " "))
+	}
+	if err != nil {
+		rc = 1
+	}
+	return
+}
+
+func EntryPoint(parent *cli.Command) {
+	create_cmd(parent, main)
+}
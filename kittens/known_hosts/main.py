@@ -0,0 +1,50 @@
+#!/usr/bin/env python
+# License: GPLv3 Copyright: 2023, Kovid Goyal <kovid at kovidgoyal.net>
+
+
+import sys
+from typing import List
+
+OPTIONS = r'''
+--file -f
+type=list
+The known hosts file to operate on. Can be specified multiple times. Defaults to
+the files used by ssh by default, :file:`~/.ssh/known_hosts` and
+:file:`~/.ssh/known_hosts2`.
+
+
+--remove
+type=bool-set
+Instead of showing the interactive browser, remove all keys for the hosts
+specified on the command line, similar to :code:`ssh-keygen -R`.
+
+
+--rescan
+type=bool-set
+Instead of showing the interactive browser, replace the keys for the hosts
+specified on the command line with the keys the hosts currently offer, as
+reported by :program:`ssh-keyscan`. Only do this if you are sure the host keys
+were changed legitimately, for example, because the hosts were re-installed.
+'''.format
+help_text = '''\
+Browse the SSH known hosts files, including hashed entries. Shows the algorithm
+and fingerprint of each host key and allows removing stale entries and
+re-scanning hosts for their current keys. If hostnames are specified, only
+entries for those hosts are shown. Use :code:`[host]:port` for hosts on
+non-standard ports.
+'''
+usage = '[hostname ...]'
+
+
+def main(args: List[str]) -> None:
+    raise SystemExit('This should be run as kitten known_hosts')
+
+
+if __name__ == '__main__':
+    main(sys.argv)
+elif __name__ == '__doc__':
+    cd = sys.cli_docs  # type: ignore
+    cd['usage'] = usage
+    cd['options'] = OPTIONS
+    cd['help_text'] = help_text
+    cd['short_desc'] = 'Browse and manage SSH known hosts'
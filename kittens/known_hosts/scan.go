@@ -0,0 +1,68 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package known_hosts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Fetch the host keys currently offered by a host, using ssh-keyscan
+func ScanHost(host string, port int, key_types ...string) (ans []*Entry, err error) {
+	args := []string{"-T", "10", "-p", strconv.Itoa(port)}
+	if len(key_types) > 0 {
+		args = append(args, "-t", strings.Join(key_types, ","))
+	}
+	args = append(args, "--", host)
+	cmd := exec.Command("ssh-keyscan", args...)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			return nil, fmt.Errorf("Failed to run ssh-keyscan with error: %w", err)
+		}
+	}
+	for _, line := range utils.Splitlines(utils.UnsafeBytesToString(out)) {
+		if e, err := ParseLine(line); err == nil && e != nil {
+			ans = append(ans, e)
+		}
+	}
+	if len(ans) == 0 {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = "no keys were returned"
+		}
+		return nil, fmt.Errorf("Failed to scan %s for host keys: %s", HostKey(host, port), msg)
+	}
+	return
+}
+
+// Replace the key in entry with the one currently offered by the specified
+// host, which must be the host the entry is for. Returns true if the key was
+// changed.
+func RescanEntry(f *File, e *Entry, host string, port int) (changed bool, err error) {
+	scanned, err := ScanHost(host, port, e.ScanType())
+	if err != nil {
+		return false, err
+	}
+	for _, s := range scanned {
+		if s.KeyType == e.KeyType {
+			if bytes.Equal(s.Key, e.Key) {
+				return false, nil
+			}
+			f.ReplaceKey(e, s.KeyType, s.Key)
+			return true, f.Save()
+		}
+	}
+	return false, fmt.Errorf("%s no longer has a host key of type: %s", HostKey(host, port), e.Algorithm())
+}
@@ -0,0 +1,344 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package known_hosts
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type State int
+
+const (
+	BROWSING State = iota
+	SEARCHING
+	CONFIRMING_REMOVAL
+	SCANNING
+)
+
+type scan_result struct {
+	entry   *Entry
+	changed bool
+	err     error
+}
+
+type handler struct {
+	lp    *loop.Loop
+	files []*File
+
+	state       State
+	query       string
+	visible     []*Entry
+	current     int
+	scroll      int
+	status      string
+	status_err  bool
+	scan_result chan scan_result
+}
+
+func (self *handler) file_for(e *Entry) *File {
+	for _, f := range self.files {
+		if f.Path == e.Path {
+			return f
+		}
+	}
+	return nil
+}
+
+func (self *handler) query_host() (string, int) {
+	return SplitHostPort(strings.TrimSpace(self.query))
+}
+
+func (self *handler) matches_query(e *Entry) bool {
+	q := strings.ToLower(strings.TrimSpace(self.query))
+	if q == "" {
+		return true
+	}
+	if e.MatchesHost(self.query_host()) {
+		return true
+	}
+	for _, p := range e.Patterns() {
+		if strings.Contains(strings.ToLower(p), q) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *handler) update_visible() {
+	var current *Entry
+	if self.current < len(self.visible) {
+		current = self.visible[self.current]
+	}
+	self.visible = self.visible[:0]
+	self.current = 0
+	for _, f := range self.files {
+		for _, e := range f.Entries {
+			if self.matches_query(e) {
+				if e == current {
+					self.current = len(self.visible)
+				}
+				self.visible = append(self.visible, e)
+			}
+		}
+	}
+}
+
+func (self *handler) host_description(e *Entry) string {
+	if e.IsHashed() {
+		if self.query != "" && e.MatchesHost(self.query_host()) {
+			return self.query + " (hashed)"
+		}
+		return "(hashed)"
+	}
+	return e.HostField
+}
+
+func (self *handler) set_status(err bool, format string, args ...any) {
+	self.status, self.status_err = fmt.Sprintf(format, args...), err
+}
+
+func (self *handler) draw_screen() error {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return err
+	}
+	width, height := int(sz.WidthCells), int(sz.HeightCells)
+	header := fmt.Sprintf("%d known host keys", len(self.visible))
+	if self.query != "" || self.state == SEARCHING {
+		header += " matching: " + self.query
+	}
+	self.lp.Println(self.lp.SprintStyled("bold", wcswidth.TruncateToVisualLength(header, width)))
+	num_rows := max(1, height-3)
+	if self.current < self.scroll {
+		self.scroll = self.current
+	} else if self.current >= self.scroll+num_rows {
+		self.scroll = self.current - num_rows + 1
+	}
+	for i := self.scroll; i < min(len(self.visible), self.scroll+num_rows); i++ {
+		e := self.visible[i]
+		host := self.host_description(e)
+		if e.Marker != "" {
+			host = e.Marker + " " + host
+		}
+		line := fmt.Sprintf("%-10s %s  %s", e.Algorithm(), e.Fingerprint(), host)
+		line = wcswidth.TruncateToVisualLength(line, width)
+		if i == self.current {
+			line = self.lp.SprintStyled("reverse", line+strings.Repeat(" ", max(0, width-wcswidth.Stringwidth(line))))
+		}
+		self.lp.Println(line)
+	}
+	self.lp.MoveCursorTo(1, height-1)
+	if len(self.visible) > 0 {
+		e := self.visible[self.current]
+		loc := fmt.Sprintf("%s:%d", e.Path, e.LineNumber)
+		if e.Comment != "" {
+			loc += "  " + e.Comment
+		}
+		self.lp.QueueWriteString(self.lp.SprintStyled("dim", wcswidth.TruncateToVisualLength(loc, width)))
+	}
+	self.lp.MoveCursorTo(1, height)
+	var footer string
+	switch self.state {
+	case SEARCHING:
+		footer = "Search for host: " + self.query
+	case CONFIRMING_REMOVAL:
+		footer = self.lp.SprintStyled("fg=red", "Remove the selected host key? [y/n]")
+	case SCANNING:
+		footer = "Scanning host, please wait..."
+	default:
+		if self.status != "" {
+			style := "fg=green"
+			if self.status_err {
+				style = "fg=red"
+			}
+			footer = self.lp.SprintStyled(style, wcswidth.TruncateToVisualLength(self.status, width))
+		} else {
+			footer = "[/] Search  [d] Remove  [r] Re-scan  [q] Quit"
+		}
+	}
+	self.lp.QueueWriteString(footer)
+	self.lp.SetCursorVisible(self.state == SEARCHING)
+	return nil
+}
+
+func (self *handler) remove_current() {
+	e := self.visible[self.current]
+	f := self.file_for(e)
+	f.Remove(e)
+	if err := f.Save(); err != nil {
+		self.set_status(true, "Failed to save %s with error: %s", f.Path, err)
+	} else {
+		self.set_status(false, "Removed %s key for %s from %s", e.Algorithm(), self.host_description(e), filepath.Base(f.Path))
+	}
+	self.update_visible()
+	self.current = min(self.current, max(0, len(self.visible)-1))
+}
+
+func (self *handler) rescan_current() {
+	e := self.visible[self.current]
+	host, port := e.ConnectableHost()
+	if host == "" && e.IsHashed() && self.query != "" && e.MatchesHost(self.query_host()) {
+		host, port = self.query_host()
+	}
+	if host == "" {
+		self.set_status(true, "The hostname for this entry is not known, search for the host first")
+		return
+	}
+	self.state = SCANNING
+	f := self.file_for(e)
+	go func() {
+		r := scan_result{entry: e}
+		r.changed, r.err = RescanEntry(f, e, host, port)
+		self.scan_result <- r
+		self.lp.WakeupMainThread()
+	}()
+}
+
+func (self *handler) on_wakeup() error {
+	r := <-self.scan_result
+	self.state = BROWSING
+	switch {
+	case r.err != nil:
+		self.set_status(true, "%s", r.err)
+	case r.changed:
+		self.set_status(false, "Updated %s key for %s, new fingerprint: %s", r.entry.Algorithm(), self.host_description(r.entry), r.entry.Fingerprint())
+	default:
+		self.set_status(false, "The %s key for %s is unchanged", r.entry.Algorithm(), self.host_description(r.entry))
+	}
+	return self.draw_screen()
+}
+
+func (self *handler) move(delta int) {
+	if len(self.visible) > 0 {
+		self.current = max(0, min(self.current+delta, len(self.visible)-1))
+	}
+}
+
+func (self *handler) on_key_event(ev *loop.KeyEvent) error {
+	switch self.state {
+	case SCANNING:
+		if ev.MatchesPressOrRepeat("ctrl+c") {
+			ev.Handled = true
+			self.lp.Quit(1)
+		}
+		return nil
+	case SEARCHING:
+		switch {
+		case ev.MatchesPressOrRepeat("esc"):
+			self.query = ""
+			self.state = BROWSING
+		case ev.MatchesPressOrRepeat("enter"):
+			self.state = BROWSING
+		case ev.MatchesPressOrRepeat("backspace"):
+			if r := []rune(self.query); len(r) > 0 {
+				self.query = string(r[:len(r)-1])
+			}
+		default:
+			return nil
+		}
+		ev.Handled = true
+		self.update_visible()
+		return self.draw_screen()
+	case CONFIRMING_REMOVAL:
+		ev.Handled = true
+		self.state = BROWSING
+		if ev.MatchesPressOrRepeat("y") || ev.MatchesPressOrRepeat("shift+y") {
+			self.remove_current()
+		}
+		return self.draw_screen()
+	}
+	self.status = ""
+	ev.Handled = true
+	switch {
+	case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("q") || ev.MatchesPressOrRepeat("ctrl+c"):
+		self.lp.Quit(0)
+		return nil
+	case ev.MatchesPressOrRepeat("up") || ev.MatchesPressOrRepeat("k"):
+		self.move(-1)
+	case ev.MatchesPressOrRepeat("down") || ev.MatchesPressOrRepeat("j"):
+		self.move(1)
+	case ev.MatchesPressOrRepeat("page_up"):
+		if sz, err := self.lp.ScreenSize(); err == nil {
+			self.move(-max(1, int(sz.HeightCells)-3))
+		}
+	case ev.MatchesPressOrRepeat("page_down"):
+		if sz, err := self.lp.ScreenSize(); err == nil {
+			self.move(max(1, int(sz.HeightCells)-3))
+		}
+	case ev.MatchesPressOrRepeat("home"):
+		self.current = 0
+	case ev.MatchesPressOrRepeat("end"):
+		self.move(len(self.visible))
+	case ev.MatchesPressOrRepeat("/"):
+		self.state = SEARCHING
+	case ev.MatchesPressOrRepeat("d") || ev.MatchesPressOrRepeat("delete"):
+		if len(self.visible) > 0 {
+			self.state = CONFIRMING_REMOVAL
+		}
+	case ev.MatchesPressOrRepeat("r"):
+		if len(self.visible) > 0 {
+			self.rescan_current()
+		}
+	default:
+		ev.Handled = false
+		return nil
+	}
+	return self.draw_screen()
+}
+
+func (self *handler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
+	if self.state == SEARCHING {
+		self.query += text
+		self.update_visible()
+		return self.draw_screen()
+	}
+	return nil
+}
+
+func run_ui(files []*File, query string) (rc int, err error) {
+	lp, err := loop.New()
+	if err != nil {
+		return 1, err
+	}
+	h := &handler{lp: lp, files: files, query: query, scan_result: make(chan scan_result, 1)}
+	for _, f := range files {
+		if len(f.Errors) > 0 {
+			h.set_status(true, "%s", f.Errors[0])
+			break
+		}
+	}
+	lp.OnInitialize = func() (string, error) {
+		lp.AllowLineWrapping(false)
+		lp.SetWindowTitle("Known SSH hosts")
+		h.update_visible()
+		return "", h.draw_screen()
+	}
+	lp.OnFinalize = func() string {
+		lp.SetCursorVisible(true)
+		return ""
+	}
+	lp.OnResize = func(_, _ loop.ScreenSize) error { return h.draw_screen() }
+	lp.OnKeyEvent = h.on_key_event
+	lp.OnText = h.on_text
+	lp.OnWakeup = h.on_wakeup
+	if err = lp.Run(); err != nil {
+		return 1, err
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return 1, nil
+	}
+	return lp.ExitCode(), nil
+}
@@ -0,0 +1,59 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package man
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var _ = fmt.Print
+
+// Format a man page using the man program, with overstriking for
+// formatting. args are the arguments for man, that is, an optional section
+// and the page name, or the path to a file containing roff source.
+func FormatPage(args []string, width int) (string, error) {
+	cmd := exec.Command("man", args...)
+	cmd.Env = append(os.Environ(),
+		"MANWIDTH="+strconv.Itoa(max(20, width)),
+		// keep overstriking even though the output is not a terminal
+		"MAN_KEEP_FORMATTING=1",
+		// use overstriking rather than SGR escape codes in groff
+		"GROFF_NO_SGR=1",
+		"MANPAGER=cat", "PAGER=cat",
+	)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", man_error(err, &stderr, args)
+	}
+	return string(out), nil
+}
+
+func man_error(err error, stderr *bytes.Buffer, args []string) error {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("No manual entry for %s", strings.Join(args, " "))
+	}
+	return fmt.Errorf("Failed to run man with error: %w", err)
+}
+
+// Check that the man page specified by args exists, without formatting it
+func CheckPageExists(args []string) error {
+	cmd := exec.Command("man", append([]string{"-w"}, args...)...)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return man_error(err, &stderr, args)
+	}
+	return nil
+}
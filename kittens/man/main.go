@@ -0,0 +1,25 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package man
+
+import (
+	"fmt"
+
+	"kitty/tools/cli"
+)
+
+var _ = fmt.Print
+
+func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
+	if len(args) == 0 || len(args) > 2 {
+		return 1, fmt.Errorf("Must specify a man page, optionally preceded by a section")
+	}
+	if err = CheckPageExists(args); err != nil {
+		return 1, err
+	}
+	return run_ui(args, opts.Search)
+}
+
+func EntryPoint(parent *cli.Command) {
+	create_cmd(parent, main)
+}
@@ -0,0 +1,33 @@
+#!/usr/bin/env python
+# License: GPLv3 Copyright: 2023, Kovid Goyal <kovid at kovidgoyal.net>
+
+
+import sys
+from typing import List
+
+OPTIONS = r'''
+--search -s
+A regular expression to search for when the page is opened. The search is
+case-insensitive unless the expression contains upper case letters.
+'''.format
+help_text = '''\
+View man pages with formatting, navigation between sections, regular expression
+search and clickable links to other man pages and URLs. The page can be
+specified as a name, optionally preceded by a section number, as for
+:program:`man`, or as the path to a file containing a man page in roff format.
+'''
+usage = '[section] page | path/to/file.1'
+
+
+def main(args: List[str]) -> None:
+    raise SystemExit('This should be run as kitten man')
+
+
+if __name__ == '__main__':
+    main(sys.argv)
+elif __name__ == '__doc__':
+    cd = sys.cli_docs  # type: ignore
+    cd['usage'] = usage
+    cd['options'] = OPTIONS
+    cd['help_text'] = help_text
+    cd['short_desc'] = 'View man pages'
@@ -0,0 +1,232 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package man
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"kitty/tools/tui"
+	"kitty/tools/utils"
+	"kitty/tools/utils/style"
+)
+
+var _ = fmt.Print
+
+type attr uint8
+
+const (
+	BOLD attr = 1 << iota
+	UNDERLINE
+)
+
+// A link to another man page or a URL, offsets are in runes
+type Link struct {
+	Start, End int
+	URL        string
+	// The section and name of the referenced man page, empty for URLs
+	Section, Name string
+}
+
+type Line struct {
+	Text  string
+	Links []Link
+
+	runes []rune
+	attrs []attr
+}
+
+type Section struct {
+	Title string
+	Line  int
+	// Set for sub-sections such as those created by .SS
+	Subsection bool
+}
+
+type Page struct {
+	Lines    []*Line
+	Sections []Section
+}
+
+var man_ref_pat = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`([a-zA-Z0-9_][a-zA-Z0-9_.:+-]*)\(([1-9n][a-zA-Z0-9]*)\)`)
+})
+
+var url_pat = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`(?:https?|ftp)://[^\s<>"'` + "`" + `]+`)
+})
+
+// The attributes for the style spec of a span parsed from SGR formatting,
+// nroff uses italics or underline for what it underlines when overstriking
+func attrs_for_style(spec string) (ans attr) {
+	for _, x := range strings.Fields(spec) {
+		switch {
+		case x == "bold":
+			ans |= BOLD
+		case x == "italic" || strings.HasPrefix(x, "u="):
+			ans |= UNDERLINE
+		}
+	}
+	return
+}
+
+// Parse a single line of output from nroff that uses either overstriking or
+// SGR escape codes for formatting. With overstriking x<BS>x is bold and
+// _<BS>x is underlined.
+func parse_line(text string) *Line {
+	ans := Line{runes: make([]rune, 0, len(text)), attrs: make([]attr, 0, len(text))}
+	overstrike := false
+	for _, span := range style.ParseANSI(text) {
+		base := attrs_for_style(span.Style)
+		for _, ch := range span.Text {
+			switch {
+			case ch == '\b':
+				overstrike = len(ans.runes) > 0
+			case overstrike:
+				overstrike = false
+				last := len(ans.runes) - 1
+				prev, a := ans.runes[last], ans.attrs[last]
+				switch {
+				case prev == ch:
+					a |= BOLD
+				case prev == '_':
+					a |= UNDERLINE
+				}
+				ans.runes[last], ans.attrs[last] = ch, a
+			case ch == '\r':
+			default:
+				ans.runes = append(ans.runes, ch)
+				ans.attrs = append(ans.attrs, base)
+			}
+		}
+	}
+	ans.Text = string(ans.runes)
+	ans.find_links()
+	return &ans
+}
+
+func (self *Line) rune_offset(byte_offset int) int {
+	return len([]rune(self.Text[:byte_offset]))
+}
+
+func (self *Line) find_links() {
+	for _, m := range url_pat().FindAllStringIndex(self.Text, -1) {
+		url := strings.TrimRight(self.Text[m[0]:m[1]], ".,;:!?)]>")
+		self.Links = append(self.Links, Link{Start: self.rune_offset(m[0]), End: self.rune_offset(m[0] + len(url)), URL: url})
+	}
+	for _, m := range man_ref_pat().FindAllStringSubmatchIndex(self.Text, -1) {
+		start, end := self.rune_offset(m[0]), self.rune_offset(m[1])
+		overlaps := false
+		for _, l := range self.Links {
+			if start < l.End && l.Start < end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			name, section := self.Text[m[2]:m[3]], self.Text[m[4]:m[5]]
+			self.Links = append(self.Links, Link{Start: start, End: end, URL: "man:" + name + "(" + section + ")", Name: name, Section: section})
+		}
+	}
+}
+
+// A section heading is a bold line that starts at the left margin and a sub
+// section heading a bold line indented by three spaces, as output by the man
+// macros
+func (self *Line) heading() (title string, is_heading, is_subsection bool) {
+	indent := len(self.runes) - len([]rune(strings.TrimLeft(self.Text, " ")))
+	if (indent != 0 && indent != 3) || indent >= len(self.runes) || self.attrs[indent]&BOLD == 0 {
+		return
+	}
+	return strings.TrimSpace(self.Text), true, indent == 3
+}
+
+// Parse the output of man, formatted with overstriking
+func ParseFormatted(raw string) *Page {
+	ans := Page{}
+	lines := utils.Splitlines(raw)
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, text := range lines {
+		l := parse_line(text)
+		ans.Lines = append(ans.Lines, l)
+		// the first and last lines are the header and footer
+		if i > 0 && i < len(lines)-1 {
+			if title, ok, sub := l.heading(); ok {
+				ans.Sections = append(ans.Sections, Section{Title: title, Line: i, Subsection: sub})
+			}
+		}
+	}
+	return &ans
+}
+
+type highlight struct {
+	start, end int
+	current    bool
+}
+
+func (self *Line) link_at(pos int) *Link {
+	for i := range self.Links {
+		if l := &self.Links[i]; l.Start <= pos && pos < l.End {
+			return l
+		}
+	}
+	return nil
+}
+
+// Render the line with SGR formatting, search matches highlighted and links
+// as OSC 8 hyperlinks
+func (self *Line) render(highlights []highlight, hyperlinks *tui.Hyperlinks, focused *Link) string {
+	buf := strings.Builder{}
+	buf.Grow(len(self.Text) * 2)
+	prev_sgr := ""
+	var prev_link *Link
+	for i, ch := range self.runes {
+		link := self.link_at(i)
+		if link != prev_link {
+			if prev_link != nil {
+				buf.WriteString(hyperlinks.End())
+			}
+			if link != nil {
+				buf.WriteString(hyperlinks.Start(link.URL))
+			}
+			prev_link = link
+		}
+		sgr := ""
+		a := self.attrs[i]
+		if a&BOLD != 0 {
+			sgr += ";1"
+		}
+		if a&UNDERLINE != 0 {
+			sgr += ";4"
+		}
+		if link != nil && link == focused {
+			sgr += ";7"
+		}
+		for _, h := range highlights {
+			if h.start <= i && i < h.end {
+				if h.current {
+					sgr += ";30;43"
+				} else {
+					sgr += ";7"
+				}
+				break
+			}
+		}
+		if sgr != prev_sgr {
+			buf.WriteString("\x1b[" + sgr + "m")
+			prev_sgr = sgr
+		}
+		buf.WriteRune(ch)
+	}
+	if prev_link != nil {
+		buf.WriteString(hyperlinks.End())
+	}
+	if prev_sgr != "" {
+		buf.WriteString("\x1b[m")
+	}
+	return buf.String()
+}
@@ -0,0 +1,79 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package man
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"kitty/tools/tui"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestManRendering(t *testing.T) {
+	bold := func(x string) string {
+		ans := ""
+		for _, ch := range x {
+			ans += string(ch) + "\b" + string(ch)
+		}
+		return ans
+	}
+	underline := func(x string) string {
+		ans := ""
+		for _, ch := range x {
+			ans += "_\b" + string(ch)
+		}
+		return ans
+	}
+	raw := strings.Join([]string{
+		"LS(1)                User Commands               LS(1)",
+		"",
+		bold("NAME"),
+		"       ls - list directory contents",
+		"",
+		bold("DESCRIPTION"),
+		"       List  " + underline("FILE") + "s, see " + bold("dir") + "(1) and https://example.com/ls.",
+		"",
+		"   " + bold("Sub section"),
+		"       \x1b[1mbold\x1b[m \x1b[4msgr\x1b[m",
+		"",
+		"GNU coreutils 9.1            2023              LS(1)",
+		"", "",
+	}, "\n")
+	p := ParseFormatted(raw)
+	if len(p.Lines) != 12 {
+		t.Fatalf("Incorrect number of lines: %d", len(p.Lines))
+	}
+	if diff := cmp.Diff([]Section{{Title: "NAME", Line: 2}, {Title: "DESCRIPTION", Line: 5}, {Title: "Sub section", Line: 8, Subsection: true}}, p.Sections); diff != "" {
+		t.Fatalf("Incorrect sections:\n%s", diff)
+	}
+	l := p.Lines[6]
+	if diff := cmp.Diff("       List  FILEs, see dir(1) and https://example.com/ls.", l.Text); diff != "" {
+		t.Fatalf("Incorrect text:\n%s", diff)
+	}
+	if diff := cmp.Diff([]Link{
+		{Start: 35, End: 57, URL: "https://example.com/ls"},
+		{Start: 24, End: 30, URL: "man:dir(1)", Name: "dir", Section: "1"},
+	}, l.Links); diff != "" {
+		t.Fatalf("Incorrect links:\n%s", diff)
+	}
+	if l.attrs[13] != UNDERLINE || l.attrs[17] != 0 || l.attrs[24] != BOLD {
+		t.Fatalf("Incorrect formatting: %v", l.attrs)
+	}
+	if diff := cmp.Diff("       bold sgr", p.Lines[9].Text); diff != "" {
+		t.Fatalf("SGR codes not removed:\n%s", diff)
+	}
+	if a := p.Lines[9].attrs; a[7] != BOLD || a[11] != 0 || a[12] != UNDERLINE {
+		t.Fatalf("Incorrect SGR formatting: %v", a)
+	}
+	hl := tui.NewHyperlinks()
+	hl.Enabled = false
+	actual := parse_line("a "+bold("bc")+" d").render([]highlight{{start: 3, end: 5, current: true}}, hl, nil)
+	if diff := cmp.Diff("a \x1b[;1mb\x1b[;1;30;43mc\x1b[;30;43m \x1b[md", actual); diff != "" {
+		t.Fatalf("Incorrect rendering:\n%s", diff)
+	}
+}
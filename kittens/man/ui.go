@@ -0,0 +1,552 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package man
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kitty/tools/tui"
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type State int
+
+const (
+	VIEWING State = iota
+	SEARCHING
+	SHOWING_SECTIONS
+)
+
+type match struct {
+	line, start, end int
+}
+
+type page_state struct {
+	args  []string
+	page  *Page
+	top   int
+	width int
+	// the focused link as a line number and index into the links of that line
+	focused_line, focused_link int
+}
+
+func (self *page_state) title() string {
+	if len(self.args) == 2 {
+		return fmt.Sprintf("%s(%s)", self.args[1], self.args[0])
+	}
+	return strings.Join(self.args, " ")
+}
+
+func (self *page_state) focused() *Link {
+	if self.focused_line < 0 || self.focused_line >= len(self.page.Lines) {
+		return nil
+	}
+	links := self.page.Lines[self.focused_line].Links
+	if self.focused_link < 0 || self.focused_link >= len(links) {
+		return nil
+	}
+	return &links[self.focused_link]
+}
+
+type handler struct {
+	lp         *loop.Loop
+	hyperlinks *tui.Hyperlinks
+
+	current *page_state
+	history []*page_state
+
+	state         State
+	query         string
+	search        *regexp.Regexp
+	matches       []match
+	current_match int
+	section_idx   int
+	status        string
+	status_err    bool
+}
+
+func (self *handler) set_status(err bool, format string, args ...any) {
+	self.status, self.status_err = fmt.Sprintf(format, args...), err
+}
+
+func (self *handler) screen_size() (width, height int) {
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return 80, 24
+	}
+	return int(sz.WidthCells), int(sz.HeightCells)
+}
+
+func (self *handler) num_rows() int {
+	_, height := self.screen_size()
+	return max(1, height-1)
+}
+
+func (self *handler) format(args []string) (*page_state, error) {
+	width, _ := self.screen_size()
+	raw, err := FormatPage(args, width)
+	if err != nil {
+		return nil, err
+	}
+	return &page_state{args: args, page: ParseFormatted(raw), width: width, focused_line: -1}, nil
+}
+
+// Open a page, keeping the current page in the history
+func (self *handler) open(args []string) {
+	ps, err := self.format(args)
+	if err != nil {
+		self.set_status(true, "%s", err)
+		return
+	}
+	if self.current != nil {
+		self.history = append(self.history, self.current)
+	}
+	self.current = ps
+	self.lp.SetWindowTitle(ps.title())
+	self.update_matches()
+}
+
+func (self *handler) go_back() {
+	if len(self.history) == 0 {
+		return
+	}
+	self.current = self.history[len(self.history)-1]
+	self.history = self.history[:len(self.history)-1]
+	self.lp.SetWindowTitle(self.current.title())
+	if width, _ := self.screen_size(); width != self.current.width {
+		self.reformat()
+	}
+	self.update_matches()
+}
+
+// Re-format the current page for a new screen width, keeping the
+// position in the page approximately the same
+func (self *handler) reformat() {
+	ps, err := self.format(self.current.args)
+	if err != nil {
+		self.set_status(true, "%s", err)
+		return
+	}
+	if n := len(self.current.page.Lines); n > 0 {
+		ps.top = self.current.top * len(ps.page.Lines) / n
+	}
+	self.current = ps
+	self.update_matches()
+}
+
+func (self *handler) scroll_to(top int) {
+	ps := self.current
+	rows := self.num_rows()
+	ps.top = max(0, min(top, len(ps.page.Lines)-rows))
+	if ps.focused_line < ps.top || ps.focused_line >= ps.top+rows {
+		// the focused link is no longer visible
+		ps.focused_line = -1
+	}
+}
+
+func (self *handler) update_matches() {
+	self.matches = self.matches[:0]
+	self.current_match = -1
+	if self.search == nil {
+		return
+	}
+	for i, l := range self.current.page.Lines {
+		for _, m := range self.search.FindAllStringIndex(l.Text, -1) {
+			if m[1] > m[0] {
+				self.matches = append(self.matches, match{line: i, start: l.rune_offset(m[0]), end: l.rune_offset(m[1])})
+			}
+		}
+	}
+}
+
+// Move to the next match after the top of the screen or the current match
+func (self *handler) next_match(backwards bool) {
+	if len(self.matches) == 0 {
+		if self.search != nil {
+			self.set_status(true, "Pattern not found: %s", self.search)
+		}
+		return
+	}
+	if self.current_match < 0 {
+		self.current_match = len(self.matches) - 1
+		if backwards {
+			self.current_match = 0
+		}
+		for i, m := range self.matches {
+			if m.line >= self.current.top {
+				self.current_match = i - 1
+				if backwards {
+					self.current_match = i
+				}
+				break
+			}
+		}
+	}
+	delta := 1
+	if backwards {
+		delta = -1
+	}
+	self.current_match = (self.current_match + delta + len(self.matches)) % len(self.matches)
+	m := self.matches[self.current_match]
+	if rows := self.num_rows(); m.line < self.current.top || m.line >= self.current.top+rows {
+		self.scroll_to(m.line - rows/3)
+	}
+	self.set_status(false, "Match %d of %d", self.current_match+1, len(self.matches))
+}
+
+func (self *handler) start_search(query string) {
+	self.search = nil
+	if query == "" {
+		self.update_matches()
+		return
+	}
+	if strings.ToLower(query) == query {
+		query = "(?i)" + query
+	}
+	pat, err := regexp.Compile(query)
+	if err != nil {
+		self.set_status(true, "Invalid regular expression: %s", err)
+		return
+	}
+	self.search = pat
+	self.update_matches()
+	self.next_match(false)
+}
+
+// Move focus to the next link on the screen, starting from the top of the
+// screen if no visible link is focused
+func (self *handler) focus_link(backwards bool) {
+	ps := self.current
+	rows := self.num_rows()
+	type pos struct{ line, link int }
+	var links []pos
+	for i := ps.top; i < min(len(ps.page.Lines), ps.top+rows); i++ {
+		for j := range ps.page.Lines[i].Links {
+			links = append(links, pos{i, j})
+		}
+	}
+	if len(links) == 0 {
+		self.set_status(true, "No links on screen")
+		return
+	}
+	idx := -1
+	for i, p := range links {
+		if p.line == ps.focused_line && p.link == ps.focused_link {
+			idx = i
+		}
+	}
+	switch {
+	case idx < 0 && backwards:
+		idx = len(links) - 1
+	case idx < 0:
+		idx = 0
+	case backwards:
+		idx = (idx - 1 + len(links)) % len(links)
+	default:
+		idx = (idx + 1) % len(links)
+	}
+	ps.focused_line, ps.focused_link = links[idx].line, links[idx].link
+	self.set_status(false, "%s", ps.focused().URL)
+}
+
+func (self *handler) follow(link *Link) {
+	if link.Name != "" {
+		self.open([]string{link.Section, link.Name})
+		return
+	}
+	if err := utils.OpenWithRules(link.URL); err != nil {
+		self.set_status(true, "Failed to open %s with error: %s", link.URL, err)
+	}
+}
+
+func (self *handler) current_section() int {
+	ans := 0
+	for i, s := range self.current.page.Sections {
+		if s.Line <= self.current.top {
+			ans = i
+		}
+	}
+	return ans
+}
+
+func (self *handler) jump_to_section(delta int) {
+	sections := self.current.page.Sections
+	if len(sections) == 0 {
+		return
+	}
+	top := self.current.top
+	if delta > 0 {
+		for _, s := range sections {
+			if s.Line > top {
+				self.scroll_to(s.Line)
+				return
+			}
+		}
+	} else {
+		for i := len(sections) - 1; i >= 0; i-- {
+			if sections[i].Line < top {
+				self.scroll_to(sections[i].Line)
+				return
+			}
+		}
+		self.scroll_to(0)
+	}
+}
+
+func (self *handler) draw_page(width, rows int) {
+	ps := self.current
+	focused := ps.focused()
+	for row := 0; row < rows; row++ {
+		i := ps.top + row
+		if i >= len(ps.page.Lines) {
+			break
+		}
+		l := ps.page.Lines[i]
+		var hl []highlight
+		for mi, m := range self.matches {
+			if m.line == i {
+				hl = append(hl, highlight{m.start, m.end, mi == self.current_match})
+			}
+		}
+		self.lp.MoveCursorTo(1, row+1)
+		self.lp.QueueWriteString(l.render(hl, self.hyperlinks, focused))
+		for j := range l.Links {
+			link := &l.Links[j]
+			left := wcswidth.Stringwidth(string(l.runes[:link.Start]))
+			self.lp.AddMouseRegion(loop.MouseRegion{
+				Left: left, Top: row, Height: 1, Width: wcswidth.Stringwidth(string(l.runes[link.Start:link.End])),
+				OnClick: func(ev *loop.MouseEvent) error {
+					self.follow(link)
+					return self.draw_screen()
+				},
+			})
+		}
+	}
+}
+
+func (self *handler) draw_sections(width, rows int) {
+	sections := self.current.page.Sections
+	self.section_idx = max(0, min(self.section_idx, len(sections)-1))
+	first := max(0, self.section_idx-rows+1)
+	for row := 0; row < rows && first+row < len(sections); row++ {
+		s := sections[first+row]
+		text := s.Title
+		if s.Subsection {
+			text = "    " + text
+		}
+		text = wcswidth.TruncateToVisualLength(text, width)
+		if first+row == self.section_idx {
+			text = self.lp.SprintStyled("reverse", text+strings.Repeat(" ", max(0, width-wcswidth.Stringwidth(text))))
+		}
+		self.lp.MoveCursorTo(1, row+1)
+		self.lp.QueueWriteString(text)
+	}
+}
+
+func (self *handler) draw_screen() error {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	self.lp.ClearMouseRegions()
+	width, height := self.screen_size()
+	rows := max(1, height-1)
+	if self.state == SHOWING_SECTIONS {
+		self.draw_sections(width, rows)
+	} else {
+		self.draw_page(width, rows)
+	}
+	self.lp.MoveCursorTo(1, height)
+	var footer string
+	switch self.state {
+	case SEARCHING:
+		footer = "/" + self.query
+	case SHOWING_SECTIONS:
+		footer = "[Enter] Go to section  [Esc] Back"
+	default:
+		if self.status != "" {
+			footer = self.status
+			if self.status_err {
+				footer = self.lp.SprintStyled("fg=red", footer)
+			}
+		} else {
+			ps := self.current
+			pos := 100
+			if n := len(ps.page.Lines); n > rows {
+				pos = min(100, (ps.top+rows)*100/n)
+			}
+			footer = fmt.Sprintf("%s %d%%  [/] Search  [Tab] Links  [t] Sections", ps.title(), pos)
+			if len(self.history) > 0 {
+				footer += "  [b] Back"
+			}
+			footer = self.lp.SprintStyled("dim", wcswidth.TruncateToVisualLength(footer, width))
+		}
+	}
+	self.lp.QueueWriteString(footer)
+	self.lp.SetCursorVisible(self.state == SEARCHING)
+	return nil
+}
+
+func (self *handler) on_key_event(ev *loop.KeyEvent) error {
+	ev.Handled = true
+	switch self.state {
+	case SEARCHING:
+		switch {
+		case ev.MatchesPressOrRepeat("esc"):
+			self.state = VIEWING
+		case ev.MatchesPressOrRepeat("enter"):
+			self.state = VIEWING
+			self.start_search(self.query)
+		case ev.MatchesPressOrRepeat("backspace"):
+			if r := []rune(self.query); len(r) > 0 {
+				self.query = string(r[:len(r)-1])
+			} else {
+				self.state = VIEWING
+			}
+		default:
+			ev.Handled = false
+			return nil
+		}
+		return self.draw_screen()
+	case SHOWING_SECTIONS:
+		switch {
+		case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("q") || ev.MatchesPressOrRepeat("t"):
+			self.state = VIEWING
+		case ev.MatchesPressOrRepeat("up") || ev.MatchesPressOrRepeat("k"):
+			self.section_idx--
+		case ev.MatchesPressOrRepeat("down") || ev.MatchesPressOrRepeat("j"):
+			self.section_idx++
+		case ev.MatchesPressOrRepeat("enter"):
+			if sections := self.current.page.Sections; self.section_idx < len(sections) {
+				self.scroll_to(sections[self.section_idx].Line)
+			}
+			self.state = VIEWING
+		default:
+			ev.Handled = false
+			return nil
+		}
+		return self.draw_screen()
+	}
+	self.status = ""
+	ps := self.current
+	rows := self.num_rows()
+	switch {
+	case ev.MatchesPressOrRepeat("q") || ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c"):
+		self.lp.Quit(0)
+		return nil
+	case ev.MatchesPressOrRepeat("up") || ev.MatchesPressOrRepeat("k"):
+		self.scroll_to(ps.top - 1)
+	case ev.MatchesPressOrRepeat("down") || ev.MatchesPressOrRepeat("j"):
+		self.scroll_to(ps.top + 1)
+	case ev.MatchesPressOrRepeat("page_up") || ev.MatchesPressOrRepeat("shift+space"):
+		self.scroll_to(ps.top - rows)
+	case ev.MatchesPressOrRepeat("page_down") || ev.MatchesPressOrRepeat("space"):
+		self.scroll_to(ps.top + rows)
+	case ev.MatchesPressOrRepeat("ctrl+u"):
+		self.scroll_to(ps.top - rows/2)
+	case ev.MatchesPressOrRepeat("ctrl+d"):
+		self.scroll_to(ps.top + rows/2)
+	case ev.MatchesPressOrRepeat("home") || ev.MatchesPressOrRepeat("g"):
+		self.scroll_to(0)
+	case ev.MatchesPressOrRepeat("end") || ev.MatchesPressOrRepeat("shift+g"):
+		self.scroll_to(len(ps.page.Lines))
+	case ev.MatchesPressOrRepeat("]"):
+		self.jump_to_section(1)
+	case ev.MatchesPressOrRepeat("["):
+		self.jump_to_section(-1)
+	case ev.MatchesPressOrRepeat("t"):
+		if len(ps.page.Sections) > 0 {
+			self.section_idx = self.current_section()
+			self.state = SHOWING_SECTIONS
+		}
+	case ev.MatchesPressOrRepeat("/"):
+		self.query = ""
+		self.state = SEARCHING
+	case ev.MatchesPressOrRepeat("n"):
+		self.next_match(false)
+	case ev.MatchesPressOrRepeat("shift+n"):
+		self.next_match(true)
+	case ev.MatchesPressOrRepeat("tab"):
+		self.focus_link(false)
+	case ev.MatchesPressOrRepeat("shift+tab"):
+		self.focus_link(true)
+	case ev.MatchesPressOrRepeat("enter"):
+		if link := ps.focused(); link != nil {
+			self.follow(link)
+		} else {
+			self.scroll_to(ps.top + 1)
+		}
+	case ev.MatchesPressOrRepeat("b") || ev.MatchesPressOrRepeat("backspace"):
+		self.go_back()
+	default:
+		ev.Handled = false
+		return nil
+	}
+	return self.draw_screen()
+}
+
+func (self *handler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
+	if self.state == SEARCHING {
+		self.query += text
+		return self.draw_screen()
+	}
+	return nil
+}
+
+func (self *handler) on_mouse_event(ev *loop.MouseEvent) error {
+	if ev.Event_type == loop.MOUSE_PRESS && self.state == VIEWING {
+		switch {
+		case ev.Buttons&loop.MOUSE_WHEEL_UP != 0:
+			self.scroll_to(self.current.top - 3)
+		case ev.Buttons&loop.MOUSE_WHEEL_DOWN != 0:
+			self.scroll_to(self.current.top + 3)
+		default:
+			return nil
+		}
+		return self.draw_screen()
+	}
+	return nil
+}
+
+func run_ui(args []string, search string) (rc int, err error) {
+	lp, err := loop.New()
+	if err != nil {
+		return 1, err
+	}
+	h := &handler{lp: lp, hyperlinks: tui.NewHyperlinks(), current_match: -1}
+	lp.OnInitialize = func() (string, error) {
+		lp.AllowLineWrapping(false)
+		h.open(args)
+		if h.current == nil {
+			return "", fmt.Errorf("%s", h.status)
+		}
+		h.start_search(search)
+		return "", h.draw_screen()
+	}
+	lp.OnFinalize = func() string {
+		lp.SetCursorVisible(true)
+		return ""
+	}
+	lp.OnResize = func(_, _ loop.ScreenSize) error {
+		h.reformat()
+		return h.draw_screen()
+	}
+	lp.OnKeyEvent = h.on_key_event
+	lp.OnText = h.on_text
+	lp.OnMouseEvent = h.on_mouse_event
+	if err = lp.Run(); err != nil {
+		return 1, err
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return 1, nil
+	}
+	return lp.ExitCode(), nil
+}
@@ -1,6 +1,7 @@
 #!/usr/bin/env python
 # License: GPLv3 Copyright: 2020, Kovid Goyal <kovid at kovidgoyal.net>
 
+import json
 import re
 import sys
 from binascii import hexlify, unhexlify
@@ -50,7 +51,7 @@ def output_line(self) -> str:
         return self.ans
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         raise NotImplementedError()
 
 
@@ -69,7 +70,7 @@ class TerminalName(Query):
     help_text: str = f'Terminal name (e.g. :code:`{names[0]}`)'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         return appname
 
 
@@ -79,7 +80,7 @@ class TerminalVersion(Query):
     help_text: str = f'Terminal version (e.g. :code:`{str_version}`)'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         return str_version
 
 
@@ -89,7 +90,7 @@ class AllowHyperlinks(Query):
     help_text: str = 'The config option :opt:`allow_hyperlinks` in :file:`kitty.conf` for allowing hyperlinks can be :code:`yes`, :code:`no` or :code:`ask`'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         return 'ask' if opts.allow_hyperlinks == 0b11 else ('yes' if opts.allow_hyperlinks else 'no')
 
 
@@ -99,7 +100,7 @@ class FontFamily(Query):
     help_text: str = 'The current font\'s PostScript name'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         from kitty.fast_data_types import current_fonts
         cf = current_fonts()
         return str(cf['medium'].display_name())
@@ -111,7 +112,7 @@ class BoldFont(Query):
     help_text: str = 'The current bold font\'s PostScript name'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         from kitty.fast_data_types import current_fonts
         cf = current_fonts()
         return str(cf['bold'].display_name())
@@ -123,7 +124,7 @@ class ItalicFont(Query):
     help_text: str = 'The current italic font\'s PostScript name'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         from kitty.fast_data_types import current_fonts
         cf = current_fonts()
         return str(cf['italic'].display_name())
@@ -135,7 +136,7 @@ class BiFont(Query):
     help_text: str = 'The current bold-italic font\'s PostScript name'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         from kitty.fast_data_types import current_fonts
         cf = current_fonts()
         return str(cf['bi'].display_name())
@@ -147,26 +148,117 @@ class FontSize(Query):
     help_text: str = 'The current overall font size (individual windows can have different per window font sizes)'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         return f'{opts.font_size:g}'
 
 
+@query
+class OSWindowFontSize(Query):
+    name: str = 'os_window_font_size'
+    help_text: str = 'The current font size of the OS window this kitten is running in, in pts'
+
+    @staticmethod
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
+        from kitty.fast_data_types import os_window_font_size
+        return f'{os_window_font_size(os_window_id):g}' if os_window_id else f'{opts.font_size:g}'
+
+
+@query
+class CellSize(Query):
+    name: str = 'cell_size'
+    help_text: str = 'The size of a single cell in pixels, as :code:`widthxheight`'
+
+    @staticmethod
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
+        from kitty.fast_data_types import cell_size_for_window
+        w, h = cell_size_for_window(os_window_id)
+        return f'{w}x{h}'
+
+
+@query
+class BackgroundOpacity(Query):
+    name: str = 'background_opacity'
+    help_text: str = 'The current background opacity, a number between zero and one'
+
+    @staticmethod
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
+        from kitty.fast_data_types import background_opacity_of
+        ans = background_opacity_of(os_window_id) if os_window_id else None
+        return f'{opts.background_opacity if ans is None else ans:g}'
+
+
+@query
+class ColorScheme(Query):
+    name: str = 'color_scheme'
+    help_text: str = 'Either :code:`dark` or :code:`light` based on the current background color of the window'
+
+    @staticmethod
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
+        from kitty.fast_data_types import get_boss
+        bg = int(opts.background)
+        w = get_boss().window_id_map.get(window_id) if window_id else None
+        if w is not None:
+            bg = w.screen.color_profile.default_bg
+        r, g, b = (bg >> 16) & 0xff, (bg >> 8) & 0xff, bg & 0xff
+        return 'dark' if 0.299 * r + 0.587 * g + 0.114 * b < 128 else 'light'
+
+
 @query
 class ClipboardControl(Query):
     name: str = 'clipboard_control'
     help_text: str = 'The config option :opt:`clipboard_control` in :file:`kitty.conf` for allowing reads/writes to/from the clipboard'
 
     @staticmethod
-    def get_result(opts: Options) -> str:
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
         return ' '.join(opts.clipboard_control)
 
 
-def get_result(name: str) -> Optional[str]:
+@query
+class Clipboard(Query):
+    name: str = 'clipboard'
+    help_text: str = (
+        'The permitted clipboard operations via OSC 52, for example: :code:`clipboard=read,write primary=write`.'
+        ' :code:`ask` means reads require confirmation by the user.')
+
+    @staticmethod
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
+        ans = []
+        for which in ('clipboard', 'primary'):
+            ops = []
+            if f'read-{which}' in opts.clipboard_control:
+                ops.append('read')
+            elif f'read-{which}-ask' in opts.clipboard_control:
+                ops.append('ask')
+            if f'write-{which}' in opts.clipboard_control:
+                ops.append('write')
+            ans.append(f'{which}={",".join(ops)}')
+        return ' '.join(ans)
+
+
+@query
+class GraphicsLimits(Query):
+    name: str = 'graphics_limits'
+    help_text: str = (
+        'Limits of the graphics protocol, as :code:`max_image_dimension=pixels storage_limit=bytes max_data_size=bytes`.'
+        ' Images whose width or height exceeds the maximum dimension are rejected and when the storage used by images exceeds'
+        ' the limit, the oldest images are deleted.')
+
+    @staticmethod
+    def get_result(opts: Options, window_id: int, os_window_id: int) -> str:
+        # These must be kept in sync with the values in graphics.c
+        return f'max_image_dimension=10000 storage_limit={320 * 1024 * 1024} max_data_size={4 * 100000000}'
+
+
+def get_result(name: str, window_id: int = 0, os_window_id: int = 0) -> Optional[str]:
     from kitty.fast_data_types import get_options
     q = all_queries.get(name)
     if q is None:
         return None
-    return q.get_result(get_options())
+    return q.get_result(get_options(), window_id, os_window_id)
+
+
+class TimedOut(Exception):
+    pass
 
 
 def do_queries(queries: Iterable[str], cli_opts: QueryTerminalCLIOptions) -> Dict[str, str]:
@@ -174,9 +266,10 @@ def do_queries(queries: Iterable[str], cli_opts: QueryTerminalCLIOptions) -> Dic
     qstring = ''.join(a.query_code() for a in actions)
     received = b''
     pat = re.compile(rb'\x1b\[\?.+?c')
+    has_da1_response = False
 
     def more_needed(data: bytes) -> bool:
-        nonlocal received
+        nonlocal received, has_da1_response
         received += data
         has_da1_response = pat.search(received) is not None
         if has_da1_response:
@@ -190,6 +283,8 @@ def more_needed(data: bytes) -> bool:
         ttyio.send(qstring)
         ttyio.send('\x1b[c')  # DA1 query https://vt100.net/docs/vt510-rm/DA1.html
         ttyio.recv(more_needed, timeout=cli_opts.wait_for)
+    if not has_da1_response:
+        raise TimedOut(f'The terminal did not respond within {cli_opts.wait_for:g} seconds')
 
     return {a.name: a.output_line() for a in actions}
 
@@ -200,7 +295,16 @@ def options_spec() -> str:
 type=float
 default=10
 The amount of time (in seconds) to wait for a response from the terminal, after
-querying it.
+querying it. If the terminal does not respond in this time, the kitten exits
+with a non-zero exit code.
+
+
+--output-format
+choices=text,json
+default=text
+The format in which to output the results. :code:`json` outputs a single JSON
+object mapping query names to the results, with :code:`null` for unsupported
+queries, suitable for use in scripts.
 '''
 
 
@@ -215,6 +319,9 @@ def options_spec() -> str:
 
     query: data
 
+Use :code:`--output-format=json` for output that is easier to parse from
+scripts.
+
 If a particular :italic:`query` is unsupported by the running kitty version, the
 :italic:`data` will be blank.
 
@@ -247,8 +354,15 @@ def main(args: List[str] = sys.argv) -> None:
         if extra:
             raise SystemExit(f'Unknown queries: {", ".join(extra)}')
 
-    for key, val in do_queries(queries, cli_opts).items():
-        print(f'{key}:', val)
+    try:
+        results = do_queries(queries, cli_opts)
+    except TimedOut as e:
+        raise SystemExit(str(e))
+    if cli_opts.output_format == 'json':
+        print(json.dumps({k: (v or None) for k, v in results.items()}, indent=2, sort_keys=True))
+    else:
+        for key, val in results.items():
+            print(f'{key}:', val)
 
 
 if __name__ == '__main__':
@@ -18,6 +18,7 @@
 from kitty.typing import BossType
 from kitty.utils import SSHConnectionData, command_for_open, get_editor, open_cmd
 
+from ..ssh.utils import copy_over_ssh, scp_args_from_ssh_args
 from ..tui.handler import result_handler
 from ..tui.operations import faint, raw_mode, reset_terminal, styled
 from ..tui.utils import get_key_press
@@ -128,6 +129,7 @@ def __init__(self, conn_data: SSHConnectionData, remote_path: str, cli_opts: Rem
             if conn_data.identity_file:
                 cmd.extend(['-i', conn_data.identity_file])
             self.batch_cmd_prefix = cmd + ['-o', 'BatchMode=yes']
+        self.scp_args = scp_args_from_ssh_args(self.batch_cmd_prefix[1:])
 
     def check_call(self, cmd: List[str]) -> None:
         p = subprocess.Popen(cmd, stdout=subprocess.PIPE, stderr=subprocess.STDOUT, stdin=subprocess.DEVNULL)
@@ -200,13 +202,24 @@ def show_error(self, msg: str) -> None:
             self.last_error_log = ''
         show_error(msg)
 
+    def copy_with_fallback(self, upload: bool) -> bool:
+        # The remote account may not have a shell that can run cat, for
+        # example, sftp only accounts, so fallback to scp/sftp over the
+        # existing connection
+        errors = copy_over_ssh(self.scp_args, self.conn_data.hostname, self.remote_path, self.dest, upload=upload)
+        if errors:
+            self.last_error_log += '\n' + errors
+            return False
+        self.last_error_log = ''
+        return True
+
     def download(self) -> bool:
         cmdline = self.batch_cmd_prefix + [self.conn_data.hostname, 'cat', shlex.quote(self.remote_path)]
         with open(self.dest, 'wb') as f:
             cp = subprocess.run(cmdline, stdout=f, stderr=subprocess.PIPE, stdin=subprocess.DEVNULL)
-            if cp.returncode != 0:
-                self.last_error_log = f'The command: {shlex.join(cmdline)} failed\n' + cp.stderr.decode()
-                return False
+        if cp.returncode != 0:
+            self.last_error_log = f'The command: {shlex.join(cmdline)} failed\n' + cp.stderr.decode()
+            return self.copy_with_fallback(upload=False)
         return True
 
     def upload(self, suppress_output: bool = True) -> bool:
@@ -220,9 +233,9 @@ def upload(self, suppress_output: bool = True) -> bool:
                 if cp.returncode == 0:
                     return True
                 self.last_error_log = f'The command: {shlex.join(cmd)} failed\n' + cp.stdout.decode()
-            else:
-                return subprocess.run(cmd, stdin=f).returncode == 0
-        return False
+            elif subprocess.run(cmd, stdin=f).returncode == 0:
+                return True
+        return self.copy_with_fallback(upload=True)
 
 
 Result = Optional[str]
@@ -67,7 +67,11 @@ def create_kitten_handler(kitten: str, orig_args: List[str]) -> Any:
     kitten = resolved_kitten(kitten)
     m = import_kitten_main_module(config_dir, kitten)
     ans = partial(m['end'], [kitten] + orig_args)
-    setattr(ans, 'type_of_input', getattr(m['end'], 'type_of_input', None))
+    type_of_input = getattr(m['end'], 'type_of_input', None)
+    if callable(type_of_input):
+        # the type of input depends on the command line arguments
+        type_of_input = type_of_input(orig_args)
+    setattr(ans, 'type_of_input', type_of_input)
     setattr(ans, 'no_ui', getattr(m['end'], 'no_ui', False))
     setattr(ans, 'has_ready_notification', getattr(m['end'], 'has_ready_notification', False))
     return ans
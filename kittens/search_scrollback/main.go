@@ -0,0 +1,68 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package search_scrollback
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kitty/tools/cli"
+	"kitty/tools/tui"
+)
+
+var _ = fmt.Print
+
+func run_rc(args ...string) (string, error) {
+	c := tui.RemoteControlCommand(args...)
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	c.Stdout, c.Stderr = &stdout, &stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("Running kitten @ %s failed with error: %w and stderr: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func fetch_scrollback(match string) ([]Line, error) {
+	text, err := run_rc("get-text", "--match", match, "--extent", "all", "--add-wrap-markers")
+	if err != nil {
+		return nil, err
+	}
+	return SplitLines(text), nil
+}
+
+// Scroll the window so that the chosen match is near the top of the screen
+func scroll_to(match string, line *Line, m *Match, num_rows int) error {
+	if _, err := run_rc("scroll-window", "--match", match, "start"); err != nil {
+		return err
+	}
+	if amt := ScrollAmount(line.ScreenLineAt(m.Start), num_rows); amt > 0 {
+		if _, err := run_rc("scroll-window", "--match", match, strconv.Itoa(amt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
+	lines, err := fetch_scrollback(opts.Match)
+	if err != nil {
+		return 1, err
+	}
+	m, num_rows, err := run_ui(lines, strings.Join(args, " "), max(0, opts.Context))
+	if err != nil {
+		return 1, err
+	}
+	if m == nil {
+		return 0, nil
+	}
+	if err = scroll_to(opts.Match, &lines[m.Line], m, num_rows); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func EntryPoint(parent *cli.Command) {
+	create_cmd(parent, main)
+}
@@ -0,0 +1,45 @@
+#!/usr/bin/env python
+# License: GPLv3 Copyright: 2023, Kovid Goyal <kovid at kovidgoyal.net>
+
+
+import sys
+from typing import List
+
+OPTIONS = r'''
+--match -m
+default=state:overlay_parent
+The window whose scrollback is searched and scrolled to the chosen match. See
+:ref:`search_syntax` for the syntax for matching windows. Defaults to the window
+this kitten is running as an overlay over.
+
+
+--context
+type=int
+default=2
+The number of lines of context to show above and below the currently selected
+match. Use zero to not show any context.
+'''.format
+help_text = '''\
+Incrementally search the scrollback of a window using regular expressions.
+Choosing a match scrolls the window so that the match is visible. The search is
+case insensitive unless the query contains upper case letters. This kitten uses
+remote control, so it must be launched with remote control allowed, for example,
+with the mapping::
+
+    map kitty_mod+/ launch --type=overlay --allow-remote-control kitten search_scrollback
+'''
+usage = '[query]'
+
+
+def main(args: List[str]) -> None:
+    raise SystemExit('This should be run as kitten search_scrollback')
+
+
+if __name__ == '__main__':
+    main(sys.argv)
+elif __name__ == '__doc__':
+    cd = sys.cli_docs  # type: ignore
+    cd['usage'] = usage
+    cd['options'] = OPTIONS
+    cd['help_text'] = help_text
+    cd['short_desc'] = 'Search the scrollback of a window'
@@ -0,0 +1,92 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package search_scrollback
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var _ = fmt.Print
+
+// A logical line of text from the scrollback, long lines are wrapped over
+// multiple lines on the screen
+type Line struct {
+	Text string
+	// The line on the screen, counting from the top of the scrollback, this
+	// line starts on
+	ScreenLine int
+	// Byte offsets into Text at which the line wraps
+	wraps []int
+}
+
+// The line on the screen the specified byte offset into this line is on
+func (self *Line) ScreenLineAt(offset int) int {
+	ans := self.ScreenLine
+	for _, w := range self.wraps {
+		if w > offset {
+			break
+		}
+		ans++
+	}
+	return ans
+}
+
+// Split the text from kitten @ get-text --add-wrap-markers into lines, the
+// wrap markers are carriage returns
+func SplitLines(text string) []Line {
+	text = strings.TrimSuffix(text, "\n")
+	raw := strings.Split(text, "\n")
+	ans := make([]Line, len(raw))
+	screen_line := 0
+	for i, x := range raw {
+		l := Line{ScreenLine: screen_line}
+		screen_line++
+		if strings.Contains(x, "\r") {
+			parts := strings.Split(x, "\r")
+			offset := 0
+			for _, p := range parts[:len(parts)-1] {
+				offset += len(p)
+				l.wraps = append(l.wraps, offset)
+				screen_line++
+			}
+			x = strings.Join(parts, "")
+		}
+		l.Text = x
+		ans[i] = l
+	}
+	return ans
+}
+
+type Match struct {
+	// Index into the lines and byte offsets into the text of the line
+	Line, Start, End int
+}
+
+// Compile a search query, it is case insensitive unless it contains upper
+// case letters
+func CompileQuery(query string) (*regexp.Regexp, error) {
+	if strings.ToLower(query) == query {
+		query = "(?i)" + query
+	}
+	return regexp.Compile(query)
+}
+
+// Find all non-empty matches for pat in lines, in order
+func Search(lines []Line, pat *regexp.Regexp) (ans []Match) {
+	for i, l := range lines {
+		for _, m := range pat.FindAllStringIndex(l.Text, -1) {
+			if m[1] > m[0] {
+				ans = append(ans, Match{Line: i, Start: m[0], End: m[1]})
+			}
+		}
+	}
+	return
+}
+
+// The number of lines to scroll down by from the top of the scrollback, so
+// that screen_line is a third of the way down a screen with num_rows lines
+func ScrollAmount(screen_line, num_rows int) int {
+	return max(0, screen_line-num_rows/3)
+}
@@ -0,0 +1,50 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package search_scrollback
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestSearchScrollback(t *testing.T) {
+	lines := SplitLines("one\ntwo long\rwrapped\rline\nthree two\n")
+	if diff := cmp.Diff([]string{"one", "two longwrappedline", "three two"}, []string{lines[0].Text, lines[1].Text, lines[2].Text}); diff != "" {
+		t.Fatalf("Lines not split correctly:\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{0, 1, 4}, []int{lines[0].ScreenLine, lines[1].ScreenLine, lines[2].ScreenLine}); diff != "" {
+		t.Fatalf("Incorrect screen lines:\n%s", diff)
+	}
+	for offset, expected := range map[int]int{0: 1, 7: 1, 8: 2, 14: 2, 15: 3, 18: 3} {
+		if actual := lines[1].ScreenLineAt(offset); actual != expected {
+			t.Fatalf("Incorrect screen line for offset %d: %d != %d", offset, actual, expected)
+		}
+	}
+
+	pat, err := CompileQuery("TWO|l.ne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]Match{{1, 15, 19}}, Search(lines, pat)); diff != "" {
+		t.Fatalf("Smartcase query matched incorrectly:\n%s", diff)
+	}
+	pat, _ = CompileQuery("two|l.ne")
+	if diff := cmp.Diff([]Match{{1, 0, 3}, {1, 15, 19}, {2, 6, 9}}, Search(lines, pat)); diff != "" {
+		t.Fatalf("Case insensitive query matched incorrectly:\n%s", diff)
+	}
+	pat, _ = CompileQuery("x*")
+	if m := Search(lines, pat); len(m) != 0 {
+		t.Fatalf("Empty matches not ignored: %v", m)
+	}
+	if _, err = CompileQuery("("); err == nil {
+		t.Fatalf("Invalid query did not fail")
+	}
+
+	if diff := cmp.Diff([]int{0, 0, 90}, []int{ScrollAmount(3, 24), ScrollAmount(8, 24), ScrollAmount(98, 24)}); diff != "" {
+		t.Fatalf("Incorrect scroll amounts:\n%s", diff)
+	}
+}
@@ -0,0 +1,223 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package search_scrollback
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type handler struct {
+	lp      *loop.Loop
+	lines   []Line
+	context int
+
+	query   string
+	matches []Match
+	current int
+	scroll  int
+	status  string
+	chosen  *Match
+}
+
+func (self *handler) update_matches() {
+	self.matches, self.current, self.scroll, self.status = nil, 0, 0, ""
+	if self.query == "" {
+		return
+	}
+	pat, err := CompileQuery(self.query)
+	if err != nil {
+		self.status = err.Error()
+		return
+	}
+	self.matches = Search(self.lines, pat)
+	// select the match closest to the bottom of the scrollback, which is
+	// usually what is being searched for
+	self.current = max(0, len(self.matches)-1)
+}
+
+// Format a line so that the match in it is visible in width cells and highlighted
+func (self *handler) format_match(text string, start, end, width int) string {
+	prefix, match, suffix := text[:start], text[start:end], text[end:]
+	if w := wcswidth.Stringwidth(prefix); w > width/3 {
+		// show some text before the match
+		r := []rune(prefix)
+		for len(r) > 0 && wcswidth.Stringwidth(string(r)) > width/3 {
+			r = r[1:]
+		}
+		prefix = "…" + string(r)
+	}
+	prefix = strings.ReplaceAll(prefix, "\t", " ")
+	mw := wcswidth.Stringwidth(prefix) + wcswidth.Stringwidth(match)
+	suffix = wcswidth.TruncateToVisualLength(strings.ReplaceAll(suffix, "\t", " "), max(0, width-mw))
+	match = wcswidth.TruncateToVisualLength(match, max(1, width-wcswidth.Stringwidth(prefix)))
+	return prefix + self.lp.SprintStyled("fg=yellow bold", match) + suffix
+}
+
+func (self *handler) draw_results(top, num_rows, width int) {
+	if len(self.matches) == 0 {
+		return
+	}
+	if self.current < self.scroll {
+		self.scroll = self.current
+	} else if self.current >= self.scroll+num_rows {
+		self.scroll = self.current - num_rows + 1
+	}
+	nw := len(strconv.Itoa(len(self.lines)))
+	for i := self.scroll; i < min(len(self.matches), self.scroll+num_rows); i++ {
+		m := self.matches[i]
+		self.lp.MoveCursorTo(1, top+i-self.scroll)
+		num := fmt.Sprintf("%*d ", nw, m.Line+1)
+		marker := "  "
+		if i == self.current {
+			marker = self.lp.SprintStyled("fg=green bold", "> ")
+		}
+		self.lp.QueueWriteString(marker + self.lp.SprintStyled("dim", num))
+		self.lp.QueueWriteString(self.format_match(self.lines[m.Line].Text, m.Start, m.End, max(1, width-nw-3)))
+	}
+}
+
+// Show the lines around the current match
+func (self *handler) draw_context(top, num_rows, width int) {
+	if len(self.matches) == 0 {
+		return
+	}
+	m := self.matches[self.current]
+	first := max(0, m.Line-num_rows/2)
+	for i := first; i < min(len(self.lines), first+num_rows); i++ {
+		self.lp.MoveCursorTo(1, top+i-first)
+		text := strings.ReplaceAll(self.lines[i].Text, "\t", " ")
+		if i == m.Line {
+			self.lp.QueueWriteString(self.format_match(self.lines[i].Text, m.Start, m.End, width))
+		} else {
+			self.lp.QueueWriteString(self.lp.SprintStyled("dim", wcswidth.TruncateToVisualLength(text, width)))
+		}
+	}
+}
+
+func (self *handler) draw_screen() error {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return err
+	}
+	width, height := int(sz.WidthCells), int(sz.HeightCells)
+	context_rows := 0
+	if self.context > 0 {
+		context_rows = min(2*self.context+1, max(0, (height-4)/2))
+	}
+	result_rows := max(1, height-3-context_rows-1)
+	self.lp.MoveCursorTo(1, 2)
+	switch {
+	case self.status != "":
+		self.lp.QueueWriteString(self.lp.SprintStyled("fg=red", wcswidth.TruncateToVisualLength(self.status, width)))
+	case self.query == "":
+		self.lp.QueueWriteString(self.lp.SprintStyled("dim", fmt.Sprintf("Type a regular expression to search %d lines, [Enter] to scroll to the match, [Esc] to quit", len(self.lines))))
+	default:
+		msg := "No matches"
+		if len(self.matches) > 0 {
+			msg = fmt.Sprintf("Match %d of %d", self.current+1, len(self.matches))
+		}
+		self.lp.QueueWriteString(self.lp.SprintStyled("dim", msg))
+	}
+	self.draw_results(3, result_rows, width)
+	if context_rows > 0 {
+		self.lp.MoveCursorTo(1, height-context_rows)
+		self.lp.QueueWriteString(self.lp.SprintStyled("dim", strings.Repeat("─", width)))
+		self.draw_context(height-context_rows+1, context_rows, width)
+	}
+	self.lp.MoveCursorTo(1, 1)
+	self.lp.QueueWriteString(self.lp.SprintStyled("bold", "Search: ") + self.query)
+	return nil
+}
+
+func (self *handler) move(delta int) {
+	if len(self.matches) > 0 {
+		self.current = max(0, min(self.current+delta, len(self.matches)-1))
+	}
+}
+
+func (self *handler) on_key_event(ev *loop.KeyEvent) error {
+	ev.Handled = true
+	page := 1
+	if sz, err := self.lp.ScreenSize(); err == nil {
+		page = max(1, int(sz.HeightCells)/2)
+	}
+	switch {
+	case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c"):
+		self.lp.Quit(1)
+		return nil
+	case ev.MatchesPressOrRepeat("enter"):
+		if len(self.matches) > 0 {
+			self.chosen = &self.matches[self.current]
+			self.lp.Quit(0)
+		}
+		return nil
+	case ev.MatchesPressOrRepeat("up") || ev.MatchesPressOrRepeat("ctrl+p") || ev.MatchesPressOrRepeat("shift+tab"):
+		self.move(-1)
+	case ev.MatchesPressOrRepeat("down") || ev.MatchesPressOrRepeat("ctrl+n") || ev.MatchesPressOrRepeat("tab"):
+		self.move(1)
+	case ev.MatchesPressOrRepeat("page_up"):
+		self.move(-page)
+	case ev.MatchesPressOrRepeat("page_down"):
+		self.move(page)
+	case ev.MatchesPressOrRepeat("backspace"):
+		if r := []rune(self.query); len(r) > 0 {
+			self.query = string(r[:len(r)-1])
+			self.update_matches()
+		}
+	case ev.MatchesPressOrRepeat("ctrl+u"):
+		self.query = ""
+		self.update_matches()
+	default:
+		ev.Handled = false
+		return nil
+	}
+	return self.draw_screen()
+}
+
+func (self *handler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
+	self.query += text
+	self.update_matches()
+	return self.draw_screen()
+}
+
+// Run the search UI returning the chosen match, if any, and the number of
+// lines on the screen
+func run_ui(lines []Line, query string, context int) (chosen *Match, num_rows int, err error) {
+	lp, err := loop.New(loop.NoMouseTracking)
+	if err != nil {
+		return
+	}
+	h := &handler{lp: lp, lines: lines, context: context, query: query}
+	lp.OnInitialize = func() (string, error) {
+		lp.AllowLineWrapping(false)
+		lp.SetWindowTitle("Search scrollback")
+		h.update_matches()
+		return "", h.draw_screen()
+	}
+	lp.OnResize = func(_, new_size loop.ScreenSize) error { return h.draw_screen() }
+	lp.OnKeyEvent = h.on_key_event
+	lp.OnText = h.on_text
+	if err = lp.Run(); err != nil {
+		return
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return
+	}
+	if sz, serr := lp.ScreenSize(); serr == nil {
+		num_rows = int(sz.HeightCells)
+	}
+	return h.chosen, num_rows, nil
+}
@@ -21,21 +21,52 @@ func run_kitty_loop(opts *Options) (err error) {
 	if err != nil {
 		return err
 	}
+	lp.MouseTrackingMode(mouse_tracking_for(opts.Mouse))
 	ctx := markup.New(true)
 
 	lp.OnInitialize = func() (string, error) {
 		lp.SetCursorVisible(false)
 		lp.SetWindowTitle("kitty extended keyboard protocol demo")
-		lp.Println("Press any keys - Ctrl+C or Ctrl+D will terminate")
+		switch opts.OutputFormat {
+		case "text":
+			lp.Println("Press any keys - Ctrl+C or Ctrl+D will terminate")
+		case "kitty-map":
+			lp.Println("# Press any keys - Ctrl+C or Ctrl+D will terminate")
+		}
 		return "", nil
 	}
 
+	lp.OnMouseEvent = func(ev *loop.MouseEvent) error {
+		switch opts.OutputFormat {
+		case "kitty-map":
+			if q := kitty_map_for_mouse(ev); q != "" {
+				lp.Println(q)
+			}
+		case "json":
+			lp.Println(json_for_mouse(ev, "SGR-Pixels"))
+		default:
+			lp.Println(format_mouse_event(ev, "SGR-Pixels", ctx))
+			lp.Println()
+		}
+		return nil
+	}
+
 	lp.OnKeyEvent = func(e *loop.KeyEvent) (err error) {
 		e.Handled = true
 		if e.MatchesPressOrRepeat("ctrl+c") || e.MatchesPressOrRepeat("ctrl+d") {
 			lp.Quit(0)
 			return
 		}
+		switch opts.OutputFormat {
+		case "kitty-map":
+			if q := kitty_map_for_key(e); q != "" {
+				lp.Println(q)
+			}
+			return
+		case "json":
+			lp.Println(json_for_key(e))
+			return
+		}
 		mods := e.Mods.String()
 		if mods != "" {
 			mods += "+"
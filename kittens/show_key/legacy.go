@@ -29,12 +29,32 @@ func print_key(buf []byte, ctx *markup.Context) {
 			unix += string(rune(ch))
 		}
 	}
-	for _, ch := range string(buf) {
-		q := fmt.Sprintf("%#v", string(ch))
-		send_text += q[1 : len(q)-1]
-	}
+	send_text = escape_for_send_text(buf)
 	os.Stdout.WriteString(unix + "\t\t")
 	os.Stdout.WriteString(ctx.Yellow(send_text) + "\r\n")
+	if ev, protocol := decode_legacy_mouse_event(buf); ev != nil {
+		os.Stdout.WriteString(format_mouse_event(ev, protocol, ctx) + "\r\n")
+	}
+}
+
+func print_event(buf []byte, opts *Options, ctx *markup.Context) {
+	ev, protocol := decode_legacy_mouse_event(buf)
+	switch opts.OutputFormat {
+	case "kitty-map":
+		if ev == nil {
+			os.Stdout.WriteString(kitty_map_for_legacy(buf, opts.KeyMode) + "\r\n")
+		} else if q := kitty_map_for_mouse(ev); q != "" {
+			os.Stdout.WriteString(q + "\r\n")
+		}
+	case "json":
+		if ev == nil {
+			os.Stdout.WriteString(json_for_legacy(buf, opts.KeyMode) + "\r\n")
+		} else {
+			os.Stdout.WriteString(json_for_mouse(ev, protocol) + "\r\n")
+		}
+	default:
+		print_key(buf, ctx)
+	}
 }
 
 func run_legacy_loop(opts *Options) (err error) {
@@ -57,9 +77,20 @@ func run_legacy_loop(opts *Options) (err error) {
 			os.Stdout.WriteString("\x1b[?1l")
 		}()
 	}
-	fmt.Print("Press any keys - Ctrl+D will terminate this program\r\n")
+	if on, off := legacy_mouse_tracking_codes(opts.Mouse); on != "" {
+		os.Stdout.WriteString(on)
+		defer func() {
+			os.Stdout.WriteString(off)
+		}()
+	}
 	ctx := markup.New(true)
-	fmt.Print(ctx.Green("UNIX\t\tsend_text\r\n"))
+	switch opts.OutputFormat {
+	case "text":
+		fmt.Print("Press any keys - Ctrl+D will terminate this program\r\n")
+		fmt.Print(ctx.Green("UNIX\t\tsend_text\r\n"))
+	case "kitty-map":
+		fmt.Print("# Press any keys - Ctrl+D will terminate this program\r\n")
+	}
 	buf := make([]byte, 64)
 	for {
 		n, err := term.Read(buf)
@@ -72,10 +103,14 @@ func run_legacy_loop(opts *Options) (err error) {
 			}
 		}
 		if n > 0 {
-			print_key(buf[:n], ctx)
 			if n == 1 && buf[0] == 4 {
+				// Ctrl+D is not a captured event in the structured formats
+				if opts.OutputFormat == "text" {
+					print_key(buf[:n], ctx)
+				}
 				break
 			}
+			print_event(buf[:n], opts, ctx)
 		}
 	}
 	return
@@ -13,8 +13,29 @@
 The keyboard mode to use when showing keys. :code:`normal` mode is with DECCKM
 reset and :code:`application` mode is with DECCKM set. :code:`kitty` is the full
 kitty extended keyboard protocol.
+
+
+--mouse
+default=none
+type=choices
+choices=none,buttons,drag,motion
+Also enable mouse tracking and show the decoded mouse events. :code:`buttons`
+reports only presses and releases of mouse buttons, :code:`drag` also reports
+motion while a button is pressed and :code:`motion` reports all motion.
+
+
+--output-format
+default=text
+type=choices
+choices=text,kitty-map,json
+How to print the captured events. :code:`text` is a human readable description,
+:code:`kitty-map` prints :code:`map` and :code:`mouse_map` directives ready to
+paste into :file:`kitty.conf` and :code:`json` prints one JSON object per line.
+In the legacy keyboard modes the key is not known, so the :code:`map`
+directives use the placeholder :code:`KEY` and the :code:`send_text` action to
+send the bytes received.
 '''.format
-help_text = 'Show the codes generated by the terminal for key presses in various keyboard modes'
+help_text = 'Show the codes generated by the terminal for key presses and mouse events in various keyboard modes'
 usage = ''
 
 
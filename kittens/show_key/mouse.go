@@ -0,0 +1,84 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package show_key
+
+import (
+	"fmt"
+
+	"kitty/tools/cli/markup"
+	"kitty/tools/tui/loop"
+)
+
+var _ = fmt.Print
+
+func mouse_tracking_for(mode string) loop.MouseTracking {
+	switch mode {
+	case "buttons":
+		return loop.BUTTONS_ONLY_MOUSE_TRACKING
+	case "drag":
+		return loop.BUTTONS_AND_DRAG_MOUSE_TRACKING
+	case "motion":
+		return loop.FULL_MOUSE_TRACKING
+	}
+	return loop.NO_MOUSE_TRACKING
+}
+
+// The escape codes to turn mouse tracking on and off for the legacy modes,
+// which use cell based SGR reporting
+func legacy_mouse_tracking_codes(mode string) (on, off string) {
+	var m loop.Mode
+	switch mouse_tracking_for(mode) {
+	case loop.BUTTONS_ONLY_MOUSE_TRACKING:
+		m = loop.MOUSE_BUTTON_TRACKING
+	case loop.BUTTONS_AND_DRAG_MOUSE_TRACKING:
+		m = loop.MOUSE_MOTION_TRACKING
+	case loop.FULL_MOUSE_TRACKING:
+		m = loop.MOUSE_MOVE_TRACKING
+	default:
+		return
+	}
+	return m.EscapeCodeToSet() + loop.MOUSE_SGR_MODE.EscapeCodeToSet(), m.EscapeCodeToReset() + loop.MOUSE_SGR_MODE.EscapeCodeToReset()
+}
+
+func format_mouse_event(ev *loop.MouseEvent, protocol string, ctx *markup.Context) string {
+	mods := ev.Mods.String()
+	if mods != "" {
+		mods += "+"
+	}
+	etype := ev.Event_type.String()
+	if ev.Event_type == loop.MOUSE_CLICK {
+		etype += " (synthesized)"
+	}
+	ans := fmt.Sprintf("%s %s cell: %d, %d", ctx.Green(mods+ev.Buttons.String()), ctx.Yellow(etype), ev.Cell.X, ev.Cell.Y)
+	if protocol == "SGR-Pixels" {
+		ans += fmt.Sprintf(" pixel: %d, %d", ev.Pixel.X, ev.Pixel.Y)
+	}
+	return ans + " " + ctx.Dim("protocol: "+protocol)
+}
+
+// Decode a mouse event reported in the cell based SGR or the original X10
+// encoding. Returns nil if buf is not a single mouse event.
+func decode_legacy_mouse_event(buf []byte) (ev *loop.MouseEvent, protocol string) {
+	if len(buf) < 6 || buf[0] != 0x1b || buf[1] != '[' {
+		return
+	}
+	if buf[2] == 'M' && len(buf) == 6 {
+		// X10: CSI M followed by button, x and y each offset by 32
+		csi := fmt.Sprintf("<%d;%d;%dM", int(buf[3])-32, int(buf[4])-32, int(buf[5])-32)
+		return decode_sgr_cells(csi), "X10"
+	}
+	if buf[2] == '<' {
+		return decode_sgr_cells(string(buf[2:])), "SGR"
+	}
+	return
+}
+
+func decode_sgr_cells(csi string) *loop.MouseEvent {
+	// with unit cell sizes the pixel co-ordinates are the one based cell co-ordinates
+	const big = 1 << 30
+	ev := loop.MouseEventFromCSI(csi, loop.ScreenSize{WidthPx: big, HeightPx: big, CellWidth: 1, CellHeight: 1})
+	if ev != nil {
+		ev.Cell.X, ev.Cell.Y = max(0, ev.Pixel.X-1), max(0, ev.Pixel.Y-1)
+	}
+	return ev
+}
@@ -0,0 +1,122 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package show_key
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kitty/tools/tui/loop"
+)
+
+var _ = fmt.Print
+
+func modifier_names(mods loop.KeyModifiers) []string {
+	// lock keys are never part of a shortcut
+	s := (mods &^ (loop.CAPS_LOCK | loop.NUM_LOCK)).String()
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "+")
+}
+
+func key_name(e *loop.KeyEvent) string {
+	if e.Key == " " {
+		return "space"
+	}
+	return e.Key
+}
+
+// The bytes escaped the way they are specified to the send_text action
+func escape_for_send_text(buf []byte) string {
+	ans := strings.Builder{}
+	for _, ch := range string(buf) {
+		q := fmt.Sprintf("%#v", string(ch))
+		ans.WriteString(q[1 : len(q)-1])
+	}
+	return ans.String()
+}
+
+// A kitty.conf map directive for the key event. Only press events can be
+// mapped, so the empty string is returned for all others.
+func kitty_map_for_key(e *loop.KeyEvent) string {
+	if e.Type != loop.PRESS {
+		return ""
+	}
+	return fmt.Sprintf("map %s no_op", strings.Join(append(modifier_names(e.Mods), key_name(e)), "+"))
+}
+
+func json_for_key(e *loop.KeyEvent) string {
+	data := map[string]any{
+		"type": strings.ToLower(e.Type.String()), "key": key_name(e), "mods": modifier_names(e.Mods),
+		"text": e.Text, "csi": e.CSI,
+	}
+	if e.ShiftedKey != "" {
+		data["shifted_key"] = e.ShiftedKey
+	}
+	if e.AlternateKey != "" {
+		data["alternate_key"] = e.AlternateKey
+	}
+	ans, _ := json.Marshal(data)
+	return string(ans)
+}
+
+// For the legacy keyboard modes the key is not known so the map directive has
+// a placeholder for it, sending the bytes received in the current mode
+func kitty_map_for_legacy(buf []byte, key_mode string) string {
+	mode := "all"
+	switch key_mode {
+	case "normal", "application":
+		mode = key_mode
+	}
+	return fmt.Sprintf("map KEY send_text %s %s", mode, escape_for_send_text(buf))
+}
+
+func json_for_legacy(buf []byte, key_mode string) string {
+	ans, _ := json.Marshal(map[string]any{"key_mode": key_mode, "bytes": string(buf), "send_text": escape_for_send_text(buf)})
+	return string(ans)
+}
+
+func mouse_button_name(b loop.MouseButtonFlag) string {
+	switch b {
+	case loop.LEFT_MOUSE_BUTTON:
+		return "left"
+	case loop.MIDDLE_MOUSE_BUTTON:
+		return "middle"
+	case loop.RIGHT_MOUSE_BUTTON:
+		return "right"
+	case loop.FOURTH_MOUSE_BUTTON:
+		return "b4"
+	case loop.FIFTH_MOUSE_BUTTON:
+		return "b5"
+	case loop.SIXTH_MOUSE_BUTTON:
+		return "b6"
+	case loop.SEVENTH_MOUSE_BUTTON:
+		return "b7"
+	}
+	return ""
+}
+
+// A kitty.conf mouse_map directive for the mouse event, or the empty string
+// for events that cannot be mapped, such as motion and the scroll wheel
+func kitty_map_for_mouse(ev *loop.MouseEvent) string {
+	button := mouse_button_name(ev.Buttons)
+	if button == "" || ev.Event_type == loop.MOUSE_MOVE {
+		return ""
+	}
+	return fmt.Sprintf("mouse_map %s %s ungrabbed no_op", strings.Join(append(modifier_names(ev.Mods), button), "+"), ev.Event_type)
+}
+
+func json_for_mouse(ev *loop.MouseEvent, protocol string) string {
+	data := map[string]any{
+		"type": ev.Event_type.String(), "buttons": strings.ToLower(ev.Buttons.String()),
+		"mods": modifier_names(ev.Mods), "cell": []int{ev.Cell.X, ev.Cell.Y}, "protocol": protocol,
+		"synthesized": ev.Event_type == loop.MOUSE_CLICK,
+	}
+	if protocol == "SGR-Pixels" {
+		data["pixel"] = []int{ev.Pixel.X, ev.Pixel.Y}
+	}
+	ans, _ := json.Marshal(data)
+	return string(ans)
+}
@@ -0,0 +1,49 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package show_key
+
+import (
+	"fmt"
+	"testing"
+
+	"kitty/tools/tui/loop"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestShowKeyOutputFormats(t *testing.T) {
+	e := &loop.KeyEvent{Type: loop.PRESS, Mods: loop.CTRL | loop.SHIFT | loop.NUM_LOCK, Key: " ", CSI: "32;6u"}
+	if diff := cmp.Diff("map shift+ctrl+space no_op", kitty_map_for_key(e)); diff != "" {
+		t.Fatalf("Unexpected map directive for key event:\n%s", diff)
+	}
+	if diff := cmp.Diff(`{"csi":"32;6u","key":"space","mods":["shift","ctrl"],"text":"","type":"press"}`, json_for_key(e)); diff != "" {
+		t.Fatalf("Unexpected JSON for key event:\n%s", diff)
+	}
+	e.Type = loop.RELEASE
+	if q := kitty_map_for_key(e); q != "" {
+		t.Fatalf("Release events should not generate a map directive, got: %#v", q)
+	}
+	if diff := cmp.Diff(`map KEY send_text application \x1bOA`, kitty_map_for_legacy([]byte("\x1bOA"), "application")); diff != "" {
+		t.Fatalf("Unexpected map directive for legacy key:\n%s", diff)
+	}
+	if diff := cmp.Diff(`map KEY send_text all \x01`, kitty_map_for_legacy([]byte{1}, "unchanged")); diff != "" {
+		t.Fatalf("Unexpected map directive for legacy key:\n%s", diff)
+	}
+
+	ev, protocol := decode_legacy_mouse_event([]byte("\x1b[<4;10;3M"))
+	if ev == nil || protocol != "SGR" {
+		t.Fatalf("Failed to decode SGR mouse event, got: %v %#v", ev, protocol)
+	}
+	if diff := cmp.Diff("mouse_map shift+left press ungrabbed no_op", kitty_map_for_mouse(ev)); diff != "" {
+		t.Fatalf("Unexpected mouse_map directive:\n%s", diff)
+	}
+	if diff := cmp.Diff(`{"buttons":"left","cell":[9,2],"mods":["shift"],"protocol":"SGR","synthesized":false,"type":"press"}`, json_for_mouse(ev, protocol)); diff != "" {
+		t.Fatalf("Unexpected JSON for mouse event:\n%s", diff)
+	}
+	ev, protocol = decode_legacy_mouse_event([]byte("\x1b[M\x20\x21\x21"))
+	if ev == nil || protocol != "X10" || ev.Cell.X != 0 || ev.Cell.Y != 0 {
+		t.Fatalf("Failed to decode X10 mouse event, got: %v %#v", ev, protocol)
+	}
+}
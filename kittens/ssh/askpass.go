@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"kitty/kittens/known_hosts"
 	"kitty/tools/cli"
 	"kitty/tools/tty"
 	"kitty/tools/utils/shm"
@@ -43,6 +44,12 @@ func RunSSHAskpass() {
 		q_type = "confirm"
 	}
 	is_fingerprint_check := strings.Contains(msg, "(yes/no/[fingerprint])")
+	if is_fingerprint_check {
+		if note := known_hosts.HostKeyPromptNote(msg); note != "" {
+			msg, _ = strings.CutSuffix(msg, "\n")
+			msg += "\n" + note + "\n"
+		}
+	}
 	q := map[string]any{
 		"message":     msg,
 		"type":        q_type,
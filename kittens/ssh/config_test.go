@@ -4,6 +4,7 @@ package ssh
 
 import (
 	"fmt"
+	"kitty/tools/config"
 	"kitty/tools/utils"
 	"os"
 	"path/filepath"
@@ -111,3 +112,35 @@ func TestSSHConfigParsing(t *testing.T) {
 	}
 
 }
+
+func TestSSHOptionsMetadata(t *testing.T) {
+	m := OptionsMetadata.Find("askpass")
+	if m == nil {
+		t.Fatalf("No metadata for the askpass option")
+	}
+	if diff := cmp.Diff(config.OptionMetadata{Name: "askpass", Type: "Askpass_Choice_Type", Default: "unless-set", Choices: []string{"unless-set", "ssh", "native"}}, *m); diff != "" {
+		t.Fatalf("Incorrect metadata for askpass:\n%s", diff)
+	}
+	if m = OptionsMetadata.Find("env"); m == nil || !m.IsMultiple {
+		t.Fatalf("Incorrect metadata for env: %#v", m)
+	}
+	if OptionsMetadata.Find("not_an_option") != nil {
+		t.Fatalf("Found metadata for a non-existent option")
+	}
+	c := NewConfig()
+	for _, m := range OptionsMetadata {
+		if err := c.Parse(m.Name, m.Default); err != nil && !m.IsMultiple {
+			t.Fatalf("Failed to parse the default value of %s with error: %s", m.Name, err)
+		}
+		if _, found := c.Get(m.Name); !found {
+			t.Fatalf("Could not get the value of %s", m.Name)
+		}
+	}
+	c.Parse("askpass", "ssh")
+	if val, found := c.Get("askpass"); !found || val != Askpass_ssh {
+		t.Fatalf("Incorrect value for askpass: %#v", val)
+	}
+	if _, found := c.Get("not_an_option"); found {
+		t.Fatalf("Got the value of a non-existent option")
+	}
+}
@@ -119,7 +119,9 @@ func parse_kitten_args(found_extra_args []string, username, hostname_for_match s
 	return
 }
 
-func connection_sharing_args(kitty_pid int) ([]string, error) {
+// The ssh options to share the connection to a host between all ssh, scp and
+// sftp processes started in the kitty instance with the specified PID
+func ConnectionSharingArgs(kitty_pid int) ([]string, error) {
 	rd := utils.RuntimeDir()
 	// Bloody OpenSSH generates a 40 char hash and in creating the socket
 	// appends a 27 char temp suffix to it. Socket max path length is approx
@@ -633,7 +635,7 @@ func run_ssh(ssh_args, server_args, found_extra_args []string) (rc int, err erro
 		if err != nil {
 			return 1, fmt.Errorf("Invalid KITTY_PID env var not an integer: %#v", os.Getenv("KITTY_PID"))
 		}
-		control_master_args, err = connection_sharing_args(kpid)
+		control_master_args, err = ConnectionSharingArgs(kpid)
 		if err != nil {
 			return 1, err
 		}
@@ -732,7 +734,16 @@ func run_ssh(ssh_args, server_args, found_extra_args []string) (rc int, err erro
 		term.WriteAllString(restore_escape_codes)
 		term.RestoreAndClose()
 	}()
+	// preparing the data to bootstrap the remote host can take a while, so
+	// show an indeterminate progress indicator in terminals that support it
+	report_progress := loop.TerminalCapabilitiesFromEnvironment().ProgressReporting
+	if report_progress {
+		term.WriteAllString(loop.ProgressEscapeCode(loop.PROGRESS_INDETERMINATE, 0))
+	}
 	err = get_remote_command(&cd)
+	if report_progress {
+		term.WriteAllString(loop.ProgressEscapeCode(loop.PROGRESS_HIDDEN, 0))
+	}
 	if err != nil {
 		return 1, err
 	}
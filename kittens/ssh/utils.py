@@ -3,10 +3,12 @@
 
 
 import os
+import shlex
+import shutil
 import subprocess
 import traceback
 from contextlib import suppress
-from typing import Any, Dict, Iterator, List, Optional, Sequence, Set, Tuple
+from typing import Any, Dict, Iterator, List, Optional, Sequence, Set, Tuple, Union
 
 from kitty.types import run_once
 from kitty.utils import SSHConnectionData
@@ -336,3 +338,89 @@ def get_connection_data(args: List[str], cwd: str = '', extra_args: Tuple[str, .
             identity_file = os.path.normpath(os.path.join(cwd or os.getcwd(), identity_file))
 
     return SSHConnectionData(found_ssh, host_name, port, identity_file, tuple(found_extra_args))
+
+
+# ssh options that scp and sftp accept with the same meaning
+scp_compatible_options = frozenset('46ACcFiJo')
+
+
+def scp_args_from_ssh_args(ssh_args: Sequence[str]) -> List[str]:
+    ''' Convert the options from an ssh command line, without the ssh binary
+    and the destination, into options for scp and sftp so that they use the
+    same connection, re-using any ControlMaster it has. '''
+    boolean_ssh_args, other_ssh_args = get_ssh_cli()
+    ans: List[str] = []
+    args = iter(ssh_args)
+    for argument in args:
+        if argument == '--':
+            break
+        if not argument.startswith('-'):
+            continue
+        all_args = argument[1:]
+        for i, arg in enumerate(all_args):
+            if f'-{arg}' in boolean_ssh_args:
+                if arg in scp_compatible_options:
+                    ans.append(f'-{arg}')
+                continue
+            if f'-{arg}' in other_ssh_args:
+                val = all_args[i+1:] or next(args, '')
+                if arg in scp_compatible_options:
+                    ans.extend((f'-{arg}', val))
+                elif arg == 'p':
+                    ans.extend(('-P', val))
+                elif arg == 'l':
+                    # -l means something else for scp
+                    ans.extend(('-o', f'User={val}'))
+            break
+    return ans
+
+
+def scp_args_for_connection_data(conn_data: Union[Sequence[str], SSHConnectionData]) -> Tuple[List[str], str]:
+    ''' The scp and sftp options and the hostname to use to copy files over
+    the connection from Window.ssh_connection_data(). '''
+    if isinstance(conn_data, SSHConnectionData):
+        ssh_args = []
+        if conn_data.port:
+            ssh_args += ['-p', str(conn_data.port)]
+        if conn_data.identity_file:
+            ssh_args += ['-i', conn_data.identity_file]
+        return scp_args_from_ssh_args(ssh_args), conn_data.hostname
+    # the ssh command line of the ssh kitten, ending with -- hostname
+    ssh_cmdline = list(conn_data[1:])
+    return scp_args_from_ssh_args(ssh_cmdline[1:-2]), ssh_cmdline[-1]
+
+
+def sftp_quote(path: str) -> str:
+    return '"' + path.replace('\\', '\\\\').replace('"', '\\"') + '"'
+
+
+def copy_over_ssh(scp_args: Sequence[str], hostname: str, remote_path: str, local_path: str, upload: bool = False) -> str:
+    ''' Copy a file to or from the remote machine using scp, falling back to
+    sftp if scp fails, for servers that have disabled scp. Returns an empty
+    string on success, otherwise the error output. '''
+    if ':' in hostname.rpartition('@')[2]:
+        # IPv6 address
+        user, sep, host = hostname.rpartition('@')
+        hostname = f'{user}{sep}[{host}]'
+    # Force the legacy scp protocol, in which the remote path is interpreted
+    # by the remote shell and so must be quoted. Servers without it, and old
+    # scp versions that lack -O, are handled by the sftp fallback.
+    remote = f'{hostname}:{shlex.quote(remote_path)}'
+    errors = []
+    for exe in ('scp', 'sftp'):
+        if not shutil.which(exe):
+            errors.append(f'The {exe} program was not found')
+            continue
+        if exe == 'scp':
+            cmd = ['scp', '-q', '-B', '-O'] + list(scp_args) + ([local_path, remote] if upload else [remote, local_path])
+            batch = None
+        else:
+            cmd = ['sftp', '-q', '-b', '-'] + list(scp_args) + [hostname]
+            batch = f'{"put" if upload else "get"} {sftp_quote(local_path if upload else remote_path)} {sftp_quote(remote_path if upload else local_path)}\n'
+        cp = subprocess.run(
+            cmd, input=batch.encode('utf-8') if batch else None, stdout=subprocess.PIPE, stderr=subprocess.STDOUT,
+            stdin=None if batch else subprocess.DEVNULL)
+        if cp.returncode == 0:
+            return ''
+        errors.append(f'The command: {shlex.join(cmd)} failed\n' + cp.stdout.decode('utf-8', 'replace'))
+    return '\n'.join(errors)
@@ -58,11 +58,15 @@ func TestRelevantKittyOpts(t *testing.T) {
 	tdir := t.TempDir()
 	path := filepath.Join(tdir, "kitty.conf")
 	os.WriteFile(path, []byte("term XXX\nshell_integration changed\nterm abcd"), 0o600)
-	rko := read_relevant_kitty_opts(path)
-	if rko.Term != "abcd" {
-		t.Fatalf("Unexpected TERM: %s", RelevantKittyOpts().Term)
-	}
-	if rko.Shell_integration != "changed" {
-		t.Fatalf("Unexpected shell_integration: %s", RelevantKittyOpts().Shell_integration)
+	cache_dir := filepath.Join(tdir, "cache")
+	for i := 0; i < 2; i++ {
+		// the second time the cached values are used
+		rko := read_relevant_kitty_opts(path, cache_dir)
+		if rko.Term != "abcd" {
+			t.Fatalf("Unexpected TERM: %s", RelevantKittyOpts().Term)
+		}
+		if rko.Shell_integration != "changed" {
+			t.Fatalf("Unexpected shell_integration: %s", RelevantKittyOpts().Shell_integration)
+		}
 	}
 }
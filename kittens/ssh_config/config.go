@@ -0,0 +1,259 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ssh_config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/utils"
+	"kitty/tools/utils/shlex"
+)
+
+var _ = fmt.Print
+
+// A single line from an ssh_config file. Lines that are not modified are
+// written back exactly as they were read.
+type Line struct {
+	// The keyword as written in the file, empty for blank lines and comments
+	Key string
+	// Everything after the keyword and its separator
+	Value string
+
+	text, indent string
+	modified     bool
+}
+
+func (self *Line) Keyword() string { return strings.ToLower(self.Key) }
+
+// The arguments in the value, with quotes removed
+func (self *Line) Args() []string {
+	ans, err := shlex.Split(self.Value)
+	if err != nil {
+		return strings.Fields(self.Value)
+	}
+	return ans
+}
+
+func (self *Line) String() string {
+	if !self.modified {
+		return self.text
+	}
+	return self.indent + self.Key + " " + self.Value
+}
+
+func ParseLine(text string) *Line {
+	ans := Line{text: text}
+	line := strings.TrimSpace(text)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return &ans
+	}
+	ans.indent = text[:strings.Index(text, line)]
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		ans.Key = line
+		return &ans
+	}
+	ans.Key = line[:idx]
+	ans.Value = strings.TrimSpace(line[idx:])
+	if v, found := strings.CutPrefix(ans.Value, "="); found {
+		ans.Value = strings.TrimSpace(v)
+	}
+	return &ans
+}
+
+// Quote a value for ssh_config if it contains whitespace
+func QuoteValue(val string) string {
+	if strings.ContainsAny(val, " \t") {
+		return `"` + val + `"`
+	}
+	return val
+}
+
+// A Host or Match block along with its options. The lines before the first
+// Host or Match line in a file are in a block with no header.
+type Block struct {
+	Header *Line
+	Lines  []*Line
+}
+
+func (self *Block) IsMatch() bool { return self.Header != nil && self.Header.Keyword() == "match" }
+
+// The patterns of a Host block or the criteria of a Match block
+func (self *Block) Patterns() []string {
+	if self.Header == nil {
+		return nil
+	}
+	return self.Header.Args()
+}
+
+func (self *Block) find(key string) *Line {
+	key = strings.ToLower(key)
+	for _, l := range self.Lines {
+		if l.Keyword() == key {
+			return l
+		}
+	}
+	return nil
+}
+
+// The value of the first occurrence of key in this block, which is the one
+// ssh uses, unquoted if it is a single argument
+func (self *Block) Get(key string) string {
+	l := self.find(key)
+	if l == nil {
+		return ""
+	}
+	if args := l.Args(); len(args) == 1 {
+		return args[0]
+	}
+	return l.Value
+}
+
+func (self *Block) indent() string {
+	for _, l := range self.Lines {
+		if l.Key != "" {
+			return l.indent
+		}
+	}
+	if self.Header == nil {
+		return ""
+	}
+	return "    "
+}
+
+// Set the value of key, changing the first occurrence of it in the block,
+// adding it after the last option in the block if not present or removing
+// all occurrences if val is empty. Returns true if the block was changed.
+func (self *Block) Set(key, val string) bool {
+	l := self.find(key)
+	if val == "" {
+		if l == nil {
+			return false
+		}
+		k := strings.ToLower(key)
+		self.Lines = utils.Filter(self.Lines, func(x *Line) bool { return x.Keyword() != k })
+		return true
+	}
+	val = QuoteValue(val)
+	if l != nil {
+		if l.Value == val {
+			return false
+		}
+		l.Value, l.modified = val, true
+		return true
+	}
+	pos := 0
+	for i, x := range self.Lines {
+		if x.Key != "" {
+			pos = i + 1
+		}
+	}
+	l = &Line{Key: key, Value: val, indent: self.indent(), modified: true}
+	self.Lines = append(self.Lines[:pos], append([]*Line{l}, self.Lines[pos:]...)...)
+	return true
+}
+
+// Change the patterns of a Host block
+func (self *Block) SetPatterns(patterns []string) {
+	self.Header.Value = strings.Join(patterns, " ")
+	self.Header.modified = true
+}
+
+// An ssh_config file, preserving comments, ordering and formatting
+type File struct {
+	Path   string
+	Blocks []*Block
+
+	dirty bool
+}
+
+func Parse(path, data string) *File {
+	ans := &File{Path: path, Blocks: []*Block{{}}}
+	for _, text := range utils.Splitlines(data) {
+		l := ParseLine(text)
+		switch l.Keyword() {
+		case "host", "match":
+			ans.Blocks = append(ans.Blocks, &Block{Header: l})
+		default:
+			b := ans.Blocks[len(ans.Blocks)-1]
+			b.Lines = append(b.Lines, l)
+		}
+	}
+	return ans
+}
+
+// Load an ssh_config file, a file that does not exist is treated as empty
+func LoadFile(path string) (*File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Parse(path, ""), nil
+		}
+		return nil, err
+	}
+	return Parse(path, utils.UnsafeBytesToString(raw)), nil
+}
+
+// The Host and Match blocks in this file
+func (self *File) Hosts() []*Block {
+	return utils.Filter(self.Blocks, func(b *Block) bool { return b.Header != nil })
+}
+
+func (self *File) MarkDirty() { self.dirty = true }
+
+// Append a new Host block, separated from the previous block by a blank line
+func (self *File) AddHost(patterns []string) *Block {
+	prev := self.Blocks[len(self.Blocks)-1]
+	if n := len(prev.Lines); (n > 0 && prev.Lines[n-1].String() != "") || (n == 0 && prev.Header != nil) {
+		prev.Lines = append(prev.Lines, &Line{})
+	}
+	ans := &Block{Header: &Line{Key: "Host", modified: true}}
+	ans.SetPatterns(patterns)
+	self.Blocks = append(self.Blocks, ans)
+	self.dirty = true
+	return ans
+}
+
+func (self *File) Remove(b *Block) {
+	self.Blocks = utils.Filter(self.Blocks, func(x *Block) bool { return x != b })
+	self.dirty = true
+}
+
+func (self *File) String() string {
+	buf := strings.Builder{}
+	for _, b := range self.Blocks {
+		if b.Header != nil {
+			buf.WriteString(b.Header.String())
+			buf.WriteByte('\n')
+		}
+		for _, l := range b.Lines {
+			buf.WriteString(l.String())
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.String()
+}
+
+func (self *File) Save() error {
+	if !self.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(self.Path), 0o700); err != nil {
+		return err
+	}
+	if err := utils.AtomicUpdateFile(self.Path, utils.UnsafeStringToBytes(self.String()), 0o600); err != nil {
+		return err
+	}
+	self.dirty = false
+	return nil
+}
+
+// The per-user configuration file used by ssh
+func DefaultFile() string {
+	return filepath.Join(utils.Expanduser("~/.ssh"), "config")
+}
@@ -0,0 +1,160 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ssh_config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestSSHConfig(t *testing.T) {
+	tdir := t.TempDir()
+	path := filepath.Join(tdir, "config")
+	extra := filepath.Join(tdir, "extra.conf")
+	data := strings.Join([]string{
+		"# global settings",
+		"ServerAliveInterval 30",
+		"",
+		"Host work *.work.example.com",
+		"\tHostName=work.example.com",
+		"\t# the user",
+		"\tUser alice",
+		"",
+		"Match originalhost home user bob",
+		"  Port 2222",
+		"",
+		"Host home",
+		"  HostName %h.example.org",
+		"  IdentityFile \"~/.ssh/my key\"",
+		"  Include " + extra,
+		"",
+		"Host *",
+		"  User default",
+		"  Port 22",
+		"  IdentityFile ~/.ssh/id_ed25519",
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(extra, []byte("ForwardAgent yes\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(data, f.String()); diff != "" {
+		t.Fatalf("File not round tripped:\n%s", diff)
+	}
+	hosts := f.Hosts()
+	if len(hosts) != 4 || !hosts[1].IsMatch() {
+		t.Fatalf("Incorrect hosts: %#v", hosts)
+	}
+	if diff := cmp.Diff([]string{"work", "*.work.example.com"}, hosts[0].Patterns()); diff != "" {
+		t.Fatalf("Incorrect patterns:\n%s", diff)
+	}
+	if hosts[0].Get("hostname") != "work.example.com" || hosts[2].Get("IdentityFile") != "~/.ssh/my key" {
+		t.Fatalf("Incorrect values: %#v", hosts[0].Lines)
+	}
+
+	effective := func(dest string) (ans []string) {
+		for _, s := range f.Effective(dest) {
+			ans = append(ans, s.Key+" "+s.Value)
+		}
+		return
+	}
+	if diff := cmp.Diff([]string{
+		"serveraliveinterval 30", "hostname work.example.com", "user alice", "port 22", "identityfile ~/.ssh/id_ed25519",
+	}, effective("x.work.example.com")); diff != "" {
+		t.Fatalf("Incorrect effective config:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{
+		"serveraliveinterval 30", "port 2222", "hostname home.example.org", "identityfile ~/.ssh/my key", "forwardagent yes", "identityfile ~/.ssh/id_ed25519", "user bob",
+	}, effective("bob@home")); diff != "" {
+		t.Fatalf("Incorrect effective config:\n%s", diff)
+	}
+
+	hosts[0].Set("User", "")
+	hosts[0].Set("Port", "2200")
+	hosts[0].Set("hostname", "new.example.com")
+	hosts[0].SetPatterns([]string{"work"})
+	f.Remove(hosts[1])
+	b := f.AddHost([]string{"new"})
+	b.Set("HostName", "192.0.2.1")
+	b.Set("IdentityFile", "~/a b")
+	if err = f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"# global settings",
+		"ServerAliveInterval 30",
+		"",
+		"Host work",
+		"\tHostName new.example.com",
+		"\tPort 2200",
+		"\t# the user",
+		"",
+		"Host home",
+		"  HostName %h.example.org",
+		"  IdentityFile \"~/.ssh/my key\"",
+		"  Include " + extra,
+		"",
+		"Host *",
+		"  User default",
+		"  Port 22",
+		"  IdentityFile ~/.ssh/id_ed25519",
+		"",
+		"Host new",
+		"    HostName 192.0.2.1",
+		"    IdentityFile \"~/a b\"",
+	}, "\n") + "\n"
+	if diff := cmp.Diff(expected, string(raw)); diff != "" {
+		t.Fatalf("Incorrect file contents after saving:\n%s", diff)
+	}
+}
+
+func TestSSHConfigValidation(t *testing.T) {
+	field := func(key string) Field {
+		for _, f := range Fields {
+			if f.Key == key {
+				return f
+			}
+		}
+		t.Fatalf("No field named: %s", key)
+		return Field{}
+	}
+	for _, x := range []struct {
+		key, val string
+		valid    bool
+	}{
+		{"Port", "22", true},
+		{"Port", "0", false},
+		{"Port", "x", false},
+		{"HostName", "example.com", true},
+		{"HostName", "a b", false},
+		{"ProxyJump", "user@jump:2222,other", true},
+		{"ProxyJump", "a b", false},
+		{"ForwardAgent", "Yes", true},
+		{"ForwardAgent", "maybe", false},
+		{"IdentityFile", "~/.ssh/id_%h", true},
+		{"IdentityFile", "/does/not/exist", false},
+	} {
+		if err := field(x.key).Validate(x.val); (err == nil) != x.valid {
+			t.Fatalf("Incorrect validation of %s %#v: %v", x.key, x.val, err)
+		}
+	}
+	if ValidatePatterns(nil) == nil || ValidatePatterns([]string{"!"}) == nil || ValidatePatterns([]string{"a", "!b"}) != nil {
+		t.Fatalf("Incorrect validation of host patterns")
+	}
+}
@@ -0,0 +1,199 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ssh_config
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/utils"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+// Options that can be specified multiple times, all values are used
+var multi_valued = map[string]bool{
+	"identityfile": true, "certificatefile": true, "localforward": true, "remoteforward": true,
+	"dynamicforward": true, "sendenv": true, "setenv": true,
+}
+
+// A setting in the effective configuration for a host
+type Setting struct {
+	// The lowercased keyword, as output by ssh -G
+	Key, Value string
+	// Where the setting comes from, empty for defaults
+	Path       string
+	LineNumber int
+}
+
+type resolver struct {
+	host, user, local_user string
+	ans                    []Setting
+	seen                   map[string]bool
+	depth                  int
+}
+
+// Match a comma separated list of patterns, with the same semantics as ssh,
+// negated patterns take precedence
+func match_pattern_list(text string, patterns ...string) bool {
+	matched := false
+	for _, p := range patterns {
+		for _, x := range strings.Split(p, ",") {
+			negated := strings.HasPrefix(x, "!")
+			if m, err := filepath.Match(strings.ToLower(strings.TrimPrefix(x, "!")), strings.ToLower(text)); m && err == nil {
+				if negated {
+					return false
+				}
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+func (self *resolver) current(key string) string {
+	for _, s := range self.ans {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+func (self *resolver) matches(b *Block) bool {
+	args := b.Patterns()
+	if !b.IsMatch() {
+		return match_pattern_list(self.host, args...)
+	}
+	hostname := self.current("hostname")
+	if hostname == "" {
+		hostname = self.host
+	}
+	user := self.user
+	if user == "" {
+		user = utils.IfElse(self.seen["user"], self.current("user"), self.local_user)
+	}
+	for i := 0; i < len(args); i++ {
+		criterion := strings.ToLower(args[i])
+		negated := strings.HasPrefix(criterion, "!")
+		criterion = strings.TrimPrefix(criterion, "!")
+		var matched bool
+		switch criterion {
+		case "all":
+			matched = true
+		case "host", "originalhost", "user", "localuser":
+			if i+1 >= len(args) {
+				return false
+			}
+			i++
+			text := map[string]string{"host": hostname, "originalhost": self.host, "user": user, "localuser": self.local_user}[criterion]
+			matched = match_pattern_list(text, args[i])
+		default:
+			// criteria such as exec cannot be evaluated without running
+			// commands, treat them as not matching
+			return false
+		}
+		if matched == negated {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *resolver) add(path string, line_number int, l *Line) {
+	key := l.Keyword()
+	if self.seen[key] && !multi_valued[key] {
+		return
+	}
+	self.seen[key] = true
+	val := strings.Join(l.Args(), " ")
+	if key == "hostname" {
+		val = strings.ReplaceAll(val, "%h", self.host)
+	}
+	self.ans = append(self.ans, Setting{Key: key, Value: val, Path: path, LineNumber: line_number})
+}
+
+func (self *resolver) include(pattern string) {
+	if !filepath.IsAbs(pattern) {
+		if strings.HasPrefix(pattern, "~") {
+			pattern = utils.Expanduser(pattern)
+		} else {
+			pattern = filepath.Join(utils.Expanduser("~/.ssh"), pattern)
+		}
+	}
+	matches, _ := filepath.Glob(pattern)
+	slices.Sort(matches)
+	for _, path := range matches {
+		if f, err := LoadFile(path); err == nil {
+			self.process(f)
+		}
+	}
+}
+
+func (self *resolver) process(f *File) {
+	if self.depth > 16 {
+		return
+	}
+	self.depth++
+	defer func() { self.depth-- }()
+	line_number := 0
+	for _, b := range f.Blocks {
+		active := true
+		if b.Header != nil {
+			line_number++
+			active = self.matches(b)
+		}
+		for _, l := range b.Lines {
+			line_number++
+			if !active || l.Key == "" {
+				continue
+			}
+			if l.Keyword() == "include" {
+				for _, x := range l.Args() {
+					self.include(x)
+				}
+			} else {
+				self.add(f.Path, line_number, l)
+			}
+		}
+	}
+}
+
+// Split [user@]host into its parts
+func SplitUserHost(x string) (username, host string) {
+	if u, h, found := strings.Cut(x, "@"); found {
+		return u, h
+	}
+	return "", x
+}
+
+// The effective configuration for connecting to the specified destination,
+// of the form [user@]host, similar to the output of ssh -G. Settings are in
+// the order they are found in the file, followed by defaults for the
+// hostname, user and port. Match criteria that require running commands are
+// treated as not matching.
+func (self *File) Effective(destination string) []Setting {
+	r := resolver{seen: make(map[string]bool)}
+	r.user, r.host = SplitUserHost(destination)
+	if u, err := user.Current(); err == nil {
+		r.local_user = u.Username
+	}
+	r.process(self)
+	defaults := []Setting{{Key: "hostname", Value: r.host}, {Key: "user", Value: r.local_user}, {Key: "port", Value: "22"}}
+	if r.user != "" {
+		// a user specified on the command line takes precedence
+		r.ans = utils.Filter(r.ans, func(s Setting) bool { return s.Key != "user" })
+		r.seen["user"] = false
+		defaults[1].Value = r.user
+	}
+	for _, d := range defaults {
+		if !r.seen[d.Key] {
+			r.ans = append(r.ans, d)
+		}
+	}
+	return r.ans
+}
@@ -0,0 +1,112 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ssh_config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// A commonly used option that can be edited via the form in the UI
+type Field struct {
+	Key, Help string
+	// Validate a non-empty value, empty values remove the option
+	Validate func(string) error
+}
+
+var hostname_pat = regexp.MustCompile(`^[a-zA-Z0-9._:%\[\]-]+$`)
+var jump_host_pat = regexp.MustCompile(`^(?:ssh://)?(?:[^@\s,]+@)?[a-zA-Z0-9._%\[\]:-]+$`)
+
+func no_whitespace(val string) error {
+	if strings.ContainsAny(val, " \t") {
+		return fmt.Errorf("Must not contain spaces")
+	}
+	return nil
+}
+
+func validate_hostname(val string) error {
+	if !hostname_pat.MatchString(val) {
+		return fmt.Errorf("Not a valid hostname or IP address")
+	}
+	return nil
+}
+
+func validate_port(val string) error {
+	if p, err := strconv.Atoi(val); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("Must be a number from 1 to 65535")
+	}
+	return nil
+}
+
+func validate_non_negative_int(val string) error {
+	if p, err := strconv.Atoi(val); err != nil || p < 0 {
+		return fmt.Errorf("Must be a non-negative number")
+	}
+	return nil
+}
+
+func validate_identity_file(val string) error {
+	if strings.ContainsRune(val, '%') || strings.Contains(val, "${") || strings.EqualFold(val, "none") {
+		// contains tokens that are expanded by ssh
+		return nil
+	}
+	if _, err := os.Stat(utils.Expanduser(val)); err != nil {
+		return fmt.Errorf("The file %s does not exist", val)
+	}
+	return nil
+}
+
+func validate_proxy_jump(val string) error {
+	if strings.EqualFold(val, "none") {
+		return nil
+	}
+	for _, x := range strings.Split(val, ",") {
+		if !jump_host_pat.MatchString(x) {
+			return fmt.Errorf("%#v is not of the form [user@]host[:port]", x)
+		}
+	}
+	return nil
+}
+
+func one_of(choices ...string) func(string) error {
+	return func(val string) error {
+		for _, c := range choices {
+			if strings.EqualFold(val, c) {
+				return nil
+			}
+		}
+		return fmt.Errorf("Must be one of: %s", strings.Join(choices, ", "))
+	}
+}
+
+var Fields = []Field{
+	{"HostName", "The real hostname or IP address to connect to", validate_hostname},
+	{"User", "The user to log in as", no_whitespace},
+	{"Port", "The port to connect to", validate_port},
+	{"IdentityFile", "The file from which the identity (private key) is read", validate_identity_file},
+	{"ProxyJump", "Jump hosts to connect via, as comma separated [user@]host[:port]", validate_proxy_jump},
+	{"ForwardAgent", "Whether to forward the connection to the authentication agent", one_of("yes", "no")},
+	{"ServerAliveInterval", "Seconds after which to send a keepalive if no data is received, zero to disable", validate_non_negative_int},
+	{"Compression", "Whether to use compression", one_of("yes", "no")},
+	{"StrictHostKeyChecking", "Whether to automatically add the keys of new hosts", one_of("yes", "accept-new", "no", "off", "ask")},
+}
+
+// Validate the patterns of a Host line
+func ValidatePatterns(patterns []string) error {
+	if len(patterns) == 0 {
+		return fmt.Errorf("At least one host pattern is required")
+	}
+	for _, p := range patterns {
+		if strings.TrimLeft(p, "!") == "" {
+			return fmt.Errorf("%#v is not a valid host pattern", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ssh_config
+
+import (
+	"fmt"
+
+	"kitty/tools/cli"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+func print_effective(f *File, destination string) {
+	for _, s := range f.Effective(destination) {
+		fmt.Println(s.Key, s.Value)
+	}
+}
+
+func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
+	if len(args) > 1 {
+		return 1, fmt.Errorf("Only a single hostname can be specified")
+	}
+	if opts.PrintEffective && len(args) == 0 {
+		return 1, fmt.Errorf("No hostname specified")
+	}
+	path := DefaultFile()
+	if opts.File != "" {
+		path = utils.Expanduser(opts.File)
+	}
+	f, err := LoadFile(path)
+	if err != nil {
+		return 1, err
+	}
+	destination := ""
+	if len(args) > 0 {
+		destination = args[0]
+	}
+	if opts.PrintEffective {
+		print_effective(f, destination)
+		return
+	}
+	return run_ui(f, destination)
+}
+
+func EntryPoint(parent *cli.Command) {
+	create_cmd(parent, main)
+}
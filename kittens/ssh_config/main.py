@@ -0,0 +1,40 @@
+#!/usr/bin/env python
+# License: GPLv3 Copyright: 2023, Kovid Goyal <kovid at kovidgoyal.net>
+
+
+import sys
+from typing import List
+
+OPTIONS = r'''
+--file -f
+The SSH configuration file to edit. Defaults to the per-user configuration file
+used by ssh, :file:`~/.ssh/config`.
+
+
+--print-effective -p
+type=bool-set
+Instead of showing the interactive editor, print the effective configuration
+for the host specified on the command line, similar to :code:`ssh -G`.
+'''.format
+help_text = '''\
+Edit the SSH client configuration file. Lists the hosts in the file and allows
+adding, removing and editing them, with validation of commonly used options.
+Comments, formatting and the order of entries in the file are preserved. The
+effective configuration for a hostname, as ssh would use it, can be previewed.
+If a hostname is specified, the effective configuration for it is shown first.
+'''
+usage = '[[user@]hostname]'
+
+
+def main(args: List[str]) -> None:
+    raise SystemExit('This should be run as kitten ssh_config')
+
+
+if __name__ == '__main__':
+    main(sys.argv)
+elif __name__ == '__doc__':
+    cd = sys.cli_docs  # type: ignore
+    cd['usage'] = usage
+    cd['options'] = OPTIONS
+    cd['help_text'] = help_text
+    cd['short_desc'] = 'Edit the SSH client configuration'
@@ -0,0 +1,511 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package ssh_config
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type State int
+
+const (
+	BROWSING State = iota
+	EDITING
+	EDITING_FIELD
+	PROMPTING_FOR_HOST
+	PREVIEWING
+	CONFIRMING_REMOVAL
+)
+
+type form struct {
+	// nil when adding a new host
+	block          *Block
+	labels, values []string
+	errors         []string
+	current        int
+	original_value string
+}
+
+func new_form(b *Block) *form {
+	ans := &form{block: b, labels: []string{"Host"}, values: []string{""}}
+	if b != nil {
+		ans.labels[0] = b.Header.Key
+		ans.values[0] = b.Header.Value
+	}
+	for _, f := range Fields {
+		ans.labels = append(ans.labels, f.Key)
+		v := ""
+		if b != nil {
+			v = b.Get(f.Key)
+		}
+		ans.values = append(ans.values, v)
+	}
+	ans.errors = make([]string, len(ans.values))
+	return ans
+}
+
+func (self *form) validate(i int) bool {
+	self.errors[i] = ""
+	val := strings.TrimSpace(self.values[i])
+	var err error
+	switch {
+	case i == 0:
+		if self.block == nil || !self.block.IsMatch() {
+			err = ValidatePatterns(strings.Fields(val))
+		}
+	case val != "":
+		err = Fields[i-1].Validate(val)
+	}
+	if err != nil {
+		self.errors[i] = err.Error()
+	}
+	return err == nil
+}
+
+type handler struct {
+	lp   *loop.Loop
+	file *File
+
+	state      State
+	current    int
+	scroll     int
+	status     string
+	status_err bool
+	form       *form
+	prompt     string
+	preview    []Setting
+	preview_of string
+}
+
+func (self *handler) set_status(err bool, format string, args ...any) {
+	self.status, self.status_err = fmt.Sprintf(format, args...), err
+}
+
+func (self *handler) hosts() []*Block { return self.file.Hosts() }
+
+func host_summary(b *Block) string {
+	dest := b.Get("HostName")
+	if u := b.Get("User"); u != "" {
+		dest = u + "@" + utils.IfElse(dest == "", "…", dest)
+	}
+	if p := b.Get("Port"); p != "" {
+		dest += ":" + p
+	}
+	if j := b.Get("ProxyJump"); j != "" {
+		dest += " via " + j
+	}
+	return dest
+}
+
+// A hostname from the patterns of a Host block suitable for previewing
+func preview_host(b *Block) string {
+	if b == nil || b.IsMatch() {
+		return ""
+	}
+	for _, p := range b.Patterns() {
+		if !strings.HasPrefix(p, "!") && !strings.ContainsAny(p, "*?") {
+			return p
+		}
+	}
+	return ""
+}
+
+func (self *handler) draw_lines(lines []string, current, height, width int) {
+	num_rows := max(1, height-3)
+	if current < self.scroll {
+		self.scroll = current
+	} else if current >= self.scroll+num_rows {
+		self.scroll = current - num_rows + 1
+	}
+	for i := self.scroll; i < min(len(lines), self.scroll+num_rows); i++ {
+		line := wcswidth.TruncateToVisualLength(lines[i], width)
+		if i == current {
+			line = self.lp.SprintStyled("reverse", line+strings.Repeat(" ", max(0, width-wcswidth.Stringwidth(line))))
+		}
+		self.lp.Println(line)
+	}
+}
+
+func (self *handler) draw_host_list(width, height int) (header, details string) {
+	hosts := self.hosts()
+	header = fmt.Sprintf("%d hosts in %s", len(hosts), self.file.Path)
+	lines := make([]string, len(hosts))
+	for i, b := range hosts {
+		lines[i] = b.Header.Key + " " + b.Header.Value
+		if s := host_summary(b); s != "" {
+			lines[i] += "  → " + s
+		}
+	}
+	self.draw_lines(lines, self.current, height, width)
+	if self.current < len(hosts) {
+		if ident := hosts[self.current].Get("IdentityFile"); ident != "" {
+			details = "IdentityFile: " + ident
+		}
+	}
+	return
+}
+
+func (self *handler) draw_form(width, height int) (header, details string) {
+	f := self.form
+	if f.block == nil {
+		header = "Adding a new host"
+	} else {
+		header = "Editing: " + f.block.Header.Key + " " + f.block.Header.Value
+	}
+	lw := 0
+	for _, l := range f.labels {
+		lw = max(lw, len(l))
+	}
+	lines := make([]string, len(f.labels))
+	for i, l := range f.labels {
+		lines[i] = fmt.Sprintf("%*s: %s", lw, l, f.values[i])
+		if self.state == EDITING_FIELD && i == f.current {
+			lines[i] += "█"
+		}
+		if f.errors[i] != "" {
+			lines[i] += "  " + self.lp.SprintStyled("fg=red", f.errors[i])
+		}
+	}
+	self.draw_lines(lines, f.current, height, width)
+	if f.current > 0 {
+		details = Fields[f.current-1].Help
+	} else if f.block == nil || !f.block.IsMatch() {
+		details = "Space separated host patterns, can contain the wildcards * and ?"
+	}
+	return
+}
+
+func (self *handler) draw_preview(width, height int) (header, details string) {
+	header = "Effective configuration for: " + self.preview_of
+	kw := 0
+	for _, s := range self.preview {
+		kw = max(kw, len(s.Key))
+	}
+	lines := make([]string, len(self.preview))
+	for i, s := range self.preview {
+		lines[i] = fmt.Sprintf("%-*s %s", kw, s.Key, s.Value)
+		if s.Path == "" {
+			lines[i] += self.lp.SprintStyled("dim", "  (default)")
+		} else if s.Path != self.file.Path {
+			lines[i] += self.lp.SprintStyled("dim", fmt.Sprintf("  (%s:%d)", s.Path, s.LineNumber))
+		}
+	}
+	self.draw_lines(lines, self.current, height, width)
+	if self.current < len(self.preview) {
+		if s := self.preview[self.current]; s.Path != "" {
+			details = fmt.Sprintf("From %s:%d", s.Path, s.LineNumber)
+		}
+	}
+	return
+}
+
+func (self *handler) draw_screen() error {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return err
+	}
+	width, height := int(sz.WidthCells), int(sz.HeightCells)
+	self.lp.Println()
+	var header, details string
+	switch self.state {
+	case EDITING, EDITING_FIELD:
+		header, details = self.draw_form(width, height)
+	case PREVIEWING:
+		header, details = self.draw_preview(width, height)
+	default:
+		header, details = self.draw_host_list(width, height)
+	}
+	self.lp.MoveCursorTo(1, 1)
+	self.lp.QueueWriteString(self.lp.SprintStyled("bold", wcswidth.TruncateToVisualLength(header, width)))
+	self.lp.MoveCursorTo(1, height-1)
+	self.lp.QueueWriteString(self.lp.SprintStyled("dim", wcswidth.TruncateToVisualLength(details, width)))
+	self.lp.MoveCursorTo(1, height)
+	var footer string
+	switch self.state {
+	case PROMPTING_FOR_HOST:
+		footer = "Preview the configuration for [user@]host: " + self.prompt
+	case CONFIRMING_REMOVAL:
+		footer = self.lp.SprintStyled("fg=red", "Remove the selected host? [y/n]")
+	case EDITING:
+		footer = "[Enter] Edit field  [Ctrl+S] Save  [Esc] Cancel"
+	case EDITING_FIELD:
+		footer = "[Enter] Done  [Esc] Undo changes to this field"
+	case PREVIEWING:
+		footer = "[Esc] Back"
+	default:
+		if self.status != "" {
+			style := "fg=green"
+			if self.status_err {
+				style = "fg=red"
+			}
+			footer = self.lp.SprintStyled(style, wcswidth.TruncateToVisualLength(self.status, width))
+		} else {
+			footer = "[e] Edit  [a] Add  [d] Remove  [p] Preview  [q] Quit"
+		}
+	}
+	self.lp.QueueWriteString(footer)
+	self.lp.SetCursorVisible(self.state == PROMPTING_FOR_HOST)
+	return nil
+}
+
+func (self *handler) save(action string) {
+	if err := self.file.Save(); err != nil {
+		self.set_status(true, "Failed to save %s with error: %s", self.file.Path, err)
+	} else {
+		self.set_status(false, "%s and saved %s", action, self.file.Path)
+	}
+}
+
+func (self *handler) save_form() {
+	f := self.form
+	ok := true
+	for i := len(f.values) - 1; i >= 0; i-- {
+		if !f.validate(i) {
+			ok, f.current = false, i
+		}
+	}
+	if !ok {
+		return
+	}
+	b := f.block
+	patterns := strings.Fields(f.values[0])
+	if b == nil {
+		b = self.file.AddHost(patterns)
+		self.current = len(self.hosts()) - 1
+	} else if strings.Join(patterns, " ") != strings.Join(b.Patterns(), " ") {
+		b.SetPatterns(patterns)
+		self.file.MarkDirty()
+	}
+	for i, field := range Fields {
+		if b.Set(field.Key, strings.TrimSpace(f.values[i+1])) {
+			self.file.MarkDirty()
+		}
+	}
+	self.state, self.form = BROWSING, nil
+	self.save(utils.IfElse(f.block == nil, "Added host", "Updated host"))
+}
+
+func (self *handler) show_preview(destination string) {
+	self.preview_of = destination
+	self.preview = self.file.Effective(destination)
+	self.state, self.current, self.scroll = PREVIEWING, 0, 0
+}
+
+func (self *handler) handle_movement(ev *loop.KeyEvent, current *int, num_items int) bool {
+	page := 1
+	if sz, err := self.lp.ScreenSize(); err == nil {
+		page = max(1, int(sz.HeightCells)-4)
+	}
+	move := func(delta int) {
+		if num_items > 0 {
+			*current = max(0, min(*current+delta, num_items-1))
+		}
+	}
+	switch {
+	case ev.MatchesPressOrRepeat("up") || ev.MatchesPressOrRepeat("k"):
+		move(-1)
+	case ev.MatchesPressOrRepeat("down") || ev.MatchesPressOrRepeat("j"):
+		move(1)
+	case ev.MatchesPressOrRepeat("page_up"):
+		move(-page)
+	case ev.MatchesPressOrRepeat("page_down"):
+		move(page)
+	case ev.MatchesPressOrRepeat("home"):
+		*current = 0
+	case ev.MatchesPressOrRepeat("end"):
+		move(num_items)
+	default:
+		return false
+	}
+	return true
+}
+
+func (self *handler) on_key_event(ev *loop.KeyEvent) error {
+	ev.Handled = true
+	switch self.state {
+	case EDITING_FIELD:
+		f := self.form
+		switch {
+		case ev.MatchesPressOrRepeat("esc"):
+			f.values[f.current] = f.original_value
+			f.errors[f.current] = ""
+			self.state = EDITING
+		case ev.MatchesPressOrRepeat("enter"):
+			if f.validate(f.current) {
+				self.state = EDITING
+			}
+		case ev.MatchesPressOrRepeat("backspace"):
+			if r := []rune(f.values[f.current]); len(r) > 0 {
+				f.values[f.current] = string(r[:len(r)-1])
+			}
+		case ev.MatchesPressOrRepeat("ctrl+u"):
+			f.values[f.current] = ""
+		default:
+			ev.Handled = false
+			return nil
+		}
+		return self.draw_screen()
+	case EDITING:
+		f := self.form
+		switch {
+		case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c"):
+			self.state, self.form = BROWSING, nil
+		case ev.MatchesPressOrRepeat("ctrl+s"):
+			self.save_form()
+		case ev.MatchesPressOrRepeat("enter"):
+			f.original_value = f.values[f.current]
+			self.state = EDITING_FIELD
+		case ev.MatchesPressOrRepeat("tab"):
+			self.move_form(1)
+		case ev.MatchesPressOrRepeat("shift+tab"):
+			self.move_form(-1)
+		default:
+			if !self.handle_movement(ev, &f.current, len(f.values)) {
+				ev.Handled = false
+				return nil
+			}
+		}
+		return self.draw_screen()
+	case PROMPTING_FOR_HOST:
+		switch {
+		case ev.MatchesPressOrRepeat("esc"):
+			self.state = BROWSING
+		case ev.MatchesPressOrRepeat("enter"):
+			if dest := strings.TrimSpace(self.prompt); dest != "" {
+				self.show_preview(dest)
+			}
+		case ev.MatchesPressOrRepeat("backspace"):
+			if r := []rune(self.prompt); len(r) > 0 {
+				self.prompt = string(r[:len(r)-1])
+			}
+		default:
+			ev.Handled = false
+			return nil
+		}
+		return self.draw_screen()
+	case PREVIEWING:
+		switch {
+		case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("q"):
+			self.state, self.current, self.scroll = BROWSING, 0, 0
+			self.select_host_for(self.preview_of)
+		default:
+			if !self.handle_movement(ev, &self.current, len(self.preview)) {
+				ev.Handled = false
+				return nil
+			}
+		}
+		return self.draw_screen()
+	case CONFIRMING_REMOVAL:
+		self.state = BROWSING
+		if ev.MatchesPressOrRepeat("y") || ev.MatchesPressOrRepeat("shift+y") {
+			b := self.hosts()[self.current]
+			self.file.Remove(b)
+			self.current = min(self.current, max(0, len(self.hosts())-1))
+			self.save("Removed " + b.Header.Key + " " + b.Header.Value)
+		}
+		return self.draw_screen()
+	}
+	self.status = ""
+	hosts := self.hosts()
+	switch {
+	case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("q") || ev.MatchesPressOrRepeat("ctrl+c"):
+		self.lp.Quit(0)
+		return nil
+	case ev.MatchesPressOrRepeat("e") || ev.MatchesPressOrRepeat("enter"):
+		if len(hosts) > 0 {
+			self.form = new_form(hosts[self.current])
+			self.state = EDITING
+		}
+	case ev.MatchesPressOrRepeat("a"):
+		self.form = new_form(nil)
+		self.form.original_value = ""
+		self.state = EDITING_FIELD
+	case ev.MatchesPressOrRepeat("d") || ev.MatchesPressOrRepeat("delete"):
+		if len(hosts) > 0 {
+			self.state = CONFIRMING_REMOVAL
+		}
+	case ev.MatchesPressOrRepeat("p"):
+		self.prompt = ""
+		if len(hosts) > 0 {
+			self.prompt = preview_host(hosts[self.current])
+		}
+		self.state = PROMPTING_FOR_HOST
+	default:
+		if !self.handle_movement(ev, &self.current, len(hosts)) {
+			ev.Handled = false
+			return nil
+		}
+	}
+	return self.draw_screen()
+}
+
+func (self *handler) move_form(delta int) {
+	f := self.form
+	f.current = (f.current + delta + len(f.values)) % len(f.values)
+}
+
+// Select the first host block that applies to destination
+func (self *handler) select_host_for(destination string) {
+	_, host := SplitUserHost(destination)
+	for i, b := range self.hosts() {
+		if !b.IsMatch() && match_pattern_list(host, b.Patterns()...) {
+			self.current = i
+			return
+		}
+	}
+}
+
+func (self *handler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
+	switch self.state {
+	case EDITING_FIELD:
+		self.form.values[self.form.current] += text
+	case PROMPTING_FOR_HOST:
+		self.prompt += text
+	default:
+		return nil
+	}
+	return self.draw_screen()
+}
+
+func run_ui(file *File, destination string) (rc int, err error) {
+	lp, err := loop.New()
+	if err != nil {
+		return 1, err
+	}
+	h := &handler{lp: lp, file: file}
+	if destination != "" {
+		h.show_preview(destination)
+	}
+	lp.OnInitialize = func() (string, error) {
+		lp.AllowLineWrapping(false)
+		lp.SetWindowTitle("SSH configuration")
+		return "", h.draw_screen()
+	}
+	lp.OnFinalize = func() string {
+		lp.SetCursorVisible(true)
+		return ""
+	}
+	lp.OnResize = func(_, _ loop.ScreenSize) error { return h.draw_screen() }
+	lp.OnKeyEvent = h.on_key_event
+	lp.OnText = h.on_text
+	if err = lp.Run(); err != nil {
+		return 1, err
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return 1, nil
+	}
+	return lp.ExitCode(), nil
+}
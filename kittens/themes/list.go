@@ -4,6 +4,7 @@ package themes
 
 import (
 	"fmt"
+	"strings"
 
 	"kitty/tools/themes"
 	"kitty/tools/utils"
@@ -50,11 +51,34 @@ func limit_lengths(text string) string {
 	return t + "…"
 }
 
-func (self *ThemesList) UpdateThemes(themes *themes.Themes) {
-	self.themes, self.all_themes = themes, themes
-	if self.current_search != "" {
-		self.themes = self.all_themes.Copy()
-		self.display_strings = utils.Map(limit_lengths, self.themes.ApplySearch(self.current_search))
+// Split a search query into the expression to fuzzy match against theme
+// names and the tags to filter by, which are the words starting with #
+func parse_search_query(query string) (expression string, tags []string) {
+	words := strings.Fields(query)
+	rest := make([]string, 0, len(words))
+	for _, w := range words {
+		if tag, is_tag := strings.CutPrefix(w, "#"); is_tag {
+			if tag != "" {
+				tags = append(tags, strings.ToLower(tag))
+			}
+		} else {
+			rest = append(rest, w)
+		}
+	}
+	return strings.Join(rest, " "), tags
+}
+
+func (self *ThemesList) UpdateThemes(all_themes *themes.Themes) {
+	self.themes, self.all_themes = all_themes, all_themes
+	expression, tags := parse_search_query(self.current_search)
+	if len(tags) > 0 {
+		self.themes = self.all_themes.Filtered(func(t *themes.Theme) bool { return t.MatchesTags(tags...) })
+	}
+	if expression != "" {
+		if self.themes == self.all_themes {
+			self.themes = self.all_themes.Copy()
+		}
+		self.display_strings = utils.Map(limit_lengths, self.themes.ApplySearch(expression))
 	} else {
 		self.display_strings = utils.Map(limit_lengths, self.themes.Names())
 	}
@@ -0,0 +1,108 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package themes
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kitty/tools/themes"
+	"kitty/tools/tui"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Applies the theme being previewed to all kitty windows using remote
+// control, restoring the configured colors if no theme is chosen
+type live_preview struct {
+	tdir string
+	// a conf file containing the colors configured when the kitten started
+	original_colors string
+	requests        chan string
+	done            chan bool
+	failed          bool
+}
+
+func run_set_colors(args ...string) error {
+	c := tui.RemoteControlCommand(append([]string{"set-colors", "--all"}, args...)...)
+	stderr := bytes.Buffer{}
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("Failed to set colors with error: %w and stderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func new_live_preview() (ans *live_preview, err error) {
+	// remote control over the tty cannot be used as the kitten is reading
+	// from it
+	if os.Getenv("KITTY_LISTEN_ON") == "" {
+		return nil, fmt.Errorf("Live preview requires kitty to listen for remote control connections on a socket, see the listen_on option in kitty.conf")
+	}
+	c := tui.RemoteControlCommand("get-colors", "--configured")
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	c.Stdout, c.Stderr = &stdout, &stderr
+	if err = c.Run(); err != nil {
+		return nil, fmt.Errorf("Getting the current colors failed with error: %w and stderr: %s", err, stderr.String())
+	}
+	ans = &live_preview{requests: make(chan string, 1), done: make(chan bool)}
+	if ans.tdir, err = os.MkdirTemp("", "kitty-themes-*"); err != nil {
+		return nil, err
+	}
+	ans.original_colors = filepath.Join(ans.tdir, "original.conf")
+	if err = os.WriteFile(ans.original_colors, stdout.Bytes(), 0600); err != nil {
+		os.RemoveAll(ans.tdir)
+		return nil, err
+	}
+	go ans.run()
+	return ans, nil
+}
+
+func (self *live_preview) run() {
+	defer close(self.done)
+	theme_colors := filepath.Join(self.tdir, "theme.conf")
+	for code := range self.requests {
+		if self.failed {
+			continue
+		}
+		// apply the theme on top of the original colors so that colors not
+		// specified by the theme are reset
+		if os.WriteFile(theme_colors, utils.UnsafeStringToBytes(code), 0600) != nil || run_set_colors(self.original_colors, theme_colors) != nil {
+			self.failed = true
+		}
+	}
+}
+
+// Queue the theme to be applied, replacing any theme not yet applied, so that
+// rapidly scrolling through themes does not lag
+func (self *live_preview) apply(t *themes.Theme) {
+	code, err := t.Code()
+	if err != nil {
+		return
+	}
+	for {
+		select {
+		case self.requests <- code:
+			return
+		default:
+			select {
+			case <-self.requests:
+			default:
+			}
+		}
+	}
+}
+
+// Wait for any pending theme to be applied and restore the original colors
+// unless keep_current is true
+func (self *live_preview) finish(keep_current bool) {
+	close(self.requests)
+	<-self.done
+	if !keep_current {
+		run_set_colors(self.original_colors)
+	}
+	os.RemoveAll(self.tdir)
+}
@@ -65,6 +65,11 @@ func main(_ *cli.Command, opts *Options, args []string) (rc int, err error) {
 	}
 	cv := utils.NewCachedValues("unicode-input", &CachedData{Category: "All"})
 	h := &handler{lp: lp, opts: opts, cached_data: cv.Load()}
+	if opts.LivePreview {
+		if h.live_preview, err = new_live_preview(); err != nil {
+			return 1, err
+		}
+	}
 	defer cv.Save()
 	lp.OnInitialize = func() (string, error) {
 		lp.AllowLineWrapping(false)
@@ -44,6 +44,16 @@
 kitty.conf is edited. This is most useful if you add :code:`include themes.conf`
 to your kitty.conf and then have the kitten operate only on :file:`themes.conf`,
 allowing :code:`kitty.conf` to remain unchanged.
+
+
+--live-preview
+type=bool-set
+When running interactively, apply the theme being previewed to all kitty
+windows, not just the window the kitten is running in, so that you can see how
+it looks with your actual content. If you quit without choosing a theme, the
+configured colors are restored in all windows. Requires remote control to be
+enabled and kitty to be listening on a socket, see :opt:`allow_remote_control`
+and :opt:`listen_on`.
 '''.format
 
 def main(args: List[str]) -> None:
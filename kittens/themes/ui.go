@@ -72,6 +72,12 @@ type handler struct {
 	colors_set_once  bool
 	tabs             []string
 	rl               *readline.Readline
+	// the tags toggled with keyboard shortcuts that themes must have, empty
+	// for any
+	contrast_filter, vividness_filter string
+	// nil unless the theme being previewed is applied to all windows
+	live_preview   *live_preview
+	theme_accepted bool
 }
 
 // fetching {{{
@@ -114,6 +120,10 @@ func (self *handler) finalize() {
 		t.Close()
 		self.themes_closer = nil
 	}
+	if self.live_preview != nil {
+		self.live_preview.finish(self.theme_accepted)
+		self.live_preview = nil
+	}
 }
 
 func (self *handler) initialize() {
@@ -186,6 +196,9 @@ func (self *handler) set_colors_to_current_theme() bool {
 			raw, err := t.AsEscapeCodes()
 			if err == nil {
 				self.lp.QueueWriteString(raw)
+				if self.live_preview != nil {
+					self.live_preview.apply(t)
+				}
 				return true
 			}
 		}
@@ -194,8 +207,21 @@ func (self *handler) set_colors_to_current_theme() bool {
 	return true
 }
 
+func (self *handler) matches_tag_filters(t *themes.Theme) bool {
+	tags := make([]string, 0, 2)
+	for _, x := range []string{self.contrast_filter, self.vividness_filter} {
+		if x != "" {
+			tags = append(tags, x)
+		}
+	}
+	return t.MatchesTags(tags...)
+}
+
 func (self *handler) redraw_after_category_change() {
-	self.themes_list.UpdateThemes(self.all_themes.Filtered(self.category_filters[self.current_category()]))
+	category_filter := self.category_filters[self.current_category()]
+	self.themes_list.UpdateThemes(self.all_themes.Filtered(func(t *themes.Theme) bool {
+		return category_filter(t) && self.matches_tag_filters(t)
+	}))
 	self.set_colors_to_current_theme()
 	self.draw_screen()
 }
@@ -222,6 +248,19 @@ func (self *handler) next_category(delta int) {
 	self.redraw_after_category_change()
 }
 
+func next_tag_filter(current string, choices ...string) string {
+	idx := slices.Index(choices, current) + 1
+	return choices[idx%len(choices)]
+}
+
+func tag_filter_label(x string) string {
+	if x == "" {
+		return "any"
+	}
+	x, _, _ = strings.Cut(x, "-")
+	return x
+}
+
 func (self *handler) next(delta int, allow_wrapping bool) {
 	if self.themes_list.Next(delta, allow_wrapping) {
 		self.set_colors_to_current_theme()
@@ -288,6 +327,18 @@ func (self *handler) on_browsing_key_event(ev *loop.KeyEvent) error {
 		self.start_search()
 		return nil
 	}
+	if ev.MatchesPressOrRepeat("o") {
+		ev.Handled = true
+		self.contrast_filter = next_tag_filter(self.contrast_filter, "", themes.TAG_HIGH_CONTRAST, themes.TAG_LOW_CONTRAST)
+		self.redraw_after_category_change()
+		return nil
+	}
+	if ev.MatchesPressOrRepeat("v") {
+		ev.Handled = true
+		self.vividness_filter = next_tag_filter(self.vividness_filter, "", themes.TAG_VIVID, themes.TAG_MUTED)
+		self.redraw_after_category_change()
+		return nil
+	}
 	if ev.MatchesPressOrRepeat("c") || ev.MatchesPressOrRepeat("enter") {
 		ev.Handled = true
 		if self.themes_list == nil || self.themes_list.Len() == 0 {
@@ -353,6 +404,8 @@ func (self *handler) draw_bottom_bar() {
 	}
 	draw_tab("search (/)", "s")
 	draw_tab("accept (⏎)", "c")
+	draw_tab("contrast: "+tag_filter_label(self.contrast_filter), "o")
+	draw_tab("vividness: "+tag_filter_label(self.vividness_filter), "v")
 	self.lp.QueueWriteString("\x1b[m")
 }
 
@@ -475,6 +528,8 @@ func (self *handler) draw_theme_demo() {
 		self.lp.PrintStyled("italic", center_string(theme.Author(), sz))
 		next_line()
 	}
+	self.lp.PrintStyled("dim", center_string(strings.Join(utils.Map(func(x string) string { return "#" + x }, theme.Tags()), " "), sz))
+	next_line()
 	if theme.Blurb() != "" {
 		next_line()
 		write_para(theme.Blurb())
@@ -505,6 +560,7 @@ func (self *handler) on_accepting_key_event(ev *loop.KeyEvent) error {
 	if ev.MatchesPressOrRepeat("p") {
 		ev.Handled = true
 		self.themes_list.CurrentTheme().SaveInDir(utils.ConfigDir())
+		self.theme_accepted = true
 		self.update_recent()
 		self.lp.Quit(0)
 		return nil
@@ -512,6 +568,7 @@ func (self *handler) on_accepting_key_event(ev *loop.KeyEvent) error {
 	if ev.MatchesPressOrRepeat("m") {
 		ev.Handled = true
 		self.themes_list.CurrentTheme().SaveInConf(utils.ConfigDir(), self.opts.ReloadIn, self.opts.ConfigFileName)
+		self.theme_accepted = true
 		self.update_recent()
 		self.lp.Quit(0)
 		return nil
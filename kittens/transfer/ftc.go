@@ -133,6 +133,8 @@ type FileTransmissionCommand struct {
 	Mtime       time.Duration `json:"mod,omitempty"`
 	Permissions fs.FileMode   `json:"prm,omitempty"`
 	Size        int64         `json:"sz,omitempty" default:"-1"`
+	Owner       string        `json:"own,omitempty" encoding:"base64"`
+	Xattrs      string        `json:"xa,omitempty" encoding:"base64"`
 
 	Data []byte `json:"d,omitempty"`
 }
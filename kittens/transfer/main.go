@@ -54,6 +54,15 @@ func main(cmd *cli.Command, opts *Options, args []string) (rc int, err error) {
 	if len(args) == 0 {
 		return 1, fmt.Errorf("Must specify at least one file to transfer")
 	}
+	if opts.OverSsh != "" {
+		if err = transfer_over_ssh(opts, args); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+	if _, err = ParsePreserve(opts.Preserve); err != nil {
+		return 1, err
+	}
 	switch opts.Direction {
 	case "send", "download":
 		err, rc = send_main(opts, args)
@@ -9,7 +9,8 @@
 help_text = '''\
 Transfer files over the TTY device. Can be used to send files between any two
 computers provided there is a TTY connection between them, such as over SSH.
-Supports copying files, directories (recursively), symlinks and hardlinks.  Can
+Supports copying files, directories (recursively), symlinks and hardlinks,
+preserving permissions, ownership and extended attributes.  Can
 even use an rsync like protocol to copy only changes between files.  When
 copying multiple files, use the --confirm-paths option to see what exactly will
 be copied. The easiest way to use this kitten is to first ssh into the remote
@@ -106,6 +107,21 @@ def option_text() -> str:
 read the actual password.
 
 
+--over-ssh
+Instead of using the TTY file transfer protocol, copy files between this
+computer and the specified SSH destination, such as :code:`user@host`, using
+:program:`scp`, falling back to :program:`sftp` for servers that have disabled
+scp. Useful when the remote computer lacks the kitten or file transfer is not
+possible over the TTY. Run it on the local computer, when run in kitty the
+connection already made to the destination by the :doc:`ssh kitten
+</kittens/ssh>` is re-used. The :option:`--direction` is relative to this
+computer, so :code:`send` copies local files to the remote computer and remote
+paths are relative to the home directory. Only :code:`normal` mode is
+supported, directories are copied recursively, permissions and modification
+times are preserved and options controlling the file transfer protocol are
+ignored.
+
+
 --confirm-paths -c
 type=bool-set
 Before actually transferring files, show a mapping of local file names to remote
@@ -118,6 +134,35 @@ def option_text() -> str:
 update it to match the file on the sending side, potentially saving lots of
 bandwidth and also automatically resuming partial transfers. Note that this will
 actually degrade performance on fast links or with small files, so use with care.
+
+
+--exclude
+type=list
+A glob pattern for files and directories to skip when sending the contents of
+directories. The patterns use the same syntax as :file:`.gitignore` files and
+are matched against paths relative to the directory being sent. Can be
+specified multiple times.
+
+
+--respect-ignore-files
+type=bool-set
+When sending the contents of directories, skip the files and directories
+ignored by the rules in :file:`.gitignore` and :file:`.ignore` files, as well
+as :file:`.git` directories.
+
+
+--preserve
+default=symlinks,hardlinks,mode
+A comma separated list of file properties to preserve. :code:`symlinks` copies
+symbolic links as links, instead of the files they point to. :code:`hardlinks`
+re-creates hard links between the copied files, instead of making separate
+copies. :code:`mode` preserves the permission bits of files. :code:`ownership`
+preserves the user and group that own files, matched by name, which usually
+requires super-user privileges on the receiving computer, except for files
+owned by the receiving user. :code:`xattrs` preserves extended attributes. Use
+:code:`all` to preserve everything and :code:`none` to preserve nothing but
+modification times. Properties that could not be preserved are listed at the
+end of the transfer.
 '''
 
 
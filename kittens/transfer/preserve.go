@@ -0,0 +1,228 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package transfer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"kitty/tools/cli/markup"
+
+	"golang.org/x/sys/unix"
+)
+
+var _ = fmt.Print
+
+// The file properties to preserve when transferring files
+type Preserve struct {
+	Symlinks, Hardlinks, Mode, Ownership, Xattrs bool
+}
+
+var default_preserve = Preserve{Symlinks: true, Hardlinks: true, Mode: true}
+
+// Parse a comma separated list of file properties to preserve, an empty
+// list means the default properties
+func ParsePreserve(spec string) (ans Preserve, err error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return default_preserve, nil
+	}
+	for _, x := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(x) {
+		case "symlinks":
+			ans.Symlinks = true
+		case "hardlinks":
+			ans.Hardlinks = true
+		case "mode":
+			ans.Mode = true
+		case "ownership":
+			ans.Ownership = true
+		case "xattrs":
+			ans.Xattrs = true
+		case "all":
+			ans = Preserve{true, true, true, true, true}
+		case "none", "":
+		default:
+			return ans, fmt.Errorf("Unknown file property to preserve: %#v, must be one of: symlinks, hardlinks, mode, ownership, xattrs, all or none", x)
+		}
+	}
+	return
+}
+
+// The owner of a file as user:group names, falling back to numeric ids for
+// users and groups that have no names
+func file_owner(s fs.FileInfo) string {
+	st, ok := s.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	uid, gid := strconv.FormatUint(uint64(st.Uid), 10), strconv.FormatUint(uint64(st.Gid), 10)
+	if u, err := user.LookupId(uid); err == nil {
+		uid = u.Username
+	}
+	if g, err := user.LookupGroupId(gid); err == nil {
+		gid = g.Name
+	}
+	return uid + ":" + gid
+}
+
+func lookup_owner(owner string) (uid, gid int, err error) {
+	uname, gname, found := strings.Cut(owner, ":")
+	if !found {
+		return 0, 0, fmt.Errorf("Invalid owner: %#v", owner)
+	}
+	if u, lerr := user.Lookup(uname); lerr == nil {
+		uid, err = strconv.Atoi(u.Uid)
+	} else if uid, err = strconv.Atoi(uname); err != nil {
+		return 0, 0, fmt.Errorf("Unknown user: %s", uname)
+	}
+	if err != nil {
+		return
+	}
+	if g, lerr := user.LookupGroup(gname); lerr == nil {
+		gid, err = strconv.Atoi(g.Gid)
+	} else if gid, err = strconv.Atoi(gname); err != nil {
+		return 0, 0, fmt.Errorf("Unknown group: %s", gname)
+	}
+	return
+}
+
+// Change the owner of path, without following symlinks, to owner as
+// returned by file_owner(). Does nothing if the owner is already correct, so
+// that it works without privileges for files owned by the current user.
+func set_owner(path, owner string) error {
+	uid, gid, err := lookup_owner(owner)
+	if err != nil {
+		return err
+	}
+	if s, err := os.Lstat(path); err == nil {
+		if st, ok := s.Sys().(*syscall.Stat_t); ok && int(st.Uid) == uid && int(st.Gid) == gid {
+			return nil
+		}
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// Serialize extended attributes as a JSON object mapping names to base64
+// encoded values
+func encode_xattrs(xattrs map[string][]byte) string {
+	if len(xattrs) == 0 {
+		return ""
+	}
+	m := make(map[string]string, len(xattrs))
+	for k, v := range xattrs {
+		m[k] = base64.StdEncoding.EncodeToString(v)
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func decode_xattrs(serialized string) (map[string][]byte, error) {
+	if serialized == "" {
+		return nil, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(serialized), &m); err != nil {
+		return nil, fmt.Errorf("Invalid extended attributes: %w", err)
+	}
+	ans := make(map[string][]byte, len(m))
+	for k, v := range m {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value for the extended attribute %s: %w", k, err)
+		}
+		ans[k] = b
+	}
+	return ans, nil
+}
+
+func xattr_buffer(size func([]byte) (int, error)) ([]byte, error) {
+	for {
+		sz, err := size(nil)
+		if err != nil || sz == 0 {
+			return nil, err
+		}
+		buf := make([]byte, sz)
+		n, err := size(buf)
+		if errors.Is(err, unix.ERANGE) {
+			// the attribute grew between the two calls
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// Read the extended attributes of path, without following symlinks,
+// serialized with encode_xattrs()
+func read_xattrs(path string) (string, error) {
+	names, err := xattr_buffer(func(b []byte) (int, error) { return unix.Llistxattr(path, b) })
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return "", nil
+		}
+		return "", err
+	}
+	ans := make(map[string][]byte)
+	for _, name := range bytes.Split(bytes.TrimRight(names, "\x00"), []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		n := string(name)
+		val, err := xattr_buffer(func(b []byte) (int, error) { return unix.Lgetxattr(path, n, b) })
+		if err != nil {
+			return "", fmt.Errorf("Failed to read the extended attribute %s with error: %w", n, err)
+		}
+		ans[n] = val
+	}
+	return encode_xattrs(ans), nil
+}
+
+// Set the extended attributes serialized with encode_xattrs() on path,
+// without following symlinks
+func write_xattrs(path, serialized string) error {
+	xattrs, err := decode_xattrs(serialized)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for name, val := range xattrs {
+		if err := unix.Lsetxattr(path, name, val, 0); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// A file some of whose properties could not be preserved
+type not_preserved struct {
+	name    string
+	reasons []string
+}
+
+func print_preservation_summary(ctx *markup.Context, entries []not_preserved) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Some properties of %d files could not be preserved\n", len(entries))
+	for _, e := range entries {
+		fmt.Fprintln(os.Stderr, ctx.Yellow(e.name))
+		for _, r := range e.reasons {
+			fmt.Fprintln(os.Stderr, ` `, r)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestPreserve(t *testing.T) {
+	for spec, expected := range map[string]Preserve{
+		"":                 default_preserve,
+		"none":             {},
+		"all":              {true, true, true, true, true},
+		"mode, ownership":  {Mode: true, Ownership: true},
+		"xattrs,symlinks,": {Symlinks: true, Xattrs: true},
+	} {
+		actual, err := ParsePreserve(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Failed to parse %#v:\n%s", spec, diff)
+		}
+	}
+	if _, err := ParsePreserve("mode,acls"); err == nil {
+		t.Fatalf("Parsing an unknown property did not fail")
+	}
+
+	xattrs := map[string][]byte{"user.a": []byte("1\x00\xff"), "user.b": nil}
+	decoded, err := decode_xattrs(encode_xattrs(xattrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string][]byte{"user.a": []byte("1\x00\xff"), "user.b": {}}, decoded); diff != "" {
+		t.Fatalf("Extended attributes not round tripped:\n%s", diff)
+	}
+	if encode_xattrs(nil) != "" {
+		t.Fatalf("No extended attributes not encoded as the empty string")
+	}
+
+	tdir := t.TempDir()
+	r := filepath.Join(tdir, "r")
+	os.WriteFile(r, nil, 0o600)
+	s, _ := os.Lstat(r)
+	if err = set_owner(r, file_owner(s)); err != nil {
+		t.Fatalf("Setting the owner of a file to its current owner failed with error: %s", err)
+	}
+	if _, _, err = lookup_owner("no-such-user-for-kitty-tests:0"); err == nil {
+		t.Fatalf("Looking up an unknown user did not fail")
+	}
+
+	b := filepath.Join(tdir, "b")
+	os.Mkdir(b, 0o700)
+	os.WriteFile(filepath.Join(b, "f"), nil, 0o600)
+	os.Symlink("f", filepath.Join(b, "s"))
+	os.Link(filepath.Join(b, "f"), filepath.Join(b, "h"))
+	os.Symlink("..", filepath.Join(b, "loop"))
+	os.Symlink("missing", filepath.Join(b, "broken"))
+	types := func(preserve string) map[string]FileType {
+		files, err := files_for_send(&Options{Preserve: preserve}, []string{b, "/dest"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ans := make(map[string]FileType, len(files))
+		for _, f := range files {
+			if _, found := ans[f.remote_path]; found {
+				t.Fatalf("The file %s was sent more than once", f.remote_path)
+			}
+			ans[f.remote_path] = f.file_type
+		}
+		return ans
+	}
+	actual := types("none")
+	// the loop symlink points to tdir, which is followed, but not the loop
+	// symlink inside it, as tdir has already been sent
+	for path, ft := range map[string]FileType{
+		"/dest/b/s": FileType_regular, "/dest/b/h": FileType_regular, "/dest/b/broken": FileType_symlink,
+		"/dest/b/loop": FileType_directory, "/dest/b/loop/r": FileType_regular,
+	} {
+		if actual[path] != ft {
+			t.Fatalf("The file %s has type %s instead of %s in: %v", path, actual[path], ft, actual)
+		}
+	}
+	if _, found := actual["/dest/b/loop/b/loop/r"]; found {
+		t.Fatalf("A symlink loop was followed: %v", actual)
+	}
+	actual = types("symlinks,hardlinks")
+	if actual["/dest/b/s"] != FileType_symlink || actual["/dest/b/loop"] != FileType_symlink {
+		t.Fatalf("Symlinks were followed: %v", actual)
+	}
+	if actual["/dest/b/h"] != FileType_link && actual["/dest/b/f"] != FileType_link {
+		t.Fatalf("Hardlinks were not detected: %v", actual)
+	}
+}
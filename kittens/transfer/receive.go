@@ -144,6 +144,8 @@ type remote_file struct {
 	decompressor                 utils.StreamDecompressor
 	compression_type             Compression
 	remote_symlink_value         string
+	owner, xattrs                string
+	not_preserved                []string
 	actual_file                  output_file
 	patch_file                   patch_file
 }
@@ -249,13 +251,28 @@ func syscall_mode(i os.FileMode) (o uint32) {
 	return
 }
 
-func (self *remote_file) apply_metadata() {
+func (self *remote_file) apply_metadata(p Preserve) {
+	// ownership must be changed before the mode as changing it can clear
+	// the set-user-id and set-group-id bits
+	if p.Ownership && self.owner != "" {
+		if err := set_owner(self.expanded_local_path, self.owner); err != nil {
+			self.not_preserved = append(self.not_preserved, "Ownership: "+err.Error())
+		}
+	}
+	if p.Xattrs && self.xattrs != "" {
+		if err := write_xattrs(self.expanded_local_path, self.xattrs); err != nil {
+			self.not_preserved = append(self.not_preserved, "Extended attributes: "+err.Error())
+		}
+	}
 	t := unix.NsecToTimespec(int64(self.mtime))
 	for {
 		if err := unix.UtimesNanoAt(unix.AT_FDCWD, self.expanded_local_path, []unix.Timespec{t, t}, unix.AT_SYMLINK_NOFOLLOW); err == nil || !(errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN)) {
 			break
 		}
 	}
+	if !p.Mode {
+		return
+	}
 	if self.ftype == FileType_symlink {
 		for {
 			if err := unix.Fchmodat(unix.AT_FDCWD, self.expanded_local_path, syscall_mode(self.permissions), unix.AT_SYMLINK_NOFOLLOW); err == nil || !(errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN)) {
@@ -267,6 +284,31 @@ func (self *remote_file) apply_metadata() {
 	}
 }
 
+// Create a copy of the already received file src, used instead of a link
+// when links are not being preserved
+func (self *remote_file) copy_from(src *remote_file) error {
+	if err := os.MkdirAll(filepath.Dir(self.expanded_local_path), 0o755); err != nil {
+		return err
+	}
+	s, err := os.Open(src.expanded_local_path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	os.Remove(self.expanded_local_path)
+	d, err := os.Create(self.expanded_local_path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(d, s); err != nil {
+		d.Close()
+		return err
+	}
+	self.ftype = FileType_regular
+	self.permissions, self.owner, self.xattrs = src.permissions, src.owner, src.xattrs
+	return d.Close()
+}
+
 func new_remote_file(opts *Options, ftc *FileTransmissionCommand, file_id uint64) (*remote_file, error) {
 	spec_id, err := strconv.Atoi(ftc.File_id)
 	if err != nil {
@@ -275,7 +317,7 @@ func new_remote_file(opts *Options, ftc *FileTransmissionCommand, file_id uint64
 	ans := &remote_file{
 		expected_size: ftc.Size, ftype: ftc.Ftype, mtime: ftc.Mtime, spec_id: spec_id, file_id: strconv.FormatUint(file_id, 10),
 		permissions: ftc.Permissions, remote_path: ftc.Name, display_name: wcswidth.StripEscapeCodes(ftc.Name),
-		remote_id: ftc.Status, remote_target: string(ftc.Data), parent: ftc.Parent,
+		remote_id: ftc.Status, remote_target: string(ftc.Data), parent: ftc.Parent, owner: ftc.Owner, xattrs: ftc.Xattrs,
 	}
 	compression_capable := ftc.Ftype == FileType_regular && ftc.Size > 4096 && should_be_compressed(ftc.Name, opts.Compress)
 	if compression_capable {
@@ -486,6 +528,7 @@ func (self *handler) abort_with_error(err error, delay ...time.Duration) {
 	self.lp.Println(`Waiting to ensure terminal cancels transfer, will quit in no more than`, d)
 	self.manager.send(FileTransmissionCommand{Action: Action_cancel}, self.lp.QueueWriteString)
 	self.manager.state = state_canceled
+	self.lp.SetProgress(loop.PROGRESS_ERROR, 100)
 	self.lp.AddTimer(d, false, self.do_error_quit)
 }
 
@@ -496,6 +539,10 @@ func (self *handler) do_error_quit(loop.IdType) error {
 
 func (self *manager) finalize_transfer() (err error) {
 	self.transfer_done = true
+	preserve, err := ParsePreserve(self.cli_opts.Preserve)
+	if err != nil {
+		return err
+	}
 	rid_map := make(map[string]*remote_file)
 	for _, f := range self.files {
 		rid_map[f.remote_id] = f
@@ -511,6 +558,12 @@ func (self *manager) finalize_transfer() (err error) {
 			if !found {
 				return fmt.Errorf(`Hard link with remote id: {%s} not found`, f.remote_target)
 			}
+			if !preserve.Hardlinks {
+				if err = f.copy_from(tgt); err != nil {
+					return fmt.Errorf(`Failed to copy %s with error: %w`, tgt.expanded_local_path, err)
+				}
+				break
+			}
 			if err = os.MkdirAll(filepath.Dir(f.expanded_local_path), 0o755); err == nil {
 				os.Remove(f.expanded_local_path)
 				err = os.Link(tgt.expanded_local_path, f.expanded_local_path)
@@ -525,6 +578,12 @@ func (self *manager) finalize_transfer() (err error) {
 				if !found {
 					return fmt.Errorf(`Symbolic link with remote id: {%s} not found`, f.remote_target)
 				}
+				if !preserve.Symlinks && tgt.ftype == FileType_regular {
+					if err = f.copy_from(tgt); err != nil {
+						return fmt.Errorf(`Failed to copy %s with error: %w`, tgt.expanded_local_path, err)
+					}
+					break
+				}
 				lt = tgt.expanded_local_path
 				if !strings.HasPrefix(f.remote_symlink_value, "/") {
 					if lt, err = filepath.Rel(filepath.Dir(f.expanded_local_path), lt); err != nil {
@@ -542,8 +601,11 @@ func (self *manager) finalize_transfer() (err error) {
 			if err = os.Symlink(lt, f.expanded_local_path); err != nil {
 				return fmt.Errorf(`Failed to create symlink with error: %w`, err)
 			}
+			if !preserve.Symlinks {
+				f.not_preserved = append(f.not_preserved, "Symbolic link created as a link as it could not be followed")
+			}
 		}
-		f.apply_metadata()
+		f.apply_metadata(preserve)
 	}
 	return
 }
@@ -781,7 +843,7 @@ func (self *handler) print_check_paths() {
 		}
 		self.lp.Println(df.display_name, "→", lpath)
 	}
-	self.lp.Println(fmt.Sprintf(`Transferring %d file(s) of total size: %s`, len(self.manager.files), humanize.Size(self.manager.progress_tracker.total_size_of_all_files)))
+	self.lp.Println(fmt.Sprintf(`Transferring %d file(s) of total size: %s`, len(self.manager.files), humanize.CurrentLocale().Size(uint64(self.manager.progress_tracker.total_size_of_all_files))))
 	self.print_continue_msg()
 }
 
@@ -883,6 +945,7 @@ func (self *handler) on_file_transfer_response(ftc *FileTransmissionCommand) (er
 	}
 	if self.manager.transfer_done {
 		self.manager.send(FileTransmissionCommand{Action: Action_finish}, self.lp.QueueWriteString)
+		self.lp.SetUrgencyHint("Finished receiving files", "")
 		self.quit_after_write_code = 0
 		self.refresh_progress(0)
 	} else if self.transmit_started {
@@ -991,6 +1054,7 @@ func (self *handler) draw_files() {
 	} else {
 		self.lp.Println(`File data transfer has not yet started`)
 	}
+	report_progress(self.lp, p.total_transferred, p.total_bytes_to_transfer, is_complete, is_complete && self.quit_after_write_code != 0)
 }
 
 func (self *handler) schedule_progress_update(delay time.Duration) {
@@ -1059,7 +1123,7 @@ func (self *handler) on_key_event(ev *loop.KeyEvent) error {
 }
 
 func receive_loop(opts *Options, spec []string, dest string) (err error, rc int) {
-	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors)
+	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors, loop.FocusTracking)
 	if err != nil {
 		return err, 1
 	}
@@ -1157,6 +1221,13 @@ func receive_loop(opts *Options, spec []string, dest string) (err error, rc int)
 	if tsf > 0 && dsz+ssz > 0 && rc == 0 {
 		print_rsync_stats(tsf, dsz, ssz)
 	}
+	np := []not_preserved{}
+	for _, f := range handler.manager.files {
+		if len(f.not_preserved) > 0 {
+			np = append(np, not_preserved{f.expanded_local_path, f.not_preserved})
+		}
+	}
+	print_preservation_summary(handler.ctx, np)
 	return
 }
 
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -94,6 +95,8 @@ type File struct {
 	mtime                                                 time.Time
 	file_size, bytes_to_transmit                          int64
 	permissions                                           fs.FileMode
+	owner, xattrs                                         string
+	not_preserved                                         []string
 	remote_path                                           string
 	rsync_capable, compression_capable                    bool
 	remote_final_path                                     string
@@ -135,41 +138,117 @@ func NewFile(opts *Options, local_path, expanded_local_path string, file_id int,
 	return &ans
 }
 
+// Read the metadata, other than size and mtime, to send for this file
+func (self *File) read_extended_metadata(p Preserve) {
+	if !p.Mode {
+		self.permissions = 0
+	}
+	if p.Ownership {
+		self.owner = file_owner(self.stat_result)
+	}
+	if p.Xattrs {
+		if x, err := read_xattrs(self.expanded_local_path); err == nil {
+			self.xattrs = x
+		} else {
+			self.not_preserved = append(self.not_preserved, "Extended attributes: "+err.Error())
+		}
+	}
+}
+
+func walk_options(opts *Options) utils.WalkOptions {
+	return utils.WalkOptions{Exclude: opts.Exclude, RespectIgnoreFiles: opts.RespectIgnoreFiles}
+}
+
 func process(opts *Options, paths []string, remote_base string, counter *int) (ans []*File, err error) {
+	preserve, err := ParsePreserve(opts.Preserve)
+	if err != nil {
+		return nil, err
+	}
+	add := func(x, expanded string, s fs.FileInfo, remote_base string, note string) {
+		var ft FileType
+		switch {
+		case s.IsDir():
+			ft = FileType_directory
+		case s.Mode()&fs.ModeSymlink == fs.ModeSymlink:
+			ft = FileType_symlink
+		case s.Mode().IsRegular():
+			ft = FileType_regular
+		default:
+			return
+		}
+		*counter += 1
+		f := NewFile(opts, x, expanded, *counter, s, remote_base, ft)
+		if note != "" {
+			f.not_preserved = append(f.not_preserved, note)
+		}
+		f.read_extended_metadata(preserve)
+		ans = append(ans, f)
+	}
+	// when not preserving symlinks, send the files they point to instead
+	follow := func(expanded string, s fs.FileInfo) (fs.FileInfo, string) {
+		if preserve.Symlinks || s.Mode()&fs.ModeSymlink == 0 {
+			return s, ""
+		}
+		ts, err := os.Stat(expanded)
+		if err != nil {
+			return s, "Symbolic link sent as a link as it could not be followed: " + err.Error()
+		}
+		return ts, ""
+	}
+	visited_dirs := utils.NewSet[FileHash]()
+	var add_dir func(x, expanded string, s fs.FileInfo, remote_base string) error
+	add_dir = func(x, expanded string, s fs.FileInfo, remote_base string) error {
+		if st, ok := s.Sys().(*syscall.Stat_t); ok {
+			// followed symlinks can create loops
+			fh := FileHash{uint64(st.Dev), st.Ino}
+			if visited_dirs.Has(fh) {
+				return nil
+			}
+			visited_dirs.Add(fh)
+		}
+		new_remote_base := remote_base
+		if new_remote_base != "" {
+			new_remote_base = strings.TrimRight(new_remote_base, "/") + "/" + filepath.Base(x) + "/"
+		} else {
+			new_remote_base = strings.TrimRight(filepath.ToSlash(x), "/") + "/"
+		}
+		entries, err := utils.WalkFiltered(expanded, walk_options(opts))
+		if err != nil {
+			return fmt.Errorf("Failed to read the directory %s with error: %w", x, err)
+		}
+		for _, e := range entries {
+			es, err := e.Info()
+			if err != nil {
+				return fmt.Errorf("Failed to stat %s with error: %w", e.Path, err)
+			}
+			rb := new_remote_base
+			if parent := path.Dir(e.RelPath); parent != "." {
+				rb += parent + "/"
+			}
+			ex := filepath.Join(x, filepath.FromSlash(e.RelPath))
+			es, note := follow(e.Path, es)
+			add(ex, e.Path, es, rb, note)
+			if es.IsDir() && e.Type()&fs.ModeSymlink != 0 {
+				// the walk does not descend into symlinks to directories
+				if err = add_dir(ex, e.Path, es, rb); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
 	for _, x := range paths {
 		expanded := expand_home(x)
 		s, err := os.Lstat(expanded)
 		if err != nil {
 			return ans, fmt.Errorf("Failed to stat %s with error: %w", x, err)
 		}
+		s, note := follow(expanded, s)
+		add(x, expanded, s, remote_base, note)
 		if s.IsDir() {
-			*counter += 1
-			ans = append(ans, NewFile(opts, x, expanded, *counter, s, remote_base, FileType_directory))
-			new_remote_base := remote_base
-			if new_remote_base != "" {
-				new_remote_base = strings.TrimRight(new_remote_base, "/") + "/" + filepath.Base(x) + "/"
-			} else {
-				new_remote_base = strings.TrimRight(filepath.ToSlash(x), "/") + "/"
-			}
-			contents, err := os.ReadDir(expanded)
-			if err != nil {
-				return ans, fmt.Errorf("Failed to read the directory %s with error: %w", x, err)
-			}
-			new_paths := make([]string, len(contents))
-			for i, y := range contents {
-				new_paths[i] = filepath.Join(x, y.Name())
-			}
-			new_ans, err := process(opts, new_paths, new_remote_base, counter)
-			if err != nil {
+			if err = add_dir(x, expanded, s, remote_base); err != nil {
 				return ans, err
 			}
-			ans = append(ans, new_ans...)
-		} else if s.Mode()&fs.ModeSymlink == fs.ModeSymlink {
-			*counter += 1
-			ans = append(ans, NewFile(opts, x, expanded, *counter, s, remote_base, FileType_symlink))
-		} else if s.Mode().IsRegular() {
-			*counter += 1
-			ans = append(ans, NewFile(opts, x, expanded, *counter, s, remote_base, FileType_regular))
 		}
 	}
 	return
@@ -219,8 +298,9 @@ func files_for_send(opts *Options, args []string) (files []*File, err error) {
 	for _, f := range files {
 		groups[f.file_hash] = append(groups[f.file_hash], f)
 	}
+	preserve, _ := ParsePreserve(opts.Preserve)
 	for _, group := range groups {
-		if len(group) > 1 {
+		if len(group) > 1 && preserve.Hardlinks {
 			for _, lf := range group[1:] {
 				lf.file_type = FileType_link
 				lf.hard_link_target = "fid:" + group[0].file_id
@@ -474,19 +554,19 @@ func render_progress_in_width(path string, p Progress, width int, ctx *markup.Co
 	unit_style := ctx.Dim(`|`)
 	sep, trail, _ := strings.Cut(unit_style, "|")
 	var ratio, rate, eta string
+	loc := humanize.CurrentLocale()
 	if p.is_complete || p.bytes_so_far >= p.total_bytes {
-		ratio = humanize.Size(uint64(p.total_bytes), humanize.SizeOptions{Separator: sep})
-		rate = humanize.Size(uint64(safe_divide(float64(p.total_bytes), p.secs_so_far)), humanize.SizeOptions{Separator: sep}) + `/s`
-		eta = ctx.Green(humanize.ShortDuration(time.Duration(float64(time.Second) * p.secs_so_far)))
+		ratio = loc.Size(uint64(p.total_bytes), humanize.SizeOptions{Separator: sep})
+		rate = loc.Size(uint64(safe_divide(float64(p.total_bytes), p.secs_so_far)), humanize.SizeOptions{Separator: sep}) + `/s`
+		eta = ctx.Green(loc.ShortDuration(time.Duration(float64(time.Second) * p.secs_so_far)))
 	} else {
-		tb := humanize.Size(p.total_bytes)
-		sval, _, _ := strings.Cut(tb, " ")
+		sval, _, _ := strings.Cut(humanize.Size(p.total_bytes), " ")
 		val, _ := strconv.ParseFloat(sval, 64)
-		ratio = humanize.FormatNumber(val*safe_divide(p.bytes_so_far, p.total_bytes)) + `/` + strings.ReplaceAll(tb, ` `, sep)
-		rate = humanize.Size(p.bytes_per_sec, humanize.SizeOptions{Separator: sep}) + `/s`
+		ratio = loc.FormatNumber(val*safe_divide(p.bytes_so_far, p.total_bytes)) + `/` + loc.Size(uint64(p.total_bytes), humanize.SizeOptions{Separator: sep})
+		rate = loc.Size(uint64(p.bytes_per_sec), humanize.SizeOptions{Separator: sep}) + `/s`
 		bytes_left := p.total_bytes - p.bytes_so_far
 		eta_seconds := safe_divide(bytes_left, p.bytes_per_sec)
-		eta = humanize.ShortDuration(time.Duration(float64(time.Second) * eta_seconds))
+		eta = loc.ShortDuration(time.Duration(float64(time.Second) * eta_seconds))
 	}
 	lft := p.spinner_char + ` `
 	max_space_for_path := width/2 - wcswidth.Stringwidth(lft)
@@ -518,6 +598,20 @@ func (self *SendHandler) render_progress(name string, p Progress) {
 	self.lp.QueueWriteString(render_progress_in_width(name, p, int(sz.WidthCells), self.ctx))
 }
 
+// Report overall progress to the terminal for display in its tab bar or taskbar
+func report_progress(lp *loop.Loop, bytes_so_far, total_bytes int64, is_complete, failed bool) {
+	switch {
+	case failed:
+		lp.SetProgress(loop.PROGRESS_ERROR, 100)
+	case is_complete:
+		lp.ClearProgress()
+	case bytes_so_far > 0 && total_bytes > 0:
+		lp.SetProgress(loop.PROGRESS_NORMAL, int(100*bytes_so_far/total_bytes))
+	default:
+		lp.SetProgress(loop.PROGRESS_INDETERMINATE, 0)
+	}
+}
+
 func (self *SendHandler) draw_progress() {
 	self.lp.AllowLineWrapping(false)
 	defer self.lp.AllowLineWrapping(true)
@@ -574,6 +668,8 @@ func (self *SendHandler) draw_progress() {
 		self.lp.QueueWriteString(`File data transfer has not yet started`)
 	}
 	self.lp.Println()
+	p := self.manager.progress_tracker
+	report_progress(self.lp, p.total_reported_progress, p.total_bytes_to_transfer, is_complete, is_complete && self.quit_after_write_code != 0)
 	self.schedule_progress_update(self.spinner.Interval())
 	self.progress_drawn = true
 }
@@ -660,7 +756,7 @@ func (self *File) metadata_command(use_rsync bool) *FileTransmissionCommand {
 	return &FileTransmissionCommand{
 		Action: Action_file, Compression: self.compression, Ftype: self.file_type,
 		Name: self.remote_path, Permissions: self.permissions, Mtime: time.Duration(self.mtime.UnixNano()),
-		File_id: self.file_id, Ttype: self.ttype,
+		File_id: self.file_id, Ttype: self.ttype, Owner: self.owner, Xattrs: self.xattrs,
 	}
 }
 
@@ -732,7 +828,13 @@ func (self *SendManager) on_file_status_update(ftc *FileTransmissionCommand) err
 			file.remote_final_path = ftc.Name
 		}
 		file.state = ACKNOWLEDGED
-		if ftc.Status == `OK` {
+		status, msg, _ := strings.Cut(ftc.Status, ":")
+		if status == `OK` {
+			// the terminal reports file properties it could not preserve
+			// as the message
+			if msg != "" {
+				file.not_preserved = append(file.not_preserved, strings.Split(msg, "; ")...)
+			}
 			if ftc.Size > 0 {
 				change := int64(ftc.Size) - file.reported_progress
 				file.reported_progress = int64(ftc.Size)
@@ -876,7 +978,7 @@ func (self *SendHandler) print_check_paths() {
 			self.ctx.Prettify(fmt.Sprintf(":%s:`%s` ", df.file_type.Color(), df.file_type.ShortText())),
 			df.display_name, ` → `, fn)
 	}
-	hsize := humanize.Size(self.manager.progress_tracker.total_bytes_to_transfer)
+	hsize := humanize.CurrentLocale().Size(uint64(self.manager.progress_tracker.total_bytes_to_transfer))
 	if n := len(self.manager.files); n == 1 {
 		self.lp.Println(fmt.Sprintf(`Transferring %d file of total size: %s`, n, hsize))
 	} else {
@@ -1069,6 +1171,9 @@ func (self *SendHandler) transfer_finished() {
 	}
 	self.transfer_finish_sent = true
 	self.finish_cmd_write_id = self.send_payload(FileTransmissionCommand{Action: Action_finish}.Serialize())
+	// draw attention to long transfers that complete while the user is
+	// doing something else
+	self.lp.SetUrgencyHint("Finished sending files", "")
 }
 
 func (self *SendHandler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
@@ -1110,6 +1215,7 @@ func (self *SendHandler) abort_transfer(delay ...time.Duration) {
 	}
 	self.send_payload(FileTransmissionCommand{Action: Action_cancel}.Serialize())
 	self.manager.state = SEND_CANCELED
+	self.lp.SetProgress(loop.PROGRESS_ERROR, 100)
 	self.lp.AddTimer(d, false, func(loop.IdType) error {
 		self.lp.Quit(1)
 		return nil
@@ -1187,7 +1293,7 @@ func (self *SendHandler) on_interrupt() {
 }
 
 func send_loop(opts *Options, files []*File) (err error, rc int) {
-	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors)
+	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors, loop.FocusTracking)
 	if err != nil {
 		return err, 1
 	}
@@ -1251,6 +1357,13 @@ func send_loop(opts *Options, files []*File) (err error, rc int) {
 			print_rsync_stats(tsf, p.total_transferred, int64(p.signature_bytes))
 		}
 	}
+	np := []not_preserved{}
+	for _, f := range files {
+		if len(f.not_preserved) > 0 && f.err_msg == "" {
+			np = append(np, not_preserved{f.display_name, f.not_preserved})
+		}
+	}
+	print_preservation_summary(handler.ctx, np)
 	if len(handler.failed_files) > 0 {
 		fmt.Fprintf(os.Stderr, "Transfer of %d out of %d files failed\n", len(handler.failed_files), len(handler.manager.files))
 		for _, f := range handler.failed_files {
@@ -0,0 +1,99 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package transfer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"kitty/kittens/ssh"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// The options to make scp and sftp re-use the connection made by the ssh
+// kitten, when running in kitty
+func ssh_connection_args() []string {
+	kpid, err := strconv.Atoi(os.Getenv("KITTY_PID"))
+	if err != nil {
+		return nil
+	}
+	ans, err := ssh.ConnectionSharingArgs(kpid)
+	if err != nil {
+		return nil
+	}
+	return ans
+}
+
+func sftp_quote(path string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path) + `"`
+}
+
+// The paths to pass to scp and the sftp batch commands to copy the files. scp
+// is made to use its legacy protocol, in which remote paths are interpreted
+// by the remote shell, so that it works with servers that have disabled
+// sftp.
+func over_ssh_commands(opts *Options, args []string) (scp_paths []string, sftp_batch string, err error) {
+	if opts.Mode != "normal" {
+		return nil, "", fmt.Errorf("Only the normal mode is supported when transferring files over SSH")
+	}
+	if len(args) < 2 {
+		return nil, "", fmt.Errorf("Must specify at least one source and a destination when transferring files over SSH")
+	}
+	sources, dest := args[:len(args)-1], args[len(args)-1]
+	sending := opts.Direction == "send" || opts.Direction == "download"
+	remote := func(path string) string { return opts.OverSsh + ":" + utils.QuoteStringForSH(path) }
+	batch := strings.Builder{}
+	for _, src := range sources {
+		if sending {
+			src = utils.Expanduser(src)
+			scp_paths = append(scp_paths, src)
+			fmt.Fprintf(&batch, "put -rp %s %s\n", sftp_quote(src), sftp_quote(dest))
+		} else {
+			scp_paths = append(scp_paths, remote(src))
+			fmt.Fprintf(&batch, "get -rp %s %s\n", sftp_quote(src), sftp_quote(utils.Expanduser(dest)))
+		}
+	}
+	if sending {
+		scp_paths = append(scp_paths, remote(dest))
+	} else {
+		scp_paths = append(scp_paths, utils.Expanduser(dest))
+	}
+	return scp_paths, batch.String(), nil
+}
+
+// Copy files with scp, falling back to sftp, instead of the file transfer
+// protocol
+func transfer_over_ssh(opts *Options, args []string) (err error) {
+	scp_paths, sftp_batch, err := over_ssh_commands(opts, args)
+	if err != nil {
+		return err
+	}
+	conn_args := ssh_connection_args()
+	errors := []string{}
+	for _, exe := range []string{"scp", "sftp"} {
+		if _, err = exec.LookPath(exe); err != nil {
+			errors = append(errors, fmt.Sprintf("The %s program was not found", exe))
+			continue
+		}
+		var c *exec.Cmd
+		if exe == "scp" {
+			c = exec.Command(exe, utils.Concat([]string{"-q", "-B", "-O", "-r", "-p"}, conn_args, scp_paths)...)
+		} else {
+			c = exec.Command(exe, utils.Concat([]string{"-q", "-b", "-"}, conn_args, []string{opts.OverSsh})...)
+			c.Stdin = strings.NewReader(sftp_batch)
+		}
+		output := bytes.Buffer{}
+		c.Stdout, c.Stderr = &output, &output
+		if err = c.Run(); err == nil {
+			return nil
+		}
+		errors = append(errors, fmt.Sprintf("Running %s failed with error: %s\n%s", exe, err, strings.TrimSpace(output.String())))
+	}
+	return fmt.Errorf("%s", strings.Join(errors, "\n"))
+}
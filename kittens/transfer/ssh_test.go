@@ -0,0 +1,39 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestOverSSHCommands(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	opts := &Options{Mode: "normal", Direction: "send", OverSsh: "user@host"}
+	tt := func(expected_scp []string, expected_batch string, args ...string) {
+		scp, batch, err := over_ssh_commands(opts, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected_scp, scp); diff != "" {
+			t.Fatalf("Unexpected scp paths for %v:\n%s", args, diff)
+		}
+		if diff := cmp.Diff(expected_batch, batch); diff != "" {
+			t.Fatalf("Unexpected sftp commands for %v:\n%s", args, diff)
+		}
+	}
+	tt([]string{"a", filepath.Join(home, "b"), `user@host:'d it'"'"'s'`}, "put -rp \"a\" \"d it's\"\nput -rp \""+filepath.Join(home, "b")+"\" \"d it's\"\n", "a", "~/b", "d it's")
+	opts.Direction = "receive"
+	tt([]string{`user@host:'a "b"'`, "/c"}, "get -rp \"a \\\"b\\\"\" \"/c\"\n", `a "b"`, "/c")
+
+	opts.Mode = "mirror"
+	if _, _, err := over_ssh_commands(opts, []string{"a", "b"}); err == nil {
+		t.Fatalf("Mirror mode did not fail")
+	}
+}
@@ -107,8 +107,9 @@ func should_be_compressed(path, strategy string) bool {
 }
 
 func print_rsync_stats(total_bytes, delta_bytes, signature_bytes int64) {
+	loc := humanize.CurrentLocale()
 	fmt.Println("Rsync stats:")
-	fmt.Printf("  Delta size: %s Signature size: %s\n", humanize.Size(delta_bytes), humanize.Size(signature_bytes))
+	fmt.Printf("  Delta size: %s Signature size: %s\n", loc.Size(uint64(delta_bytes)), loc.Size(uint64(signature_bytes)))
 	frac := float64(delta_bytes+signature_bytes) / float64(utils.Max(1, total_bytes))
-	fmt.Printf("  Transmitted: %s of a total of %s (%.1f%%)\n", humanize.Size(delta_bytes+signature_bytes), humanize.Size(total_bytes), frac*100)
+	fmt.Printf("  Transmitted: %s of a total of %s (%s%%)\n", loc.Size(uint64(delta_bytes+signature_bytes)), loc.Size(uint64(total_bytes)), loc.FormatNumber(frac*100, 1))
 }
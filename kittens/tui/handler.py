@@ -218,12 +218,15 @@ def f(*a: Any, **kw: Any) -> Any:
         return cast(DecoratedFunc, f)
 
 
+TypeOfInput = Union[None, str, Callable[[Sequence[str]], str]]
+
+
 class HandleResult:
 
-    type_of_input: Optional[str] = None
+    type_of_input: TypeOfInput = None
     no_ui: bool = False
 
-    def __init__(self, impl: Callable[..., Any], type_of_input: Optional[str], no_ui: bool, has_ready_notification: bool):
+    def __init__(self, impl: Callable[..., Any], type_of_input: TypeOfInput, no_ui: bool, has_ready_notification: bool):
         self.impl = impl
         self.no_ui = no_ui
         self.type_of_input = type_of_input
@@ -234,7 +237,7 @@ def __call__(self, args: Sequence[str], data: Any, target_window_id: int, boss:
 
 
 def result_handler(
-    type_of_input: Optional[str] = None,
+    type_of_input: TypeOfInput = None,
     no_ui: bool = False,
     has_ready_notification: bool = Handler.overlay_ready_report_needed
 ) -> Callable[[Callable[..., Any]], HandleResult]:
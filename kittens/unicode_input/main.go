@@ -115,8 +115,9 @@ func load_favorites(refresh bool) []rune {
 }
 
 type CachedData struct {
-	Recent []rune `json:"recent,omitempty"`
-	Mode   string `json:"mode,omitempty"`
+	Recent  []rune `json:"recent,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Preview bool   `json:"preview,omitempty"`
 }
 
 var cached_data *CachedData
@@ -165,6 +166,7 @@ type handler struct {
 	emoji_variation string
 	checkpoints_key checkpoints_key
 	table           table
+	show_preview    bool
 
 	current_tab_formatter, tab_bar_formatter, chosen_formatter, chosen_name_formatter, dim_formatter func(...any) string
 }
@@ -344,6 +346,9 @@ func (self *handler) draw_screen() {
 	writeln()
 	writeln(self.choice_line)
 	sz, _ := self.lp.ScreenSize()
+	if self.show_preview {
+		self.draw_preview(writeln)
+	}
 
 	write_help := func(x string) {
 		lines := style.WrapTextAsLines(x, int(sz.WidthCells)-1, style.WrapOptions{})
@@ -368,6 +373,21 @@ func (self *handler) draw_screen() {
 	}
 }
 
+func (self *handler) draw_preview(writeln func(...any)) {
+	if self.current_char == InvalidChar {
+		writeln(self.dim_formatter("No character chosen to preview"))
+		return
+	}
+	lines := preview_lines(self.current_char, self.resolved_char())
+	w := 0
+	for _, l := range lines {
+		w = utils.Max(w, len(l.label))
+	}
+	for _, l := range lines {
+		writeln(self.dim_formatter(fmt.Sprintf("%*s:", w, l.label)), l.text)
+	}
+}
+
 func (self *handler) on_text(text string, from_key_event, in_bracketed_paste bool) error {
 	err := self.rl.OnText(text, from_key_event, in_bracketed_paste)
 	if err != nil {
@@ -503,6 +523,9 @@ func (self *handler) on_key_event(event *loop.KeyEvent) (err error) {
 	} else if event.MatchesPressOrRepeat("f4") || event.MatchesPressOrRepeat("ctrl+4") {
 		event.Handled = true
 		self.switch_mode(FAVORITES)
+	} else if event.MatchesPressOrRepeat("f5") {
+		event.Handled = true
+		self.show_preview = !self.show_preview
 	} else if event.MatchesPressOrRepeat("ctrl+tab") || event.MatchesPressOrRepeat("ctrl+]") {
 		event.Handled = true
 		self.next_mode(1)
@@ -554,7 +577,7 @@ func run_loop(opts *Options) (lp *loop.Loop, err error) {
 	cached_data = cv.Load()
 	defer cv.Save()
 
-	h := handler{recent: cached_data.Recent, lp: lp, emoji_variation: opts.EmojiVariation}
+	h := handler{recent: cached_data.Recent, lp: lp, emoji_variation: opts.EmojiVariation, show_preview: cached_data.Preview}
 	switch opts.Tab {
 	case "previous":
 		switch cached_data.Mode {
@@ -606,6 +629,7 @@ func run_loop(opts *Options) (lp *loop.Loop, err error) {
 		return
 	}
 	if h.err == nil {
+		cached_data.Preview = h.show_preview
 		switch h.mode {
 		case HEX:
 			cached_data.Mode = "HEX"
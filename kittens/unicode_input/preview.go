@@ -0,0 +1,85 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package unicode_input
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+var _ = fmt.Print
+
+// Combining marks are rendered on a dotted circle when shown in isolation
+const DOTTED_CIRCLE rune = 0x25cc
+
+const (
+	RLE = "\u202b" // right-to-left embedding
+	PDF = "\u202c" // pop directional formatting
+)
+
+var rtl_scripts = []*unicode.RangeTable{
+	unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko,
+	unicode.Samaritan, unicode.Mandaic, unicode.Adlam, unicode.Hanifi_Rohingya,
+}
+
+func is_combining(ch rune) bool {
+	return unicode.In(ch, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
+func is_rtl(ch rune) bool {
+	return unicode.In(ch, rtl_scripts...)
+}
+
+// The UTF-8 encoding of text as space separated hex bytes
+func utf8_bytes(text string) string {
+	parts := make([]string, len(text))
+	for i := 0; i < len(text); i++ {
+		parts[i] = fmt.Sprintf("%02x", text[i])
+	}
+	return strings.Join(parts, " ")
+}
+
+// The UTF-16 encoding of text as space separated hex code units
+func utf16_units(text string) string {
+	units := utf16.Encode([]rune(text))
+	parts := make([]string, len(units))
+	for i, u := range units {
+		parts[i] = fmt.Sprintf("%04x", u)
+	}
+	return strings.Join(parts, " ")
+}
+
+type preview_line struct {
+	label, text string
+}
+
+// The lines of the preview pane for ch, which is the resolved character,
+// including any emoji variation selector, for the codepoint cp
+func preview_lines(cp rune, ch string) []preview_line {
+	isolated, composed, rtl := ch, "", ""
+	if is_combining(cp) {
+		isolated = string(DOTTED_CIRCLE) + ch
+		composed = "a" + ch + " e" + ch + " o" + ch
+		// a combining mark on Hebrew letters, in a right-to-left context
+		rtl = RLE + "שָׁלוֹם ש" + ch + "ל" + ch + PDF
+	} else {
+		rtl = RLE + "שָׁלוֹם " + ch + " עוֹלָם" + PDF
+	}
+	ans := []preview_line{{"Isolated", isolated}}
+	if composed != "" {
+		ans = append(ans, preview_line{"Composed", composed})
+	}
+	direction := "left-to-right"
+	if is_rtl(cp) {
+		direction = "right-to-left"
+	}
+	ans = append(ans,
+		preview_line{"RTL context", rtl},
+		preview_line{"Direction", direction},
+		preview_line{"UTF-8", utf8_bytes(ch)},
+		preview_line{"UTF-16", utf16_units(ch)},
+	)
+	return ans
+}
@@ -0,0 +1,46 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package unicode_input
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestUnicodeInputPreview(t *testing.T) {
+	for text, expected := range map[string][]string{
+		"a":       {"61", "0061"},
+		"€":       {"e2 82 ac", "20ac"},
+		"😀":       {"f0 9f 98 80", "d83d de00"},
+		"☺\ufe0f": {"e2 98 ba ef b8 8f", "263a fe0f"},
+	} {
+		actual := []string{utf8_bytes(text), utf16_units(text)}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Incorrect encodings for %#v:\n%s", text, diff)
+		}
+	}
+	labels := func(cp rune) (ans []string) {
+		for _, l := range preview_lines(cp, string(cp)) {
+			ans = append(ans, l.label)
+		}
+		return
+	}
+	if diff := cmp.Diff([]string{"Isolated", "Composed", "RTL context", "Direction", "UTF-8", "UTF-16"}, labels(0x301)); diff != "" {
+		t.Fatalf("Incorrect preview for combining character:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Isolated", "RTL context", "Direction", "UTF-8", "UTF-16"}, labels('x')); diff != "" {
+		t.Fatalf("Incorrect preview for non-combining character:\n%s", diff)
+	}
+	if l := preview_lines(0x301, "\u0301")[0]; l.text != "\u25cc\u0301" {
+		t.Fatalf("Combining character not shown on a dotted circle: %#v", l.text)
+	}
+	for cp, expected := range map[rune]bool{'א': true, 'ب': true, 'x': false, 0x301: false} {
+		if is_rtl(cp) != expected {
+			t.Fatalf("Incorrect direction for U+%x", cp)
+		}
+	}
+}
@@ -42,6 +42,7 @@
 )
 from .conf.utils import BadLine, KeyAction, to_cmdline
 from .config import common_opts_as_dict, prepare_config_file_for_editing
+from .encryption_keys import load_encryption_key
 from .constants import (
     RC_ENCRYPTION_PROTOCOL_VERSION,
     appname,
@@ -68,15 +69,16 @@
     GLFW_RELEASE,
     IMPERATIVE_CLOSE_REQUESTED,
     NO_CLOSE_REQUESTED,
+    WINDOW_MAXIMIZED,
     ChildMonitor,
     Color,
-    EllipticCurveKey,
     KeyEvent,
     SingleKey,
     add_timer,
     apply_options_update,
     background_opacity_of,
     change_background_opacity,
+    change_os_window_state,
     cocoa_hide_app,
     cocoa_hide_other_apps,
     cocoa_minimize_os_window,
@@ -90,6 +92,7 @@
     get_boss,
     get_options,
     get_os_window_size,
+    get_os_window_workarea,
     global_font_size,
     is_modifier_key,
     last_focused_os_window_id,
@@ -107,6 +110,7 @@
     set_in_sequence_mode,
     set_options,
     set_os_window_chrome,
+    set_os_window_geometry,
     set_os_window_size,
     set_os_window_title,
     thread_write,
@@ -120,7 +124,7 @@
 from .layout.base import set_layout_options
 from .notify import notification_activated
 from .options.types import Options
-from .options.utils import MINIMUM_FONT_SIZE, KeyMap, SubSequenceMap
+from .options.utils import MINIMUM_FONT_SIZE, KeyMap, PeerCredentialsPolicy, SubSequenceMap
 from .os_window_size import initial_window_size_func
 from .rgb import color_from_int
 from .session import Session, create_sessions, get_os_window_sizing_data
@@ -139,6 +143,7 @@
     log_error,
     macos_version,
     open_url,
+    os_window_geometry_for_preset,
     parse_address_spec,
     parse_os_window_state,
     parse_uri_list,
@@ -332,7 +337,7 @@ def __init__(
         self.clipboard = Clipboard()
         self.primary_selection = Clipboard(ClipboardType.primary_selection)
         self.update_check_started = False
-        self.encryption_key = EllipticCurveKey()
+        self.encryption_key = load_encryption_key(opts.remote_control_encryption_key)
         self.encryption_public_key = f'{RC_ENCRYPTION_PROTOCOL_VERSION}:{base64.b85encode(self.encryption_key.public).decode("ascii")}'
         self.clipboard_buffers: Dict[str, str] = {}
         self.update_check_process: Optional['PopenType[bytes]'] = None
@@ -591,7 +596,17 @@ def add_child(self, window: Window) -> None:
         self.child_monitor.add_child(window.id, window.child.pid, window.child.child_fd, window.screen)
         self.window_id_map[window.id] = window
 
-    def _handle_remote_command(self, cmd: str, window: Optional[Window] = None, peer_id: int = 0) -> RCResponse:
+    def peer_credentials_policy(self) -> Optional[PeerCredentialsPolicy]:
+        from fnmatch import fnmatchcase
+        policies = get_options().remote_control_peer_credentials
+        for pat, policy in policies.items():
+            if pat and fnmatchcase(self.listening_on, pat):
+                return policy
+        return policies.get('')
+
+    def _handle_remote_command(
+        self, cmd: str, window: Optional[Window] = None, peer_id: int = 0, peer_credentials: Tuple[int, int, int] = (-1, -1, -1)
+    ) -> RCResponse:
         from .remote_control import is_cmd_allowed, parse_cmd
         response = None
         window = window or None
@@ -601,6 +616,13 @@ def _handle_remote_command(self, cmd: str, window: Optional[Window] = None, peer
                 return {'ok': False, 'error': 'Remote control is disabled'}
             if self.allow_remote_control == 'socket-only' and peer_id == 0:
                 return {'ok': False, 'error': 'Remote control is allowed over a socket only'}
+        peer_credentials_allowed = False
+        if peer_id > 0 and (policy := self.peer_credentials_policy()) is not None:
+            if not policy.allows(*peer_credentials):
+                uid, gid, pid = peer_credentials
+                log_error(f'Refusing remote control request from process: {pid} with uid: {uid} and gid: {gid} as denied by remote_control_peer_credentials')
+                return {'ok': False, 'error': 'Remote control from this user or process is not allowed by remote_control_peer_credentials in kitty.conf'}
+            peer_credentials_allowed = not policy.require_password
         try:
             pcmd = parse_cmd(cmd, self.encryption_key)
         except Exception as e:
@@ -623,7 +645,7 @@ def _handle_remote_command(self, cmd: str, window: Optional[Window] = None, peer
         extra_data: Dict[str, Any] = {}
         try:
             allowed_unconditionally = (
-                self.allow_remote_control == 'y' or (peer_id > 0 and self.allow_remote_control in ('socket-only', 'socket')) or
+                self.allow_remote_control == 'y' or (peer_id > 0 and self.allow_remote_control in ('socket-only', 'socket')) or peer_credentials_allowed or
                 (window and window.remote_control_allowed(pcmd, extra_data)))
         except PermissionError:
             return {'ok': False, 'error': 'Remote control disallowed by window specific password'}
@@ -756,12 +778,12 @@ def call_remote_control(self, self_window: Optional[Window], args: Tuple[str, ..
                 return None
             raise
 
-    def peer_message_received(self, msg_bytes: bytes, peer_id: int) -> Union[bytes, bool, None]:
+    def peer_message_received(self, msg_bytes: bytes, peer_id: int, peer_credentials: Tuple[int, int, int] = (-1, -1, -1)) -> Union[bytes, bool, None]:
         cmd_prefix = b'\x1bP@kitty-cmd'
         terminator = b'\x1b\\'
         if msg_bytes.startswith(cmd_prefix) and msg_bytes.endswith(terminator):
             cmd = msg_bytes[len(cmd_prefix):-len(terminator)].decode('utf-8')
-            response = self._handle_remote_command(cmd, peer_id=peer_id)
+            response = self._handle_remote_command(cmd, peer_id=peer_id, peer_credentials=peer_credentials)
             if response is None:
                 return None
             if isinstance(response, AsyncResponse):
@@ -1469,9 +1491,26 @@ def resize_os_window(self, os_window_id: int, width: int, height: int, unit: str
         if metrics is None:
             return
         has_window_scaling = is_macos or is_wayland()
-        w, h = get_new_os_window_size(metrics, width, height, unit, incremental, has_window_scaling)
+        workarea_size = (0, 0)
+        if unit == 'percent':
+            workarea = get_os_window_workarea(os_window_id)
+            if workarea is None:
+                return
+            workarea_size = workarea[2], workarea[3]
+        w, h = get_new_os_window_size(metrics, width, height, unit, incremental, has_window_scaling, workarea_size)
         set_os_window_size(os_window_id, w, h)
 
+    def move_os_window_to_preset(self, os_window_id: int, preset: str) -> None:
+        # the presets other than maximized are relative to the work area of
+        # the monitor the window is on, note that on Wayland windows cannot
+        # be moved, so only the size is changed
+        if preset == 'maximized':
+            change_os_window_state(WINDOW_MAXIMIZED, os_window_id)
+            return
+        workarea = get_os_window_workarea(os_window_id)
+        if workarea is not None:
+            set_os_window_geometry(os_window_id, *os_window_geometry_for_preset(preset, workarea))
+
     def tab_for_id(self, tab_id: int) -> Optional[Tab]:
         for tm in self.os_window_map.values():
             tab = tm.tab_for_id(tab_id)
@@ -2558,11 +2597,24 @@ def set_colors(self, *args: str) -> None:
             return
         c.response_from_kitty(self, self.active_window, PayloadGetter(c, payload if isinstance(payload, dict) else {}))
 
+    def _restore_focus_after_move(self, window: Optional[Window], tab: Optional[Tab]) -> None:
+        if window is not None and tab is not None and window.tabref() is tab:
+            self.set_active_window(window, switch_os_window_if_needed=True, for_keep_focus=True)
+        elif tab is not None:
+            # the focused window was moved, keep the focus on the tab it was in, if it still exists
+            tm = tab.tab_manager_ref()
+            if tm is not None and tab in tm.tabs:
+                tm.set_active_tab(tab)
+                if current_focused_os_window_id() != tm.os_window_id:
+                    focus_os_window(tm.os_window_id, True)
+
     def _move_window_to(
         self,
         window: Optional[Window] = None,
         target_tab_id: Optional[Union[str, int]] = None,
-        target_os_window_id: Optional[Union[str, int]] = None
+        target_os_window_id: Optional[Union[str, int]] = None,
+        tab_location: str = 'last',
+        keep_focus: bool = False,
     ) -> None:
         window = window or self.active_window
         if not window:
@@ -2570,6 +2622,9 @@ def _move_window_to(
         src_tab = self.tab_for_window(window)
         if src_tab is None:
             return
+        active_window, active_tab = self.active_window, self.active_tab
+        if target_tab_id is None and isinstance(target_os_window_id, int):
+            target_tab_id = 'new'
         with self.suppress_focus_change_events():
             if target_os_window_id == 'new':
                 target_os_window_id = self.add_os_window()
@@ -2585,9 +2640,9 @@ def _move_window_to(
                     else:
                         tm = self.os_window_map[target_os_window_id]
                     if target_tab_id == 'new':
-                        target_tab = tm.new_tab(empty_tab=True)
+                        target_tab = tm.new_tab(empty_tab=True, location=tab_location)
                     else:
-                        target_tab = tm.tab_at_location(target_tab_id) or tm.new_tab(empty_tab=True)
+                        target_tab = tm.tab_at_location(target_tab_id) or tm.new_tab(empty_tab=True, location=tab_location)
                 else:
                     for tab in self.all_tabs:
                         if tab.id == target_tab_id:
@@ -2600,19 +2655,28 @@ def _move_window_to(
             for detached_window in src_tab.detach_window(window):
                 target_tab.attach_window(detached_window)
             self._cleanup_tab_after_window_removal(src_tab)
-            target_tab.make_active()
+            if keep_focus:
+                self._restore_focus_after_move(active_window, active_tab)
+            else:
+                target_tab.make_active()
 
-    def _move_tab_to(self, tab: Optional[Tab] = None, target_os_window_id: Optional[int] = None) -> None:
+    def _move_tab_to(
+        self, tab: Optional[Tab] = None, target_os_window_id: Optional[int] = None, tab_location: str = 'last', keep_focus: bool = False
+    ) -> None:
         tab = tab or self.active_tab
         if tab is None:
             return
+        active_window, active_tab = self.active_window, self.active_tab
         if target_os_window_id is None:
             target_os_window_id = self.add_os_window()
         tm = self.os_window_map[target_os_window_id]
-        target_tab = tm.new_tab(empty_tab=True)
+        target_tab = tm.new_tab(empty_tab=True, location=tab_location)
         target_tab.take_over_from(tab)
         self._cleanup_tab_after_window_removal(tab)
-        target_tab.make_active()
+        if keep_focus and active_tab is not tab:
+            self._restore_focus_after_move(active_window, active_tab)
+        else:
+            target_tab.make_active()
 
     def choose_entry(
         self, title: str, entries: Iterable[Tuple[Union[_T, str, None], str]],
@@ -43,10 +43,15 @@ extern PyTypeObject Screen_Type;
 
 static void (*parse_func)(Screen*, PyObject*, monotonic_t);
 
+typedef struct {
+    long long uid, gid, pid;
+} PeerCredentials;
+
 typedef struct {
     char *data;
     size_t sz;
     id_type peer_id;
+    PeerCredentials peer_credentials;
 } Message;
 
 typedef struct {
@@ -467,7 +472,7 @@ parse_input(ChildMonitor *self) {
             Message *msg = msgs + i;
             PyObject *resp = NULL;
             if (msg->data) {
-                resp = PyObject_CallMethod(global_state.boss, "peer_message_received", "y#K", msg->data, (int)msg->sz, msg->peer_id);
+                resp = PyObject_CallMethod(global_state.boss, "peer_message_received", "y#K(LLL)", msg->data, (int)msg->sz, msg->peer_id, msg->peer_credentials.uid, msg->peer_credentials.gid, msg->peer_credentials.pid);
                 free(msg->data);
                 if (!resp) PyErr_Print();
             }
@@ -1552,6 +1557,7 @@ typedef struct {
     size_t num_of_unresponded_messages_sent_to_main_thread, fd_array_idx;
     bool finished_reading;
     int fd;
+    PeerCredentials credentials;
     struct {
         char *data;
         size_t capacity, used, command_end;
@@ -1576,6 +1582,34 @@ typedef struct pollfd PollFD;
 #define PEER_LIMIT 256
 #define nuke_socket(s) { shutdown(s, SHUT_RDWR); safe_close(s, __FILE__, __LINE__); }
 
+static void
+get_peer_credentials(int fd, PeerCredentials *ans) {
+    // credentials are only available for UNIX sockets, -1 means unknown
+    ans->uid = -1; ans->gid = -1; ans->pid = -1;
+    struct sockaddr_storage addr; socklen_t addr_len = sizeof(addr);
+    if (getsockname(fd, (struct sockaddr*)&addr, &addr_len) != 0 || addr.ss_family != AF_UNIX) return;
+#if defined(__linux__)
+    struct ucred cred;
+    socklen_t len = sizeof(cred);
+    if (getsockopt(fd, SOL_SOCKET, SO_PEERCRED, &cred, &len) == 0) {
+        ans->uid = cred.uid; ans->gid = cred.gid; ans->pid = cred.pid;
+    }
+#elif defined(__OpenBSD__)
+    struct sockpeercred cred;
+    socklen_t len = sizeof(cred);
+    if (getsockopt(fd, SOL_SOCKET, SO_PEERCRED, &cred, &len) == 0) {
+        ans->uid = cred.uid; ans->gid = cred.gid; ans->pid = cred.pid;
+    }
+#else
+    uid_t uid; gid_t gid;
+    if (getpeereid(fd, &uid, &gid) == 0) { ans->uid = uid; ans->gid = gid; }
+#ifdef LOCAL_PEERPID
+    pid_t pid; socklen_t len = sizeof(pid);
+    if (getsockopt(fd, SOL_LOCAL, LOCAL_PEERPID, &pid, &len) == 0) ans->pid = pid;
+#endif
+#endif
+}
+
 static bool
 accept_peer(int listen_fd, bool shutting_down) {
     int peer = accept(listen_fd, NULL, NULL);
@@ -1590,6 +1624,7 @@ accept_peer(int listen_fd, bool shutting_down) {
         memset(p, 0, sizeof(Peer));
         p->fd = peer; p->id = ++peer_id_counter;
         if (!p->id) p->id = ++peer_id_counter;
+        get_peer_credentials(peer, &p->credentials);
     } else {
         log_error("Too many peers want to talk, ignoring one.");
         nuke_socket(peer);
@@ -1620,6 +1655,7 @@ queue_peer_message(ChildMonitor *self, Peer *peer) {
         }
     }
     m->peer_id = peer->id;
+    m->peer_credentials = peer->credentials;
     peer->num_of_unresponded_messages_sent_to_main_thread++;
     talk_mutex(unlock);
     wakeup_main_loop();
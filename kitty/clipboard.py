@@ -370,6 +370,9 @@ def parse_osc_5522(self, data: str) -> None:
                 aliases = base64.standard_b64decode(epayload).decode('utf-8').split()
                 for alias in aliases:
                     wr.aliases[alias] = mime
+        elif typ == 'wabort':
+            # the client interrupted the write, discard the partial data
+            self.in_flight_write_request = None
         elif typ == 'wdata':
             wr = self.in_flight_write_request
             w = get_boss().window_id_map.get(self.window_id)
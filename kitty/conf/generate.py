@@ -525,14 +525,17 @@ def gen_go_code(defn: Definition) -> str:
     go_types = {}
     go_parsers = {}
     defaults = {}
+    defaults_as_string = {}
     multiopts = {''}
     for option in sorted(defn.iter_all_options(), key=lambda a: natural_keys(a.name)):
         name = option.name.capitalize()
         if isinstance(option, MultiOption):
             go_types[name], go_parsers[name] = go_type_data(option.parser_func, option.ctype, True)
             multiopts.add(name)
+            defaults_as_string[name] = '\n'.join(x.defval_as_str for x in option if x.add_to_default)
         else:
             defaults[name] = option.parser_func(option.defval_as_string)
+            defaults_as_string[name] = option.defval_as_string
             if option.choices:
                 choices[name] = option.choices
                 go_types[name] = f'{name}_Choice_Type'
@@ -616,9 +619,28 @@ def cval(x: str) -> str:
         a(f'default: return ans, fmt.Errorf("%#v is not a valid value for %s. Valid values are: %s", val, "{c}", "{vals}")')
         a('}''}')
 
+    a('var OptionsMetadata = config.OptionsMetadata{')
+    for oname, gotype in go_types.items():
+        is_multiple = oname in multiopts
+        a('{'f'Name: "{oname.lower()}", Type: "{"[]" if is_multiple else ""}{gotype}", Default: "{serialize_as_go_string(defaults_as_string[oname])}",')
+        if oname in choices:
+            a('Choices: []string{' + ', '.join(f'"{c}"' for c in choices[oname]) + '},')
+        if is_multiple:
+            a('IsMultiple: true,')
+        a('},')
+    a('}')
+
+    a('// Get the value of the named option without using reflection')
+    a('func (c *Config) Get(key string) (ans any, found bool) {')
+    a('switch key {')
+    for oname in go_types:
+        a(f'case "{oname.lower()}": return c.{oname}, true')
+    a('}')
+    a('return}')
+
     a('func (c *Config) Parse(key, val string) (err error) {')
     a('switch key {')
-    a('default: return fmt.Errorf("Unknown configuration key: %#v", key)')
+    a('default: return OptionsMetadata.UnknownKeyError(key)')
     for oname, pname in go_parsers.items():
         ol = oname.lower()
         is_multiple = oname in multiopts
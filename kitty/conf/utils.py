@@ -10,6 +10,7 @@
     Any,
     Callable,
     Dict,
+    FrozenSet,
     Generator,
     Generic,
     Iterable,
@@ -32,6 +33,7 @@
 from ..utils import expandvars, log_error
 
 key_pat = re.compile(r'([a-zA-Z][a-zA-Z0-9_-]*)\s+(.+)$')
+heredoc_pat = re.compile(r'<<([a-zA-Z_][a-zA-Z0-9_]*)$')
 ItemParser = Callable[[str, str, Dict[str, Any]], bool]
 T = TypeVar('T')
 
@@ -184,6 +186,38 @@ def os_name() -> str:
     return 'unknown'
 
 
+def resolve_secret_reference(val: str) -> str:
+    '''
+    Resolve values of the form cmd:some command or keyring:service/account to
+    the output of the command or the secret stored in the OS keyring. Must
+    match resolve_secret() in tools/config/secrets.go.
+    '''
+    kind, sep, spec = val.partition(':')
+    if not sep or kind not in ('cmd', 'keyring'):
+        return val
+    if kind == 'cmd':
+        argv = shlex.split(spec)
+        if not argv:
+            raise ValueError('No command specified to get the value from')
+    else:
+        service, _, account = spec.partition('/')
+        if not service:
+            raise ValueError('No service specified for the keyring lookup')
+        if is_macos:
+            argv = ['/usr/bin/security', 'find-generic-password', '-w', '-s', service] + (['-a', account] if account else [])
+        else:
+            argv = ['secret-tool', 'lookup', 'service', service] + (['account', account] if account else [])
+    import subprocess
+    try:
+        cp = subprocess.run(argv, stdin=subprocess.DEVNULL, stdout=subprocess.PIPE, stderr=subprocess.PIPE)
+    except OSError as e:
+        raise ValueError(f'Running {argv[0]} failed with error: {e}') from e
+    if cp.returncode != 0:
+        msg = cp.stderr.decode('utf-8', 'replace').strip()
+        raise ValueError(f'Running {argv[0]} failed with exit code: {cp.returncode}' + (f': {msg}' if msg else ''))
+    return cp.stdout.decode('utf-8', 'replace').rstrip('\r\n')
+
+
 class NamedLineIterator:
 
     def __init__(self, name: str, lines: Iterator[str]):
@@ -194,12 +228,23 @@ def __iter__(self) -> Iterator[str]:
         return self.lines
 
 
+def has_continuation(line: str) -> bool:
+    '''
+    A line ending with a single backslash is continued on the next line, lines
+    ending with two backslashes are left as is. Must match has_continuation()
+    in tools/config/api.go.
+    '''
+    return line.endswith('\\') and not line.endswith('\\\\')
+
+
 def parse_line(
     line: str,
     parse_conf_item: ItemParser,
     ans: Dict[str, Any],
     base_path_for_includes: str,
-    accumulate_bad_lines: Optional[List[BadLine]] = None
+    accumulate_bad_lines: Optional[List[BadLine]] = None,
+    secret_keys: FrozenSet[str] = frozenset(),
+    multi_line_value: Optional[str] = None,
 ) -> None:
     line = line.strip()
     if not line or line.startswith('#'):
@@ -209,6 +254,8 @@ def parse_line(
         log_error(f'Ignoring invalid config line: {line!r}')
         return
     key, val = m.groups()
+    if multi_line_value is not None:
+        val = multi_line_value
     if key in ('include', 'globinclude', 'envinclude'):
         val = expandvars(os.path.expanduser(val.strip()), {'KITTY_OS': os_name()})
         if key == 'globinclude':
@@ -223,7 +270,8 @@ def parse_line(
                             NamedLineIterator(os.path.join(base_path_for_includes, ''), iter(os.environ[x].splitlines())),
                             parse_conf_item,
                             ans,
-                            accumulate_bad_lines
+                            accumulate_bad_lines,
+                            secret_keys
                         )
             return
         else:
@@ -234,7 +282,7 @@ def parse_line(
             try:
                 with open(val, encoding='utf-8', errors='replace') as include:
                     with currently_parsing.set_file(val):
-                        _parse(include, parse_conf_item, ans, accumulate_bad_lines)
+                        _parse(include, parse_conf_item, ans, accumulate_bad_lines, secret_keys)
             except FileNotFoundError:
                 log_error(
                     'Could not find included config file: {}, ignoring'.
@@ -246,6 +294,9 @@ def parse_line(
                     format(val)
                 )
         return
+    if key in secret_keys and multi_line_value is None:
+        # only for keys that opt in, as this can run commands
+        val = resolve_secret_reference(val)
     if not parse_conf_item(key, val, ans):
         log_error(f'Ignoring unknown config key: {key}')
 
@@ -254,7 +305,8 @@ def _parse(
     lines: Iterable[str],
     parse_conf_item: ItemParser,
     ans: Dict[str, Any],
-    accumulate_bad_lines: Optional[List[BadLine]] = None
+    accumulate_bad_lines: Optional[List[BadLine]] = None,
+    secret_keys: FrozenSet[str] = frozenset(),
 ) -> None:
     name = getattr(lines, 'name', None)
     if name:
@@ -262,24 +314,48 @@ def _parse(
     else:
         from ..constants import config_dir
         base_path_for_includes = config_dir
-    for i, line in enumerate(lines):
+    numbered_lines = enumerate(lines, start=1)
+    for lnum, line in numbered_lines:
+        line = line.rstrip('\r\n')
+        if not line.lstrip(' ').startswith('#'):
+            while has_continuation(line):
+                nl = next(numbered_lines, None)
+                if nl is None:
+                    break
+                line = line[:-1] + nl[1].rstrip('\r\n').lstrip(' \t')
         try:
-            with currently_parsing.set_line(line, i + 1):
-                parse_line(line, parse_conf_item, ans, base_path_for_includes, accumulate_bad_lines)
+            with currently_parsing.set_line(line, lnum):
+                multi_line_value = None
+                # a value of the form <<MARKER is the following lines, up to
+                # a line containing only MARKER
+                m = key_pat.match(line.strip())
+                hm = None if m is None else heredoc_pat.match(m.group(2).strip())
+                if hm is not None:
+                    marker, value_lines = hm.group(1), []
+                    for _, nl in numbered_lines:
+                        nl = nl.rstrip('\r\n')
+                        if nl.strip() == marker:
+                            break
+                        value_lines.append(nl)
+                    else:
+                        raise ValueError(f'The multi-line value is not terminated by a line containing only: {marker}')
+                    multi_line_value = '\n'.join(value_lines)
+                parse_line(line, parse_conf_item, ans, base_path_for_includes, accumulate_bad_lines, secret_keys, multi_line_value)
         except Exception as e:
             if accumulate_bad_lines is None:
                 raise
-            accumulate_bad_lines.append(BadLine(i + 1, line.rstrip(), e, currently_parsing.file))
+            accumulate_bad_lines.append(BadLine(lnum, line.rstrip(), e, currently_parsing.file))
 
 
 def parse_config_base(
     lines: Iterable[str],
     parse_conf_item: ItemParser,
     ans: Dict[str, Any],
-    accumulate_bad_lines: Optional[List[BadLine]] = None
+    accumulate_bad_lines: Optional[List[BadLine]] = None,
+    secret_keys: FrozenSet[str] = frozenset(),
 ) -> None:
     _parse(
-        lines, parse_conf_item, ans, accumulate_bad_lines
+        lines, parse_conf_item, ans, accumulate_bad_lines, secret_keys
     )
 
 
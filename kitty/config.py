@@ -148,6 +148,11 @@ def finalize_mouse_mappings(opts: Options, accumulate_bad_lines: Optional[List[B
     opts.mousemap = mousemap
 
 
+# Options whose values can be read from a command or the OS keyring, see
+# resolve_secret_reference()
+secret_keys = frozenset({'remote_control_password'})
+
+
 def parse_config(lines: Iterable[str], accumulate_bad_lines: Optional[List[BadLine]] = None) -> Dict[str, Any]:
     from .options.parse import create_result_dict, parse_conf_item
     ans: Dict[str, Any] = create_result_dict()
@@ -155,7 +160,8 @@ def parse_config(lines: Iterable[str], accumulate_bad_lines: Optional[List[BadLi
         lines,
         parse_conf_item,
         ans,
-        accumulate_bad_lines=accumulate_bad_lines
+        accumulate_bad_lines=accumulate_bad_lines,
+        secret_keys=secret_keys,
     )
     return ans
 
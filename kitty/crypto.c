@@ -114,9 +114,10 @@ typedef struct {
 static PyObject *
 new_ec_key(PyTypeObject *type, PyObject *args, PyObject *kwds) {
     EllipticCurveKey *self;
-    static const char* kwlist[] = {"algorithm", NULL};
+    static const char* kwlist[] = {"algorithm", "private_key", NULL};
     int algorithm = EVP_PKEY_X25519, nid = NID_X25519;
-    if (!PyArg_ParseTupleAndKeywords(args, kwds, "|i", (char**)kwlist, &algorithm)) return NULL;
+    const char *private_key = NULL; Py_ssize_t private_key_len = 0;
+    if (!PyArg_ParseTupleAndKeywords(args, kwds, "|iy#", (char**)kwlist, &algorithm, &private_key, &private_key_len)) return NULL;
     switch(algorithm) {
         case EVP_PKEY_X25519: break;
         default: PyErr_SetString(PyExc_KeyError, "Unknown algorithm"); return NULL;
@@ -126,9 +127,13 @@ new_ec_key(PyTypeObject *type, PyObject *args, PyObject *kwds) {
 #define cleanup() { if (key) EVP_PKEY_free(key); key = NULL; if (pctx) EVP_PKEY_CTX_free(pctx); pctx = NULL; }
 #define ssl_error(text) { cleanup(); return set_error_from_openssl(text); }
 
-    if (NULL == (pctx = EVP_PKEY_CTX_new_id(nid, NULL))) ssl_error("Failed to create context for key generation");
-    if(1 != EVP_PKEY_keygen_init(pctx)) ssl_error("Failed to initialize keygen context");
-    if (1 != EVP_PKEY_keygen(pctx, &key)) ssl_error("Failed to generate key");
+    if (private_key) {
+        if (NULL == (key = EVP_PKEY_new_raw_private_key(algorithm, NULL, (const unsigned char*)private_key, private_key_len))) ssl_error("Failed to create key from private key");
+    } else {
+        if (NULL == (pctx = EVP_PKEY_CTX_new_id(nid, NULL))) ssl_error("Failed to create context for key generation");
+        if(1 != EVP_PKEY_keygen_init(pctx)) ssl_error("Failed to initialize keygen context");
+        if (1 != EVP_PKEY_keygen(pctx, &key)) ssl_error("Failed to generate key");
+    }
 
     self = (EllipticCurveKey *)type->tp_alloc(type, 0);
     if (self) {
@@ -828,6 +828,14 @@ def change_os_window_state(state: int, os_window_id: Optional[int] = 0) -> None:
     pass
 
 
+def get_os_window_workarea(os_window_id: int) -> Optional[Tuple[int, int, int, int]]:
+    pass
+
+
+def set_os_window_geometry(os_window_id: int, x: int, y: int, width: int, height: int) -> bool:
+    pass
+
+
 def change_background_opacity(os_window_id: int, opacity: float) -> bool:
     pass
 
@@ -1463,7 +1471,8 @@ class Secret:
 class EllipticCurveKey:
 
     def __init__(
-        self, algorithm: int = 0  # X25519
+        self, algorithm: int = 0,  # X25519
+        private_key: Optional[bytes] = None
     ): pass
 
     def derive_secret(
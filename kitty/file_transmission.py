@@ -8,12 +8,12 @@
 import re
 import stat
 import tempfile
-from base64 import b85decode
+from base64 import b85decode, standard_b64decode, standard_b64encode
 from collections import defaultdict, deque
 from contextlib import suppress
 from dataclasses import Field, dataclass, field, fields
 from enum import Enum, auto
-from functools import partial
+from functools import lru_cache, partial
 from gettext import gettext as _
 from itertools import count
 from time import monotonic, time_ns
@@ -67,6 +67,58 @@ def split_for_transfer(
         data = data[chunk_size:]
 
 
+@lru_cache(maxsize=64)
+def owner_names(uid: int, gid: int) -> str:
+    import grp
+    import pwd
+    try:
+        uname = pwd.getpwuid(uid).pw_name
+    except KeyError:
+        uname = str(uid)
+    try:
+        gname = grp.getgrgid(gid).gr_name
+    except KeyError:
+        gname = str(gid)
+    return f'{uname}:{gname}'
+
+
+def lookup_owner(owner: str) -> Tuple[int, int]:
+    import grp
+    import pwd
+    uname, sep, gname = owner.partition(':')
+    if not sep:
+        raise ValueError(f'Invalid owner: {owner}')
+    try:
+        uid = pwd.getpwnam(uname).pw_uid
+    except KeyError:
+        if not uname.isdigit():
+            raise ValueError(f'Unknown user: {uname}')
+        uid = int(uname)
+    try:
+        gid = grp.getgrnam(gname).gr_gid
+    except KeyError:
+        if not gname.isdigit():
+            raise ValueError(f'Unknown group: {gname}')
+        gid = int(gname)
+    return uid, gid
+
+
+def read_xattrs(path: str) -> str:
+    # extended attributes are serialized as a JSON object mapping names to
+    # base64 encoded values
+    if not hasattr(os, 'listxattr'):
+        return ''
+    try:
+        names = os.listxattr(path, follow_symlinks=False)
+    except OSError:
+        return ''
+    ans: Dict[str, str] = {}
+    for name in names:
+        with suppress(OSError):
+            ans[name] = standard_b64encode(os.getxattr(path, name, follow_symlinks=False)).decode('ascii')
+    return json.dumps(ans) if ans else ''
+
+
 def iter_file_metadata(file_specs: Iterable[Tuple[str, str]]) -> Iterator[Union['FileTransmissionCommand', 'TransmissionError']]:
     file_map: DefaultDict[Tuple[int, int], List[FileTransmissionCommand]] = defaultdict(list)
     counter = count()
@@ -87,7 +139,8 @@ def make_ftc(path: str, spec_id: str, sr: Optional[os.stat_result] = None, paren
             raise ValueError('Not an appropriate file type')
         ans = FileTransmissionCommand(
             action=Action.file, file_id=spec_id, mtime=sr.st_mtime_ns, permissions=stat.S_IMODE(sr.st_mode),
-            name=path, status=str(next(counter)), size=sr.st_size, ftype=ftype, parent=parent
+            name=path, status=str(next(counter)), size=sr.st_size, ftype=ftype, parent=parent,
+            owner=owner_names(sr.st_uid, sr.st_gid), xattrs=read_xattrs(path),
         )
         file_map[skey(sr)].append(ans)
         return ans
@@ -265,6 +318,8 @@ class FileTransmissionCommand:
     name: str = field(default='', metadata={'base64': True, 'sname': 'n'})
     status: str = field(default='', metadata={'base64': True, 'sname': 'st'})
     parent: str = field(default='', metadata={'sname': 'pr'})
+    owner: str = field(default='', metadata={'base64': True, 'sname': 'own'})
+    xattrs: str = field(default='', metadata={'base64': True, 'sname': 'xa'})
     data: bytes = field(default=b'', repr=False, metadata={'sname': 'd'})
 
     def __repr__(self) -> str:
@@ -456,6 +511,10 @@ def __init__(self, ftc: FileTransmissionCommand) -> None:
         self.permissions = ftc.permissions
         if self.permissions != FileTransmissionCommand.permissions:
             self.permissions = stat.S_IMODE(self.permissions)
+        self.owner = ftc.owner
+        self.xattrs = ftc.xattrs
+        # file properties that could not be preserved, reported to the client
+        self.not_preserved: List[str] = []
         self.ftype = ftc.ftype
         self.ttype = ftc.ttype
         self.link_target = b''
@@ -486,7 +545,38 @@ def make_parent_dirs(self) -> str:
             os.makedirs(d, exist_ok=True)
         return d
 
+    def apply_ownership_and_xattrs(self) -> None:
+        if self.owner:
+            try:
+                uid, gid = lookup_owner(self.owner)
+                sr = os.stat(self.name, follow_symlinks=False)
+                if sr.st_uid != uid or sr.st_gid != gid:
+                    os.chown(self.name, uid, gid, follow_symlinks=False)
+            except (OSError, ValueError) as err:
+                self.not_preserved.append(f'Ownership: {err}')
+        if self.xattrs:
+            if not hasattr(os, 'setxattr'):
+                self.not_preserved.append('Extended attributes: not supported on this platform')
+                return
+            failed = []
+            try:
+                xattrs = json.loads(self.xattrs)
+            except ValueError as err:
+                self.not_preserved.append(f'Extended attributes: {err}')
+                return
+            for name, val in xattrs.items():
+                try:
+                    os.setxattr(self.name, name, standard_b64decode(val), follow_symlinks=False)
+                except (OSError, ValueError) as err:
+                    failed.append(f'{name} ({err})')
+            if failed:
+                self.not_preserved.append('Extended attributes: ' + ', '.join(failed))
+
     def apply_metadata(self, is_symlink: bool = False) -> None:
+        if self.ftype is not FileType.directory:
+            # ownership must be changed before the mode as changing it can
+            # clear the set-user-id and set-group-id bits
+            self.apply_ownership_and_xattrs()
         if self.permissions != FileTransmissionCommand.permissions:
             if is_symlink:
                 with suppress(NotImplementedError):
@@ -544,7 +634,8 @@ def write_data(self, all_files: Dict[str, 'DestFile'], data: bytes, is_last: boo
                 self.make_parent_dirs()
                 self.unlink_existing_if_needed()
                 flags = os.O_RDWR | os.O_CREAT | os.O_TRUNC | getattr(os, 'O_CLOEXEC', 0) | getattr(os, 'O_BINARY', 0)
-                self.actual_file = open(os.open(self.name, flags, self.permissions), mode='r+b', closefd=True)
+                mode = 0o666 if self.permissions == FileTransmissionCommand.permissions else self.permissions
+                self.actual_file = open(os.open(self.name, flags, mode), mode='r+b', closefd=True)
             af = self.actual_file
             if decompressed or is_last:
                 af.write(decompressed)
@@ -1020,7 +1111,9 @@ def handle_receive_cmd(self, cmd: FileTransmissionCommand) -> None:
                     except OSError as err:
                         self.send_fail_on_os_error(err, 'Failed to create directory', ar, df.file_id)
                     else:
-                        self.send_status_response(ErrorCode.OK, ar.id, df.file_id, name=df.name)
+                        # the mode and mtime of directories are set at the end of the transfer
+                        df.apply_ownership_and_xattrs()
+                        self.send_status_response(ErrorCode.OK, ar.id, df.file_id, name=df.name, msg='; '.join(df.not_preserved))
                 else:
                     if ar.send_acknowledgements:
                         sz = df.existing_stat.st_size if df.existing_stat is not None else -1
@@ -1048,7 +1141,8 @@ def handle_receive_cmd(self, cmd: FileTransmissionCommand) -> None:
                 if ar.send_acknowledgements:
                     if df.closed:
                         self.send_status_response(
-                            code=ErrorCode.OK, request_id=ar.id, file_id=df.file_id, name=df.name, size=df.bytes_written)
+                            code=ErrorCode.OK, request_id=ar.id, file_id=df.file_id, name=df.name, size=df.bytes_written,
+                            msg='; '.join(df.not_preserved))
                     elif df.bytes_written > before:
                         self.send_status_response(
                             code=ErrorCode.PROGRESS, request_id=ar.id, file_id=df.file_id, size=df.bytes_written)
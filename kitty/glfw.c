@@ -1602,6 +1602,56 @@ change_os_window_state(PyObject *self UNUSED, PyObject *args) {
     Py_RETURN_NONE;
 }
 
+static GLFWmonitor*
+monitor_for_os_window(OSWindow *w) {
+    GLFWmonitor *ans = glfwGetWindowMonitor(w->handle);  // only set for fullscreen windows
+    if (ans) return ans;
+    int count = 0;
+    GLFWmonitor **monitors = glfwGetMonitors(&count);
+    // window positions are not available on Wayland
+    if (!global_state.is_wayland && monitors && count > 1) {
+        int x, y, width, height, best = 0;
+        glfwGetWindowPos(w->handle, &x, &y);
+        glfwGetWindowSize(w->handle, &width, &height);
+        for (int i = 0; i < count; i++) {
+            int mx, my, mw, mh;
+            glfwGetMonitorWorkarea(monitors[i], &mx, &my, &mw, &mh);
+            int ow = MIN(x + width, mx + mw) - MAX(x, mx), oh = MIN(y + height, my + mh) - MAX(y, my);
+            if (ow > 0 && oh > 0 && ow * oh > best) { best = ow * oh; ans = monitors[i]; }
+        }
+    }
+    return ans ? ans : glfwGetPrimaryMonitor();
+}
+
+static PyObject*
+get_os_window_workarea(PyObject *self UNUSED, PyObject *args) {
+    id_type wid;
+    if (!PyArg_ParseTuple(args, "K", &wid)) return NULL;
+    OSWindow *w = os_window_for_id(wid);
+    if (!w || !w->handle) Py_RETURN_NONE;
+    GLFWmonitor *monitor = monitor_for_os_window(w);
+    if (!monitor) Py_RETURN_NONE;
+    int x, y, width, height;
+    glfwGetMonitorWorkarea(monitor, &x, &y, &width, &height);
+    return Py_BuildValue("iiii", x, y, width, height);
+}
+
+static PyObject*
+set_os_window_geometry(PyObject *self UNUSED, PyObject *args) {
+    id_type wid;
+    int x, y, width, height;
+    if (!PyArg_ParseTuple(args, "Kiiii", &wid, &x, &y, &width, &height)) return NULL;
+    OSWindow *w = os_window_for_id(wid);
+    if (!w || !w->handle) Py_RETURN_FALSE;
+    if (glfwGetWindowAttrib(w->handle, GLFW_MAXIMIZED)) glfwRestoreWindow(w->handle);
+    // the geometry includes the window decorations
+    int left = 0, top = 0, right = 0, bottom = 0;
+    glfwGetWindowFrameSize(w->handle, &left, &top, &right, &bottom);
+    if (!global_state.is_wayland) glfwSetWindowPos(w->handle, x + left, y + top);
+    glfwSetWindowSize(w->handle, MAX(1, width - left - right), MAX(1, height - top - bottom));
+    Py_RETURN_TRUE;
+}
+
 void
 request_window_attention(id_type kitty_window_id, bool audio_bell) {
     OSWindow *w = os_window_for_kitty_window(kitty_window_id);
@@ -1960,6 +2010,8 @@ static PyMethodDef module_methods[] = {
     METHODB(toggle_fullscreen, METH_VARARGS),
     METHODB(toggle_maximized, METH_VARARGS),
     METHODB(change_os_window_state, METH_VARARGS),
+    METHODB(get_os_window_workarea, METH_VARARGS),
+    METHODB(set_os_window_geometry, METH_VARARGS),
     METHODB(glfw_window_hint, METH_VARARGS),
     METHODB(x11_display, METH_NOARGS),
     METHODB(get_click_interval, METH_NOARGS),
@@ -19,6 +19,8 @@
     'yaml': 'text/yaml',
     'js': 'text/javascript',
     'json': 'text/json',
+    'six': 'image/x-sixel',
+    'sixel': 'image/x-sixel',
 }
 
 
@@ -4,6 +4,7 @@
 
 import os
 import shutil
+import threading
 from contextlib import suppress
 from typing import Any, Container, Dict, FrozenSet, Iterable, Iterator, List, NamedTuple, Optional, Sequence, Tuple
 
@@ -693,6 +694,11 @@ def __init__(self, msg: str) -> None:
         self.args: List[str] = []
         self.cwd = self.file_name = self.file_localpath = ''
         self.file_data = b''
+        self.remote_path = ''
+        # the scp options and hostname used to save the file when the kitten
+        # is no longer running, for files on remote machines
+        self.ssh_connection: Optional[Tuple[List[str], str]] = None
+        self.upload_lock = threading.Lock()
         self.file_inode = -1, -1
         self.file_size = -1
         self.version = 0
@@ -727,7 +733,7 @@ def __init__(self, msg: str) -> None:
             if m is not None:
                 self.line_number = int(m.group(1))
         self.file_name = os.path.basename(self.file_spec)
-        self.file_localpath = os.path.normpath(os.path.join(self.cwd, self.file_spec))
+        self.file_localpath = self.remote_path = os.path.normpath(os.path.join(self.cwd, self.file_spec))
         self.is_local_file = False
         with suppress(OSError):
             st = os.stat(self.file_localpath)
@@ -758,29 +764,49 @@ def file_mod_time(self) -> int:
         return os.stat(self.file_localpath).st_mtime_ns
 
     def schedule_check(self) -> None:
-        if not self.abort_signaled:
+        if not self.abort_signaled or self.ssh_connection is not None:
             add_timer(self.check_status, 1.0, False)
 
     def on_edit_window_close(self, window: Window) -> None:
         self.check_status()
 
+    def upload_over_ssh(self) -> None:
+        if self.ssh_connection is None:
+            log_error(f'Could not save changes to {self.remote_path} as the edit-in-kitty command is no longer running')
+            return
+        from kittens.ssh.utils import copy_over_ssh
+        scp_args, hostname = self.ssh_connection
+
+        def upload() -> None:
+            with self.upload_lock:
+                errors = copy_over_ssh(scp_args, hostname, self.remote_path, self.file_localpath, upload=True)
+            if errors:
+                log_error(f'Failed to save changes to {self.remote_path} on {hostname} with error:\n{errors}')
+        threading.Thread(target=upload, name='EditInKittyUpload', daemon=True).start()
+
     def check_status(self, timer_id: Optional[int] = None) -> None:
-        if self.abort_signaled:
+        # When the edit-in-kitty command is interrupted, changes to remote
+        # files continue to be saved using scp/sftp, until the editor is closed
+        if self.abort_signaled and self.ssh_connection is None:
             return
         boss = get_boss()
         source_window = boss.window_id_map.get(self.source_window_id)
-        if source_window is not None and not self.is_local_file:
+        if not self.is_local_file:
             mtime = self.file_mod_time
             if mtime != self.last_mod_time:
                 self.last_mod_time = mtime
-                data = self.read_data()
-                self.send_data(source_window, 'UPDATE', data)
+                if source_window is not None and not self.abort_signaled:
+                    self.send_data(source_window, 'UPDATE', self.read_data())
+                else:
+                    self.upload_over_ssh()
         editor_window = boss.window_id_map.get(self.editor_window_id)
         if editor_window is None:
-            edits_in_flight.pop(self.source_window_id, None)
-            if source_window is not None:
+            if edits_in_flight.get(self.source_window_id) is self:
+                del edits_in_flight[self.source_window_id]
+            if source_window is not None and not self.abort_signaled:
                 self.send_data(source_window, 'DONE')
             self.abort_signaled = self.abort_signaled or 'closed'
+            self.ssh_connection = None
         else:
             self.schedule_check()
 
@@ -855,6 +881,11 @@ def remote_edit(msg: str, window: Window) -> None:
         if q is not None:
             q.abort_signaled = c.abort_signaled
         return
+    if not c.is_local_file:
+        conn_data = window.ssh_connection_data()
+        if conn_data is not None:
+            from kittens.ssh.utils import scp_args_for_connection_data
+            c.ssh_connection = scp_args_for_connection_data(conn_data)
     cmdline = get_editor(path_to_edit=c.file_localpath, line_number=c.line_number)
     w = launch(get_boss(), c.opts, cmdline, active=window)
     if w is not None:
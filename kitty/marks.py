@@ -2,7 +2,7 @@
 # License: GPLv3 Copyright: 2020, Kovid Goyal <kovid at kovidgoyal.net>
 
 import re
-from ctypes import POINTER, c_uint, c_void_p, cast
+from ctypes import POINTER, addressof, c_uint, c_void_p, cast
 from typing import Callable, Generator, Iterable, Pattern, Sequence, Tuple, Union
 
 from .utils import resolve_custom_file
@@ -77,6 +77,29 @@ def marker(text: str, left_address: int, right_address: int, color_address: int)
     return marker
 
 
+def combine_markers(markers: Sequence[MarkerFunc]) -> MarkerFunc:
+    # Runs all the markers and merges their matches. Where matches overlap,
+    # the match that starts first wins, ties going to the earlier marker.
+
+    def marker(text: str, left_address: int, right_address: int, color_address: int) -> Generator[None, None, None]:
+        left, right, colorv = get_output_variables(left_address, right_address, color_address)
+        ml, mr, mc = c_uint(0), c_uint(0), c_uint(0)
+        matches = []
+        for m in markers:
+            for _ in m(text, addressof(ml), addressof(mr), addressof(mc)):
+                matches.append((ml.value, mr.value, mc.value))
+        matches.sort(key=lambda x: x[0])
+        pos = 0
+        for start, end, color in matches:
+            if start < pos:
+                continue
+            left.value, right.value, colorv.value = start, end, color
+            pos = end + 1
+            yield
+
+    return marker
+
+
 def marker_from_spec(ftype: str, spec: Union[str, Sequence[Tuple[int, str]]], flags: int) -> MarkerFunc:
     if ftype == 'regex':
         assert not isinstance(spec, str)
@@ -2847,6 +2847,61 @@
 
 Relative paths are resolved from the kitty configuration directory.
 See :ref:`rc_custom_auth` for details.
+
+So that passwords need not be stored in the config file, the value can be read
+from the output of a command or from the OS keyring (using
+:program:`secret-tool` on Linux and the Keychain on macOS), for example::
+
+    remote_control_password cmd:pass show kitty/rc
+    remote_control_password keyring:kitty/rc
+
+The command output or keyring secret is used as the whole value, so it can
+include a list of allowed actions, as above.
+''')
+
+opt('remote_control_encryption_key', 'none',
+    option_type='config_or_absolute_path',
+    long_text='''
+Path to a file containing the private key kitty uses for :ref:`rc_crypto`.
+By default, a new key is generated every time kitty starts. Using an existing
+key means the public key does not change, so :envvar:`KITTY_PUBLIC_KEY` can be
+set permanently on remote systems, to the corresponding age recipient
+(:code:`age1...`) or :code:`ssh-ed25519` public key. Supported are age
+identity files, as created by :code:`age-keygen`, and OpenSSH ed25519 private
+keys that are not passphrase protected. age plugin identities, such as for
+hardware keys, are not supported. Relative paths are resolved from the kitty
+config directory. Changing this option by reloading the config is not
+supported.
+''')
+
+opt('+remote_control_peer_credentials', '',
+    option_type='remote_control_peer_credentials',
+    add_to_default=False,
+    long_text='''
+Authorize remote control requests received over a UNIX socket (see
+:opt:`listen_on`) based on the credentials of the process that connected to the
+socket, useful on multi-user machines. Specify users, groups and process ids,
+by name or number. For example::
+
+    remote_control_peer_credentials uid=alice,1001 gid=wheel
+
+Processes running as one of the specified users or with one of the specified
+groups as their primary group, or having one of the specified process ids are
+allowed, all other connections to the socket are refused, regardless of
+:opt:`allow_remote_control`. Allowed processes can control kitty without a
+password, unless :code:`--require-password` is used, in which case, passwords
+are checked as usual, see :opt:`remote_control_password`::
+
+    remote_control_peer_credentials --require-password uid=alice
+
+By default, this applies to any socket kitty listens on. To apply it to only
+some sockets, use :code:`--socket` with a glob pattern matching the socket
+address, this option can be specified multiple times for different sockets::
+
+    remote_control_peer_credentials --socket=unix:/tmp/shared-kitty* gid=staff
+
+Requests received over TCP sockets are refused when this option applies, as
+peer credentials are not available for them.
 ''')
 
 opt('allow_remote_control', 'no',
@@ -13,12 +13,12 @@
     deprecated_hide_window_decorations_aliases, deprecated_macos_show_window_title_in_menubar_alias,
     deprecated_send_text, disable_ligatures, edge_width, env, font_features, hide_window_decorations,
     macos_option_as_alt, macos_titlebar_color, modify_font, narrow_symbols, optional_edge_width,
-    parse_map, parse_mouse_map, paste_actions, remote_control_password, resize_debounce_time,
-    scrollback_lines, scrollback_pager_history_size, shell_integration, store_multiple, symbol_map,
-    tab_activity_symbol, tab_bar_edge, tab_bar_margin_height, tab_bar_min_tabs, tab_fade,
-    tab_font_style, tab_separator, tab_title_template, titlebar_color, to_cursor_shape, to_font_size,
-    to_layout_names, to_modifiers, url_prefixes, url_style, visual_window_select_characters,
-    window_border_width, window_size
+    parse_map, parse_mouse_map, paste_actions, remote_control_password, remote_control_peer_credentials,
+    resize_debounce_time, scrollback_lines, scrollback_pager_history_size, shell_integration,
+    store_multiple, symbol_map, tab_activity_symbol, tab_bar_edge, tab_bar_margin_height,
+    tab_bar_min_tabs, tab_fade, tab_font_style, tab_separator, tab_title_template, titlebar_color,
+    to_cursor_shape, to_font_size, to_layout_names, to_modifiers, url_prefixes, url_style,
+    visual_window_select_characters, window_border_width, window_size
 )
 
 
@@ -1149,10 +1149,17 @@ def pointer_shape_when_grabbed(self, val: str, ans: typing.Dict[str, typing.Any]
     def remember_window_size(self, val: str, ans: typing.Dict[str, typing.Any]) -> None:
         ans['remember_window_size'] = to_bool(val)
 
+    def remote_control_encryption_key(self, val: str, ans: typing.Dict[str, typing.Any]) -> None:
+        ans['remote_control_encryption_key'] = config_or_absolute_path(val)
+
     def remote_control_password(self, val: str, ans: typing.Dict[str, typing.Any]) -> None:
         for k, v in remote_control_password(val, ans["remote_control_password"]):
             ans["remote_control_password"][k] = v
 
+    def remote_control_peer_credentials(self, val: str, ans: typing.Dict[str, typing.Any]) -> None:
+        for k, v in remote_control_peer_credentials(val, ans["remote_control_peer_credentials"]):
+            ans["remote_control_peer_credentials"][k] = v
+
     def repaint_delay(self, val: str, ans: typing.Dict[str, typing.Any]) -> None:
         ans['repaint_delay'] = positive_int(val)
 
@@ -1410,6 +1417,7 @@ def create_result_dict() -> typing.Dict[str, typing.Any]:
         'modify_font': {},
         'narrow_symbols': {},
         'remote_control_password': {},
+        'remote_control_peer_credentials': {},
         'symbol_map': {},
         'watcher': {},
         'map': [],
@@ -409,7 +409,9 @@
  'pointer_shape_when_dragging',
  'pointer_shape_when_grabbed',
  'remember_window_size',
+ 'remote_control_encryption_key',
  'remote_control_password',
+ 'remote_control_peer_credentials',
  'repaint_delay',
  'resize_debounce_time',
  'resize_in_steps',
@@ -566,6 +568,7 @@ class Options:
     pointer_shape_when_dragging: choices_for_pointer_shape_when_dragging = 'beam'
     pointer_shape_when_grabbed: choices_for_pointer_shape_when_grabbed = 'arrow'
     remember_window_size: bool = True
+    remote_control_encryption_key: typing.Optional[str] = None
     repaint_delay: int = 10
     resize_debounce_time: typing.Tuple[float, float] = (0.1, 0.5)
     resize_in_steps: bool = False
@@ -632,6 +635,7 @@ class Options:
     modify_font: typing.Dict[str, kitty.fonts.FontModification] = {}
     narrow_symbols: typing.Dict[typing.Tuple[int, int], int] = {}
     remote_control_password: typing.Dict[str, typing.Sequence[str]] = {}
+    remote_control_peer_credentials: typing.Dict[str, kitty.options.utils.PeerCredentialsPolicy] = {}
     symbol_map: typing.Dict[typing.Tuple[int, int], str] = {}
     watcher: typing.Dict[str, str] = {}
     map: typing.List[kitty.options.utils.KeyDefinition] = []
@@ -753,6 +757,7 @@ def __setattr__(self, key: str, val: typing.Any) -> typing.Any:
 defaults.modify_font = {}
 defaults.narrow_symbols = {}
 defaults.remote_control_password = {}
+defaults.remote_control_peer_credentials = {}
 defaults.symbol_map = {}
 defaults.watcher = {}
 defaults.map = [
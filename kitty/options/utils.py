@@ -706,6 +706,51 @@ def remote_control_password(val: str, current_val: Dict[str, str]) -> Iterable[T
             yield parts[0], tuple(parts[1:])
 
 
+class PeerCredentialsPolicy(NamedTuple):
+    uids: FrozenSet[int] = frozenset()
+    gids: FrozenSet[int] = frozenset()
+    pids: FrozenSet[int] = frozenset()
+    require_password: bool = False
+
+    def allows(self, uid: int, gid: int, pid: int) -> bool:
+        # credentials are -1 when unknown, for example for TCP sockets
+        return (uid > -1 and uid in self.uids) or (gid > -1 and gid in self.gids) or (pid > 0 and pid in self.pids)
+
+
+def remote_control_peer_credentials(val: str, current_val: Dict[str, PeerCredentialsPolicy]) -> Iterable[Tuple[str, PeerCredentialsPolicy]]:
+    import grp
+    import pwd
+    val = val.strip()
+    if not val:
+        return
+    socket = ''
+    require_password = False
+    ids: Dict[str, List[int]] = {'uid': [], 'gid': [], 'pid': []}
+    for part in to_cmdline(val, expand=False):
+        if part == '--require-password':
+            require_password = True
+            continue
+        if part.startswith('--socket='):
+            socket = part[len('--socket='):]
+            continue
+        key, sep, items = part.partition('=')
+        if not sep or key not in ids:
+            raise ValueError(f'Invalid peer credentials specification: {part}')
+        for x in items.split(','):
+            if not x:
+                continue
+            try:
+                ids[key].append(int(x))
+            except ValueError:
+                if key == 'uid':
+                    ids[key].append(pwd.getpwnam(x).pw_uid)
+                elif key == 'gid':
+                    ids[key].append(grp.getgrnam(x).gr_gid)
+                else:
+                    raise
+    yield socket, PeerCredentialsPolicy(frozenset(ids['uid']), frozenset(ids['gid']), frozenset(ids['pid']), require_password)
+
+
 def clipboard_control(x: str) -> Tuple[str, ...]:
     return tuple(x.lower().split())
 
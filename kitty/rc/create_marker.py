@@ -17,17 +17,27 @@ class CreateMarker(RemoteCommand):
     match/str: Which window to create the marker in
     self/bool: Boolean indicating whether to create marker in the window the command is run in
     marker_spec/list.str: A list or arguments that define the marker specification, for example: ['text', '1', 'ERROR']
+    name/str: The name of the marker, named markers are applied in addition to other markers
     '''
 
     short_desc = 'Create a marker that highlights specified text'
     desc = (
         'Create a marker which can highlight text in the specified window. For example:'
-        ' :code:`create_marker text 1 ERROR`. For full details see: :doc:`marks`'
+        ' :code:`create_marker text 1 ERROR`. The number in the specification chooses the colors'
+        ' used for the matches, which can be changed with the :opt:`mark1_foreground` and similar options.'
+        ' Normally, creating a marker replaces the existing marker, use :option:`--name` to create'
+        ' multiple markers, for example, to highlight errors and warnings independently. For full details see: :doc:`marks`'
     )
     options_spec = MATCH_WINDOW_OPTION + '''\n
 --self
 type=bool-set
 Apply marker to the window this command is run in, rather than the active window.
+
+
+--name
+A name for the marker. Named markers are applied in addition to any other
+markers in the window and replace only an existing marker with the same name.
+They can be removed individually with :code:`kitten @ remove-marker --name`.
 '''
     args = RemoteCommand.Args(spec='MARKER SPECIFICATION', json_field='marker_spec', minimum_count=2)
 
@@ -38,13 +48,13 @@ def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: Arg
             parse_marker_spec(args[0], args[1:])
         except Exception as err:
             self.fatal(f"Failed to parse marker specification {' '.join(args)} with error: {err}")
-        return {'match': opts.match, 'self': opts.self, 'marker_spec': args}
+        return {'match': opts.match, 'self': opts.self, 'marker_spec': args, 'name': opts.name}
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
         args = payload_get('marker_spec')
         for window in self.windows_for_match_payload(boss, window, payload_get):
             if window:
-                window.set_marker(args)
+                window.set_marker(args, payload_get('name') or '')
         return None
 
 
@@ -1,7 +1,7 @@
 #!/usr/bin/env python
 # License: GPLv3 Copyright: 2020, Kovid Goyal <kovid at kovidgoyal.net>
 
-from typing import TYPE_CHECKING, Optional
+from typing import TYPE_CHECKING, Any, Dict, Optional
 
 from .base import MATCH_TAB_OPTION, ArgsType, Boss, MatchError, PayloadGetType, PayloadType, RCOptions, RemoteCommand, ResponseType, Window
 
@@ -14,6 +14,8 @@ class DetachTab(RemoteCommand):
     protocol_spec = __doc__ = '''
     match/str: Which tab to detach
     target_tab/str: Which tab to move the detached tab to the OS window it is run in
+    tab_location/choices.first.before.after.last: Where to place the detached tab in the OS window it is moved to
+    keep_focus/bool: Boolean indicating whether the currently focused window should retain focus
     self/bool: Boolean indicating whether to detach the tab the command is run in
     '''
 
@@ -23,17 +25,35 @@ class DetachTab(RemoteCommand):
         ' or add them to the OS window containing the tab specified by :option:`kitty @ detach-tab --target-tab`'
     )
     options_spec = MATCH_TAB_OPTION + '\n\n' + MATCH_TAB_OPTION.replace('--match -m', '--target-tab -t') + '''\n
+--tab-location
+type=choices
+choices=last,first,before,after
+default=last
+Where to place the detached tabs relative to the other tabs in the OS window
+they are moved to. :code:`before` and :code:`after` are relative to the active
+tab in that OS window.
+
+
+--keep-focus
+type=bool-set
+Keep the focus on the currently focused window, instead of moving it to the
+detached tabs. Has no effect when the focused tab is itself detached.
+
+
 --self
 type=bool-set
 Detach the tab this command is run in, rather than the active tab.
 '''
 
     def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: ArgsType) -> PayloadType:
-        return {'match': opts.match, 'target_tab': opts.target_tab, 'self': opts.self}
+        return {
+            'match': opts.match, 'target_tab': opts.target_tab, 'tab_location': opts.tab_location,
+            'keep_focus': opts.keep_focus, 'self': opts.self,
+        }
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
         match = payload_get('target_tab')
-        kwargs = {}
+        kwargs: Dict[str, Any] = {'tab_location': payload_get('tab_location') or 'last', 'keep_focus': bool(payload_get('keep_focus'))}
         if match:
             targets = tuple(boss.match_tabs(match))
             if not targets:
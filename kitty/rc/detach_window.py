@@ -1,7 +1,7 @@
 #!/usr/bin/env python
 # License: GPLv3 Copyright: 2020, Kovid Goyal <kovid at kovidgoyal.net>
 
-from typing import TYPE_CHECKING, Optional, Union
+from typing import TYPE_CHECKING, Any, Dict, Optional, Union
 
 from .base import MATCH_TAB_OPTION, MATCH_WINDOW_OPTION, ArgsType, Boss, MatchError, PayloadGetType, PayloadType, RCOptions, RemoteCommand, ResponseType, Window
 
@@ -14,6 +14,9 @@ class DetachWindow(RemoteCommand):
     protocol_spec = __doc__ = '''
     match/str: Which window to detach
     target_tab/str: Which tab to move the detached window to
+    target_os_window/str: Which OS window to move the detached window to, as a tab to match or new
+    tab_location/choices.first.before.after.last: Where to place the new tab if one is created
+    keep_focus/bool: Boolean indicating whether the currently focused window should retain focus
     self/bool: Boolean indicating whether to detach the window the command is run in
     '''
 
@@ -21,25 +24,53 @@ class DetachWindow(RemoteCommand):
     desc = (
         'Detach the specified windows and either move them into a new tab, a new OS window'
         ' or add them to the specified tab. Use the special value :code:`new` for :option:`kitty @ detach-window --target-tab`'
-        ' to move to a new tab. If no target tab is specified the windows are moved to a new OS window.'
+        ' to move to a new tab. If no target tab is specified the windows are moved to a new tab in the OS window'
+        ' specified by :option:`kitty @ detach-window --target-os-window` or, failing that, a new OS window.'
     )
     options_spec = (
         MATCH_WINDOW_OPTION + '\n\n' + MATCH_TAB_OPTION.replace('--match -m', '--target-tab -t') +
         '''Use the special value :code:`new` to move to a new tab.
 
 
+--target-os-window
+The OS window to move the windows to, specified as a tab to match, the windows
+are placed in a new tab in the OS window containing the first matching tab. Use
+the special value :code:`new` to move to a new OS window. Ignored when
+:option:`kitty @ detach-window --target-tab` is specified.
+
+
+--tab-location
+type=choices
+choices=last,first,before,after
+default=last
+Where to place the new tab, if one is created, relative to the other tabs in
+its OS window. :code:`before` and :code:`after` are relative to the active
+tab.
+
+
+--keep-focus
+type=bool-set
+Keep the focus on the currently focused window, instead of moving it to the
+detached windows. If the focused window is itself detached, the focus stays on
+the tab it was in.
+
+
 --self
 type=bool-set
 Detach the window this command is run in, rather than the active window.
 ''')
 
     def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: ArgsType) -> PayloadType:
-        return {'match': opts.match, 'target_tab': opts.target_tab, 'self': opts.self}
+        return {
+            'match': opts.match, 'target_tab': opts.target_tab, 'target_os_window': opts.target_os_window,
+            'tab_location': opts.tab_location, 'keep_focus': opts.keep_focus, 'self': opts.self,
+        }
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
         windows = self.windows_for_match_payload(boss, window, payload_get)
         match = payload_get('target_tab')
         target_tab_id: Optional[Union[str, int]] = None
+        target_os_window_id: Union[str, int] = 'new'
         newval: Union[str, int] = 'new'
         if match:
             if match == 'new':
@@ -49,7 +80,14 @@ def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get:
                 if not tabs:
                     raise MatchError(match, 'tabs')
                 target_tab_id = tabs[0].id
-        kwargs = {'target_os_window_id': newval} if target_tab_id is None else {'target_tab_id': target_tab_id}
+        elif (os_window_match := payload_get('target_os_window')) and os_window_match != 'new':
+            tabs = tuple(boss.match_tabs(os_window_match))
+            if not tabs:
+                raise MatchError(os_window_match, 'tabs')
+            target_os_window_id = tabs[0].os_window_id
+        kwargs: Dict[str, Any] = {'target_os_window_id': target_os_window_id} if target_tab_id is None else {'target_tab_id': target_tab_id}
+        kwargs['tab_location'] = payload_get('tab_location') or 'last'
+        kwargs['keep_focus'] = bool(payload_get('keep_focus'))
         for window in windows:
             if window:
                 boss._move_window_to(window=window, **kwargs)
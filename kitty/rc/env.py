@@ -34,14 +34,18 @@ def message_to_kitty(self, global_opts: RCOptions, opts: Any, args: ArgsType) ->
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
         from kitty.child import default_env, set_default_env
+        from kitty.options.utils import DELETE_ENV_VAR
         from kitty.utils import expandvars
         new_env = payload_get('env') or {}
         env = default_env().copy()
         for k, v in new_env.items():
             if k.endswith('='):
-                env.pop(k, None)
+                # variables from the environment of the kitty process must be
+                # marked for deletion rather than removed, as they are added
+                # back when setting the default environment
+                env[k[:-1]] = DELETE_ENV_VAR
             else:
-                env[k] = expandvars(v or '', env)
+                env[k] = expandvars(v or '', {ek: ev for ek, ev in env.items() if ev is not DELETE_ENV_VAR})
         set_default_env(env)
         return None
 
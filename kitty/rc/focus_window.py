@@ -15,11 +15,25 @@
 class FocusWindow(RemoteCommand):
     protocol_spec = __doc__ = '''
     match/str: The window to focus
+    direction/choices.none.left.right.up.down.recent: Focus the neighbor of the active window in the tab of the matched window instead, \
+        one of :code:`none`, :code:`left`, :code:`right`, :code:`up`, :code:`down` or :code:`recent`
     '''
 
     short_desc = 'Focus the specified window'
-    desc = 'Focus the specified window, if no window is specified, focus the window this command is run inside.'
+    desc = (
+        'Focus the specified window, if no window is specified, focus the window this command is run inside.'
+        ' Alternately, focus a window relative to the active window, similar to the :ac:`neighboring_window`'
+        ' and :ac:`nth_window` actions, using :option:`--direction`.'
+    )
     options_spec = MATCH_WINDOW_OPTION + '''\n\n
+--direction
+default=none
+choices=none,left,right,up,down,recent
+Instead of focusing the matched window, focus the window in the specified
+direction from the active window in the tab containing the matched window.
+:code:`recent` focuses the previously active window in that tab.
+
+
 --no-response
 type=bool-set
 default=false
@@ -28,11 +42,23 @@ class FocusWindow(RemoteCommand):
 '''
 
     def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: ArgsType) -> PayloadType:
-        return {'match': opts.match}
+        return {'match': opts.match, 'direction': opts.direction}
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
+        direction = payload_get('direction') or 'none'
         for window in self.windows_for_match_payload(boss, window, payload_get):
             if window:
+                if direction != 'none':
+                    tab = window.tabref()
+                    if tab is None:
+                        break
+                    if direction == 'recent':
+                        tab.nth_window(-1)
+                    else:
+                        tab.neighboring_window({'up': 'top', 'down': 'bottom'}.get(direction, direction))
+                    window = tab.active_window
+                    if window is None:
+                        break
                 os_window_id = boss.set_active_window(window)
                 if os_window_id:
                     focus_os_window(os_window_id, True)
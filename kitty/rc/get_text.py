@@ -22,6 +22,7 @@ class GetText(RemoteCommand):
     wrap_markers/bool: Boolean, if True add wrap markers to output
     clear_selection/bool: Boolean, if True clear the selection in the matched window
     self/bool: Boolean, if True use window the command was run in
+    spans/bool: Boolean, if True send ANSI formatting codes for the client to convert the text into styled spans
     '''
 
     short_desc = 'Get text from the specified window'
@@ -64,6 +65,18 @@ class GetText(RemoteCommand):
 --self
 type=bool-set
 Get text from the window this command is run in, rather than the active window.
+
+
+--spans
+type=bool-set
+Return the text as a JSON array of spans instead, so that the formatting can be
+reconstructed without parsing ANSI escape codes. Each span is an object with a
+:code:`text` key and keys for the formatting of the text that is not the default:
+:code:`fg`, :code:`bg` and :code:`underline_color` which are either indices
+into the 256 color table or :code:`#rrggbb` strings, :code:`bold`, :code:`dim`,
+:code:`italic`, :code:`reverse`, :code:`strikethrough`, :code:`underline` which
+is the underline style and :code:`hyperlink` which is an object with the
+:code:`url` and optionally :code:`id` of the hyperlink.
 '''
 
     field_to_option_map = {'wrap_markers': 'add_wrap_markers', 'cursor': 'add_cursor'}
@@ -77,6 +90,7 @@ def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: Arg
             'wrap_markers': opts.add_wrap_markers,
             'clear_selection': opts.clear_selection,
             'self': opts.self,
+            'spans': opts.spans,
         }
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
@@ -86,41 +100,45 @@ def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get:
             window = windows[0]
         else:
             return None
+        as_spans = bool(payload_get('spans'))
+        as_ansi = as_spans or bool(payload_get('ansi'))
         if payload_get('extent') == 'selection':
-            ans = window.text_for_selection(as_ansi=payload_get('ansi'))
+            ans = window.text_for_selection(as_ansi=as_ansi)
         elif payload_get('extent') == 'first_cmd_output_on_screen':
             ans = window.cmd_output(
                 CommandOutput.first_on_screen,
-                as_ansi=bool(payload_get('ansi')),
+                as_ansi=as_ansi,
                 add_wrap_markers=bool(payload_get('wrap_markers')),
             )
         elif payload_get('extent') == 'last_cmd_output':
             ans = window.cmd_output(
                 CommandOutput.last_run,
-                as_ansi=bool(payload_get('ansi')),
+                as_ansi=as_ansi,
                 add_wrap_markers=bool(payload_get('wrap_markers')),
             )
         elif payload_get('extent') == 'last_non_empty_output':
             ans = window.cmd_output(
                 CommandOutput.last_non_empty,
-                as_ansi=bool(payload_get('ansi')),
+                as_ansi=as_ansi,
                 add_wrap_markers=bool(payload_get('wrap_markers')),
             )
         elif payload_get('extent') == 'last_visited_cmd_output':
             ans = window.cmd_output(
                 CommandOutput.last_visited,
-                as_ansi=bool(payload_get('ansi')),
+                as_ansi=as_ansi,
                 add_wrap_markers=bool(payload_get('wrap_markers')),
             )
         else:
             ans = window.as_text(
-                as_ansi=bool(payload_get('ansi')),
+                as_ansi=as_ansi,
                 add_history=payload_get('extent') == 'all',
-                add_cursor=bool(payload_get('cursor')),
+                add_cursor=bool(payload_get('cursor')) and not as_spans,
                 add_wrap_markers=bool(payload_get('wrap_markers')),
             )
         if payload_get('clear_selection'):
             window.clear_selection()
+        if as_spans:
+            return ans or ''
         return ans
 
 
@@ -15,22 +15,28 @@ class RemoveMarker(RemoteCommand):
     protocol_spec = __doc__ = '''
     match/str: Which window to remove the marker from
     self/bool: Boolean indicating whether to detach the window the command is run in
+    name/str: The name of the marker to remove, if not specified all markers are removed
     '''
 
-    short_desc = 'Remove the currently set marker, if any.'
+    short_desc = 'Remove the currently set markers, if any.'
     options_spec = MATCH_WINDOW_OPTION + '''\n
 --self
 type=bool-set
 Apply marker to the window this command is run in, rather than the active window.
+
+
+--name
+Remove only the marker with the specified name, created with
+:code:`kitten @ create-marker --name`. By default, all markers are removed.
 '''
 
     def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: ArgsType) -> PayloadType:
-        return {'match': opts.match, 'self': opts.self}
+        return {'match': opts.match, 'self': opts.self, 'name': opts.name}
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
         for window in self.windows_for_match_payload(boss, window, payload_get):
             if window:
-                window.remove_marker()
+                window.remove_marker(payload_get('name') or '')
         return None
 
 
@@ -15,9 +15,12 @@ class ResizeOSWindow(RemoteCommand):
     self/bool: Boolean indicating whether to close the window the command is run in
     incremental/bool: Boolean indicating whether to adjust the size incrementally
     action/choices.resize.toggle-fullscreen.toggle-maximized: One of :code:`resize, toggle-fullscreen` or :code:`toggle-maximized`
-    unit/choices.cells.pixels: One of :code:`cells` or :code:`pixels`
+    unit/choices.cells.pixels.percent: One of :code:`cells`, :code:`pixels` or :code:`percent`
     width/int: Integer indicating desired window width
     height/int: Integer indicating desired window height
+    preset/choices.none.maximized.half-left.half-right.half-top.half-bottom.center: The preset to resize the window to, \
+        one of :code:`none`, :code:`maximized`, :code:`half-left`, :code:`half-right`, :code:`half-top`, \
+        :code:`half-bottom` or :code:`center`
     '''
 
     short_desc = 'Resize the specified OS Windows'
@@ -35,8 +38,10 @@ class ResizeOSWindow(RemoteCommand):
 
 --unit
 default=cells
-choices=cells,pixels
-The unit in which to interpret specified sizes.
+choices=cells,pixels,percent
+The unit in which to interpret specified sizes. :code:`percent` means percentages
+of the size of the screen the window is on, excluding areas such as panels and
+docks.
 
 
 --width
@@ -51,6 +56,16 @@ class ResizeOSWindow(RemoteCommand):
 Change the height of the window. Zero leaves the height unchanged.
 
 
+--preset
+default=none
+choices=none,maximized,half-left,half-right,half-top,half-bottom,center
+Resize the window to a preset size and position on the screen the window is on,
+instead of using :option:`--width` and :option:`--height`. For example,
+:code:`half-left` makes the window occupy the left half of the screen and
+:code:`center` makes it occupy the center quarter of the screen. Note that
+on Wayland, windows cannot be moved, so only their size is changed.
+
+
 --incremental
 type=bool-set
 Treat the specified sizes as increments on the existing window size
@@ -73,7 +88,7 @@ def message_to_kitty(self, global_opts: RCOptions, opts: 'CLIOptions', args: Arg
         return {
             'match': opts.match, 'action': opts.action, 'unit': opts.unit,
             'width': opts.width, 'height': opts.height, 'self': opts.self,
-            'incremental': opts.incremental
+            'incremental': opts.incremental, 'preset': opts.preset,
         }
 
     def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get: PayloadGetType) -> ResponseType:
@@ -81,7 +96,9 @@ def response_from_kitty(self, boss: Boss, window: Optional[Window], payload_get:
         if windows:
             ac = payload_get('action')
             for os_window_id in {w.os_window_id for w in windows if w}:
-                if ac == 'resize':
+                if ac == 'resize' and payload_get('preset') not in (None, 'none'):
+                    boss.move_os_window_to_preset(os_window_id, payload_get('preset'))
+                elif ac == 'resize':
                     boss.resize_os_window(
                         os_window_id, width=payload_get('width'), height=payload_get('height'),
                         unit=payload_get('unit'), incremental=payload_get('incremental')
@@ -274,6 +274,31 @@ def handle_cmd(
 If no password is available, kitty will usually just send the remote control command
 without a password. This option can be used to force it to :code:`always` or :code:`never` use
 the supplied password.
+
+
+--socket-owner
+When connecting to kitty over a UNIX socket, verify that the socket is owned by
+the specified user, given as a user name or numeric uid, by checking the
+credentials of the process listening on it. Useful to prevent sending commands,
+which may include passwords, to a process impersonating kitty on a shared
+socket path. Not supported on all platforms.
+
+
+--use-session-cache
+type=bool-set
+When sending encrypted commands, re-use the encryption key from previous
+invocations, saving the key exchange for every command. The key is stored in
+the cache directory, readable only by the current user, and is used for at most
+a day. Useful when running many commands from scripts.
+
+
+--interactive -i
+type=bool-set
+Start an interactive shell to control kitty. It supports completion of
+commands, options and window and tab ids, has persistent history, pretty prints
+JSON responses and can repeat a command periodically by prefixing it with
+:code:`watch`. This is the default when no command is specified. When a command
+is specified, it is run first and then the shell is started.
 '''.format, appname=appname)
 
 
@@ -948,9 +948,14 @@ class SSHConnectionData(NamedTuple):
 
 
 def get_new_os_window_size(
-    metrics: 'OSWindowSize', width: int, height: int, unit: str, incremental: bool = False, has_window_scaling: bool = True
+    metrics: 'OSWindowSize', width: int, height: int, unit: str, incremental: bool = False, has_window_scaling: bool = True,
+    workarea_size: Tuple[int, int] = (0, 0),
 ) -> Tuple[int, int]:
-    if unit == 'cells':
+    if unit == 'percent':
+        # percentages of the work area of the monitor the window is on
+        width = round(workarea_size[0] * width / 100)
+        height = round(workarea_size[1] * height / 100)
+    elif unit == 'cells':
         cw = metrics['cell_width']
         ch = metrics['cell_height']
         width *= cw
@@ -967,6 +972,22 @@ def get_new_os_window_size(
     return w, h
 
 
+def os_window_geometry_for_preset(preset: str, workarea: Tuple[int, int, int, int]) -> Tuple[int, int, int, int]:
+    ''' Return the x, y, width and height of the preset within the specified work area '''
+    x, y, width, height = workarea
+    if preset == 'half-left':
+        return x, y, width // 2, height
+    if preset == 'half-right':
+        return x + width // 2, y, width - width // 2, height
+    if preset == 'half-top':
+        return x, y, width, height // 2
+    if preset == 'half-bottom':
+        return x, y + height // 2, width, height - height // 2
+    if preset == 'center':
+        return x + width // 4, y + height // 4, width // 2, height // 2
+    return workarea
+
+
 def get_all_processes() -> Iterable[int]:
     if is_macos:
         from kitty.fast_data_types import get_all_processes as f
@@ -89,6 +89,7 @@
 from .types import MouseEvent, OverlayType, WindowGeometry, ac, run_once
 from .typing import BossType, ChildType, EdgeLiteral, TabType, TypedDict
 from .utils import (
+    SSHConnectionData,
     docs_url,
     key_val_matcher,
     kitty_ansi_sanitizer_pat,
@@ -110,6 +111,7 @@
 
 if TYPE_CHECKING:
     from .file_transmission import FileTransmission
+    from .marks import MarkerFunc
 
 
 class CwdRequestType(Enum):
@@ -515,6 +517,8 @@ def __init__(
         self.actions_on_focus_change: List[Callable[['Window', bool], None]] = []
         self.actions_on_removal: List[Callable[['Window'], None]] = []
         self.current_marker_spec: Optional[Tuple[str, Union[str, Tuple[Tuple[int, str], ...]]]] = None
+        self.current_marker: Optional['MarkerFunc'] = None
+        self.named_markers: Dict[str, 'MarkerFunc'] = {}
         self.kitten_result_processors: List[Callable[['Window', Any], None]] = []
         self.pty_resized_once = False
         self.last_reported_pty_size = (-1, -1, -1, -1)
@@ -886,6 +890,21 @@ def osc_1337(self, raw_data: str) -> None:
                 ukey, has_equal, uval = val.partition('=')
                 self.set_user_var(ukey, (standard_b64decode(uval) if uval else b'') if has_equal == '=' else None)
 
+    def cmd_finished(self, raw_data: str) -> None:
+        # OSC 133;D;exit_status;duration=milliseconds sent by shell integration
+        # when a command finishes, recorded as user vars so that it is available
+        # to tab bar templates and via remote control
+        parts = raw_data.split(';')[1:]
+        if not parts or not parts[0].lstrip('-').isdigit():
+            return
+        duration = ''
+        for x in parts[1:]:
+            k, sep, v = x.partition('=')
+            if k == 'duration' and v.isdigit():
+                duration = v
+        self.set_user_var('kitty_cmd_exit_status', parts[0])
+        self.set_user_var('kitty_cmd_duration', duration or None)
+
     def desktop_notify(self, osc_code: int, raw_data: str) -> None:
         if osc_code == 1337:
             self.osc_1337(raw_data)
@@ -946,24 +965,24 @@ def hyperlink_open_confirmed(self, url: str, cwd: Optional[str], q: str) -> None
         elif q == 'c':
             set_clipboard_string(url)
 
-    def handle_remote_file(self, netloc: str, remote_path: str) -> None:
+    def ssh_connection_data(self) -> Union[None, List[str], SSHConnectionData]:
+        ''' The data needed to connect to the host that the SSH client running
+        in this window is connected to. For the ssh kitten this is its ssh
+        command line, so that its connection can be shared. '''
         from kittens.remote_file.main import is_ssh_kitten_sentinel
         from kittens.ssh.utils import get_connection_data
-
-        from .utils import SSHConnectionData
-        args = self.ssh_kitten_cmdline()
-        conn_data: Union[None, List[str], SSHConnectionData] = None
-        if args:
+        if self.ssh_kitten_cmdline():
             ssh_cmdline = sorted(self.child.foreground_processes, key=lambda p: p['pid'])[-1]['cmdline'] or ['']
             if 'ControlPath=' in ' '.join(ssh_cmdline):
                 idx = ssh_cmdline.index('--')
-                conn_data = [is_ssh_kitten_sentinel] + list(ssh_cmdline[:idx + 2])
+                return [is_ssh_kitten_sentinel] + list(ssh_cmdline[:idx + 2])
+        return get_connection_data(self.child.foreground_cmdline, self.child.foreground_cwd or self.child.current_cwd or '')
+
+    def handle_remote_file(self, netloc: str, remote_path: str) -> None:
+        conn_data = self.ssh_connection_data()
         if conn_data is None:
-            args = self.child.foreground_cmdline
-            conn_data = get_connection_data(args, self.child.foreground_cwd or self.child.current_cwd or '')
-            if conn_data is None:
-                get_boss().show_error('Could not handle remote file', f'No SSH connection data found in: {args}')
-                return
+            get_boss().show_error('Could not handle remote file', f'No SSH connection data found in: {self.child.foreground_cmdline}')
+            return
         get_boss().run_kitten(
             'remote_file', '--hostname', netloc.partition(':')[0], '--path', remote_path,
             '--ssh-connection-data', json.dumps(conn_data)
@@ -1122,7 +1141,7 @@ def set_color_table_color(self, code: int, value: str) -> None:
             self.refresh()
 
     def request_capabilities(self, q: str) -> None:
-        for result in get_capabilities(q, get_options()):
+        for result in get_capabilities(q, get_options(), self.id, self.os_window_id):
             self.screen.send_escape_code_to_child(DCS, result)
 
     def handle_remote_cmd(self, cmd: str) -> None:
@@ -1668,27 +1687,47 @@ def toggle_marker(self, ftype: str, spec: Union[str, Tuple[Tuple[int, str], ...]
         from .marks import marker_from_spec
         key = ftype, spec
         if key == self.current_marker_spec:
-            self.remove_marker()
-            return
-        self.screen.set_marker(marker_from_spec(ftype, spec, flags))
-        self.current_marker_spec = key
+            self.current_marker_spec = self.current_marker = None
+        else:
+            self.current_marker = marker_from_spec(ftype, spec, flags)
+            self.current_marker_spec = key
+        self.apply_markers()
 
-    def set_marker(self, spec: Union[str, Sequence[str]]) -> None:
+    def set_marker(self, spec: Union[str, Sequence[str]], name: str = '') -> None:
         from .marks import marker_from_spec
         from .options.utils import parse_marker_spec, toggle_marker
         if isinstance(spec, str):
             func, (ftype, spec_, flags) = toggle_marker('toggle_marker', spec)
         else:
             ftype, spec_, flags = parse_marker_spec(spec[0], spec[1:])
-        key = ftype, spec_
-        self.screen.set_marker(marker_from_spec(ftype, spec_, flags))
-        self.current_marker_spec = key
-
-    @ac('mk', 'Remove a previously created marker')
-    def remove_marker(self) -> None:
-        if self.current_marker_spec is not None:
+        marker = marker_from_spec(ftype, spec_, flags)
+        if name:
+            self.named_markers[name] = marker
+        else:
+            self.current_marker = marker
+            self.current_marker_spec = ftype, spec_
+        self.apply_markers()
+
+    def apply_markers(self) -> None:
+        from .marks import combine_markers
+        markers = list(self.named_markers.values())
+        if self.current_marker is not None:
+            markers.insert(0, self.current_marker)
+        if not markers:
             self.screen.set_marker()
-            self.current_marker_spec = None
+        else:
+            self.screen.set_marker(markers[0] if len(markers) == 1 else combine_markers(markers))
+
+    @ac('mk', 'Remove all previously created markers or only the marker with the specified name')
+    def remove_marker(self, name: str = '') -> None:
+        if name:
+            changed = self.named_markers.pop(name, None) is not None
+        else:
+            changed = self.current_marker is not None or bool(self.named_markers)
+            self.current_marker_spec = self.current_marker = None
+            self.named_markers.clear()
+        if changed:
+            self.apply_markers()
 
     @ac('mk', 'Scroll to the next or previous mark of the specified type')
     def scroll_to_mark(self, prev: bool = True, mark: int = 0) -> None:
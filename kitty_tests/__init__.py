@@ -63,6 +63,9 @@ def desktop_notify(self, osc_code: int, raw_data: str) -> None:
     def open_url(self, url: str, hyperlink_id: int) -> None:
         self.open_urls.append((url, hyperlink_id))
 
+    def cmd_finished(self, raw_data: str) -> None:
+        self.finished_cmds.append(raw_data)
+
     def clipboard_control(self, data: str, is_partial: bool = False) -> None:
         self.cc_buf.append((data, is_partial))
 
@@ -73,6 +76,7 @@ def clear(self) -> None:
         self.iutf8 = True
         self.notifications = []
         self.open_urls = []
+        self.finished_cmds = []
         self.cc_buf = []
         self.bell_count = 0
         self.clone_cmds = []
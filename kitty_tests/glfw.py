@@ -16,7 +16,7 @@ def test_os_window_size_calculation(self):
         from kitty.utils import get_new_os_window_size
 
         def t(w, h, width=0, height=0, unit='cells', incremental=False):
-            self.ae((w, h), get_new_os_window_size(metrics, width, height, unit, incremental, has_window_scaling))
+            self.ae((w, h), get_new_os_window_size(metrics, width, height, unit, incremental, has_window_scaling, (1920, 1080)))
 
         with self.subTest(has_window_scaling=False):
             has_window_scaling = False
@@ -39,6 +39,18 @@ def t(w, h, width=0, height=0, unit='cells', incremental=False):
             t(80 * metrics['cell_width'] / metrics['xscale'] + metrics['width'], 100, 80, incremental=True)
             t(1217, 100, 1217, unit='pixels')
             t(1217 + metrics['width'], 100, 1217, unit='pixels', incremental=True)
+            t(960, 270, 50, 25, unit='percent')
+            t(960 + metrics['width'], 100, 50, unit='percent', incremental=True)
+
+    def test_os_window_presets(self):
+        from kitty.utils import os_window_geometry_for_preset as p
+        wa = (100, 20, 1001, 701)
+        self.ae(p('maximized', wa), wa)
+        self.ae(p('half-left', wa), (100, 20, 500, 701))
+        self.ae(p('half-right', wa), (600, 20, 501, 701))
+        self.ae(p('half-top', wa), (100, 20, 1001, 350))
+        self.ae(p('half-bottom', wa), (100, 370, 1001, 351))
+        self.ae(p('center', wa), (350, 195, 500, 350))
 
     @unittest.skipIf(is_macos, 'Skipping test on macOS because glfw-cocoa.so is not built with backend_utils')
     def test_utf_8_strndup(self):
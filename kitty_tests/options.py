@@ -64,6 +64,17 @@ def keys_for_func(opts, name):
             'size:Test': FontModification(ModificationType.size, ModificationValue(-1., ModificationUnit.pixel), 'Test'),
         })
 
+        # only options that opt in can have their values read from commands
+        opts = p("""remote_control_password cmd:echo '"my pass" get-colors'""", 'shell cmd:echo x')
+        self.ae(opts.remote_control_password, {'my pass': ('get-colors',)})
+        self.ae(opts.shell, 'cmd:echo x')
+
+        # line continuations and multi-line values, must match tools/config/api.go
+        opts = p('font_size 1\\', '  3', 'shell <<END', '/bin/sh', '  -l', 'END')
+        self.ae(opts.font_size, 13)
+        self.ae(opts.shell, '/bin/sh\n  -l')
+        p('shell <<END', '/bin/sh', bad_line_num=1)
+
         # test the aliasing options
         opts = p('env A=1', 'env B=x$A', 'env C=', 'env D', 'clear_all_shortcuts y', 'kitten_alias a b --moo', 'map f1 kitten a arg')
         self.ae(opts.env, {'A': '1', 'B': 'x1', 'C': '', 'D': DELETE_ENV_VAR})
@@ -2,7 +2,7 @@
 # License: GPL v3 Copyright: 2016, Kovid Goyal <kovid at kovidgoyal.net>
 
 from kitty.fast_data_types import DECAWM, DECCOLM, DECOM, IRM, Cursor, parse_bytes
-from kitty.marks import marker_from_function, marker_from_regex
+from kitty.marks import combine_markers, marker_from_function, marker_from_regex
 from kitty.window import pagerhist
 
 from . import BaseTest
@@ -736,6 +736,8 @@ def mark_x(text):
 
         s.set_marker(marker_from_function(mark_x))
         self.ae(s.marked_cells(), [(0, 1, 1), (2, 1, 2), (4, 1, 3)])
+        s.set_marker(combine_markers((marker_from_regex('ba', 1), marker_from_regex('a', 2), marker_from_regex('y', 3))))
+        self.ae(s.marked_cells(), [(0, 0, 2), (1, 0, 1), (2, 0, 1), (3, 0, 2), (1, 1, 3), (3, 1, 3)])
         s = self.create_screen(lines=5, scrollback=10)
         for i in range(15):
             s.draw(str(i))
@@ -1026,6 +1028,9 @@ def mark_prompt():
         def mark_output():
             parse_bytes(s, b'\033]133;C\007')
 
+        parse_bytes(s, b'\033]133;D;1;duration=12\007')
+        self.ae(s.callbacks.finished_cmds, ['D;1;duration=12'])
+
         for i in range(4):
             mark_prompt()
             s.draw(f'$ {i}')
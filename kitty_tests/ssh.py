@@ -11,7 +11,7 @@
 from contextlib import suppress
 from functools import lru_cache
 
-from kittens.ssh.utils import get_connection_data
+from kittens.ssh.utils import get_connection_data, scp_args_for_connection_data, scp_args_from_ssh_args
 from kitty.constants import is_macos, kitten_exe, runtime_dir
 from kitty.fast_data_types import CURSOR_BEAM, shm_unlink
 from kitty.utils import SSHConnectionData
@@ -56,6 +56,16 @@ def t(cmdline, binary='ssh', host='main', port=None, identity_file='', extra_arg
         t('ssh --kitten=one -p 12 --kitten two -ix main', identity_file='x', port=12, extra_args=(('--kitten', 'one'), ('--kitten', 'two')))
         self.assertTrue(runtime_dir())
 
+    def test_ssh_scp_args(self):
+        def t(cmdline, expected):
+            self.ae(scp_args_from_ssh_args(cmdline.split()), expected.split())
+
+        t('-t -o ControlMaster=auto -oControlPath=/cp', '-o ControlMaster=auto -o ControlPath=/cp')
+        t('-p 22 -l user -iident -v', '-P 22 -o User=user -i ident')
+        t('-4tC -- host -p 33', '-4 -C')
+        self.ae(scp_args_for_connection_data(['sentinel', 'ssh', '-t', '-o', 'ControlPath=/cp', '--', 'user@host']), (['-o', 'ControlPath=/cp'], 'user@host'))
+        self.ae(scp_args_for_connection_data(SSHConnectionData('ssh', 'host', 33, '/ident')), (['-P', '33', '-i', '/ident'], 'host'))
+
     @property
     @lru_cache()
     def all_possible_sh(self):
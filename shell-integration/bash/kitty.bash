@@ -76,7 +76,7 @@ fi
 builtin declare -A _ksi_prompt
 _ksi_prompt=(
     [cursor]='y' [title]='y' [mark]='y' [complete]='y' [cwd]='y' [ps0]='' [ps0_suffix]='' [ps1]='' [ps1_suffix]='' [ps2]=''
-    [hostname_prefix]='' [sourced]='y' [last_reported_cwd]=''
+    [command_status]='y' [hostname_prefix]='' [sourced]='y' [last_reported_cwd]='' [dcs_start]=$'\eP' [dcs_end]=$'\e\\'
 )
 
 _ksi_main() {
@@ -89,6 +89,10 @@ _ksi_main() {
             "no-prompt-mark") _ksi_prompt[mark]='n';;
             "no-complete") _ksi_prompt[complete]='n';;
             "no-cwd") _ksi_prompt[cwd]='n';;
+            "no-command-status") _ksi_prompt[command_status]='n';;
+            # wrap the escape codes kitty uses in the tmux passthrough escape code
+            "tmux-passthrough") _ksi_prompt[dcs_start]=$'\ePtmux;\e\eP'; _ksi_prompt[dcs_end]=$'\e\e\\\e\\';;
+            "no-kitty-escapes") _ksi_prompt[dcs_start]='';;
         esac
     done
     IFS="$ifs"
@@ -98,8 +102,9 @@ _ksi_main() {
     _ksi_debug_print() {
         # print a line to STDERR of parent kitty process
         builtin local b
+        [[ -z "${_ksi_prompt[dcs_start]}" ]] && builtin return
         b=$(builtin command base64 <<< "${@}")
-        builtin printf "\eP@kitty-print|%s\e\\" "${b//[[:space:]]}}"
+        builtin printf "%s@kitty-print|%s%s" "${_ksi_prompt[dcs_start]}" "${b//[[:space:]]}}" "${_ksi_prompt[dcs_end]}"
     }
 
     _ksi_set_mark() {
@@ -115,7 +120,22 @@ _ksi_main() {
     builtin unset -f _ksi_set_mark
     _ksi_prompt[secondary_prompt]="\n${_ksi_prompt[start_secondary_mark]}\[\e]133;A;k=s\a\]${_ksi_prompt[end_secondary_mark]}"
 
+    _ksi_restore_status() {
+        # set $? to the exit status of the last command, without forking a subshell
+        builtin return "${_ksi_prompt[status]}"
+    }
+
     _ksi_prompt_command() {
+        if [[ -n "${_ksi_prompt[cmd_start]}" ]]; then
+            # a command was run, report its exit status and duration in milliseconds
+            if [[ "${_ksi_prompt[cmd_start]}" == "none" ]]; then
+                builtin printf "\e]133;D;%s\a" "${_ksi_prompt[status]}"
+            else
+                builtin local start="${_ksi_prompt[cmd_start]/[.,]/}" now="${EPOCHREALTIME/[.,]/}"
+                builtin printf "\e]133;D;%s;duration=%s\a" "${_ksi_prompt[status]}" "$(( (now - start) / 1000 ))"
+            fi
+            builtin unset "_ksi_prompt[cmd_start]"
+        fi
         # we first remove any previously added kitty code from the prompt variables and then add
         # it back, to ensure we have only a single instance
         if [[ -n "${_ksi_prompt[ps0]}" ]]; then
@@ -207,6 +227,12 @@ _ksi_main() {
         _ksi_prompt[ps1]+="\[\e]133;A\a\]"
         _ksi_prompt[ps2]+="\[\e]133;A;k=s\a\]"
         _ksi_prompt[ps0]+="\[\e]133;C\a\]"
+        if [[ "${_ksi_prompt[command_status]}" == "y" ]]; then
+            # record when the command starts, in the shell itself rather than a
+            # subshell, by using an assignment as the key of an empty lookup.
+            # EPOCHREALTIME needs bash 5, without it only the exit status is reported.
+            _ksi_prompt[ps0]+='${_ksi_prompt[${_ksi_prompt[cmd_start]=${EPOCHREALTIME:-none}}]}'
+        fi
     fi
 
     builtin alias edit-in-kitty="kitten edit-in-kitty"
@@ -255,12 +281,16 @@ _ksi_main() {
     # otherwise append a string. We check if _ksi_prompt_command exists as some shell
     # scripts stupidly export PROMPT_COMMAND making it inherited by all programs launched
     # from the shell
-    builtin local pc
+    # The exit status of the last command is recorded before any other prompt
+    # commands are run, as they will change it, and then restored so that
+    # the other prompt commands still see it in $?
+    builtin local pc sc
     pc='builtin declare -F _ksi_prompt_command > /dev/null 2> /dev/null && _ksi_prompt_command'
+    sc='_ksi_prompt[status]=$?; builtin declare -F _ksi_restore_status > /dev/null 2> /dev/null && _ksi_restore_status'
     if [[ -z "${PROMPT_COMMAND}" ]]; then
-        PROMPT_COMMAND=([0]="$pc")
+        PROMPT_COMMAND=([0]="$sc" [1]="$pc")
     elif [[ $(builtin declare -p PROMPT_COMMAND 2> /dev/null) =~ 'declare -a PROMPT_COMMAND' ]]; then
-        PROMPT_COMMAND+=("$pc")
+        PROMPT_COMMAND=("$sc" "${PROMPT_COMMAND[@]}" "$pc")
     else
         builtin local oldval
         oldval=$(builtin shopt -p extglob)
@@ -268,7 +298,7 @@ _ksi_main() {
         PROMPT_COMMAND="${PROMPT_COMMAND%%+([[:space:]])}"
         PROMPT_COMMAND="${PROMPT_COMMAND%%+(;)}"
         builtin eval "$oldval"
-        PROMPT_COMMAND+="; $pc"
+        PROMPT_COMMAND="$sc; $PROMPT_COMMAND; $pc"
     fi
     if [ -n "${KITTY_IS_CLONE_LAUNCH}" ]; then
         builtin local orig_conda_env="$CONDA_DEFAULT_ENV"
@@ -321,6 +351,10 @@ case :$SHELLOPTS: in
   *)
 
 _ksi_transmit_data() {
+    if [[ -z "${_ksi_prompt[dcs_start]}" ]]; then
+        builtin printf "%s\n" "The terminal multiplexer does not pass through the escape codes needed to communicate with kitty" > /dev/stderr
+        builtin return 1
+    fi
     builtin local data
     data="${1//[[:space:]]}"
     builtin local pos=0
@@ -328,12 +362,12 @@ _ksi_transmit_data() {
     while [ $pos -lt ${#data} ]; do
         builtin local chunk="${data:$pos:2048}"
         pos=$(($pos+2048))
-        builtin printf '\eP@kitty-%s|%s:%s\e\\' "${2}" "${chunk_num}" "${chunk}"
+        builtin printf '%s@kitty-%s|%s:%s%s' "${_ksi_prompt[dcs_start]}" "${2}" "${chunk_num}" "${chunk}" "${_ksi_prompt[dcs_end]}"
         chunk_num=$(($chunk_num+1))
     done
     # save history so it is available in new shell
     [ "$3" = "save_history" ] && builtin history -a
-    builtin printf '\eP@kitty-%s|\e\\' "${2}"
+    builtin printf '%s@kitty-%s|%s' "${_ksi_prompt[dcs_start]}" "${2}" "${_ksi_prompt[dcs_end]}"
 }
 
 clone-in-kitty() {
@@ -30,6 +30,14 @@ function __ksi_schedule --on-event fish_prompt -d "Setup kitty integration after
     set --local _ksi (string split " " -- "$KITTY_SHELL_INTEGRATION")
     set --erase KITTY_SHELL_INTEGRATION
 
+    # Wrap the escape codes used to communicate with kitty for terminal multiplexers
+    if contains "no-kitty-escapes" $_ksi
+        set --global __ksi_dcs_start ""
+    else if contains "tmux-passthrough" $_ksi
+        set --global __ksi_dcs_start \ePtmux\;\e\eP
+        set --global __ksi_dcs_end \e\e\\\e\\
+    end
+
     # Enable cursor shape changes for default mode and vi mode
     if not contains "no-cursor" $_ksi
         function __ksi_set_cursor --on-variable fish_key_bindings -d "Set the cursor shape for different modes when switching key bindings"
@@ -85,9 +93,16 @@ function __ksi_schedule --on-event fish_prompt -d "Setup kitty integration after
             echo -en "\e]133;C\a"
         end
 
-        function __ksi_mark_output_end --on-event fish_postexec
-            set --global __ksi_prompt_state post-exec
-            echo -en "\e]133;D;$status\a"
+        if contains "no-command-status" $_ksi
+            function __ksi_mark_output_end --on-event fish_postexec
+                set --global __ksi_prompt_state post-exec
+                echo -en "\e]133;D\a"
+            end
+        else
+            function __ksi_mark_output_end --on-event fish_postexec
+                set --global __ksi_prompt_state post-exec
+                echo -en "\e]133;D;$status;duration=$CMD_DURATION\a"
+            end
         end
 
         # With prompt marking, kitty clears the current prompt on resize,
@@ -160,17 +175,24 @@ function edit-in-kitty --wraps "kitten edit-in-kitty" -d "Edit the specified fil
     kitten edit-in-kitty $argv
 end
 
+set --global __ksi_dcs_start \eP
+set --global __ksi_dcs_end \e\\
+
 function __ksi_transmit_data -d "Transmit data to kitty using chunked DCS escapes"
+    if test -z "$__ksi_dcs_start"
+        echo "The terminal multiplexer does not pass through the escape codes needed to communicate with kitty" >&2
+        return 1
+    end
     set --local data (string replace --regex --all -- "\s" "" "$argv[1]")
     set --local data_len (string length -- "$data")
     set --local pos 1
     set --local chunk_num 0
     while test "$pos" -le $data_len
-        printf \eP@kitty-%s\|%s:%s\e\\ "$argv[2]" "$chunk_num" (string sub --start $pos --length 2048 -- "$data")
+        printf "%s@kitty-%s|%s:%s%s" "$__ksi_dcs_start" "$argv[2]" "$chunk_num" (string sub --start $pos --length 2048 -- "$data") "$__ksi_dcs_end"
         set pos (math $pos + 2048)
         set chunk_num (math $chunk_num + 1)
     end
-    printf \eP@kitty-%s\|\e\\ "$argv[2]"
+    printf "%s@kitty-%s|%s" "$__ksi_dcs_start" "$argv[2]" "$__ksi_dcs_end"
 end
 
 function clone-in-kitty -d "Clone the current fish session into a new kitty window"
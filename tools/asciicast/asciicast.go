@@ -0,0 +1,246 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+// Reading and writing terminal session recordings in the asciicast v2 format,
+// see https://docs.asciinema.org/manual/asciicast/v2/
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+const VERSION = 2
+
+const (
+	OUTPUT = "o"
+	INPUT  = "i"
+	RESIZE = "r"
+	MARKER = "m"
+)
+
+type Header struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Command       string            `json:"command,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+type Event struct {
+	// Seconds since the start of the recording
+	Time float64
+	Type string
+	Data string
+}
+
+func (self Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{self.Time, self.Type, self.Data})
+}
+
+func (self *Event) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("An event must have three fields, not %d", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &self.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(fields[1], &self.Type); err != nil {
+		return err
+	}
+	return json.Unmarshal(fields[2], &self.Data)
+}
+
+// Writes events to a recording, with times relative to when the writer was
+// created. Safe to use from multiple goroutines.
+type Writer struct {
+	mutex   sync.Mutex
+	output  *bufio.Writer
+	started time.Time
+	// bytes at the end of the last output and input that are an incomplete
+	// UTF-8 sequence, prepended to the next output and input respectively
+	pending_output, pending_input []byte
+}
+
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	h.Version = VERSION
+	ans := &Writer{output: bufio.NewWriter(w), started: time.Now()}
+	if h.Timestamp == 0 {
+		h.Timestamp = ans.started.Unix()
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	ans.output.Write(data)
+	if err = ans.output.WriteByte('\n'); err != nil {
+		return nil, err
+	}
+	return ans, ans.output.Flush()
+}
+
+func (self *Writer) write_event(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	self.output.Write(data)
+	if err = self.output.WriteByte('\n'); err != nil {
+		return err
+	}
+	return self.output.Flush()
+}
+
+// The length of the longest prefix of data that does not end with an
+// incomplete UTF-8 sequence
+func complete_utf8_prefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+func (self *Writer) WriteEvent(typ string, data []byte) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	e := Event{Time: time.Since(self.started).Seconds(), Type: typ}
+	if typ == OUTPUT || typ == INPUT {
+		pending := &self.pending_output
+		if typ == INPUT {
+			pending = &self.pending_input
+		}
+		data = append(*pending, data...)
+		n := complete_utf8_prefix(data)
+		*pending = slices.Clone(data[n:])
+		data = data[:n]
+		if len(data) == 0 {
+			return nil
+		}
+	}
+	e.Data = string(data)
+	return self.write_event(e)
+}
+
+func (self *Writer) WriteOutput(data []byte) error {
+	return self.WriteEvent(OUTPUT, data)
+}
+
+func (self *Writer) WriteResize(width, height int) error {
+	return self.WriteEvent(RESIZE, []byte(fmt.Sprintf("%dx%d", width, height)))
+}
+
+type Recording struct {
+	Header Header
+	Events []Event
+}
+
+func Read(r io.Reader) (ans *Recording, err error) {
+	scanner := bufio.NewScanner(r)
+	// output events can be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	ans = &Recording{}
+	lnum := 0
+	for scanner.Scan() {
+		lnum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if lnum == 1 {
+			if err = json.Unmarshal([]byte(line), &ans.Header); err != nil {
+				return nil, fmt.Errorf("Invalid header in the recording: %w", err)
+			}
+			if ans.Header.Version != VERSION {
+				return nil, fmt.Errorf("Unsupported asciicast version: %d only version %d is supported", ans.Header.Version, VERSION)
+			}
+			continue
+		}
+		var e Event
+		if err = json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("Invalid event on line %d of the recording: %w", lnum, err)
+		}
+		ans.Events = append(ans.Events, e)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lnum == 0 {
+		return nil, fmt.Errorf("The recording is empty")
+	}
+	slices.SortStableFunc(ans.Events, func(a, b Event) int {
+		switch {
+		case a.Time < b.Time:
+			return -1
+		case a.Time > b.Time:
+			return 1
+		}
+		return 0
+	})
+	return ans, nil
+}
+
+// Shorten pauses between events longer than limit seconds to limit
+func (self *Recording) LimitIdleTime(limit float64) {
+	if limit <= 0 {
+		return
+	}
+	prev, shift := 0., 0.
+	for i := range self.Events {
+		e := &self.Events[i]
+		if gap := e.Time - prev; gap > limit {
+			shift += gap - limit
+		}
+		prev = e.Time
+		e.Time -= shift
+	}
+}
+
+func (self *Recording) Duration() float64 {
+	if len(self.Events) == 0 {
+		return 0
+	}
+	return self.Events[len(self.Events)-1].Time
+}
+
+// The index of the first event after the time t
+func (self *Recording) IndexAfter(t float64) int {
+	idx, _ := slices.BinarySearchFunc(self.Events, t, func(e Event, t float64) int {
+		if e.Time <= t {
+			return -1
+		}
+		return 1
+	})
+	return idx
+}
+
+// The concatenated output of the events in the range [start, end)
+func (self *Recording) Output(start, end int) string {
+	ans := strings.Builder{}
+	for _, e := range self.Events[start:end] {
+		if e.Type == OUTPUT {
+			ans.WriteString(e.Data)
+		}
+	}
+	return ans.String()
+}
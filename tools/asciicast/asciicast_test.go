@@ -0,0 +1,81 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package asciicast
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestAsciicast(t *testing.T) {
+	buf := bytes.Buffer{}
+	w, err := NewWriter(&buf, Header{Width: 80, Height: 24, Title: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteOutput([]byte("abc"))
+	// a multi-byte character split across two writes
+	w.WriteOutput([]byte("x\xe2\x82"))
+	// input between the two parts does not get mixed up with the output
+	w.WriteEvent(INPUT, []byte("\xc3"))
+	w.WriteEvent(INPUT, []byte("\xa9"))
+	w.WriteOutput([]byte("\xac"))
+	w.WriteResize(100, 30)
+	rec, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Header{Version: VERSION, Width: 80, Height: 24, Title: "test", Timestamp: rec.Header.Timestamp}, rec.Header); diff != "" {
+		t.Fatalf("Header not read correctly:\n%s", diff)
+	}
+	types := []string{}
+	for _, e := range rec.Events {
+		types = append(types, e.Type)
+	}
+	if diff := cmp.Diff([]string{OUTPUT, OUTPUT, INPUT, OUTPUT, RESIZE}, types); diff != "" {
+		t.Fatalf("Events not read correctly:\n%s", diff)
+	}
+	if diff := cmp.Diff("abcx€", rec.Output(0, len(rec.Events))); diff != "" {
+		t.Fatalf("Output not read correctly:\n%s", diff)
+	}
+	if diff := cmp.Diff("é", rec.Events[2].Data); diff != "" {
+		t.Fatalf("Input not read correctly:\n%s", diff)
+	}
+	if diff := cmp.Diff("100x30", rec.Events[4].Data); diff != "" {
+		t.Fatalf("Resize not read correctly:\n%s", diff)
+	}
+
+	rec, err = Read(strings.NewReader(`{"version": 2, "width": 10, "height": 5}
+[0.5, "o", "a"]
+[10.5, "o", "b"]
+[1.5, "o", "c"]
+[12, "o", "d"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("acbd", rec.Output(0, len(rec.Events))); diff != "" {
+		t.Fatalf("Events not sorted:\n%s", diff)
+	}
+	if diff := cmp.Diff(2, rec.IndexAfter(1.5)); diff != "" {
+		t.Fatalf("IndexAfter failed:\n%s", diff)
+	}
+	rec.LimitIdleTime(2)
+	times := []float64{}
+	for _, e := range rec.Events {
+		times = append(times, e.Time)
+	}
+	if diff := cmp.Diff([]float64{0.5, 1.5, 3.5, 5}, times); diff != "" {
+		t.Fatalf("Idle time not limited correctly:\n%s", diff)
+	}
+
+	if _, err = Read(strings.NewReader(`{"version": 1}`)); err == nil {
+		t.Fatalf("No error for unsupported version")
+	}
+}
@@ -0,0 +1,132 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package asciicast
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"kitty/tools/tty"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+type RecordOptions struct {
+	Title         string
+	IdleTimeLimit float64
+	// Also record the input sent to the program
+	RecordInput bool
+}
+
+// Run cmd in a new pseudo-terminal, connected to the controlling terminal,
+// recording its output to w until it exits. Returns the exit code of cmd.
+func Record(w io.Writer, cmd []string, opts RecordOptions) (exit_code int, err error) {
+	term, err := tty.OpenControllingTerm()
+	if err != nil {
+		return 1, err
+	}
+	defer term.Close()
+	sz, err := term.GetSize()
+	if err != nil {
+		return 1, err
+	}
+	master, slave_path, err := tty.OpenPty()
+	if err != nil {
+		return 1, err
+	}
+	defer master.Close()
+	if err = tty.SetSize(int(master.Fd()), sz); err != nil {
+		return 1, err
+	}
+	slave, err := os.OpenFile(slave_path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return 1, err
+	}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = slave, slave, slave
+	// make the pseudo-terminal the controlling terminal of the child
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	h := Header{
+		Width: int(sz.Col), Height: int(sz.Row), Title: opts.Title, IdleTimeLimit: opts.IdleTimeLimit,
+		Command: strings.Join(utils.Map(utils.QuoteStringForSH, cmd), " "), Env: map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}
+	writer, err := NewWriter(w, h)
+	if err != nil {
+		return 1, err
+	}
+	if err = c.Start(); err != nil {
+		slave.Close()
+		return 1, err
+	}
+	slave.Close()
+	if err = term.ApplyOperations(tty.TCSANOW, tty.SetRaw); err != nil {
+		return 1, err
+	}
+	defer term.Restore()
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, unix.SIGWINCH)
+	defer signal.Stop(resized)
+	go func() {
+		for range resized {
+			if sz, err := term.GetSize(); err == nil && tty.SetSize(int(master.Fd()), sz) == nil {
+				writer.WriteResize(int(sz.Col), int(sz.Row))
+			}
+		}
+	}()
+	go func() {
+		buf := make([]byte, 8192)
+		for {
+			n, err := term.Read(buf)
+			if n > 0 {
+				if opts.RecordInput {
+					writer.WriteEvent(INPUT, buf[:n])
+				}
+				if _, werr := master.Write(buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	buf := make([]byte, 64*1024)
+	for {
+		n, rerr := master.Read(buf)
+		if n > 0 {
+			if err = term.WriteAll(buf[:n]); err != nil {
+				break
+			}
+			if err = writer.WriteOutput(buf[:n]); err != nil {
+				break
+			}
+		}
+		if rerr != nil {
+			// reading from the master fails with EIO once all processes
+			// using the slave have exited
+			if !errors.Is(rerr, io.EOF) && !errors.Is(rerr, unix.EIO) {
+				err = rerr
+			}
+			break
+		}
+	}
+	werr := c.Wait()
+	if err != nil {
+		return 1, err
+	}
+	var ee *exec.ExitError
+	if errors.As(werr, &ee) {
+		return ee.ExitCode(), nil
+	}
+	return 0, werr
+}
@@ -125,6 +125,35 @@ func (self *Option) parsed_value() any {
 	}
 }
 
+// The command line arguments that would result in the values specified for
+// this option on the command line, useful to forward options to another
+// program
+func (self *Option) CmdlineArgs() []string {
+	ans := make([]string, 0, len(self.values_from_cmdline))
+	long_alias := func(unset bool) string {
+		name := ""
+		for _, a := range self.Aliases {
+			if a.IsUnset == unset && (name == "" || !a.IsShort) {
+				name = a.String()
+			}
+		}
+		return name
+	}
+	for _, val := range self.values_from_cmdline {
+		switch self.OptionType {
+		case BoolOption:
+			if name := long_alias(val == "false"); name != "" {
+				ans = append(ans, name)
+			}
+		case CountOption:
+			ans = append(ans, long_alias(false))
+		default:
+			ans = append(ans, long_alias(false)+"="+val)
+		}
+	}
+	return ans
+}
+
 func (self *Option) parse_value(val string) (any, error) {
 	switch self.OptionType {
 	case BoolOption:
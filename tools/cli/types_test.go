@@ -91,7 +91,20 @@ func TestCLIParsing(t *testing.T) {
 	rt(child1, "test child1 --list -3 -p --list one", &options{FromParent: 1, List: []string{"-3", "one"}})
 	rt(gc1, "test -p child1 -p gc1 xxx", &empty_options{}, "xxx")
 
-	_, err := child1.ParseArgs(strings.Split("test child1 --choices x", " "))
+	cmd, err := child1.ParseArgs(strings.Split("test -pp child1 --set-me --list a --list b=c -s x y", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarded := []string{}
+	for _, opt := range cmd.AllOptions() {
+		forwarded = append(forwarded, opt.CmdlineArgs()...)
+	}
+	if expected := []string{"--simple-string=x", "--set-me", "--list=a", "--list=b=c", "--from-parent", "--from-parent"}; !reflect.DeepEqual(expected, forwarded) {
+		t.Fatalf("Command line args for options incorrect (expected != actual):\n%#v != %#v", expected, forwarded)
+	}
+	root.ResetAfterParseArgs()
+
+	_, err = child1.ParseArgs(strings.Split("test child1 --choices x", " "))
 	if err == nil {
 		t.Fatalf("Invalid choice not caught")
 	}
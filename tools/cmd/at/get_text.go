@@ -0,0 +1,26 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package at
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"kitty/tools/utils/style"
+)
+
+var _ = fmt.Print
+
+// kitty sends the text with ANSI formatting codes when spans are requested,
+// convert it into spans here so that there is a single parser for them
+func convert_get_text_response(text string) (string, error) {
+	if !options_get_text.Spans {
+		return text, nil
+	}
+	ans, err := json.MarshalIndent(style.ParseANSIAsJSON(text), "", "  ")
+	return string(ans), err
+}
+
+func init() {
+	response_converters["get-text"] = convert_get_text_response
+}
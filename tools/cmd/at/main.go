@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"reflect"
 	"strconv"
@@ -37,6 +38,16 @@ type GlobalOptions struct {
 	to_network, to_address, password string
 	to_address_is_from_env_var       bool
 	already_setup                    bool
+	// set when running commands from the interactive kitty shell
+	in_shell bool
+	// the uid that must own the listening UNIX socket, -1 if unchecked
+	socket_owner int
+
+	// state re-used by all commands sent by this process, such as the
+	// commands run from the kitty shell
+	encryption_session     *crypto.EncryptionSession
+	encryption_session_for string
+	conn                   net.Conn
 }
 
 var global_options GlobalOptions
@@ -80,6 +91,11 @@ func get_pubkey(encoded_key string) (encryption_version string, pubkey []byte, e
 			return
 		}
 	}
+	if strings.HasPrefix(encoded_key, "age1") || strings.HasPrefix(encoded_key, "ssh-") {
+		// the public key for the remote_control_encryption_key used by kitty
+		pubkey, err = crypto.ParsePublicKey(encoded_key)
+		return kitty.RC_ENCRYPTION_PROTOCOL_VERSION, pubkey, err
+	}
 	encryption_version, encoded_key, found := strings.Cut(encoded_key, ":")
 	if !found {
 		err = fmt.Errorf("KITTY_PUBLIC_KEY environment variable does not have a : in it")
@@ -154,6 +170,31 @@ func debug_to_log(args ...any) {
 
 var serializer serializer_func = simple_serializer
 
+// Use a single key pair for all commands sent to a kitty instance, as the
+// python client does, so that commands sent in multiple chunks and commands
+// run from the kitty shell need only a single key exchange. With
+// --use-session-cache the session is also re-used by later invocations.
+func get_encryption_session(encryption_version string, pubkey []byte) (*crypto.EncryptionSession, error) {
+	key := encryption_version + ":" + string(pubkey)
+	if global_options.encryption_session == nil || global_options.encryption_session_for != key {
+		var session *crypto.EncryptionSession
+		if rc_global_opts.UseSessionCache {
+			session = load_cached_encryption_session(key)
+		}
+		if session == nil {
+			var err error
+			if session, err = crypto.NewEncryptionSession(pubkey, encryption_version); err != nil {
+				return nil, err
+			}
+			if rc_global_opts.UseSessionCache {
+				save_cached_encryption_session(key, session)
+			}
+		}
+		global_options.encryption_session, global_options.encryption_session_for = session, key
+	}
+	return global_options.encryption_session, nil
+}
+
 func create_serializer(password string, encoded_pubkey string, io_data *rc_io_data) (err error) {
 	io_data.serializer = simple_serializer
 	if password != "" {
@@ -161,8 +202,12 @@ func create_serializer(password string, encoded_pubkey string, io_data *rc_io_da
 		if err != nil {
 			return err
 		}
+		session, err := get_encryption_session(encryption_version, pubkey)
+		if err != nil {
+			return err
+		}
 		io_data.serializer = func(rc *utils.RemoteControlCmd) (ans []byte, err error) {
-			ec, err := crypto.Encrypt_cmd(rc, global_options.password, pubkey, encryption_version)
+			ec, err := session.EncryptCmd(rc, global_options.password)
 			if err != nil {
 				return
 			}
@@ -265,10 +310,9 @@ func get_response(do_io func(io_data *rc_io_data) ([]byte, error), io_data *rc_i
 	return
 }
 
-func send_rc_command(io_data *rc_io_data) (err error) {
-	err = setup_global_options(io_data.cmd)
-	if err != nil {
-		return err
+func get_rc_response(io_data *rc_io_data) (response *Response, err error) {
+	if err = setup_global_options(io_data.cmd); err != nil {
+		return
 	}
 	wid, err := strconv.Atoi(os.Getenv("KITTY_WINDOW_ID"))
 	if err == nil && wid > 0 {
@@ -278,18 +322,14 @@ func send_rc_command(io_data *rc_io_data) (err error) {
 	if err != nil {
 		return
 	}
-	var response *Response
 	if global_options.to_network == "" {
-		response, err = get_response(do_tty_io, io_data)
-		if err != nil {
-			return
-		}
-	} else {
-		response, err = get_response(do_socket_io, io_data)
-		if err != nil {
-			return
-		}
+		return get_response(do_tty_io, io_data)
 	}
+	return get_response(do_socket_io, io_data)
+}
+
+func send_rc_command(io_data *rc_io_data) (err error) {
+	response, err := get_rc_response(io_data)
 	if err != nil || response == nil {
 		return err
 	}
@@ -302,7 +342,18 @@ func send_rc_command(io_data *rc_io_data) (err error) {
 	if response.Data.is_string && io_data.string_response_is_err {
 		return fmt.Errorf("%s", response.Data.as_str)
 	}
+	if convert := response_converters[io_data.rc.Cmd]; convert != nil && response.Data.is_string {
+		if response.Data.as_str, err = convert(response.Data.as_str); err != nil {
+			return err
+		}
+	}
 	if response.Data.as_str != "" {
+		if global_options.in_shell {
+			if pp, ok := pretty_print_json(response.Data.as_str); ok {
+				fmt.Println(pp)
+				return
+			}
+		}
 		fmt.Println(strings.TrimRight(response.Data.as_str, "\n \t"))
 	}
 	return
@@ -367,6 +418,10 @@ func register_at_cmd(f func(*cli.Command) *cli.Command) {
 	all_commands = append(all_commands, f)
 }
 
+// Functions to convert the string responses of commands, keyed by command
+// name, for commands that post-process responses on the client side
+var response_converters = map[string]func(string) (string, error){}
+
 func setup_global_options(cmd *cli.Command) (err error) {
 	if global_options.already_setup {
 		return nil
@@ -387,6 +442,12 @@ func setup_global_options(cmd *cli.Command) (err error) {
 		global_options.to_network = network
 		global_options.to_address = address
 	}
+	global_options.socket_owner = -1
+	if rc_global_opts.SocketOwner != "" {
+		if global_options.socket_owner, err = utils.LookupUid(rc_global_opts.SocketOwner); err != nil {
+			return fmt.Errorf("Unknown socket owner: %s with error: %w", rc_global_opts.SocketOwner, err)
+		}
+	}
 	q, err := get_password(rc_global_opts.Password, rc_global_opts.PasswordFile, rc_global_opts.PasswordEnv, rc_global_opts.UsePassword)
 	global_options.password = q
 	global_options.already_setup = true
@@ -408,6 +469,15 @@ func EntryPoint(tool_root *cli.Command) *cli.Command {
 
 	for _, reg_func := range all_commands {
 		c := reg_func(at_root_command)
+		run := c.Run
+		c.Run = func(cmd *cli.Command, args []string) (int, error) {
+			rc, err := run(cmd, args)
+			// like python -i, run the command and then start the shell
+			if err == nil && rc_global_opts.Interactive && !global_options.in_shell {
+				return shell_main(at_root_command, nil)
+			}
+			return rc, err
+		}
 		clone := tool_root.AddClone("", c)
 		clone.Name = "@" + c.Name
 		clone.Hidden = true
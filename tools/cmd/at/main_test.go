@@ -5,6 +5,7 @@ package at
 import (
 	"encoding/json"
 	"fmt"
+	"kitty/tools/cli/markup"
 	"kitty/tools/crypto"
 	"kitty/tools/utils"
 	"testing"
@@ -102,3 +103,15 @@ func TestRCSerialization(t *testing.T) {
 		t.Fatal("Incorrect version in encrypted command: ", ec.Version)
 	}
 }
+
+func TestPrettyPrintJSON(t *testing.T) {
+	formatter = markup.New(false)
+	actual, ok := pretty_print_json(`{"b": [1, {"x": null}, []], "a": "q"}`)
+	expected := "{\n  \"b\": [\n    1,\n    {\n      \"x\": null\n    },\n    []\n  ],\n  \"a\": \"q\"\n}"
+	if !ok || actual != expected {
+		t.Fatalf("Incorrect pretty printing:\n%#v != %#v", expected, actual)
+	}
+	if _, ok = pretty_print_json("not json"); ok {
+		t.Fatal("Pretty printed non-JSON data")
+	}
+}
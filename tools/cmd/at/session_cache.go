@@ -0,0 +1,87 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package at
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"kitty/tools/crypto"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Cached sessions are used for at most this long, so that the secret shared
+// with kitty is not left on disk indefinitely
+const max_session_cache_age = 24 * time.Hour
+
+func session_cache_dir() string {
+	return filepath.Join(utils.CacheDir(), "rc-sessions")
+}
+
+func session_cache_path(key string) string {
+	h := sha256.Sum256(utils.UnsafeStringToBytes(key))
+	return filepath.Join(session_cache_dir(), hex.EncodeToString(h[:16]))
+}
+
+// Only trust cache files that are private to the current user, since anyone
+// that can read or replace them can impersonate us to kitty
+func is_private_file(s os.FileInfo) bool {
+	if !s.Mode().IsRegular() || s.Mode().Perm()&0o077 != 0 {
+		return false
+	}
+	st, ok := s.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Geteuid()
+}
+
+func load_cached_encryption_session(key string) *crypto.EncryptionSession {
+	path := session_cache_path(key)
+	s, err := os.Lstat(path)
+	if err != nil || !is_private_file(s) || time.Since(s.ModTime()) > max_session_cache_age {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	session, err := crypto.DeserializeEncryptionSession(data)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+func prune_session_cache(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if s, err := e.Info(); err == nil && time.Since(s.ModTime()) > max_session_cache_age {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// Failures are ignored as the cache is only an optimization
+func save_cached_encryption_session(key string, session *crypto.EncryptionSession) {
+	data, err := session.Serialize()
+	if err != nil {
+		return
+	}
+	dir := session_cache_dir()
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return
+	}
+	if s, err := os.Lstat(dir); err != nil || !s.IsDir() || s.Mode().Perm()&0o077 != 0 {
+		return
+	}
+	prune_session_cache(dir)
+	_ = utils.AtomicWriteFile(session_cache_path(key), data, 0o600)
+}
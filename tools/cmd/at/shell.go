@@ -3,11 +3,15 @@
 package at
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +31,8 @@ const prompt = "🐱 "
 
 var ErrExec = errors.New("Execute command")
 
+var watch_prefix = regexp.MustCompile(`^\s*watch\s+(?:(?:-n|--interval)(?:\s+|=)\S+\s+)?`)
+
 func shell_loop(rl *readline.Readline, kill_if_signaled bool) (int, error) {
 	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors)
 	if err != nil {
@@ -111,6 +117,8 @@ func show_basic_help() {
 	}
 	fmt.Fprintln(&output, " ", formatter.Green("help"))
 	fmt.Fprintln(&output, "   ", "Show this help")
+	fmt.Fprintln(&output, " ", formatter.Green("watch"))
+	fmt.Fprintln(&output, "   ", "Repeat a command periodically, for example: watch -n 5 ls")
 	fmt.Fprintln(&output, " ", formatter.Green("exit"))
 	fmt.Fprintln(&output, "   ", "Exit this shell")
 	cli.ShowHelpInPager(output.String())
@@ -144,6 +152,9 @@ func exec_command(at_root_command *cli.Command, rl *readline.Readline, cmdline s
 			fmt.Println("Exit this shell")
 		case "help":
 			fmt.Println("Show help")
+		case "watch":
+			fmt.Println("Usage: watch [-n seconds] command [args ...]")
+			fmt.Println("Run the command every two seconds, or the specified number of seconds, until Ctrl+C is pressed")
 		default:
 			sc := at_root_command.FindSubCommand(parsed_cmdline[1])
 			if sc == nil {
@@ -154,26 +165,265 @@ func exec_command(at_root_command *cli.Command, rl *readline.Readline, cmdline s
 			}
 		}
 		return true
+	case "watch":
+		hi.ExitCode = watch_command(at_root_command, parsed_cmdline[1:])
+		hi.Duration = time.Now().Sub(hi.Timestamp)
+		rl.AddHistoryItem(hi)
 	default:
 		if at_root_command.FindSubCommand(parsed_cmdline[0]) == nil {
 			hi.ExitCode = 1
 			fmt.Fprintln(os.Stderr, "No command named", formatter.BrightRed(parsed_cmdline[0])+". Type help for a list of commands")
 			return true
 		}
-		cmdline := []string{"kitten", "@"}
-		cmdline = append(cmdline, parsed_cmdline...)
-		root := cli.NewRootCommand()
-		EntryPoint(root)
-		hi.ExitCode = root.ExecArgs(cmdline)
+		hi.ExitCode = run_at_command(parsed_cmdline)
 		hi.Duration = time.Now().Sub(hi.Timestamp)
 		rl.AddHistoryItem(hi)
 	}
 	return true
 }
 
+func run_at_command(args []string) int {
+	cmdline := []string{"kitten", "@"}
+	cmdline = append(cmdline, args...)
+	root := cli.NewRootCommand()
+	EntryPoint(root)
+	// the command may have changed the windows
+	window_list.stale = true
+	return root.ExecArgs(cmdline)
+}
+
+func parse_watch_cmdline(args []string) (interval time.Duration, cmdline []string, err error) {
+	interval = 2 * time.Second
+	val := ""
+	if len(args) > 0 {
+		switch {
+		case args[0] == "-n" || args[0] == "--interval":
+			if len(args) < 2 {
+				return 0, nil, fmt.Errorf("No interval specified for watch")
+			}
+			val, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--interval="):
+			val, args = args[0][len("--interval="):], args[1:]
+		}
+	}
+	if val != "" {
+		secs, err := strconv.ParseFloat(val, 64)
+		if err != nil || secs <= 0 {
+			return 0, nil, fmt.Errorf("Invalid interval for watch: %s", val)
+		}
+		interval = time.Duration(secs * float64(time.Second))
+	}
+	if len(args) == 0 {
+		return 0, nil, fmt.Errorf("No command specified for watch")
+	}
+	return interval, args, nil
+}
+
+// Run a command repeatedly in the alternate screen until interrupted,
+// returning the exit code of the last run
+func watch_command(at_root_command *cli.Command, args []string) (exit_code int) {
+	interval, args, err := parse_watch_cmdline(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if at_root_command.FindSubCommand(args[0]) == nil {
+		fmt.Fprintln(os.Stderr, "No command named", formatter.BrightRed(args[0])+". Type help for a list of commands")
+		return 1
+	}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+	os.Stdout.WriteString("\x1b[?1049h")
+	defer os.Stdout.WriteString("\x1b[?1049l")
+	header := formatter.Dim(fmt.Sprintf("Every %s: %s (press Ctrl+C to stop)", interval, strings.Join(utils.Map(utils.QuoteStringForSH, args), " ")))
+	for {
+		os.Stdout.WriteString("\x1b[H\x1b[2J")
+		fmt.Println(header)
+		fmt.Println()
+		exit_code = run_at_command(args)
+		select {
+		case <-interrupted:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+type shell_window struct {
+	id    int
+	title string
+}
+
+// The windows and tabs in kitty, used for completion of ids
+var window_list struct {
+	windows, tabs []shell_window
+	stale, failed bool
+}
+
+func refresh_window_list(cmd *cli.Command) {
+	if !window_list.stale || window_list.failed {
+		return
+	}
+	rc, err := create_rc_ls(nil)
+	if err != nil {
+		return
+	}
+	io_data := rc_io_data{cmd: cmd, rc: rc, timeout: 5 * time.Second}
+	response, err := get_rc_response(&io_data)
+	if err != nil || response == nil || !response.Ok {
+		// dont keep trying, for example, if remote control is not allowed
+		window_list.failed = true
+		return
+	}
+	type entry struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	}
+	var os_windows []struct {
+		Tabs []struct {
+			entry
+			Windows []entry `json:"windows"`
+		} `json:"tabs"`
+	}
+	if err = json.Unmarshal(utils.UnsafeStringToBytes(response.Data.as_str), &os_windows); err != nil {
+		window_list.failed = true
+		return
+	}
+	window_list.windows, window_list.tabs = nil, nil
+	for _, osw := range os_windows {
+		for _, t := range osw.Tabs {
+			window_list.tabs = append(window_list.tabs, shell_window{t.Id, t.Title})
+			for _, w := range t.Windows {
+				window_list.windows = append(window_list.windows, shell_window{w.Id, w.Title})
+			}
+		}
+	}
+	window_list.stale = false
+}
+
+// Complete id:N for the values of the --match and --match-tab options
+func complete_ids(ans *cli.Completions, argv []string) {
+	if len(argv) < 2 {
+		return
+	}
+	word, prev := argv[len(argv)-1], argv[len(argv)-2]
+	if !strings.HasPrefix("id:", word) && !strings.HasPrefix(word, "id:") {
+		return
+	}
+	var items []shell_window
+	title := ""
+	switch prev {
+	case "--match", "-m":
+		items, title = window_list.windows, "Windows"
+	case "--match-tab", "-t":
+		items, title = window_list.tabs, "Tabs"
+	default:
+		return
+	}
+	mg := ans.AddMatchGroup(title)
+	for _, w := range items {
+		if q := "id:" + strconv.Itoa(w.id); strings.HasPrefix(q, word) {
+			mg.AddMatch(q, w.title)
+		}
+	}
+}
+
+func json_token_as_string(tok json.Token) string {
+	switch v := tok.(type) {
+	case string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(tok)
+}
+
+// Indent and colorize data if it is a JSON object or array, preserving the
+// order of keys
+func pretty_print_json(data string) (string, bool) {
+	data = strings.TrimSpace(data)
+	if data == "" || (data[0] != '{' && data[0] != '[') || !json.Valid(utils.UnsafeStringToBytes(data)) {
+		return "", false
+	}
+	type container struct {
+		is_object, expecting_key bool
+		count                    int
+	}
+	stack := []container{}
+	ans := strings.Builder{}
+	newline := func() {
+		ans.WriteByte('\n')
+		ans.WriteString(strings.Repeat("  ", len(stack)))
+	}
+	before_value := func() {
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.is_object && !top.expecting_key {
+				return
+			}
+			if top.count > 0 {
+				ans.WriteByte(',')
+			}
+			top.count++
+			newline()
+		}
+	}
+	after_value := func() {
+		if len(stack) > 0 && stack[len(stack)-1].is_object {
+			stack[len(stack)-1].expecting_key = !stack[len(stack)-1].expecting_key
+		}
+	}
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			switch v {
+			case '{', '[':
+				before_value()
+				ans.WriteRune(rune(v))
+				stack = append(stack, container{is_object: v == '{', expecting_key: v == '{'})
+			default:
+				had_items := stack[len(stack)-1].count > 0
+				stack = stack[:len(stack)-1]
+				if had_items {
+					newline()
+				}
+				ans.WriteRune(rune(v))
+				after_value()
+			}
+		case string:
+			before_value()
+			if len(stack) > 0 && stack[len(stack)-1].is_object && stack[len(stack)-1].expecting_key {
+				ans.WriteString(formatter.Cyan(json_token_as_string(v)) + ": ")
+			} else {
+				ans.WriteString(formatter.Green(json_token_as_string(v)))
+			}
+			after_value()
+		default:
+			before_value()
+			ans.WriteString(formatter.Yellow(json_token_as_string(v)))
+			after_value()
+		}
+	}
+	return ans.String(), true
+}
+
 func completions(before_cursor, after_cursor string) (ans *cli.Completions) {
 	const prefix = "kitten @ "
-	text := prefix + before_cursor
+	// complete the watched command
+	watch_prefix_len := len(watch_prefix.FindString(before_cursor))
+	text := prefix + before_cursor[watch_prefix_len:]
 	argv, position_of_last_arg := shlex.SplitForCompletion(text)
 	if len(argv) == 0 || position_of_last_arg < len(prefix) {
 		return
@@ -192,9 +442,11 @@ func completions(before_cursor, after_cursor string) (ans *cli.Completions) {
 	}
 	add_sc("help", "Show help")
 	add_sc("exit", "Exit the kitty shell")
+	add_sc("watch", "Repeat a command periodically")
 	root.Validate()
 	ans = root.GetCompletions(argv, nil)
-	ans.CurrentWordIdx = position_of_last_arg - len(prefix)
+	complete_ids(ans, argv)
+	ans.CurrentWordIdx = position_of_last_arg - len(prefix) + watch_prefix_len
 	return
 }
 
@@ -203,6 +455,8 @@ func shell_main(cmd *cli.Command, args []string) (int, error) {
 	if err != nil {
 		return 1, err
 	}
+	global_options.in_shell = true
+	window_list.stale = true
 	formatter = markup.New(true)
 	fmt.Println("Welcome to the kitty shell!")
 	fmt.Println("Use", formatter.Green("help"), "for assistance or", formatter.Green("exit"), "to quit.")
@@ -225,11 +479,12 @@ func shell_main(cmd *cli.Command, args []string) (int, error) {
 		return ans
 	}
 
-	rl = readline.New(nil, readline.RlInit{Prompt: prompt, Completer: combined_completer, HistoryPath: filepath.Join(utils.CacheDir(), "shell.history.json")})
+	rl = readline.New(nil, readline.RlInit{Prompt: prompt, Completer: combined_completer, HistoryPath: filepath.Join(utils.CacheDir(), "shell.history.json"), HistoryExpansion: true})
 	defer func() {
 		rl.Shutdown()
 	}()
 	for {
+		refresh_window_list(cmd)
 		rc, err := shell_loop(rl, true)
 		if err != nil {
 			if err == ErrExec {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"time"
 
 	"kitty/tools/tui/loop"
@@ -150,11 +151,63 @@ func simple_socket_io(conn *net.Conn, io_data *rc_io_data) (serialized_response
 	return read_response_from_conn(conn, io_data.timeout)
 }
 
-func do_socket_io(io_data *rc_io_data) (serialized_response []byte, err error) {
-	conn, err := net.Dial(global_options.to_network, global_options.to_address)
+// Return the connection left open by a previous command, if kitty has not
+// closed it in the meantime
+func cached_conn() net.Conn {
+	conn := global_options.conn
+	global_options.conn = nil
+	if conn == nil {
+		return nil
+	}
+	var buf [1]byte
+	conn.SetReadDeadline(time.Now())
+	if _, err := conn.Read(buf[:]); !errors.Is(err, os.ErrDeadlineExceeded) {
+		// either kitty closed the connection or sent unexpected data
+		conn.Close()
+		return nil
+	}
+	return conn
+}
+
+func check_socket_owner(conn net.Conn) error {
+	if global_options.socket_owner < 0 {
+		return nil
+	}
+	if global_options.to_network != "unix" {
+		return fmt.Errorf("The socket owner can only be verified when connecting to kitty over a UNIX socket")
+	}
+	creds, err := utils.PeerCredentialsOf(conn)
 	if err != nil {
-		return
+		return fmt.Errorf("Could not verify the owner of the socket %s with error: %w", global_options.to_address, err)
+	}
+	if creds.Uid != global_options.socket_owner {
+		return fmt.Errorf("The socket %s is owned by the user %d not by the user %d, refusing to connect", global_options.to_address, creds.Uid, global_options.socket_owner)
 	}
-	defer conn.Close()
-	return simple_socket_io(&conn, io_data)
+	return nil
+}
+
+func do_socket_io(io_data *rc_io_data) (serialized_response []byte, err error) {
+	conn := cached_conn()
+	if conn == nil {
+		if conn, err = net.Dial(global_options.to_network, global_options.to_address); err != nil {
+			return
+		}
+		if err = check_socket_owner(conn); err != nil {
+			conn.Close()
+			return
+		}
+	}
+	reusable := false
+	defer func() {
+		if reusable {
+			global_options.conn = conn
+		} else {
+			conn.Close()
+		}
+	}()
+	serialized_response, err = simple_socket_io(&conn, io_data)
+	// commands that read from stdin or stream data leave the connection in an
+	// unknown state
+	reusable = err == nil && io_data.on_key_event == nil && !io_data.rc.Stream
+	return
 }
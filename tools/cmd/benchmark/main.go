@@ -0,0 +1,362 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package benchmark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"kitty/tools/cli"
+	"kitty/tools/tty"
+	"kitty/tools/tui/graphics"
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils/humanize"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type Options struct {
+	DataSize       int
+	LatencySamples int
+	Render         bool
+	Tty            string
+}
+
+const reset_screen = "\x1b[m\x1b[H\x1b[2J"
+
+// How long to wait for the terminal to respond to a query before giving up
+const response_timeout = 2 * time.Minute
+
+type result struct {
+	desc      string
+	data_size int
+	duration  time.Duration
+}
+
+type latency_result struct {
+	min, max, mean time.Duration
+	samples        int
+}
+
+type benchmark struct {
+	opts   *Options
+	term   *tty.Term
+	rng    *rand.Rand
+	buf    []byte
+	inbuf  []byte
+	report []result
+}
+
+// Wait for the response to a DA1 query, which terminals send only after
+// processing all data written before the query
+func (self *benchmark) wait_for_primary_device_attributes() error {
+	self.inbuf = self.inbuf[:0]
+	deadline := time.Now().Add(response_timeout)
+	for {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return fmt.Errorf("Timed out waiting for the terminal to respond to a device attributes query")
+		}
+		n, err := self.term.ReadWithTimeout(self.buf, timeout)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return err
+		}
+		self.inbuf = append(self.inbuf, self.buf[:n]...)
+		if idx := bytes.Index(self.inbuf, []byte("\x1b[?")); idx > -1 && bytes.IndexByte(self.inbuf[idx:], 'c') > -1 {
+			return nil
+		}
+	}
+}
+
+func (self *benchmark) write_and_wait(data string) (time.Duration, error) {
+	start := time.Now()
+	if err := self.term.WriteAllString(data + "\x1b[c"); err != nil {
+		return 0, err
+	}
+	if err := self.wait_for_primary_device_attributes(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// Send chunk repeatedly till at least opts.DataSize bytes have been written,
+// timing how long the terminal takes to process it all
+func (self *benchmark) run(desc string, chunk string) (err error) {
+	if _, err = self.write_and_wait(reset_screen + "Running: " + desc + "\r\n"); err != nil {
+		return
+	}
+	reps := max(1, self.opts.DataSize*1024*1024/len(chunk))
+	data := strings.Repeat(chunk, reps)
+	if !self.opts.Render {
+		// synchronized output prevents the terminal from rendering, so that
+		// only parsing and layout are measured
+		data = "\x1b[?2026h" + data + "\x1b[?2026l"
+	}
+	duration, err := self.write_and_wait(data)
+	if err != nil {
+		return
+	}
+	self.report = append(self.report, result{desc: desc, data_size: reps * len(chunk), duration: duration})
+	return
+}
+
+func (self *benchmark) random_choice(items []rune) rune {
+	return items[self.rng.Intn(len(items))]
+}
+
+func (self *benchmark) ascii_printable(buf *strings.Builder, size int) {
+	for i := 0; i < size; i++ {
+		buf.WriteByte(byte(' ' + self.rng.Intn('~'-' '+1)))
+	}
+}
+
+func (self *benchmark) plain_text() string {
+	buf := strings.Builder{}
+	for buf.Len() < 1024*1024 {
+		self.ascii_printable(&buf, self.rng.Intn(160))
+		buf.WriteString("\r\n")
+	}
+	return buf.String()
+}
+
+func (self *benchmark) sgr_text() string {
+	buf := strings.Builder{}
+	color := func(base int) {
+		switch self.rng.Intn(3) {
+		case 0:
+			fmt.Fprintf(&buf, "%d", base+self.rng.Intn(8))
+		case 1:
+			fmt.Fprintf(&buf, "%d:5:%d", base+8, self.rng.Intn(256))
+		default:
+			fmt.Fprintf(&buf, "%d:2:%d:%d:%d", base+8, self.rng.Intn(256), self.rng.Intn(256), self.rng.Intn(256))
+		}
+	}
+	attrs := []string{"1", "2", "3", "4", "4:3", "5", "7", "9", "53"}
+	for buf.Len() < 1024*1024 {
+		buf.WriteString("\x1b[")
+		color(30)
+		buf.WriteByte(';')
+		color(40)
+		for i := self.rng.Intn(3); i > 0; i-- {
+			buf.WriteByte(';')
+			buf.WriteString(attrs[self.rng.Intn(len(attrs))])
+		}
+		buf.WriteByte('m')
+		self.ascii_printable(&buf, 1+self.rng.Intn(12))
+		if self.rng.Intn(8) == 0 {
+			buf.WriteString("\x1b[m\r\n")
+		}
+	}
+	buf.WriteString("\x1b[m")
+	return buf.String()
+}
+
+func unicode_range(first, last rune) (ans []rune) {
+	ans = make([]rune, 0, last-first+1)
+	for ch := first; ch <= last; ch++ {
+		ans = append(ans, ch)
+	}
+	return
+}
+
+func (self *benchmark) unicode_text() string {
+	latin := unicode_range(0xc0, 0x24f)
+	combining := unicode_range(0x300, 0x36f)
+	box_drawing := unicode_range(0x2500, 0x257f)
+	cjk := unicode_range(0x4e00, 0x4fff)
+	emoji := unicode_range(0x1f600, 0x1f64f)
+	buf := strings.Builder{}
+	for buf.Len() < 1024*1024 {
+		for i := self.rng.Intn(80); i > 0; i-- {
+			switch self.rng.Intn(8) {
+			case 0, 1, 2:
+				buf.WriteRune(self.random_choice(latin))
+			case 3:
+				buf.WriteRune(self.random_choice(latin))
+				buf.WriteRune(self.random_choice(combining))
+			case 4:
+				buf.WriteRune(self.random_choice(box_drawing))
+			case 5, 6:
+				buf.WriteRune(self.random_choice(cjk))
+			default:
+				buf.WriteRune(self.random_choice(emoji))
+			}
+		}
+		buf.WriteString("\r\n")
+	}
+	return buf.String()
+}
+
+func (self *benchmark) images() string {
+	const width, height = 512, 512
+	pixels := make([]byte, width*height*4)
+	self.rng.Read(pixels)
+	buf := strings.Builder{}
+	// random pixel data is incompressible so no compression is used
+	gc := &graphics.GraphicsCommand{}
+	gc.SetAction(graphics.GRT_action_transmit).SetFormat(graphics.GRT_format_rgba).SetQuiet(graphics.GRT_quiet_silent)
+	gc.SetDataWidth(width).SetDataHeight(height).SetImageId(1)
+	gc.WriteWithPayloadTo(&buf, pixels)
+	gc = &graphics.GraphicsCommand{}
+	gc.SetAction(graphics.GRT_action_delete).SetDelete(graphics.GRT_free_by_id).SetQuiet(graphics.GRT_quiet_silent).SetImageId(1)
+	gc.WriteWithPayloadTo(&buf, nil)
+	return buf.String()
+}
+
+// Measure the time from sending a query to receiving its response, which
+// includes the time the terminal takes to generate input for the program
+// running in it
+func (self *benchmark) latency() (ans latency_result, err error) {
+	if _, err = self.write_and_wait(reset_screen + "Running: Input latency\r\n"); err != nil {
+		return
+	}
+	var total time.Duration
+	for ans.samples < self.opts.LatencySamples {
+		d, err := self.write_and_wait("")
+		if err != nil {
+			return ans, err
+		}
+		if ans.samples == 0 || d < ans.min {
+			ans.min = d
+		}
+		ans.max = max(ans.max, d)
+		total += d
+		ans.samples++
+	}
+	if ans.samples > 0 {
+		ans.mean = total / time.Duration(ans.samples)
+	}
+	return
+}
+
+var all_benchmarks = []string{"ascii", "sgr", "unicode", "images", "latency"}
+
+func format_throughput(r result) string {
+	return humanize.Bytes(uint64(float64(r.data_size)/r.duration.Seconds())) + "/s"
+}
+
+func print_report(report []result, lr *latency_result) {
+	caps := loop.TerminalCapabilitiesFromEnvironment()
+	term := caps.Terminal.String()
+	if caps.Version != "" {
+		term += " " + caps.Version
+	}
+	fmt.Println("Terminal:", term)
+	if len(report) > 0 {
+		fmt.Println()
+		fmt.Printf("%-24s %12s %12s %14s\n", "Benchmark", "Data", "Time", "Throughput")
+		for _, r := range report {
+			fmt.Printf("%-24s %12s %12s %14s\n", r.desc, humanize.Bytes(uint64(r.data_size)), r.duration.Round(time.Millisecond), format_throughput(r))
+		}
+	}
+	if lr != nil {
+		fmt.Println()
+		fmt.Printf("Input latency over %d samples: min: %s mean: %s max: %s\n", lr.samples, lr.min.Round(time.Microsecond), lr.mean.Round(time.Microsecond), lr.max.Round(time.Microsecond))
+	}
+}
+
+func main(args []string, opts *Options) (rc int, err error) {
+	if len(args) == 0 {
+		args = all_benchmarks
+	}
+	for _, x := range args {
+		if !slices.Contains(all_benchmarks, x) {
+			return 1, fmt.Errorf("Unknown benchmark: %s. Known benchmarks: %s", x, strings.Join(all_benchmarks, ", "))
+		}
+	}
+	if opts.DataSize < 1 {
+		return 1, fmt.Errorf("The data size must be at least one MB")
+	}
+	term, err := tty.OpenDevice(opts.Tty, tty.SetRaw)
+	if err != nil {
+		return 1, err
+	}
+	b := benchmark{opts: opts, term: term, rng: rand.New(rand.NewSource(0)), buf: make([]byte, 4096)}
+	var lr *latency_result
+	err = func() (err error) {
+		defer func() {
+			_ = term.WriteAllString(reset_screen + "\x1b[?1049l")
+			term.RestoreAndClose()
+		}()
+		if err = term.WriteAllString("\x1b[?1049h"); err != nil {
+			return
+		}
+		for _, x := range args {
+			switch x {
+			case "ascii":
+				err = b.run("Plain text", b.plain_text())
+			case "sgr":
+				err = b.run("SGR heavy text", b.sgr_text())
+			case "unicode":
+				err = b.run("Unicode heavy text", b.unicode_text())
+			case "images":
+				err = b.run("Images", b.images())
+			case "latency":
+				var r latency_result
+				if r, err = b.latency(); err == nil {
+					lr = &r
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+		return
+	}()
+	if err != nil {
+		return 1, err
+	}
+	print_report(b.report, lr)
+	return
+}
+
+func EntryPoint(root *cli.Command) *cli.Command {
+	sc := root.AddSubCommand(&cli.Command{
+		Name:             "benchmark",
+		Usage:            "[options] [benchmarks ...]",
+		ShortDescription: "Benchmark the performance of the terminal",
+		HelpText: "Measure how fast the terminal this kitten is running in processes plain text, text with lots of formatting (SGR escape codes), text with lots of non-ASCII characters and images sent via the graphics protocol, and the round-trip latency for input generated by the terminal, printing a report that can be used to compare terminals or track performance regressions. " +
+			"Individual benchmarks can be selected by name, one of: " + strings.Join(all_benchmarks, ", ") + ". By default, all are run. " +
+			"It is best to run this in a window of a fixed size with nothing else running, as the results depend on the size of the window and load on the system.",
+		Run: func(cmd *cli.Command, args []string) (ret int, err error) {
+			opts := &Options{}
+			err = cmd.GetOptionValues(opts)
+			if err != nil {
+				return 1, err
+			}
+			return main(args, opts)
+		},
+	})
+	sc.Add(cli.OptionSpec{
+		Name:    "--data-size",
+		Default: "32",
+		Type:    "int",
+		Help:    "The amount of data (in MB) to send for each of the throughput benchmarks.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name:    "--latency-samples",
+		Default: "100",
+		Type:    "int",
+		Help:    "The number of round trips over which to measure the input latency.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--render",
+		Type: "bool-set",
+		Help: "Allow the terminal to render the data while it is being received. By default, the synchronized output mode is used to prevent rendering, so that only the speed of parsing the data is measured, in terminals that support that mode.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--tty",
+		Help: "The terminal device to benchmark instead of the terminal the kitten is running in, such as another pseudo-terminal or a serial port, as :code:`path[:baud_rate]`, for example: :code:`/dev/ttyUSB0:115200`.",
+	})
+	return sc
+}
@@ -0,0 +1,176 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package edit_in_kitty
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Handles saves from the editor, detecting when the file being edited has
+// been changed on disk by something else since it was read, so that those
+// changes are not silently overwritten.
+type save_handler struct {
+	lp   *loop.Loop
+	path string
+	perm fs.FileMode
+	// the editor content the next save is based on
+	base []byte
+	// the hash of the file contents as last read from or written to disk
+	disk_hash [sha256.Size]byte
+	// once the user has chosen to merge, all subsequent saves are merged
+	merging bool
+	// a save waiting for the user to decide how to resolve a conflict
+	pending         []byte
+	pending_disk    []byte
+	quit_on_resolve bool
+}
+
+func new_save_handler(path string, perm fs.FileMode, original []byte) *save_handler {
+	return &save_handler{path: path, perm: perm, base: original, disk_hash: sha256.Sum256(original)}
+}
+
+func (self *save_handler) print(format string, args ...any) {
+	self.lp.QueueWriteString(strings.ReplaceAll(fmt.Sprintf(format, args...), "\n", "\r\n"))
+}
+
+func (self *save_handler) write(data []byte) error {
+	if err := utils.AtomicWriteFile(self.path, data, self.perm); err != nil {
+		return fmt.Errorf("Failed to write data to %s with error: %w", self.path, err)
+	}
+	self.disk_hash = sha256.Sum256(data)
+	return nil
+}
+
+func (self *save_handler) on_data(data_type string, data []byte) error {
+	current, err := os.ReadFile(self.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("Failed to read %s with error: %w", self.path, err)
+		}
+		current = nil
+	}
+	if bytes.Equal(current, data) {
+		// the editor is editing the file in place
+		self.base, self.disk_hash = data, sha256.Sum256(data)
+		return nil
+	}
+	if self.merging {
+		return self.merge(data, current)
+	}
+	if sha256.Sum256(current) == self.disk_hash {
+		self.base = data
+		return self.write(data)
+	}
+	if self.pending == nil {
+		self.print("\n%s has been changed by some other program since it was opened for editing.\n"+
+			"[o]verwrite the changes, [m]erge them with your edits or [s]kip this save? ", self.path)
+	}
+	self.pending, self.pending_disk = data, current
+	return nil
+}
+
+// Returns true if the loop can quit now that editing is done
+func (self *save_handler) on_done() bool {
+	if self.pending != nil {
+		self.quit_on_resolve = true
+		return false
+	}
+	return true
+}
+
+func (self *save_handler) on_key(ev *loop.KeyEvent) (err error) {
+	if self.pending == nil {
+		return nil
+	}
+	data, current := self.pending, self.pending_disk
+	switch {
+	case ev.MatchesPressOrRepeat("o"):
+		self.print("overwrite\n")
+		self.base = data
+		err = self.write(data)
+	case ev.MatchesPressOrRepeat("m"):
+		self.print("merge\n")
+		self.merging = true
+		err = self.merge(data, current)
+	case ev.MatchesPressOrRepeat("s"):
+		self.print("skip\n")
+	default:
+		return nil
+	}
+	ev.Handled = true
+	self.pending, self.pending_disk = nil, nil
+	if self.quit_on_resolve {
+		self.lp.Quit(0)
+	}
+	return
+}
+
+func (self *save_handler) merge(edited, current []byte) error {
+	merged, conflicts, err := merge3(self.base, edited, current)
+	if err != nil {
+		return err
+	}
+	self.base = edited
+	if err = self.write(merged); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		self.print("\nMerging changes into %s resulted in %d conflicts, look for the conflict markers in the file to fix them.\n", self.path, conflicts)
+	}
+	return nil
+}
+
+// Perform a three way merge of the changes from base to edited and base to
+// current using either git or diff3. Returns the merged data and the number of
+// conflicts, if known.
+func merge3(base, edited, current []byte) (merged []byte, conflicts int, err error) {
+	tdir, err := os.MkdirTemp("", "edit-in-kitty-merge-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tdir)
+	paths := make([]string, 3)
+	for i, x := range [][]byte{edited, base, current} {
+		paths[i] = filepath.Join(tdir, fmt.Sprint(i))
+		if err = os.WriteFile(paths[i], x, 0o600); err != nil {
+			return
+		}
+	}
+	labels := []string{"-L", "your edits", "-L", "original", "-L", "on disk"}
+	var cmd *exec.Cmd
+	is_git := false
+	if git := utils.FindExe("git"); filepath.IsAbs(git) {
+		cmd, is_git = exec.Command(git, append(append([]string{"merge-file", "-p"}, labels...), paths...)...), true
+	} else if diff3 := utils.FindExe("diff3"); filepath.IsAbs(diff3) {
+		cmd = exec.Command(diff3, append(append([]string{"-m"}, labels...), paths...)...)
+	} else {
+		return nil, 0, fmt.Errorf("Merging requires either git or diff3 to be installed")
+	}
+	merged, err = cmd.Output()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		// git exits with the number of conflicts and diff3 with 1 if there are conflicts
+		if code := ee.ExitCode(); is_git && code > 0 && code < 128 {
+			conflicts, err = code, nil
+		} else if !is_git && code == 1 {
+			conflicts, err = bytes.Count(append([]byte{'\n'}, merged...), []byte("\n<<<<<<< ")), nil
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to merge changes with error: %w", err)
+	}
+	return
+}
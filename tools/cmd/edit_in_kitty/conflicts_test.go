@@ -0,0 +1,36 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package edit_in_kitty
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"kitty/tools/utils"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestMerge3(t *testing.T) {
+	if !filepath.IsAbs(utils.FindExe("git")) && !filepath.IsAbs(utils.FindExe("diff3")) {
+		t.Skip("Skipping as neither git nor diff3 are available")
+	}
+	base := "1\n2\n3\n4\n5\n"
+	merged, conflicts, err := merge3([]byte(base), []byte("one\n2\n3\n4\n5\n"), []byte("1\n2\n3\n4\nfive\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("one\n2\n3\n4\nfive\n", string(merged)); diff != "" || conflicts != 0 {
+		t.Fatalf("Unexpected merge result with %d conflicts:\n%s", conflicts, diff)
+	}
+	_, conflicts, err = merge3([]byte(base), []byte("one\n2\n3\n4\n5\n"), []byte("uno\n2\n3\n4\n5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflicts != 1 {
+		t.Fatalf("Unexpected number of conflicts: %d", conflicts)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -26,13 +27,12 @@ func encode(x string) string {
 	return base64.StdEncoding.EncodeToString(utils.UnsafeStringToBytes(x))
 }
 
-type OnDataCallback = func(data_type string, data []byte) error
-
-func edit_loop(data_to_send string, kill_if_signaled bool, on_data OnDataCallback) (err error) {
+func edit_loop(data_to_send string, kill_if_signaled bool, saver *save_handler) (err error) {
 	lp, err := loop.New(loop.NoAlternateScreen, loop.NoRestoreColors, loop.NoMouseTracking)
 	if err != nil {
 		return
 	}
+	saver.lp = lp
 	current_text := strings.Builder{}
 	data := strings.Builder{}
 	data.Grow(4096)
@@ -51,7 +51,9 @@ func edit_loop(data_to_send string, kill_if_signaled bool, on_data OnDataCallbac
 				if line == "KITTY_DATA_END" {
 					lp.QueueWriteString(update_type + "\r\n")
 					if update_type == "DONE" {
-						lp.Quit(0)
+						if saver.on_done() {
+							lp.Quit(0)
+						}
 						return nil
 					}
 					b, err := base64.StdEncoding.DecodeString(data.String())
@@ -59,7 +61,7 @@ func edit_loop(data_to_send string, kill_if_signaled bool, on_data OnDataCallbac
 					data.Grow(4096)
 					started = false
 					if err == nil {
-						err = on_data(update_type, b)
+						err = saver.on_data(update_type, b)
 					}
 					update_type = ""
 					if err != nil {
@@ -133,6 +135,9 @@ func edit_loop(data_to_send string, kill_if_signaled bool, on_data OnDataCallbac
 	const abort_msg = "\x1bP@kitty-edit|0:abort_signaled=interrupt\x1b\\\x1bP@kitty-edit|\x1b\\"
 
 	lp.OnKeyEvent = func(event *loop.KeyEvent) error {
+		if err := saver.on_key(event); err != nil || event.Handled {
+			return err
+		}
 		if event.MatchesPressOrRepeat("ctrl+c") || event.MatchesPressOrRepeat("esc") {
 			event.Handled = true
 			canceled = true
@@ -164,7 +169,7 @@ func edit_loop(data_to_send string, kill_if_signaled bool, on_data OnDataCallbac
 	return
 }
 
-func edit_in_kitty(path string, opts *Options) (err error) {
+func edit_in_kitty(path string, forwarded_args []string, opts *Options) (err error) {
 	read_file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("Failed to open %s for reading with error: %w", path, err)
@@ -205,20 +210,13 @@ func edit_in_kitty(path string, opts *Options) (err error) {
 		return fmt.Errorf("Failed to get the current working directory with error: %w", err)
 	}
 	add_encoded("cwd", cwd)
-	for _, arg := range os.Args[2:] {
+	for _, arg := range forwarded_args {
 		add_encoded("a", arg)
 	}
 	add("file_inode", fmt.Sprintf("%d:%d:%d", s.Dev, s.Ino, s.Mtim.Nano()))
 	add_encoded("file_data", utils.UnsafeBytesToString(file_data))
 	fmt.Println("Waiting for editing to be completed, press Esc to abort...")
-	write_data := func(data_type string, rdata []byte) (err error) {
-		err = utils.AtomicWriteFile(path, rdata, fs.FileMode(s.Mode).Perm())
-		if err != nil {
-			err = fmt.Errorf("Failed to write data to %s with error: %w", path, err)
-		}
-		return
-	}
-	err = edit_loop(data.String(), true, write_data)
+	err = edit_loop(data.String(), true, new_save_handler(path, fs.FileMode(s.Mode).Perm(), file_data))
 	if err != nil {
 		if err == tui.Canceled {
 			return err
@@ -228,6 +226,39 @@ func edit_in_kitty(path string, opts *Options) (err error) {
 	return
 }
 
+// The arguments to forward to kitty for editing path, built from the options
+// specified on the command line
+func args_to_forward(cmd *cli.Command, path string) []string {
+	ans := []string{}
+	for _, opt := range cmd.AllOptions() {
+		if opt.Name != "MaxFileSize" {
+			ans = append(ans, opt.CmdlineArgs()...)
+		}
+	}
+	return append(ans, path)
+}
+
+// Let the user pick files from the directory and edit them one after another
+func edit_files_in_dir(cmd *cli.Command, dir string, opts *Options) (rc int, err error) {
+	chosen, err := pick_files(dir)
+	if err != nil {
+		if err == tui.Canceled {
+			return 1, nil
+		}
+		return 1, err
+	}
+	for i, name := range chosen {
+		path := filepath.Join(dir, name)
+		if len(chosen) > 1 {
+			fmt.Printf("Editing file %d of %d: %s\n", i+1, len(chosen), path)
+		}
+		if err = edit_in_kitty(path, args_to_forward(cmd, path), opts); err != nil {
+			return 1, err
+		}
+	}
+	return 0, nil
+}
+
 type Options struct {
 	MaxFileSize int
 }
@@ -235,9 +266,10 @@ type Options struct {
 func EntryPoint(parent *cli.Command) *cli.Command {
 	sc := parent.AddSubCommand(&cli.Command{
 		Name:             "edit-in-kitty",
-		Usage:            "[options] file-to-edit",
+		Usage:            "[options] file-or-directory-to-edit",
 		ShortDescription: "Edit a file in a kitty overlay window",
-		HelpText: "Edit the specified file in a kitty overlay window. Works over SSH as well.\n\n" +
+		HelpText: "Edit the specified file in a kitty overlay window. Works over SSH as well. If a directory is specified, " +
+			"a list of the files in it is shown to choose the files to edit from.\n\n" +
 			"For usage instructions see: https://sw.kovidgoyal.net/kitty/shell-integration/#edit-file",
 		Run: func(cmd *cli.Command, args []string) (ret int, err error) {
 			if len(args) == 0 {
@@ -253,7 +285,10 @@ func EntryPoint(parent *cli.Command) *cli.Command {
 			if err != nil {
 				return 1, err
 			}
-			err = edit_in_kitty(args[0], &opts)
+			if st, serr := os.Stat(args[0]); serr == nil && st.IsDir() {
+				return edit_files_in_dir(cmd, args[0], &opts)
+			}
+			err = edit_in_kitty(args[0], args_to_forward(cmd, args[0]), &opts)
 			return 0, err
 		},
 	})
@@ -0,0 +1,275 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package edit_in_kitty
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"kitty/tools/tui"
+	"kitty/tools/tui/loop"
+	"kitty/tools/tui/subseq"
+	"kitty/tools/utils"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+const MAX_PICKER_FILES = 100000
+
+// The list of files in a directory, filled in by a background goroutine so
+// the picker can be used while a large directory tree is still being listed
+type file_listing struct {
+	lock  sync.Mutex
+	files []string
+	done  bool
+	err   error
+}
+
+func (self *file_listing) snapshot() (files []string, done bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.files, self.done
+}
+
+func (self *file_listing) walk(root string, on_batch func()) {
+	batch := make([]string, 0, 256)
+	flush := func() {
+		self.lock.Lock()
+		self.files = append(self.files, batch...)
+		self.lock.Unlock()
+		batch = batch[:0]
+		on_batch()
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// ignore unreadable directories
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(name, ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			batch = append(batch, rel)
+		}
+		if len(batch) >= cap(batch) {
+			flush()
+			if len(self.files) >= MAX_PICKER_FILES {
+				return fs.SkipAll
+			}
+		}
+		return nil
+	})
+	self.lock.Lock()
+	self.files = append(self.files, batch...)
+	self.done, self.err = true, err
+	self.lock.Unlock()
+	on_batch()
+}
+
+type file_picker struct {
+	lp             *loop.Loop
+	root           string
+	listing        file_listing
+	query          string
+	matches        []*subseq.Match
+	num_scored     int
+	current, top   int
+	selected       map[string]bool
+	screen_size    loop.ScreenSize
+	chosen         []string
+	scoring_needed bool
+}
+
+func (self *file_picker) update_matches() {
+	files, _ := self.listing.snapshot()
+	if !self.scoring_needed && self.num_scored == len(files) {
+		return
+	}
+	self.num_scored, self.scoring_needed = len(files), false
+	if self.query == "" {
+		self.matches = utils.Map(func(x string) *subseq.Match { return &subseq.Match{Text: x} }, files)
+	} else {
+		self.matches = utils.Filter(subseq.ScoreItems(self.query, files, subseq.Options{}), func(m *subseq.Match) bool { return m.Score > 0 })
+		sort.SliceStable(self.matches, func(i, j int) bool { return self.matches[i].Score > self.matches[j].Score })
+	}
+	self.current = max(0, min(self.current, len(self.matches)-1))
+}
+
+func (self *file_picker) num_of_rows() int {
+	return max(1, int(self.screen_size.HeightCells)-2)
+}
+
+func (self *file_picker) draw_screen() {
+	self.update_matches()
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.ClearScreen()
+	width := int(self.screen_size.WidthCells)
+	self.lp.QueueWriteString(self.lp.SprintStyled("fg=green", "> ") + self.query)
+	rows := self.num_of_rows()
+	if self.current < self.top {
+		self.top = self.current
+	} else if self.current >= self.top+rows {
+		self.top = self.current - rows + 1
+	}
+	for i, y := self.top, 2; i < len(self.matches) && y < rows+2; i, y = i+1, y+1 {
+		m := self.matches[i]
+		self.lp.MoveCursorTo(1, y)
+		marker := "  "
+		if self.selected[m.Text] {
+			marker = self.lp.SprintStyled("fg=green", "✓ ")
+		}
+		text := wcswidth.TruncateToVisualLength(m.Text, width-2)
+		if len(m.Positions) > 0 {
+			text = highlight_positions(self.lp, text, m.Positions)
+		}
+		if i == self.current {
+			text = self.lp.SprintStyled("reverse", text)
+		}
+		self.lp.QueueWriteString(marker + text)
+	}
+	_, done := self.listing.snapshot()
+	status := fmt.Sprintf("%d of %d files", len(self.matches), self.num_scored)
+	if !done {
+		status += ", listing…"
+	}
+	self.lp.MoveCursorTo(1, int(self.screen_size.HeightCells))
+	footer := "Enter: edit  Tab: select multiple  Esc: cancel  " + status
+	self.lp.QueueWriteString(self.lp.SprintStyled("dim", wcswidth.TruncateToVisualLength(footer, width-1)))
+	self.lp.MoveCursorTo(3+wcswidth.Stringwidth(self.query), 1)
+}
+
+func highlight_positions(lp *loop.Loop, text string, positions []int) string {
+	b := strings.Builder{}
+	prev := 0
+	for _, p := range positions {
+		if p >= len(text) || p < prev {
+			continue
+		}
+		_, sz := utf8.DecodeRuneInString(text[p:])
+		b.WriteString(text[prev:p])
+		b.WriteString(lp.SprintStyled("fg=magenta bold", text[p:p+sz]))
+		prev = p + sz
+	}
+	b.WriteString(text[prev:])
+	return b.String()
+}
+
+func (self *file_picker) on_key_event(ev *loop.KeyEvent) error {
+	switch {
+	case ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c"):
+		ev.Handled = true
+		self.lp.Quit(1)
+		return nil
+	case ev.MatchesPressOrRepeat("enter"):
+		ev.Handled = true
+		for name := range self.selected {
+			self.chosen = append(self.chosen, name)
+		}
+		sort.Strings(self.chosen)
+		if len(self.chosen) == 0 && self.current < len(self.matches) {
+			self.chosen = append(self.chosen, self.matches[self.current].Text)
+		}
+		self.lp.Quit(0)
+		return nil
+	case ev.MatchesPressOrRepeat("tab"):
+		ev.Handled = true
+		if self.current < len(self.matches) {
+			name := self.matches[self.current].Text
+			if self.selected[name] {
+				delete(self.selected, name)
+			} else {
+				self.selected[name] = true
+			}
+			self.current = min(self.current+1, len(self.matches)-1)
+		}
+	case ev.MatchesPressOrRepeat("up"):
+		ev.Handled = true
+		self.current = max(0, self.current-1)
+	case ev.MatchesPressOrRepeat("down"):
+		ev.Handled = true
+		self.current = max(0, min(self.current+1, len(self.matches)-1))
+	case ev.MatchesPressOrRepeat("page_up"):
+		ev.Handled = true
+		self.current = max(0, self.current-self.num_of_rows())
+	case ev.MatchesPressOrRepeat("page_down"):
+		ev.Handled = true
+		self.current = max(0, min(self.current+self.num_of_rows(), len(self.matches)-1))
+	case ev.MatchesPressOrRepeat("backspace"):
+		ev.Handled = true
+		if self.query != "" {
+			r := []rune(self.query)
+			self.query = string(r[:len(r)-1])
+			self.scoring_needed, self.current = true, 0
+		}
+	default:
+		return nil
+	}
+	self.draw_screen()
+	return nil
+}
+
+// Let the user choose files to edit from the specified directory. Returns
+// the chosen paths, relative to the directory.
+func pick_files(root string) (chosen []string, err error) {
+	lp, err := loop.New()
+	if err != nil {
+		return
+	}
+	self := &file_picker{lp: lp, root: root, selected: make(map[string]bool)}
+	lp.OnInitialize = func() (string, error) {
+		lp.SetWindowTitle("Choose files to edit in: " + root)
+		lp.AllowLineWrapping(false)
+		sz, err := lp.ScreenSize()
+		if err != nil {
+			return "", err
+		}
+		self.screen_size = sz
+		go self.listing.walk(root, func() { lp.WakeupMainThread() })
+		self.draw_screen()
+		return "", nil
+	}
+	lp.OnWakeup = func() error {
+		self.draw_screen()
+		return nil
+	}
+	lp.OnResize = func(_, new_size loop.ScreenSize) error {
+		self.screen_size = new_size
+		self.draw_screen()
+		return nil
+	}
+	lp.OnText = func(text string, from_key_event, in_bracketed_paste bool) error {
+		self.query += text
+		self.scoring_needed, self.current = true, 0
+		self.draw_screen()
+		return nil
+	}
+	lp.OnKeyEvent = self.on_key_event
+	if err = lp.Run(); err != nil {
+		return
+	}
+	if ds := lp.DeathSignalName(); ds != "" {
+		lp.KillIfSignalled()
+		return nil, &tui.KilledBySignal{Msg: fmt.Sprint("Killed by signal: ", ds), SignalName: ds}
+	}
+	if lp.ExitCode() != 0 {
+		return nil, tui.Canceled
+	}
+	if self.listing.err != nil && len(self.chosen) == 0 {
+		return nil, fmt.Errorf("Failed to list files in %s with error: %w", root, self.listing.err)
+	}
+	return self.chosen, nil
+}
@@ -0,0 +1,75 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package record
+
+import (
+	"fmt"
+	"os"
+
+	"kitty/tools/asciicast"
+	"kitty/tools/cli"
+	"kitty/tools/tui"
+)
+
+var _ = fmt.Print
+
+type Options struct {
+	Title         string
+	IdleTimeLimit float64
+	RecordInput   bool
+}
+
+func main(args []string, opts *Options) (rc int, err error) {
+	if len(args) == 0 {
+		return 1, fmt.Errorf("Must specify the file to save the recording to")
+	}
+	path, cmd := args[0], args[1:]
+	if len(cmd) == 0 {
+		cmd = tui.ResolveShell("")
+	}
+	// recordings can contain passwords and other sensitive input
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 1, err
+	}
+	defer f.Close()
+	fmt.Printf("Recording to %s, exit the program to stop recording\r\n", path)
+	rc, err = asciicast.Record(f, cmd, asciicast.RecordOptions{Title: opts.Title, IdleTimeLimit: opts.IdleTimeLimit, RecordInput: opts.RecordInput})
+	if err == nil {
+		fmt.Printf("Recording saved to %s, play it with: kitten replay %s\r\n", path, path)
+	}
+	return
+}
+
+func EntryPoint(root *cli.Command) *cli.Command {
+	sc := root.AddSubCommand(&cli.Command{
+		Name:             "record",
+		Usage:            "[options] file [command to run ...]",
+		ShortDescription: "Record a terminal session",
+		HelpText:         "Run a command, by default the user's shell, recording everything it outputs to the terminal, with timing information, to the specified file in the asciicast v2 format. The recording can be played back using :code:`kitten replay` or any other program that supports asciicast recordings.",
+		Run: func(cmd *cli.Command, args []string) (ret int, err error) {
+			opts := &Options{}
+			err = cmd.GetOptionValues(opts)
+			if err != nil {
+				return 1, err
+			}
+			return main(args, opts)
+		},
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--title",
+		Help: "A title for the recording.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name:    "--idle-time-limit",
+		Type:    "float",
+		Default: "0",
+		Help:    "Limit pauses in the playback of the recording to this number of seconds. Zero means no limit.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--record-input",
+		Type: "bool-set",
+		Help: "Also record the keys pressed. Note that this records everything typed, including passwords.",
+	})
+	return sc
+}
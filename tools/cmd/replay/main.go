@@ -0,0 +1,263 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package replay
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"kitty/tools/asciicast"
+	"kitty/tools/cli"
+	"kitty/tools/tui/loop"
+)
+
+var _ = fmt.Print
+
+const SEEK_STEP = 5.0
+const MIN_SPEED, MAX_SPEED = 0.125, 16.0
+
+type Options struct {
+	Speed         float64
+	IdleTimeLimit float64
+	Paused        bool
+}
+
+type player struct {
+	lp  *loop.Loop
+	rec *asciicast.Recording
+	// index of the next event to be played
+	idx int
+	// the position in the recording, in seconds, at anchor_time
+	position    float64
+	anchor_time time.Time
+	speed       float64
+	paused      bool
+	timer_id    loop.IdType
+}
+
+func (self *player) current_position() float64 {
+	if self.paused || self.idx >= len(self.rec.Events) {
+		return self.position
+	}
+	return self.position + time.Since(self.anchor_time).Seconds()*self.speed
+}
+
+func (self *player) finished() bool { return self.idx >= len(self.rec.Events) }
+
+func (self *player) cancel_timer() {
+	if self.timer_id != 0 {
+		self.lp.RemoveTimer(self.timer_id)
+		self.timer_id = 0
+	}
+}
+
+func (self *player) schedule_next() {
+	self.cancel_timer()
+	if self.paused || self.finished() {
+		return
+	}
+	delay := time.Duration((self.rec.Events[self.idx].Time - self.current_position()) / self.speed * float64(time.Second))
+	self.timer_id, _ = self.lp.AddTimer(max(0, delay), false, self.on_timer)
+}
+
+func (self *player) on_timer(loop.IdType) error {
+	self.timer_id = 0
+	pos := self.current_position()
+	end := self.rec.IndexAfter(pos)
+	if end > self.idx {
+		self.lp.QueueWriteString(self.rec.Output(self.idx, end))
+		self.idx = end
+	}
+	if self.finished() {
+		self.position = self.rec.Duration()
+		self.draw_status()
+		return nil
+	}
+	self.schedule_next()
+	return nil
+}
+
+func (self *player) draw_status() {
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return
+	}
+	state := "Paused"
+	if self.finished() {
+		state = "Finished"
+	}
+	status := fmt.Sprintf(" %s %.1fs/%.1fs at %gx  space:play/pause  ←/→:seek  +/-:speed  .:step  q:quit ", state, self.current_position(), self.rec.Duration(), self.speed)
+	self.lp.SaveCursorPosition()
+	self.lp.MoveCursorTo(1, int(sz.HeightCells))
+	self.lp.QueueWriteString("\x1b[m")
+	self.lp.ClearToEndOfLine()
+	self.lp.QueueWriteString(self.lp.SprintStyled("reverse", status))
+	self.lp.RestoreCursorPosition()
+}
+
+// Redraw the screen as it was at the current position by replaying all
+// output from the start of the recording
+func (self *player) rerender() {
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.lp.QueueWriteString("\x1b[!p\x1b[m\x1b[H\x1b[2J")
+	self.lp.QueueWriteString(self.rec.Output(0, self.idx))
+	if self.paused || self.finished() {
+		self.draw_status()
+	}
+}
+
+func (self *player) seek(pos float64) {
+	self.position = max(0, min(pos, self.rec.Duration()))
+	self.anchor_time = time.Now()
+	self.idx = self.rec.IndexAfter(self.position)
+	if self.position == 0 {
+		self.idx = 0
+	}
+	self.rerender()
+	self.schedule_next()
+}
+
+func (self *player) toggle_pause() {
+	if self.finished() {
+		self.paused = false
+		self.seek(0)
+		return
+	}
+	self.position = self.current_position()
+	self.anchor_time = time.Now()
+	self.paused = !self.paused
+	self.rerender()
+	self.schedule_next()
+}
+
+func (self *player) set_speed(speed float64) {
+	self.position = self.current_position()
+	self.anchor_time = time.Now()
+	self.speed = max(MIN_SPEED, min(speed, MAX_SPEED))
+	if self.paused {
+		self.draw_status()
+	}
+	self.schedule_next()
+}
+
+// Play the next event when paused
+func (self *player) step() {
+	if !self.paused || self.finished() {
+		return
+	}
+	e := self.rec.Events[self.idx]
+	self.position = e.Time
+	self.idx = self.rec.IndexAfter(e.Time)
+	self.rerender()
+}
+
+func (self *player) on_key_event(ev *loop.KeyEvent) error {
+	ev.Handled = true
+	switch {
+	case ev.MatchesPressOrRepeat("q") || ev.MatchesPressOrRepeat("esc") || ev.MatchesPressOrRepeat("ctrl+c"):
+		self.lp.Quit(0)
+	case ev.MatchesPressOrRepeat("space"):
+		self.toggle_pause()
+	case ev.MatchesPressOrRepeat("left"):
+		self.seek(self.current_position() - SEEK_STEP*self.speed)
+	case ev.MatchesPressOrRepeat("right"):
+		self.seek(self.current_position() + SEEK_STEP*self.speed)
+	case ev.MatchesPressOrRepeat("home"):
+		self.seek(0)
+	case ev.MatchesPressOrRepeat("end"):
+		self.seek(self.rec.Duration())
+	case ev.MatchesPressOrRepeat("+") || ev.MatchesPressOrRepeat("=") || ev.MatchesPressOrRepeat("up"):
+		self.set_speed(self.speed * 2)
+	case ev.MatchesPressOrRepeat("-") || ev.MatchesPressOrRepeat("down"):
+		self.set_speed(self.speed / 2)
+	case ev.MatchesPressOrRepeat("."):
+		self.step()
+	default:
+		ev.Handled = false
+	}
+	return nil
+}
+
+func main(args []string, opts *Options) (rc int, err error) {
+	if len(args) != 1 {
+		return 1, fmt.Errorf("Must specify exactly one recording to play")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return 1, err
+	}
+	rec, err := asciicast.Read(f)
+	f.Close()
+	if err != nil {
+		return 1, err
+	}
+	if opts.IdleTimeLimit > 0 {
+		rec.LimitIdleTime(opts.IdleTimeLimit)
+	} else if opts.IdleTimeLimit == 0 {
+		rec.LimitIdleTime(rec.Header.IdleTimeLimit)
+	}
+	lp, err := loop.New(loop.NoMouseTracking)
+	if err != nil {
+		return 1, err
+	}
+	p := player{lp: lp, rec: rec, paused: opts.Paused, speed: max(MIN_SPEED, min(opts.Speed, MAX_SPEED))}
+	lp.OnInitialize = func() (string, error) {
+		if rec.Header.Title != "" {
+			lp.SetWindowTitle(rec.Header.Title)
+		}
+		p.seek(0)
+		return "", nil
+	}
+	lp.OnResize = func(old_size, new_size loop.ScreenSize) error {
+		p.rerender()
+		return nil
+	}
+	lp.OnKeyEvent = p.on_key_event
+	if err = lp.Run(); err != nil {
+		return 1, err
+	}
+	ds := lp.DeathSignalName()
+	if ds != "" {
+		fmt.Println("Killed by signal: ", ds)
+		lp.KillIfSignalled()
+		return 1, nil
+	}
+	return lp.ExitCode(), nil
+}
+
+func EntryPoint(root *cli.Command) *cli.Command {
+	sc := root.AddSubCommand(&cli.Command{
+		Name:             "replay",
+		Usage:            "[options] recording",
+		ShortDescription: "Play back a recorded terminal session",
+		HelpText:         "Play back a terminal session recorded in the asciicast v2 format, for example, by :code:`kitten record`. Press :kbd:`Space` to pause or resume, the :kbd:`Left` and :kbd:`Right` arrow keys to seek, :kbd:`+` and :kbd:`-` to change the playback speed, :kbd:`.` to step through the recording event by event while paused and :kbd:`q` to quit.",
+		Run: func(cmd *cli.Command, args []string) (ret int, err error) {
+			opts := &Options{}
+			err = cmd.GetOptionValues(opts)
+			if err != nil {
+				return 1, err
+			}
+			return main(args, opts)
+		},
+	})
+	sc.Add(cli.OptionSpec{
+		Name:    "--speed",
+		Type:    "float",
+		Default: "1",
+		Help:    "The initial playback speed, as a multiple of the recorded speed.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name:    "--idle-time-limit",
+		Type:    "float",
+		Default: "0",
+		Help:    "Limit pauses between events to this number of seconds. Zero means use the limit specified in the recording, and a negative number means no limit.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--paused",
+		Type: "bool-set",
+		Help: "Start with playback paused.",
+	})
+	return sc
+}
@@ -0,0 +1,179 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package run_shell
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Variables that change how programs are loaded or that are used by kitty
+// itself are never imported, even if allowed
+var ENV_ALWAYS_DENIED = []string{"!LD_*", "!DYLD_*", "!KITTY_*"}
+
+// A single variable from an env file, a nil value means the variable must be unset
+type env_var struct {
+	name  string
+	value *string
+}
+
+func is_valid_env_name(name string) bool {
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return false
+	}
+	for _, ch := range name {
+		if ch != '_' && !(ch < 128 && (unicode.IsLetter(ch) || unicode.IsDigit(ch))) {
+			return false
+		}
+	}
+	return true
+}
+
+func unquote_env_value(val string) (string, error) {
+	if len(val) < 2 {
+		return val, nil
+	}
+	switch val[0] {
+	case '\'':
+		if val[len(val)-1] != '\'' {
+			return "", fmt.Errorf("Unterminated single quoted value")
+		}
+		return val[1 : len(val)-1], nil
+	case '"':
+		if val[len(val)-1] != '"' {
+			return "", fmt.Errorf("Unterminated double quoted value")
+		}
+		buf := strings.Builder{}
+		escaped := false
+		for _, ch := range val[1 : len(val)-1] {
+			if escaped {
+				escaped = false
+				switch ch {
+				case 'n':
+					ch = '\n'
+				case 't':
+					ch = '\t'
+				case '"', '\\', '$':
+				default:
+					buf.WriteRune('\\')
+				}
+			} else if ch == '\\' {
+				escaped = true
+				continue
+			}
+			buf.WriteRune(ch)
+		}
+		return buf.String(), nil
+	}
+	return val, nil
+}
+
+// Parse lines of the form KEY=VALUE, optionally prefixed by export. Values
+// can be quoted, but no expansion of any kind is performed.
+func parse_dotenv(data string) (ans []env_var, err error) {
+	for i, line := range utils.Splitlines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		name, val, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || !is_valid_env_name(name) {
+			return nil, fmt.Errorf("Line %d is not of the form NAME=VALUE", i+1)
+		}
+		if val, err = unquote_env_value(strings.TrimSpace(val)); err != nil {
+			return nil, fmt.Errorf("Line %d: %w", i+1, err)
+		}
+		ans = append(ans, env_var{name, &val})
+	}
+	return
+}
+
+// Parse a JSON object mapping names to values, as output by direnv export json,
+// where null values indicate the variable must be unset
+func parse_json_env(data []byte) (ans []env_var, err error) {
+	var m map[string]*string
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	names := maps.Keys(m)
+	slices.Sort(names)
+	for _, name := range names {
+		if !is_valid_env_name(name) {
+			return nil, fmt.Errorf("Invalid environment variable name: %#v", name)
+		}
+		ans = append(ans, env_var{name, m[name]})
+	}
+	return
+}
+
+func parse_env_file(data []byte) ([]env_var, error) {
+	if text := strings.TrimSpace(utils.UnsafeBytesToString(data)); strings.HasPrefix(text, "{") {
+		return parse_json_env(data)
+	}
+	return parse_dotenv(utils.UnsafeBytesToString(data))
+}
+
+// Whether the named variable is allowed by the glob patterns, patterns
+// prefixed with ! deny and take precedence
+func env_name_allowed(name string, patterns []string) bool {
+	allowed := false
+	for _, p := range patterns {
+		pat, negated := strings.CutPrefix(p, "!")
+		if matched, _ := path.Match(pat, name); matched {
+			if negated {
+				return false
+			}
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// Apply the variables from the specified env files to the environment of this
+// process, in order. Files that do not exist are ignored. Only variables
+// matching the allow patterns are applied, since an env file can be placed in
+// a directory by anyone and variables such as PATH or BASH_ENV can be used to
+// run arbitrary code, nothing is allowed by default. Returns the names of
+// variables that were not applied as they are not allowed.
+func load_env_files(paths, allow []string) (refused []string, err error) {
+	allow = append(slices.Clone(allow), ENV_ALWAYS_DENIED...)
+	for _, p := range paths {
+		data, err := os.ReadFile(utils.Expanduser(p))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return refused, err
+		}
+		vars, err := parse_env_file(data)
+		if err != nil {
+			return refused, fmt.Errorf("Failed to parse the env file %s with error: %w", p, err)
+		}
+		for _, v := range vars {
+			switch {
+			case !env_name_allowed(v.name, allow):
+				refused = append(refused, v.name)
+			case v.value == nil:
+				os.Unsetenv(v.name)
+			default:
+				os.Setenv(v.name, *v.value)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,55 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package run_shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestEnvFileParsing(t *testing.T) {
+	s := func(x string) *string { return &x }
+	q := func(data string, expected ...env_var) {
+		actual, err := parse_env_file([]byte(data))
+		if err != nil {
+			t.Fatalf("Failed to parse %#v with error: %s", data, err)
+		}
+		if diff := cmp.Diff(expected, actual, cmp.AllowUnexported(env_var{})); diff != "" {
+			t.Fatalf("Failed to parse %#v\n%s", data, diff)
+		}
+	}
+	q("# comment\n\nA=1\nexport B = two words \nC='$HOME \\n'\nD=\"a\\\"b\\n$c\"\nE=",
+		env_var{"A", s("1")}, env_var{"B", s("two words")}, env_var{"C", s("$HOME \\n")}, env_var{"D", s("a\"b\n$c")}, env_var{"E", s("")})
+	q(`{"B": "x", "A": null}`, env_var{"A", nil}, env_var{"B", s("x")})
+	for _, bad := range []string{"A", "1A=x", "A B=x", "A='x", `{"A=": "x"}`} {
+		if _, err := parse_env_file([]byte(bad)); err == nil {
+			t.Fatalf("Parsing %#v did not fail", bad)
+		}
+	}
+
+	for name, expected := range map[string]bool{"PATH": true, "LD_PRELOAD": false, "KITTY_WINDOW_ID": false} {
+		if actual := env_name_allowed(name, append([]string{"*"}, ENV_ALWAYS_DENIED...)); actual != expected {
+			t.Fatalf("Allowed status of %s was %v instead of %v", name, actual, expected)
+		}
+	}
+	if env_name_allowed("PATH", []string{"PROJECT_*"}) || !env_name_allowed("PROJECT_X", []string{"PROJECT_*"}) {
+		t.Fatalf("Allow patterns not matched correctly")
+	}
+
+	// nothing is allowed by default
+	env_file := filepath.Join(t.TempDir(), "env")
+	os.WriteFile(env_file, []byte("KITTY_TEST_ENV_FILE_PATH=x"), 0o600)
+	refused, err := load_env_files([]string{env_file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"KITTY_TEST_ENV_FILE_PATH"}, refused); diff != "" || os.Getenv("KITTY_TEST_ENV_FILE_PATH") != "" {
+		t.Fatalf("Variable from env file was not refused by default:\n%s", diff)
+	}
+}
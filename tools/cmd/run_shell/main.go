@@ -4,6 +4,8 @@ package run_shell
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"kitty/tools/cli"
 	"kitty/tools/tui"
@@ -14,9 +16,20 @@ var _ = fmt.Print
 type Options struct {
 	Shell            string
 	ShellIntegration string
+	EnvFile          []string
+	EnvAllow         []string
 }
 
 func main(args []string, opts *Options) (rc int, err error) {
+	if len(opts.EnvFile) > 0 {
+		refused, err := load_env_files(opts.EnvFile, opts.EnvAllow)
+		if err != nil {
+			return 1, err
+		}
+		if len(refused) > 0 {
+			fmt.Fprintln(os.Stderr, "Ignored the following variables from env files as they are not allowed by --env-allow:", strings.Join(refused, " "))
+		}
+	}
 	if len(args) > 0 {
 		tui.RunCommandRestoringTerminalToSaneStateAfter(args)
 	}
@@ -51,5 +64,23 @@ func EntryPoint(root *cli.Command) *cli.Command {
 		Default: ".",
 		Help:    "Specify the shell command to run. The default value of :code:`.` will use the parent shell if recognized, falling back to the value of the :opt:`shell` option from :file:`kitty.conf`.",
 	})
+	sc.Add(cli.OptionSpec{
+		Name: "--env-file",
+		Type: "list",
+		Help: "Path to a file with environment variables to set before running the shell and the optional command. Can be specified multiple times, with later files taking precedence. " +
+			"Relative paths are resolved with respect to the current working directory and files that do not exist are ignored, so that, for example, :code:`--env-file .kitty-env` " +
+			"can be used in a session file for any project. The file must either have lines of the form :code:`NAME=VALUE`, " +
+			"with values optionally quoted, or be a JSON object mapping names to values, as output by :code:`direnv export json`, where a value of :code:`null` unsets the variable. " +
+			"The contents are never executed and no expansion of variables or commands is performed.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--env-allow",
+		Type: "list",
+		Help: "A glob pattern matching the names of the variables from :option:`--env-file` that are allowed to be set. Can be specified multiple times. " +
+			"Patterns prefixed with :code:`!` prevent matching variables from being set and take precedence. Variables that are not allowed are ignored with a warning. " +
+			"If not specified, no variables are allowed. Be careful when allowing variables such as :envvar:`PATH` or :envvar:`BASH_ENV`, as they can be used to run arbitrary code, " +
+			"by anyone able to create an env file in a directory you use. The patterns :code:`" + strings.Join(ENV_ALWAYS_DENIED, " ") + "` are always used, so that variables " +
+			"that control how programs are loaded and those used by kitty itself are never set.",
+	})
 	return sc
 }
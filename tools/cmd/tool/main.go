@@ -8,20 +8,30 @@ import (
 	"kitty/kittens/ask"
 	"kitty/kittens/clipboard"
 	"kitty/kittens/diff"
+	"kitty/kittens/git_browser"
 	"kitty/kittens/hints"
 	"kitty/kittens/hyperlinked_grep"
 	"kitty/kittens/icat"
+	"kitty/kittens/input_latency"
+	"kitty/kittens/known_hosts"
+	"kitty/kittens/man"
+	"kitty/kittens/search_scrollback"
 	"kitty/kittens/show_key"
 	"kitty/kittens/ssh"
+	"kitty/kittens/ssh_config"
 	"kitty/kittens/themes"
 	"kitty/kittens/transfer"
 	"kitty/kittens/unicode_input"
 	"kitty/tools/cli"
 	"kitty/tools/cmd/at"
+	"kitty/tools/cmd/benchmark"
 	"kitty/tools/cmd/edit_in_kitty"
 	"kitty/tools/cmd/pytest"
+	"kitty/tools/cmd/record"
+	"kitty/tools/cmd/replay"
 	"kitty/tools/cmd/run_shell"
 	"kitty/tools/cmd/show_error"
+	"kitty/tools/cmd/unicode_width"
 	"kitty/tools/cmd/update_self"
 	"kitty/tools/tui"
 )
@@ -58,11 +68,31 @@ func KittyToolEntryPoints(root *cli.Command) {
 	hints.EntryPoint(root)
 	// hints
 	diff.EntryPoint(root)
+	// known_hosts
+	known_hosts.EntryPoint(root)
+	// ssh_config
+	ssh_config.EntryPoint(root)
+	// man
+	man.EntryPoint(root)
+	// git_browser
+	git_browser.EntryPoint(root)
+	// search_scrollback
+	search_scrollback.EntryPoint(root)
+	// input_latency
+	input_latency.EntryPoint(root)
 	// themes
 	themes.EntryPoint(root)
 	themes.ParseEntryPoint(root)
+	// benchmark
+	benchmark.EntryPoint(root)
+	// unicode-width
+	unicode_width.EntryPoint(root)
 	// run-shell
 	run_shell.EntryPoint(root)
+	// record
+	record.EntryPoint(root)
+	// replay
+	replay.EntryPoint(root)
 	// show_error
 	show_error.EntryPoint(root)
 	// __pytest__
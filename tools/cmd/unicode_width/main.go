@@ -0,0 +1,253 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package unicode_width
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"kitty/tools/cli"
+	"kitty/tools/tty"
+	"kitty/tools/tui/loop"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type Options struct {
+	Format string
+	Tty    string
+}
+
+type test_case struct {
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Text        string `json:"text"`
+}
+
+type test_result struct {
+	test_case
+	Codepoints []string `json:"codepoints"`
+	// The width as computed by wcswidth
+	Expected int `json:"expected"`
+	// The width as rendered by the terminal
+	Actual int  `json:"actual"`
+	Ok     bool `json:"ok"`
+}
+
+type report struct {
+	Terminal   string        `json:"terminal"`
+	Results    []test_result `json:"results"`
+	Mismatches int           `json:"mismatches"`
+}
+
+var test_cases = []test_case{
+	{"ascii", "Printable ASCII", "abc XYZ 123"},
+	{"wide", "CJK ideographs", "中文"},
+	{"wide", "Hangul syllables", "한글"},
+	{"wide", "Fullwidth forms", "ＡＢ"},
+	{"emoji", "Emoji with default emoji presentation", "\U0001f600"},
+	{"emoji", "Emoji with default text presentation", "❤"},
+	{"emoji", "Text presentation emoji with VS16", "❤\ufe0f"},
+	{"emoji", "Emoji presentation emoji with VS15", "\U0001f600\ufe0e"},
+	{"emoji", "Emoji with skin tone modifier", "\U0001f44d\U0001f3fd"},
+	{"emoji", "Regional indicator pair (flag)", "\U0001f1ee\U0001f1f3"},
+	{"emoji", "Lone regional indicator", "\U0001f1ee"},
+	{"emoji", "Keycap sequence", "1\ufe0f\u20e3"},
+	{"zwj", "ZWJ family sequence", "\U0001f468\u200d\U0001f469\u200d\U0001f467"},
+	{"zwj", "ZWJ profession sequence", "\U0001f469\u200d\U0001f52c"},
+	{"zwj", "ZWJ sequence with skin tones", "\U0001f9d1\U0001f3fb\u200d\U0001f91d\u200d\U0001f9d1\U0001f3ff"},
+	{"zwj", "ZWJ rainbow flag", "\U0001f3f3\ufe0f\u200d\U0001f308"},
+	{"combining", "Latin letter with combining acute", "e\u0301"},
+	{"combining", "Latin letter with multiple combining marks", "a\u0300\u0316\u0323"},
+	{"combining", "Combining mark with no base", "\u0301"},
+	{"combining", "Devanagari with virama", "क\u094dष"},
+	{"combining", "Hangul conjoining jamo", "\u1100\u1161\u11a8"},
+	{"ambiguous", "Inverted exclamation mark", "¡"},
+	{"ambiguous", "Greek small alpha", "α"},
+	{"ambiguous", "Circled digit one", "①"},
+	{"ambiguous", "Box drawing", "─│"},
+	{"ambiguous", "Private use area", "\ue000"},
+	{"zero-width", "Zero width space", "a\u200bb"},
+	{"zero-width", "Soft hyphen", "a\u00adb"},
+	{"zero-width", "Word joiner", "a\u2060b"},
+	{"zero-width", "Lone variation selector", "\ufe0f"},
+}
+
+func codepoints(text string) []string {
+	ans := make([]string, 0, len(text))
+	for _, ch := range text {
+		ans = append(ans, fmt.Sprintf("U+%04X", ch))
+	}
+	return ans
+}
+
+var cursor_position_report = regexp.MustCompile(`\x1b\[(\d+);(\d+)R`)
+
+// Parse all complete cursor position reports in data, returning the
+// columns and the unparsed remainder of data
+func parse_cursor_position_reports(data []byte) (columns []int, remainder []byte) {
+	matches := cursor_position_report.FindAllSubmatchIndex(data, -1)
+	for _, m := range matches {
+		col, _ := strconv.Atoi(string(data[m[4]:m[5]]))
+		columns = append(columns, col)
+	}
+	if len(matches) > 0 {
+		data = data[matches[len(matches)-1][1]:]
+	}
+	return columns, data
+}
+
+// Print each test case at the start of a line and query the cursor position
+// after it, the column is one more than the width in cells of the test case.
+// All queries are sent at once and responses read afterwards, as terminals
+// respond to them in order.
+func measure_widths(term *tty.Term, cases []test_case) (widths []int, err error) {
+	buf := strings.Builder{}
+	for _, tc := range cases {
+		buf.WriteString("\r\x1b[2K")
+		buf.WriteString(tc.Text)
+		buf.WriteString("\x1b[6n")
+	}
+	if err = term.WriteAllString(buf.String()); err != nil {
+		return
+	}
+	readbuf := make([]byte, 4096)
+	var pending []byte
+	for len(widths) < len(cases) {
+		n, err := term.ReadWithTimeout(readbuf, 5*time.Second)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				err = fmt.Errorf("Timed out waiting for the terminal to report the cursor position")
+			}
+			return widths, err
+		}
+		pending = append(pending, readbuf[:n]...)
+		var cols []int
+		cols, pending = parse_cursor_position_reports(pending)
+		for _, c := range cols {
+			widths = append(widths, c-1)
+		}
+	}
+	return widths[:len(cases)], nil
+}
+
+func run_tests(cases []test_case, device string) (*report, error) {
+	term, err := tty.OpenDevice(device, tty.SetRaw)
+	if err != nil {
+		return nil, err
+	}
+	var widths []int
+	err = func() error {
+		defer func() {
+			_ = term.WriteAllString("\x1b[?1049l")
+			term.RestoreAndClose()
+		}()
+		if err := term.WriteAllString("\x1b[?1049h\x1b[H\x1b[2J"); err != nil {
+			return err
+		}
+		widths, err = measure_widths(term, cases)
+		return err
+	}()
+	if err != nil {
+		return nil, err
+	}
+	ans := &report{Results: make([]test_result, len(cases))}
+	if device == "" {
+		caps := loop.TerminalCapabilitiesFromEnvironment()
+		ans.Terminal = caps.Terminal.String()
+		if caps.Version != "" {
+			ans.Terminal += " " + caps.Version
+		}
+	} else {
+		// the environment describes the terminal the kitten is running in
+		ans.Terminal = "Terminal on " + device
+	}
+	for i, tc := range cases {
+		r := test_result{test_case: tc, Codepoints: codepoints(tc.Text), Expected: wcswidth.Stringwidth(tc.Text), Actual: widths[i]}
+		r.Ok = r.Expected == r.Actual
+		if !r.Ok {
+			ans.Mismatches++
+		}
+		ans.Results[i] = r
+	}
+	return ans, nil
+}
+
+func print_text_report(r *report) {
+	fmt.Println("Terminal:", r.Terminal)
+	fmt.Printf("%d of %d tests have widths different from wcswidth\n", r.Mismatches, len(r.Results))
+	if r.Mismatches == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("%-12s %-48s %8s %8s  %s\n", "Category", "Description", "wcswidth", "Terminal", "Codepoints")
+	for _, x := range r.Results {
+		if !x.Ok {
+			fmt.Printf("%-12s %-48s %8d %8d  %s\n", x.Category, x.Description, x.Expected, x.Actual, strings.Join(x.Codepoints, " "))
+		}
+	}
+}
+
+func main(args []string, opts *Options) (rc int, err error) {
+	cases := test_cases
+	if len(args) > 0 {
+		cases = make([]test_case, len(args))
+		for i, x := range args {
+			cases[i] = test_case{Category: "custom", Description: "Command line argument", Text: x}
+		}
+	}
+	r, err := run_tests(cases, opts.Tty)
+	if err != nil {
+		return 1, err
+	}
+	if opts.Format == "json" {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return 1, err
+		}
+		fmt.Println(string(data))
+	} else {
+		print_text_report(r)
+	}
+	if r.Mismatches > 0 {
+		rc = 2
+	}
+	return
+}
+
+func EntryPoint(root *cli.Command) *cli.Command {
+	sc := root.AddSubCommand(&cli.Command{
+		Name:             "unicode-width",
+		Usage:            "[options] [text to test ...]",
+		ShortDescription: "Check the widths of Unicode text in the terminal",
+		HelpText: "Print test patterns of emoji, ZWJ sequences, combining marks, ambiguous width characters and the like, querying the position of the cursor after each, to find where the widths used by the terminal this kitten is running in disagree with the widths computed by the wcswidth implementation in kitten. " +
+			"If any text is specified on the command line, only it is tested. " +
+			"The exit code is 2 if there are disagreements.",
+		Run: func(cmd *cli.Command, args []string) (ret int, err error) {
+			opts := &Options{}
+			err = cmd.GetOptionValues(opts)
+			if err != nil {
+				return 1, err
+			}
+			return main(args, opts)
+		},
+	})
+	sc.Add(cli.OptionSpec{
+		Name:    "--format",
+		Default: "json",
+		Choices: "json, text",
+		Help:    "The format of the report. :code:`json` is a machine readable report with the results of all tests, :code:`text` lists only the tests where the widths disagree.",
+	})
+	sc.Add(cli.OptionSpec{
+		Name: "--tty",
+		Help: "The terminal device to test instead of the terminal the kitten is running in, such as another pseudo-terminal or a serial port, as :code:`path[:baud_rate]`, for example: :code:`/dev/ttyUSB0:115200`.",
+	})
+	return sc
+}
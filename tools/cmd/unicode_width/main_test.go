@@ -0,0 +1,26 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package unicode_width
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestCursorPositionReportParsing(t *testing.T) {
+	cols, rest := parse_cursor_position_reports([]byte("\x1b[1;3R\x1b[1;12R\x1b[2;"))
+	if diff := cmp.Diff([]int{3, 12}, cols); diff != "" {
+		t.Fatalf("Incorrect columns parsed:\n%s", diff)
+	}
+	if string(rest) != "\x1b[2;" {
+		t.Fatalf("Incorrect remainder: %#v", string(rest))
+	}
+	cols, rest = parse_cursor_position_reports(append(rest, []byte("1R")...))
+	if diff := cmp.Diff([]int{1}, cols); diff != "" || len(rest) != 0 {
+		t.Fatalf("Incorrect columns parsed from continued data: %#v\n%s", string(rest), diff)
+	}
+}
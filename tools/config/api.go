@@ -11,7 +11,9 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 
 	"kitty/tools/utils"
 )
@@ -33,10 +35,16 @@ type ConfigParser struct {
 	LineHandler     func(key, val string) error
 	CommentsHandler func(line string) error
 	SourceHandler   func(text, path string)
+	// Keys whose values can be references to secrets, see resolve_secret().
+	// Since resolving a reference can run a command, this must never be set
+	// when parsing untrusted files, such as themes.
+	SecretKeys []string
 
-	bad_lines     []ConfigLine
-	seen_includes map[string]bool
-	override_env  []string
+	bad_lines        []ConfigLine
+	seen_includes    map[string]bool
+	override_env     []string
+	resolved_secrets map[string]string
+	recorder         *parse_recorder
 }
 
 type Scanner interface {
@@ -49,6 +57,25 @@ func (self *ConfigParser) BadLines() []ConfigLine {
 	return self.bad_lines
 }
 
+// A line ending with a single backslash is continued on the next line, lines
+// ending with two backslashes are left as is
+func has_continuation(line string) bool {
+	return strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`)
+}
+
+var heredoc_pat = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`^<<([a-zA-Z_][a-zA-Z0-9_]*)$`)
+})
+
+// A value of the form <<MARKER means that the value is the following lines,
+// up to a line containing only MARKER
+func heredoc_marker(val string) string {
+	if m := heredoc_pat().FindStringSubmatch(val); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
 func (self *ConfigParser) parse(scanner Scanner, name, base_path_for_includes string, depth int) error {
 	if self.seen_includes[name] { // avoid include loops
 		return nil
@@ -79,6 +106,7 @@ func (self *ConfigParser) parse(scanner Scanner, name, base_path_for_includes st
 		if line == "" {
 			continue
 		}
+		start_lnum := lnum
 		if line[0] == '#' {
 			if self.CommentsHandler != nil {
 				err := self.CommentsHandler(line)
@@ -88,13 +116,43 @@ func (self *ConfigParser) parse(scanner Scanner, name, base_path_for_includes st
 			}
 			continue
 		}
+		for has_continuation(line) && scanner.Scan() {
+			lnum++
+			line = line[:len(line)-1] + strings.TrimLeft(scanner.Text(), " \t")
+		}
 		key, val, _ := strings.Cut(line, " ")
 		val = strings.TrimSpace(val)
+		is_heredoc := false
+		if marker := heredoc_marker(val); marker != "" {
+			lines := []string{}
+			found := false
+			for scanner.Scan() {
+				lnum++
+				l := scanner.Text()
+				if strings.TrimSpace(l) == marker {
+					found = true
+					break
+				}
+				lines = append(lines, l)
+			}
+			if !found {
+				self.bad_lines = append(self.bad_lines, ConfigLine{Src_file: name, Line: line, Line_number: start_lnum, Err: fmt.Errorf("The multi-line value is not terminated by a line containing only: %s", marker)})
+				continue
+			}
+			val, is_heredoc = strings.Join(lines, "\n"), true
+		}
 		switch key {
 		default:
-			err := self.LineHandler(key, val)
+			var err error
+			if !is_heredoc {
+				val, err = self.resolve_secret(key, val)
+			}
+			if err == nil {
+				self.note_line(key, val)
+				err = self.LineHandler(key, val)
+			}
 			if err != nil {
-				self.bad_lines = append(self.bad_lines, ConfigLine{Src_file: name, Line: line, Line_number: lnum, Err: err})
+				self.bad_lines = append(self.bad_lines, ConfigLine{Src_file: name, Line: line, Line_number: start_lnum, Err: err})
 			}
 		case "include", "globinclude", "envinclude":
 			var includes []string
@@ -111,8 +169,15 @@ func (self *ConfigParser) parse(scanner Scanner, name, base_path_for_includes st
 					if err == nil {
 						includes = matches
 					}
+					// the matches change when files are added to or removed from the directory
+					if dir := filepath.Dir(aval); strings.ContainsAny(dir, `*?[\`) {
+						self.note_uncacheable()
+					} else {
+						self.note_file(dir)
+					}
 				}
 			case "envinclude":
+				self.note_uncacheable()
 				env := self.override_env
 				if env == nil {
 					env = os.Environ()
@@ -130,6 +195,7 @@ func (self *ConfigParser) parse(scanner Scanner, name, base_path_for_includes st
 			}
 			if len(includes) > 0 {
 				for _, incpath := range includes {
+					self.note_file(incpath)
 					raw, err := os.ReadFile(incpath)
 					if err == nil {
 						err := recurse(bytes.NewReader(raw), incpath, filepath.Dir(incpath))
@@ -152,6 +218,7 @@ func (self *ConfigParser) ParseFiles(paths ...string) error {
 		if err == nil {
 			path = apath
 		}
+		self.note_file(path)
 		raw, err := os.ReadFile(path)
 		if err != nil {
 			return err
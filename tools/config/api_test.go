@@ -3,6 +3,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -60,3 +61,86 @@ globinclude sub/c?.conf
 		t.Fatalf("Unexpected bad lines:\n%s", diff)
 	}
 }
+
+func TestConfigMultiLineValues(t *testing.T) {
+	var parsed_lines []string
+	p := ConfigParser{LineHandler: func(key, val string) error {
+		parsed_lines = append(parsed_lines, key+" "+val)
+		return nil
+	}}
+	err := p.ParseOverrides("a one \\", "  two \\", "three", "b c:\\\\", "script <<END", "  #!/bin/sh", "", "echo \\", "END", "d four", "e <<EOF", "unterminated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]string{"a one two three", "b c:\\\\", "script   #!/bin/sh\n\necho \\", "d four"}, parsed_lines)
+	if diff != "" {
+		t.Fatalf("Unexpected parsed config values:\n%s", diff)
+	}
+	if len(p.BadLines()) != 1 || p.BadLines()[0].Line_number != 11 {
+		t.Fatalf("Unexpected bad lines: %v", p.BadLines())
+	}
+}
+
+func TestConfigSecretReferences(t *testing.T) {
+	var parsed_lines []string
+	p := ConfigParser{SecretKeys: []string{"password", "other", "multi", "bad"}, LineHandler: func(key, val string) error {
+		parsed_lines = append(parsed_lines, key+" "+val)
+		return nil
+	}}
+	err := p.ParseOverrides("password cmd:echo 'a secret'", "other cmds:echo x", "multi <<END", "cmd:echo x", "END", "bad cmd:", "bad cmd:'unterminated", "untrusted cmd:echo x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the values of SecretKeys are resolved
+	diff := cmp.Diff([]string{"password a secret", "other cmds:echo x", "multi cmd:echo x", "untrusted cmd:echo x"}, parsed_lines)
+	if diff != "" {
+		t.Fatalf("Unexpected parsed config values:\n%s", diff)
+	}
+	if len(p.BadLines()) != 2 {
+		t.Fatalf("Unexpected bad lines: %v", p.BadLines())
+	}
+}
+
+func TestParsedConfigCache(t *testing.T) {
+	tdir := t.TempDir()
+	cache_dir := filepath.Join(tdir, "cache")
+	conf_file := filepath.Join(tdir, "a.conf")
+	os.Mkdir(filepath.Join(tdir, "sub"), 0o700)
+	os.WriteFile(conf_file, []byte("a one\ninclude sub/b.conf\nglobinclude sub/c*.conf"), 0o600)
+	os.WriteFile(filepath.Join(tdir, "sub/b.conf"), []byte("b two"), 0o600)
+
+	parse := func(expected ...string) {
+		var parsed_lines []string
+		p := ConfigParser{SecretKeys: []string{"password"}, LineHandler: func(key, val string) error {
+			parsed_lines = append(parsed_lines, key+" "+val)
+			return nil
+		}}
+		if err := p.ParseFileWithCache(conf_file, cache_dir); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, parsed_lines); diff != "" {
+			t.Fatalf("Unexpected parsed config values:\n%s", diff)
+		}
+	}
+	cache_path := parsed_config_cache_path(cache_dir, conf_file)
+	parse("a one", "b two")
+	if _, err := os.Stat(cache_path); err != nil {
+		t.Fatalf("The parsed config was not cached: %s", err)
+	}
+	// values from the cache are used when the files are unchanged
+	raw, _ := os.ReadFile(cache_path)
+	os.WriteFile(cache_path, bytes.Replace(raw, []byte(`"two"`), []byte(`"cached"`), 1), 0o600)
+	parse("a one", "b cached")
+
+	os.WriteFile(filepath.Join(tdir, "sub/b.conf"), []byte("b three"), 0o600)
+	parse("a one", "b three")
+	os.WriteFile(filepath.Join(tdir, "sub/c1.conf"), []byte("c four"), 0o600)
+	parse("a one", "b three", "c four")
+
+	os.WriteFile(conf_file, []byte("a one\npassword cmd:echo secret"), 0o600)
+	os.Remove(cache_path)
+	parse("a one", "password secret")
+	if _, err := os.Stat(cache_path); err == nil {
+		t.Fatalf("A config with a value from a command was cached")
+	}
+}
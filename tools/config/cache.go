@@ -0,0 +1,140 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// Increment when the format of the cache changes
+const parsed_config_cache_version = 1
+
+// The directory in which parsed config files are cached, shared by all kittens
+func ParsedConfigCacheDir() string {
+	return filepath.Join(utils.CacheDir(), "parsed-config")
+}
+
+type file_signature struct {
+	Path    string `json:"path"`
+	Mtime   int64  `json:"mtime"`
+	Size    int64  `json:"size"`
+	Missing bool   `json:"missing,omitempty"`
+}
+
+func signature_of(path string) file_signature {
+	ans := file_signature{Path: path}
+	if s, err := os.Stat(path); err == nil {
+		ans.Mtime, ans.Size = s.ModTime().UnixNano(), s.Size()
+	} else {
+		ans.Missing = true
+	}
+	return ans
+}
+
+// The key, value pairs from parsing a config file and all the files it
+// includes, along with the signatures of those files, used to check that the
+// cache is still valid
+type parsed_config struct {
+	Version int              `json:"version"`
+	Path    string           `json:"path"`
+	Files   []file_signature `json:"files"`
+	Lines   [][2]string      `json:"lines"`
+}
+
+func (self *parsed_config) is_valid(path string) bool {
+	if self.Version != parsed_config_cache_version || self.Path != path || len(self.Files) == 0 {
+		return false
+	}
+	for _, f := range self.Files {
+		if signature_of(f.Path) != f {
+			return false
+		}
+	}
+	return true
+}
+
+// Records what is read while parsing so that it can be cached
+type parse_recorder struct {
+	files       []file_signature
+	lines       [][2]string
+	uncacheable bool
+}
+
+func (self *ConfigParser) note_file(path string) {
+	if self.recorder != nil {
+		self.recorder.files = append(self.recorder.files, signature_of(path))
+	}
+}
+
+// Note that the result of parsing depends on something other than the
+// contents of the files read, such as the environment or the output of
+// commands, and so must not be cached
+func (self *ConfigParser) note_uncacheable() {
+	if self.recorder != nil {
+		self.recorder.uncacheable = true
+	}
+}
+
+func (self *ConfigParser) note_line(key, val string) {
+	if self.recorder != nil {
+		self.recorder.lines = append(self.recorder.lines, [2]string{key, val})
+	}
+}
+
+func parsed_config_cache_path(cache_dir, path string) string {
+	h := sha256.Sum256(utils.UnsafeStringToBytes(path))
+	return filepath.Join(cache_dir, hex.EncodeToString(h[:16])+".json")
+}
+
+// Parse the specified config file like ParseFiles(), using a cache of the
+// parsed key, value pairs in cache_dir. The cache is used when none of the
+// files read while parsing have changed since it was created, in which case
+// the files are not parsed at all, only LineHandler is called. Config files
+// that use envinclude or resolve values from commands or the keyring are
+// never cached. An empty cache_dir means do not use a cache.
+func (self *ConfigParser) ParseFileWithCache(path, cache_dir string) error {
+	if apath, err := filepath.Abs(path); err == nil {
+		path = apath
+	}
+	if cache_dir == "" || self.CommentsHandler != nil || self.SourceHandler != nil {
+		return self.ParseFiles(path)
+	}
+	cache_path := parsed_config_cache_path(cache_dir, path)
+	if raw, err := os.ReadFile(cache_path); err == nil {
+		var pc parsed_config
+		if json.Unmarshal(raw, &pc) == nil && pc.is_valid(path) {
+			for _, kv := range pc.Lines {
+				if err := self.LineHandler(kv[0], kv[1]); err != nil {
+					self.bad_lines = append(self.bad_lines, ConfigLine{Src_file: path, Line: strings.TrimSpace(kv[0] + " " + kv[1]), Err: err})
+				}
+			}
+			return nil
+		}
+	}
+	self.recorder = &parse_recorder{}
+	defer func() { self.recorder = nil }()
+	num_bad_lines := len(self.bad_lines)
+	if err := self.ParseFiles(path); err != nil {
+		return err
+	}
+	if r := self.recorder; !r.uncacheable && len(self.bad_lines) == num_bad_lines {
+		pc := parsed_config{Version: parsed_config_cache_version, Path: path, Files: r.files, Lines: r.lines}
+		if raw, err := json.Marshal(&pc); err == nil {
+			if err = os.MkdirAll(cache_dir, 0o700); err == nil {
+				// the config may contain sensitive values
+				utils.AtomicUpdateFile(cache_path, raw, 0o600)
+			}
+		}
+	}
+	return nil
+}
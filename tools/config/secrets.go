@@ -0,0 +1,99 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"kitty/tools/utils/shlex"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+// Run a command and return its output, with trailing newlines removed
+func output_of(name string, args ...string) (string, error) {
+	c := exec.Command(name, args...)
+	stderr := bytes.Buffer{}
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", fmt.Errorf("Running %s failed with error: %w", name, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+func secret_from_command(cmdline string) (string, error) {
+	argv, err := shlex.Split(cmdline)
+	if err != nil {
+		return "", fmt.Errorf("The command %#v is invalid with error: %w", cmdline, err)
+	}
+	if len(argv) == 0 {
+		return "", errors.New("No command specified to get the value from")
+	}
+	return output_of(argv[0], argv[1:]...)
+}
+
+// Lookup a secret in the OS keyring. The spec is of the form service/account,
+// the account being optional.
+func secret_from_keyring(spec string) (string, error) {
+	service, account, _ := strings.Cut(spec, "/")
+	if service == "" {
+		return "", errors.New("No service specified for the keyring lookup")
+	}
+	if runtime.GOOS == "darwin" {
+		args := []string{"find-generic-password", "-w", "-s", service}
+		if account != "" {
+			args = append(args, "-a", account)
+		}
+		return output_of("/usr/bin/security", args...)
+	}
+	args := []string{"lookup", "service", service}
+	if account != "" {
+		args = append(args, "account", account)
+	}
+	return output_of("secret-tool", args...)
+}
+
+// Resolve values of the form cmd:some command or keyring:service/account to
+// the output of the command or the secret stored in the OS keyring, so that
+// secrets such as passwords do not need to be stored in config files. Only
+// values of keys in SecretKeys are resolved, other values are returned
+// unchanged.
+func (self *ConfigParser) resolve_secret(key, val string) (string, error) {
+	if !slices.Contains(self.SecretKeys, key) {
+		return val, nil
+	}
+	kind, spec, found := strings.Cut(val, ":")
+	if !found || (kind != "cmd" && kind != "keyring") {
+		return val, nil
+	}
+	self.note_uncacheable()
+	if ans, ok := self.resolved_secrets[val]; ok {
+		return ans, nil
+	}
+	var ans string
+	var err error
+	if kind == "cmd" {
+		ans, err = secret_from_command(spec)
+	} else {
+		ans, err = secret_from_keyring(spec)
+	}
+	if err != nil {
+		return "", err
+	}
+	if self.resolved_secrets == nil {
+		self.resolved_secrets = make(map[string]string)
+	}
+	self.resolved_secrets[val] = ans
+	return ans, nil
+}
@@ -311,3 +311,48 @@ func ResolveShortcuts(actions []*KeyAction) []*KeyAction {
 	}
 	return maps.Values(action_map)
 }
+
+// Information about a configuration option, generated from its definition
+type OptionMetadata struct {
+	Name string
+	// The Go type of the field corresponding to the option in Config
+	Type string
+	// The default value as it would appear in a conf file. For options that
+	// can be specified multiple times, the default values, one per line.
+	Default string
+	// The valid values for options that accept a fixed set of values
+	Choices []string
+	// Whether the option can be specified multiple times
+	IsMultiple bool
+}
+
+type OptionsMetadata []OptionMetadata
+
+// Find the metadata for the named option, returns nil if no such option exists
+func (self OptionsMetadata) Find(name string) *OptionMetadata {
+	for i, x := range self {
+		if x.Name == name {
+			return &self[i]
+		}
+	}
+	return nil
+}
+
+// An error for an option name that does not exist, suggesting similarly
+// named options, if any
+func (self OptionsMetadata) UnknownKeyError(key string) error {
+	suggestions := []string{}
+	for _, x := range self {
+		if utils.LevenshteinDistance(x.Name, key, true) <= 2 {
+			suggestions = append(suggestions, x.Name)
+		}
+	}
+	if len(suggestions) > 0 {
+		return fmt.Errorf("Unknown configuration key: %#v, did you mean: %s", key, strings.Join(suggestions, ", "))
+	}
+	return fmt.Errorf("Unknown configuration key: %#v", key)
+}
+
+func (self OptionsMetadata) Names() []string {
+	return utils.Map(func(x OptionMetadata) string { return x.Name }, self)
+}
@@ -42,3 +42,16 @@ func TestNormalizeShortcuts(t *testing.T) {
 		}
 	}
 }
+
+func TestUnknownKeyError(t *testing.T) {
+	m := OptionsMetadata{{Name: "askpass"}, {Name: "share_connections"}, {Name: "env", IsMultiple: true}}
+	for q, expected := range map[string]string{
+		"askpas":        `Unknown configuration key: "askpas", did you mean: askpass`,
+		"ENV":           `Unknown configuration key: "ENV", did you mean: env`,
+		"something_odd": `Unknown configuration key: "something_odd"`,
+	} {
+		if actual := m.UnknownKeyError(q).Error(); actual != expected {
+			t.Fatalf("Failed with input: %#v\n%#v != %#v", q, expected, actual)
+		}
+	}
+}
@@ -54,7 +54,29 @@ func b85_decode(data string) (decoded []byte, err error) {
 	return
 }
 
-func encrypt(plaintext []byte, alice_public_key []byte, encryption_protocol string) (iv []byte, tag []byte, ciphertext []byte, bob_public_key []byte, err error) {
+// An EncryptionSession uses a single key pair, and the secret it shares with
+// kitty, to encrypt any number of messages, so that only the first message
+// pays for key generation and exchange.
+type EncryptionSession struct {
+	encryption_protocol string
+	encoded_pubkey      string
+	shared_secret       []byte
+	aesgcm              cipher.AEAD
+}
+
+func new_encryption_session(encryption_protocol, encoded_pubkey string, shared_secret []byte) (*EncryptionSession, error) {
+	block, err := aes.NewCipher(shared_secret)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptionSession{encryption_protocol: encryption_protocol, encoded_pubkey: encoded_pubkey, shared_secret: shared_secret, aesgcm: aesgcm}, nil
+}
+
+func NewEncryptionSession(alice_public_key []byte, encryption_protocol string) (ans *EncryptionSession, err error) {
 	bob_private_key, bob_public_key, err := KeyPair(encryption_protocol)
 	if err != nil {
 		return
@@ -64,26 +86,77 @@ func encrypt(plaintext []byte, alice_public_key []byte, encryption_protocol stri
 		return
 	}
 	shared_secret_hashed := sha256.Sum256(shared_secret_raw)
-	shared_secret := shared_secret_hashed[:]
-	block, err := aes.NewCipher(shared_secret)
+	return new_encryption_session(encryption_protocol, b85_encode(bob_public_key), shared_secret_hashed[:])
+}
+
+type serialized_encryption_session struct {
+	Protocol     string `json:"protocol"`
+	Pubkey       string `json:"pubkey"`
+	SharedSecret string `json:"shared_secret"`
+}
+
+// Serialize the session so that it can be re-used by other processes. The
+// result contains the secret shared with kitty, so it must be stored where
+// only the current user can read it.
+func (self *EncryptionSession) Serialize() ([]byte, error) {
+	return json.Marshal(serialized_encryption_session{Protocol: self.encryption_protocol, Pubkey: self.encoded_pubkey, SharedSecret: b85_encode(self.shared_secret)})
+}
+
+func DeserializeEncryptionSession(data []byte) (*EncryptionSession, error) {
+	var s serialized_encryption_session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Protocol != "1" {
+		return nil, fmt.Errorf("Unknown encryption protocol: %s", s.Protocol)
+	}
+	shared_secret, err := b85_decode(s.SharedSecret)
 	if err != nil {
-		return
+		return nil, err
 	}
-	aesgcm, err := cipher.NewGCM(block)
+	return new_encryption_session(s.Protocol, s.Pubkey, shared_secret)
+}
+
+func (self *EncryptionSession) encrypt(plaintext []byte) (ans utils.EncryptedRemoteControlCmd, err error) {
+	iv := make([]byte, self.aesgcm.NonceSize())
+	_, err = rand.Read(iv)
 	if err != nil {
 		return
 	}
-	iv = make([]byte, aesgcm.NonceSize())
-	_, err = rand.Read(iv)
+	output := self.aesgcm.Seal(nil, iv, plaintext, nil)
+	ciphertext := output[0 : len(output)-16]
+	tag := output[len(output)-16:]
+	ans = utils.EncryptedRemoteControlCmd{IV: b85_encode(iv), Tag: b85_encode(tag), Pubkey: self.encoded_pubkey, Encrypted: b85_encode(ciphertext)}
+	if self.encryption_protocol != "1" {
+		ans.EncProto = self.encryption_protocol
+	}
+	return
+}
+
+func (self *EncryptionSession) EncryptCmd(cmd *utils.RemoteControlCmd, password string) (encrypted_cmd utils.EncryptedRemoteControlCmd, err error) {
+	cmd.Password = password
+	cmd.Timestamp = time.Now().UnixNano()
+	plaintext, err := json.Marshal(cmd)
 	if err != nil {
 		return
 	}
-	output := aesgcm.Seal(nil, iv, plaintext, nil)
-	ciphertext = output[0 : len(output)-16]
-	tag = output[len(output)-16:]
+	if encrypted_cmd, err = self.encrypt(plaintext); err == nil {
+		encrypted_cmd.Version = cmd.Version
+	}
 	return
 }
 
+func (self *EncryptionSession) EncryptData(data []byte) (ans []byte, err error) {
+	d := make([]byte, 0, len(data)+32)
+	d = append(d, []byte(fmt.Sprintf("%s:", strconv.FormatInt(time.Now().UnixNano(), 10)))...)
+	d = append(d, data...)
+	ec, err := self.encrypt(d)
+	if err != nil {
+		return
+	}
+	return json.Marshal(ec)
+}
+
 func KeyPair(encryption_protocol string) (private_key []byte, public_key []byte, err error) {
 	switch encryption_protocol {
 	case "1":
@@ -115,36 +188,19 @@ func DecodePublicKey(raw string) (encryption_protocol string, pubkey []byte, err
 }
 
 func Encrypt_cmd(cmd *utils.RemoteControlCmd, password string, other_pubkey []byte, encryption_protocol string) (encrypted_cmd utils.EncryptedRemoteControlCmd, err error) {
-	cmd.Password = password
-	cmd.Timestamp = time.Now().UnixNano()
-	plaintext, err := json.Marshal(cmd)
+	session, err := NewEncryptionSession(other_pubkey, encryption_protocol)
 	if err != nil {
 		return
 	}
-	iv, tag, ciphertext, pubkey, err := encrypt(plaintext, other_pubkey, encryption_protocol)
-	encrypted_cmd = utils.EncryptedRemoteControlCmd{
-		Version: cmd.Version, IV: b85_encode(iv), Tag: b85_encode(tag), Pubkey: b85_encode(pubkey), Encrypted: b85_encode(ciphertext)}
-	if encryption_protocol != "1" {
-		encrypted_cmd.EncProto = encryption_protocol
-	}
-	return
+	return session.EncryptCmd(cmd, password)
 }
 
 func Encrypt_data(data []byte, other_pubkey []byte, encryption_protocol string) (ans []byte, err error) {
-	d := make([]byte, 0, len(data)+32)
-	d = append(d, []byte(fmt.Sprintf("%s:", strconv.FormatInt(time.Now().UnixNano(), 10)))...)
-	d = append(d, data...)
-	iv, tag, ciphertext, pubkey, err := encrypt(d, other_pubkey, encryption_protocol)
+	session, err := NewEncryptionSession(other_pubkey, encryption_protocol)
 	if err != nil {
 		return
 	}
-	ec := utils.EncryptedRemoteControlCmd{
-		IV: b85_encode(iv), Tag: b85_encode(tag), Pubkey: b85_encode(pubkey), Encrypted: b85_encode(ciphertext)}
-	if encryption_protocol != "1" {
-		ec.EncProto = encryption_protocol
-	}
-	ans, err = json.Marshal(ec)
-	return
+	return session.EncryptData(data)
 }
 
 // }}}
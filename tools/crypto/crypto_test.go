@@ -0,0 +1,89 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+func TestEncryptionSession(t *testing.T) {
+	private_key, public_key, err := KeyPair("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := NewEncryptionSession(public_key, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypt := func(ec utils.EncryptedRemoteControlCmd) (ans utils.RemoteControlCmd) {
+		t.Helper()
+		pubkey, err := b85_decode(ec.Pubkey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret, err := curve25519_derive_shared_secret(private_key, pubkey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := sha256.Sum256(secret)
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		aesgcm, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		iv, _ := b85_decode(ec.IV)
+		tag, _ := b85_decode(ec.Tag)
+		ciphertext, _ := b85_decode(ec.Encrypted)
+		plaintext, err := aesgcm.Open(nil, iv, append(ciphertext, tag...), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(plaintext, &ans); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	var encrypted []utils.EncryptedRemoteControlCmd
+	for _, name := range []string{"ls", "set-font-size"} {
+		ec, err := session.EncryptCmd(&utils.RemoteControlCmd{Cmd: name, Version: [3]int{0, 26, 0}}, "pw")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := decrypt(ec); actual.Cmd != name || actual.Password != "pw" {
+			t.Fatalf("Decrypted command incorrect: %#v", actual)
+		}
+		encrypted = append(encrypted, ec)
+	}
+	if encrypted[0].Pubkey != encrypted[1].Pubkey {
+		t.Fatalf("The session key was not re-used")
+	}
+	if encrypted[0].IV == encrypted[1].IV {
+		t.Fatalf("The IV was re-used")
+	}
+	serialized, err := session.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session, err = DeserializeEncryptionSession(serialized); err != nil {
+		t.Fatal(err)
+	}
+	ec, err := session.EncryptCmd(&utils.RemoteControlCmd{Cmd: "ls"}, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := decrypt(ec); actual.Cmd != "ls" || ec.Pubkey != encrypted[0].Pubkey {
+		t.Fatalf("Deserialized session did not encrypt correctly: %#v", actual)
+	}
+}
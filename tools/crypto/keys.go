@@ -0,0 +1,322 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var _ = fmt.Print
+
+// Existing keys can be used as the key material for remote control
+// encryption. age X25519 identities and recipients are used as is, OpenSSH
+// ed25519 keys are converted to the equivalent X25519 keys, the same way age
+// does for SSH recipients.
+
+const bech32_charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32_polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32_hrp_expand(hrp string) []byte {
+	ans := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		ans = append(ans, hrp[i]>>5)
+	}
+	ans = append(ans, 0)
+	for i := 0; i < len(hrp); i++ {
+		ans = append(ans, hrp[i]&31)
+	}
+	return ans
+}
+
+// Regroup bits, for conversion between 8 and 5 bit groups
+func convert_bits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<to - 1
+	ans := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		if uint32(v)>>from != 0 {
+			return nil, fmt.Errorf("Invalid data for bit conversion")
+		}
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			ans = append(ans, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ans = append(ans, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, fmt.Errorf("Invalid padding in bech32 data")
+	}
+	return ans, nil
+}
+
+func bech32_decode(s string) (hrp string, data []byte, err error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("Mixed case bech32 string")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, fmt.Errorf("Invalid bech32 string: separator missing or checksum too short")
+	}
+	hrp = s[:pos]
+	values := make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		idx := strings.IndexByte(bech32_charset, s[i])
+		if idx < 0 {
+			return "", nil, fmt.Errorf("Invalid character in bech32 string: %#v", string(s[i]))
+		}
+		values = append(values, byte(idx))
+	}
+	if bech32_polymod(append(bech32_hrp_expand(hrp), values...)) != 1 {
+		return "", nil, fmt.Errorf("Invalid bech32 checksum")
+	}
+	data, err = convert_bits(values[:len(values)-6], 5, 8, false)
+	return
+}
+
+func bech32_encode(hrp string, data []byte) (string, error) {
+	values, err := convert_bits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	hrp = strings.ToLower(hrp)
+	mod := bech32_polymod(append(append(bech32_hrp_expand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	b := strings.Builder{}
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(bech32_charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32_charset[(mod>>(5*(5-i)))&31])
+	}
+	return b.String(), nil
+}
+
+const (
+	AGE_IDENTITY_HRP  = "AGE-SECRET-KEY-"
+	AGE_RECIPIENT_HRP = "age"
+)
+
+func parse_bech32_key(s, expected_hrp string) ([]byte, error) {
+	hrp, data, err := bech32_decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != strings.ToLower(expected_hrp) {
+		return nil, fmt.Errorf("Not an age key, unknown type: %s", hrp)
+	}
+	if len(data) != 32 {
+		return nil, fmt.Errorf("age key has incorrect length: %d", len(data))
+	}
+	return data, nil
+}
+
+// Encode an X25519 public key as an age recipient
+func EncodeAgeRecipient(pubkey []byte) (string, error) {
+	return bech32_encode(AGE_RECIPIENT_HRP, pubkey)
+}
+
+// The X25519 private key for an ed25519 private key seed
+func ed25519_to_x25519_private_key(seed []byte) []byte {
+	h := sha512.Sum512(seed)
+	return h[:32]
+}
+
+var curve25519_p = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// The X25519 public key for an ed25519 public key, using the birational map
+// from the Edwards curve to the Montgomery curve: u = (1 + y) / (1 - y)
+func ed25519_to_x25519_public_key(pubkey []byte) ([]byte, error) {
+	if len(pubkey) != 32 {
+		return nil, fmt.Errorf("ed25519 public key has incorrect length: %d", len(pubkey))
+	}
+	le_to_be := func(b []byte) []byte {
+		ans := make([]byte, len(b))
+		for i, x := range b {
+			ans[len(b)-1-i] = x
+		}
+		return ans
+	}
+	be := le_to_be(pubkey)
+	be[0] &= 0x7f // the sign bit of x
+	y := new(big.Int).SetBytes(be)
+	if y.Cmp(curve25519_p) >= 0 {
+		return nil, fmt.Errorf("Invalid ed25519 public key")
+	}
+	one := big.NewInt(1)
+	denominator := new(big.Int).Sub(one, y)
+	denominator.Mod(denominator, curve25519_p)
+	if denominator.Sign() == 0 {
+		return nil, fmt.Errorf("Invalid ed25519 public key")
+	}
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, denominator.ModInverse(denominator, curve25519_p))
+	u.Mod(u, curve25519_p)
+	ans := le_to_be(u.FillBytes(make([]byte, 32)))
+	return ans, nil
+}
+
+type ssh_reader struct {
+	data []byte
+	err  error
+}
+
+func (self *ssh_reader) uint32() uint32 {
+	if self.err != nil {
+		return 0
+	}
+	if len(self.data) < 4 {
+		self.err = fmt.Errorf("SSH key data truncated")
+		return 0
+	}
+	ans := binary.BigEndian.Uint32(self.data)
+	self.data = self.data[4:]
+	return ans
+}
+
+func (self *ssh_reader) string() []byte {
+	n := self.uint32()
+	if self.err != nil {
+		return nil
+	}
+	if uint32(len(self.data)) < n {
+		self.err = fmt.Errorf("SSH key data truncated")
+		return nil
+	}
+	ans := self.data[:n]
+	self.data = self.data[n:]
+	return ans
+}
+
+func parse_ssh_public_key(line string) ([]byte, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("Invalid SSH public key")
+	}
+	if fields[0] != "ssh-ed25519" {
+		return nil, fmt.Errorf("Unsupported SSH key type: %s only ssh-ed25519 keys are supported", fields[0])
+	}
+	raw, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SSH public key: %w", err)
+	}
+	r := ssh_reader{data: raw}
+	key_type, key := r.string(), r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if string(key_type) != "ssh-ed25519" {
+		return nil, fmt.Errorf("Unsupported SSH key type: %s", key_type)
+	}
+	return ed25519_to_x25519_public_key(key)
+}
+
+func parse_openssh_private_key(block *pem.Block) ([]byte, error) {
+	const magic = "openssh-key-v1\x00"
+	if !bytes.HasPrefix(block.Bytes, []byte(magic)) {
+		return nil, fmt.Errorf("Invalid OpenSSH private key")
+	}
+	r := ssh_reader{data: block.Bytes[len(magic):]}
+	cipher_name, _, _ := r.string(), r.string(), r.string()
+	num_keys := r.uint32()
+	_ = r.string()
+	private_keys := ssh_reader{data: r.string()}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if string(cipher_name) != "none" {
+		return nil, fmt.Errorf("Passphrase protected SSH private keys are not supported")
+	}
+	if num_keys != 1 {
+		return nil, fmt.Errorf("OpenSSH private key files with %d keys are not supported", num_keys)
+	}
+	if check1, check2 := private_keys.uint32(), private_keys.uint32(); check1 != check2 {
+		return nil, fmt.Errorf("Corrupted OpenSSH private key")
+	}
+	key_type := private_keys.string()
+	_ = private_keys.string()
+	key := private_keys.string()
+	if private_keys.err != nil {
+		return nil, private_keys.err
+	}
+	if string(key_type) != "ssh-ed25519" {
+		return nil, fmt.Errorf("Unsupported SSH key type: %s only ssh-ed25519 keys are supported", key_type)
+	}
+	if len(key) != 64 {
+		return nil, fmt.Errorf("ed25519 private key has incorrect length: %d", len(key))
+	}
+	return ed25519_to_x25519_private_key(key[:32]), nil
+}
+
+// Parse the contents of a file containing an age identity, as created by
+// age-keygen, or an unencrypted OpenSSH ed25519 private key, returning the
+// equivalent X25519 private key. Identities for age plugins, such as those
+// for hardware keys, are not supported as their private keys are not
+// available.
+func ParsePrivateKey(data []byte) (private_key []byte, err error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "OPENSSH PRIVATE KEY" {
+			return nil, fmt.Errorf("Unsupported private key type: %s", block.Type)
+		}
+		return parse_openssh_private_key(block)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "AGE-PLUGIN-") {
+			return nil, fmt.Errorf("age plugin identities are not supported")
+		}
+		return parse_bech32_key(line, AGE_IDENTITY_HRP)
+	}
+	return nil, fmt.Errorf("No private key found")
+}
+
+// Parse an age recipient or an ssh-ed25519 public key, in authorized_keys
+// format, returning the equivalent X25519 public key.
+func ParsePublicKey(raw string) (public_key []byte, err error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "ssh-") {
+		return parse_ssh_public_key(raw)
+	}
+	return parse_bech32_key(raw, AGE_RECIPIENT_HRP)
+}
+
+// The X25519 public key corresponding to the specified private key
+func PublicKeyFor(private_key []byte) ([]byte, error) {
+	k, err := ecdh.X25519().NewPrivateKey(private_key)
+	if err != nil {
+		return nil, err
+	}
+	return k.PublicKey().Bytes(), nil
+}
@@ -0,0 +1,113 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestBech32(t *testing.T) {
+	for _, valid := range []string{"A12UEL5L", "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw"} {
+		if _, _, err := bech32_decode(valid); err != nil {
+			t.Fatalf("Failed to decode valid bech32 string %s with error: %s", valid, err)
+		}
+	}
+	for _, invalid := range []string{"A12UEL5X", "A12uEL5L", "12UEL5L", "abc1rzg"} {
+		if _, _, err := bech32_decode(invalid); err == nil {
+			t.Fatalf("Invalid bech32 string %s decoded without error", invalid)
+		}
+	}
+	data := []byte("some data to encode as bech32")
+	encoded, err := bech32_encode("test", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hrp, decoded, err := bech32_decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hrp != "test" || string(decoded) != string(data) {
+		t.Fatalf("bech32 round trip failed: %s %#v", hrp, string(decoded))
+	}
+}
+
+func TestKeyParsing(t *testing.T) {
+	// age keys
+	private_key, public_key, err := KeyPair("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := bech32_encode(AGE_IDENTITY_HRP, private_key)
+	identity = strings.ToUpper(identity)
+	recipient, err := EncodeAgeRecipient(public_key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := ParsePrivateKey([]byte("# created: 2023-08-01T10:00:00Z\n# public key: " + recipient + "\n" + identity + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(private_key, q); diff != "" {
+		t.Fatalf("Incorrect age identity parsing:\n%s", diff)
+	}
+	if q, err = ParsePublicKey(recipient); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(public_key, q); diff != "" {
+		t.Fatalf("Incorrect age recipient parsing:\n%s", diff)
+	}
+	if _, err = ParsePublicKey(identity); err == nil {
+		t.Fatalf("age identity accepted as a public key")
+	}
+
+	// ssh keys
+	ed_public, ed_private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssh_string := func(b []byte) []byte {
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...)
+	}
+	ssh_public := append(ssh_string([]byte("ssh-ed25519")), ssh_string(ed_public)...)
+	private_section := []byte{1, 2, 3, 4, 1, 2, 3, 4}
+	private_section = append(private_section, ssh_public...)
+	private_section = append(private_section, ssh_string(ed_private)...)
+	private_section = append(private_section, ssh_string([]byte("comment"))...)
+	private_section = append(private_section, 1, 2, 3)
+	raw := []byte("openssh-key-v1\x00")
+	for _, x := range []string{"none", "none", ""} {
+		raw = append(raw, ssh_string([]byte(x))...)
+	}
+	raw = binary.BigEndian.AppendUint32(raw, 1)
+	raw = append(raw, ssh_string(ssh_public)...)
+	raw = append(raw, ssh_string(private_section)...)
+	x_private, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: raw}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x_public, err := ParsePublicKey("ssh-ed25519 " + base64.StdEncoding.EncodeToString(ssh_public) + " user@host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := PublicKeyFor(x_private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, x_public); diff != "" {
+		t.Fatalf("Converted ssh public key does not match converted private key:\n%s", diff)
+	}
+	if _, err = ParsePublicKey("ssh-rsa AAAAB3NzaC1yc2E="); err == nil {
+		t.Fatalf("ssh-rsa key accepted")
+	}
+}
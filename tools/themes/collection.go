@@ -540,6 +540,7 @@ type Theme struct {
 	zip_reader                  *zip.File
 	is_user_defined             bool
 	path_for_user_defined_theme string
+	tags                        []string
 }
 
 func (self *Theme) Name() string        { return self.metadata.Name }
@@ -687,7 +688,9 @@ func (self *Theme) SaveInConf(config_dir, reload_in, config_file_name string) (e
 }
 
 func (self *Theme) Settings() (map[string]string, error) {
-	if self.zip_reader != nil {
+	// the code might have been loaded already by Code(), which does not
+	// parse the settings
+	if self.settings == nil {
 		code, err := self.load_code()
 		if err != nil {
 			return nil, err
@@ -0,0 +1,125 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package themes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"kitty/tools/utils"
+	"kitty/tools/utils/style"
+)
+
+var _ = fmt.Print
+
+const (
+	TAG_DARK          = "dark"
+	TAG_LIGHT         = "light"
+	TAG_HIGH_CONTRAST = "high-contrast"
+	TAG_LOW_CONTRAST  = "low-contrast"
+	TAG_VIVID         = "vivid"
+	TAG_MUTED         = "muted"
+)
+
+var AllTags = []string{TAG_DARK, TAG_LIGHT, TAG_HIGH_CONTRAST, TAG_LOW_CONTRAST, TAG_VIVID, TAG_MUTED}
+
+// The WCAG relative luminance of a color
+func relative_luminance(c style.RGBA) float64 {
+	channel := func(x uint8) float64 {
+		v := float64(x) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.Red) + 0.7152*channel(c.Green) + 0.0722*channel(c.Blue)
+}
+
+// The WCAG contrast ratio between two colors, from 1 to 21
+func contrast_ratio(a, b style.RGBA) float64 {
+	la, lb := relative_luminance(a), relative_luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// The chroma of a color, from 0 for grays to 1 for fully saturated colors
+func chroma(c style.RGBA) float64 {
+	return float64(utils.Max(c.Red, c.Green, c.Blue)-utils.Min(c.Red, c.Green, c.Blue)) / 255
+}
+
+func tags_for_settings(settings map[string]string, is_dark bool) (ans []string) {
+	color := func(key, defval string) style.RGBA {
+		if val := strings.TrimSpace(settings[key]); val != "" {
+			if c, err := style.ParseColor(val); err == nil {
+				return c
+			}
+		}
+		c, _ := style.ParseColor(defval)
+		return c
+	}
+	if is_dark {
+		ans = append(ans, TAG_DARK)
+	} else {
+		ans = append(ans, TAG_LIGHT)
+	}
+	switch cr := contrast_ratio(color("foreground", style.DefaultColors.Foreground), color("background", style.DefaultColors.Background)); {
+	case cr >= 12:
+		ans = append(ans, TAG_HIGH_CONTRAST)
+	case cr < 7:
+		ans = append(ans, TAG_LOW_CONTRAST)
+	}
+	// the average chroma of the non-gray colors of the 16 color palette
+	total := 0.
+	for _, i := range []int{1, 2, 3, 4, 5, 6, 9, 10, 11, 12, 13, 14} {
+		def := style.RGBA{}
+		def.FromRGB(style.ColorTable[i])
+		total += chroma(color("color"+strconv.Itoa(i), def.AsRGBSharp()))
+	}
+	switch avg := total / 12; {
+	case avg >= 0.55:
+		ans = append(ans, TAG_VIVID)
+	case avg < 0.3:
+		ans = append(ans, TAG_MUTED)
+	}
+	return
+}
+
+// Tags describing the theme: dark or light, high or low contrast between the
+// foreground and background and vivid or muted colors. Computing the tags
+// requires loading the theme code.
+func (self *Theme) Tags() []string {
+	if self.tags == nil {
+		settings, err := self.Settings()
+		if err != nil {
+			settings = nil
+		}
+		self.tags = tags_for_settings(settings, self.IsDark())
+	}
+	return self.tags
+}
+
+// Whether the theme has tags starting with every one of the specified prefixes
+func (self *Theme) MatchesTags(prefixes ...string) bool {
+	if len(prefixes) == 0 {
+		// avoid loading the theme code to compute the tags
+		return true
+	}
+	tags := self.Tags()
+	for _, p := range prefixes {
+		found := false
+		for _, t := range tags {
+			if strings.HasPrefix(t, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package themes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestThemeTags(t *testing.T) {
+	palette := func(colors ...string) map[string]string {
+		ans := map[string]string{}
+		for i, n := range []int{1, 2, 3, 4, 5, 6, 9, 10, 11, 12, 13, 14} {
+			ans[fmt.Sprintf("color%d", n)] = colors[i%len(colors)]
+		}
+		return ans
+	}
+	tt := func(settings map[string]string, is_dark bool, expected ...string) {
+		if diff := cmp.Diff(expected, tags_for_settings(settings, is_dark)); diff != "" {
+			t.Fatalf("Unexpected tags for %v:\n%s", settings, diff)
+		}
+	}
+	s := palette("#ff0000", "#00ff00", "#0000ff")
+	s["foreground"], s["background"] = "#ffffff", "#000000"
+	tt(s, true, TAG_DARK, TAG_HIGH_CONTRAST, TAG_VIVID)
+	s = palette("#807070", "#708070")
+	s["foreground"], s["background"] = "#777777", "#eeeeee"
+	tt(s, false, TAG_LIGHT, TAG_LOW_CONTRAST, TAG_MUTED)
+	s = palette("#c04040", "#40c040", "#4040c0")
+	s["foreground"], s["background"] = "#cccccc", "#1c1c1c"
+	tt(s, true, TAG_DARK)
+
+	p := filepath.Join(t.TempDir(), "user.conf")
+	conf := "## name: User\nforeground #777777\nbackground #eeeeee\n"
+	for k, v := range palette("#807070", "#708070") {
+		conf += k + " " + v + "\n"
+	}
+	if err := os.WriteFile(p, []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, load_code_first := range []bool{false, true} {
+		theme, err := ThemeFromFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		theme.settings = nil
+		if load_code_first {
+			if _, err = theme.Code(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if diff := cmp.Diff([]string{TAG_LIGHT, TAG_LOW_CONTRAST, TAG_MUTED}, theme.Tags()); diff != "" {
+			t.Fatalf("Unexpected tags for user theme:\n%s", diff)
+		}
+	}
+
+	theme := &Theme{metadata: &ThemeMetadata{Is_dark: true}, settings: palette("#ff0000")}
+	if !theme.MatchesTags("dark", "viv") || theme.MatchesTags("light") || !theme.MatchesTags() {
+		t.Fatalf("Tag matching failed for theme with tags: %v", theme.Tags())
+	}
+}
@@ -0,0 +1,153 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tty
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var _ = fmt.Print
+
+// Query the size of the text area in pixels and the primary device
+// attributes. Every terminal responds to the latter, so its response marks the
+// end of the responses, even if the terminal does not support the former.
+const pixel_size_query = "\x1b[14t\x1b[c"
+
+// Parse the response to CSI 14 t which is of the form CSI 4 ; height ; width t
+// returning false if data does not contain a complete response
+func parse_pixel_size_report(data []byte) (width, height int, found bool) {
+	for len(data) > 0 {
+		idx := bytes.Index(data, []byte("\x1b[4;"))
+		if idx < 0 {
+			return
+		}
+		data = data[idx+4:]
+		end := bytes.IndexByte(data, 't')
+		if end < 0 {
+			return
+		}
+		h, w, ok := bytes.Cut(data[:end], []byte{';'})
+		if ok {
+			hv, herr := strconv.Atoi(string(h))
+			wv, werr := strconv.Atoi(string(w))
+			if herr == nil && werr == nil && hv >= 0 && wv >= 0 {
+				return wv, hv, true
+			}
+		}
+		data = data[end+1:]
+	}
+	return
+}
+
+// Whether data contains the terminal's response to the primary device
+// attributes query, which is of the form CSI ? ... c
+func has_device_attributes_response(data []byte) bool {
+	for len(data) > 0 {
+		idx := bytes.Index(data, []byte("\x1b[?"))
+		if idx < 0 {
+			return false
+		}
+		data = data[idx+3:]
+		for i, ch := range data {
+			if ch == 'c' {
+				return true
+			}
+			if (ch < '0' || ch > '9') && ch != ';' {
+				data = data[i:]
+				break
+			}
+		}
+	}
+	return false
+}
+
+// Query the terminal for the size of its text area in pixels using the CSI 14
+// t escape code, waiting at most timeout for a response. Any other input
+// received while waiting is discarded.
+func (self *Term) QueryPixelSize(timeout time.Duration) (width, height int, err error) {
+	if err = self.ApplyOperations(TCSANOW, SetRaw, SetNoEcho); err != nil {
+		return
+	}
+	defer self.PopStateWhen(TCSANOW)
+	if err = self.WriteAllString(pixel_size_query); err != nil {
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	data := make([]byte, 0, 256)
+	buf := make([]byte, 256)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return 0, 0, fmt.Errorf("Timed out waiting for the terminal to report its size in pixels")
+		}
+		n, rerr := self.ReadWithTimeout(buf, remaining)
+		if rerr != nil {
+			if errors.Is(rerr, unix.EAGAIN) || errors.Is(rerr, unix.EINTR) || errors.Is(rerr, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return 0, 0, rerr
+		}
+		data = append(data, buf[:n]...)
+		if has_device_attributes_response(data) {
+			if width, height, found := parse_pixel_size_report(data); found {
+				return width, height, nil
+			}
+			return 0, 0, fmt.Errorf("The terminal does not support reporting its size in pixels")
+		}
+	}
+}
+
+// Get the size of the terminal, like GetSize(), except that when the kernel
+// does not know the size in pixels, the terminal is queried for it, waiting
+// at most timeout for a response.
+func (self *Term) GetSizeWithPixels(timeout time.Duration) (*unix.Winsize, error) {
+	sz, err := self.GetSize()
+	if err != nil || (sz.Xpixel > 0 && sz.Ypixel > 0) {
+		return sz, err
+	}
+	w, h, err := self.QueryPixelSize(timeout)
+	if err != nil {
+		// the size in cells is still useful
+		return sz, nil
+	}
+	sz.Xpixel, sz.Ypixel = uint16(w), uint16(h)
+	return sz, nil
+}
+
+// Set the size of the terminal in pixels, leaving the size in cells
+// unchanged. This causes the kernel to send SIGWINCH to the foreground
+// process group of the terminal, which is how programs are informed of the
+// new size.
+func SetSizeInPixels(fd int, width, height int) error {
+	sz, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return err
+	}
+	sz.Xpixel, sz.Ypixel = uint16(width), uint16(height)
+	return SetSize(fd, sz)
+}
+
+// Call callback with the new size of the terminal, as reported by the kernel,
+// whenever it is resized. The callback is called in a separate goroutine. Call
+// the returned function to stop watching for resizes.
+func (self *Term) NotifyOnResize(callback func(sz *unix.Winsize, err error)) (stop func()) {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, unix.SIGWINCH)
+	go func() {
+		for range resized {
+			callback(self.GetSize())
+		}
+	}()
+	return func() {
+		signal.Stop(resized)
+		close(resized)
+	}
+}
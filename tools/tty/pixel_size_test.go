@@ -0,0 +1,28 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tty
+
+import (
+	"fmt"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestPixelSizeReport(t *testing.T) {
+	test := func(raw string, width, height int, found, has_da bool) {
+		w, h, f := parse_pixel_size_report([]byte(raw))
+		if w != width || h != height || f != found {
+			t.Fatalf("Failed to parse pixel size from: %#v got: %d %d %v", raw, w, h, f)
+		}
+		if q := has_device_attributes_response([]byte(raw)); q != has_da {
+			t.Fatalf("Device attributes response presence in %#v incorrect: %v", raw, q)
+		}
+	}
+	test("", 0, 0, false, false)
+	test("\x1b[4;600;800t\x1b[?62;c", 800, 600, true, true)
+	test("x\x1b[4;600t\x1b[4;1;2t", 2, 1, true, false)
+	test("\x1b[4;600;80", 0, 0, false, false)
+	test("\x1b[?1;2", 0, 0, false, false)
+	test("\x1b[?1x\x1b[?c", 0, 0, false, true)
+}
@@ -0,0 +1,37 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tty
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func open_pty() (master *os.File, slave_path string, err error) {
+	fd, err := eintr_retry_intret(func() (int, error) {
+		return unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	})
+	if err != nil {
+		return nil, "", &os.PathError{Op: "open", Path: "/dev/ptmx", Err: err}
+	}
+	fail := func(op string, err error) (*os.File, string, error) {
+		unix.Close(fd)
+		return nil, "", fmt.Errorf("Failed to %s the pseudo-terminal with error: %w", op, err)
+	}
+	// grantpt() and unlockpt()
+	if err = unix.IoctlSetInt(fd, unix.TIOCPTYGRANT, 0); err != nil {
+		return fail("grant access to", err)
+	}
+	if err = unix.IoctlSetInt(fd, unix.TIOCPTYUNLK, 0); err != nil {
+		return fail("unlock", err)
+	}
+	// ptsname()
+	buf := make([]byte, 128)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(unix.TIOCPTYGNAME), uintptr(unsafe.Pointer(&buf[0]))); errno != 0 {
+		return fail("get the name of", errno)
+	}
+	return os.NewFile(uintptr(fd), "/dev/ptmx"), unix.ByteSliceToString(buf), nil
+}
@@ -0,0 +1,31 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tty
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func open_pty() (master *os.File, slave_path string, err error) {
+	fd, err := eintr_retry_intret(func() (int, error) {
+		return unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	})
+	if err != nil {
+		return nil, "", &os.PathError{Op: "open", Path: "/dev/ptmx", Err: err}
+	}
+	// unlockpt()
+	if err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(fd)
+		return nil, "", fmt.Errorf("Failed to unlock the pseudo-terminal with error: %w", err)
+	}
+	// ptsname()
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(fd)
+		return nil, "", fmt.Errorf("Failed to get the name of the pseudo-terminal with error: %w", err)
+	}
+	return os.NewFile(uintptr(fd), "/dev/ptmx"), fmt.Sprintf("/dev/pts/%d", n), nil
+}
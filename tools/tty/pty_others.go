@@ -0,0 +1,13 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+//go:build !linux && !darwin
+
+package tty
+
+import (
+	"fmt"
+	"os"
+)
+
+func open_pty() (master *os.File, slave_path string, err error) {
+	return nil, "", fmt.Errorf("Creating pseudo-terminals is not supported on this platform")
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -134,6 +135,68 @@ func OpenControllingTerm(operations ...TermiosOperation) (self *Term, err error)
 	return OpenTerm(Ctermid(), operations...)
 }
 
+// Have the terminal device ignore modem control lines and enable its
+// receiver, needed for serial ports without modem control wiring
+var SetLocal TermiosOperation = func(t *unix.Termios) {
+	t.Cflag |= unix.CLOCAL | unix.CREAD
+}
+
+func set_speed_field[T ~int32 | ~uint32 | ~uint64](field *T, val int) {
+	*field = T(val)
+}
+
+// Set the input and output speed of the terminal device, for serial ports
+func SetSpeed(baud_rate int) (TermiosOperation, error) {
+	speed, found := baud_rates[baud_rate]
+	if !found {
+		return nil, fmt.Errorf("The baud rate %d is not supported", baud_rate)
+	}
+	return func(t *unix.Termios) { set_speed(t, speed) }, nil
+}
+
+// Parse a device specification of the form path[:baud_rate], for example:
+// /dev/ttyUSB0:115200
+func ParseDeviceSpec(spec string) (path string, baud_rate int, err error) {
+	path = spec
+	if idx := strings.LastIndexByte(spec, ':'); idx > -1 {
+		if baud_rate, err = strconv.Atoi(spec[idx+1:]); err != nil || baud_rate < 1 {
+			return "", 0, fmt.Errorf("Invalid baud rate in the terminal device specification: %#v", spec)
+		}
+		path = spec[:idx]
+	}
+	if path == "" {
+		return "", 0, fmt.Errorf("No path in the terminal device specification: %#v", spec)
+	}
+	return
+}
+
+// Open the terminal device specified as path[:baud_rate], such as another
+// pseudo-terminal or a serial port, to use it instead of the controlling
+// terminal. An empty specification opens the controlling terminal. When a baud
+// rate is specified, the device is also set to ignore modem control lines.
+func OpenDevice(spec string, operations ...TermiosOperation) (self *Term, err error) {
+	if spec == "" {
+		return OpenControllingTerm(operations...)
+	}
+	path, baud_rate, err := ParseDeviceSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if baud_rate > 0 {
+		op, err := SetSpeed(baud_rate)
+		if err != nil {
+			return nil, err
+		}
+		operations = append([]TermiosOperation{op, SetLocal}, operations...)
+	}
+	self, err = OpenTerm(path, operations...)
+	if err == nil && !IsTerminal(uintptr(self.Fd())) {
+		self.Close()
+		return nil, fmt.Errorf("%s is not a terminal device", path)
+	}
+	return
+}
+
 func (self *Term) Fd() int {
 	if self.os_file == nil {
 		return -1
@@ -385,3 +448,13 @@ func DrainControllingTTY(wait_for time.Duration) {
 		break
 	}
 }
+
+// Create a new pseudo-terminal, returning the master side and the path to the
+// slave side
+func OpenPty() (master *os.File, slave_path string, err error) {
+	return open_pty()
+}
+
+func SetSize(fd int, sz *unix.Winsize) error {
+	return eintr_retry_noret(func() error { return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, sz) })
+}
@@ -8,6 +8,18 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+var baud_rates = map[int]int{
+	50: unix.B50, 75: unix.B75, 110: unix.B110, 134: unix.B134, 150: unix.B150, 200: unix.B200,
+	300: unix.B300, 600: unix.B600, 1200: unix.B1200, 1800: unix.B1800, 2400: unix.B2400,
+	4800: unix.B4800, 9600: unix.B9600, 19200: unix.B19200, 38400: unix.B38400, 57600: unix.B57600,
+	115200: unix.B115200, 230400: unix.B230400,
+}
+
+func set_speed(t *unix.Termios, speed int) {
+	set_speed_field(&t.Ispeed, speed)
+	set_speed_field(&t.Ospeed, speed)
+}
+
 func Tcgetattr(fd int, argp *unix.Termios) error {
 	return unix.IoctlSetTermios(fd, unix.TIOCGETA, argp)
 }
@@ -13,16 +13,12 @@ var _ = fmt.Print
 
 func DCSToKitty(msgtype, payload string) (string, error) {
 	data := base64.StdEncoding.EncodeToString(utils.UnsafeStringToBytes(payload))
-	ans := "\x1bP@kitty-" + msgtype + "|" + data
-	tmux := TmuxSocketAddress()
-	if tmux != "" {
-		err := TmuxAllowPassthrough()
-		if err != nil {
+	ans := "\x1bP@kitty-" + msgtype + "|" + data + "\033\\"
+	if pt := DetectPassthrough(); pt != NO_PASSTHROUGH {
+		if err := pt.Enable(); err != nil {
 			return "", err
 		}
-		ans = "\033Ptmux;\033" + ans + "\033\033\\\033\\"
-	} else {
-		ans += "\033\\"
+		ans = pt.Wrap(ans)
 	}
 	return ans, nil
 }
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -65,7 +64,7 @@ type ImageCollection struct {
 	Shm_supported, Files_supported      atomic.Bool
 	detection_file_id, detection_shm_id uint32
 	temp_file_map                       map[uint32]*temp_resource
-	running_in_tmux                     bool
+	passthrough                         tui.PassthroughMode
 
 	mutex            sync.Mutex
 	image_id_counter uint32
@@ -183,11 +182,10 @@ func (self *ImageCollection) PlaceImageSubRect(lp *loop.Loop, key string, page_s
 }
 
 func (self *ImageCollection) Initialize(lp *loop.Loop) {
-	tmux := tui.TmuxSocketAddress()
-	if tmux != "" && tui.TmuxAllowPassthrough() == nil {
-		self.running_in_tmux = true
+	if pt := tui.DetectPassthrough(); pt != tui.NO_PASSTHROUGH && pt.Enable() == nil {
+		self.passthrough = pt
 	}
-	if !self.running_in_tmux {
+	if self.passthrough == tui.NO_PASSTHROUGH {
 		g := func(t GRT_t, payload string) uint32 {
 			self.image_id_counter++
 			g1 := self.new_graphics_command()
@@ -320,13 +318,7 @@ func NewImageCollection(paths ...string) *ImageCollection {
 }
 
 func (self *ImageCollection) new_graphics_command() *GraphicsCommand {
-	gc := GraphicsCommand{}
-	if self.running_in_tmux {
-		gc.WrapPrefix = "\033Ptmux;"
-		gc.WrapSuffix = "\033\\"
-		gc.EncodeSerializedDataFunc = func(x string) string { return strings.ReplaceAll(x, "\033", "\033\033") }
-	}
-	return &gc
+	return &GraphicsCommand{Passthrough: self.passthrough}
 }
 
 func transmit_by_escape_code(lp *loop.Loop, image_id uint32, temp_file_map map[uint32]*temp_resource, frame *images.ImageFrame, gc *GraphicsCommand) {
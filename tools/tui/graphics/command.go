@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"kitty/tools/tui"
 	"kitty/tools/tui/loop"
 	"kitty/tools/utils"
 )
@@ -144,8 +145,8 @@ type GraphicsCommand struct {
 
 	z int32
 
-	WrapPrefix, WrapSuffix   string
-	EncodeSerializedDataFunc func(string) string
+	// Wrap the escape codes for passing through terminal multiplexers
+	Passthrough tui.PassthroughMode
 
 	response_message string
 }
@@ -200,45 +201,29 @@ func (self GraphicsCommand) String() string {
 }
 
 func (self *GraphicsCommand) serialize_to(buf io.StringWriter, chunk string) (err error) {
-	var ws func(string)
-	if self.EncodeSerializedDataFunc == nil {
-		ws = func(s string) {
-			_, err = buf.WriteString(s)
-		}
-	} else {
-		ws = func(s string) {
-			_, err = buf.WriteString(self.EncodeSerializedDataFunc(s))
-		}
+	if self.Passthrough != tui.NO_PASSTHROUGH {
+		b := strings.Builder{}
+		b.Grow(len(chunk) + 128)
+		self.serialize_unwrapped_to(&b, chunk)
+		_, err = buf.WriteString(self.Passthrough.Wrap(b.String()))
+		return
 	}
-	if self.WrapPrefix != "" {
-		_, err = buf.WriteString(self.WrapPrefix)
-		if err != nil {
-			return err
-		}
-		if self.WrapSuffix != "" {
-			defer func() {
-				if err == nil {
-					_, err = buf.WriteString(self.WrapSuffix)
-				}
-			}()
+	return self.serialize_unwrapped_to(buf, chunk)
+}
+
+func (self *GraphicsCommand) serialize_unwrapped_to(buf io.StringWriter, chunk string) (err error) {
+	ws := func(s string) {
+		if err == nil {
+			_, err = buf.WriteString(s)
 		}
 	}
 	ws("\033_G")
-	if err == nil {
-		items := self.serialize_non_default_fields()
-		ws(strings.Join(items, ","))
-		if err == nil {
-			if len(chunk) > 0 {
-				ws(";")
-				if err == nil {
-					ws(chunk)
-				}
-			}
-			if err == nil {
-				ws("\033\\")
-			}
-		}
+	ws(strings.Join(self.serialize_non_default_fields(), ","))
+	if len(chunk) > 0 {
+		ws(";")
+		ws(chunk)
 	}
+	ws("\033\\")
 	return
 }
 
@@ -293,8 +278,7 @@ func (self *GraphicsCommand) WriteWithPayloadTo(o io.StringWriter, payload []byt
 			return err
 		}
 		gc = GraphicsCommand{
-			q: self.q, a: self.a, WrapPrefix: self.WrapPrefix, WrapSuffix: self.WrapSuffix,
-			EncodeSerializedDataFunc: self.EncodeSerializedDataFunc}
+			q: self.q, a: self.a, Passthrough: self.Passthrough}
 	}
 	return
 }
@@ -35,9 +35,19 @@ func TestGraphicsCommandSerialization(t *testing.T) {
 		}
 	}
 
-	test_chunked_payload := func(payload []byte) {
+	test_chunked_payload := func(payload []byte, streaming bool) {
 		c := &GraphicsCommand{}
-		data := c.AsAPC([]byte(payload))
+		data := ""
+		if streaming {
+			c.SetFormat(GRT_format_rgba)
+			b := strings.Builder{}
+			if err := c.WriteStreamingPayloadTo(&b, bytes.NewReader(payload), len(payload)%2 == 0); err != nil {
+				t.Fatal(err)
+			}
+			data = b.String()
+		} else {
+			data = c.AsAPC([]byte(payload))
+		}
 		encoded := strings.Builder{}
 		compressed := false
 		is_first := true
@@ -91,10 +101,17 @@ func TestGraphicsCommandSerialization(t *testing.T) {
 		t.Fatalf("Failed to parse payload:\n%s", diff)
 	}
 
-	test_chunked_payload([]byte("abcd"))
 	data := make([]byte, 8111)
 	rand.Read(data)
-	test_chunked_payload(data)
-	test_chunked_payload([]byte(strings.Repeat("a", 8007)))
+	for _, streaming := range []bool{false, true} {
+		test_chunked_payload([]byte("abcd"), streaming)
+		test_chunked_payload(data, streaming)
+		test_chunked_payload([]byte(strings.Repeat("a", 8007)), streaming)
+	}
+	// payloads that are exact and inexact multiples of the chunk size
+	data = make([]byte, raw_chunk_size*3+1)
+	rand.Read(data)
+	test_chunked_payload(data[:raw_chunk_size*2], true)
+	test_chunked_payload(data, true)
 
 }
@@ -0,0 +1,121 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package graphics
+
+import (
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+var _ = fmt.Print
+
+// The number of payload bytes that base64 encode to a single 4096 byte chunk
+const raw_chunk_size = 4096 / 4 * 3
+
+// An io.WriteCloser that base64 encodes the data written to it and writes it
+// out as a sequence of chunked graphics commands as soon as each chunk is
+// complete, so that the full payload never needs to be in memory.
+type chunked_payload_writer struct {
+	first_command *GraphicsCommand
+	gc            GraphicsCommand
+	o             io.StringWriter
+	raw           [raw_chunk_size]byte
+	num_raw       int
+	// a complete chunk is only written once it is known whether it is the
+	// last chunk or not
+	pending     [4096]byte
+	pending_raw int
+	has_pending bool
+	num_written int
+	err         error
+}
+
+func (self *chunked_payload_writer) write_pending(is_last bool) error {
+	if is_last {
+		self.gc.m = GRT_more_nomore
+	} else {
+		self.gc.m = GRT_more_more
+	}
+	// the chunk is converted to a string as some writers hold on to it
+	if err := self.gc.serialize_to(self.o, string(self.pending[:base64.StdEncoding.EncodedLen(self.pending_raw)])); err != nil {
+		return err
+	}
+	self.num_written++
+	self.has_pending = false
+	self.gc = GraphicsCommand{
+		q: self.first_command.q, a: self.first_command.a, Passthrough: self.first_command.Passthrough}
+	return nil
+}
+
+func (self *chunked_payload_writer) Write(p []byte) (n int, err error) {
+	if self.err != nil {
+		return 0, self.err
+	}
+	for len(p) > 0 {
+		c := copy(self.raw[self.num_raw:], p)
+		self.num_raw += c
+		p = p[c:]
+		n += c
+		if self.num_raw == len(self.raw) {
+			if self.has_pending {
+				if self.err = self.write_pending(false); self.err != nil {
+					return n, self.err
+				}
+			}
+			self.encode_raw()
+		}
+	}
+	return
+}
+
+func (self *chunked_payload_writer) encode_raw() {
+	base64.StdEncoding.Encode(self.pending[:], self.raw[:self.num_raw])
+	self.pending_raw, self.num_raw, self.has_pending = self.num_raw, 0, true
+}
+
+func (self *chunked_payload_writer) Close() error {
+	if self.err != nil {
+		return self.err
+	}
+	if self.num_raw > 0 {
+		if self.has_pending {
+			if self.err = self.write_pending(false); self.err != nil {
+				return self.err
+			}
+		}
+		self.encode_raw()
+	}
+	if self.has_pending {
+		self.err = self.write_pending(true)
+	} else if self.num_written == 0 {
+		self.err = self.gc.serialize_to(self.o, "")
+	}
+	return self.err
+}
+
+// Write the command with the payload read from r, encoding and writing it in
+// chunks as it is read, so that memory use is bounded regardless of the size
+// of the payload. Unlike WriteWithPayloadTo, it is not possible to know in
+// advance if compression will reduce the payload size, so non-PNG data is
+// always compressed when compress is true.
+func (self *GraphicsCommand) WriteStreamingPayloadTo(o io.StringWriter, r io.Reader, compress bool) (err error) {
+	w := &chunked_payload_writer{first_command: self, gc: *self, o: o}
+	var dest io.Writer = w
+	var z *zlib.Writer
+	if compress && self.Format() != GRT_format_png {
+		w.gc.SetCompression(GRT_compression_zlib)
+		z = zlib.NewWriter(w)
+		dest = z
+	}
+	if _, err = io.Copy(dest, r); err != nil {
+		return err
+	}
+	if z != nil {
+		if err = z.Close(); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
@@ -0,0 +1,127 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"kitty/tools/utils"
+	"kitty/tools/utils/style"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+const HYPERLINK_END = "\x1b]8;;\x1b\\"
+
+// Escape a URL for use in an OSC 8 escape code. The protocol only allows
+// printable ASCII in URLs, so everything else is percent encoded.
+func EscapeHyperlinkURL(u string) string {
+	needs_escape := func(b byte) bool { return b < 32 || b > 126 }
+	n := 0
+	for i := 0; i < len(u); i++ {
+		if needs_escape(u[i]) {
+			n++
+		}
+	}
+	if n == 0 {
+		return u
+	}
+	b := strings.Builder{}
+	b.Grow(len(u) + 2*n)
+	for i := 0; i < len(u); i++ {
+		if c := u[i]; needs_escape(c) {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// A file:// URL for the specified path with an optional fragment, typically
+// a line number
+func FileURL(path, fragment string) string {
+	if q, err := filepath.Abs(path); err == nil {
+		path = q
+	}
+	path = filepath.ToSlash(path)
+	path = strings.Join(utils.Map(url.PathEscape, strings.Split(path, "/")), "/")
+	ans := "file://" + utils.Hostname() + path
+	if fragment != "" {
+		ans += "#" + fragment
+	}
+	return ans
+}
+
+// Emit OSC 8 hyperlinks. Every distinct URL is given a unique id that is
+// re-used whenever the same URL is emitted again, so that terminals treat
+// all the pieces of a link, for example, the parts of a link that is
+// split over multiple lines, as a single link.
+type Hyperlinks struct {
+	// Set to false to emit only the plain text of links
+	Enabled bool
+
+	id_prefix string
+	ids       *utils.LRUCache[string, string]
+	counter   uint64
+}
+
+func NewHyperlinks() *Hyperlinks {
+	prefix, err := utils.HumanRandomId(32)
+	if err != nil {
+		prefix = strconv.Itoa(os.Getpid())
+	}
+	return &Hyperlinks{Enabled: true, id_prefix: prefix + "-", ids: utils.NewLRUCache[string, string](4096)}
+}
+
+func (self *Hyperlinks) IdFor(url string) string {
+	return self.ids.MustGetOrCreate(url, func(string) string {
+		self.counter++
+		return self.id_prefix + strconv.FormatUint(self.counter, 36)
+	})
+}
+
+// The escape code to start a hyperlink for the specified URL
+func (self *Hyperlinks) Start(url string) string {
+	if !self.Enabled || url == "" {
+		return ""
+	}
+	return "\x1b]8;id=" + self.IdFor(url) + ";" + EscapeHyperlinkURL(url) + "\x1b\\"
+}
+
+func (self *Hyperlinks) End() string {
+	if !self.Enabled {
+		return ""
+	}
+	return HYPERLINK_END
+}
+
+func (self *Hyperlinks) Wrap(url, text string) string {
+	if !self.Enabled || url == "" {
+		return text
+	}
+	return self.Start(url) + text + HYPERLINK_END
+}
+
+// Truncate text to at most width cells and wrap it in a hyperlink. The
+// hyperlink is always closed, so it never extends into following text.
+func (self *Hyperlinks) Truncate(url, text string, width int) string {
+	return self.Wrap(url, wcswidth.TruncateToVisualLength(text, width))
+}
+
+// Wrap text into lines of at most width cells, with each line containing a
+// complete hyperlink, so that no line leaves a hyperlink open. All lines
+// share the same link id.
+func (self *Hyperlinks) WrapLines(url, text string, width int) []string {
+	lines := style.WrapTextAsLines(text, width, style.WrapOptions{})
+	for i, line := range lines {
+		lines[i] = self.Wrap(url, line)
+	}
+	return lines
+}
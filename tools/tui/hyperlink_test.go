@@ -0,0 +1,31 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestHyperlinks(t *testing.T) {
+	if actual := EscapeHyperlinkURL("file:///a b/\x1b\\é"); actual != "file:///a b/%1B\\%C3%A9" {
+		t.Fatalf("Incorrect escaping: %#v", actual)
+	}
+	h := NewHyperlinks()
+	a, b := h.IdFor("x"), h.IdFor("y")
+	if a == b || h.IdFor("x") != a {
+		t.Fatalf("ids not re-used: %#v %#v %#v", a, b, h.IdFor("x"))
+	}
+	for _, line := range h.WrapLines("x", strings.Repeat("abc ", 10), 9) {
+		if !strings.HasPrefix(line, "\x1b]8;id="+a+";x\x1b\\") || !strings.HasSuffix(line, HYPERLINK_END) {
+			t.Fatalf("Line not wrapped in hyperlink: %#v", line)
+		}
+	}
+	h.Enabled = false
+	if actual := h.Truncate("x", "abcdef", 3); actual != "abc" {
+		t.Fatalf("Disabled hyperlinks not respected: %#v", actual)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sys/unix"
@@ -41,12 +42,15 @@ type Loop struct {
 	terminal_options                       TerminalStateOptions
 	screen_size                            ScreenSize
 	escape_code_parser                     wcswidth.EscapeCodeParser
+	csi_decoder                            csi_decoder
 	keep_going                             bool
 	death_signal                           unix.Signal
 	exit_code                              int
 	timers, timers_temp                    []*timer
 	timer_id_counter, write_msg_id_counter IdType
 	wakeup_channel                         chan byte
+	main_thread_callbacks_mutex            sync.Mutex
+	main_thread_callbacks                  []func() error
 	pending_writes                         []write_msg
 	tty_write_channel                      chan write_msg
 	pending_mouse_events                   *utils.RingBuffer[MouseEvent]
@@ -54,6 +58,20 @@ type Loop struct {
 	style_cache                            map[string]func(...any) string
 	style_ctx                              style.Context
 	atomic_update_active                   bool
+	downsample_colors                      bool
+	color_depth_query_pending              *utils.Set[string]
+	mouse_regions                          []*MouseRegion
+	hovered_mouse_region                   *MouseRegion
+	suppress_audible_bell, focus_lost      bool
+	urgency_notification_counter           uint64
+	pending_terminal_queries               []*pending_terminal_query
+	mode_state_cache                       map[Mode]ModeState
+	kitty_keyboard_flags_cached            bool
+	kitty_keyboard_supported               bool
+	kitty_keyboard_flags                   KeyboardStateBits
+	terminal_capabilities                  *TerminalCapabilities
+	progress_reporting_from_env            *bool
+	key_holds                              *key_hold_tracker
 
 	// Suspend the loop restoring terminal state, and run the provided function. When it returns terminal state is
 	// put back to what it was before suspending unless the function returns an error or an error occurs saving/restoring state.
@@ -69,10 +87,12 @@ type Loop struct {
 	// shutdown
 	OnFinalize func() string
 
-	// Called when a key event happens
+	// Called when a key event happens. The event is re-used for subsequent
+	// events so must not be retained after this function returns.
 	OnKeyEvent func(event *KeyEvent) error
 
-	// Called when a mouse event happens
+	// Called when a mouse event happens. The event is re-used for subsequent
+	// events so must not be retained after this function returns.
 	OnMouseEvent func(event *MouseEvent) error
 
 	// Called when text is received either from a key event or directly from the terminal
@@ -82,6 +102,12 @@ type Loop struct {
 	// Called when the terminal is resized
 	OnResize func(old_size ScreenSize, new_size ScreenSize) error
 
+	// Called when the size of a cell in pixels changes, for example, because
+	// the font size or the screen DPI changed. Called after OnResize. Useful
+	// to rescale displayed images, as the number of cells on the screen need
+	// not change.
+	OnCellSizeChange func(old_size ScreenSize, new_size ScreenSize) error
+
 	// Called when writing is done
 	OnWriteComplete func(msg_id IdType, has_pending_writes bool) error
 
@@ -97,6 +123,12 @@ type Loop struct {
 	// Called when resuming from a SIGTSTP or Ctrl-z
 	OnResumeFromStop func() error
 
+	// Called when the terminal window gains or loses focus, see FocusTracking
+	OnFocusChange func(focused bool) error
+
+	// Called when the detected color depth of the terminal changes, see DownsampleColors
+	OnColorDepthChanged func(depth style.ColorDepth) error
+
 	// Called when main loop is woken up
 	OnWakeup func() error
 
@@ -290,6 +322,29 @@ func (self *Loop) WakeupMainThread() bool {
 	}
 }
 
+// Call the specified function on the goroutine running the loop. Can be
+// called from any goroutine while the loop is running. Errors returned by the
+// function are treated like errors returned by any other callback.
+func (self *Loop) CallOnMainThread(callback func() error) {
+	self.main_thread_callbacks_mutex.Lock()
+	self.main_thread_callbacks = append(self.main_thread_callbacks, callback)
+	self.main_thread_callbacks_mutex.Unlock()
+	self.WakeupMainThread()
+}
+
+func (self *Loop) run_main_thread_callbacks() error {
+	self.main_thread_callbacks_mutex.Lock()
+	callbacks := self.main_thread_callbacks
+	self.main_thread_callbacks = nil
+	self.main_thread_callbacks_mutex.Unlock()
+	for _, f := range callbacks {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (self *Loop) QueueWriteString(data string) IdType {
 	self.write_msg_id_counter++
 	msg := write_msg{str: data, bytes: nil, id: self.write_msg_id_counter}
@@ -0,0 +1,75 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+var _ = fmt.Print
+
+// Make Bell() a no-op, use for kittens that have an option to disable bells
+func SuppressAudibleBell(self *Loop) {
+	self.suppress_audible_bell = true
+}
+
+// Have the terminal report when its window gains or loses keyboard focus,
+// see OnFocusChange and HasFocus
+func FocusTracking(self *Loop) {
+	self.terminal_options.focus_tracking = true
+}
+
+// Whether the terminal window has focus. Always true unless focus tracking
+// is enabled.
+func (self *Loop) HasFocus() bool {
+	return !self.focus_lost
+}
+
+func (self *Loop) handle_focus_change(focused bool) error {
+	self.focus_lost = !focused
+	if !focused && self.key_holds != nil {
+		self.key_holds.clear(self)
+	}
+	if self.OnFocusChange != nil {
+		return self.OnFocusChange(focused)
+	}
+	return nil
+}
+
+// Ring the terminal bell, unless audible bells have been suppressed
+func (self *Loop) Bell() {
+	if !self.suppress_audible_bell {
+		self.Beep()
+	}
+}
+
+// Ask the terminal to draw the user's attention to its window, for example,
+// when a long running operation completes. Does nothing if the window has
+// focus, which requires focus tracking to detect. A desktop notification
+// with the specified title and body is sent using OSC 99 and the bell is rung,
+// since kitty and most X11 terminals set the urgency hint of their window
+// when the bell rings in an unfocused window. This is done even if audible
+// bells are suppressed, as the bell is needed for the urgency hint.
+func (self *Loop) SetUrgencyHint(title, body string) {
+	if self.terminal_options.focus_tracking && self.HasFocus() {
+		return
+	}
+	if title != "" {
+		self.urgency_notification_counter++
+		id := fmt.Sprintf("kitten-%d", self.urgency_notification_counter)
+		b := strings.Builder{}
+		enc := base64.StdEncoding.EncodeToString
+		done := "1"
+		if body != "" {
+			done = "0"
+		}
+		fmt.Fprintf(&b, "\x1b]99;i=%s:d=%s:e=1;%s\x1b\\", id, done, enc([]byte(title)))
+		if body != "" {
+			fmt.Fprintf(&b, "\x1b]99;i=%s:d=1:e=1:p=body;%s\x1b\\", id, enc([]byte(body)))
+		}
+		self.QueueWriteString(b.String())
+	}
+	self.Beep()
+}
@@ -0,0 +1,40 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestUrgencyHint(t *testing.T) {
+	lp := new_loop()
+	SuppressAudibleBell(lp)
+	FocusTracking(lp)
+	written := func() string {
+		ans := strings.Builder{}
+		for _, w := range lp.pending_writes {
+			ans.WriteString(w.str)
+		}
+		lp.pending_writes = nil
+		return ans.String()
+	}
+	lp.Bell()
+	if w := written(); w != "" {
+		t.Fatalf("Suppressed bell rang: %#v", w)
+	}
+	lp.SetUrgencyHint("title", "")
+	if w := written(); w != "" {
+		t.Fatalf("Urgency hint set for focused window: %#v", w)
+	}
+	if err := lp.handle_focus_change(false); err != nil {
+		t.Fatal(err)
+	}
+	lp.SetUrgencyHint("title", "")
+	if w := written(); !strings.HasPrefix(w, "\x1b]99;") || !strings.HasSuffix(w, "\a") {
+		t.Fatalf("Urgency hint not set with audible bell suppressed: %#v", w)
+	}
+}
@@ -0,0 +1,186 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/utils/style"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type cell struct {
+	text  string // empty for the trailing cell of a wide character
+	style string
+	width uint8 // zero for the trailing cell of a wide character
+}
+
+var blank_cell = cell{text: " ", width: 1}
+
+// A retained mode model of the screen. Kittens draw into the buffer and
+// Render() generates the minimal stream of escape codes needed to update the
+// screen from the previously rendered frame. Text drawn into the buffer must
+// not contain escape codes, formatting is specified using the style specs
+// accepted by style.Context.SprintFunc().
+type CellBuffer struct {
+	width, height     int
+	cells, prev       []cell
+	needs_full_redraw bool
+	style_ctx         *style.Context
+	cursor_x          int
+	cursor_y          int
+}
+
+func NewCellBuffer(width, height int, ctx *style.Context) *CellBuffer {
+	ans := &CellBuffer{style_ctx: ctx}
+	ans.Resize(width, height)
+	return ans
+}
+
+// Create a cell buffer the size of the screen that uses the styling of this loop
+func (self *Loop) NewCellBuffer() (*CellBuffer, error) {
+	sz, err := self.ScreenSize()
+	if err != nil {
+		return nil, err
+	}
+	return NewCellBuffer(int(sz.WidthCells), int(sz.HeightCells), &self.style_ctx), nil
+}
+
+// Render the changes in the buffer since the last render to the screen
+func (self *Loop) RenderCellBuffer(b *CellBuffer) {
+	if s := b.Render(); s != "" {
+		if !self.atomic_update_active {
+			self.StartAtomicUpdate()
+			defer self.EndAtomicUpdate()
+		}
+		self.QueueWriteString(s)
+	}
+}
+
+func (self *CellBuffer) Size() (width, height int) { return self.width, self.height }
+
+// Change the size of the buffer, clearing it. The next render will redraw the full screen.
+func (self *CellBuffer) Resize(width, height int) {
+	self.width, self.height = max(0, width), max(0, height)
+	self.cells = make([]cell, self.width*self.height)
+	self.prev = make([]cell, len(self.cells))
+	self.Clear()
+	self.Invalidate()
+}
+
+// Force the next render to redraw the full screen. Needed when something else
+// has modified the screen, for example, after resuming from being suspended.
+func (self *CellBuffer) Invalidate() {
+	self.needs_full_redraw = true
+}
+
+func (self *CellBuffer) Clear() {
+	for i := range self.cells {
+		self.cells[i] = blank_cell
+	}
+}
+
+// Fill the specified rectangle with blank cells in the specified style
+func (self *CellBuffer) Fill(x, y, width, height int, spec string) {
+	c := cell{text: " ", width: 1, style: spec}
+	for r := max(0, y); r < min(y+height, self.height); r++ {
+		for col := max(0, x); col < min(x+width, self.width); col++ {
+			self.set_cell(col, r, c)
+		}
+	}
+}
+
+// Set the position the cursor is left at after rendering, zero based
+func (self *CellBuffer) SetCursor(x, y int) {
+	self.cursor_x, self.cursor_y = x, y
+}
+
+func (self *CellBuffer) set_cell(x, y int, c cell) {
+	idx := y*self.width + x
+	// overwriting either half of a wide character erases the other half
+	if existing := self.cells[idx]; existing.width == 0 && x > 0 {
+		self.cells[idx-1] = blank_cell
+	} else if existing.width == 2 && x+1 < self.width {
+		self.cells[idx+1] = blank_cell
+	}
+	if c.width == 2 {
+		if x+1 >= self.width {
+			c = cell{text: " ", width: 1, style: c.style}
+		} else {
+			if self.cells[idx+1].width == 2 && x+2 < self.width {
+				self.cells[idx+2] = blank_cell
+			}
+			self.cells[idx+1] = cell{style: c.style}
+		}
+	}
+	self.cells[idx] = c
+}
+
+// Draw text starting at the specified cell, zero based. Text that does not
+// fit is truncated. Returns the x position after the drawn text.
+func (self *CellBuffer) DrawString(x, y int, spec, text string) int {
+	if y < 0 || y >= self.height {
+		return x
+	}
+	it := wcswidth.NewCellIterator(text)
+	for it.Forward() && x < self.width {
+		t := it.Current()
+		w := wcswidth.Stringwidth(t)
+		if w < 1 {
+			continue
+		}
+		if x >= 0 {
+			self.set_cell(x, y, cell{text: t, style: spec, width: uint8(min(2, w))})
+		}
+		x += w
+	}
+	return x
+}
+
+func (self *CellBuffer) Render() string {
+	b := strings.Builder{}
+	current_style := ""
+	if self.needs_full_redraw {
+		b.WriteString("\x1b[m\x1b[H\x1b[2J")
+		for i := range self.prev {
+			self.prev[i] = blank_cell
+		}
+	}
+	cx, cy := -1, -1
+	for y := 0; y < self.height; y++ {
+		row, prev := self.cells[y*self.width:(y+1)*self.width], self.prev[y*self.width:(y+1)*self.width]
+		for x := 0; x < self.width; x++ {
+			c := row[x]
+			// the trailing cells of wide characters are drawn along with the character
+			if c == prev[x] || c.width == 0 {
+				continue
+			}
+			if cy == y && cx < x && cx > -1 {
+				fmt.Fprintf(&b, "\x1b[%dC", x-cx)
+			} else if cy != y || cx != x {
+				fmt.Fprintf(&b, "\x1b[%d;%dH", y+1, x+1)
+			}
+			if c.style != current_style {
+				b.WriteString("\x1b[m")
+				p, _ := self.style_ctx.EscapeCodesForSpec(c.style)
+				b.WriteString(p)
+				current_style = c.style
+			}
+			b.WriteString(c.text)
+			cx, cy = x+int(c.width), y
+		}
+	}
+	copy(self.prev, self.cells)
+	if b.Len() == 0 && !self.needs_full_redraw {
+		return ""
+	}
+	self.needs_full_redraw = false
+	if current_style != "" {
+		b.WriteString("\x1b[m")
+	}
+	fmt.Fprintf(&b, "\x1b[%d;%dH", self.cursor_y+1, self.cursor_x+1)
+	return b.String()
+}
@@ -0,0 +1,48 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"kitty/tools/utils/style"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestCellBuffer(t *testing.T) {
+	ctx := style.Context{AllowEscapeCodes: true}
+	b := NewCellBuffer(6, 2, &ctx)
+	ac := func(expected string) {
+		t.Helper()
+		if diff := cmp.Diff(expected, b.Render()); diff != "" {
+			t.Fatalf("Unexpected render output:\n%s", diff)
+		}
+	}
+	ac("\x1b[m\x1b[H\x1b[2J\x1b[1;1H")
+	ac("")
+	b.DrawString(1, 0, "", "ab")
+	b.DrawString(4, 0, "", "c")
+	ac("\x1b[1;2Hab\x1b[1Cc\x1b[1;1H")
+	b.DrawString(1, 0, "", "ab")
+	ac("")
+	b.DrawString(0, 1, "bold", "x")
+	ac("\x1b[2;1H\x1b[m\x1b[1mx\x1b[m\x1b[1;1H")
+	// wide characters
+	if x := b.DrawString(4, 1, "", "中a"); x != 6 {
+		t.Fatalf("Unexpected x after drawing wide character: %d", x)
+	}
+	ac("\x1b[2;5H中\x1b[1;1H")
+	b.DrawString(5, 1, "", "z")
+	ac("\x1b[2;5H z\x1b[1;1H")
+	b.DrawString(5, 0, "", "中")
+	ac("")
+	b.DrawString(2, 0, "", "中")
+	ac("\x1b[1;3H中\x1b[1;1H")
+	b.Resize(2, 1)
+	b.SetCursor(1, 0)
+	ac("\x1b[m\x1b[H\x1b[2J\x1b[1;2H")
+}
@@ -0,0 +1,31 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Print
+
+// Whether the size of a cell in pixels is different in the two screen sizes.
+// Sizes for which the terminal did not report the size in pixels are never
+// different.
+func (self ScreenSize) CellSizeDiffers(other ScreenSize) bool {
+	if self.CellWidth == 0 || self.CellHeight == 0 || other.CellWidth == 0 || other.CellHeight == 0 {
+		return false
+	}
+	return self.CellWidth != other.CellWidth || self.CellHeight != other.CellHeight
+}
+
+func (self *Loop) on_screen_size_changed(old_size ScreenSize) error {
+	if self.OnResize != nil {
+		if err := self.OnResize(old_size, self.screen_size); err != nil {
+			return err
+		}
+	}
+	if self.OnCellSizeChange != nil && old_size.CellSizeDiffers(self.screen_size) {
+		return self.OnCellSizeChange(old_size, self.screen_size)
+	}
+	return nil
+}
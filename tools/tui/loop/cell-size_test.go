@@ -0,0 +1,49 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestCellSizeChange(t *testing.T) {
+	sz := func(cols, rows, cw, ch uint) ScreenSize {
+		return ScreenSize{WidthCells: cols, HeightCells: rows, WidthPx: cols * cw, HeightPx: rows * ch, CellWidth: cw, CellHeight: ch, updated: true}
+	}
+	lp := new_loop()
+	var events []string
+	lp.OnResize = func(old_size, new_size ScreenSize) error {
+		events = append(events, fmt.Sprintf("resize: %dx%d", new_size.WidthCells, new_size.HeightCells))
+		return nil
+	}
+	lp.OnCellSizeChange = func(old_size, new_size ScreenSize) error {
+		events = append(events, fmt.Sprintf("cell size: %dx%d -> %dx%d", old_size.CellWidth, old_size.CellHeight, new_size.CellWidth, new_size.CellHeight))
+		return nil
+	}
+	for _, x := range []struct {
+		old, new ScreenSize
+		expected []string
+	}{
+		{sz(80, 24, 10, 20), sz(100, 30, 10, 20), []string{"resize: 100x30"}},
+		{sz(80, 24, 10, 20), sz(40, 12, 20, 40), []string{"resize: 40x12", "cell size: 10x20 -> 20x40"}},
+		// DPI change with the same number of cells
+		{sz(80, 24, 10, 20), sz(80, 24, 20, 40), []string{"resize: 80x24", "cell size: 10x20 -> 20x40"}},
+		// unknown pixel sizes
+		{sz(80, 24, 0, 0), sz(80, 24, 10, 20), []string{"resize: 80x24"}},
+		{sz(80, 24, 10, 20), sz(80, 24, 0, 0), []string{"resize: 80x24"}},
+	} {
+		events = nil
+		lp.screen_size = x.new
+		if err := lp.on_screen_size_changed(x.old); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(x.expected, events); diff != "" {
+			t.Fatalf("Incorrect events for %v -> %v:\n%s", x.old, x.new, diff)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kitty/tools/utils"
+	"kitty/tools/utils/style"
+)
+
+var _ = fmt.Print
+
+// Names of the terminfo capabilities used to detect color support
+var color_depth_capabilities = []string{"RGB", "Tc", "colors"}
+
+// Convert colors in styles produced by SprintStyled() and friends to the
+// nearest 256 or 16 color palette entries for terminals that do not support
+// true color. The color depth is initially guessed from the environment and
+// refined by querying the terminal, see OnColorDepthChanged.
+func DownsampleColors(self *Loop) {
+	self.downsample_colors = true
+}
+
+func (self *Loop) ColorDepth() style.ColorDepth {
+	return self.style_ctx.ColorDepth
+}
+
+func (self *Loop) set_color_depth(depth style.ColorDepth) error {
+	if depth == self.style_ctx.ColorDepth {
+		return nil
+	}
+	self.style_ctx.ColorDepth = depth
+	self.style_cache = make(map[string]func(...any) string)
+	if self.OnColorDepthChanged != nil {
+		return self.OnColorDepthChanged(depth)
+	}
+	return nil
+}
+
+func (self *Loop) query_color_depth() {
+	self.color_depth_query_pending = nil
+	if !self.downsample_colors {
+		self.style_ctx.ColorDepth = style.TRUE_COLOR
+		return
+	}
+	self.style_ctx.ColorDepth = style.ColorDepthFromEnvironment()
+	if self.style_ctx.ColorDepth != style.TRUE_COLOR {
+		names := make([]string, len(color_depth_capabilities))
+		for i, x := range color_depth_capabilities {
+			names[i] = hex.EncodeToString(utils.UnsafeStringToBytes(x))
+		}
+		self.QueueWriteString("\x1bP+q" + strings.Join(names, ";") + "\x1b\\")
+		// terminals can respond to each capability separately so track
+		// the ones that have not been responded to yet
+		self.color_depth_query_pending = utils.NewSetWithItems(color_depth_capabilities...)
+	}
+}
+
+// Handle an XTGETTCAP response, returning true if it was a response to the
+// color depth query
+func (self *Loop) handle_color_depth_response(raw []byte) (bool, error) {
+	if self.color_depth_query_pending == nil || len(raw) < 3 || raw[1] != '+' || raw[2] != 'r' {
+		return false, nil
+	}
+	valid := raw[0] == '1'
+	recognized := false
+	depth := self.style_ctx.ColorDepth
+	for _, item := range strings.Split(string(raw[3:]), ";") {
+		hname, hval, _ := strings.Cut(item, "=")
+		name, err := hex.DecodeString(hname)
+		if err != nil || !self.color_depth_query_pending.Has(string(name)) {
+			continue
+		}
+		self.color_depth_query_pending.Discard(string(name))
+		switch string(name) {
+		case "RGB", "Tc":
+			recognized = true
+			if valid {
+				depth = style.TRUE_COLOR
+			}
+		case "colors":
+			recognized = true
+			val, err := hex.DecodeString(hval)
+			if !valid || err != nil {
+				continue
+			}
+			if n, err := strconv.Atoi(string(val)); err == nil && depth != style.TRUE_COLOR {
+				switch {
+				case n >= 1<<24:
+					depth = style.TRUE_COLOR
+				case n >= 256:
+					depth = style.COLORS_256
+				default:
+					depth = style.COLORS_16
+				}
+			}
+		}
+	}
+	if !recognized {
+		return false, nil
+	}
+	if self.color_depth_query_pending.Len() == 0 {
+		self.color_depth_query_pending = nil
+	}
+	return true, self.set_color_depth(depth)
+}
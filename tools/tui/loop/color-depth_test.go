@@ -0,0 +1,40 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"kitty/tools/utils"
+	"kitty/tools/utils/style"
+)
+
+var _ = fmt.Print
+
+func TestColorDepthResponse(t *testing.T) {
+	h := func(x string) string { return hex.EncodeToString([]byte(x)) }
+	test := func(expected style.ColorDepth, responses ...string) {
+		lp := new_loop()
+		lp.style_ctx.ColorDepth = style.COLORS_16
+		lp.color_depth_query_pending = utils.NewSetWithItems(color_depth_capabilities...)
+		for _, r := range responses {
+			if handled, err := lp.handle_color_depth_response([]byte(r)); err != nil || !handled {
+				t.Fatalf("Response %#v not handled: %v", r, err)
+			}
+		}
+		if lp.color_depth_query_pending != nil {
+			t.Fatalf("Query still pending after %#v: %v", responses, lp.color_depth_query_pending.AsSlice())
+		}
+		if lp.ColorDepth() != expected {
+			t.Fatalf("Unexpected color depth for %#v: %d != %d", responses, lp.ColorDepth(), expected)
+		}
+		if handled, _ := lp.handle_color_depth_response([]byte("1+r" + h("colors") + "=" + h("256"))); handled {
+			t.Fatalf("Response handled after query completed")
+		}
+	}
+	test(style.TRUE_COLOR, "1+r"+h("RGB")+"=;"+h("Tc")+"=;"+h("colors")+"="+h("256"))
+	test(style.COLORS_256, "0+r"+h("RGB"), "0+r"+h("Tc"), "1+r"+h("colors")+"="+h("256"))
+	test(style.COLORS_16, "0+r"+h("RGB")+";"+h("Tc")+";"+h("colors"))
+}
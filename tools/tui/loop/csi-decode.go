@@ -0,0 +1,257 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+var _ = fmt.Print
+
+// The numeric parameters of a CSI escape code of the form 1:2:3;4;5X
+// The buffers are re-used between escape codes so that decoding does not
+// allocate once they have grown to a steady state.
+type csi_params struct {
+	values []int
+	// index into values of the first sub-parameter of each section
+	section_starts []int
+	trailer        byte
+}
+
+func (self *csi_params) num_sections() int { return len(self.section_starts) }
+
+func (self *csi_params) section(i int) []int {
+	if i >= len(self.section_starts) {
+		return nil
+	}
+	end := len(self.values)
+	if i+1 < len(self.section_starts) {
+		end = self.section_starts[i+1]
+	}
+	return self.values[self.section_starts[i]:end]
+}
+
+// Parse raw, which must not include the leading CSI. Empty sub-parameters
+// get the value from missing for their section, or zero. Returns false if
+// raw contains anything other than numbers separated by : and ; followed by a
+// single trailer byte.
+func (self *csi_params) parse(raw []byte, missing ...int) bool {
+	self.values, self.section_starts = self.values[:0], self.section_starts[:0]
+	if len(raw) == 0 {
+		return false
+	}
+	self.trailer = raw[len(raw)-1]
+	raw = raw[:len(raw)-1]
+	self.section_starts = append(self.section_starts, 0)
+	missing_val := func() int {
+		if s := len(self.section_starts) - 1; s < len(missing) {
+			return missing[s]
+		}
+		return 0
+	}
+	val, has_digits, negative := 0, false, false
+	finish := func() {
+		switch {
+		case has_digits && negative:
+			val = -val
+		case !has_digits:
+			val = missing_val()
+		}
+		self.values = append(self.values, val)
+		val, has_digits, negative = 0, false, false
+	}
+	for i, ch := range raw {
+		switch {
+		case '0' <= ch && ch <= '9':
+			if val < 1<<31 {
+				val = val*10 + int(ch-'0')
+			}
+			has_digits = true
+		case ch == '-' && !has_digits && !negative && (i == 0 || raw[i-1] == ':' || raw[i-1] == ';'):
+			negative = true
+		case ch == ':':
+			finish()
+		case ch == ';':
+			finish()
+			self.section_starts = append(self.section_starts, len(self.values))
+		default:
+			return false
+		}
+	}
+	if negative && !has_digits {
+		return false
+	}
+	finish()
+	return true
+}
+
+var ascii_strings = func() (ans [128]string) {
+	for i := range ans {
+		ans[i] = string(rune(i))
+	}
+	return
+}()
+
+func string_for_rune(r rune) string {
+	if 0 <= r && r < 128 {
+		return ascii_strings[r]
+	}
+	return string(r)
+}
+
+// Decodes key and mouse events from CSI escape codes, re-using its buffers
+// and filling in caller supplied events so that the common case of decoding
+// a stream of input events does not allocate
+type csi_decoder struct {
+	params     csi_params
+	text_buf   []byte
+	csi_cache  map[string]string
+	csi_cache2 map[string]string
+}
+
+const csi_cache_size = 256
+
+// Return the CSI as a string, re-using previously created strings for CSIs
+// that were seen recently, which covers key repeats and fast typing
+func (self *csi_decoder) csi_string(raw []byte) string {
+	if ans, found := self.csi_cache[string(raw)]; found {
+		return ans
+	}
+	if ans, found := self.csi_cache2[string(raw)]; found {
+		self.csi_cache[ans] = ans
+		return ans
+	}
+	if len(self.csi_cache) >= csi_cache_size {
+		// keep the previous generation so that recently used strings survive
+		self.csi_cache, self.csi_cache2 = self.csi_cache2, self.csi_cache
+		clear(self.csi_cache)
+	}
+	if self.csi_cache == nil {
+		self.csi_cache, self.csi_cache2 = make(map[string]string, csi_cache_size), make(map[string]string, csi_cache_size)
+	}
+	ans := string(raw)
+	self.csi_cache[ans] = ans
+	return ans
+}
+
+func key_name_for_number(keynum int, trailer byte) string {
+	switch keynum {
+	case 0:
+		return ""
+	case 13:
+		if trailer == 'u' {
+			return "ENTER"
+		}
+		return "F3"
+	default:
+		if val, ok := csi_number_to_functional_number_map[keynum]; ok {
+			keynum = val
+		}
+		if val, ok := functional_key_number_to_name_map[keynum]; ok {
+			return val
+		}
+		return string_for_rune(rune(keynum))
+	}
+}
+
+func is_key_event_trailer(trailer byte) bool {
+	switch trailer {
+	case 'u', '~', 'A', 'B', 'C', 'D', 'E', 'H', 'F', 'P', 'Q', 'R', 'S':
+		return true
+	}
+	return false
+}
+
+var letter_trailer_to_csi_number = func() (ans [128]int) {
+	for k, v := range letter_trailer_to_csi_number_map {
+		ans[k[0]] = v
+	}
+	return
+}()
+
+// Fill in ans with the key event encoded by raw, returning false if raw is
+// not a key event. The CSI field is left for the caller to fill in.
+func (self *csi_decoder) decode_key_event(raw []byte, ans *KeyEvent) bool {
+	if len(raw) == 0 || !is_key_event_trailer(raw[len(raw)-1]) {
+		return false
+	}
+	if raw[len(raw)-1] == '~' && len(raw) == 4 && raw[0] == '2' && raw[1] == '0' && (raw[2] == '0' || raw[2] == '1') {
+		// bracketed paste start and end
+		return false
+	}
+	p := &self.params
+	if !p.parse(raw, 0, 1, 0) {
+		return false
+	}
+	first_section, second_section, third_section := p.section(0), p.section(1), p.section(2)
+	*ans = KeyEvent{Type: PRESS}
+	keynum := letter_trailer_to_csi_number[p.trailer]
+	if keynum == 0 {
+		if len(first_section) == 0 {
+			return false
+		}
+		keynum = first_section[0]
+	}
+	ans.Key = key_name_for_number(keynum, p.trailer)
+	if len(first_section) > 1 {
+		ans.ShiftedKey = key_name_for_number(first_section[1], p.trailer)
+	}
+	if len(first_section) > 2 {
+		ans.AlternateKey = key_name_for_number(first_section[2], p.trailer)
+	}
+	if len(second_section) > 0 {
+		ans.Mods = KeyModifiers(second_section[0] - 1)
+	}
+	if len(second_section) > 1 {
+		switch second_section[1] {
+		case 2:
+			ans.Type = REPEAT
+		case 3:
+			ans.Type = RELEASE
+		}
+	}
+	switch len(third_section) {
+	case 0:
+	case 1:
+		ans.Text = string_for_rune(rune(third_section[0]))
+	default:
+		self.text_buf = self.text_buf[:0]
+		for _, ch := range third_section {
+			self.text_buf = utf8.AppendRune(self.text_buf, rune(ch))
+		}
+		ans.Text = string(self.text_buf)
+	}
+	return true
+}
+
+// Fill in ans with the SGR mouse event encoded by raw, returning false if
+// raw is not a mouse event
+func (self *csi_decoder) decode_mouse_event(raw []byte, screen_size ScreenSize, ans *MouseEvent) bool {
+	if len(raw) < 2 || raw[0] != '<' || (raw[len(raw)-1] != 'm' && raw[len(raw)-1] != 'M') {
+		return false
+	}
+	prev := raw[0]
+	for _, ch := range raw[1:] {
+		if (ch == ';' || ch == 'm' || ch == 'M') && (prev == ';' || prev == '<') {
+			// all three fields are required
+			return false
+		}
+		prev = ch
+	}
+	p := &self.params
+	if !p.parse(raw[1:]) || p.num_sections() != 3 {
+		return false
+	}
+	cb, x, y := p.section(0), p.section(1), p.section(2)
+	if len(cb) != 1 || len(x) != 1 || len(y) != 1 {
+		return false
+	}
+	*ans = MouseEvent{}
+	fill_mouse_event(ans, cb[0], x[0], y[0], p.trailer == 'm', screen_size)
+	return true
+}
+
+var key_event_pool = sync.Pool{New: func() any { return &KeyEvent{} }}
+var mouse_event_pool = sync.Pool{New: func() any { return &MouseEvent{} }}
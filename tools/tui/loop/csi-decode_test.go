@@ -0,0 +1,123 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"kitty/tools/utils"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestCSIParams(t *testing.T) {
+	p := csi_params{}
+	tp := func(raw string, expected ...[]int) {
+		t.Helper()
+		if !p.parse([]byte(raw), 7, 8) {
+			t.Fatalf("Failed to parse: %#v", raw)
+		}
+		actual := make([][]int, p.num_sections())
+		for i := range actual {
+			actual[i] = p.section(i)
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Failed to parse %#v:\n%s", raw, diff)
+		}
+	}
+	tp("1u", []int{1})
+	tp("u", []int{7})
+	tp("1:2:3;;-4u", []int{1, 2, 3}, []int{8}, []int{-4})
+	tp("97::98;5:3u", []int{97, 7, 98}, []int{5, 3})
+	for _, raw := range []string{"?1u", "1-u", "1;-u", "1 u"} {
+		if p.parse([]byte(raw)) {
+			t.Fatalf("Incorrectly parsed invalid CSI: %#v", raw)
+		}
+	}
+}
+
+func TestCSIDecoding(t *testing.T) {
+	d := csi_decoder{}
+	ke := func(csi string, expected KeyEvent) {
+		t.Helper()
+		actual := KeyEvent{}
+		if !d.decode_key_event([]byte(csi), &actual) {
+			t.Fatalf("Failed to decode key event from: %#v", csi)
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Failed to decode key event from %#v:\n%s", csi, diff)
+		}
+	}
+	ke("97u", KeyEvent{Type: PRESS, Key: "a"})
+	ke("97:65;2u", KeyEvent{Type: PRESS, Mods: SHIFT, Key: "a", ShiftedKey: "A"})
+	ke("1;5:3A", KeyEvent{Type: RELEASE, Mods: CTRL, Key: "UP"})
+	ke("13u", KeyEvent{Type: PRESS, Key: "ENTER"})
+	ke("15~", KeyEvent{Type: PRESS, Key: "F5"})
+	ke("97;1:2;97u", KeyEvent{Type: REPEAT, Key: "a", Text: "a"})
+	ke("101;;101:769u", KeyEvent{Type: PRESS, Key: "e", Text: "e\u0301"})
+	for _, csi := range []string{"200~", "201~", "?1u", "<0;1;2M", "1;2X"} {
+		if d.decode_key_event([]byte(csi), &KeyEvent{}) {
+			t.Fatalf("Incorrectly decoded a key event from: %#v", csi)
+		}
+	}
+
+	sz := ScreenSize{WidthPx: 100, HeightPx: 100, CellWidth: 10, CellHeight: 20}
+	me := func(csi string, et MouseEventType, buttons MouseButtonFlag, mods KeyModifiers, x, y int) {
+		t.Helper()
+		actual := MouseEvent{}
+		if !d.decode_mouse_event([]byte(csi), sz, &actual) {
+			t.Fatalf("Failed to decode mouse event from: %#v", csi)
+		}
+		expected := MouseEvent{Event_type: et, Buttons: buttons, Mods: mods}
+		expected.Pixel.X, expected.Pixel.Y = x, y
+		expected.Cell.X, expected.Cell.Y = pixel_to_cell(x, 100, 10), pixel_to_cell(y, 100, 20)
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Failed to decode mouse event from %#v:\n%s", csi, diff)
+		}
+	}
+	me("<0;15;45M", MOUSE_PRESS, LEFT_MOUSE_BUTTON, 0, 15, 45)
+	me("<6;15;45m", MOUSE_RELEASE, RIGHT_MOUSE_BUTTON, SHIFT, 15, 45)
+	me("<32;-3;200M", MOUSE_MOVE, LEFT_MOUSE_BUTTON, 0, -3, 200)
+	me("<64;1;1M", MOUSE_PRESS, MOUSE_WHEEL_UP, 0, 1, 1)
+	for _, csi := range []string{"<0;1M", "<0;1;M", "<;1;1M", "<0;1;1;2M", "<0:1;1;1M", "0;1;1M", "<0;1;1u"} {
+		if d.decode_mouse_event([]byte(csi), sz, &MouseEvent{}) {
+			t.Fatalf("Incorrectly decoded a mouse event from: %#v", csi)
+		}
+	}
+}
+
+func TestInputParsingDoesNotAllocate(t *testing.T) {
+	lp := new_loop()
+	lp.pending_mouse_events = utils.NewRingBuffer[MouseEvent](4)
+	lp.screen_size = ScreenSize{WidthCells: 10, HeightCells: 10, WidthPx: 100, HeightPx: 200, CellWidth: 10, CellHeight: 20, updated: true}
+	num_keys, num_mouse := 0, 0
+	lp.OnKeyEvent = func(ev *KeyEvent) error {
+		num_keys++
+		ev.Handled = true
+		return nil
+	}
+	lp.OnMouseEvent = func(ev *MouseEvent) error {
+		num_mouse++
+		return nil
+	}
+	lp.OnText = func(text string, from_key_event, in_bracketed_paste bool) error { return nil }
+	input := []byte("\x1b[97;;97u\x1b[97;1:3u\x1b[1;5A\x1b[<32;15;45M\x1b[<0;15;45M\x1b[<0;15;45mab\x1b[200~pasted\x1b[201~")
+	if err := lp.dispatch_input_data(input); err != nil {
+		t.Fatal(err)
+	}
+	if num_keys != 3 || num_mouse != 4 {
+		t.Fatalf("Unexpected number of events: %d keys %d mouse", num_keys, num_mouse)
+	}
+	if race_detector_enabled {
+		t.Skip("Allocations cannot be counted with the race detector enabled")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = lp.dispatch_input_data(input)
+	})
+	if allocs > 0 {
+		t.Fatalf("Parsing input allocated %v times per run", allocs)
+	}
+}
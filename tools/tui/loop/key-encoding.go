@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"kitty"
+	"kitty/tools/utils"
 )
 
 // key encoding mappings {{{
@@ -108,6 +110,12 @@ type KeyEvent struct {
 
 	// The CSI string this key event was decoded from. Empty if not decoded from CSI.
 	CSI string
+
+	// How long the key has been held down, set for repeat and release events
+	// when KeyHoldTracking() is enabled
+	HoldDuration time.Duration
+	// True for repeat events generated by KeyHoldTracking() rather than the terminal
+	Synthesized bool
 }
 
 func (self *KeyEvent) String() string {
@@ -133,101 +141,12 @@ func (self *KeyEvent) HasCapsLock() bool {
 }
 
 func KeyEventFromCSI(csi string) *KeyEvent {
-	if len(csi) == 0 {
-		return nil
-	}
-	orig_csi := csi
-	last_char := csi[len(csi)-1:]
-	if !strings.Contains("u~ABCDEHFPQRS", last_char) || (last_char == "~" && (csi == "200~" || csi == "201~")) {
+	d := csi_decoder{}
+	ans := KeyEvent{}
+	if !d.decode_key_event(utils.UnsafeStringToBytes(csi), &ans) {
 		return nil
 	}
-	csi = csi[:len(csi)-1]
-	sections := strings.Split(csi, ";")
-
-	get_sub_sections := func(section string, missing int) []int {
-		p := strings.Split(section, ":")
-		ans := make([]int, len(p))
-		for i, x := range p {
-			if x == "" {
-				ans[i] = missing
-			} else {
-				q, err := strconv.Atoi(x)
-				if err != nil {
-					return nil
-				}
-				ans[i] = q
-			}
-		}
-		return ans
-	}
-	first_section := get_sub_sections(sections[0], 0)
-	second_section := []int{}
-	third_section := []int{}
-	if len(sections) > 1 {
-		second_section = get_sub_sections(sections[1], 1)
-	}
-	if len(sections) > 2 {
-		third_section = get_sub_sections(sections[2], 0)
-	}
-	var ans = KeyEvent{Type: PRESS, CSI: orig_csi}
-	var keynum int
-	if val, ok := letter_trailer_to_csi_number_map[last_char]; ok {
-		keynum = val
-	} else {
-		if len(first_section) == 0 {
-			return nil
-		}
-		keynum = first_section[0]
-	}
-
-	key_name := func(keynum int) string {
-		switch keynum {
-		case 0:
-			return ""
-		case 13:
-			if last_char == "u" {
-				return "ENTER"
-			}
-			return "F3"
-		default:
-			if val, ok := csi_number_to_functional_number_map[keynum]; ok {
-				keynum = val
-			}
-			ans := ""
-			if val, ok := functional_key_number_to_name_map[keynum]; ok {
-				ans = val
-			} else {
-				ans = string(rune(keynum))
-			}
-			return ans
-		}
-	}
-
-	ans.Key = key_name(keynum)
-	if len(first_section) > 1 {
-		ans.ShiftedKey = key_name(first_section[1])
-	}
-	if len(first_section) > 2 {
-		ans.AlternateKey = key_name(first_section[2])
-	}
-	if len(second_section) > 0 {
-		ans.Mods = KeyModifiers(second_section[0] - 1)
-	}
-	if len(second_section) > 1 {
-		switch second_section[1] {
-		case 2:
-			ans.Type = REPEAT
-		case 3:
-			ans.Type = RELEASE
-		}
-	}
-	if len(third_section) > 0 {
-		runes := make([]rune, len(third_section))
-		for i, ch := range third_section {
-			runes[i] = rune(ch)
-		}
-		ans.Text = string(runes)
-	}
+	ans.CSI = csi
 	return &ans
 }
 
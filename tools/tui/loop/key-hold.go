@@ -0,0 +1,127 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"time"
+)
+
+var _ = fmt.Print
+
+type KeyHoldOptions struct {
+	// The delay after a key is pressed before repeat events are synthesized
+	// for it. Zero means repeat events are never synthesized, only hold
+	// durations are reported.
+	RepeatDelay time.Duration
+	// The interval between synthesized repeat events
+	RepeatInterval time.Duration
+}
+
+type held_key struct {
+	pressed_at       time.Time
+	event            KeyEvent
+	timer_id         IdType
+	terminal_repeats bool
+}
+
+type key_hold_tracker struct {
+	opts KeyHoldOptions
+	held map[string]*held_key
+	// Repeats are synthesized only once the terminal is known to send
+	// release events, otherwise they would never stop
+	releases_seen bool
+}
+
+// Track how long keys are held down, setting KeyEvent.HoldDuration for
+// repeat and release events. If opts.RepeatDelay is non-zero, repeat events
+// are synthesized for held keys when the terminal does not send them itself,
+// so that input handling is the same in all terminals. The synthesized
+// events have KeyEvent.Synthesized set. Requires a terminal that reports key
+// release events, so the REPORT_KEY_EVENT_TYPES keyboard flag is turned on.
+func (self *Loop) KeyHoldTracking(opts KeyHoldOptions) *Loop {
+	if opts.RepeatInterval <= 0 {
+		opts.RepeatInterval = 50 * time.Millisecond
+	}
+	self.key_holds = &key_hold_tracker{opts: opts, held: make(map[string]*held_key)}
+	self.terminal_options.kitty_keyboard_mode |= REPORT_KEY_EVENT_TYPES
+	return self
+}
+
+// The keys currently held down, only available with KeyHoldTracking()
+func (self *Loop) HeldKeys() []string {
+	if self.key_holds == nil {
+		return nil
+	}
+	ans := make([]string, 0, len(self.key_holds.held))
+	for k := range self.key_holds.held {
+		ans = append(ans, k)
+	}
+	return ans
+}
+
+func (self *key_hold_tracker) stop_synthesizing(lp *Loop, h *held_key) {
+	if h.timer_id != 0 {
+		lp.RemoveTimer(h.timer_id)
+		h.timer_id = 0
+	}
+}
+
+func (self *key_hold_tracker) synthesize_repeat(lp *Loop, h *held_key) error {
+	ev := h.event
+	ev.Type, ev.Synthesized, ev.Handled = REPEAT, true, false
+	ev.HoldDuration = time.Since(h.pressed_at)
+	return lp.handle_key_event(&ev)
+}
+
+func (self *key_hold_tracker) start_synthesizing(lp *Loop, h *held_key) {
+	h.timer_id, _ = lp.AddTimer(self.opts.RepeatDelay, false, func(IdType) error {
+		if h.terminal_repeats {
+			h.timer_id = 0
+			return nil
+		}
+		h.timer_id, _ = lp.AddTimer(self.opts.RepeatInterval, true, func(IdType) error {
+			return self.synthesize_repeat(lp, h)
+		})
+		return self.synthesize_repeat(lp, h)
+	})
+}
+
+// Update the tracked state for an event received from the terminal
+func (self *key_hold_tracker) on_key_event(lp *Loop, ev *KeyEvent) {
+	now := time.Now()
+	switch ev.Type {
+	case PRESS:
+		if h := self.held[ev.Key]; h != nil {
+			// the release was lost, for example, because focus changed
+			self.stop_synthesizing(lp, h)
+		}
+		h := &held_key{pressed_at: now, event: *ev}
+		self.held[ev.Key] = h
+		if self.opts.RepeatDelay > 0 && self.releases_seen {
+			self.start_synthesizing(lp, h)
+		}
+	case REPEAT:
+		if h := self.held[ev.Key]; h != nil {
+			ev.HoldDuration = now.Sub(h.pressed_at)
+			h.terminal_repeats = true
+			self.stop_synthesizing(lp, h)
+		}
+	case RELEASE:
+		self.releases_seen = true
+		if h := self.held[ev.Key]; h != nil {
+			ev.HoldDuration = now.Sub(h.pressed_at)
+			self.stop_synthesizing(lp, h)
+			delete(self.held, ev.Key)
+		}
+	}
+}
+
+// Forget all held keys, as no release events are received for keys released
+// while the terminal does not have keyboard focus
+func (self *key_hold_tracker) clear(lp *Loop) {
+	for k, h := range self.held {
+		self.stop_synthesizing(lp, h)
+		delete(self.held, k)
+	}
+}
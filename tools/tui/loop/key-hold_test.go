@@ -0,0 +1,80 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestKeyHoldTracking(t *testing.T) {
+	lp := new_loop()
+	lp.timers, lp.timers_temp = make([]*timer, 0), make([]*timer, 0)
+	lp.KeyHoldTracking(KeyHoldOptions{RepeatDelay: 100 * time.Millisecond, RepeatInterval: 10 * time.Millisecond})
+	var events []string
+	var last_hold time.Duration
+	lp.OnKeyEvent = func(ev *KeyEvent) error {
+		events = append(events, fmt.Sprintf("%s %s %v", ev.Type, ev.Key, ev.Synthesized))
+		last_hold = ev.HoldDuration
+		ev.Handled = true
+		return nil
+	}
+	send := func(data string, expected ...string) {
+		events = nil
+		if err := lp.dispatch_input_data([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, events); diff != "" {
+			t.Fatalf("Unexpected events for %#v:\n%s", data, diff)
+		}
+	}
+	fire_timers := func(after time.Duration, expected ...string) {
+		events = nil
+		if err := lp.dispatch_timers(time.Now().Add(after)); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, events); diff != "" {
+			t.Fatalf("Unexpected synthesized events:\n%s", diff)
+		}
+	}
+
+	// no repeats are synthesized until the terminal is known to send releases
+	send("\x1b[97u", "PRESS a false")
+	fire_timers(time.Second)
+	send("\x1b[97;1:3u", "RELEASE a false")
+	if last_hold <= 0 {
+		t.Fatalf("Hold duration not reported on release")
+	}
+
+	send("\x1b[97u", "PRESS a false")
+	fire_timers(0)
+	fire_timers(time.Second, "REPEAT a true")
+	fire_timers(2*time.Second, "REPEAT a true")
+	if diff := cmp.Diff([]string{"a"}, lp.HeldKeys()); diff != "" {
+		t.Fatalf("Unexpected held keys:\n%s", diff)
+	}
+	send("\x1b[97;1:3u", "RELEASE a false")
+	if len(lp.timers) != 0 || len(lp.HeldKeys()) != 0 {
+		t.Fatalf("Key still tracked after release")
+	}
+
+	// the terminal sending its own repeats stops synthesis
+	send("\x1b[97u\x1b[97;1:2u", "PRESS a false", "REPEAT a false")
+	fire_timers(time.Second)
+	send("\x1b[97;1:3u", "RELEASE a false")
+
+	// losing focus forgets held keys
+	send("\x1b[97u", "PRESS a false")
+	if err := lp.handle_focus_change(false); err != nil {
+		t.Fatal(err)
+	}
+	fire_timers(time.Second)
+	if len(lp.HeldKeys()) != 0 {
+		t.Fatalf("Key still tracked after focus loss")
+	}
+}
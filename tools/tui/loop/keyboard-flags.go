@@ -0,0 +1,57 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Print
+
+// Push keyboard protocol enhancement flags onto the terminal's stack, for
+// example, when a component that needs key release events gains focus. The
+// flags set with the loop options such as FullKeyboardProtocol() are at the
+// bottom of the stack. Pushed flags are re-applied when resuming after being
+// suspended.
+func (self *Loop) PushKeyboardFlags(flags KeyboardStateBits) {
+	self.terminal_options.keyboard_flags_stack = append(self.terminal_options.keyboard_flags_stack, flags)
+	if self.keep_going {
+		self.QueueWriteString(fmt.Sprintf("\x1b[>%du", flags))
+	}
+}
+
+// Pop the flags pushed by the last call to PushKeyboardFlags(), restoring
+// the previous flags. Does nothing if no flags are pushed.
+func (self *Loop) PopKeyboardFlags() {
+	if n := len(self.terminal_options.keyboard_flags_stack); n > 0 {
+		self.terminal_options.keyboard_flags_stack = self.terminal_options.keyboard_flags_stack[:n-1]
+		if self.keep_going {
+			self.QueueWriteString("\x1b[<u")
+		}
+	}
+}
+
+// Replace the most recently pushed flags, pushing them if none are pushed.
+// Useful when focus moves between sibling components that need different
+// flags.
+func (self *Loop) SetKeyboardFlags(flags KeyboardStateBits) {
+	stack := self.terminal_options.keyboard_flags_stack
+	if len(stack) == 0 {
+		self.PushKeyboardFlags(flags)
+		return
+	}
+	if stack[len(stack)-1] != flags {
+		stack[len(stack)-1] = flags
+		if self.keep_going {
+			self.QueueWriteString(fmt.Sprintf("\x1b[=%d;1u", flags))
+		}
+	}
+}
+
+// The currently active keyboard protocol enhancement flags
+func (self *Loop) KeyboardFlags() KeyboardStateBits {
+	if n := len(self.terminal_options.keyboard_flags_stack); n > 0 {
+		return self.terminal_options.keyboard_flags_stack[n-1]
+	}
+	return self.terminal_options.kitty_keyboard_mode
+}
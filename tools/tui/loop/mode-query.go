@@ -0,0 +1,241 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var _ = fmt.Print
+
+type ModeState uint8
+
+const (
+	MODE_NOT_RECOGNIZED ModeState = iota
+	MODE_SET
+	MODE_RESET
+	MODE_PERMANENTLY_SET
+	MODE_PERMANENTLY_RESET
+)
+
+func (self ModeState) IsSet() bool { return self == MODE_SET || self == MODE_PERMANENTLY_SET }
+func (self ModeState) IsSupported() bool {
+	return self != MODE_NOT_RECOGNIZED && self <= MODE_PERMANENTLY_RESET
+}
+
+func (self ModeState) String() string {
+	switch self {
+	case MODE_SET:
+		return "set"
+	case MODE_RESET:
+		return "reset"
+	case MODE_PERMANENTLY_SET:
+		return "permanently-set"
+	case MODE_PERMANENTLY_RESET:
+		return "permanently-reset"
+	}
+	return "not-recognized"
+}
+
+// The DECRQM escape code to query the state of this mode
+func (self Mode) EscapeCodeToQuery() string {
+	return self.escape_code("$p")
+}
+
+type TerminalQuery struct {
+	// Modes to query with DECRQM
+	Modes []Mode
+	// Query the current flags of the kitty keyboard protocol
+	KittyKeyboardFlags bool
+	// Settings to query with DECRQSS, for example: "m" for SGR or " q" for the cursor shape
+	Settings []string
+	// Query the name and version of the terminal with XTVERSION
+	TerminalVersion bool
+	// Query the position of the cursor with CPR
+	CursorPosition bool
+}
+
+type TerminalQueryResults struct {
+	Modes map[Mode]ModeState
+	// Whether the terminal supports the kitty keyboard protocol and the
+	// currently active flags, only valid if they were queried
+	KittyKeyboardSupported bool
+	KittyKeyboardFlags     KeyboardStateBits
+	// The values of the queried settings, without the trailing setting
+	// name. Settings the terminal does not support are missing.
+	Settings map[string]string
+	// The name and version of the terminal, for example: kitty(0.31.0),
+	// empty if not queried or the terminal does not support XTVERSION
+	TerminalVersion string
+	// The attributes from the primary device attributes response, the first
+	// of which is the conformance level of the terminal
+	DeviceAttributes []int
+	// The zero based position of the cursor, only valid if CursorPositionKnown is true
+	CursorPosition      struct{ X, Y int }
+	CursorPositionKnown bool
+}
+
+type pending_terminal_query struct {
+	query    TerminalQuery
+	results  TerminalQueryResults
+	settings []string
+	callback func(*TerminalQueryResults) error
+}
+
+// Query the terminal for the state of the specified modes, the kitty keyboard
+// protocol and settings. The queries are followed by a primary device
+// attributes request, which all terminals respond to, so that queries that
+// terminals silently ignore are reported as unsupported instead of hanging.
+// The callback is called once, when all responses have been received. Mode
+// states and keyboard protocol support are cached, so if only cached values
+// are queried, the callback is called immediately. Note that the device
+// attributes response is consumed and not sent to OnEscapeCode.
+func (self *Loop) QueryTerminal(q TerminalQuery, callback func(*TerminalQueryResults) error) error {
+	p := &pending_terminal_query{query: q, callback: callback, settings: q.Settings}
+	p.results.Modes = make(map[Mode]ModeState, len(q.Modes))
+	p.results.Settings = make(map[string]string, len(q.Settings))
+	b := strings.Builder{}
+	for _, m := range q.Modes {
+		if s, found := self.mode_state_cache[m]; found {
+			p.results.Modes[m] = s
+		} else {
+			b.WriteString(m.EscapeCodeToQuery())
+		}
+	}
+	if q.KittyKeyboardFlags {
+		if self.kitty_keyboard_flags_cached {
+			p.results.KittyKeyboardSupported = self.kitty_keyboard_supported
+			p.results.KittyKeyboardFlags = self.kitty_keyboard_flags
+		} else {
+			b.WriteString("\x1b[?u")
+		}
+	}
+	for _, s := range q.Settings {
+		b.WriteString("\x1bP$q" + s + "\x1b\\")
+	}
+	if q.TerminalVersion {
+		b.WriteString("\x1b[>0q")
+	}
+	if q.CursorPosition {
+		b.WriteString("\x1b[6n")
+	}
+	if b.Len() == 0 {
+		return callback(&p.results)
+	}
+	b.WriteString("\x1b[c")
+	self.pending_terminal_queries = append(self.pending_terminal_queries, p)
+	self.QueueWriteString(b.String())
+	return nil
+}
+
+// The cached state of the specified mode from a previous QueryTerminal() call
+func (self *Loop) CachedModeState(m Mode) (ModeState, bool) {
+	s, found := self.mode_state_cache[m]
+	return s, found
+}
+
+func (self *Loop) cache_mode_state(m Mode, s ModeState) {
+	if self.mode_state_cache == nil {
+		self.mode_state_cache = make(map[Mode]ModeState)
+	}
+	self.mode_state_cache[m] = s
+}
+
+func (self *Loop) handle_terminal_query_csi(csi string) (bool, error) {
+	if len(self.pending_terminal_queries) == 0 || len(csi) < 2 {
+		return false, nil
+	}
+	p := self.pending_terminal_queries[0]
+	switch {
+	case strings.HasSuffix(csi, "$y"):
+		// DECRPM response: [?] Ps ; Pm $ y
+		payload, is_private := strings.CutPrefix(csi[:len(csi)-2], "?")
+		num, state, found := strings.Cut(payload, ";")
+		if !found {
+			return false, nil
+		}
+		n, err := strconv.ParseUint(num, 10, 31)
+		s, serr := strconv.ParseUint(state, 10, 8)
+		if err != nil || serr != nil {
+			return false, nil
+		}
+		m := Mode(n)
+		if is_private {
+			m |= private
+		}
+		ms := ModeState(s)
+		if !ms.IsSupported() {
+			ms = MODE_NOT_RECOGNIZED
+		}
+		self.cache_mode_state(m, ms)
+		p.results.Modes[m] = ms
+		return true, nil
+	case csi[0] == '?' && csi[len(csi)-1] == 'u' && p.query.KittyKeyboardFlags:
+		n, err := strconv.ParseUint(csi[1:len(csi)-1], 10, 8)
+		if err != nil {
+			return false, nil
+		}
+		self.kitty_keyboard_flags_cached, self.kitty_keyboard_supported, self.kitty_keyboard_flags = true, true, KeyboardStateBits(n)
+		p.results.KittyKeyboardSupported, p.results.KittyKeyboardFlags = true, KeyboardStateBits(n)
+		return true, nil
+	case csi[len(csi)-1] == 'R' && p.query.CursorPosition:
+		// CPR response: Pr ; Pc R, which is the same as the legacy encoding
+		// of F3 with modifiers, so it is only accepted while a query is pending
+		parts := strings.Split(strings.TrimPrefix(csi[:len(csi)-1], "?"), ";")
+		if len(parts) < 2 {
+			return false, nil
+		}
+		y, err := strconv.Atoi(parts[0])
+		x, xerr := strconv.Atoi(parts[1])
+		if err != nil || xerr != nil || x < 1 || y < 1 {
+			return false, nil
+		}
+		p.results.CursorPosition.X, p.results.CursorPosition.Y, p.results.CursorPositionKnown = x-1, y-1, true
+		return true, nil
+	case csi[0] == '?' && csi[len(csi)-1] == 'c':
+		// primary device attributes, marks the end of the query
+		self.pending_terminal_queries = self.pending_terminal_queries[1:]
+		for _, x := range strings.Split(csi[1:len(csi)-1], ";") {
+			if n, err := strconv.Atoi(x); err == nil {
+				p.results.DeviceAttributes = append(p.results.DeviceAttributes, n)
+			}
+		}
+		for _, m := range p.query.Modes {
+			if _, found := p.results.Modes[m]; !found {
+				p.results.Modes[m] = MODE_NOT_RECOGNIZED
+				self.cache_mode_state(m, MODE_NOT_RECOGNIZED)
+			}
+		}
+		if p.query.KittyKeyboardFlags && !self.kitty_keyboard_flags_cached {
+			self.kitty_keyboard_flags_cached = true
+		}
+		return true, p.callback(&p.results)
+	}
+	return false, nil
+}
+
+func (self *Loop) handle_terminal_query_dcs(raw []byte) bool {
+	if len(self.pending_terminal_queries) == 0 || len(raw) < 2 {
+		return false
+	}
+	p := self.pending_terminal_queries[0]
+	if raw[0] == '>' && raw[1] == '|' && p.query.TerminalVersion {
+		p.results.TerminalVersion = string(raw[2:])
+		return true
+	}
+	if len(raw) < 3 || raw[1] != '$' || raw[2] != 'r' {
+		return false
+	}
+	if len(p.settings) == 0 {
+		return false
+	}
+	// DECRPSS responses arrive in the order the settings were queried
+	s := p.settings[0]
+	p.settings = p.settings[1:]
+	if raw[0] == '1' {
+		p.results.Settings[s] = strings.TrimSuffix(string(raw[3:]), s)
+	}
+	return true
+}
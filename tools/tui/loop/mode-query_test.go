@@ -0,0 +1,60 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestTerminalQuery(t *testing.T) {
+	lp := new_loop()
+	var results *TerminalQueryResults
+	cb := func(r *TerminalQueryResults) error { results = r; return nil }
+	err := lp.QueryTerminal(TerminalQuery{Modes: []Mode{PENDING_UPDATE, BRACKETED_PASTE, IRM}, KittyKeyboardFlags: true, Settings: []string{"m", " q"}}, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("\x1b[?2026$p\x1b[?2004$p\x1b[4$p\x1b[?u\x1bP$qm\x1b\\\x1bP$q q\x1b\\\x1b[c", lp.pending_writes[0].str); diff != "" {
+		t.Fatalf("Unexpected query:\n%s", diff)
+	}
+	for _, csi := range []string{"?2026;2$y", "?2004;1$y", "?5u"} {
+		if err = lp.handle_csi([]byte(csi)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lp.handle_dcs([]byte("1$r0;1m"))
+	lp.handle_dcs([]byte("0$r"))
+	if results != nil {
+		t.Fatalf("Callback called before query completed")
+	}
+	if err = lp.handle_csi([]byte("?62;c")); err != nil {
+		t.Fatal(err)
+	}
+	expected := &TerminalQueryResults{
+		Modes:                  map[Mode]ModeState{PENDING_UPDATE: MODE_RESET, BRACKETED_PASTE: MODE_SET, IRM: MODE_NOT_RECOGNIZED},
+		KittyKeyboardSupported: true, KittyKeyboardFlags: 5, Settings: map[string]string{"m": "0;1"}, DeviceAttributes: []int{62},
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Fatalf("Unexpected query results:\n%s", diff)
+	}
+	// cached results are returned immediately
+	results = nil
+	lp.QueryTerminal(TerminalQuery{Modes: []Mode{BRACKETED_PASTE}, KittyKeyboardFlags: true}, cb)
+	if results == nil || results.Modes[BRACKETED_PASTE] != MODE_SET || len(lp.pending_writes) != 1 {
+		t.Fatalf("Cached results not used: %v", results)
+	}
+	// cursor position reports are not decoded as key events
+	results = nil
+	lp.OnKeyEvent = func(ev *KeyEvent) error { t.Fatalf("Cursor position report decoded as key event: %s", ev); return nil }
+	lp.QueryTerminal(TerminalQuery{CursorPosition: true}, cb)
+	lp.handle_csi([]byte("12;5R"))
+	lp.handle_csi([]byte("?1;2c"))
+	if results == nil || !results.CursorPositionKnown || results.CursorPosition.X != 4 || results.CursorPosition.Y != 11 {
+		t.Fatalf("Cursor position not reported: %v", results)
+	}
+}
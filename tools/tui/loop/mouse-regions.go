@@ -0,0 +1,97 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Print
+
+// A rectangular region of the screen, in zero based cell co-ordinates, that
+// receives mouse events. Hover tracking requires the loop to have motion
+// tracking enabled via MouseTrackingMode().
+type MouseRegion struct {
+	Left, Top, Width, Height int
+	// Regions are typically re-registered on every redraw, set an Id to have
+	// hover state preserved across redraws
+	Id string
+
+	// Called for every mouse event in the region, return true to prevent
+	// further processing of the event, including the loop's OnMouseEvent
+	OnMouseEvent func(ev *MouseEvent) (bool, error)
+	// Called when a click happens in the region. Clicks handled here are not
+	// sent to the loop's OnMouseEvent
+	OnClick func(ev *MouseEvent) error
+	// Called when the mouse enters and leaves the region
+	OnEnter, OnLeave func(ev *MouseEvent) error
+}
+
+func (self *MouseRegion) Contains(x, y int) bool {
+	return self.Left <= x && x < self.Left+self.Width && self.Top <= y && y < self.Top+self.Height
+}
+
+func same_mouse_region(a, b *MouseRegion) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a == b || (a.Id != "" && a.Id == b.Id)
+}
+
+// Register a region to receive mouse events. Regions registered later are
+// on top of regions registered earlier.
+func (self *Loop) AddMouseRegion(r MouseRegion) *MouseRegion {
+	ans := &r
+	self.mouse_regions = append(self.mouse_regions, ans)
+	return ans
+}
+
+// Remove all registered mouse regions, typically called at the start of a redraw
+func (self *Loop) ClearMouseRegions() {
+	self.mouse_regions = self.mouse_regions[:0]
+}
+
+// The top most region containing the specified cell or nil
+func (self *Loop) MouseRegionAt(x, y int) *MouseRegion {
+	for i := len(self.mouse_regions) - 1; i >= 0; i-- {
+		if r := self.mouse_regions[i]; r.Contains(x, y) {
+			return r
+		}
+	}
+	return nil
+}
+
+// The region the mouse pointer is currently over or nil
+func (self *Loop) HoveredMouseRegion() *MouseRegion {
+	return self.hovered_mouse_region
+}
+
+func (self *Loop) route_mouse_event_to_regions(ev *MouseEvent) (bool, error) {
+	r := self.MouseRegionAt(ev.Cell.X, ev.Cell.Y)
+	if prev := self.hovered_mouse_region; !same_mouse_region(prev, r) {
+		self.hovered_mouse_region = r
+		if prev != nil && prev.OnLeave != nil {
+			if err := prev.OnLeave(ev); err != nil {
+				return false, err
+			}
+		}
+		if r != nil && r.OnEnter != nil {
+			if err := r.OnEnter(ev); err != nil {
+				return false, err
+			}
+		}
+	}
+	self.hovered_mouse_region = r
+	if r == nil {
+		return false, nil
+	}
+	if r.OnMouseEvent != nil {
+		if handled, err := r.OnMouseEvent(ev); handled || err != nil {
+			return handled, err
+		}
+	}
+	if ev.Event_type == MOUSE_CLICK && r.OnClick != nil {
+		return true, r.OnClick(ev)
+	}
+	return false, nil
+}
@@ -0,0 +1,63 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"kitty/tools/utils"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestMouseRegions(t *testing.T) {
+	lp := new_loop()
+	lp.pending_mouse_events = utils.NewRingBuffer[MouseEvent](4)
+	var actions []string
+	lp.OnMouseEvent = func(ev *MouseEvent) error {
+		actions = append(actions, "loop:"+ev.Event_type.String())
+		return nil
+	}
+	add := func(name string, left, top, width, height int) {
+		lp.AddMouseRegion(MouseRegion{
+			Left: left, Top: top, Width: width, Height: height, Id: name,
+			OnClick: func(*MouseEvent) error { actions = append(actions, name+":click"); return nil },
+			OnEnter: func(*MouseEvent) error { actions = append(actions, name+":enter"); return nil },
+			OnLeave: func(*MouseEvent) error { actions = append(actions, name+":leave"); return nil },
+		})
+	}
+	ev := func(et MouseEventType, x, y int) {
+		e := MouseEvent{Event_type: et}
+		e.Cell.X, e.Cell.Y = x, y
+		if err := lp.handle_mouse_event(&e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ac := func(expected ...string) {
+		t.Helper()
+		if diff := cmp.Diff(expected, actions); diff != "" {
+			t.Fatalf("Unexpected actions:\n%s", diff)
+		}
+		actions = nil
+	}
+	add("a", 0, 0, 4, 1)
+	add("b", 2, 0, 4, 2)
+	ev(MOUSE_MOVE, 0, 0)
+	ac("a:enter", "loop:move")
+	ev(MOUSE_MOVE, 2, 0)
+	ac("a:leave", "b:enter", "loop:move")
+	ev(MOUSE_PRESS, 3, 1)
+	ev(MOUSE_RELEASE, 3, 1)
+	ac("loop:press", "loop:release", "b:click")
+	// re-registering regions with the same ids preserves hover state
+	lp.ClearMouseRegions()
+	add("a", 0, 0, 4, 1)
+	add("b", 2, 0, 4, 2)
+	ev(MOUSE_MOVE, 5, 1)
+	ac("loop:move")
+	ev(MOUSE_MOVE, 7, 1)
+	ac("b:leave", "loop:move")
+}
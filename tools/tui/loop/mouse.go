@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"kitty/tools/utils"
 )
 
 var _ = fmt.Print
@@ -109,27 +111,9 @@ func pixel_to_cell(px, length, cell_length int) int {
 	return px / cell_length
 }
 
-func decode_sgr_mouse(text string, screen_size ScreenSize) *MouseEvent {
-	last_letter := text[len(text)-1]
-	text = text[:len(text)-1]
-	parts := strings.Split(text, ";")
-	if len(parts) != 3 {
-		return nil
-	}
-	cb, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return nil
-	}
-	ans := MouseEvent{}
-	ans.Pixel.X, err = strconv.Atoi(parts[1])
-	if err != nil {
-		return nil
-	}
-	if len(parts[2]) < 1 {
-		return nil
-	}
-	ans.Pixel.Y, err = strconv.Atoi(parts[2])
-	if last_letter == 'm' {
+func fill_mouse_event(ans *MouseEvent, cb, x, y int, is_release bool, screen_size ScreenSize) {
+	ans.Pixel.X, ans.Pixel.Y = x, y
+	if is_release {
 		ans.Event_type = MOUSE_RELEASE
 	} else if cb&MOTION_INDICATOR != 0 {
 		ans.Event_type = MOUSE_MOVE
@@ -153,20 +137,13 @@ func decode_sgr_mouse(text string, screen_size ScreenSize) *MouseEvent {
 	}
 	ans.Cell.X = pixel_to_cell(ans.Pixel.X, int(screen_size.WidthPx), int(screen_size.CellWidth))
 	ans.Cell.Y = pixel_to_cell(ans.Pixel.Y, int(screen_size.HeightPx), int(screen_size.CellHeight))
-
-	return &ans
 }
 
 func MouseEventFromCSI(csi string, screen_size ScreenSize) *MouseEvent {
-	if len(csi) == 0 {
-		return nil
-	}
-	last_char := csi[len(csi)-1]
-	if last_char != 'm' && last_char != 'M' {
-		return nil
-	}
-	if !strings.HasPrefix(csi, "<") {
+	d := csi_decoder{}
+	ans := MouseEvent{}
+	if !d.decode_mouse_event(utils.UnsafeStringToBytes(csi), screen_size, &ans) {
 		return nil
 	}
-	return decode_sgr_mouse(csi[1:], screen_size)
+	return &ans
 }
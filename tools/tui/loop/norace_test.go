@@ -0,0 +1,7 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build !race
+
+package loop
+
+const race_detector_enabled = false
@@ -0,0 +1,68 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Print
+
+// The states of a task reported with the ConEmu/Windows Terminal OSC 9;4
+// escape code, shown by supporting terminals in their tab bar or taskbar
+type ProgressState uint8
+
+const (
+	PROGRESS_HIDDEN ProgressState = iota
+	PROGRESS_NORMAL
+	PROGRESS_ERROR
+	PROGRESS_INDETERMINATE
+	PROGRESS_PAUSED
+)
+
+type progress_report struct {
+	state   ProgressState
+	percent int
+}
+
+// The escape code to report task progress, percent is ignored by terminals
+// for the hidden and indeterminate states
+func ProgressEscapeCode(state ProgressState, percent int) string {
+	return fmt.Sprintf("\x1b]9;4;%d;%d\x1b\\", state, max(0, min(percent, 100)))
+}
+
+func (self *Loop) progress_reporting_supported() bool {
+	if self.terminal_capabilities != nil {
+		return self.terminal_capabilities.ProgressReporting
+	}
+	if self.progress_reporting_from_env == nil {
+		s := TerminalCapabilitiesFromEnvironment().ProgressReporting
+		self.progress_reporting_from_env = &s
+	}
+	return *self.progress_reporting_from_env
+}
+
+// Report the progress of a long running task to the terminal. Does nothing
+// when the terminal is not known to support progress reporting, see
+// TerminalCapabilities. Repeated reports of the same progress are not sent
+// and the progress is cleared automatically when the loop exits or is
+// suspended, and re-reported when it resumes.
+func (self *Loop) SetProgress(state ProgressState, percent int) {
+	if !self.progress_reporting_supported() {
+		return
+	}
+	if state == PROGRESS_HIDDEN || state == PROGRESS_INDETERMINATE {
+		percent = 0
+	}
+	r := progress_report{state, max(0, min(percent, 100))}
+	if r == self.terminal_options.progress {
+		return
+	}
+	self.terminal_options.progress = r
+	self.QueueWriteString(ProgressEscapeCode(r.state, r.percent))
+}
+
+// Clear any progress previously reported with SetProgress()
+func (self *Loop) ClearProgress() {
+	self.SetProgress(PROGRESS_HIDDEN, 0)
+}
@@ -0,0 +1,41 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestProgressReporting(t *testing.T) {
+	if actual := ProgressEscapeCode(PROGRESS_NORMAL, 120); actual != "\x1b]9;4;1;100\x1b\\" {
+		t.Fatalf("Incorrect progress escape code: %#v", actual)
+	}
+	supported := true
+	lp := &Loop{progress_reporting_from_env: &supported}
+	lp.SetProgress(PROGRESS_NORMAL, 10)
+	lp.SetProgress(PROGRESS_NORMAL, 10)
+	lp.SetProgress(PROGRESS_INDETERMINATE, 50)
+	if n := len(lp.pending_writes); n != 2 {
+		t.Fatalf("Unexpected number of progress escape codes sent: %d", n)
+	}
+	if actual := lp.terminal_options.ResetStateEscapeCodes(); !strings.HasSuffix(actual, ProgressEscapeCode(PROGRESS_HIDDEN, 0)) {
+		t.Fatalf("Progress not cleared when resetting terminal state: %#v", actual)
+	}
+	if actual := lp.terminal_options.SetStateEscapeCodes(); !strings.HasSuffix(actual, ProgressEscapeCode(PROGRESS_INDETERMINATE, 0)) {
+		t.Fatalf("Progress not restored when setting terminal state: %#v", actual)
+	}
+	lp.ClearProgress()
+	if actual := lp.terminal_options.ResetStateEscapeCodes(); strings.Contains(actual, "\x1b]9;4;") {
+		t.Fatalf("Cleared progress reported when resetting terminal state: %#v", actual)
+	}
+	supported = false
+	lp = &Loop{progress_reporting_from_env: &supported}
+	lp.SetProgress(PROGRESS_NORMAL, 10)
+	if n := len(lp.pending_writes); n != 0 {
+		t.Fatalf("Progress reported to a terminal that does not support it")
+	}
+}
@@ -0,0 +1,9 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build race
+
+package loop
+
+// The race detector instruments memory accesses and allocates, so allocation
+// counts are meaningless under it
+const race_detector_enabled = true
@@ -0,0 +1,130 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var _ = fmt.Print
+
+type RunCommandOptions struct {
+	// The working directory and environment of the command, defaulting to
+	// those of this process
+	Dir string
+	Env []string
+
+	// Capture the output of the command, rather than letting it write to the
+	// terminal. The UI is not suspended, instead the command is run in the
+	// background without a controlling terminal, with its stdin connected to
+	// /dev/null. OnComplete must be set.
+	CaptureOutput bool
+	// Called on the loop goroutine with chunks of the output of the command
+	// as it is received, when capturing output, for example, to display it
+	// in a region of the screen with tui.OutputPane
+	OnOutput func(data []byte) error
+	// Called on the loop goroutine with the result when a command whose
+	// output is captured exits
+	OnComplete func(res CommandResult, err error) error
+
+	// When not capturing output, wait for the user to press Enter after the
+	// command exits, so that its output can be read before the UI is
+	// restored.
+	WaitForEnter bool
+}
+
+type CommandResult struct {
+	// The combined stdout and stderr of the command, when captured
+	Output   []byte
+	ExitCode int
+}
+
+type capture_writer struct {
+	output    bytes.Buffer
+	lp        *Loop
+	on_output func([]byte) error
+}
+
+func (self *capture_writer) Write(data []byte) (int, error) {
+	self.output.Write(data)
+	if self.on_output != nil {
+		chunk := bytes.Clone(data)
+		self.lp.CallOnMainThread(func() error { return self.on_output(chunk) })
+	}
+	return len(data), nil
+}
+
+// Run the specified command, with its stdio connected to the terminal, while
+// the UI is suspended. When the command exits the terminal is put back into
+// the state the loop needs, after which the UI must be redrawn as the command
+// could have changed anything on the screen. A command that exits with a
+// non-zero exit code is not an error, the error is for failure to run the
+// command at all. Unlike SuspendAndRun(), the loop is always restored, even
+// if running the command fails. When capturing output, this returns as soon
+// as the command is started and the result is delivered to
+// opts.OnComplete instead.
+func (self *Loop) RunCommand(argv []string, opts RunCommandOptions) (ans CommandResult, err error) {
+	if len(argv) == 0 {
+		return ans, fmt.Errorf("No command specified to run")
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir, cmd.Env = opts.Dir, opts.Env
+	exit_code := func(err error) (int, error) {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return ee.ExitCode(), nil
+		}
+		return 0, err
+	}
+	run := func() (err error) {
+		ans.ExitCode, err = exit_code(cmd.Run())
+		return
+	}
+	if opts.CaptureOutput {
+		if opts.OnComplete == nil {
+			return ans, fmt.Errorf("OnComplete must be set to capture the output of %s", argv[0])
+		}
+		w := &capture_writer{lp: self, on_output: opts.OnOutput}
+		cmd.Stdout, cmd.Stderr = w, w
+		// a new session so the command cannot interfere with the terminal
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		if err = cmd.Start(); err != nil {
+			return
+		}
+		go func() {
+			res := CommandResult{}
+			// Wait() returns only after all output has been written
+			code, werr := exit_code(cmd.Wait())
+			res.ExitCode, res.Output = code, w.output.Bytes()
+			self.CallOnMainThread(func() error { return opts.OnComplete(res, werr) })
+		}()
+		return
+	}
+	if self.SuspendAndRun == nil {
+		return ans, fmt.Errorf("Cannot run %s in the terminal as the loop is not running", argv[0])
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	var run_err error
+	err = self.SuspendAndRun(func() error {
+		// errors are not returned here as that would leave the loop suspended
+		run_err = run()
+		if opts.WaitForEnter {
+			if run_err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to run", argv[0], "with error:", run_err)
+			}
+			fmt.Print("\r\nPress Enter to continue")
+			var ln string
+			fmt.Scanln(&ln)
+		}
+		return nil
+	})
+	if err == nil {
+		err = run_err
+	}
+	return
+}
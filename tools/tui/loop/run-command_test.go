@@ -0,0 +1,65 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestRunCommand(t *testing.T) {
+	lp, _ := New()
+	lp.wakeup_channel = make(chan byte, 256)
+	var res CommandResult
+	var err error
+	completed := false
+	output := []byte{}
+	_, err = lp.RunCommand([]string{"sh", "-c", "echo out; echo err >&2; exit 3"}, RunCommandOptions{
+		CaptureOutput: true,
+		OnOutput:      func(data []byte) error { output = append(output, data...); return nil },
+		OnComplete: func(r CommandResult, e error) error {
+			res, err, completed = r, e, true
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the command is run in the background, with the result delivered via
+	// the wakeup mechanism of the loop
+	for deadline := time.Now().Add(10 * time.Second); !completed && time.Now().Before(deadline); {
+		select {
+		case <-lp.wakeup_channel:
+			if err := lp.run_main_thread_callbacks(); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if !completed {
+		t.Fatalf("Running the command did not complete")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(CommandResult{Output: []byte("out\nerr\n"), ExitCode: 3}, res); diff != "" {
+		t.Fatalf("Unexpected result of running command:\n%s", diff)
+	}
+	if diff := cmp.Diff("out\nerr\n", string(output)); diff != "" {
+		t.Fatalf("Unexpected output of running command:\n%s", diff)
+	}
+	if _, err = lp.RunCommand([]string{"sh", "-c", "exit 0"}, RunCommandOptions{}); err == nil {
+		t.Fatalf("Running a command in the terminal did not fail with the loop not running")
+	}
+	if _, err = lp.RunCommand([]string{"sh", "-c", "exit 0"}, RunCommandOptions{CaptureOutput: true}); err == nil {
+		t.Fatalf("Capturing output without OnComplete did not fail")
+	}
+	if _, err = lp.RunCommand(nil, RunCommandOptions{CaptureOutput: true}); err == nil {
+		t.Fatalf("Running no command did not fail")
+	}
+}
@@ -65,15 +65,25 @@ func (self *Loop) update_screen_size() error {
 }
 
 func (self *Loop) handle_csi(raw []byte) error {
-	csi := string(raw)
-	ke := KeyEventFromCSI(csi)
-	if ke != nil {
+	if self.terminal_options.focus_tracking && len(raw) == 1 && (raw[0] == 'I' || raw[0] == 'O') {
+		return self.handle_focus_change(raw[0] == 'I')
+	}
+	if len(self.pending_terminal_queries) > 0 {
+		if handled, err := self.handle_terminal_query_csi(string(raw)); handled {
+			return err
+		}
+	}
+	ke := key_event_pool.Get().(*KeyEvent)
+	defer key_event_pool.Put(ke)
+	if self.csi_decoder.decode_key_event(raw, ke) {
+		ke.CSI = self.csi_decoder.csi_string(raw)
 		return self.handle_key_event(ke)
 	}
 	sz, err := self.ScreenSize()
 	if err == nil {
-		me := MouseEventFromCSI(csi, sz)
-		if me != nil {
+		me := mouse_event_pool.Get().(*MouseEvent)
+		defer mouse_event_pool.Put(me)
+		if self.csi_decoder.decode_mouse_event(raw, sz, me) {
 			return self.handle_mouse_event(me)
 		}
 	}
@@ -93,9 +103,21 @@ func is_click(a, b *MouseEvent) bool {
 
 }
 
-func (self *Loop) handle_mouse_event(ev *MouseEvent) error {
+func (self *Loop) dispatch_mouse_event(ev *MouseEvent) error {
+	if len(self.mouse_regions) > 0 || self.hovered_mouse_region != nil {
+		if handled, err := self.route_mouse_event_to_regions(ev); handled || err != nil {
+			return err
+		}
+	}
 	if self.OnMouseEvent != nil {
-		err := self.OnMouseEvent(ev)
+		return self.OnMouseEvent(ev)
+	}
+	return nil
+}
+
+func (self *Loop) handle_mouse_event(ev *MouseEvent) error {
+	if self.OnMouseEvent != nil || len(self.mouse_regions) > 0 || self.hovered_mouse_region != nil {
+		err := self.dispatch_mouse_event(ev)
 		if err != nil {
 			return err
 		}
@@ -105,11 +127,14 @@ func (self *Loop) handle_mouse_event(ev *MouseEvent) error {
 		case MOUSE_RELEASE:
 			self.pending_mouse_events.WriteAllAndDiscardOld(*ev)
 			if self.pending_mouse_events.Len() > 1 {
-				events := self.pending_mouse_events.ReadAll()
+				var buf [4]MouseEvent
+				events := buf[:self.pending_mouse_events.ReadTillEmpty(buf[:])]
 				if is_click(&events[len(events)-2], &events[len(events)-1]) {
-					e := events[len(events)-1]
+					e := mouse_event_pool.Get().(*MouseEvent)
+					defer mouse_event_pool.Put(e)
+					*e = events[len(events)-1]
 					e.Event_type = MOUSE_CLICK
-					err = self.OnMouseEvent(&e)
+					err = self.dispatch_mouse_event(e)
 					if err != nil {
 						return err
 					}
@@ -121,6 +146,9 @@ func (self *Loop) handle_mouse_event(ev *MouseEvent) error {
 }
 
 func (self *Loop) handle_key_event(ev *KeyEvent) error {
+	if self.key_holds != nil && !ev.Synthesized {
+		self.key_holds.on_key_event(self, ev)
+	}
 	if self.OnKeyEvent != nil {
 		err := self.OnKeyEvent(ev)
 		if err != nil {
@@ -152,6 +180,12 @@ func (self *Loop) handle_osc(raw []byte) error {
 }
 
 func (self *Loop) handle_dcs(raw []byte) error {
+	if handled, err := self.handle_color_depth_response(raw); handled {
+		return err
+	}
+	if self.handle_terminal_query_dcs(raw) {
+		return nil
+	}
 	if self.OnRCResponse != nil && bytes.HasPrefix(raw, utils.UnsafeStringToBytes("@kitty-cmd")) {
 		return self.OnRCResponse(raw[len("@kitty-cmd"):])
 	}
@@ -184,7 +218,7 @@ func (self *Loop) handle_pm(raw []byte) error {
 
 func (self *Loop) handle_rune(raw rune) error {
 	if self.OnText != nil {
-		return self.OnText(string(raw), false, self.escape_code_parser.InBracketedPaste())
+		return self.OnText(string_for_rune(raw), false, self.escape_code_parser.InBracketedPaste())
 	}
 	return nil
 }
@@ -236,13 +270,13 @@ func (self *Loop) on_SIGPIPE() error {
 
 func (self *Loop) on_SIGWINCH() error {
 	self.screen_size.updated = false
-	if self.OnResize != nil {
+	if self.OnResize != nil || self.OnCellSizeChange != nil {
 		old_size := self.screen_size
 		err := self.update_screen_size()
 		if err != nil {
 			return err
 		}
-		return self.OnResize(old_size, self.screen_size)
+		return self.on_screen_size_changed(old_size)
 	}
 	return nil
 }
@@ -322,6 +356,7 @@ func (self *Loop) run() (err error) {
 	}
 
 	self.QueueWriteString(self.terminal_options.SetStateEscapeCodes())
+	self.query_color_depth()
 	needs_reset_escape_codes := true
 
 	shutdown_tty_reader := func() {
@@ -444,6 +479,9 @@ func (self *Loop) run() (err error) {
 			for len(self.wakeup_channel) > 0 {
 				<-self.wakeup_channel
 			}
+			if err = self.run_main_thread_callbacks(); err != nil {
+				return err
+			}
 			if self.OnWakeup != nil {
 				err = self.OnWakeup()
 				if err != nil {
@@ -0,0 +1,185 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type TerminalKind uint8
+
+const (
+	UNKNOWN_TERMINAL TerminalKind = iota
+	KITTY_TERMINAL
+	WEZTERM_TERMINAL
+	ITERM2_TERMINAL
+	VTE_TERMINAL
+	TMUX_TERMINAL
+	WINDOWS_TERMINAL
+	CONEMU_TERMINAL
+	GHOSTTY_TERMINAL
+)
+
+func (self TerminalKind) String() string {
+	switch self {
+	case KITTY_TERMINAL:
+		return "kitty"
+	case WEZTERM_TERMINAL:
+		return "WezTerm"
+	case ITERM2_TERMINAL:
+		return "iTerm2"
+	case VTE_TERMINAL:
+		return "VTE"
+	case TMUX_TERMINAL:
+		return "tmux"
+	case WINDOWS_TERMINAL:
+		return "Windows Terminal"
+	case CONEMU_TERMINAL:
+		return "ConEmu"
+	case GHOSTTY_TERMINAL:
+		return "Ghostty"
+	}
+	return "unknown"
+}
+
+// What the terminal this process is running in supports. When running
+// inside a terminal multiplexer such as tmux, these are the capabilities of
+// the multiplexer, not of the terminal it is running in.
+type TerminalCapabilities struct {
+	Terminal TerminalKind
+	// The version of the terminal, if known
+	Version string
+
+	// Graphics protocols
+	KittyGraphicsProtocol bool
+	ITerm2ImageProtocol   bool
+	Sixel                 bool
+
+	KittyKeyboardProtocol bool
+
+	// Whether OSC 52 can be used to write to the clipboard and the maximum
+	// size of a single OSC 52 escape code, zero if unlimited
+	OSC52        bool
+	OSC52MaxSize int
+
+	// Whether task progress can be reported with OSC 9;4. Many terminals
+	// use OSC 9 for desktop notifications so it must not be sent to them.
+	ProgressReporting bool
+}
+
+// The capabilities of well known terminals in their default configuration
+var terminal_capabilities_db = map[TerminalKind]TerminalCapabilities{
+	UNKNOWN_TERMINAL: {OSC52: true},
+	KITTY_TERMINAL:   {KittyGraphicsProtocol: true, KittyKeyboardProtocol: true, OSC52: true},
+	// WezTerm supports the kitty keyboard protocol only if enable_kitty_keyboard is set
+	WEZTERM_TERMINAL: {KittyGraphicsProtocol: true, ITerm2ImageProtocol: true, Sixel: true, OSC52: true},
+	ITERM2_TERMINAL:  {ITerm2ImageProtocol: true, OSC52: true},
+	VTE_TERMINAL:     {},
+	// escape codes larger than the tmux input buffer are discarded
+	TMUX_TERMINAL:    {OSC52: true, OSC52MaxSize: 1024 * 1024},
+	WINDOWS_TERMINAL: {OSC52: true, ProgressReporting: true},
+	CONEMU_TERMINAL:  {ProgressReporting: true},
+	GHOSTTY_TERMINAL: {KittyGraphicsProtocol: true, KittyKeyboardProtocol: true, OSC52: true, ProgressReporting: true},
+}
+
+func capabilities_for(kind TerminalKind, version string) TerminalCapabilities {
+	ans := terminal_capabilities_db[kind]
+	ans.Terminal, ans.Version = kind, version
+	return ans
+}
+
+func terminal_from_environment() (TerminalKind, string) {
+	if os.Getenv("TMUX") != "" {
+		return TMUX_TERMINAL, ""
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "WezTerm":
+		return WEZTERM_TERMINAL, os.Getenv("TERM_PROGRAM_VERSION")
+	case "iTerm.app":
+		return ITERM2_TERMINAL, os.Getenv("TERM_PROGRAM_VERSION")
+	case "tmux":
+		return TMUX_TERMINAL, os.Getenv("TERM_PROGRAM_VERSION")
+	case "ghostty":
+		return GHOSTTY_TERMINAL, os.Getenv("TERM_PROGRAM_VERSION")
+	}
+	if os.Getenv("TERM") == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "" {
+		return KITTY_TERMINAL, ""
+	}
+	if v := os.Getenv("VTE_VERSION"); v != "" {
+		return VTE_TERMINAL, v
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		return WINDOWS_TERMINAL, ""
+	}
+	if os.Getenv("ConEmuPID") != "" {
+		return CONEMU_TERMINAL, os.Getenv("ConEmuBuild")
+	}
+	return UNKNOWN_TERMINAL, ""
+}
+
+// Parse an XTVERSION response such as: kitty(0.31.0) or tmux 3.3a
+func terminal_from_version(raw string) (kind TerminalKind, version string) {
+	name, version, found := strings.Cut(raw, "(")
+	if found {
+		version = strings.TrimSuffix(version, ")")
+	} else {
+		name, version, _ = strings.Cut(raw, " ")
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "kitty":
+		kind = KITTY_TERMINAL
+	case "wezterm":
+		kind = WEZTERM_TERMINAL
+	case "iterm2":
+		kind = ITERM2_TERMINAL
+	case "vte":
+		kind = VTE_TERMINAL
+	case "tmux":
+		kind = TMUX_TERMINAL
+	case "ghostty":
+		kind = GHOSTTY_TERMINAL
+	}
+	return kind, strings.TrimSpace(version)
+}
+
+// The capabilities of the terminal this process is running in, guessed from
+// environment variables such as TERM and TERM_PROGRAM, without querying the
+// terminal. Unreliable over SSH, where most of these are not forwarded.
+func TerminalCapabilitiesFromEnvironment() TerminalCapabilities {
+	return capabilities_for(terminal_from_environment())
+}
+
+func capabilities_from_query_results(r *TerminalQueryResults) TerminalCapabilities {
+	kind, version := terminal_from_version(r.TerminalVersion)
+	if kind == UNKNOWN_TERMINAL {
+		kind, version = terminal_from_environment()
+	}
+	ans := capabilities_for(kind, version)
+	ans.KittyKeyboardProtocol = r.KittyKeyboardSupported
+	if len(r.DeviceAttributes) > 1 && slices.Contains(r.DeviceAttributes[1:], 4) {
+		ans.Sixel = true
+	}
+	return ans
+}
+
+// Identify the terminal by querying it, refining the guesses from the
+// environment with its XTVERSION and device attributes responses and whether
+// it responds to kitty keyboard protocol queries. The callback is called once,
+// when all responses have been received, or immediately if the terminal has
+// already been identified.
+func (self *Loop) DetectTerminalCapabilities(callback func(*TerminalCapabilities) error) error {
+	if self.terminal_capabilities != nil {
+		return callback(self.terminal_capabilities)
+	}
+	return self.QueryTerminal(TerminalQuery{TerminalVersion: true, KittyKeyboardFlags: true}, func(r *TerminalQueryResults) error {
+		c := capabilities_from_query_results(r)
+		self.terminal_capabilities = &c
+		return callback(self.terminal_capabilities)
+	})
+}
@@ -0,0 +1,71 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestTerminalCapabilities(t *testing.T) {
+	for _, x := range []string{"TMUX", "TERM_PROGRAM", "TERM_PROGRAM_VERSION", "TERM", "KITTY_WINDOW_ID", "VTE_VERSION", "WT_SESSION", "ConEmuPID", "ConEmuBuild"} {
+		t.Setenv(x, "")
+	}
+	env := func(expected TerminalKind, version string, vars ...string) {
+		t.Helper()
+		for i := 0; i < len(vars); i += 2 {
+			t.Setenv(vars[i], vars[i+1])
+		}
+		c := TerminalCapabilitiesFromEnvironment()
+		if c.Terminal != expected || c.Version != version {
+			t.Fatalf("Incorrect terminal detected from %v: %s %#v", vars, c.Terminal, c.Version)
+		}
+		for i := 0; i < len(vars); i += 2 {
+			t.Setenv(vars[i], "")
+		}
+	}
+	env(UNKNOWN_TERMINAL, "")
+	env(KITTY_TERMINAL, "", "TERM", "xterm-kitty")
+	env(WEZTERM_TERMINAL, "20230712", "TERM_PROGRAM", "WezTerm", "TERM_PROGRAM_VERSION", "20230712")
+	env(ITERM2_TERMINAL, "3.4.19", "TERM_PROGRAM", "iTerm.app", "TERM_PROGRAM_VERSION", "3.4.19")
+	env(VTE_TERMINAL, "7200", "VTE_VERSION", "7200")
+	env(WINDOWS_TERMINAL, "", "WT_SESSION", "a6b8b3d0")
+	env(TMUX_TERMINAL, "", "TMUX", "/tmp/tmux-1000/default,1234,0", "TERM", "xterm-kitty")
+
+	for raw, expected := range map[string][2]string{
+		"kitty(0.31.0)": {"kitty", "0.31.0"}, "WezTerm 20230712-072601-f4abf8fd": {"WezTerm", "20230712-072601-f4abf8fd"},
+		"tmux 3.3a": {"tmux", "3.3a"}, "iTerm2 3.5.0": {"iTerm2", "3.5.0"}, "foot(1.16.2)": {"unknown", "1.16.2"},
+	} {
+		kind, version := terminal_from_version(raw)
+		if diff := cmp.Diff(expected, [2]string{kind.String(), version}); diff != "" {
+			t.Fatalf("Failed to parse XTVERSION response %#v:\n%s", raw, diff)
+		}
+	}
+
+	lp := new_loop()
+	var caps *TerminalCapabilities
+	cb := func(c *TerminalCapabilities) error { caps = c; return nil }
+	if err := lp.DetectTerminalCapabilities(cb); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("\x1b[?u\x1b[>0q\x1b[c", lp.pending_writes[0].str); diff != "" {
+		t.Fatalf("Unexpected query:\n%s", diff)
+	}
+	lp.handle_dcs([]byte(">|WezTerm 20230712"))
+	if err := lp.handle_csi([]byte("?62;4;22c")); err != nil {
+		t.Fatal(err)
+	}
+	expected := &TerminalCapabilities{Terminal: WEZTERM_TERMINAL, Version: "20230712", KittyGraphicsProtocol: true, ITerm2ImageProtocol: true, Sixel: true, OSC52: true}
+	if diff := cmp.Diff(expected, caps); diff != "" {
+		t.Fatalf("Unexpected capabilities:\n%s", diff)
+	}
+	caps = nil
+	lp.DetectTerminalCapabilities(cb)
+	if caps == nil || len(lp.pending_writes) != 1 {
+		t.Fatalf("Detected capabilities not cached")
+	}
+}
@@ -98,6 +98,9 @@ type TerminalStateOptions struct {
 	alternate_screen, restore_colors bool
 	mouse_tracking                   MouseTracking
 	kitty_keyboard_mode              KeyboardStateBits
+	focus_tracking                   bool
+	keyboard_flags_stack             []KeyboardStateBits
+	progress                         progress_report
 }
 
 func set_modes(sb *strings.Builder, modes ...Mode) {
@@ -128,6 +131,9 @@ func (self *TerminalStateOptions) SetStateEscapeCodes() string {
 		IRM, DECKM, DECSCNM, BRACKETED_PASTE, FOCUS_TRACKING,
 		MOUSE_BUTTON_TRACKING, MOUSE_MOTION_TRACKING, MOUSE_MOVE_TRACKING, MOUSE_UTF8_MODE, MOUSE_SGR_MODE)
 	set_modes(&sb, DECARM, DECAWM, DECTCEM)
+	if self.focus_tracking {
+		set_modes(&sb, FOCUS_TRACKING)
+	}
 	if self.alternate_screen {
 		set_modes(&sb, ALTERNATE_SCREEN)
 		sb.WriteString(CLEAR_SCREEN)
@@ -137,6 +143,9 @@ func (self *TerminalStateOptions) SetStateEscapeCodes() string {
 	} else {
 		sb.WriteString("\033[>u")
 	}
+	for _, flags := range self.keyboard_flags_stack {
+		sb.WriteString(fmt.Sprintf("\033[>%du", flags))
+	}
 	if self.mouse_tracking != NO_MOUSE_TRACKING {
 		sb.WriteString(MOUSE_SGR_PIXEL_MODE.EscapeCodeToSet())
 		switch self.mouse_tracking {
@@ -148,13 +157,20 @@ func (self *TerminalStateOptions) SetStateEscapeCodes() string {
 			sb.WriteString(MOUSE_MOVE_TRACKING.EscapeCodeToSet())
 		}
 	}
+	if self.progress.state != PROGRESS_HIDDEN {
+		sb.WriteString(ProgressEscapeCode(self.progress.state, self.progress.percent))
+	}
 	return sb.String()
 }
 
 func (self *TerminalStateOptions) ResetStateEscapeCodes() string {
 	var sb strings.Builder
 	sb.Grow(64)
-	sb.WriteString("\033[<u")
+	if n := len(self.keyboard_flags_stack); n > 0 {
+		sb.WriteString(fmt.Sprintf("\033[<%du", n+1))
+	} else {
+		sb.WriteString("\033[<u")
+	}
 	if self.alternate_screen {
 		sb.WriteString(ALTERNATE_SCREEN.EscapeCodeToReset())
 	} else {
@@ -165,6 +181,9 @@ func (self *TerminalStateOptions) ResetStateEscapeCodes() string {
 		sb.WriteString(RESTORE_CURSOR)
 	}
 	sb.WriteString(RESTORE_COLORS)
+	if self.progress.state != PROGRESS_HIDDEN {
+		sb.WriteString(ProgressEscapeCode(PROGRESS_HIDDEN, 0))
+	}
 	return sb.String()
 }
 
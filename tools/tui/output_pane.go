@@ -0,0 +1,113 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+// Displays the output of a command in a region of the screen, keeping the
+// rest of the UI, see RunCommandInPane(). Escape codes in the output are
+// removed and only the most recent lines are kept.
+type OutputPane struct {
+	// The maximum number of complete lines to keep, defaults to 1000
+	MaxLines int
+
+	lines   []string
+	partial strings.Builder
+}
+
+func (self *OutputPane) add_line(line string) {
+	max_lines := self.MaxLines
+	if max_lines <= 0 {
+		max_lines = 1000
+	}
+	self.lines = append(self.lines, line)
+	if extra := len(self.lines) - max_lines; extra > 0 {
+		self.lines = append(self.lines[:0], self.lines[extra:]...)
+	}
+}
+
+func (self *OutputPane) Write(data []byte) (int, error) {
+	text := string(data)
+	for text != "" {
+		line, rest, found := strings.Cut(text, "\n")
+		self.partial.WriteString(line)
+		if !found {
+			break
+		}
+		self.add_line(self.partial.String())
+		self.partial.Reset()
+		text = rest
+	}
+	return len(data), nil
+}
+
+func clean_output_line(line string) string {
+	line = wcswidth.StripEscapeCodes(line)
+	// a carriage return means the rest of the line overwrites the start
+	if idx := strings.LastIndexByte(strings.TrimRight(line, "\r"), '\r'); idx > -1 {
+		line = line[idx+1:]
+	}
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			if r == '\t' {
+				return ' '
+			}
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// The lines of output, including an incomplete last line, if any
+func (self *OutputPane) Lines() []string {
+	ans := make([]string, 0, len(self.lines)+1)
+	for _, line := range self.lines {
+		ans = append(ans, clean_output_line(line))
+	}
+	if self.partial.Len() > 0 {
+		ans = append(ans, clean_output_line(self.partial.String()))
+	}
+	return ans
+}
+
+func (self *OutputPane) Clear() {
+	self.lines = self.lines[:0]
+	self.partial.Reset()
+}
+
+// Draw the last lines of the output into the region with the specified
+// zero based top left corner and size, blanking the rest of the region
+func (self *OutputPane) Draw(lp *loop.Loop, left, top, width, height int) {
+	lines := self.Lines()
+	lines = lines[max(0, len(lines)-height):]
+	for y := 0; y < height; y++ {
+		line := ""
+		if y < len(lines) {
+			line = wcswidth.TruncateToVisualLength(lines[y], width)
+		}
+		lp.MoveCursorTo(left+1, top+y+1)
+		lp.QueueWriteString(line + strings.Repeat(" ", max(0, width-wcswidth.Stringwidth(line))))
+	}
+}
+
+// Run a command capturing its output into pane, without suspending the UI.
+// redraw is called whenever there is new output, it should call pane.Draw().
+// on_complete is called when the command exits.
+func RunCommandInPane(lp *loop.Loop, argv []string, opts loop.RunCommandOptions, pane *OutputPane, redraw func() error, on_complete func(res loop.CommandResult, err error) error) error {
+	opts.CaptureOutput = true
+	opts.OnOutput = func(data []byte) error {
+		pane.Write(data)
+		return redraw()
+	}
+	opts.OnComplete = on_complete
+	_, err := lp.RunCommand(argv, opts)
+	return err
+}
@@ -0,0 +1,30 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestOutputPane(t *testing.T) {
+	p := OutputPane{MaxLines: 3}
+	w := func(text string) { p.Write([]byte(text)) }
+	test := func(expected ...string) {
+		if diff := cmp.Diff(expected, p.Lines()); diff != "" {
+			t.Fatalf("Unexpected lines:\n%s", diff)
+		}
+	}
+	w("one\n\x1b[31mtw")
+	test("one", "tw")
+	w("o\x1b[m\r\n")
+	test("one", "two")
+	w("50%\r100%\tdone\nfour\nfive")
+	test("two", "100% done", "four", "five")
+	p.Clear()
+	test([]string{}...)
+}
@@ -0,0 +1,246 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/utils/style"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+type Overlay struct {
+	Title, Message, Footer string
+	// Maximum width of the dialog in cells, defaults to most of the screen width
+	MaxWidth int
+
+	// Called with key events while this overlay is on top of the stack. Set
+	// ev.Handled to prevent the default handling, which is to dismiss the
+	// overlay on esc.
+	OnKeyEvent func(ev *loop.KeyEvent) error
+	// Called with text while this overlay is on top of the stack
+	OnText func(text string, from_key_event bool, in_bracketed_paste bool) error
+	// Called after the overlay has been removed from the stack
+	OnDismiss func() error
+
+	geometry overlay_geometry
+}
+
+type overlay_geometry struct {
+	left, top, width, height int // of the outer box, zero based
+	inner_width              int
+	title, footer            string
+	lines                    []string
+}
+
+func (self *overlay_geometry) contains(x, y int) bool {
+	return self.left <= x && x < self.left+self.width && self.top <= y && y < self.top+self.height
+}
+
+func layout_overlay(o *Overlay, screen_width, screen_height int) (g overlay_geometry) {
+	const chrome = 4 // borders plus one cell of padding on either side
+	max_inner := screen_width*4/5 - chrome
+	if o.MaxWidth > 0 {
+		max_inner = min(o.MaxWidth, screen_width-chrome)
+	}
+	max_inner = max(1, max_inner)
+	g.lines = style.WrapTextAsLines(o.Message, max_inner, style.WrapOptions{})
+	g.title = wcswidth.TruncateToVisualLength(o.Title, max(0, max_inner-2))
+	g.footer = wcswidth.TruncateToVisualLength(o.Footer, max_inner)
+	g.inner_width = max(wcswidth.Stringwidth(g.title)+2, wcswidth.Stringwidth(g.footer))
+	for _, line := range g.lines {
+		g.inner_width = max(g.inner_width, wcswidth.Stringwidth(line))
+	}
+	g.inner_width = min(g.inner_width, max_inner)
+	extra := 2
+	if g.footer != "" {
+		extra += 2
+	}
+	if max_lines := max(1, screen_height-extra); len(g.lines) > max_lines {
+		g.lines = g.lines[:max_lines]
+	}
+	g.width = g.inner_width + chrome
+	g.height = len(g.lines) + extra
+	g.left = max(0, (screen_width-g.width)/2)
+	g.top = max(0, (screen_height-g.height)/2)
+	return
+}
+
+// A stack of modal dialogs drawn centered over a dimmed version of whatever
+// the kitten has drawn. While the stack is not empty kittens should send all
+// input to it rather than handling it themselves, for example:
+//
+//	if !overlays.IsEmpty() {
+//		return overlays.OnKeyEvent(ev)
+//	}
+type OverlayStack struct {
+	lp       *loop.Loop
+	overlays []*Overlay
+	redraw   func() error
+}
+
+// redraw must draw the complete screen of the kitten and then call Draw() so
+// that the overlays are drawn on top. It is called when an overlay is dismissed.
+func NewOverlayStack(lp *loop.Loop, redraw func() error) *OverlayStack {
+	return &OverlayStack{lp: lp, redraw: redraw}
+}
+
+func (self *OverlayStack) IsEmpty() bool { return len(self.overlays) == 0 }
+
+func (self *OverlayStack) Top() *Overlay {
+	if len(self.overlays) == 0 {
+		return nil
+	}
+	return self.overlays[len(self.overlays)-1]
+}
+
+func (self *OverlayStack) Push(o *Overlay) {
+	self.overlays = append(self.overlays, o)
+	self.lp.StartAtomicUpdate()
+	defer self.lp.EndAtomicUpdate()
+	self.draw_overlay(o)
+}
+
+func (self *OverlayStack) Pop() error {
+	o := self.Top()
+	if o == nil {
+		return nil
+	}
+	self.overlays = self.overlays[:len(self.overlays)-1]
+	if err := self.redraw(); err != nil {
+		return err
+	}
+	if o.OnDismiss != nil {
+		return o.OnDismiss()
+	}
+	return nil
+}
+
+func (self *OverlayStack) Clear() error {
+	for !self.IsEmpty() {
+		if err := self.Pop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *OverlayStack) dim_screen() {
+	sz, err := self.lp.ScreenSize()
+	if err == nil {
+		self.lp.QueueWriteString(fmt.Sprintf("\x1b[1;1;%d;%d;2$r", sz.HeightCells, sz.WidthCells))
+	}
+}
+
+func (self *OverlayStack) draw_overlay(o *Overlay) {
+	sz, err := self.lp.ScreenSize()
+	if err != nil {
+		return
+	}
+	self.dim_screen()
+	o.geometry = layout_overlay(o, int(sz.WidthCells), int(sz.HeightCells))
+	g := &o.geometry
+	y := g.top + 1
+	line := func(text string) {
+		self.lp.MoveCursorTo(g.left+1, y)
+		self.lp.QueueWriteString(text)
+		y++
+	}
+	padded := func(text string) string {
+		return "│ " + text + strings.Repeat(" ", max(0, g.inner_width-wcswidth.Stringwidth(text))) + "\x1b[m │"
+	}
+	self.lp.QueueWriteString("\x1b[m")
+	top := "╭"
+	if g.title != "" {
+		top += "─" + self.lp.SprintStyled("bold", " "+g.title+" ")
+	}
+	line(top + strings.Repeat("─", max(0, g.width-1-wcswidth.Stringwidth(top))) + "╮")
+	for _, l := range g.lines {
+		line(padded(l))
+	}
+	if g.footer != "" {
+		line(padded(""))
+		line(padded(self.lp.SprintStyled("dim", g.footer)))
+	}
+	line("╰" + strings.Repeat("─", g.width-2) + "╯")
+}
+
+// Draw all overlays on top of the current screen contents. Must be called by
+// the kitten at the end of drawing its screen.
+func (self *OverlayStack) Draw() {
+	for _, o := range self.overlays {
+		self.draw_overlay(o)
+	}
+}
+
+func (self *OverlayStack) OnKeyEvent(ev *loop.KeyEvent) error {
+	o := self.Top()
+	if o == nil {
+		return nil
+	}
+	if o.OnKeyEvent != nil {
+		if err := o.OnKeyEvent(ev); err != nil || ev.Handled {
+			return err
+		}
+	}
+	if ev.MatchesPressOrRepeat("esc") {
+		ev.Handled = true
+		return self.Pop()
+	}
+	return nil
+}
+
+func (self *OverlayStack) OnText(text string, from_key_event bool, in_bracketed_paste bool) error {
+	if o := self.Top(); o != nil && o.OnText != nil {
+		return o.OnText(text, from_key_event, in_bracketed_paste)
+	}
+	return nil
+}
+
+// Clicking outside the top most overlay dismisses it.
+func (self *OverlayStack) OnMouseEvent(ev *loop.MouseEvent) error {
+	if o := self.Top(); o != nil && ev.Event_type == loop.MOUSE_CLICK && !o.geometry.contains(ev.Cell.X, ev.Cell.Y) {
+		return self.Pop()
+	}
+	return nil
+}
+
+// Show a message, such as an error or a help screen, dismissed by esc, enter or q
+func (self *OverlayStack) ShowMessage(title, message string) *Overlay {
+	o := &Overlay{Title: title, Message: message, Footer: "Press Esc to close"}
+	o.OnKeyEvent = func(ev *loop.KeyEvent) error {
+		if ev.MatchesPressOrRepeat("enter") || ev.MatchesPressOrRepeat("q") {
+			ev.Handled = true
+			return self.Pop()
+		}
+		return nil
+	}
+	self.Push(o)
+	return o
+}
+
+// Ask the user a yes/no question. callback is called with the answer after the
+// overlay is dismissed. Dismissing the overlay by any means other than
+// pressing y or enter is treated as no.
+func (self *OverlayStack) Confirm(title, message string, callback func(confirmed bool) error) *Overlay {
+	confirmed := false
+	o := &Overlay{Title: title, Message: message, Footer: "[Y]es  [N]o"}
+	o.OnKeyEvent = func(ev *loop.KeyEvent) error {
+		switch {
+		case ev.MatchesPressOrRepeat("y") || ev.MatchesPressOrRepeat("enter"):
+			confirmed = true
+		case ev.MatchesPressOrRepeat("n"):
+		default:
+			return nil
+		}
+		ev.Handled = true
+		return self.Pop()
+	}
+	o.OnDismiss = func() error { return callback(confirmed) }
+	self.Push(o)
+	return o
+}
@@ -0,0 +1,36 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestOverlayLayout(t *testing.T) {
+	test := func(o Overlay, sw, sh int) overlay_geometry {
+		g := layout_overlay(&o, sw, sh)
+		if g.left < 0 || g.top < 0 || g.left+g.width > sw || g.top+g.height > sh {
+			t.Fatalf("Overlay %#v does not fit on %dx%d screen: %#v", o, sw, sh, g)
+		}
+		if g.width != g.inner_width+4 {
+			t.Fatalf("Overlay width %d inconsistent with inner width %d", g.width, g.inner_width)
+		}
+		return g
+	}
+	g := test(Overlay{Title: "Title", Message: "Some text"}, 80, 24)
+	if g.height != 3 || g.inner_width != 9 || g.left != (80-13)/2 {
+		t.Fatalf("Unexpected geometry: %#v", g)
+	}
+	g = test(Overlay{Message: strings.Repeat("word ", 100), Footer: "footer"}, 40, 10)
+	if len(g.lines) != 6 {
+		t.Fatalf("Message not truncated to screen height: %d", len(g.lines))
+	}
+	test(Overlay{Title: strings.Repeat("x", 100), MaxWidth: 200}, 20, 3)
+	if !g.contains(g.left, g.top) || g.contains(g.left+g.width, g.top) {
+		t.Fatalf("contains() is incorrect for: %#v", g)
+	}
+}
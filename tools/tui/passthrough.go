@@ -0,0 +1,93 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var _ = fmt.Print
+
+// Terminal multiplexers do not forward escape codes they do not understand,
+// such as graphics protocol escape codes, to the terminal they are running in.
+// Instead they provide a DCS escape code that wraps arbitrary data to be passed
+// through to the terminal.
+type PassthroughMode int
+
+const (
+	NO_PASSTHROUGH PassthroughMode = iota
+	TMUX_PASSTHROUGH
+	SCREEN_PASSTHROUGH
+)
+
+// The maximum number of bytes passed through in a single DCS escape code.
+// tmux discards escape codes that overflow its 1MB input buffer and GNU screen
+// truncates strings longer than a few hundred bytes.
+const (
+	TMUX_MAX_PASSTHROUGH_LENGTH   = 512 * 1024
+	SCREEN_MAX_PASSTHROUGH_LENGTH = 512
+)
+
+// The terminal multiplexer, if any, this process is running in
+func DetectPassthrough() PassthroughMode {
+	if TmuxSocketAddress() != "" {
+		return TMUX_PASSTHROUGH
+	}
+	if os.Getenv("STY") != "" {
+		return SCREEN_PASSTHROUGH
+	}
+	return NO_PASSTHROUGH
+}
+
+// Ensure the multiplexer will actually pass through wrapped escape codes
+func (self PassthroughMode) Enable() error {
+	if self == TMUX_PASSTHROUGH {
+		return TmuxAllowPassthrough()
+	}
+	return nil
+}
+
+// Wrap the escape code esc so that it is passed through to the terminal,
+// splitting it into multiple DCS escape codes if it is too long.
+func (self PassthroughMode) Wrap(esc string) string {
+	switch self {
+	case TMUX_PASSTHROUGH:
+		return wrap_for_tmux(esc)
+	case SCREEN_PASSTHROUGH:
+		return wrap_for_screen(esc)
+	}
+	return esc
+}
+
+func wrap_for_tmux(esc string) string {
+	b := strings.Builder{}
+	b.Grow(len(esc) + 64)
+	for len(esc) > 0 {
+		n := min(len(esc), TMUX_MAX_PASSTHROUGH_LENGTH)
+		b.WriteString("\x1bPtmux;")
+		b.WriteString(strings.ReplaceAll(esc[:n], "\x1b", "\x1b\x1b"))
+		b.WriteString("\x1b\\")
+		esc = esc[n:]
+	}
+	return b.String()
+}
+
+func wrap_for_screen(esc string) string {
+	b := strings.Builder{}
+	b.Grow(len(esc) + 4*(len(esc)/SCREEN_MAX_PASSTHROUGH_LENGTH+1))
+	for len(esc) > 0 {
+		n := min(len(esc), SCREEN_MAX_PASSTHROUGH_LENGTH)
+		// screen has no way to escape ST so split the data between the ESC
+		// and backslash of any ST in it
+		if i := strings.Index(esc[:min(len(esc), n+1)], "\x1b\\"); i > -1 {
+			n = i + 1
+		}
+		b.WriteString("\x1bP")
+		b.WriteString(esc[:n])
+		b.WriteString("\x1b\\")
+		esc = esc[n:]
+	}
+	return b.String()
+}
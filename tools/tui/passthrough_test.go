@@ -0,0 +1,72 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestPassthroughWrapping(t *testing.T) {
+	esc := "\x1b_Ga=T;abcd\x1b\\"
+	if actual := NO_PASSTHROUGH.Wrap(esc); actual != esc {
+		t.Fatalf("Escape code unexpectedly wrapped: %#v", actual)
+	}
+	if diff := cmp.Diff("\x1bPtmux;\x1b\x1b_Ga=T;abcd\x1b\x1b\\\x1b\\", TMUX_PASSTHROUGH.Wrap(esc)); diff != "" {
+		t.Fatalf("Incorrect tmux wrapping:\n%s", diff)
+	}
+	if diff := cmp.Diff("\x1bP\x1b_Ga=T;abcd\x1b\x1b\\\x1bP\\\x1b\\", SCREEN_PASSTHROUGH.Wrap(esc)); diff != "" {
+		t.Fatalf("Incorrect screen wrapping:\n%s", diff)
+	}
+
+	unwrap := func(wrapped, prefix string, max_length int) (chunks []string) {
+		t.Helper()
+		for wrapped != "" {
+			rest, found := strings.CutPrefix(wrapped, prefix)
+			if !found {
+				t.Fatalf("Wrapped data does not start with the DCS prefix: %#v", wrapped[:min(32, len(wrapped))])
+			}
+			// an ST either terminates the DCS or is preceded by an escaped ESC
+			i := 0
+			for {
+				j := strings.Index(rest[i:], "\x1b\\")
+				if j < 0 {
+					t.Fatalf("Wrapped data is not terminated")
+				}
+				if prefix == "\x1bPtmux;" && strings.Count(rest[:i+j+1], "\x1b")%2 == 0 {
+					i += j + 2
+					continue
+				}
+				chunks = append(chunks, rest[:i+j])
+				wrapped = rest[i+j+2:]
+				break
+			}
+			if n := len(chunks[len(chunks)-1]); n > max_length*2 {
+				t.Fatalf("Chunk of length %d too long", n)
+			}
+		}
+		return
+	}
+	long := "\x1b]99;;" + strings.Repeat("abc\x1b", 700) + "\x1b\\"
+	chunks := unwrap(SCREEN_PASSTHROUGH.Wrap(long), "\x1bP", SCREEN_MAX_PASSTHROUGH_LENGTH)
+	if len(chunks) < 6 {
+		t.Fatalf("Long escape code not split for screen, got %d chunks", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c) > SCREEN_MAX_PASSTHROUGH_LENGTH || strings.Contains(c, "\x1b\\") {
+			t.Fatalf("Invalid screen chunk: %#v", c)
+		}
+	}
+	if diff := cmp.Diff(long, strings.Join(chunks, "")); diff != "" {
+		t.Fatalf("Screen chunks do not reassemble to the original:\n%s", diff)
+	}
+	chunks = unwrap(TMUX_PASSTHROUGH.Wrap(long), "\x1bPtmux;", TMUX_MAX_PASSTHROUGH_LENGTH)
+	if diff := cmp.Diff([]string{strings.ReplaceAll(long, "\x1b", "\x1b\x1b")}, chunks); diff != "" {
+		t.Fatalf("Incorrect tmux wrapping:\n%s", diff)
+	}
+}
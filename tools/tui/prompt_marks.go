@@ -0,0 +1,230 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ = fmt.Print
+
+// The prefix of the OSC 133 escape codes used by shell integration to mark
+// prompts and command output. kitty emits these at the start of lines when
+// outputting the screen contents as ANSI text, for example via get-text
+// --ansi or the scrollback pager.
+const PROMPT_MARK_PREFIX = "\x1b]133;"
+
+type PromptMarkType byte
+
+const (
+	PROMPT_START     PromptMarkType = 'A'
+	COMMAND_START    PromptMarkType = 'B'
+	OUTPUT_START     PromptMarkType = 'C'
+	COMMAND_FINISHED PromptMarkType = 'D'
+)
+
+type PromptMark struct {
+	Type PromptMarkType
+	// Set for the continuation lines of multi-line prompts, marked with k=s
+	Secondary bool
+	// The exit status for COMMAND_FINISHED marks, -1 if not known
+	ExitStatus int
+	// The duration of the command for COMMAND_FINISHED marks, as reported by
+	// shell integration, -1 if not known
+	Duration time.Duration
+	// Byte offsets of the escape code in the text
+	Start, End int
+	// The zero based number of the line the mark is on
+	Line int
+}
+
+// Parse the payload of an OSC 133 escape code, that is, everything after
+// the 133;
+func ParsePromptMark(payload string) (ans PromptMark, ok bool) {
+	if payload == "" {
+		return
+	}
+	ans.Type = PromptMarkType(payload[0])
+	ans.ExitStatus, ans.Duration = -1, -1
+	switch ans.Type {
+	case PROMPT_START, COMMAND_START, OUTPUT_START, COMMAND_FINISHED:
+	default:
+		return ans, false
+	}
+	if len(payload) > 1 && payload[1] != ';' {
+		return ans, false
+	}
+	for i, x := range strings.Split(payload, ";")[1:] {
+		if k, v, found := strings.Cut(x, "="); found {
+			switch k {
+			case "k":
+				ans.Secondary = v == "s"
+			case "duration":
+				if ms, err := strconv.ParseUint(v, 10, 64); err == nil {
+					ans.Duration = time.Duration(ms) * time.Millisecond
+				}
+			}
+		} else if i == 0 && ans.Type == COMMAND_FINISHED {
+			if q, err := strconv.Atoi(x); err == nil {
+				ans.ExitStatus = q
+			}
+		}
+	}
+	return ans, true
+}
+
+// Find all OSC 133 marks in text, terminated by either ST or BEL
+func FindPromptMarks(text string) (ans []PromptMark) {
+	line, counted_upto := 0, 0
+	for pos := 0; pos < len(text); {
+		idx := strings.Index(text[pos:], PROMPT_MARK_PREFIX)
+		if idx < 0 {
+			break
+		}
+		start := pos + idx
+		payload_start := start + len(PROMPT_MARK_PREFIX)
+		end := strings.IndexAny(text[payload_start:], "\x1b\a")
+		if end < 0 {
+			break
+		}
+		payload := text[payload_start : payload_start+end]
+		end += payload_start
+		if text[end] == '\a' {
+			end++
+		} else if strings.HasPrefix(text[end:], "\x1b\\") {
+			end += 2
+		} else {
+			pos = end
+			continue
+		}
+		pos = end
+		if m, ok := ParsePromptMark(payload); ok {
+			line += strings.Count(text[counted_upto:start], "\n")
+			counted_upto = start
+			m.Start, m.End, m.Line = start, end, line
+			ans = append(ans, m)
+		}
+	}
+	return
+}
+
+// Remove all OSC 133 marks from text
+func StripPromptMarks(text string) string {
+	marks := FindPromptMarks(text)
+	if len(marks) == 0 {
+		return text
+	}
+	ans := strings.Builder{}
+	ans.Grow(len(text))
+	prev := 0
+	for _, m := range marks {
+		ans.WriteString(text[prev:m.Start])
+		prev = m.End
+	}
+	ans.WriteString(text[prev:])
+	return ans.String()
+}
+
+// A single command as delimited by prompt marks. All text is with the marks
+// removed, line numbers are the same with and without marks.
+type CommandBlock struct {
+	// The prompt, including the command line, when the command start is not marked
+	Prompt string
+	// The command line, when the command start is marked
+	Command string
+	Output  string
+	// The line the prompt starts on, -1 for output at the start of the text
+	// whose prompt is not present
+	PromptLine int
+	// The line the output starts on, -1 if there is no output
+	OutputLine int
+	// The exit status of the command, -1 if not known
+	ExitStatus int
+	// The duration of the command, -1 if not known
+	Duration time.Duration
+}
+
+func (self CommandBlock) HasOutput() bool { return self.OutputLine > -1 }
+
+type command_region uint8
+
+const (
+	in_prompt command_region = iota
+	in_command
+	in_output
+	after_output
+)
+
+// Split text into the commands delimited by the prompt marks in it. Text
+// before the first prompt is treated as the output of a command whose prompt
+// is not present in text.
+func SplitIntoCommands(text string) (ans []CommandBlock) {
+	var prompt, command, output strings.Builder
+	cb := CommandBlock{PromptLine: -1, OutputLine: 0, ExitStatus: -1, Duration: -1}
+	region := in_output
+	finish := func() {
+		cb.Prompt, cb.Command, cb.Output = prompt.String(), command.String(), output.String()
+		if cb.PromptLine > -1 || cb.Output != "" {
+			ans = append(ans, cb)
+		}
+		prompt.Reset()
+		command.Reset()
+		output.Reset()
+	}
+	add := func(s string) {
+		switch region {
+		case in_prompt:
+			prompt.WriteString(s)
+		case in_command:
+			command.WriteString(s)
+		case in_output:
+			output.WriteString(s)
+		}
+	}
+	prev := 0
+	for _, m := range FindPromptMarks(text) {
+		add(text[prev:m.Start])
+		prev = m.End
+		switch m.Type {
+		case PROMPT_START:
+			if !m.Secondary || region == in_output || region == after_output {
+				finish()
+				cb = CommandBlock{PromptLine: m.Line, OutputLine: -1, ExitStatus: -1, Duration: -1}
+				region = in_prompt
+			}
+		case COMMAND_START:
+			if region == in_prompt {
+				region = in_command
+			}
+		case OUTPUT_START:
+			if cb.PromptLine < 0 {
+				// output from before this mark belongs to some other command
+				output.Reset()
+			}
+			cb.OutputLine = m.Line
+			region = in_output
+		case COMMAND_FINISHED:
+			cb.ExitStatus, cb.Duration = m.ExitStatus, m.Duration
+			region = after_output
+		}
+	}
+	add(text[prev:])
+	finish()
+	return
+}
+
+// The output of the last command in text that has output. Useful to get the
+// output of the last command from the ANSI text of the screen, where the
+// last command is usually the prompt currently being edited.
+func LastCommandOutput(text string) (string, bool) {
+	blocks := SplitIntoCommands(text)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if b := blocks[i]; b.HasOutput() && b.PromptLine > -1 {
+			return b.Output, true
+		}
+	}
+	return "", false
+}
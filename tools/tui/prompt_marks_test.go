@@ -0,0 +1,51 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestPromptMarks(t *testing.T) {
+	m := func(payload string) string { return PROMPT_MARK_PREFIX + payload + "\x1b\\" }
+	text := "old output\n" + m("A") + "$ ls\n" + m("C") + "a\nb\n" + m("A") + "$ false\n" + m("A;k=s") + "> x\n" + m("C") + "c\n" + m("D;1;duration=1500") + m("A") + "$ "
+	if diff := cmp.Diff("old output\n$ ls\na\nb\n$ false\n> x\nc\n$ ", StripPromptMarks(text)); diff != "" {
+		t.Fatalf("Failed to strip prompt marks:\n%s", diff)
+	}
+	if diff := cmp.Diff(PromptMark{Type: COMMAND_FINISHED, ExitStatus: 3, Duration: -1}, FindPromptMarks("\x1b]133;D;3\a")[0], cmp.FilterPath(
+		func(p cmp.Path) bool { return p.Last().String() == ".End" }, cmp.Ignore())); diff != "" {
+		t.Fatalf("Failed to parse BEL terminated mark:\n%s", diff)
+	}
+	if marks := FindPromptMarks("\x1b]133;X\x1b\\\x1b]133;A"); len(marks) != 0 {
+		t.Fatalf("Invalid marks parsed: %#v", marks)
+	}
+	expected := []CommandBlock{
+		{Output: "old output\n", PromptLine: -1, OutputLine: 0, ExitStatus: -1, Duration: -1},
+		{Prompt: "$ ls\n", Output: "a\nb\n", PromptLine: 1, OutputLine: 2, ExitStatus: -1, Duration: -1},
+		{Prompt: "$ false\n> x\n", Output: "c\n", PromptLine: 4, OutputLine: 6, ExitStatus: 1, Duration: 1500 * time.Millisecond},
+		{Prompt: "$ ", PromptLine: 7, OutputLine: -1, ExitStatus: -1, Duration: -1},
+	}
+	if diff := cmp.Diff(expected, SplitIntoCommands(text)); diff != "" {
+		t.Fatalf("Failed to split into commands:\n%s", diff)
+	}
+	if out, found := LastCommandOutput(text); !found || out != "c\n" {
+		t.Fatalf("Incorrect last command output: %#v", out)
+	}
+	text = "junk\n" + m("C") + "x\n" + m("A") + "$ " + m("B") + "echo\n" + m("C") + "echo\n"
+	expected = []CommandBlock{
+		{Output: "x\n", PromptLine: -1, OutputLine: 1, ExitStatus: -1, Duration: -1},
+		{Prompt: "$ ", Command: "echo\n", Output: "echo\n", PromptLine: 2, OutputLine: 3, ExitStatus: -1, Duration: -1},
+	}
+	if diff := cmp.Diff(expected, SplitIntoCommands(text)); diff != "" {
+		t.Fatalf("Failed to split into commands:\n%s", diff)
+	}
+	if _, found := LastCommandOutput("no marks"); found {
+		t.Fatalf("Found output without prompt marks")
+	}
+}
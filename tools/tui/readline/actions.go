@@ -217,6 +217,8 @@ func (self *Readline) move_to_end() bool {
 	return true
 }
 
+// Erase the text between start and end, returning the erased text, including
+// the line breaks, so that it can be put into the kill ring and yanked back
 func (self *Readline) erase_between(start, end Position) string {
 	if end.Less(start) {
 		start, end = end, start
@@ -242,6 +244,7 @@ func (self *Readline) erase_between(start, end Position) string {
 		} else if i == start.Y {
 			lines = append(lines, line[:start.X])
 			buf.WriteString(line[start.X:])
+			buf.WriteString("\n")
 			if self.input_state.cursor.Y == i && self.input_state.cursor.X > start.X {
 				self.input_state.cursor.X = start.X
 			}
@@ -552,7 +555,13 @@ func (self *Readline) _perform_action(ac Action, repeat_count uint) (err error,
 		}
 		return
 	case ActionAcceptInput:
-		err = ErrAcceptInput
+		// show the result of history expansion so the user can check it
+		// before it is accepted, like bash's histverify
+		if changed, herr := self.expand_history_in_input(); herr != nil {
+			err = ErrCouldNotPerformAction
+		} else if !changed {
+			err = ErrAcceptInput
+		}
 		return
 	case ActionCursorUp:
 		if self.move_cursor_vertically(-int(repeat_count)) != 0 {
@@ -616,6 +625,14 @@ func (self *Readline) _perform_action(ac Action, repeat_count uint) (err error,
 		if self.kill_previous_space_delimited_word(repeat_count, true) > 0 {
 			return
 		}
+	case ActionKillSelection:
+		if self.kill_selection() {
+			return
+		}
+	case ActionCopySelection:
+		if self.copy_selection() {
+			return
+		}
 	case ActionYank:
 		if self.yank(repeat_count, false) {
 			return
@@ -677,7 +694,11 @@ func (self *Readline) _perform_action(ac Action, repeat_count uint) (err error,
 }
 
 func (self *Readline) perform_action(ac Action, repeat_count uint) error {
+	if ac == ActionKillPreviousSpaceDelimitedWord && self.has_selection() {
+		ac = ActionKillSelection
+	}
 	err, dont_set_last_action := self._perform_action(ac, repeat_count)
+	self.clear_selection()
 	if err == nil && !dont_set_last_action {
 		self.last_action = ac
 		if self.completions.current.results != nil && ac != ActionCompleteForward && ac != ActionCompleteBackward {
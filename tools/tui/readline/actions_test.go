@@ -6,6 +6,7 @@ import (
 	"container/list"
 	"fmt"
 	"kitty/tools/cli"
+	"kitty/tools/cli/markup"
 	"kitty/tools/tui/loop"
 	"kitty/tools/utils/shlex"
 	"strconv"
@@ -139,6 +140,57 @@ func TestGetScreenLines(t *testing.T) {
 	)
 }
 
+func TestRowsToRepaint(t *testing.T) {
+	rl := new_rl()
+	rl.add_text(strings.Repeat("abcd", 10))
+	rows_for := func() []drawn_row {
+		rows, _, _, ok := rl.rows_for_screen_lines(rl.get_screen_lines())
+		if !ok {
+			t.Fatalf("Could not get rows for: %#v", rl.AllText())
+		}
+		return rows
+	}
+	trp := func(prepare func(), expected ...int) {
+		before := rows_for()
+		prepare()
+		actual := rows_to_repaint(before, rows_for())
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Did not get expected rows to repaint for: %#v\n%s", rl.AllText(), diff)
+		}
+	}
+	trp(func() {})
+	trp(func() { rl.add_text("b") }, 4)
+	trp(func() { rl.add_text("bbbbbbb") }, 4, 5)
+	rl.input_state.cursor.X = 20
+	trp(func() { rl.add_text("c") }, 2, 3, 4, 5)
+	trp(func() { rl.erase_chars_before_cursor(1, false) }, 2, 3, 4, 5)
+	trp(func() {
+		line := []byte(rl.input_state.lines[0])
+		line[20] = 'd'
+		rl.input_state.lines[0] = string(line)
+	}, 2)
+	rl.ResetText()
+	rl.add_text("xyz")
+	if _, _, _, ok := rl.rows_for_screen_lines(rl.get_screen_lines()); !ok {
+		t.Fatalf("Could not get rows for: %#v", rl.AllText())
+	}
+	rl.selection = selection{active: true}
+	if _, _, _, ok := rl.rows_for_screen_lines(rl.get_screen_lines()); ok {
+		t.Fatalf("Got rows for text with a selection")
+	}
+	rl.ResetText()
+	rl.add_text(strings.Repeat("abcd", 10))
+	rl.Redraw()
+	if rl.drawn == nil || len(rl.drawn.rows) != 5 || rl.cursor_y != 4 {
+		t.Fatalf("Full redraw did not record the drawn rows")
+	}
+	rl.input_state.cursor.X = 0
+	rl.Redraw()
+	if len(rl.drawn.rows) != 5 || rl.cursor_y != 0 {
+		t.Fatalf("Redraw of changed rows did not update the cursor position: %d", rl.cursor_y)
+	}
+}
+
 func TestCursorMovement(t *testing.T) {
 	dt := test_func(t)
 
@@ -385,6 +437,11 @@ func TestEraseChars(t *testing.T) {
 		rl.input_state.cursor = Position{X: 0, Y: 0}
 		rl.erase_between(Position{X: 1}, Position{X: 2, Y: 2})
 	}, "", "oree")
+	dt("one\ntwo\nthree", func(rl *Readline) {
+		if erased := rl.erase_between(Position{X: 1}, Position{X: 2, Y: 2}); erased != "ne\ntwo\nth" {
+			t.Fatalf("erase_between() did not return the erased text: %#v", erased)
+		}
+	}, "oree", "")
 }
 
 func TestNumberArgument(t *testing.T) {
@@ -565,3 +622,24 @@ func TestReadlineCompletion(t *testing.T) {
 	rl.perform_action(ActionCompleteBackward, 1)
 	ah("a11 ", "")
 }
+
+func TestReadlineCompletionDocumentation(t *testing.T) {
+	rl := new_rl()
+	rl.fmt_ctx = markup.New(false)
+	rl.completions.current = completion{results: &cli.Completions{Groups: []*cli.MatchGroup{{Matches: []*cli.Match{
+		{Word: "a1", Description: "one two three"}, {Word: "a2"}, {Word: "a3", Description: "one two three four five six"},
+	}}}}}
+	rl.completions.current.initialize()
+	ad := func(current_match int, expected ...string) {
+		rl.completions.current.current_match = current_match
+		actual := rl.documentation_screen_lines()
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Documentation lines not as expected for match: %d\n%s", current_match, diff)
+		}
+	}
+	ad(-1)
+	ad(0, "a1", "  one two", "  three")
+	ad(1)
+	rl.screen_height = 8
+	ad(2, "a3", "  one two", "  three…")
+}
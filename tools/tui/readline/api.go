@@ -10,6 +10,7 @@ import (
 	"kitty/tools/cli"
 	"kitty/tools/cli/markup"
 	"kitty/tools/tui/loop"
+	"kitty/tools/utils"
 	"kitty/tools/wcswidth"
 )
 
@@ -30,6 +31,8 @@ type RlInit struct {
 	DontMarkPrompts         bool
 	SyntaxHighlighter       SyntaxHighlightFunction
 	Completer               CompleterFunction
+	// Expand csh style history operators such as !! and !$ when accepting input
+	HistoryExpansion bool
 }
 
 type Position struct {
@@ -110,10 +113,11 @@ type syntax_highlighted struct {
 type Readline struct {
 	prompt, continuation_prompt Prompt
 
-	mark_prompts bool
-	loop         *loop.Loop
-	history      *History
-	kill_ring    kill_ring
+	mark_prompts      bool
+	history_expansion bool
+	loop              *loop.Loop
+	history           *History
+	kill_ring         kill_ring
 
 	input_state InputState
 	// The number of lines after the initial line on the screen
@@ -131,6 +135,11 @@ type Readline struct {
 	text_to_be_added       string
 	syntax_highlighted     syntax_highlighted
 	completions            completions
+	selection              selection
+	mouse_state            mouse_state
+	// lines of text shown below the input, such as hints or error messages
+	footer []string
+	drawn  *drawn_state
 }
 
 func (self *Readline) make_prompt(text string, is_secondary bool) Prompt {
@@ -150,7 +159,7 @@ func New(loop *loop.Loop, r RlInit) *Readline {
 		hc = 8192
 	}
 	ans := &Readline{
-		mark_prompts: !r.DontMarkPrompts, fmt_ctx: markup.New(true),
+		mark_prompts: !r.DontMarkPrompts, fmt_ctx: markup.New(true), history_expansion: r.HistoryExpansion,
 		loop: loop, input_state: InputState{lines: []string{""}}, history: NewHistory(r.HistoryPath, hc),
 		syntax_highlighted: syntax_highlighted{highlighter: r.SyntaxHighlighter},
 		completions:        completions{completer: r.Completer},
@@ -179,6 +188,16 @@ func (self *Readline) SetPrompt(prompt string) {
 	self.prompt = self.make_prompt(prompt, false)
 }
 
+// Set the text to show below the input, an empty string removes it. The text
+// is not wrapped, lines longer than the screen width are truncated.
+func (self *Readline) SetFooter(text string) {
+	if text == "" {
+		self.footer = nil
+	} else {
+		self.footer = utils.Splitlines(text)
+	}
+}
+
 func (self *Readline) Shutdown() {
 	self.history.Shutdown()
 }
@@ -193,7 +212,9 @@ func (self *Readline) ResetText() {
 	self.keyboard_state = KeyboardState{}
 	self.history_search = nil
 	self.completions.current = completion{}
+	self.clear_selection()
 	self.cursor_y = 0
+	self.drawn = nil
 }
 
 func (self *Readline) ChangeLoopAndResetText(lp *loop.Loop) {
@@ -204,13 +225,18 @@ func (self *Readline) ChangeLoopAndResetText(lp *loop.Loop) {
 func (self *Readline) Start() {
 	self.loop.SetCursorShape(loop.BAR_CURSOR, true)
 	self.loop.StartBracketedPaste()
+	self.drawn = nil
 	self.Redraw()
 }
 
 func (self *Readline) End() {
 	self.loop.SetCursorShape(loop.BLOCK_CURSOR, true)
 	self.loop.EndBracketedPaste()
+	self.drawn = nil
 	self.loop.QueueWriteString("\r\n")
+	if len(self.footer) > 0 {
+		self.loop.ClearToEndOfScreen()
+	}
 	if self.mark_prompts {
 		self.loop.QueueWriteString(PROMPT_MARK + "C" + ST)
 	}
@@ -220,12 +246,18 @@ func MarkOutputStart() string {
 	return PROMPT_MARK + "C" + ST
 }
 
+// Redraw the prompt and input, repainting only the rows that have changed
+// since the last redraw, when possible.
 func (self *Readline) Redraw() {
 	self.loop.StartAtomicUpdate()
-	self.RedrawNonAtomic()
+	if !self.redraw_changed_rows() {
+		self.redraw()
+	}
 	self.loop.EndAtomicUpdate()
 }
 
+// Redraw everything. Use this rather than Redraw() when the screen has been
+// modified by something other than this Readline since the last redraw.
 func (self *Readline) RedrawNonAtomic() {
 	self.redraw()
 }
@@ -279,6 +311,7 @@ func (self *Readline) CursorAtEndOfLine() bool {
 
 func (self *Readline) OnResize(old_size loop.ScreenSize, new_size loop.ScreenSize) error {
 	self.screen_width, self.screen_height = 0, 0
+	self.drawn = nil
 	self.Redraw()
 	return nil
 }
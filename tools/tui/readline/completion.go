@@ -8,6 +8,7 @@ import (
 
 	"kitty/tools/cli"
 	"kitty/tools/utils"
+	"kitty/tools/utils/style"
 	"kitty/tools/wcswidth"
 )
 
@@ -39,23 +40,31 @@ func (self *completion) initialize() {
 	}
 }
 
-func (self *completion) current_match_text() string {
+func (self *completion) current() (*cli.MatchGroup, *cli.Match) {
 	if self.results != nil {
 		i := 0
 		for _, g := range self.results.Groups {
 			for _, m := range g.Matches {
 				if i == self.current_match {
-					t := m.Word
-					if !g.NoTrailingSpace && t != "" {
-						t += " "
-					}
-					return t
+					return g, m
 				}
 				i++
 			}
 		}
 	}
-	return ""
+	return nil, nil
+}
+
+func (self *completion) current_match_text() string {
+	g, m := self.current()
+	if m == nil {
+		return ""
+	}
+	t := m.Word
+	if !g.NoTrailingSpace && t != "" {
+		t += " "
+	}
+	return t
 }
 
 type completions struct {
@@ -278,3 +287,27 @@ func (self *Readline) completion_screen_lines() ([]string, bool) {
 	self.completions.current.rendered_at_screen_width = self.screen_width
 	return lines, false
 }
+
+// The documentation pane shown below the list of completions, with the full
+// description of the currently selected match, if it has one
+func (self *Readline) documentation_screen_lines() []string {
+	c := &self.completions.current
+	if c.num_of_matches < 2 {
+		return nil
+	}
+	_, m := c.current()
+	if m == nil {
+		return nil
+	}
+	desc := strings.TrimSpace(m.Description)
+	if desc == "" {
+		return nil
+	}
+	lines := style.WrapTextAsLines(self.fmt_ctx.Prettify(desc), self.screen_width, style.WrapOptions{Indent: "  ", Trim_whitespace: true})
+	if max_lines := max(2, self.screen_height/4); len(lines) > max_lines {
+		lines = lines[:max_lines]
+		lines[max_lines-1] = wcswidth.TruncateToVisualLength(lines[max_lines-1], self.screen_width-1) + "…"
+	}
+	header := self.fmt_ctx.Title(wcswidth.TruncateToVisualLength(m.Word, self.screen_width))
+	return append([]string{header}, lines...)
+}
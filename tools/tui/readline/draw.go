@@ -62,11 +62,12 @@ func (self *Readline) apply_syntax_highlighting() (lines []string, cursor Positi
 		highlighter = self.history_search_highlighter
 		highlighter_name = "## history ##"
 	}
-	if highlighter == nil {
+	if self.has_selection() {
+		// the selection is shown instead of syntax highlighting
+		lines = self.lines_with_selection()
+	} else if highlighter == nil {
 		return self.input_state.lines, self.input_state.cursor
-	}
-	src := strings.Join(self.input_state.lines, "\n")
-	if len(self.syntax_highlighted.lines) > 0 && self.syntax_highlighted.last_highlighter_name == highlighter_name && self.syntax_highlighted.src_for_last_highlight == src {
+	} else if src := strings.Join(self.input_state.lines, "\n"); len(self.syntax_highlighted.lines) > 0 && self.syntax_highlighted.last_highlighter_name == highlighter_name && self.syntax_highlighted.src_for_last_highlight == src {
 		lines = self.syntax_highlighted.lines
 	} else {
 		if src == "" {
@@ -133,7 +134,111 @@ func (self *Readline) get_screen_lines() []*ScreenLine {
 	return ans
 }
 
+// A row of the terminal as drawn by a redraw
+type drawn_row struct {
+	text  string
+	width int
+}
+
+// The rows drawn by the last redraw, used to repaint only the rows that have
+// changed when editing long lines
+type drawn_state struct {
+	rows         []drawn_row
+	screen_width int
+}
+
+// The terminal rows for the screen lines along with the position of the
+// cursor. Returns false if the rows cannot be repainted individually, for
+// instance because they contain styled text, whose SGR state at the start of a
+// row depends on the rows before it.
+func (self *Readline) rows_for_screen_lines(prompt_lines []*ScreenLine) (rows []drawn_row, cursor_row, cursor_x int, ok bool) {
+	cursor_row = -1
+	rows = make([]drawn_row, len(prompt_lines))
+	for i, sl := range prompt_lines {
+		if strings.Contains(sl.Text, "\x1b") {
+			return nil, 0, 0, false
+		}
+		r := &rows[i]
+		if sl.Prompt.Length > 0 {
+			p := self.prompt_for_line_number(i)
+			r.text, r.width = p.Text, p.Length
+		}
+		r.text += sl.Text
+		r.width += sl.TextLengthInCells
+		if r.width > self.screen_width {
+			return nil, 0, 0, false
+		}
+		if sl.CursorCell > -1 && cursor_row < 0 {
+			cursor_row, cursor_x = i, sl.CursorCell
+		}
+	}
+	return rows, cursor_row, cursor_x, cursor_row > -1
+}
+
+// The indices of the rows in new_rows that differ from the rows at the same
+// position in old_rows
+func rows_to_repaint(old_rows, new_rows []drawn_row) (ans []int) {
+	for i, r := range new_rows {
+		if i >= len(old_rows) || old_rows[i] != r {
+			ans = append(ans, i)
+		}
+	}
+	return
+}
+
+// Repaint only the rows that have changed since the last redraw, so that the
+// cost of a keystroke does not grow with the length of the line being edited.
+// Returns false if a full redraw is needed instead.
+func (self *Readline) redraw_changed_rows() bool {
+	d := self.drawn
+	if d == nil || len(self.footer) > 0 || self.completions.current.num_of_matches > 1 {
+		return false
+	}
+	if self.screen_width == 0 || self.screen_height == 0 {
+		self.update_current_screen_size()
+	}
+	if d.screen_width != self.screen_width {
+		return false
+	}
+	rows, cursor_row, cursor_x, ok := self.rows_for_screen_lines(self.get_screen_lines())
+	if !ok || len(rows) > self.screen_height {
+		return false
+	}
+	current_row, num_rows_on_screen := self.cursor_y, len(d.rows)
+	move_to := func(y int) {
+		if y < num_rows_on_screen {
+			self.loop.MoveCursorVertically(y - current_row)
+		} else {
+			// this row was not drawn before, use a newline to get to it
+			// so that the screen scrolls if needed
+			self.loop.MoveCursorVertically(y - 1 - current_row)
+			self.loop.QueueWriteString("\n")
+		}
+		self.loop.QueueWriteString("\r")
+		current_row = y
+		num_rows_on_screen = max(num_rows_on_screen, y+1)
+	}
+	for _, y := range rows_to_repaint(d.rows, rows) {
+		move_to(y)
+		self.loop.QueueWriteString(rows[y].text)
+		if rows[y].width < self.screen_width {
+			// clearing a full row would erase its last cell
+			self.loop.ClearToEndOfLine()
+		}
+	}
+	if len(rows) < len(d.rows) {
+		move_to(len(rows))
+		self.loop.ClearToEndOfScreen()
+	}
+	move_to(cursor_row)
+	self.loop.MoveCursorHorizontally(cursor_x)
+	self.cursor_y = cursor_row
+	d.rows = rows
+	return true
+}
+
 func (self *Readline) redraw() {
+	self.drawn = nil
 	if self.screen_width == 0 || self.screen_height == 0 {
 		self.update_current_screen_size()
 	}
@@ -147,6 +252,9 @@ func (self *Readline) redraw() {
 	self.loop.ClearToEndOfScreen()
 	prompt_lines := self.get_screen_lines()
 	csl, csl_cached := self.completion_screen_lines()
+	if dl := self.documentation_screen_lines(); len(dl) > 0 {
+		csl, csl_cached = utils.Concat(csl, dl), false
+	}
 	render_completion_above := len(csl)+len(prompt_lines) > self.screen_height
 	completion_needs_render := len(csl) > 0 && (!render_completion_above || !self.completions.current.last_rendered_above || !csl_cached)
 	final_cursor_x := -1
@@ -217,6 +325,14 @@ func (self *Readline) redraw() {
 	if !render_completion_above {
 		move_cursor_up_by += render_completion_lines()
 	}
+	if len(self.footer) > 0 {
+		self.loop.AllowLineWrapping(false)
+		for _, line := range self.footer {
+			self.loop.QueueWriteString("\r\n" + line)
+		}
+		self.loop.AllowLineWrapping(true)
+		move_cursor_up_by += len(self.footer)
+	}
 	self.loop.MoveCursorVertically(-move_cursor_up_by)
 	self.loop.QueueWriteString("\r")
 	self.loop.MoveCursorHorizontally(final_cursor_x)
@@ -225,4 +341,9 @@ func (self *Readline) redraw() {
 	if cursor_y > 0 {
 		self.cursor_y = cursor_y
 	}
+	if len(csl) == 0 && len(self.footer) == 0 && len(prompt_lines) <= self.screen_height {
+		if rows, cursor_row, _, ok := self.rows_for_screen_lines(prompt_lines); ok && cursor_row == self.cursor_y {
+			self.drawn = &drawn_state{rows: rows, screen_width: self.screen_width}
+		}
+	}
 }
@@ -0,0 +1,179 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package readline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var _ = fmt.Print
+
+// Split a command into words at unquoted whitespace, keeping the quotes and
+// escapes in the words, so that they can be re-used verbatim
+func split_into_raw_words(cmd string) (ans []string) {
+	var quote rune
+	escaped := false
+	start := -1
+	for i, ch := range cmd {
+		if start < 0 {
+			if unicode.IsSpace(ch) {
+				continue
+			}
+			start = i
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case unicode.IsSpace(ch):
+			ans = append(ans, cmd[start:i])
+			start = -1
+		}
+	}
+	if start > -1 {
+		ans = append(ans, cmd[start:])
+	}
+	return
+}
+
+func is_history_event_terminator(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '=' || ch == '(' || ch == ';' || ch == '|' || ch == '&'
+}
+
+// Expand the csh style history operators in text using the specified history
+// items, most recent last:
+//
+//	!!        the previous command
+//	!$        the last word of the previous command
+//	!^        the first argument of the previous command
+//	!*        all arguments of the previous command
+//	!-n       the nth previous command
+//	!prefix   the most recent command starting with prefix
+//
+// Operators inside single quotes or preceded by a backslash are not
+// expanded, neither is a ! followed by whitespace, = or (.
+func expand_history(text string, items []HistoryItem) (string, error) {
+	if !strings.Contains(text, "!") {
+		return text, nil
+	}
+	previous := func(n int, event string) (string, error) {
+		if n < 1 || n > len(items) {
+			return "", fmt.Errorf("%s: event not found", event)
+		}
+		return items[len(items)-n].Cmd, nil
+	}
+	words := func(event string) ([]string, error) {
+		cmd, err := previous(1, event)
+		if err != nil {
+			return nil, err
+		}
+		return split_into_raw_words(cmd), nil
+	}
+	buf := strings.Builder{}
+	in_single_quotes, in_double_quotes, escaped := false, false, false
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\' && !in_single_quotes:
+			escaped = true
+		case ch == '\'' && !in_double_quotes:
+			in_single_quotes = !in_single_quotes
+		case ch == '"' && !in_single_quotes:
+			in_double_quotes = !in_double_quotes
+		case ch == '!' && !in_single_quotes && i+1 < len(text) && !is_history_event_terminator(text[i+1]):
+			next := text[i+1]
+			var expansion string
+			consumed := 1
+			switch next {
+			case '!':
+				cmd, err := previous(1, "!!")
+				if err != nil {
+					return "", err
+				}
+				expansion = cmd
+			case '$', '^', '*':
+				w, err := words("!" + string(next))
+				if err != nil {
+					return "", err
+				}
+				switch {
+				case next == '$' && len(w) > 0:
+					expansion = w[len(w)-1]
+				case next == '^' && len(w) > 1:
+					expansion = w[1]
+				case next == '*' && len(w) > 1:
+					expansion = strings.Join(w[1:], " ")
+				case next != '*':
+					return "", fmt.Errorf("!%c: bad word specifier", next)
+				}
+			default:
+				end := i + 1
+				for end < len(text) && !is_history_event_terminator(text[end]) && text[end] != '"' && text[end] != '\'' {
+					end++
+				}
+				event := text[i+1 : end]
+				consumed = end - i - 1
+				if event == "" {
+					// a ! followed by a quote, as in "hello!"
+					break
+				}
+				if n, err := strconv.Atoi(strings.TrimPrefix(event, "-")); err == nil && strings.HasPrefix(event, "-") {
+					cmd, err := previous(n, "!"+event)
+					if err != nil {
+						return "", err
+					}
+					expansion = cmd
+				} else {
+					found := false
+					for j := len(items) - 1; j >= 0; j-- {
+						if strings.HasPrefix(items[j].Cmd, event) {
+							expansion, found = items[j].Cmd, true
+							break
+						}
+					}
+					if !found {
+						return "", fmt.Errorf("!%s: event not found", event)
+					}
+				}
+			}
+			if consumed > 0 {
+				buf.WriteString(expansion)
+				i += consumed
+				continue
+			}
+		}
+		buf.WriteByte(ch)
+	}
+	return buf.String(), nil
+}
+
+// Expand history operators in the current input, if enabled. Returns true if
+// the input was changed, in which case it should be shown to the user for
+// confirmation instead of being accepted.
+func (self *Readline) expand_history_in_input() (bool, error) {
+	if !self.history_expansion {
+		return false, nil
+	}
+	text := self.all_text()
+	expanded, err := expand_history(text, self.history.items)
+	if err != nil {
+		return false, err
+	}
+	if expanded == text {
+		return false, nil
+	}
+	self.set_text(expanded)
+	return true, nil
+}
@@ -0,0 +1,68 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package readline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestHistoryExpansion(t *testing.T) {
+	items := []HistoryItem{{Cmd: "ls -l /tmp"}, {Cmd: `echo one "two three" four\ five`}}
+	q := func(text, expected string) {
+		actual, err := expand_history(text, items)
+		if err != nil {
+			t.Fatalf("Expanding %#v failed with error: %s", text, err)
+		}
+		if expected != actual {
+			t.Fatalf("Expanding %#v failed\n%#v != %#v", text, expected, actual)
+		}
+	}
+	q("no operators", "no operators")
+	q("sudo !!", `sudo echo one "two three" four\ five`)
+	q("cat !$", `cat four\ five`)
+	q("x !^ y", "x one y")
+	q("x !*", `x one "two three" four\ five`)
+	q("!-2", "ls -l /tmp")
+	q("!ls | wc", "ls -l /tmp | wc")
+	q("!e", items[1].Cmd)
+	q(`alone ! and != and literal \!! and '!!'`, `alone ! and != and literal \!! and '!!'`)
+	q(`"!$"`, `"four\ five"`)
+	q(`echo "hi!" 'there!'`, `echo "hi!" 'there!'`)
+
+	for _, bad := range []string{"!-3", "!nosuch", "!0"} {
+		if _, err := expand_history(bad, items); err == nil {
+			t.Fatalf("Expanding %#v did not fail", bad)
+		}
+	}
+	if _, err := expand_history("!!", nil); err == nil {
+		t.Fatalf("Expanding with no history did not fail")
+	}
+	for _, bad := range []string{"!$", "!^"} {
+		if _, err := expand_history(bad, []HistoryItem{{Cmd: " "}}); err == nil {
+			t.Fatalf("Expanding %#v with no words in the previous command did not fail", bad)
+		}
+	}
+
+	if diff := cmp.Diff([]string{"echo", "one", `"two three"`, `four\ five`}, split_into_raw_words(items[1].Cmd)); diff != "" {
+		t.Fatalf("Splitting into words failed:\n%s", diff)
+	}
+
+	rl := new_rl()
+	rl.history_expansion = true
+	rl.history.merge_items(items...)
+	rl.add_text("sudo !!")
+	if err := rl.perform_action(ActionAcceptInput, 1); err != nil {
+		t.Fatalf("Accepting input with history operators did not show the expansion: %v", err)
+	}
+	if rl.all_text() != "sudo "+items[1].Cmd {
+		t.Fatalf("Input not expanded: %#v", rl.all_text())
+	}
+	if err := rl.perform_action(ActionAcceptInput, 1); err != ErrAcceptInput {
+		t.Fatalf("Expanded input was not accepted: %v", err)
+	}
+}
@@ -66,6 +66,7 @@ func default_shortcuts() *ShortcutMap {
 		sm.AddOrPanic(ActionKillPreviousSpaceDelimitedWord, "ctrl+w")
 		sm.AddOrPanic(ActionYank, "ctrl+y")
 		sm.AddOrPanic(ActionPopYank, "alt+y")
+		sm.AddOrPanic(ActionCopySelection, "alt+w")
 
 		sm.AddOrPanic(ActionHistoryPreviousOrCursorUp, "up")
 		sm.AddOrPanic(ActionHistoryNextOrCursorDown, "down")
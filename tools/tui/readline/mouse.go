@@ -0,0 +1,224 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package readline
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"kitty/tools/tui/loop"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+const SELECTION_START = "\x1b[7m"
+const SELECTION_END = "\x1b[27m"
+
+type selection struct {
+	// The selection is the text between the anchor and the cursor
+	active bool
+	anchor Position
+}
+
+type mouse_state struct {
+	// The screen row of the first line of the prompt, which is only known
+	// after querying the terminal for the cursor position
+	origin_y        int
+	querying_origin bool
+	pending_events  []loop.MouseEvent
+	dragging        bool
+}
+
+func (self *Readline) has_selection() bool {
+	return self.selection.active && self.selection.anchor != self.input_state.cursor
+}
+
+func (self *Readline) clear_selection() {
+	self.selection = selection{}
+}
+
+func (self *Readline) selection_extent() (start, end Position) {
+	start, end = self.selection.anchor, self.input_state.cursor
+	if end.Less(start) {
+		start, end = end, start
+	}
+	return
+}
+
+func (self *Readline) selected_text() string {
+	if !self.has_selection() {
+		return ""
+	}
+	start, end := self.selection_extent()
+	if start.Y == end.Y {
+		return self.input_state.lines[start.Y][start.X:end.X]
+	}
+	lines := make([]string, 0, end.Y-start.Y+1)
+	lines = append(lines, self.input_state.lines[start.Y][start.X:])
+	lines = append(lines, self.input_state.lines[start.Y+1:end.Y]...)
+	lines = append(lines, self.input_state.lines[end.Y][:end.X])
+	return strings.Join(lines, "\n")
+}
+
+func (self *Readline) kill_selection() bool {
+	if !self.has_selection() {
+		return false
+	}
+	start, end := self.selection_extent()
+	self.kill_text(self.erase_between(start, end))
+	self.clear_selection()
+	return true
+}
+
+// Copy the selected text into the kill ring and the clipboard
+func (self *Readline) copy_selection() bool {
+	text := self.selected_text()
+	if text == "" {
+		return false
+	}
+	self.kill_ring.add_new_item(text)
+	self.loop.QueueWriteString("\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x1b\\")
+	self.clear_selection()
+	return true
+}
+
+// The input lines with the selected text highlighted
+func (self *Readline) lines_with_selection() []string {
+	start, end := self.selection_extent()
+	lines := make([]string, len(self.input_state.lines))
+	for i, line := range self.input_state.lines {
+		switch {
+		case i < start.Y || i > end.Y:
+			lines[i] = line
+		case i == start.Y && i == end.Y:
+			lines[i] = line[:start.X] + SELECTION_START + line[start.X:end.X] + SELECTION_END + line[end.X:]
+		case i == start.Y:
+			lines[i] = line[:start.X] + SELECTION_START + line[start.X:] + SELECTION_END
+		case i == end.Y:
+			lines[i] = SELECTION_START + line[:end.X] + SELECTION_END + line[end.X:]
+		default:
+			lines[i] = SELECTION_START + line + SELECTION_END
+		}
+	}
+	return lines
+}
+
+// The position in the input of the text displayed at the specified cell,
+// with y relative to the first line of the prompt. Cells after the end of a
+// line map to the end of the line.
+func (self *Readline) input_position_at(x, y int) (Position, bool) {
+	if self.screen_width == 0 || self.screen_height == 0 {
+		self.update_current_screen_size()
+	}
+	row := 0
+	for i, line := range self.input_state.lines {
+		prompt := self.prompt_for_line_number(i)
+		offset := 0
+		for is_first := true; is_first || offset < len(line); is_first = false {
+			l, _ := wcswidth.TruncateToVisualLengthWithWidth(line[offset:], self.screen_width-prompt.Length)
+			if row == y {
+				t := wcswidth.TruncateToVisualLength(l, max(0, x-prompt.Length))
+				return Position{X: offset + len(t), Y: i}, true
+			}
+			row++
+			prompt = Prompt{}
+			offset += len(l)
+			if l == "" {
+				break
+			}
+		}
+	}
+	return Position{}, false
+}
+
+// The screen row, relative to the first line of the prompt, the cursor is on
+func (self *Readline) cursor_screen_row() int {
+	lines := self.get_screen_lines()
+	for i, sl := range lines {
+		if sl.CursorCell > -1 {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+func (self *Readline) clamped_input_position_at(x, y int) Position {
+	if y < 0 {
+		return Position{}
+	}
+	if pos, ok := self.input_position_at(x, y); ok {
+		return pos
+	}
+	last := len(self.input_state.lines) - 1
+	return Position{X: len(self.input_state.lines[last]), Y: last}
+}
+
+func (self *Readline) handle_mouse_event(ev *loop.MouseEvent) (changed bool) {
+	y := ev.Cell.Y - self.mouse_state.origin_y
+	switch ev.Event_type {
+	case loop.MOUSE_PRESS:
+		if ev.Buttons&loop.LEFT_MOUSE_BUTTON == 0 {
+			return false
+		}
+		pos, ok := self.input_position_at(ev.Cell.X, y)
+		if !ok {
+			return false
+		}
+		self.input_state.cursor = pos
+		self.selection = selection{anchor: pos}
+		self.mouse_state.dragging = true
+		return true
+	case loop.MOUSE_MOVE:
+		if !self.mouse_state.dragging || ev.Buttons&loop.LEFT_MOUSE_BUTTON == 0 {
+			return false
+		}
+		self.input_state.cursor = self.clamped_input_position_at(ev.Cell.X, y)
+		self.selection.active = true
+		return true
+	case loop.MOUSE_RELEASE:
+		self.mouse_state.dragging = false
+	}
+	return false
+}
+
+// Handle mouse events, clicking in the input moves the cursor and dragging
+// selects text. The selected text can be killed with ctrl+w or copied with
+// alt+w. The loop must have mouse tracking enabled with drag tracking, see
+// loop.MouseTrackingMode().
+func (self *Readline) OnMouseEvent(ev *loop.MouseEvent) error {
+	if self.mouse_state.querying_origin {
+		self.mouse_state.pending_events = append(self.mouse_state.pending_events, *ev)
+		return nil
+	}
+	if ev.Event_type == loop.MOUSE_PRESS {
+		// the prompt might have moved since the last press, for example,
+		// because of output scrolling the screen
+		self.mouse_state.querying_origin = true
+		self.mouse_state.pending_events = append(self.mouse_state.pending_events[:0], *ev)
+		return self.loop.QueryTerminal(loop.TerminalQuery{CursorPosition: true}, func(r *loop.TerminalQueryResults) error {
+			self.mouse_state.querying_origin = false
+			if !r.CursorPositionKnown {
+				self.mouse_state.pending_events = self.mouse_state.pending_events[:0]
+				return nil
+			}
+			self.mouse_state.origin_y = r.CursorPosition.Y - self.cursor_screen_row()
+			changed := false
+			for i := range self.mouse_state.pending_events {
+				if self.handle_mouse_event(&self.mouse_state.pending_events[i]) {
+					changed = true
+				}
+			}
+			self.mouse_state.pending_events = self.mouse_state.pending_events[:0]
+			if changed {
+				self.Redraw()
+			}
+			return nil
+		})
+	}
+	if self.handle_mouse_event(ev) {
+		self.Redraw()
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package readline
+
+import (
+	"fmt"
+	"testing"
+
+	"kitty/tools/tui/loop"
+)
+
+var _ = fmt.Print
+
+func TestMouseSelection(t *testing.T) {
+	rl := new_rl()
+	rl.add_text("hello world\nfoo")
+	mouse := func(etype loop.MouseEventType, x, y int) bool {
+		ev := loop.MouseEvent{Event_type: etype, Buttons: loop.LEFT_MOUSE_BUTTON}
+		ev.Cell.X, ev.Cell.Y = x, y
+		return rl.handle_mouse_event(&ev)
+	}
+	// the first line wraps after 7 characters as the prompt is 3 cells wide
+	if rl.cursor_screen_row() != 2 {
+		t.Fatalf("Cursor not on the third screen row: %d", rl.cursor_screen_row())
+	}
+	if !mouse(loop.MOUSE_PRESS, 5, 0) || rl.input_state.cursor != (Position{X: 2, Y: 0}) {
+		t.Fatalf("Click did not move the cursor: %v", rl.input_state.cursor)
+	}
+	if rl.has_selection() {
+		t.Fatalf("Click created a selection")
+	}
+	if mouse(loop.MOUSE_PRESS, 0, 10) {
+		t.Fatalf("Click outside the prompt was handled")
+	}
+	mouse(loop.MOUSE_PRESS, 5, 0)
+	mouse(loop.MOUSE_MOVE, 1, 1)
+	mouse(loop.MOUSE_MOVE, 3, 2)
+	mouse(loop.MOUSE_RELEASE, 3, 2)
+	if rl.selected_text() != "llo world\nf" {
+		t.Fatalf("Unexpected selected text: %#v", rl.selected_text())
+	}
+	if lines := rl.lines_with_selection(); lines[0] != "he"+SELECTION_START+"llo world"+SELECTION_END || lines[1] != SELECTION_START+"f"+SELECTION_END+"oo" {
+		t.Fatalf("Selection not highlighted correctly: %#v", lines)
+	}
+	rl.perform_action(ActionKillPreviousSpaceDelimitedWord, 1)
+	if rl.all_text() != "heoo" || rl.has_selection() {
+		t.Fatalf("Selection not killed: %#v", rl.all_text())
+	}
+	rl.perform_action(ActionYank, 1)
+	if rl.all_text() != "hello world\nfoo" {
+		t.Fatalf("Killed selection not yanked: %#v", rl.all_text())
+	}
+	// dragging past the end of the input selects to the end
+	mouse(loop.MOUSE_PRESS, 3, 0)
+	mouse(loop.MOUSE_MOVE, 0, 20)
+	if rl.selected_text() != "hello world\nfoo" {
+		t.Fatalf("Unexpected selected text: %#v", rl.selected_text())
+	}
+	rl.perform_action(ActionCursorLeft, 1)
+	if rl.has_selection() {
+		t.Fatalf("Selection not cleared by other actions")
+	}
+}
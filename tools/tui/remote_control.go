@@ -0,0 +1,22 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+
+	"kitty/tools/utils"
+)
+
+var _ = fmt.Print
+
+// A command to run the remote control command with the specified arguments
+// using the kitten from the running kitty, falling back to the one in PATH
+func RemoteControlCommand(args ...string) *exec.Cmd {
+	exe := utils.KittyExe()
+	if exe == "" {
+		exe = "kitten"
+	}
+	return exec.Command(exe, append([]string{"@"}, args...)...)
+}
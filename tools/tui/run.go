@@ -29,7 +29,7 @@ type KittyOpts struct {
 	Shell, Shell_integration string
 }
 
-func read_relevant_kitty_opts(path string) KittyOpts {
+func read_relevant_kitty_opts(path, cache_dir string) KittyOpts {
 	ans := KittyOpts{Shell: kitty.KittyConfigDefaults.Shell, Shell_integration: kitty.KittyConfigDefaults.Shell_integration}
 	handle_line := func(key, val string) error {
 		switch key {
@@ -41,7 +41,7 @@ func read_relevant_kitty_opts(path string) KittyOpts {
 		return nil
 	}
 	cp := config.ConfigParser{LineHandler: handle_line}
-	cp.ParseFiles(path)
+	cp.ParseFileWithCache(path, cache_dir)
 	if ans.Shell == "" {
 		ans.Shell = kitty.KittyConfigDefaults.Shell
 	}
@@ -62,7 +62,7 @@ func get_effective_ksi_env_var(x string) string {
 }
 
 var relevant_kitty_opts = sync.OnceValue(func() KittyOpts {
-	return read_relevant_kitty_opts(filepath.Join(utils.ConfigDir(), "kitty.conf"))
+	return read_relevant_kitty_opts(filepath.Join(utils.ConfigDir(), "kitty.conf"), config.ParsedConfigCacheDir())
 })
 
 func get_shell_from_kitty_conf() (shell string) {
@@ -264,7 +264,7 @@ func Setup(shell_name string, ksi_var string, argv []string, env map[string]stri
 	}
 	argv, env, err = setup_func_for_shell(shell_name)(ksi_dir, slices.Clone(argv), maps.Clone(env))
 	if err == nil {
-		env[`KITTY_SHELL_INTEGRATION`] = ksi_var
+		env[`KITTY_SHELL_INTEGRATION`] = adjust_for_multiplexer(ksi_var, env)
 	}
 	return argv, env, err
 }
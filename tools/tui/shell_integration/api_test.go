@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 var _ = fmt.Print
@@ -40,3 +41,29 @@ func TestExtractShellIntegration(t *testing.T) {
 		t.Fatalf("Failed to update shell integration file")
 	}
 }
+
+func TestCommandStatusFromUserVars(t *testing.T) {
+	if _, found := CommandStatusFromUserVars(map[string]string{"x": "1"}); found {
+		t.Fatalf("Found command status without user vars")
+	}
+	cs, found := CommandStatusFromUserVars(map[string]string{EXIT_STATUS_USER_VAR: "2", DURATION_USER_VAR: "1500"})
+	if !found || cs.ExitStatus != 2 || cs.Duration != 1500*time.Millisecond || !cs.Failed() || !cs.RanLongerThan(time.Second) {
+		t.Fatalf("Incorrect command status: %#v", cs)
+	}
+	cs, found = CommandStatusFromUserVars(map[string]string{EXIT_STATUS_USER_VAR: "0"})
+	if !found || cs.Failed() || cs.Duration != -1 || cs.RanLongerThan(0) {
+		t.Fatalf("Incorrect command status: %#v", cs)
+	}
+}
+
+func TestAdjustForMultiplexer(t *testing.T) {
+	test := func(ksi_var string, env map[string]string, expected string) {
+		if actual := adjust_for_multiplexer(ksi_var, env); actual != expected {
+			t.Fatalf("Incorrect shell integration options for %#v with env %v: %#v != %#v", ksi_var, env, expected, actual)
+		}
+	}
+	test("enabled", map[string]string{}, "enabled")
+	test("enabled", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"}, "enabled tmux-passthrough")
+	test("no-cwd  no-title", map[string]string{"STY": "1.pts-0.host"}, "no-cwd no-title no-prompt-mark no-command-status no-kitty-escapes")
+	test("enabled", map[string]string{"TMUX": "x", "STY": "y"}, "enabled tmux-passthrough")
+}
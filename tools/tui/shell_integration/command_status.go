@@ -0,0 +1,57 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package shell_integration
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var _ = fmt.Print
+
+// When a command finishes, shell integration sends OSC 133;D;exit_status;duration=ms
+// which kitty records in the user vars of the window. These can be read via
+// remote control, for example, from the output of kitten @ ls.
+const (
+	EXIT_STATUS_USER_VAR = "kitty_cmd_exit_status"
+	DURATION_USER_VAR    = "kitty_cmd_duration"
+)
+
+// The shell integration option to turn off reporting of the exit status and
+// duration of commands, pass it in the ksi_var to Setup()
+const NO_COMMAND_STATUS = "no-command-status"
+
+type CommandStatus struct {
+	ExitStatus int
+	// The duration of the command, -1 if the shell did not report it
+	Duration time.Duration
+}
+
+func (self CommandStatus) Failed() bool { return self.ExitStatus != 0 }
+
+// Whether the command ran for at least threshold, used to notify about the
+// completion of long running commands
+func (self CommandStatus) RanLongerThan(threshold time.Duration) bool {
+	return self.Duration > -1 && self.Duration >= threshold
+}
+
+// The status of the last command that finished in a window, from the user
+// vars of that window
+func CommandStatusFromUserVars(user_vars map[string]string) (ans CommandStatus, found bool) {
+	es, found := user_vars[EXIT_STATUS_USER_VAR]
+	if !found {
+		return
+	}
+	var err error
+	if ans.ExitStatus, err = strconv.Atoi(es); err != nil {
+		return ans, false
+	}
+	ans.Duration = -1
+	if d, ok := user_vars[DURATION_USER_VAR]; ok {
+		if ms, err := strconv.ParseUint(d, 10, 64); err == nil {
+			ans.Duration = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return ans, true
+}
@@ -0,0 +1,57 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package shell_integration
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type multiplexer int
+
+const (
+	no_multiplexer multiplexer = iota
+	tmux_multiplexer
+	screen_multiplexer
+)
+
+// The terminal multiplexer, if any, the shell will run inside, based on the
+// environment the shell will be run with
+func detect_multiplexer(env map[string]string) multiplexer {
+	if env[`TMUX`] != "" {
+		return tmux_multiplexer
+	}
+	if env[`STY`] != "" {
+		return screen_multiplexer
+	}
+	return no_multiplexer
+}
+
+// Adjust the shell integration options for the multiplexer, if any. tmux
+// understands the prompt marking and working directory escape codes itself and
+// can pass through the escape codes kitty uses for clone-in-kitty, etc. when
+// they are wrapped. GNU screen discards unknown escape codes and truncates long
+// wrapped ones, so features that depend on them are turned off.
+func adjust_for_multiplexer(ksi_var string, env map[string]string) string {
+	opts := strings.Fields(ksi_var)
+	add := func(items ...string) {
+		for _, x := range items {
+			if !slices.Contains(opts, x) {
+				opts = append(opts, x)
+			}
+		}
+	}
+	switch detect_multiplexer(env) {
+	case tmux_multiplexer:
+		add(`tmux-passthrough`)
+	case screen_multiplexer:
+		add(`no-prompt-mark`, NO_COMMAND_STATUS, `no-cwd`, `no-kitty-escapes`)
+	default:
+		return ksi_var
+	}
+	return strings.Join(opts, " ")
+}
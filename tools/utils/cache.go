@@ -30,9 +30,9 @@ func (self *LRUCache[K, V]) Get(key K) (ans V, found bool) {
 }
 
 func (self *LRUCache[K, V]) Set(key K, val V) {
-	self.lock.RLock()
+	self.lock.Lock()
 	self.data[key] = val
-	self.lock.RUnlock()
+	self.lock.Unlock()
 	return
 }
 
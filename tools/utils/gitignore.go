@@ -0,0 +1,167 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var _ = fmt.Print
+
+type ignore_rule struct {
+	pat      *regexp.Regexp
+	negated  bool
+	dir_only bool
+}
+
+// The rules from a single .gitignore file, matched against paths relative to
+// the directory containing the file
+type ignore_rules struct {
+	// The directory containing the file relative to the root of the walk,
+	// with a trailing slash, empty for the root
+	base  string
+	rules []ignore_rule
+}
+
+// Convert a glob using the .gitignore syntax to a regular expression
+func gitignore_glob_to_regexp(pat string) string {
+	anchored := strings.Contains(pat, "/")
+	pat = strings.TrimPrefix(pat, "/")
+	ans := strings.Builder{}
+	ans.WriteString("^")
+	if !anchored {
+		ans.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pat); i++ {
+		ch := pat[i]
+		switch ch {
+		case '*':
+			if i+1 < len(pat) && pat[i+1] == '*' && (i == 0 || pat[i-1] == '/') {
+				if i+2 == len(pat) {
+					ans.WriteString(".*")
+					i++
+					continue
+				}
+				if pat[i+2] == '/' {
+					ans.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+			}
+			ans.WriteString("[^/]*")
+		case '?':
+			ans.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pat[i+1:], ']')
+			if end < 0 {
+				ans.WriteString(`\[`)
+				continue
+			}
+			class := pat[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			ans.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pat) {
+				i++
+				ch = pat[i]
+			}
+			ans.WriteString(regexp.QuoteMeta(string(ch)))
+		default:
+			ans.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	ans.WriteString("$")
+	return ans.String()
+}
+
+// Parse the lines from a .gitignore file or, if is_file is false, globs not
+// read from a file, which cannot be comments and can end with spaces
+func parse_ignore_rules(base string, is_file bool, lines ...string) *ignore_rules {
+	ans := &ignore_rules{base: base, rules: make([]ignore_rule, 0, len(lines))}
+	for _, line := range lines {
+		if is_file {
+			if !strings.HasSuffix(line, `\ `) {
+				line = strings.TrimRight(line, " \t\r")
+			}
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+		}
+		if line == "" {
+			continue
+		}
+		r := ignore_rule{}
+		if strings.HasPrefix(line, "!") {
+			r.negated = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dir_only = true
+			line = line[:len(line)-1]
+		}
+		var err error
+		if r.pat, err = regexp.Compile(gitignore_glob_to_regexp(line)); err == nil {
+			ans.rules = append(ans.rules, r)
+		}
+	}
+	return ans
+}
+
+func load_ignore_rules(path, base string) *ignore_rules {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	ans := parse_ignore_rules(base, true, Splitlines(UnsafeBytesToString(raw))...)
+	if len(ans.rules) == 0 {
+		return nil
+	}
+	return ans
+}
+
+// Returns whether the path, relative to the root of the walk, matches and
+// whether the matching rule is a negated rule. The last matching rule wins.
+func (self *ignore_rules) match(path string, is_dir bool) (matched, negated bool) {
+	path, found := strings.CutPrefix(path, self.base)
+	if !found {
+		return
+	}
+	for i := len(self.rules) - 1; i >= 0; i-- {
+		r := &self.rules[i]
+		if r.dir_only && !is_dir {
+			continue
+		}
+		if r.pat.MatchString(path) {
+			return true, r.negated
+		}
+	}
+	return
+}
+
+// A stack of ignore rules, from the root of the walk to the current
+// directory, rules from deeper directories take precedence
+type ignore_stack []*ignore_rules
+
+func (self ignore_stack) is_ignored(path string, is_dir bool) bool {
+	for i := len(self) - 1; i >= 0; i-- {
+		if matched, negated := self[i].match(path, is_dir); matched {
+			return !negated
+		}
+	}
+	return false
+}
+
+func (self ignore_stack) push(r *ignore_rules) ignore_stack {
+	if r == nil {
+		return self
+	}
+	ans := make(ignore_stack, len(self), len(self)+1)
+	copy(ans, self)
+	return append(ans, r)
+}
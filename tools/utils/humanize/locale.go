@@ -0,0 +1,154 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package humanize
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ = fmt.Print
+
+const (
+	NO_BREAK_SPACE        = "\u00a0"
+	NARROW_NO_BREAK_SPACE = "\u202f"
+)
+
+// The conventions used to format numbers in a locale
+type Locale struct {
+	Decimal, Thousands string
+	// Placed between a number and its unit
+	UnitSeparator string
+	// Placed between the hours, minutes and seconds of a time
+	TimeSeparator string
+}
+
+var DefaultLocale = Locale{Decimal: ".", Thousands: ",", UnitSeparator: " ", TimeSeparator: ":"}
+
+var decimal_comma_dot_grouping = Locale{Decimal: ",", Thousands: ".", UnitSeparator: NO_BREAK_SPACE, TimeSeparator: ":"}
+var decimal_comma_space_grouping = Locale{Decimal: ",", Thousands: NARROW_NO_BREAK_SPACE, UnitSeparator: NO_BREAK_SPACE, TimeSeparator: ":"}
+
+var locales_by_language = map[string]Locale{
+	"da": {Decimal: ",", Thousands: ".", UnitSeparator: NO_BREAK_SPACE, TimeSeparator: "."},
+	"de": decimal_comma_dot_grouping,
+	"el": decimal_comma_dot_grouping,
+	"es": decimal_comma_dot_grouping,
+	"hr": decimal_comma_dot_grouping,
+	"id": decimal_comma_dot_grouping,
+	"it": decimal_comma_dot_grouping,
+	"nl": decimal_comma_dot_grouping,
+	"pt": decimal_comma_dot_grouping,
+	"ro": decimal_comma_dot_grouping,
+	"sl": decimal_comma_dot_grouping,
+	"sr": decimal_comma_dot_grouping,
+	"tr": decimal_comma_dot_grouping,
+	"vi": decimal_comma_dot_grouping,
+
+	"bg": decimal_comma_space_grouping,
+	"cs": decimal_comma_space_grouping,
+	"et": decimal_comma_space_grouping,
+	"fi": {Decimal: ",", Thousands: NARROW_NO_BREAK_SPACE, UnitSeparator: NO_BREAK_SPACE, TimeSeparator: "."},
+	"fr": {Decimal: ",", Thousands: NARROW_NO_BREAK_SPACE, UnitSeparator: NARROW_NO_BREAK_SPACE, TimeSeparator: ":"},
+	"hu": decimal_comma_space_grouping,
+	"lt": decimal_comma_space_grouping,
+	"lv": decimal_comma_space_grouping,
+	"nb": decimal_comma_space_grouping,
+	"nn": decimal_comma_space_grouping,
+	"no": decimal_comma_space_grouping,
+	"pl": decimal_comma_space_grouping,
+	"ru": decimal_comma_space_grouping,
+	"sk": decimal_comma_space_grouping,
+	"sv": decimal_comma_space_grouping,
+	"uk": decimal_comma_space_grouping,
+}
+
+var locales_by_name = map[string]Locale{
+	"de_CH": {Decimal: ".", Thousands: "’", UnitSeparator: NO_BREAK_SPACE, TimeSeparator: ":"},
+	"it_CH": {Decimal: ".", Thousands: "’", UnitSeparator: NO_BREAK_SPACE, TimeSeparator: ":"},
+	"pt_PT": decimal_comma_space_grouping,
+}
+
+// The locale for a POSIX locale name such as de_DE.UTF-8 or fr_FR@euro.
+// Unknown locales use the conventions of DefaultLocale.
+func LocaleFromName(name string) Locale {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	if ans, found := locales_by_name[name]; found {
+		return ans
+	}
+	lang, _, _ := strings.Cut(name, "_")
+	if ans, found := locales_by_language[strings.ToLower(lang)]; found {
+		return ans
+	}
+	return DefaultLocale
+}
+
+// The locale used for formatting numbers, as specified by the LC_ALL,
+// LC_NUMERIC and LANG environment variables
+var CurrentLocale = sync.OnceValue(func() Locale {
+	for _, q := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if val := os.Getenv(q); val != "" {
+			return LocaleFromName(val)
+		}
+	}
+	return DefaultLocale
+})
+
+// Convert a number formatted with a period as the decimal separator and no
+// digit grouping to the conventions of this locale
+func (self Locale) localize_number(n string) string {
+	sign := ""
+	if strings.HasPrefix(n, "-") {
+		sign, n = "-", n[1:]
+	}
+	whole, frac, has_frac := strings.Cut(n, ".")
+	// four digit numbers are not grouped
+	if len(whole) > 4 {
+		groups := make([]string, 0, len(whole)/3+1)
+		first := len(whole) % 3
+		if first > 0 {
+			groups = append(groups, whole[:first])
+		}
+		for i := first; i < len(whole); i += 3 {
+			groups = append(groups, whole[i:i+3])
+		}
+		whole = strings.Join(groups, self.Thousands)
+	}
+	if has_frac {
+		return sign + whole + self.Decimal + frac
+	}
+	return sign + whole
+}
+
+// Like the package level FormatNumber but using the conventions of this locale
+func (self Locale) FormatNumber(n float64, max_num_of_decimals ...int) string {
+	return self.localize_number(FormatNumber(n, max_num_of_decimals...))
+}
+
+// Like the package level Size but using the conventions of this locale. The
+// unit separator of the locale is used unless one is specified in opts.
+func (self Locale) Size(s uint64, opts ...SizeOptions) string {
+	var o SizeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	sep := o.Separator
+	if sep == "" {
+		sep = self.UnitSeparator
+	}
+	o.Separator = "\x00"
+	num, unit, _ := strings.Cut(Size(s, o), o.Separator)
+	return self.localize_number(num) + sep + unit
+}
+
+// Like the package level ShortDuration but using the conventions of this locale
+func (self Locale) ShortDuration(val time.Duration) string {
+	ans := ShortDuration(val)
+	if self.TimeSeparator != ":" {
+		ans = strings.ReplaceAll(ans, ":", self.TimeSeparator)
+	}
+	return ans
+}
@@ -0,0 +1,38 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package humanize
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestLocalizedFormatting(t *testing.T) {
+	q := func(locale, actual, expected string) {
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Failed for locale: %s\n%s", locale, diff)
+		}
+	}
+	for name, expected := range map[string]string{
+		"C":           "1,234,567.5 | 1.2 MB | 01:02:03",
+		"en_US.UTF-8": "1,234,567.5 | 1.2 MB | 01:02:03",
+		"de_DE.UTF-8": "1.234.567,5 | 1,2\u00a0MB | 01:02:03",
+		"de_CH":       "1’234’567.5 | 1.2\u00a0MB | 01:02:03",
+		"fr_FR@euro":  "1\u202f234\u202f567,5 | 1,2\u202fMB | 01:02:03",
+		"fi_FI.UTF-8": "1\u202f234\u202f567,5 | 1,2\u00a0MB | 01.02.03",
+	} {
+		loc := LocaleFromName(name)
+		actual := loc.FormatNumber(1234567.5) + " | " + loc.Size(1234567) + " | " + loc.ShortDuration(time.Hour+2*time.Minute+3*time.Second)
+		q(name, actual, expected)
+	}
+	loc := LocaleFromName("de_DE")
+	q("de_DE", loc.FormatNumber(-1234.567), "-1234,57")
+	q("de_DE", loc.FormatNumber(12345), "12.345")
+	q("de_DE", loc.Size(1536, SizeOptions{Base: 1024, Separator: "_"}), "1,5_KiB")
+	q("de_DE", loc.Size(5), "5\u00a0B")
+}
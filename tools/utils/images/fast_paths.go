@@ -0,0 +1,184 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package images
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+var _ = fmt.Print
+
+// Fast paths for the pixel conversions that dominate when displaying large
+// images. Most large images, such as screenshots, are fully or mostly opaque,
+// so whole runs of opaque pixels are processed eight or sixteen bytes at a
+// time instead of one byte at a time, with only the translucent pixels going
+// through the per pixel code.
+//
+// These are plain Go, working on machine words rather than using SIMD
+// assembly, so that they work unchanged on every platform kitty supports.
+// They cover conversion to RGB/RGBA, unpremultiplication, flipping and
+// shrinking, see Resize().
+
+const opaque_alpha_mask uint64 = 0xff000000ff000000
+
+// The number of leading pixels of the four bytes per pixel row pix that are
+// fully opaque
+func opaque_prefix_length(pix []uint8) int {
+	i := 0
+	for ; i+16 <= len(pix); i += 16 {
+		if binary.LittleEndian.Uint64(pix[i:])&binary.LittleEndian.Uint64(pix[i+8:])&opaque_alpha_mask != opaque_alpha_mask {
+			break
+		}
+	}
+	for ; i+4 <= len(pix) && pix[i+3] == 0xff; i += 4 {
+	}
+	return i / 4
+}
+
+// Copy the four bytes per pixel src to the three bytes per pixel dst,
+// dropping the alpha channel.
+func drop_alpha(src, dst []uint8) {
+	for len(src) >= 16 && len(dst) >= 12 {
+		lo, hi := binary.LittleEndian.Uint64(src), binary.LittleEndian.Uint64(src[8:])
+		p0, p1 := lo&0xffffff, (lo>>32)&0xffffff
+		p2, p3 := hi&0xffffff, (hi>>32)&0xffffff
+		binary.LittleEndian.PutUint64(dst, p0|p1<<24|p2<<48)
+		binary.LittleEndian.PutUint32(dst[8:], uint32(p2>>16|p3<<8))
+		src, dst = src[16:], dst[12:]
+	}
+	for len(src) >= 4 && len(dst) >= 3 {
+		dst[0], dst[1], dst[2] = src[0], src[1], src[2]
+		src, dst = src[4:], dst[3:]
+	}
+}
+
+// Convert a row of premultiplied RGBA pixels to non-premultiplied RGBA
+func unpremultiply_row(src, dst []uint8) {
+	for len(src) >= 4 {
+		n := opaque_prefix_length(src) * 4
+		copy(dst, src[:n])
+		src, dst = src[n:], dst[n:]
+		if len(src) >= 4 {
+			unpremultiply_pixel(src[:4:4], dst[:4:4])
+			src, dst = src[4:], dst[4:]
+		}
+	}
+}
+
+func unpremultiply_pixel(s, d []uint8) {
+	switch a := s[3]; a {
+	case 0:
+		d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+	case 0xff:
+		d[0], d[1], d[2], d[3] = s[0], s[1], s[2], a
+	default:
+		a16 := uint16(a)
+		d[0] = uint8(uint16(s[0]) * 0xff / a16)
+		d[1] = uint8(uint16(s[1]) * 0xff / a16)
+		d[2] = uint8(uint16(s[2]) * 0xff / a16)
+		d[3] = a
+	}
+}
+
+// Convert a row of non-premultiplied RGBA pixels to RGB, blending onto the
+// opaque base color
+func (s *scanner_rgb) nrgba_row_to_rgb(src, dst []uint8) {
+	for len(src) >= 4 {
+		n := opaque_prefix_length(src)
+		drop_alpha(src[:n*4], dst)
+		src, dst = src[n*4:], dst[n*3:]
+		if len(src) >= 4 {
+			blend(dst[:3:3], s.opaque_base, src[0], src[1], src[2], src[3])
+			src, dst = src[4:], dst[3:]
+		}
+	}
+}
+
+// Convert a row of premultiplied RGBA pixels to RGB, blending onto the
+// opaque base color
+func (s *scanner_rgb) rgba_row_to_rgb(src, dst []uint8) {
+	for len(src) >= 4 {
+		n := opaque_prefix_length(src)
+		drop_alpha(src[:n*4], dst)
+		src, dst = src[n*4:], dst[n*3:]
+		if len(src) >= 4 {
+			s.rgba_pixel_to_rgb(src[:4:4], dst[:3:3])
+			src, dst = src[4:], dst[3:]
+		}
+	}
+}
+
+func (s *scanner_rgb) rgba_pixel_to_rgb(src, d []uint8) {
+	switch a := src[3]; a {
+	case 0:
+		d[0] = s.opaque_base_uint[0]
+		d[1] = s.opaque_base_uint[1]
+		d[2] = s.opaque_base_uint[2]
+	case 0xff:
+		d[0], d[1], d[2] = src[0], src[1], src[2]
+	default:
+		a16 := uint16(a)
+		blend(d, s.opaque_base, uint8(uint16(src[0])*0xff/a16), uint8(uint16(src[1])*0xff/a16), uint8(uint16(src[2])*0xff/a16), a)
+	}
+}
+
+// Whether all pixels of a four bytes per pixel image whose alpha is the
+// fourth byte are opaque
+func is_opaque_rgba(pix []uint8, stride, width, height int) bool {
+	for y := 0; y < height; y++ {
+		if opaque_prefix_length(pix[y*stride:y*stride+width*4]) != width {
+			return false
+		}
+	}
+	return true
+}
+
+// Shrink src to half its size, rounding down, by averaging each 2x2 block of
+// pixels. Colors are weighted by alpha so that transparent pixels do not
+// darken their neighbors.
+func halve_nrgba(src *image.NRGBA) *image.NRGBA {
+	width, height := src.Rect.Dx()/2, src.Rect.Dy()/2
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		r0 := src.Pix[2*y*src.Stride:]
+		r1 := src.Pix[(2*y+1)*src.Stride:]
+		out := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+		for x := 0; x < width; x++ {
+			block := [4][]uint8{r0[8*x : 8*x+4], r0[8*x+4 : 8*x+8], r1[8*x : 8*x+4], r1[8*x+4 : 8*x+8]}
+			var r, g, b, a uint32
+			for _, p := range block {
+				pa := uint32(p[3])
+				r += uint32(p[0]) * pa
+				g += uint32(p[1]) * pa
+				b += uint32(p[2]) * pa
+				a += pa
+			}
+			if a > 0 {
+				o := out[4*x : 4*x+4]
+				o[0], o[1], o[2] = uint8((r+a/2)/a), uint8((g+a/2)/a), uint8((b+a/2)/a)
+				o[3] = uint8((a + 2) / 4)
+			}
+		}
+	}
+	return dst
+}
+
+// Resize img to width x height using Lanczos resampling. When shrinking by
+// more than a factor of four, the image is first halved repeatedly with a
+// cheap box filter, so that the expensive Lanczos filter only has to run on
+// an image a few times larger than the result rather than the original.
+func Resize(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	if width > 0 && height > 0 && b.Dx() >= 4*width && b.Dy() >= 4*height {
+		src := imaging.Clone(img)
+		for src.Rect.Dx() >= 4*width && src.Rect.Dy() >= 4*height {
+			src = halve_nrgba(src)
+		}
+		img = src
+	}
+	return imaging.Resize(img, width, height, imaging.Lanczos)
+}
@@ -0,0 +1,125 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package images
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+// Fill pix with runs of opaque pixels interspersed with translucent and
+// transparent ones, so that both the fast and slow paths are exercised
+func random_pixels(pix []uint8, premultiplied bool) {
+	r := rand.New(rand.NewSource(1729))
+	r.Read(pix)
+	for i := 0; i+4 <= len(pix); i += 4 {
+		switch x := r.Intn(16); {
+		case x == 0:
+			pix[i+3] = 0
+		case x < 4:
+		default:
+			pix[i+3] = 0xff
+		}
+		if premultiplied {
+			for c := 0; c < 3; c++ {
+				pix[i+c] = uint8(uint16(pix[i+c]) * uint16(pix[i+3]) / 0xff)
+			}
+		}
+	}
+}
+
+func TestFastPixelConversions(t *testing.T) {
+	const width, height = 37, 11
+	rect := image.Rect(0, 0, width, height)
+	nrgba, rgba := image.NewNRGBA(rect), image.NewRGBA(rect)
+	random_pixels(nrgba.Pix, false)
+	random_pixels(rgba.Pix, true)
+	base := NRGBColor{11, 22, 33}
+	fbase := []float64{11, 22, 33}
+
+	expected_rgba := make([]uint8, len(rgba.Pix))
+	expected_nrgba_rgb := make([]uint8, width*height*3)
+	expected_rgba_rgb := make([]uint8, width*height*3)
+	for i, j := 0, 0; i < len(rgba.Pix); i, j = i+4, j+3 {
+		s, d := rgba.Pix[i:i+4], expected_rgba[i:i+4]
+		switch a := s[3]; a {
+		case 0:
+		case 0xff:
+			copy(d, s)
+		default:
+			for c := 0; c < 3; c++ {
+				d[c] = uint8(uint16(s[c]) * 0xff / uint16(a))
+			}
+			d[3] = a
+		}
+		switch a := s[3]; a {
+		case 0:
+			copy(expected_rgba_rgb[j:j+3], []uint8{base.R, base.G, base.B})
+		default:
+			blend(expected_rgba_rgb[j:j+3], fbase, d[0], d[1], d[2], a)
+		}
+		n := nrgba.Pix[i : i+4]
+		blend(expected_nrgba_rgb[j:j+3], fbase, n[0], n[1], n[2], n[3])
+	}
+
+	ctx := Context{}
+	actual := image.NewNRGBA(rect)
+	ctx.Paste(actual, rgba, image.Point{}, nil)
+	if diff := cmp.Diff(expected_rgba, actual.Pix); diff != "" {
+		t.Fatalf("Converting RGBA to NRGBA failed:\n%s", diff)
+	}
+	for _, x := range []struct {
+		name     string
+		img      image.Image
+		expected []uint8
+	}{{"RGBA", rgba, expected_rgba_rgb}, {"NRGBA", nrgba, expected_nrgba_rgb}} {
+		actual := NewNRGB(rect)
+		ctx.Paste(actual, x.img, image.Point{}, &base)
+		if diff := cmp.Diff(x.expected, actual.Pix); diff != "" {
+			t.Fatalf("Converting %s to RGB failed:\n%s", x.name, diff)
+		}
+	}
+
+	for i := 3; i < len(nrgba.Pix); i += 4 {
+		nrgba.Pix[i] = 0xff
+	}
+	if !IsOpaque(nrgba) {
+		t.Fatalf("Opaque image not detected as opaque")
+	}
+	nrgba.Pix[len(nrgba.Pix)-1] = 0xfe
+	if IsOpaque(nrgba) {
+		t.Fatalf("Translucent image detected as opaque")
+	}
+	if !IsOpaque(nrgba.SubImage(image.Rect(0, 0, width, height-1))) {
+		t.Fatalf("Opaque sub image not detected as opaque")
+	}
+}
+
+func TestFastResize(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	copy(src.Pix, []uint8{
+		200, 0, 0, 255, 0, 0, 0, 0, 10, 20, 30, 255, 30, 40, 50, 255,
+		100, 0, 0, 255, 0, 0, 0, 0, 20, 30, 40, 255, 40, 50, 60, 255,
+	})
+	if diff := cmp.Diff([]uint8{150, 0, 0, 128, 25, 35, 45, 255}, halve_nrgba(src).Pix); diff != "" {
+		t.Fatalf("Halving an image failed:\n%s", diff)
+	}
+
+	big := image.NewNRGBA(image.Rect(0, 0, 403, 211))
+	for i := 0; i < len(big.Pix); i += 4 {
+		copy(big.Pix[i:], []uint8{12, 34, 56, 255})
+	}
+	small := Resize(big, 20, 10)
+	if b := small.Bounds(); b.Dx() != 20 || b.Dy() != 10 {
+		t.Fatalf("Resized image has wrong size: %v", b)
+	}
+	if c := small.(*image.NRGBA).NRGBAAt(7, 3); c.R != 12 || c.G != 34 || c.B != 56 || c.A != 255 {
+		t.Fatalf("Resizing a solid image changed its color to: %v", c)
+	}
+}
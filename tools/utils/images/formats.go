@@ -19,6 +19,7 @@ var _ = fmt.Print
 
 var DecodableImageTypes = map[string]bool{
 	"image/jpeg": true, "image/png": true, "image/bmp": true, "image/tiff": true, "image/webp": true, "image/gif": true,
+	"image/x-sixel": true,
 }
 
 var EncodableImageTypes = map[string]bool{
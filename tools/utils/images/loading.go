@@ -133,7 +133,7 @@ func (self *ImageFrame) Resize(x_frac, y_frac float64) *ImageFrame {
 	ans := *self
 	ans.Width = int(x_frac * float64(width))
 	ans.Height = int(y_frac * float64(height))
-	ans.Img = imaging.Resize(self.Img, ans.Width, ans.Height, imaging.Lanczos)
+	ans.Img = Resize(self.Img, ans.Width, ans.Height)
 	ans.Left = int(x_frac * float64(left))
 	ans.Top = int(y_frac * float64(top))
 	return &ans
@@ -12,13 +12,15 @@ var _ = fmt.Print
 func IsOpaque(img image.Image) bool {
 	switch img.(type) {
 	case *image.RGBA:
-		return img.(*image.RGBA).Opaque()
+		i := img.(*image.RGBA)
+		return is_opaque_rgba(i.Pix, i.Stride, i.Rect.Dx(), i.Rect.Dy())
 	case *image.RGBA64:
 		return img.(*image.RGBA64).Opaque()
 	case *image.NRGBA:
-		return img.(*image.NRGBA).Opaque()
+		i := img.(*image.NRGBA)
+		return is_opaque_rgba(i.Pix, i.Stride, i.Rect.Dx(), i.Rect.Dy())
 	case *image.NRGBA64:
-		return img.(*image.NRGBA).Opaque()
+		return img.(*image.NRGBA64).Opaque()
 	case *image.Alpha:
 		return img.(*image.Alpha).Opaque()
 	case *image.Alpha16:
@@ -0,0 +1,316 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package images
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type DitherMethod int
+
+const (
+	NO_DITHER DitherMethod = iota
+	// Ordered dithering with an 8x8 Bayer matrix, fast and stable, so it does
+	// not shimmer in animations, but with a visible cross hatch pattern
+	ORDERED_DITHER
+	// Floyd-Steinberg error diffusion, slower but better looking
+	FLOYD_STEINBERG_DITHER
+)
+
+type QuantizeOptions struct {
+	// The maximum number of colors in the palette, from 2 to 256, defaults
+	// to 256. Ignored if Palette is specified.
+	MaxColors int
+	// The palette to use, for example, the 256 colors of the terminal. When
+	// nil a palette is computed from the colors in the image. Fully
+	// transparent colors in it are used for transparent pixels.
+	Palette color.Palette
+	Dither  DitherMethod
+	// Trade quality for speed, by using a fixed palette of evenly spaced
+	// colors instead of computing one from the image and by matching colors
+	// to the palette approximately
+	Fast bool
+}
+
+// At most this many pixels are used when computing palettes, larger images
+// are sampled
+const max_pixels_for_palette = 256 * 256
+
+// A palette of evenly spaced colors, at most max_colors in size. For less
+// than eight colors it is a palette of grays.
+func FixedPalette(max_colors int) color.Palette {
+	max_colors = max(2, min(max_colors, 256))
+	if max_colors < 8 {
+		ans := make(color.Palette, max_colors)
+		for i := range ans {
+			v := uint8(i * 255 / (max_colors - 1))
+			ans[i] = color.NRGBA{v, v, v, 255}
+		}
+		return ans
+	}
+	levels := int(math.Cbrt(float64(max_colors) + 0.5))
+	ans := make(color.Palette, 0, levels*levels*levels)
+	level := func(i int) uint8 { return uint8(i * 255 / (levels - 1)) }
+	for r := 0; r < levels; r++ {
+		for g := 0; g < levels; g++ {
+			for b := 0; b < levels; b++ {
+				ans = append(ans, color.NRGBA{level(r), level(g), level(b), 255})
+			}
+		}
+	}
+	return ans
+}
+
+type color_box struct {
+	colors [][3]uint8
+	// the channel with the largest range and the size of that range
+	channel, spread int
+}
+
+func new_color_box(colors [][3]uint8) *color_box {
+	ans := &color_box{colors: colors}
+	for c := 0; c < 3; c++ {
+		lo, hi := 255, 0
+		for _, x := range colors {
+			lo, hi = min(lo, int(x[c])), max(hi, int(x[c]))
+		}
+		if hi-lo > ans.spread || c == 0 {
+			ans.channel, ans.spread = c, hi-lo
+		}
+	}
+	return ans
+}
+
+func (self *color_box) average() color.NRGBA {
+	var sum [3]int
+	for _, x := range self.colors {
+		for c := 0; c < 3; c++ {
+			sum[c] += int(x[c])
+		}
+	}
+	n := len(self.colors)
+	return color.NRGBA{uint8((sum[0] + n/2) / n), uint8((sum[1] + n/2) / n), uint8((sum[2] + n/2) / n), 255}
+}
+
+// Compute a palette of at most max_colors colors that best represent the
+// colors in img, using the median cut algorithm. Pixels that are more than
+// half transparent are ignored, if there are any, the first color in the
+// palette is transparent.
+func MedianCutPalette(img image.Image, max_colors int) color.Palette {
+	max_colors = max(2, min(max_colors, 256))
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	step := max(1, w*h/max_pixels_for_palette)
+	colors := make([][3]uint8, 0, min(w*h, max_pixels_for_palette+w))
+	has_transparent := false
+	s := newScanner(img)
+	row, row_y := make([]uint8, w*4), -1
+	for i := 0; i < w*h; i += step {
+		x, y := i%w, i/w
+		if y != row_y {
+			s.scan(0, y, w, y+1, row)
+			row_y = y
+		}
+		p := row[x*4 : x*4+4]
+		if p[3] < 128 {
+			has_transparent = true
+		} else {
+			colors = append(colors, [3]uint8{p[0], p[1], p[2]})
+		}
+	}
+	ans := make(color.Palette, 0, max_colors)
+	if has_transparent {
+		ans = append(ans, color.NRGBA{})
+		max_colors--
+	}
+	if len(colors) == 0 {
+		return append(ans, color.NRGBA{A: 255})
+	}
+	boxes := []*color_box{new_color_box(colors)}
+	for len(boxes) < max_colors {
+		idx := -1
+		for i, box := range boxes {
+			if box.spread > 0 && (idx < 0 || box.spread*len(box.colors) > boxes[idx].spread*len(boxes[idx].colors)) {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			// every box contains a single color
+			break
+		}
+		box := boxes[idx]
+		c := box.channel
+		slices.SortFunc(box.colors, func(a, b [3]uint8) int { return int(a[c]) - int(b[c]) })
+		median := len(box.colors) / 2
+		// do not split runs of the same value across boxes
+		for median > 0 && box.colors[median][c] == box.colors[median-1][c] {
+			median--
+		}
+		if median == 0 {
+			for median < len(box.colors) && box.colors[median][c] == box.colors[0][c] {
+				median++
+			}
+		}
+		boxes[idx] = new_color_box(box.colors[:median])
+		boxes = append(boxes, new_color_box(box.colors[median:]))
+	}
+	for _, box := range boxes {
+		ans = append(ans, box.average())
+	}
+	return ans
+}
+
+// Finds the color in a palette closest to a given color
+type palette_index struct {
+	colors [][3]int32
+	// the index of the transparent color, -1 if there is none
+	transparent int
+	// the number of low bits of each channel to ignore, so that the nearest
+	// colors fit in a fixed size lookup table
+	shift uint
+	// the nearest palette color plus one for every color, with the low bits of
+	// each channel ignored, zero for colors not yet looked up
+	cache []uint16
+}
+
+func new_palette_index(p color.Palette, fast bool) *palette_index {
+	ans := &palette_index{colors: make([][3]int32, len(p)), transparent: -1, shift: 2}
+	for i, c := range p {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if n.A == 0 && ans.transparent < 0 {
+			ans.transparent = i
+		}
+		ans.colors[i] = [3]int32{int32(n.R), int32(n.G), int32(n.B)}
+	}
+	if fast {
+		ans.shift = 3
+	}
+	// 18 bits, 512KB or 15 bits, 64KB when fast
+	ans.cache = make([]uint16, 1<<(3*(8-ans.shift)))
+	return ans
+}
+
+func (self *palette_index) nearest(r, g, b int32) uint8 {
+	bits := 8 - self.shift
+	key := (r>>self.shift)<<(2*bits) | (g>>self.shift)<<bits | b>>self.shift
+	if ans := self.cache[key]; ans != 0 {
+		return uint8(ans - 1)
+	}
+	// use the center of the bucket
+	half := int32(1) << (self.shift - 1)
+	r, g, b = (r>>self.shift)<<self.shift|half, (g>>self.shift)<<self.shift|half, (b>>self.shift)<<self.shift|half
+	ans, best := 0, int32(math.MaxInt32)
+	for i, c := range self.colors {
+		if i == self.transparent {
+			continue
+		}
+		// weighted to approximate the sensitivity of the eye to each channel
+		dr, dg, db := r-c[0], g-c[1], b-c[2]
+		if d := 2*dr*dr + 4*dg*dg + 3*db*db; d < best {
+			ans, best = i, d
+		}
+	}
+	self.cache[key] = uint16(ans + 1)
+	return uint8(ans)
+}
+
+// The 8x8 Bayer threshold matrix
+var bayer_matrix = [8][8]int32{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+func clamp_channel(x int32) int32 {
+	return max(0, min(x, 255))
+}
+
+// Reduce the colors in img to a palette of at most 256 colors, optionally
+// dithering, for output in formats such as sixel or colored block characters
+// that need indexed colors. Pixels that are more than half transparent use
+// the transparent color from the palette, if it has one.
+func Quantize(img image.Image, opts QuantizeOptions) *image.Paletted {
+	palette := opts.Palette
+	if len(palette) == 0 {
+		max_colors := opts.MaxColors
+		if max_colors <= 0 {
+			max_colors = 256
+		}
+		if opts.Fast {
+			if IsOpaque(img) {
+				palette = FixedPalette(max_colors)
+			} else {
+				palette = append(FixedPalette(max_colors-1), color.NRGBA{})
+			}
+		} else {
+			palette = MedianCutPalette(img, max_colors)
+		}
+	}
+	if len(palette) > 256 {
+		palette = palette[:256]
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	ans := image.NewPaletted(image.Rect(0, 0, w, h), palette)
+	pi := new_palette_index(palette, opts.Fast)
+	s := newScanner(img)
+	row := make([]uint8, w*4)
+	// the spread of the ordered dither is the distance between neighboring
+	// colors in a palette of this size with evenly spaced colors
+	spread := 256 / max(1, int32(math.Round(math.Cbrt(float64(len(palette))))))
+	// errors for Floyd-Steinberg dithering, in units of 1/16, with a column
+	// of padding on either side
+	var cur, next []int32
+	if opts.Dither == FLOYD_STEINBERG_DITHER {
+		cur, next = make([]int32, (w+2)*3), make([]int32, (w+2)*3)
+	}
+	for y := 0; y < h; y++ {
+		s.scan(0, y, w, y+1, row)
+		pix := ans.Pix[y*ans.Stride : y*ans.Stride+w]
+		for x := 0; x < w; x++ {
+			p := row[x*4 : x*4+4]
+			if p[3] < 128 && pi.transparent > -1 {
+				pix[x] = uint8(pi.transparent)
+				continue
+			}
+			r, g, b := int32(p[0]), int32(p[1]), int32(p[2])
+			switch opts.Dither {
+			case ORDERED_DITHER:
+				offset := (bayer_matrix[y&7][x&7]*2 + 1 - 64) * spread / 128
+				r, g, b = r+offset, g+offset, b+offset
+			case FLOYD_STEINBERG_DITHER:
+				e := cur[(x+1)*3 : (x+1)*3+3]
+				r, g, b = r+e[0]/16, g+e[1]/16, b+e[2]/16
+			}
+			r, g, b = clamp_channel(r), clamp_channel(g), clamp_channel(b)
+			idx := pi.nearest(r, g, b)
+			pix[x] = idx
+			if cur != nil {
+				c := pi.colors[idx]
+				for i, e := range [3]int32{r - c[0], g - c[1], b - c[2]} {
+					cur[(x+2)*3+i] += e * 7
+					next[x*3+i] += e * 3
+					next[(x+1)*3+i] += e * 5
+					next[(x+2)*3+i] += e
+				}
+			}
+		}
+		if cur != nil {
+			cur, next = next, cur
+			clear(next)
+		}
+	}
+	return ans
+}
@@ -0,0 +1,73 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package images
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestQuantize(t *testing.T) {
+	if n := len(FixedPalette(256)); n != 216 {
+		t.Fatalf("Unexpected size of fixed palette: %d", n)
+	}
+	if diff := cmp.Diff(color.Palette{color.NRGBA{0, 0, 0, 255}, color.NRGBA{127, 127, 127, 255}, color.NRGBA{255, 255, 255, 255}}, FixedPalette(3)); diff != "" {
+		t.Fatalf("Unexpected fixed palette of grays:\n%s", diff)
+	}
+
+	// an image with four colors and transparent pixels is reproduced exactly
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {10, 20, 30, 255}, {}}
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.SetNRGBA(x, y, colors[(x+y)%len(colors)])
+		}
+	}
+	for _, dither := range []DitherMethod{NO_DITHER, FLOYD_STEINBERG_DITHER} {
+		q := Quantize(img, QuantizeOptions{MaxColors: 5, Dither: dither})
+		if n := len(q.Palette); n != 5 {
+			t.Fatalf("Unexpected palette size: %d", n)
+		}
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				if diff := cmp.Diff(color.Color(colors[(x+y)%len(colors)]), q.At(x, y)); diff != "" {
+					t.Fatalf("Incorrect pixel at (%d, %d) with dither: %d\n%s", x, y, dither, diff)
+				}
+			}
+		}
+	}
+	if n := len(MedianCutPalette(img, 3)); n != 3 {
+		t.Fatalf("Unexpected palette size: %d", n)
+	}
+
+	// dithering mid gray with black and white gives half white pixels
+	gray := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range gray.Pix {
+		gray.Pix[i] = 128
+		if i%4 == 3 {
+			gray.Pix[i] = 255
+		}
+	}
+	bw := color.Palette{color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}}
+	num_white := func(q *image.Paletted) (ans int) {
+		for _, p := range q.Pix {
+			ans += int(p)
+		}
+		return
+	}
+	for _, dither := range []DitherMethod{ORDERED_DITHER, FLOYD_STEINBERG_DITHER} {
+		q := Quantize(gray, QuantizeOptions{Palette: bw, Dither: dither})
+		if n := num_white(q); n < 120 || n > 136 {
+			t.Fatalf("Unexpected number of white pixels with dither %d: %d", dither, n)
+		}
+	}
+	if n := num_white(Quantize(gray, QuantizeOptions{Palette: bw, Fast: true})); n != 256 {
+		t.Fatalf("Unexpected number of white pixels without dithering: %d", n)
+	}
+}
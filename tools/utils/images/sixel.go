@@ -0,0 +1,388 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package images
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"math/bits"
+)
+
+var _ = fmt.Print
+
+// Sixel images larger than this in either dimension are rejected, to guard
+// against malicious repeat counts and raster attributes
+const SIXEL_MAX_DIMENSION = 16 * 1024
+
+// Sixel images with more pixels than this are rejected, as even with
+// dimensions below the maximum they could need gigabytes of memory
+const SIXEL_MAX_PIXELS = 32 * 1024 * 1024
+
+const SIXEL_MAX_COLOR_REGISTERS = 64 * 1024
+
+var ErrNotSixel = errors.New("Data is not a sixel image")
+
+// The default color registers of the VT340, as RGB percentages
+var sixel_default_palette = [16][3]int{
+	{0, 0, 0}, {20, 20, 80}, {80, 13, 13}, {20, 80, 20},
+	{80, 20, 80}, {20, 80, 80}, {80, 80, 20}, {53, 53, 53},
+	{26, 26, 26}, {33, 33, 60}, {60, 26, 26}, {33, 60, 33},
+	{60, 33, 60}, {33, 60, 60}, {60, 60, 33}, {80, 80, 80},
+}
+
+func percent_to_uint8(p int) uint8 {
+	return uint8((max(0, min(p, 100))*255 + 50) / 100)
+}
+
+// Convert a color in the DEC HLS color space, where hue 0 is blue, to RGB
+func dec_hls_to_rgb(hue, lightness, saturation int) color.NRGBA {
+	h := math.Mod(float64(hue+240), 360) / 60
+	l, s := float64(max(0, min(lightness, 100)))/100, float64(max(0, min(saturation, 100)))/100
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	conv := func(v float64) uint8 { return uint8(math.Round((v + m) * 255)) }
+	return color.NRGBA{conv(r), conv(g), conv(b), 255}
+}
+
+type sixel_decoder struct {
+	r                      *bufio.Reader
+	palette                []color.NRGBA
+	current_color          int
+	x, y                   int
+	width, height          int
+	transparent_background bool
+	// nil when only the size of the image is needed
+	img *image.NRGBA
+}
+
+func (self *sixel_decoder) read_params() (ans []int, err error) {
+	ans = make([]int, 0, 8)
+	num, has_num := 0, false
+	for {
+		b, err := self.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case '0' <= b && b <= '9':
+			num = min(num*10+int(b-'0'), math.MaxInt32)
+			has_num = true
+		case b == ';':
+			ans = append(ans, num)
+			num, has_num = 0, false
+		default:
+			if has_num || len(ans) > 0 {
+				ans = append(ans, num)
+			}
+			return ans, self.r.UnreadByte()
+		}
+	}
+}
+
+func (self *sixel_decoder) read_header() error {
+	b, err := self.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b == 0x1b {
+		if b, err = self.r.ReadByte(); err != nil {
+			return err
+		}
+		if b != 'P' {
+			return ErrNotSixel
+		}
+	} else if b != 0x90 {
+		return ErrNotSixel
+	}
+	params, err := self.read_params()
+	if err != nil {
+		return err
+	}
+	if b, err = self.r.ReadByte(); err != nil {
+		return err
+	}
+	if b != 'q' {
+		return ErrNotSixel
+	}
+	self.transparent_background = len(params) > 1 && params[1] == 1
+	return nil
+}
+
+func (self *sixel_decoder) color_register(idx int) *color.NRGBA {
+	if idx >= len(self.palette) {
+		self.palette = append(self.palette, make([]color.NRGBA, idx+1-len(self.palette))...)
+	}
+	return &self.palette[idx]
+}
+
+func (self *sixel_decoder) resize(width, height int) error {
+	width, height = max(self.width, width), max(self.height, height)
+	if width > SIXEL_MAX_DIMENSION || height > SIXEL_MAX_DIMENSION || width*height > SIXEL_MAX_PIXELS {
+		return fmt.Errorf("Sixel image too large: %dx%d", width, height)
+	}
+	self.width, self.height = width, height
+	if self.img == nil {
+		return nil
+	}
+	if b := self.img.Rect; self.width > b.Dx() || self.height > b.Dy() {
+		// grow only the dimensions that need it, with some slack to avoid
+		// copying on every sixel, but never beyond the limits
+		grow := func(needed, current int) int {
+			if needed <= current {
+				return current
+			}
+			return max(needed, min(2*current, SIXEL_MAX_DIMENSION))
+		}
+		w, h := grow(self.width, b.Dx()), grow(self.height, b.Dy())
+		if w*h > SIXEL_MAX_PIXELS {
+			w, h = self.width, self.height
+		}
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < b.Dy(); y++ {
+			copy(img.Pix[y*img.Stride:], self.img.Pix[y*self.img.Stride:y*self.img.Stride+self.img.Stride])
+		}
+		self.img = img
+	}
+	return nil
+}
+
+func (self *sixel_decoder) put(sixel byte, count int) error {
+	if sixel != 0 {
+		if err := self.resize(self.x+count, self.y+bits.Len8(sixel)); err != nil {
+			return err
+		}
+		if self.img != nil {
+			c := *self.color_register(self.current_color)
+			c.A = 255
+			for i := 0; i < 6; i++ {
+				if sixel&(1<<i) != 0 {
+					off := (self.y+i)*self.img.Stride + self.x*4
+					for p := self.img.Pix[off : off+count*4]; len(p) > 0; p = p[4:] {
+						p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+					}
+				}
+			}
+		}
+	} else if err := self.resize(self.x+count, 0); err != nil {
+		return err
+	}
+	self.x += count
+	return nil
+}
+
+func (self *sixel_decoder) decode() error {
+	if err := self.read_header(); err != nil {
+		return err
+	}
+	self.palette = make([]color.NRGBA, 256)
+	for i, c := range sixel_default_palette {
+		self.palette[i] = color.NRGBA{percent_to_uint8(c[0]), percent_to_uint8(c[1]), percent_to_uint8(c[2]), 255}
+	}
+	for {
+		b, err := self.r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// be lenient with truncated data
+				return nil
+			}
+			return err
+		}
+		switch {
+		case '?' <= b && b <= '~':
+			err = self.put(b-'?', 1)
+		case b == '!':
+			var params []int
+			if params, err = self.read_params(); err == nil {
+				if b, err = self.r.ReadByte(); err == nil && '?' <= b && b <= '~' {
+					count := 1
+					if len(params) > 0 {
+						count = max(1, params[0])
+					}
+					err = self.put(b-'?', count)
+				}
+			}
+		case b == '#':
+			var params []int
+			if params, err = self.read_params(); err == nil && len(params) > 0 {
+				self.current_color = min(params[0], SIXEL_MAX_COLOR_REGISTERS-1)
+				if len(params) > 4 {
+					c := self.color_register(self.current_color)
+					switch params[1] {
+					case 1:
+						*c = dec_hls_to_rgb(params[2], params[3], params[4])
+					case 2:
+						*c = color.NRGBA{percent_to_uint8(params[2]), percent_to_uint8(params[3]), percent_to_uint8(params[4]), 255}
+					}
+				}
+			}
+		case b == '"':
+			var params []int
+			if params, err = self.read_params(); err == nil && len(params) > 3 {
+				err = self.resize(params[2], params[3])
+			}
+		case b == '$':
+			self.x = 0
+		case b == '-':
+			self.x = 0
+			self.y += 6
+		case b == 0x1b || b == 0x9c:
+			// ST terminates the image
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func new_sixel_decoder(r io.Reader) *sixel_decoder {
+	return &sixel_decoder{r: bufio.NewReader(r)}
+}
+
+// Decode a sixel image, as output by tools such as img2sixel
+func DecodeSixel(r io.Reader) (image.Image, error) {
+	d := new_sixel_decoder(r)
+	d.img = image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	if err := d.decode(); err != nil {
+		return nil, err
+	}
+	if d.width == 0 || d.height == 0 {
+		return nil, fmt.Errorf("Sixel image has no pixels")
+	}
+	ans := d.img.SubImage(image.Rect(0, 0, d.width, d.height)).(*image.NRGBA)
+	if !d.transparent_background {
+		// pixels that were never drawn are the background color, black
+		for i := 3; i < len(d.img.Pix); i += 4 {
+			d.img.Pix[i] = 255
+		}
+	}
+	return ans, nil
+}
+
+// The size of a sixel image. The image data is parsed in full as sixel images
+// do not need to declare their size up front.
+func DecodeSixelConfig(r io.Reader) (image.Config, error) {
+	d := new_sixel_decoder(r)
+	if err := d.decode(); err != nil {
+		return image.Config{}, err
+	}
+	if d.width == 0 || d.height == 0 {
+		return image.Config{}, fmt.Errorf("Sixel image has no pixels")
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: d.width, Height: d.height}, nil
+}
+
+func init() {
+	image.RegisterFormat("sixel", "\x1bP", DecodeSixel, DecodeSixelConfig)
+	image.RegisterFormat("sixel", "\x90", DecodeSixel, DecodeSixelConfig)
+}
+
+// Write a row of sixels using repeat introducers for runs, omitting trailing
+// empty sixels
+func write_sixel_row(w *bufio.Writer, row []uint8) {
+	for len(row) > 0 && row[len(row)-1] == 0 {
+		row = row[:len(row)-1]
+	}
+	for len(row) > 0 {
+		n := 1
+		for n < len(row) && row[n] == row[0] {
+			n++
+		}
+		if n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, row[0]+'?')
+		} else {
+			for i := 0; i < n; i++ {
+				w.WriteByte(row[0] + '?')
+			}
+		}
+		row = row[n:]
+	}
+}
+
+// Encode an image as sixels, for display in terminals that do not support the
+// kitty graphics protocol. The image is first reduced to at most 256 colors
+// using Quantize() with the specified options. Pixels that are quantized to
+// the transparent color, if any, are not drawn.
+func EncodeSixel(w io.Writer, img image.Image, opts QuantizeOptions) error {
+	q := Quantize(img, opts)
+	width, height := q.Rect.Dx(), q.Rect.Dy()
+	transparent := -1
+	for i, c := range q.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparent = i
+			break
+		}
+	}
+	bw := bufio.NewWriter(w)
+	background := 0
+	if transparent > -1 {
+		background = 1
+	}
+	fmt.Fprintf(bw, "\x1bP0;%d;0q\"1;1;%d;%d", background, width, height)
+	to_percent := func(x uint8) int { return (int(x)*100 + 127) / 255 }
+	for i, c := range q.Palette {
+		if i != transparent {
+			n := color.NRGBAModel.Convert(c).(color.NRGBA)
+			fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, to_percent(n.R), to_percent(n.G), to_percent(n.B))
+		}
+	}
+	row := make([]uint8, width)
+	used := make([]bool, len(q.Palette))
+	for y := 0; y < height; y += 6 {
+		band := min(6, height-y)
+		clear(used)
+		for _, idx := range q.Pix[y*q.Stride : (y+band-1)*q.Stride+width] {
+			used[idx] = true
+		}
+		first := true
+		for c, is_used := range used {
+			if !is_used || c == transparent {
+				continue
+			}
+			if !first {
+				// overprint the band in the next color
+				bw.WriteByte('$')
+			}
+			first = false
+			clear(row)
+			for dy := 0; dy < band; dy++ {
+				for x, idx := range q.Pix[(y+dy)*q.Stride : (y+dy)*q.Stride+width] {
+					if int(idx) == c {
+						row[x] |= 1 << dy
+					}
+				}
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			write_sixel_row(bw, row)
+		}
+		if y+6 < height {
+			bw.WriteByte('-')
+		}
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
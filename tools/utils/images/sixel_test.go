@@ -0,0 +1,101 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package images
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestSixelDecoding(t *testing.T) {
+	red, green := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 255, 0, 255}
+	if diff := cmp.Diff(red, dec_hls_to_rgb(120, 50, 100)); diff != "" {
+		t.Fatalf("Incorrect HLS conversion:\n%s", diff)
+	}
+	data := "\x1bP0;1;0q\"1;1;3;7#1;2;100;0;0#1!3~-#2;2;0;100;0#2N\x1b\\"
+	c, format, err := image.DecodeConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "sixel" || c.Width != 3 || c.Height != 10 {
+		t.Fatalf("Incorrect config for sixel image: %s %dx%d", format, c.Width, c.Height)
+	}
+	img, format, err := image.Decode(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); format != "sixel" || b.Dx() != 3 || b.Dy() != 10 {
+		t.Fatalf("Incorrect sixel image: %s %v", format, b)
+	}
+	nrgba := img.(*image.NRGBA)
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 3; x++ {
+			expected := color.NRGBA{}
+			switch {
+			case y < 6:
+				expected = red
+			case x == 0:
+				expected = green
+			}
+			if diff := cmp.Diff(expected, nrgba.NRGBAAt(x, y)); diff != "" {
+				t.Fatalf("Incorrect pixel at (%d, %d):\n%s", x, y, diff)
+			}
+		}
+	}
+	// an opaque background and the default palette
+	img, err = DecodeSixel(strings.NewReader("\x1bPq#2~~$#0??-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 2 || b.Dy() != 6 || !IsOpaque(img) {
+		t.Fatalf("Incorrect sixel image with opaque background: %v opaque: %v", b, IsOpaque(img))
+	}
+	if _, err = DecodeSixel(strings.NewReader("\x1bPq!99999999~")); err == nil {
+		t.Fatalf("Sixel image with huge repeat count not rejected")
+	}
+	// each dimension is below the maximum but the number of pixels is not
+	if _, err = DecodeSixel(strings.NewReader("\x1bPq!16000~" + strings.Repeat("-", 16000/6) + "~")); err == nil {
+		t.Fatalf("Sixel image with too many pixels not rejected")
+	}
+	if _, err = DecodeSixelConfig(strings.NewReader("\x1bPq\"1;1;16000;16000~")); err == nil {
+		t.Fatalf("Sixel image with too many pixels in its raster attributes not rejected")
+	}
+}
+
+func TestSixelEncoding(t *testing.T) {
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {}}
+	img := image.NewNRGBA(image.Rect(0, 0, 11, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 11; x++ {
+			img.SetNRGBA(x, y, colors[(x/5+y)%len(colors)])
+		}
+	}
+	var b strings.Builder
+	if err := EncodeSixel(&b, img, QuantizeOptions{MaxColors: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "!") {
+		t.Fatalf("Runs not encoded with repeat introducers: %#v", b.String())
+	}
+	decoded, err := DecodeSixel(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(img.Rect, decoded.Bounds()); diff != "" {
+		t.Fatalf("Incorrect size of encoded sixel image:\n%s", diff)
+	}
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 11; x++ {
+			if diff := cmp.Diff(img.NRGBAAt(x, y), decoded.(*image.NRGBA).NRGBAAt(x, y)); diff != "" {
+				t.Fatalf("Incorrect pixel at (%d, %d):\n%s", x, y, diff)
+			}
+		}
+	}
+}
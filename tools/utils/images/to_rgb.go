@@ -192,11 +192,8 @@ func (s *scanner_rgb) scan(x1, y1, x2, y2 int, dst []uint8) {
 		j := 0
 		for y := y1; y < y2; y++ {
 			i := y*img.Stride + x1*4
-			for x := x1; x < x2; x++ {
-				blend(dst[j:j+3:j+3], s.opaque_base, img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3])
-				j += 3
-				i += 4
-			}
+			s.nrgba_row_to_rgb(img.Pix[i:i+(x2-x1)*4], dst[j:j+(x2-x1)*3])
+			j += (x2 - x1) * 3
 		}
 
 	case *image.NRGBA64:
@@ -214,29 +211,8 @@ func (s *scanner_rgb) scan(x1, y1, x2, y2 int, dst []uint8) {
 		j := 0
 		for y := y1; y < y2; y++ {
 			i := y*img.Stride + x1*4
-			for x := x1; x < x2; x++ {
-				d := dst[j : j+3 : j+3]
-				a := img.Pix[i+3]
-				switch a {
-				case 0:
-					d[0] = s.opaque_base_uint[0]
-					d[1] = s.opaque_base_uint[1]
-					d[2] = s.opaque_base_uint[2]
-				case 0xff:
-					s := img.Pix[i : i+3 : i+3]
-					d[0] = s[0]
-					d[1] = s[1]
-					d[2] = s[2]
-				default:
-					r16 := uint16(img.Pix[i])
-					g16 := uint16(img.Pix[i+1])
-					b16 := uint16(img.Pix[i+2])
-					a16 := uint16(a)
-					blend(d, s.opaque_base, uint8(r16*0xff/a16), uint8(g16*0xff/a16), uint8(b16*0xff/a16), a)
-				}
-				j += 3
-				i += 4
-			}
+			s.rgba_row_to_rgb(img.Pix[i:i+(x2-x1)*4], dst[j:j+(x2-x1)*3])
+			j += (x2 - x1) * 3
 		}
 
 	case *image.RGBA64:
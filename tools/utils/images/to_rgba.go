@@ -78,38 +78,13 @@ func (s *scanner) scan(x1, y1, x2, y2 int, dst []uint8) {
 		}
 
 	case *image.RGBA:
+		size := (x2 - x1) * 4
 		j := 0
+		i := y1*img.Stride + x1*4
 		for y := y1; y < y2; y++ {
-			i := y*img.Stride + x1*4
-			for x := x1; x < x2; x++ {
-				d := dst[j : j+4 : j+4]
-				a := img.Pix[i+3]
-				switch a {
-				case 0:
-					d[0] = 0
-					d[1] = 0
-					d[2] = 0
-					d[3] = a
-				case 0xff:
-					s := img.Pix[i : i+4 : i+4]
-					d[0] = s[0]
-					d[1] = s[1]
-					d[2] = s[2]
-					d[3] = a
-				default:
-					s := img.Pix[i : i+4 : i+4]
-					r16 := uint16(s[0])
-					g16 := uint16(s[1])
-					b16 := uint16(s[2])
-					a16 := uint16(a)
-					d[0] = uint8(r16 * 0xff / a16)
-					d[1] = uint8(g16 * 0xff / a16)
-					d[2] = uint8(b16 * 0xff / a16)
-					d[3] = a
-				}
-				j += 4
-				i += 4
-			}
+			unpremultiply_row(img.Pix[i:i+size], dst[j:j+size])
+			j += size
+			i += img.Stride
 		}
 
 	case *image.RGBA64:
@@ -346,6 +321,10 @@ func (self *Context) paste_nrgba_onto_opaque(background *image.NRGBA, img image.
 	bg := [3]float64{float64(bgcol.R), float64(bgcol.G), float64(bgcol.B)}
 	self.run_paste(src, background, pos, func(dst []byte) {
 		for len(dst) > 0 {
+			if n := opaque_prefix_length(dst) * 4; n > 0 {
+				dst = dst[n:]
+				continue
+			}
 			a := float64(dst[3]) / 255.0
 			for i := range dst[:3] {
 				// uint8() automatically converts floats greater than 255 but less than 256 to 255
@@ -39,6 +39,7 @@ func (self *Context) FlipPixelsV(bytes_per_pixel, width, height int, pix []uint8
 	stride := bytes_per_pixel * width
 	num := height / 2
 	self.Parallel(0, num, func(ys <-chan int) {
+		tmp := make([]uint8, stride)
 		for y := range ys {
 			upper := y
 			lower := height - 1 - y
@@ -46,9 +47,9 @@ func (self *Context) FlipPixelsV(bytes_per_pixel, width, height int, pix []uint8
 			b := lower * stride
 			as := pix[a : a+stride : a+stride]
 			bs := pix[b : b+stride : b+stride]
-			for i := range as {
-				as[i], bs[i] = bs[i], as[i]
-			}
+			copy(tmp, as)
+			copy(as, bs)
+			copy(bs, tmp)
 		}
 	})
 
@@ -0,0 +1,91 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+type NaturalSortOptions struct {
+	CaseInsensitive bool
+	// Used to compare the non-numeric parts of strings, for example to use
+	// locale specific collation. Defaults to comparing bytes.
+	Collate func(a, b string) int
+}
+
+func is_ascii_digit(b byte) bool { return '0' <= b && b <= '9' }
+
+// Split off the leading run of digits or non-digits from text
+func next_natural_chunk(text string) (chunk, rest string, is_number bool) {
+	is_number = is_ascii_digit(text[0])
+	i := 1
+	for i < len(text) && is_ascii_digit(text[i]) == is_number {
+		i++
+	}
+	return text[:i], text[i:], is_number
+}
+
+func compare_numeric_chunks(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(ta) != len(tb) {
+		if len(ta) < len(tb) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(ta, tb)
+}
+
+// Return a function that compares strings so that runs of digits in them
+// are compared by their numeric value, for example: file2 < file10 and
+// 1.9 < 1.10. Strings that differ only in case or leading zeros are ordered
+// using a plain comparison, so the ordering is total.
+func NaturalComparator(opts ...NaturalSortOptions) func(a, b string) int {
+	var o NaturalSortOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	collate := o.Collate
+	if collate == nil {
+		collate = strings.Compare
+	}
+	return func(a, b string) int {
+		ra, rb := a, b
+		if o.CaseInsensitive {
+			ra, rb = strings.ToLower(ra), strings.ToLower(rb)
+		}
+		for ra != "" && rb != "" {
+			ca, rest_a, a_is_number := next_natural_chunk(ra)
+			cb, rest_b, b_is_number := next_natural_chunk(rb)
+			var c int
+			if a_is_number && b_is_number {
+				c = compare_numeric_chunks(ca, cb)
+			} else {
+				c = collate(ca, cb)
+			}
+			if c != 0 {
+				return c
+			}
+			ra, rb = rest_a, rest_b
+		}
+		if ra != rb {
+			if ra == "" {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	}
+}
+
+// Compare strings using natural ordering, see NaturalComparator
+var NaturalCompare = NaturalComparator()
+
+func NaturalSort(items []string, opts ...NaturalSortOptions) {
+	slices.SortStableFunc(items, NaturalComparator(opts...))
+}
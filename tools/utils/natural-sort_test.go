@@ -0,0 +1,31 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestNaturalSort(t *testing.T) {
+	q := func(input, expected string, opts ...NaturalSortOptions) {
+		actual := strings.Split(input, " ")
+		NaturalSort(actual, opts...)
+		if diff := cmp.Diff(strings.Split(expected, " "), actual); diff != "" {
+			t.Fatalf("Failed to sort: %#v\n%s", input, diff)
+		}
+	}
+	q("file10 file2 file1", "file1 file2 file10")
+	q("1.10 1.9 1.9.1 1.2", "1.2 1.9 1.9.1 1.10")
+	q("a010 a9 a10 a09", "a09 a9 a010 a10")
+	q("b a10 B a2", "B a2 a10 b")
+	q("b a10 B a2", "a2 a10 B b", NaturalSortOptions{CaseInsensitive: true})
+	q("x 99999999999999999999999 100000000000000000000000 1", "1 99999999999999999999999 100000000000000000000000 x")
+	reverse := func(a, b string) int { return strings.Compare(b, a) }
+	q("a1 b1 a2", "b1 a1 a2", NaturalSortOptions{Collate: reverse})
+}
@@ -0,0 +1,231 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"kitty/tools/utils/shlex"
+)
+
+var _ = fmt.Print
+
+type OpenCriterion struct {
+	Type, Value string
+}
+
+// A rule for opening URLs, the URL is opened by running Actions if it matches
+// all the Criteria
+type OpenRule struct {
+	Criteria []OpenCriterion
+	Actions  [][]string
+}
+
+// Parse rules in the same format as open-actions.conf, except that actions
+// are command lines rather than kitty actions. Rules are separated by blank
+// lines, malformed lines are ignored.
+func ParseOpenRules(text string) (ans []OpenRule) {
+	current := OpenRule{}
+	finish := func() {
+		if len(current.Criteria) > 0 && len(current.Actions) > 0 {
+			ans = append(ans, current)
+		}
+		current = OpenRule{}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			finish()
+			continue
+		}
+		if line[0] == '#' {
+			continue
+		}
+		key, val, found := strings.Cut(line, " ")
+		val = strings.TrimSpace(val)
+		if !found || val == "" {
+			continue
+		}
+		switch key = strings.ToLower(key); key {
+		case "action":
+			if argv, err := shlex.Split(val); err == nil && len(argv) > 0 {
+				current.Actions = append(current.Actions, argv)
+			}
+		case "mime", "ext", "protocol", "file", "path", "url", "fragment_matches":
+			// regular expressions are case sensitive
+			if key != "url" && key != "fragment_matches" {
+				val = strings.ToLower(val)
+			}
+			current.Criteria = append(current.Criteria, OpenCriterion{Type: key, Value: val})
+		}
+	}
+	finish()
+	return
+}
+
+type open_target struct {
+	raw, path string
+	purl      *url.URL
+}
+
+func (self *open_target) matches(c OpenCriterion) bool {
+	any_of := func(matches func(string) bool) bool {
+		for _, x := range strings.Split(c.Value, ",") {
+			if matches(strings.TrimSpace(x)) {
+				return true
+			}
+		}
+		return false
+	}
+	switch c.Type {
+	case "url", "fragment_matches":
+		pat, err := regexp.Compile(c.Value)
+		if err != nil {
+			return false
+		}
+		q := self.purl.Fragment
+		if c.Type == "url" {
+			q, err = url.PathUnescape(self.raw)
+			if err != nil {
+				q = self.raw
+			}
+		}
+		return pat.MatchString(q)
+	case "mime":
+		var mt string
+		if self.purl.Scheme == "" || self.purl.Scheme == "file" {
+			mt = GuessMimeTypeWithFileSystemAccess(self.path)
+		} else {
+			mt = GuessMimeType(self.path)
+		}
+		if mt == "" {
+			return false
+		}
+		mt = strings.ToLower(mt)
+		return any_of(func(pat string) bool {
+			m, err := path.Match(pat, mt)
+			return m && err == nil
+		})
+	case "ext":
+		p := strings.ToLower(self.path)
+		return self.path != "" && any_of(func(ext string) bool { return strings.HasSuffix(p, "."+ext) })
+	case "protocol":
+		scheme := strings.ToLower(self.purl.Scheme)
+		if scheme == "" {
+			scheme = "file"
+		}
+		return any_of(func(x string) bool { return x == scheme })
+	case "path":
+		m, err := path.Match(c.Value, strings.ToLower(self.path))
+		return m && err == nil
+	case "file":
+		m, err := path.Match(c.Value, strings.ToLower(path.Base(self.path)))
+		return m && err == nil
+	}
+	return false
+}
+
+func (self *open_target) expand(arg string) string {
+	return os.Expand(arg, func(name string) string {
+		switch name {
+		case "URL":
+			return self.raw
+		case "FILE_PATH":
+			return self.path
+		case "FILE":
+			return path.Base(self.path)
+		case "FRAGMENT":
+			return self.purl.Fragment
+		case "URL_PATH":
+			ans := self.purl.EscapedPath()
+			if self.purl.RawQuery != "" {
+				ans += "?" + self.purl.RawQuery
+			}
+			if self.purl.Fragment != "" {
+				ans += "#" + self.purl.EscapedFragment()
+			}
+			return ans
+		}
+		return os.Getenv(name)
+	})
+}
+
+func system_opener() []string {
+	if runtime.GOOS == "darwin" {
+		return []string{"open"}
+	}
+	if Which("xdg-open") != "" {
+		return []string{"xdg-open"}
+	}
+	return nil
+}
+
+// The command lines to run to open the specified URL or path. These come from
+// the first matching rule, falling back to the system opener when there is
+// no matching rule.
+func OpenCommandsFor(raw string, rules []OpenRule) ([][]string, error) {
+	purl, err := url.Parse(raw)
+	if err != nil {
+		// treat it as a path, for example, one containing a literal %
+		purl = &url.URL{Path: raw}
+	}
+	t := open_target{raw: raw, path: purl.Path, purl: purl}
+	if t.purl.Scheme == "" || t.purl.Scheme == "file" {
+		t.path = Expanduser(t.path)
+	}
+	for _, r := range rules {
+		matches := true
+		for _, c := range r.Criteria {
+			if !t.matches(c) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return Map(func(argv []string) []string { return Map(t.expand, argv) }, r.Actions), nil
+		}
+	}
+	if opener := system_opener(); opener != nil {
+		return [][]string{append(opener, raw)}, nil
+	}
+	return nil, fmt.Errorf("No rule in %s matches %s and no system program to open it was found", filepath.Join(ConfigDir(), "open-with.conf"), raw)
+}
+
+var UserOpenRules = sync.OnceValue(func() []OpenRule {
+	conf_path := filepath.Join(ConfigDir(), "open-with.conf")
+	raw, err := os.ReadFile(conf_path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, "Failed to read", conf_path, "for open rules with error:", err)
+		}
+		return nil
+	}
+	return ParseOpenRules(UnsafeBytesToString(raw))
+})
+
+// Open the specified URL or path using the rules from open-with.conf in the
+// kitty config directory, falling back to the system opener. The programs
+// are run in the background, without waiting for them to finish.
+func OpenWithRules(raw string) error {
+	cmds, err := OpenCommandsFor(raw, UserOpenRules())
+	if err != nil {
+		return err
+	}
+	for _, argv := range cmds {
+		if err = exec.Command(argv[0], argv[1:]...).Start(); err != nil {
+			return fmt.Errorf("Failed to run %s with error: %w", argv[0], err)
+		}
+	}
+	return nil
+}
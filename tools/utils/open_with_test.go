@@ -0,0 +1,57 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestOpenRules(t *testing.T) {
+	rules := ParseOpenRules(`
+# images
+mime image/*
+action viewer $FILE_PATH
+action 'second viewer' "$FILE"
+
+protocol HTTP,https
+fragment_matches ^L[0-9]+$
+action browser --line $FRAGMENT $URL_PATH
+
+malformed
+ext txt,md
+action
+
+protocol file
+path /some/*
+action edit $FILE_PATH
+`)
+	if diff := cmp.Diff([]OpenRule{
+		{Criteria: []OpenCriterion{{"mime", "image/*"}}, Actions: [][]string{{"viewer", "$FILE_PATH"}, {"second viewer", "$FILE"}}},
+		{Criteria: []OpenCriterion{{"protocol", "http,https"}, {"fragment_matches", "^L[0-9]+$"}}, Actions: [][]string{{"browser", "--line", "$FRAGMENT", "$URL_PATH"}}},
+		{Criteria: []OpenCriterion{{"protocol", "file"}, {"path", "/some/*"}}, Actions: [][]string{{"edit", "$FILE_PATH"}}},
+	}, rules); diff != "" {
+		t.Fatalf("Open rules not parsed correctly:\n%s", diff)
+	}
+
+	test := func(url string, expected ...[]string) {
+		actual, err := OpenCommandsFor(url, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Incorrect commands to open: %s\n%s", url, diff)
+		}
+	}
+	test("/a/b%20c.png", []string{"viewer", "/a/b c.png"}, []string{"second viewer", "b c.png"})
+	test("file:///some/file.txt", []string{"edit", "/some/file.txt"})
+	test("/some/file.txt", []string{"edit", "/some/file.txt"})
+	test("https://x.com/a%20b?q=1#L12", []string{"browser", "--line", "L12", "/a%20b?q=1#L12"})
+	if cmds, err := OpenCommandsFor("https://x.com/a#nomatch", rules); err == nil && len(cmds) > 0 && cmds[0][0] == "browser" {
+		t.Fatalf("Rule with non-matching fragment was used")
+	}
+}
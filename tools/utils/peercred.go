@@ -0,0 +1,47 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"strconv"
+)
+
+var _ = fmt.Print
+
+// The credentials of the process at the other end of a UNIX socket. Fields
+// that cannot be determined on the current platform are -1.
+type PeerCredentials struct {
+	Uid, Gid, Pid int
+}
+
+// The credentials of the peer of a connected UNIX socket
+func PeerCredentialsOf(conn net.Conn) (ans PeerCredentials, err error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ans, fmt.Errorf("Peer credentials are only available for UNIX sockets")
+	}
+	rc, err := uc.SyscallConn()
+	if err != nil {
+		return
+	}
+	var serr error
+	if err = rc.Control(func(fd uintptr) { ans, serr = peer_credentials(int(fd)) }); err != nil {
+		return
+	}
+	return ans, serr
+}
+
+// Resolve a user name or numeric uid to a uid
+func LookupUid(name_or_uid string) (int, error) {
+	if uid, err := strconv.Atoi(name_or_uid); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(name_or_uid)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(u.Uid)
+}
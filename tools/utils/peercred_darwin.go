@@ -0,0 +1,30 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build darwin
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+var _ = fmt.Print
+
+// On macOS LOCAL_PEERCRED provides the uid and groups and LOCAL_PEERPID the pid
+func peer_credentials(fd int) (PeerCredentials, error) {
+	ans := PeerCredentials{-1, -1, -1}
+	c, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return ans, err
+	}
+	ans.Uid = int(c.Uid)
+	if c.Ngroups > 0 {
+		ans.Gid = int(c.Groups[0])
+	}
+	if pid, err := unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID); err == nil {
+		ans.Pid = pid
+	}
+	return ans, nil
+}
@@ -0,0 +1,27 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build freebsd
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+var _ = fmt.Print
+
+// On FreeBSD LOCAL_PEERCRED provides the uid and groups, the pid is unknown
+func peer_credentials(fd int) (PeerCredentials, error) {
+	ans := PeerCredentials{-1, -1, -1}
+	c, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return ans, err
+	}
+	ans.Uid = int(c.Uid)
+	if c.Ngroups > 0 {
+		ans.Gid = int(c.Groups[0])
+	}
+	return ans, nil
+}
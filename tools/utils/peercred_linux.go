@@ -0,0 +1,22 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build linux
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+var _ = fmt.Print
+
+// On Linux SO_PEERCRED provides all the credentials
+func peer_credentials(fd int) (PeerCredentials, error) {
+	c, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return PeerCredentials{-1, -1, -1}, err
+	}
+	return PeerCredentials{Uid: int(c.Uid), Gid: int(c.Gid), Pid: int(c.Pid)}, nil
+}
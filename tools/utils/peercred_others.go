@@ -0,0 +1,16 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+//go:build !linux && !darwin && !freebsd
+
+package utils
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var _ = fmt.Print
+
+func peer_credentials(fd int) (PeerCredentials, error) {
+	return PeerCredentials{-1, -1, -1}, fmt.Errorf("Getting the credentials of UNIX socket peers is not supported on %s", runtime.GOOS)
+}
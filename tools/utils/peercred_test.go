@@ -0,0 +1,51 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestPeerCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+		t.Skip("Peer credentials not supported on", runtime.GOOS)
+	}
+	path := filepath.Join(t.TempDir(), "sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			defer c.Close()
+			var buf [1]byte
+			_, _ = c.Read(buf[:])
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	creds, err := PeerCredentialsOf(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Uid != os.Geteuid() {
+		t.Fatalf("Incorrect peer uid: %d != %d", creds.Uid, os.Geteuid())
+	}
+	if runtime.GOOS == "linux" && creds.Pid != os.Getpid() {
+		t.Fatalf("Incorrect peer pid: %d != %d", creds.Pid, os.Getpid())
+	}
+	if uid, err := LookupUid(fmt.Sprint(os.Geteuid())); err != nil || uid != os.Geteuid() {
+		t.Fatalf("Failed to lookup numeric uid: %d %v", uid, err)
+	}
+}
@@ -0,0 +1,167 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package style
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+var underline_style_names = map[underline_style]string{
+	straight_underline: "straight", double_underline: "double", curly_underline: "curly", dotted_underline: "dotted", dashed_underline: "dashed",
+}
+
+func (self sgr_color) as_spec() string {
+	if self.number > 0 {
+		return strconv.Itoa(self.number - 1)
+	}
+	return self.color.AsRGBSharp()
+}
+
+// The formatting as a style spec, as accepted by Context.SprintFunc()
+func (self sgr_state) as_spec() string {
+	ans := make([]string, 0, 8)
+	add := func(is_set bool, spec string) {
+		if is_set {
+			ans = append(ans, spec)
+		}
+	}
+	add(self.bold, "bold")
+	add(self.dim, "dim")
+	add(self.italic, "italic")
+	add(self.reverse, "reverse")
+	add(self.strikethrough, "strikethrough")
+	if name := underline_style_names[self.underline_style]; name != "" {
+		ans = append(ans, "u="+name)
+	}
+	add(self.fg.number != 0, "fg="+self.fg.as_spec())
+	add(self.bg.number != 0, "bg="+self.bg.as_spec())
+	add(self.uc.number != 0, "uc="+self.uc.as_spec())
+	return strings.Join(ans, " ")
+}
+
+// The formatting as an object suitable for serialization to JSON, with only
+// the formatting that is not the default present
+func (self sgr_state) as_json(ans map[string]any) {
+	add := func(is_set bool, key string) {
+		if is_set {
+			ans[key] = true
+		}
+	}
+	add(self.bold, "bold")
+	add(self.dim, "dim")
+	add(self.italic, "italic")
+	add(self.reverse, "reverse")
+	add(self.strikethrough, "strikethrough")
+	if name := underline_style_names[self.underline_style]; name != "" {
+		ans["underline"] = name
+	}
+	for key, c := range map[string]sgr_color{"fg": self.fg, "bg": self.bg, "underline_color": self.uc} {
+		if c.number > 0 {
+			ans[key] = c.number - 1
+		} else if c.number < 0 {
+			ans[key] = c.color.AsRGBSharp()
+		}
+	}
+}
+
+type ansi_parser struct {
+	ep           wcswidth.EscapeCodeParser
+	sgr          sgr_state
+	url, link_id string
+	text         strings.Builder
+	spans        Spans
+	// the formatting of each span
+	states []sgr_state
+}
+
+func (self *ansi_parser) add_text() {
+	if self.text.Len() == 0 {
+		return
+	}
+	if n := len(self.spans); n > 0 && self.states[n-1] == self.sgr && self.spans[n-1].URL == self.url && self.spans[n-1].HyperlinkID == self.link_id {
+		self.spans[n-1].Text += self.text.String()
+	} else {
+		self.spans = append(self.spans, Span{Text: self.text.String(), Style: self.sgr.as_spec(), URL: self.url, HyperlinkID: self.link_id})
+		self.states = append(self.states, self.sgr)
+	}
+	self.text.Reset()
+}
+
+func (self *ansi_parser) handle_rune(ch rune) error {
+	self.text.WriteRune(ch)
+	return nil
+}
+
+func (self *ansi_parser) handle_csi(raw []byte) error {
+	if bytes.HasSuffix(raw, []byte("m")) && !bytes.HasPrefix(raw, []byte("?")) {
+		self.add_text()
+		self.sgr.apply_csi(string(raw))
+	}
+	return nil
+}
+
+func (self *ansi_parser) handle_osc(raw []byte) error {
+	if bytes.HasPrefix(raw, []byte("8;")) {
+		self.add_text()
+		h := hyperlink_state{}
+		h.apply_osc(string(raw))
+		self.url, self.link_id = h.url, ""
+		if self.url != "" {
+			for _, x := range strings.Split(h.id, ":") {
+				if k, v, found := strings.Cut(x, "="); found && k == "id" {
+					self.link_id = v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parse_ansi(text string) *ansi_parser {
+	p := ansi_parser{}
+	p.ep.HandleRune = p.handle_rune
+	p.ep.HandleCSI = p.handle_csi
+	p.ep.HandleOSC = p.handle_osc
+	_ = p.ep.ParseString(text)
+	p.add_text()
+	return &p
+}
+
+// Parse text containing ANSI escape codes into styled spans. SGR formatting
+// is converted into style specs and OSC 8 hyperlinks into URLs, all other
+// escape codes are discarded. Adjacent text with the same style is merged into
+// a single span. Use Context.RenderSpans() to convert the spans back into
+// text with escape codes.
+func ParseANSI(text string) Spans {
+	return parse_ansi(text).spans
+}
+
+// Like ParseANSI() except that the spans are objects suitable for
+// serialization to JSON. Each has a text key and keys for the formatting that
+// is not the default: fg, bg and underline_color which are either indices
+// into the 256 color table or #rrggbb strings, bold, dim, italic, reverse,
+// strikethrough, underline which is the underline style and hyperlink which
+// is an object with the url and optionally id of the hyperlink.
+func ParseANSIAsJSON(text string) []map[string]any {
+	p := parse_ansi(text)
+	ans := make([]map[string]any, len(p.spans))
+	for i, s := range p.spans {
+		ans[i] = map[string]any{"text": s.Text}
+		p.states[i].as_json(ans[i])
+		if s.URL != "" {
+			h := map[string]any{"url": s.URL}
+			if s.HyperlinkID != "" {
+				h["id"] = s.HyperlinkID
+			}
+			ans[i]["hyperlink"] = h
+		}
+	}
+	return ans
+}
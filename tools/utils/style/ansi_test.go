@@ -0,0 +1,59 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package style
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestParseANSI(t *testing.T) {
+	ctx := Context{AllowEscapeCodes: true}
+	test := func(text string, expected ...Span) {
+		actual := ParseANSI(text)
+		if diff := cmp.Diff(Spans(expected), actual); diff != "" {
+			t.Fatalf("Failed to parse: %#v\n%s", text, diff)
+		}
+		rendered := ctx.RenderSpans(actual)
+		if diff := cmp.Diff(actual, ParseANSI(rendered)); diff != "" {
+			t.Fatalf("Failed to round trip: %#v via: %#v\n%s", text, rendered, diff)
+		}
+	}
+	test("")
+	test("\x1b[31m")
+	test("plain", Span{Text: "plain"})
+	test("a\x1b[1mb\x1b[22mc", Span{Text: "a"}, Span{Text: "b", Style: "bold"}, Span{Text: "c"})
+	test("\x1b[31ma\x1b[31mb\x1b[0m", Span{Text: "ab", Style: "fg=1"})
+	test("\x1b[91;4:3ma\x1b[24;39mb", Span{Text: "a", Style: "u=curly fg=9"}, Span{Text: "b"})
+	test("\x1b[38;5;1;48;2;1;2;3;1ma", Span{Text: "a", Style: "bold fg=1 bg=#010203"})
+	test("\x1b[38:2:1:2:3;58:5:200ma", Span{Text: "a", Style: "fg=#010203 uc=200"})
+	test("\x1b[3;7;9;2ma\x1b[mb", Span{Text: "a", Style: "dim italic reverse strikethrough"}, Span{Text: "b"})
+	test("\x1b]8;id=1;http://x.com\x1b\\link\x1b]8;;\x1b\\ \x1b[2Jtext\x1b]2;title\x07",
+		Span{Text: "link", URL: "http://x.com", HyperlinkID: "1"}, Span{Text: " text"})
+	test("\x1b]8;;http://x.com\x1b\\a\x1b]8;;http://y.com\x1b\\b", Span{Text: "a", URL: "http://x.com"}, Span{Text: "b", URL: "http://y.com"})
+
+	if q := ctx.RenderSpans(ParseANSI("\x1b[97mx")); q != "\x1b[97mx\x1b[39m" {
+		t.Fatalf("Bright color not rendered correctly: %#v", q)
+	}
+}
+
+func TestParseANSIAsJSON(t *testing.T) {
+	test := func(text string, expected ...map[string]any) {
+		if diff := cmp.Diff(expected, ParseANSIAsJSON(text)); diff != "" {
+			t.Fatalf("Failed to parse: %#v\n%s", text, diff)
+		}
+	}
+	test("a\x1b]8;id=foo;moo\x1b\\\x1b[1;3;38:2:1:2:3;4:3;58:5:4mbc\x1b]8;;\x1b\\\x1b[22;23;24;39;59;41md\x1b[me\n",
+		map[string]any{"text": "a"},
+		map[string]any{"text": "bc", "bold": true, "italic": true, "fg": "#010203", "underline": "curly", "underline_color": 4,
+			"hyperlink": map[string]any{"url": "moo", "id": "foo"}},
+		map[string]any{"text": "d", "bg": 1},
+		map[string]any{"text": "e\n"},
+	)
+	test("\x1b[92ma\x1b[mb\x1b[m\x1b[mc", map[string]any{"text": "a", "fg": 10}, map[string]any{"text": "bc"})
+	test("\x1b[?25ha\x1b]133;A\x1b\\b", map[string]any{"text": "ab"})
+}
@@ -9,11 +9,14 @@ import (
 
 type Context struct {
 	AllowEscapeCodes bool
+	// Colors are converted to the nearest available colors when generating
+	// escape codes for terminals that do not support true color
+	ColorDepth ColorDepth
 }
 
 func (self *Context) SprintFunc(spec string) func(args ...any) string {
-	p := prefix_for_spec(spec)
-	s := suffix_for_spec(spec)
+	p := prefix_for_spec(spec, self.ColorDepth)
+	s := suffix_for_spec(spec, self.ColorDepth)
 
 	return func(args ...any) string {
 		body := fmt.Sprint(args...)
@@ -29,9 +32,17 @@ func (self *Context) SprintFunc(spec string) func(args ...any) string {
 	}
 }
 
+// The escape codes to turn on and turn off the formatting for the specified spec
+func (self *Context) EscapeCodesForSpec(spec string) (prefix, suffix string) {
+	if !self.AllowEscapeCodes {
+		return "", ""
+	}
+	return prefix_for_spec(spec, self.ColorDepth), suffix_for_spec(spec, self.ColorDepth)
+}
+
 func (self *Context) UrlFunc(spec string) func(string, string) string {
-	p := prefix_for_spec(spec)
-	s := suffix_for_spec(spec)
+	p := prefix_for_spec(spec, self.ColorDepth)
+	s := suffix_for_spec(spec, self.ColorDepth)
 
 	return func(url, text string) string {
 		if !self.AllowEscapeCodes {
@@ -0,0 +1,145 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package style
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var _ = fmt.Print
+
+type ColorDepth uint8
+
+const (
+	TRUE_COLOR ColorDepth = iota
+	COLORS_256
+	COLORS_16
+)
+
+func (self ColorDepth) String() string {
+	switch self {
+	case COLORS_256:
+		return "256"
+	case COLORS_16:
+		return "16"
+	}
+	return "truecolor"
+}
+
+// Guess the color depth of the terminal from the environment. This is used
+// as a fallback for terminals that do not respond to capability queries.
+func ColorDepthFromEnvironment() ColorDepth {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return TRUE_COLOR
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case term == "xterm-kitty" || strings.HasSuffix(term, "-direct"):
+		return TRUE_COLOR
+	case strings.Contains(term, "256color"):
+		return COLORS_256
+	case term == "linux" || term == "vt100" || term == "dumb" || strings.HasPrefix(term, "screen") || term == "":
+		return COLORS_16
+	}
+	return COLORS_256
+}
+
+// The default xterm values for the first 16 colors
+var ansi_16_colors = [16]RGBA{
+	{Red: 0x00, Green: 0x00, Blue: 0x00}, {Red: 0xcd, Green: 0x00, Blue: 0x00}, {Red: 0x00, Green: 0xcd, Blue: 0x00}, {Red: 0xcd, Green: 0xcd, Blue: 0x00},
+	{Red: 0x00, Green: 0x00, Blue: 0xee}, {Red: 0xcd, Green: 0x00, Blue: 0xcd}, {Red: 0x00, Green: 0xcd, Blue: 0xcd}, {Red: 0xe5, Green: 0xe5, Blue: 0xe5},
+	{Red: 0x7f, Green: 0x7f, Blue: 0x7f}, {Red: 0xff, Green: 0x00, Blue: 0x00}, {Red: 0x00, Green: 0xff, Blue: 0x00}, {Red: 0xff, Green: 0xff, Blue: 0x00},
+	{Red: 0x5c, Green: 0x5c, Blue: 0xff}, {Red: 0xff, Green: 0x00, Blue: 0xff}, {Red: 0x00, Green: 0xff, Blue: 0xff}, {Red: 0xff, Green: 0xff, Blue: 0xff},
+}
+
+var cube_levels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// The RGB value of the specified entry in the standard 256 color palette
+func ColorFromPaletteIndex(idx uint8) RGBA {
+	switch {
+	case idx < 16:
+		return ansi_16_colors[idx]
+	case idx < 232:
+		idx -= 16
+		return RGBA{Red: cube_levels[idx/36], Green: cube_levels[(idx/6)%6], Blue: cube_levels[idx%6]}
+	default:
+		v := 8 + 10*(idx-232)
+		return RGBA{Red: v, Green: v, Blue: v}
+	}
+}
+
+// A perceptually weighted distance between two colors, see https://www.compuphase.com/cmetric.htm
+func color_distance(a, b RGBA) int {
+	rmean := (int(a.Red) + int(b.Red)) / 2
+	dr, dg, db := int(a.Red)-int(b.Red), int(a.Green)-int(b.Green), int(a.Blue)-int(b.Blue)
+	return (((512 + rmean) * dr * dr) >> 8) + 4*dg*dg + (((767 - rmean) * db * db) >> 8)
+}
+
+func nearest_cube_level(v uint8) (idx int) {
+	for i, l := range cube_levels {
+		if abs_diff(l, v) < abs_diff(cube_levels[idx], v) {
+			idx = i
+		}
+	}
+	return
+}
+
+func abs_diff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// The index of the closest color in the 6x6x6 color cube or grayscale ramp
+// of the 256 color palette. The first 16 colors are not used as their values
+// are user configurable.
+func (self RGBA) Nearest256() uint8 {
+	r, g, b := nearest_cube_level(self.Red), nearest_cube_level(self.Green), nearest_cube_level(self.Blue)
+	cube := uint8(16 + 36*r + 6*g + b)
+	avg := (int(self.Red) + int(self.Green) + int(self.Blue)) / 3
+	gray := uint8(232 + max(0, min(23, (avg-3)/10)))
+	if color_distance(self, ColorFromPaletteIndex(gray)) < color_distance(self, ColorFromPaletteIndex(cube)) {
+		return gray
+	}
+	return cube
+}
+
+// The index of the closest color among the 16 basic ANSI colors
+func (self RGBA) Nearest16() (ans uint8) {
+	best := -1
+	for i, c := range ansi_16_colors {
+		if d := color_distance(self, c); best < 0 || d < best {
+			best, ans = d, uint8(i)
+		}
+	}
+	return
+}
+
+func (self *color_value) downsample(depth ColorDepth) {
+	if !self.is_set || depth == TRUE_COLOR {
+		return
+	}
+	c := &self.val
+	switch depth {
+	case COLORS_256:
+		if !c.is_numbered {
+			*c = color_type{is_numbered: true, val: RGBA{Red: c.val.Nearest256()}}
+		}
+	case COLORS_16:
+		if !c.is_numbered {
+			*c = color_type{is_numbered: true, val: RGBA{Red: c.val.Nearest16()}}
+		} else if c.val.Red > 15 {
+			*c = color_type{is_numbered: true, val: RGBA{Red: ColorFromPaletteIndex(c.val.Red).Nearest16()}}
+		}
+	}
+}
+
+func (self *sgr_code) downsample(depth ColorDepth) {
+	self.fg.downsample(depth)
+	self.bg.downsample(depth)
+	self.uc.downsample(depth)
+}
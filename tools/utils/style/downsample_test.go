@@ -0,0 +1,39 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package style
+
+import (
+	"fmt"
+	"testing"
+)
+
+var _ = fmt.Print
+
+func TestColorDownsampling(t *testing.T) {
+	for c, expected := range map[RGBA]uint8{{Red: 255}: 196, {Red: 128, Green: 128, Blue: 128}: 244, {}: 16, {Red: 0xff, Green: 0xff, Blue: 0xff}: 231} {
+		if actual := c.Nearest256(); actual != expected {
+			t.Fatalf("Nearest 256 color for %s is %d not %d", c.AsRGBSharp(), actual, expected)
+		}
+	}
+	for c, expected := range map[RGBA]uint8{{Red: 250, Green: 5, Blue: 5}: 9, {Red: 10, Green: 10, Blue: 10}: 0, {Red: 0, Green: 200, Blue: 190}: 6} {
+		if actual := c.Nearest16(); actual != expected {
+			t.Fatalf("Nearest 16 color for %s is %d not %d", c.AsRGBSharp(), actual, expected)
+		}
+	}
+	for i := 0; i < 256; i++ {
+		if i >= 16 && ColorFromPaletteIndex(uint8(i)).Nearest256() != uint8(i) {
+			t.Fatalf("Palette color %d does not map to itself", i)
+		}
+	}
+
+	test := func(depth ColorDepth, spec, expected string) {
+		ctx := Context{AllowEscapeCodes: true, ColorDepth: depth}
+		if actual := ctx.SprintFunc(spec)(""); actual != expected {
+			t.Fatalf("Formatting %#v with color depth %s failed: %#v != %#v", spec, depth, expected, actual)
+		}
+	}
+	test(TRUE_COLOR, "fg=#ff0000", "\x1b[38:2:255:0:0m\x1b[39m")
+	test(COLORS_256, "fg=#ff0000 bg=3", "\x1b[38:5:196;43m\x1b[39;49m")
+	test(COLORS_16, "fg=#ff0000", "\x1b[91m\x1b[39m")
+	test(COLORS_16, "fg=196", "\x1b[91m\x1b[39m")
+}
@@ -28,7 +28,7 @@ func (self sgr_color) as_sgr(base int) string {
 			if n <= 7 {
 				return strconv.Itoa(base + n)
 			}
-			return strconv.Itoa(base + 60 + n - 7)
+			return strconv.Itoa(base + 60 + n - 8)
 		}
 		return fmt.Sprintf("%d:5:%d", base+8, n)
 	}
@@ -36,6 +36,9 @@ func (self sgr_color) as_sgr(base int) string {
 }
 
 func (self *sgr_color) from_extended(nums []int) bool {
+	if len(nums) == 0 {
+		return false
+	}
 	switch nums[0] {
 	case 5:
 		if len(nums) > 1 {
@@ -150,8 +153,8 @@ func (self *sgr_state) apply_csi(raw string) {
 	}
 	parts := strings.Split(raw, ";")
 	nums := make([]int, 0, 8)
-	for _, part := range parts {
-		subparts := strings.Split(part, ":")
+	for i := 0; i < len(parts); i++ {
+		subparts := strings.Split(parts[i], ":")
 		nums = nums[:0]
 		for _, b := range subparts {
 			q, err := strconv.Atoi(b)
@@ -162,6 +165,19 @@ func (self *sgr_state) apply_csi(raw string) {
 		if len(nums) == 0 {
 			continue
 		}
+		if len(nums) == 1 && (nums[0] == 38 || nums[0] == 48 || nums[0] == 58) && i+1 < len(parts) {
+			// extended colors using the legacy semi-colon separated form,
+			// for example: 38;5;n or 38;2;r;g;b
+			count := 2
+			if parts[i+1] == "2" {
+				count = 4
+			}
+			for _, b := range parts[i+1 : min(len(parts), i+1+count)] {
+				q, _ := strconv.Atoi(b)
+				nums = append(nums, q)
+			}
+			i += count
+		}
 		switch nums[0] {
 		case 0:
 			self.reset()
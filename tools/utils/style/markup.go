@@ -0,0 +1,261 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package style
+
+import (
+	"fmt"
+	"strings"
+
+	"kitty/tools/utils/shlex"
+	"kitty/tools/wcswidth"
+)
+
+var _ = fmt.Print
+
+// A run of text with a single style. Style is a spec as accepted by
+// Context.SprintFunc()
+type Span struct {
+	Text, Style, URL string
+	// The id of the hyperlink, used by terminals to identify hyperlinks that
+	// span multiple lines
+	HyperlinkID string
+}
+
+type Spans []Span
+
+func (self Spans) Width() (ans int) {
+	for _, s := range self {
+		ans += wcswidth.Stringwidth(s.Text)
+	}
+	return
+}
+
+func (self Spans) PlainText() string {
+	b := strings.Builder{}
+	for _, s := range self {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// Truncate the spans so that they fit in the specified number of cells
+func (self Spans) Truncate(width int) Spans {
+	ans := make(Spans, 0, len(self))
+	for _, s := range self {
+		if width <= 0 {
+			break
+		}
+		t, w := wcswidth.TruncateToVisualLengthWithWidth(s.Text, width)
+		if t != "" {
+			s.Text = t
+			ans = append(ans, s)
+		}
+		width -= w
+	}
+	return ans
+}
+
+func (self *Context) RenderSpans(spans Spans) string {
+	b := strings.Builder{}
+	for _, s := range spans {
+		if !self.AllowEscapeCodes || (s.Style == "" && s.URL == "") {
+			b.WriteString(s.Text)
+			continue
+		}
+		p, sfx := prefix_for_spec(s.Style, self.ColorDepth), suffix_for_spec(s.Style, self.ColorDepth)
+		b.WriteString(p)
+		if s.URL != "" {
+			uc := url_code{url: s.URL, id: s.HyperlinkID}
+			b.WriteString(uc.prefix())
+			b.WriteString(s.Text)
+			b.WriteString(uc.suffix())
+		} else {
+			b.WriteString(s.Text)
+		}
+		b.WriteString(sfx)
+	}
+	return b.String()
+}
+
+// Render markup, if the markup is invalid, the text is returned with all
+// tags stripped.
+func (self *Context) Markup(text string) string {
+	spans, err := ParseMarkup(text)
+	if err != nil {
+		return StripMarkup(text)
+	}
+	return self.RenderSpans(spans)
+}
+
+var markup_escapes = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var markup_unescapes = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
+// Escape text so that it is rendered as is when embedded in markup
+func EscapeMarkup(text string) string {
+	return markup_escapes.Replace(text)
+}
+
+// Remove all tags from markup returning the plain text
+func StripMarkup(text string) string {
+	b := strings.Builder{}
+	for text != "" {
+		before, after, found := strings.Cut(text, "<")
+		b.WriteString(before)
+		if !found {
+			break
+		}
+		if _, rest, found := strings.Cut(after, ">"); found {
+			text = rest
+		} else {
+			b.WriteString("<")
+			text = after
+		}
+	}
+	return markup_unescapes.Replace(b.String())
+}
+
+// Like fmt.Sprintf() except that textual arguments (strings, errors and
+// fmt.Stringers) are escaped so they cannot contain markup. Other arguments
+// are formatted as is, so verbs such as %d and %x work as usual.
+func Markupf(format string, args ...any) string {
+	escaped := make([]any, len(args))
+	for i, a := range args {
+		switch x := a.(type) {
+		case string:
+			escaped[i] = EscapeMarkup(x)
+		case error:
+			escaped[i] = EscapeMarkup(x.Error())
+		case fmt.Stringer:
+			escaped[i] = EscapeMarkup(x.String())
+		default:
+			escaped[i] = a
+		}
+	}
+	return fmt.Sprintf(format, escaped...)
+}
+
+// Replace {name} placeholders in template with the escaped values from the
+// map. Unlike positional formatting this allows translations to re-order the
+// placeholders. Placeholders that are not in the map are left as is.
+func MarkupTemplate(template string, values map[string]any) string {
+	b := strings.Builder{}
+	b.Grow(len(template))
+	for template != "" {
+		before, after, found := strings.Cut(template, "{")
+		b.WriteString(before)
+		if !found {
+			break
+		}
+		name, rest, found := strings.Cut(after, "}")
+		if val, ok := values[name]; found && ok {
+			b.WriteString(EscapeMarkup(fmt.Sprint(val)))
+			template = rest
+		} else {
+			b.WriteString("{")
+			template = after
+		}
+	}
+	return b.String()
+}
+
+type markup_tag struct {
+	name, style, url string
+}
+
+func parse_markup_tag(raw string) (ans markup_tag, err error) {
+	fields, err := shlex.Split(raw)
+	if err != nil {
+		return ans, fmt.Errorf("Invalid markup tag: <%s> with error: %w", raw, err)
+	}
+	if len(fields) == 0 {
+		return ans, fmt.Errorf("Empty markup tag")
+	}
+	ans.name, _, _ = strings.Cut(fields[0], "=")
+	specs := make([]string, 0, len(fields))
+	switch ans.name {
+	case "a", "span":
+		fields = fields[1:]
+	case "b", "bold", "i", "italic", "u", "underline", "s", "strikethrough", "dim", "faint", "reverse", "fg", "bg", "uc":
+	default:
+		if _, is_color := named_colors[ans.name]; !is_color {
+			return ans, fmt.Errorf("Unknown markup tag: <%s>", ans.name)
+		}
+		specs = append(specs, "fg="+ans.name)
+		fields = fields[1:]
+	}
+	for _, f := range fields {
+		if key, val, found := strings.Cut(f, "="); found && (key == "href" || key == "url") {
+			ans.url = val
+		} else {
+			specs = append(specs, f)
+		}
+	}
+	ans.style = strings.Join(specs, " ")
+	return
+}
+
+// Parse markup of the form: <b fg=red>bold red text</b> <a href=https://x.org>link</a>
+// into styled spans. Tags are either style specs, as accepted by
+// Context.SprintFunc(), or color names or the special tags "span" and "a"
+// that take style specs as attributes. Tags can be nested and are closed with
+// either </name> or </>. Use &lt; &gt; and &amp; for literal <, > and &.
+func ParseMarkup(text string) (ans Spans, err error) {
+	stack := make([]markup_tag, 0, 4)
+	current := Span{}
+	add_text := func(t string) {
+		if t = markup_unescapes.Replace(t); t != "" {
+			if n := len(ans); n > 0 && ans[n-1].Style == current.Style && ans[n-1].URL == current.URL {
+				ans[n-1].Text += t
+			} else {
+				current.Text = t
+				ans = append(ans, current)
+			}
+		}
+	}
+	update_current := func() {
+		specs := make([]string, 0, len(stack))
+		current.URL = ""
+		for _, t := range stack {
+			if t.style != "" {
+				specs = append(specs, t.style)
+			}
+			if t.url != "" {
+				current.URL = t.url
+			}
+		}
+		current.Style = strings.Join(specs, " ")
+	}
+	for text != "" {
+		before, after, found := strings.Cut(text, "<")
+		add_text(before)
+		if !found {
+			break
+		}
+		raw, rest, found := strings.Cut(after, ">")
+		if !found {
+			return nil, fmt.Errorf("Unterminated markup tag: <%s", after)
+		}
+		text = rest
+		if strings.HasPrefix(raw, "/") {
+			name := strings.TrimSpace(raw[1:])
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("Closing tag: <%s> without matching opening tag", raw)
+			}
+			if name != "" && stack[len(stack)-1].name != name {
+				return nil, fmt.Errorf("Closing tag: <%s> does not match open tag: <%s>", raw, stack[len(stack)-1].name)
+			}
+			stack = stack[:len(stack)-1]
+		} else {
+			tag, err := parse_markup_tag(raw)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, tag)
+		}
+		update_current()
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("Unclosed markup tag: <%s>", stack[len(stack)-1].name)
+	}
+	return ans, nil
+}
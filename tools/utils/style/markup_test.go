@@ -0,0 +1,65 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package style
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestMarkup(t *testing.T) {
+	test := func(markup string, expected ...Span) {
+		actual, err := ParseMarkup(markup)
+		if err != nil {
+			t.Fatalf("Failed to parse %#v with error: %s", markup, err)
+		}
+		if diff := cmp.Diff(Spans(expected), actual); diff != "" {
+			t.Fatalf("Failed to parse %#v\n%s", markup, diff)
+		}
+	}
+	test("")
+	test("plain &lt;text&gt;", Span{Text: "plain <text>"})
+	test("a <b fg=red>bold <i>and</i></b> <red>red</>", Span{Text: "a "}, Span{Text: "bold ", Style: "b fg=red"},
+		Span{Text: "and", Style: "b fg=red i"}, Span{Text: " "}, Span{Text: "red", Style: "fg=red"})
+	test(`<a href="https://x.org" u=curly>link</a>`, Span{Text: "link", Style: "u=curly", URL: "https://x.org"})
+	test("<span bg=blue>x</span><span bg=blue>y</span>", Span{Text: "xy", Style: "bg=blue"})
+
+	for _, bad := range []string{"<b>x", "x</b>", "<b>x</i>", "<unknown>x</unknown>", "<b"} {
+		if _, err := ParseMarkup(bad); err == nil {
+			t.Fatalf("No error for invalid markup: %#v", bad)
+		}
+	}
+
+	spans, _ := ParseMarkup("<b>ab</b>c😀d")
+	if spans.Width() != 6 || spans.PlainText() != "abc😀d" {
+		t.Fatalf("Incorrect width: %d for: %#v", spans.Width(), spans.PlainText())
+	}
+	if diff := cmp.Diff(Spans{{Text: "ab", Style: "b"}, {Text: "c"}}, spans.Truncate(4)); diff != "" {
+		t.Fatalf("Truncation failed:\n%s", diff)
+	}
+
+	ctx := Context{AllowEscapeCodes: true}
+	if actual := ctx.Markup("<b>x</b>y"); actual != "\x1b[1mx\x1b[221my" {
+		t.Fatalf("Rendering failed: %#v", actual)
+	}
+	if actual := ctx.Markup("<b>x"); actual != "x" {
+		t.Fatalf("Rendering invalid markup failed: %#v", actual)
+	}
+	if actual := MarkupTemplate("{b} <b>{a}</b> {x}", map[string]any{"a": "<i>", "b": 1}); actual != "1 <b>&lt;i&gt;</b> {x}" {
+		t.Fatalf("Template failed: %#v", actual)
+	}
+	if actual := Markupf("<b>%s</b>", "a&b"); actual != "<b>a&amp;b</b>" {
+		t.Fatalf("Markupf failed: %#v", actual)
+	}
+	args := []any{"<x>", 3, 1.5, 255, fmt.Errorf("a<b")}
+	if actual := Markupf("%s %03d %.2f %x %v", args...); actual != "&lt;x&gt; 003 1.50 ff a&lt;b" {
+		t.Fatalf("Markupf failed: %#v", actual)
+	}
+	if args[0] != "<x>" {
+		t.Fatalf("Markupf modified its arguments: %#v", args)
+	}
+}
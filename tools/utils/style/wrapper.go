@@ -293,10 +293,13 @@ func (self sgr_code) is_empty() bool {
 }
 
 type url_code struct {
-	url string
+	url, id string
 }
 
 func (self url_code) prefix() string {
+	if self.id != "" {
+		return fmt.Sprintf("\x1b]8;id=%s;%s\x1b\\", self.id, self.url)
+	}
 	return fmt.Sprintf("\x1b]8;;%s\x1b\\", self.url)
 }
 
@@ -332,7 +335,7 @@ func (self *sgr_code) update() {
 	}
 }
 
-func parse_spec(spec string) []escape_code {
+func parse_spec(spec string, depth ColorDepth) []escape_code {
 	ans := make([]escape_code, 0, 1)
 	sgr := sgr_code{}
 	sparts, _ := shlex.Split(spec)
@@ -362,6 +365,7 @@ func parse_spec(spec string) []escape_code {
 			sgr.uc.from_string(val)
 		}
 	}
+	sgr.downsample(depth)
 	sgr.update()
 	if !sgr.is_empty() {
 		ans = append(ans, &sgr)
@@ -369,31 +373,37 @@ func parse_spec(spec string) []escape_code {
 	return ans
 }
 
-var parsed_spec_cache = make(map[string][]escape_code)
+type spec_cache_key struct {
+	spec  string
+	depth ColorDepth
+}
+
+var parsed_spec_cache = make(map[spec_cache_key][]escape_code)
 var parsed_spec_cache_mutex = sync.Mutex{}
 
-func cached_parse_spec(spec string) []escape_code {
+func cached_parse_spec(spec string, depth ColorDepth) []escape_code {
 	parsed_spec_cache_mutex.Lock()
 	defer parsed_spec_cache_mutex.Unlock()
-	if val, ok := parsed_spec_cache[spec]; ok {
+	key := spec_cache_key{spec, depth}
+	if val, ok := parsed_spec_cache[key]; ok {
 		return val
 	}
-	ans := parse_spec(spec)
-	parsed_spec_cache[spec] = ans
+	ans := parse_spec(spec, depth)
+	parsed_spec_cache[key] = ans
 	return ans
 }
 
-func prefix_for_spec(spec string) string {
+func prefix_for_spec(spec string, depth ColorDepth) string {
 	sb := strings.Builder{}
-	for _, ec := range cached_parse_spec(spec) {
+	for _, ec := range cached_parse_spec(spec, depth) {
 		sb.WriteString(ec.prefix())
 	}
 	return sb.String()
 }
 
-func suffix_for_spec(spec string) string {
+func suffix_for_spec(spec string, depth ColorDepth) string {
 	sb := strings.Builder{}
-	for _, ec := range cached_parse_spec(spec) {
+	for _, ec := range cached_parse_spec(spec, depth) {
 		sb.WriteString(ec.suffix())
 	}
 	return sb.String()
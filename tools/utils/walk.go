@@ -0,0 +1,227 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/slices"
+)
+
+var _ = fmt.Print
+
+// The names of the files containing ignore rules, in increasing order of precedence
+var IgnoreFileNames = []string{".gitignore", ".ignore"}
+
+type WalkOptions struct {
+	// Skip files and directories ignored by the rules in .gitignore and
+	// .ignore files, also skips .git directories
+	RespectIgnoreFiles bool
+	// Skip files and directories whose names start with a period
+	SkipHidden bool
+	// If not empty, only files matching at least one of these globs are
+	// reported. The globs use the .gitignore syntax and are matched against
+	// paths relative to the root.
+	Include []string
+	// Files and directories matching any of these globs are skipped, uses the
+	// same syntax as Include
+	Exclude []string
+	// Files and directories whose names match any of these globs are
+	// skipped, using the syntax of filepath.Match()
+	ExcludeNames []string
+	// If greater than zero, stop walking once more than this many entries
+	// are found and return ErrTooManyEntries, to avoid walking huge trees
+	MaxEntries int
+	// The maximum number of directories read in parallel, defaults to the
+	// number of CPUs
+	Parallelism int
+}
+
+type WalkEntry struct {
+	fs.DirEntry
+	// The path relative to the root of the walk, using / as the separator
+	RelPath string
+	// The path of the root of the walk joined with RelPath
+	Path string
+}
+
+var ErrTooManyEntries = errors.New("Too many files and directories")
+
+type filtered_walker struct {
+	root             string
+	opts             WalkOptions
+	include, exclude *ignore_rules
+	semaphore        chan bool
+	wg               sync.WaitGroup
+	mutex            sync.Mutex
+	results          []WalkEntry
+	errs             []error
+	aborted          atomic.Bool
+}
+
+func (self *filtered_walker) add(e fs.DirEntry, rel string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.results = append(self.results, WalkEntry{DirEntry: e, RelPath: rel, Path: filepath.Join(self.root, filepath.FromSlash(rel))})
+	if self.opts.MaxEntries > 0 && len(self.results) > self.opts.MaxEntries {
+		self.aborted.Store(true)
+	}
+}
+
+func (self *filtered_walker) add_error(err error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.errs = append(self.errs, err)
+}
+
+func (self *filtered_walker) is_skipped(name, rel string, is_dir bool, stack ignore_stack) bool {
+	if self.opts.SkipHidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+	if self.opts.RespectIgnoreFiles && is_dir && name == ".git" {
+		return true
+	}
+	for _, pat := range self.opts.ExcludeNames {
+		if matched, err := filepath.Match(pat, name); err == nil && matched {
+			return true
+		}
+	}
+	if self.exclude != nil {
+		if matched, negated := self.exclude.match(rel, is_dir); matched && !negated {
+			return true
+		}
+	}
+	if stack.is_ignored(rel, is_dir) {
+		return true
+	}
+	if self.include != nil && !is_dir {
+		matched, negated := self.include.match(rel, false)
+		return !matched || negated
+	}
+	return false
+}
+
+func (self *filtered_walker) walk_dir(rel string, stack ignore_stack) {
+	defer self.wg.Done()
+	if self.aborted.Load() {
+		return
+	}
+	prefix := rel
+	if prefix != "" {
+		prefix += "/"
+	}
+	dir := filepath.Join(self.root, filepath.FromSlash(rel))
+	self.semaphore <- true
+	entries, err := os.ReadDir(dir)
+	if err == nil && self.opts.RespectIgnoreFiles {
+		for _, name := range IgnoreFileNames {
+			stack = stack.push(load_ignore_rules(filepath.Join(dir, name), prefix))
+		}
+	}
+	<-self.semaphore
+	if err != nil {
+		self.add_error(err)
+		return
+	}
+	for _, e := range entries {
+		if self.aborted.Load() {
+			return
+		}
+		erel := prefix + e.Name()
+		is_dir := e.IsDir()
+		if self.is_skipped(e.Name(), erel, is_dir, stack) {
+			continue
+		}
+		self.add(e, erel)
+		if is_dir {
+			self.wg.Add(1)
+			go self.walk_dir(erel, stack)
+		}
+	}
+}
+
+// Order paths relative to the root so that directories come before their
+// contents and the entries in a directory are in natural order
+func compare_walk_paths(a, b string) int {
+	for a != "" && b != "" {
+		ca, ra, _ := strings.Cut(a, "/")
+		cb, rb, _ := strings.Cut(b, "/")
+		if c := NaturalCompare(ca, cb); c != 0 {
+			return c
+		}
+		a, b = ra, rb
+	}
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// Recursively list the contents of the directory root, reading directories
+// in parallel. Symlinks are not followed. Returns the files and directories,
+// not including root, with directories before their contents. When Include is
+// specified, directories not containing any included files are omitted.
+// Directories that cannot be read are skipped, with the errors from reading
+// them joined into the returned error, along with all the entries that
+// could be read.
+func WalkFiltered(root string, opts ...WalkOptions) (ans []WalkEntry, err error) {
+	w := filtered_walker{root: root}
+	if len(opts) > 0 {
+		w.opts = opts[0]
+	}
+	s, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !s.IsDir() {
+		return nil, &fs.PathError{Op: "walk", Path: root, Err: fmt.Errorf("not a directory")}
+	}
+	if len(w.opts.Include) > 0 {
+		w.include = parse_ignore_rules("", false, w.opts.Include...)
+	}
+	if len(w.opts.Exclude) > 0 {
+		w.exclude = parse_ignore_rules("", false, w.opts.Exclude...)
+	}
+	n := w.opts.Parallelism
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	w.semaphore = make(chan bool, n)
+	w.wg.Add(1)
+	w.walk_dir("", nil)
+	w.wg.Wait()
+	if w.aborted.Load() {
+		return nil, ErrTooManyEntries
+	}
+	err = errors.Join(w.errs...)
+	slices.SortFunc(w.results, func(a, b WalkEntry) int { return compare_walk_paths(a.RelPath, b.RelPath) })
+	if w.include == nil {
+		return w.results, err
+	}
+	// drop directories that do not contain any included files
+	ans = make([]WalkEntry, 0, len(w.results))
+	needed := NewSet[string](64)
+	for i := len(w.results) - 1; i >= 0; i-- {
+		e := w.results[i]
+		if e.IsDir() && !needed.Has(e.RelPath) {
+			continue
+		}
+		if parent := path.Dir(e.RelPath); parent != "." {
+			needed.Add(parent)
+		}
+		ans = append(ans, e)
+	}
+	return Reverse(ans), err
+}
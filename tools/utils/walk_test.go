@@ -0,0 +1,112 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ = fmt.Print
+
+func TestGitignoreMatching(t *testing.T) {
+	q := func(pat, path string, is_dir, expected bool) {
+		r := parse_ignore_rules("", true, pat)
+		if actual := (ignore_stack{r}).is_ignored(path, is_dir); actual != expected {
+			t.Fatalf("Matching %#v against the pattern %#v: %v != %v", path, pat, expected, actual)
+		}
+	}
+	q("*.o", "a.o", false, true)
+	q("*.o", "x/y/a.o", false, true)
+	q("*.o", "a.oo", false, false)
+	q("/a.o", "x/a.o", false, false)
+	q("x/*.o", "x/a.o", false, true)
+	q("x/*.o", "y/x/a.o", false, false)
+	q("build/", "build", true, true)
+	q("build/", "build", false, false)
+	q("**/logs", "a/b/logs", true, true)
+	q("logs/**", "logs/a/b", false, true)
+	q("a/**/b", "a/b", false, true)
+	q("a/**/b", "a/x/y/b", false, true)
+	q("file[0-9].txt", "file5.txt", false, true)
+	q("file[!0-9].txt", "file5.txt", false, false)
+	q(`\#x`, "#x", false, true)
+	q("# comment", "# comment", false, false)
+	if !(ignore_stack{parse_ignore_rules("", false, "#*#")}).is_ignored("#x#", false) {
+		t.Fatalf("Globs not from files treated as comments")
+	}
+
+	r := parse_ignore_rules("sub/", true, "*.log", "!keep.log")
+	s := ignore_stack{r}
+	for path, expected := range map[string]bool{"sub/a.log": true, "sub/keep.log": false, "a.log": false} {
+		if actual := s.is_ignored(path, false); actual != expected {
+			t.Fatalf("Matching %#v: %v != %v", path, expected, actual)
+		}
+	}
+}
+
+func TestWalkFiltered(t *testing.T) {
+	tdir := t.TempDir()
+	for _, x := range []string{
+		".gitignore:*.o\nbuild/\n", ".git/config", ".hidden",
+		"a.txt", "a.o", "file10.txt", "file2.txt", "build/x.txt",
+		"sub/.ignore:!keep.o\nlocal.txt", "sub/keep.o", "sub/local.txt", "sub/other.o", "sub/deep/z.txt",
+		"empty/",
+	} {
+		path, data, _ := strings.Cut(x, ":")
+		path = filepath.Join(tdir, filepath.FromSlash(path))
+		if strings.HasSuffix(x, "/") {
+			os.MkdirAll(path, 0o755)
+			continue
+		}
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(data), 0o644)
+	}
+	q := func(opts WalkOptions, expected ...string) {
+		entries, err := WalkFiltered(tdir, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := Map(func(e WalkEntry) string {
+			if e.Path != filepath.Join(tdir, e.RelPath) {
+				t.Fatalf("Incorrect path for %s: %s", e.RelPath, e.Path)
+			}
+			if e.IsDir() {
+				return e.RelPath + "/"
+			}
+			return e.RelPath
+		}, entries)
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Fatalf("Unexpected walk results with options: %#v\n%s", opts, diff)
+		}
+	}
+	q(WalkOptions{RespectIgnoreFiles: true, Parallelism: 1},
+		".gitignore", ".hidden", "a.txt", "empty/", "file2.txt", "file10.txt", "sub/", "sub/.ignore", "sub/deep/", "sub/deep/z.txt", "sub/keep.o")
+	q(WalkOptions{RespectIgnoreFiles: true, SkipHidden: true, Exclude: []string{"sub/deep"}},
+		"a.txt", "empty/", "file2.txt", "file10.txt", "sub/", "sub/keep.o")
+	q(WalkOptions{Include: []string{"*.txt"}, Exclude: []string{"file*"}},
+		"a.txt", "build/", "build/x.txt", "sub/", "sub/deep/", "sub/deep/z.txt", "sub/local.txt")
+	q(WalkOptions{SkipHidden: true},
+		"a.o", "a.txt", "build/", "build/x.txt", "empty/", "file2.txt", "file10.txt", "sub/", "sub/deep/", "sub/deep/z.txt", "sub/keep.o", "sub/local.txt", "sub/other.o")
+	q(WalkOptions{ExcludeNames: []string{"*.o", "sub", "empty/"}},
+		".git/", ".git/config", ".gitignore", ".hidden", "a.txt", "build/", "build/x.txt", "empty/", "file2.txt", "file10.txt")
+	if _, err := WalkFiltered(filepath.Join(tdir, "a.txt")); err == nil {
+		t.Fatalf("No error when walking a file")
+	}
+	if _, err := WalkFiltered(tdir, WalkOptions{MaxEntries: 5}); err != ErrTooManyEntries {
+		t.Fatalf("Walking did not stop when there are too many entries: %v", err)
+	}
+	if os.Geteuid() != 0 {
+		os.Chmod(filepath.Join(tdir, "build"), 0)
+		defer os.Chmod(filepath.Join(tdir, "build"), 0o755)
+		entries, err := WalkFiltered(tdir, WalkOptions{SkipHidden: true, Include: []string{"*.txt"}})
+		if err == nil || len(entries) == 0 {
+			t.Fatalf("Walking a tree with an unreadable directory did not return both entries and an error: %v %v", entries, err)
+		}
+	}
+}
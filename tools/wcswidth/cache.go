@@ -0,0 +1,109 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package wcswidth
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var _ = fmt.Print
+
+// Strings longer than this are not cached as they are unlikely to repeat
+const MAX_CACHED_STRING_LENGTH = 256
+
+type width_cache_entry struct {
+	key        string
+	width      int
+	prev, next int
+}
+
+// A fixed size LRU cache of string widths. The entries form a doubly linked
+// list, most recently used first, threaded through a fixed array so that
+// lookups and updates do not allocate.
+type width_cache struct {
+	lock    sync.Mutex
+	index   map[string]int
+	entries []width_cache_entry
+	head    int
+}
+
+func new_width_cache(size int) *width_cache {
+	return &width_cache{index: make(map[string]int, size), entries: make([]width_cache_entry, 0, size), head: -1}
+}
+
+func (self *width_cache) unlink(i int) {
+	e := &self.entries[i]
+	self.entries[e.prev].next = e.next
+	self.entries[e.next].prev = e.prev
+	if self.head == i {
+		self.head = e.next
+		if self.head == i {
+			self.head = -1
+		}
+	}
+}
+
+func (self *width_cache) push_front(i int) {
+	e := &self.entries[i]
+	if self.head < 0 {
+		e.prev, e.next = i, i
+	} else {
+		h := &self.entries[self.head]
+		e.prev, e.next = h.prev, self.head
+		self.entries[h.prev].next = i
+		h.prev = i
+	}
+	self.head = i
+}
+
+func (self *width_cache) get(key string) (width int, found bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	i, found := self.index[key]
+	if !found {
+		return
+	}
+	if i != self.head {
+		self.unlink(i)
+		self.push_front(i)
+	}
+	return self.entries[i].width, true
+}
+
+func (self *width_cache) set(key string, width int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if i, found := self.index[key]; found {
+		self.entries[i].width = width
+		return
+	}
+	var i int
+	if len(self.entries) < cap(self.entries) {
+		i = len(self.entries)
+		self.entries = append(self.entries, width_cache_entry{})
+	} else {
+		// re-use the least recently used entry
+		i = self.entries[self.head].prev
+		self.unlink(i)
+		delete(self.index, self.entries[i].key)
+	}
+	// the key may be backed by a buffer owned by the caller
+	key = strings.Clone(key)
+	self.entries[i].key, self.entries[i].width = key, width
+	self.index[key] = i
+	self.push_front(i)
+}
+
+var string_width_cache = sync.OnceValue(func() *width_cache { return new_width_cache(512) })
+
+// The width of text if it consists only of printable ASCII characters
+func printable_ascii_width(text string) (int, bool) {
+	for i := 0; i < len(text); i++ {
+		if text[i] < 0x20 || text[i] > 0x7e {
+			return 0, false
+		}
+	}
+	return len(text), true
+}
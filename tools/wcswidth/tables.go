@@ -0,0 +1,77 @@
+// License: GPLv3 Copyright: 2023, Kovid Goyal, <kovid at kovidgoyal.net>
+
+package wcswidth
+
+import (
+	"fmt"
+)
+
+var _ = fmt.Print
+
+type wcwidth_range struct {
+	first, last rune
+	width       int8
+}
+
+type codepoint_range struct {
+	first, last rune
+}
+
+// The widths of the first 256 codepoints, looked up directly since they are
+// by far the most common
+var latin1_widths = func() (ans [256]int8) {
+	for i := range ans {
+		ans[i] = int8(runewidth_from_table(rune(i)))
+	}
+	return
+}()
+
+func runewidth_from_table(code rune) int {
+	lo, hi := 0, len(wcwidth_ranges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		r := &wcwidth_ranges[mid]
+		switch {
+		case code < r.first:
+			hi = mid
+		case code > r.last:
+			lo = mid + 1
+		default:
+			return int(r.width)
+		}
+	}
+	return 1
+}
+
+// The width of the codepoint in cells. Negative widths indicate special
+// categories: -1 non-printing, -2 East Asian ambiguous width, -3 private use
+// and -4 not assigned.
+func Runewidth(code rune) int {
+	if 0x20 <= code && code <= 0x7e {
+		return 1
+	}
+	if 0 <= code && code < rune(len(latin1_widths)) {
+		return int(latin1_widths[code])
+	}
+	return runewidth_from_table(code)
+}
+
+func IsEmojiPresentationBase(code rune) bool {
+	if code < emoji_presentation_base_ranges[0].first {
+		return false
+	}
+	lo, hi := 0, len(emoji_presentation_base_ranges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		r := &emoji_presentation_base_ranges[mid]
+		switch {
+		case code < r.first:
+			hi = mid
+		case code > r.last:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
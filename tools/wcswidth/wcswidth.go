@@ -126,11 +126,30 @@ func (self *WCWidthIterator) CurrentWidth() int {
 	return self.current_width
 }
 
-func Stringwidth(text string) int {
+func stringwidth(text string) int {
 	w := CreateWCWidthIterator()
 	return w.Parse(utils.UnsafeStringToBytes(text))
 }
 
+// The width of text in cells, ignoring escape codes. The widths of short
+// strings are cached, as the same strings, such as titles and file names, are
+// typically measured repeatedly when rendering.
+func Stringwidth(text string) int {
+	if ans, ok := printable_ascii_width(text); ok {
+		return ans
+	}
+	if len(text) > MAX_CACHED_STRING_LENGTH {
+		return stringwidth(text)
+	}
+	c := string_width_cache()
+	if ans, found := c.get(text); found {
+		return ans
+	}
+	ans := stringwidth(text)
+	c.set(text, ans)
+	return ans
+}
+
 func StripEscapeCodes(text string) string {
 	out := strings.Builder{}
 	out.Grow(len(text))
@@ -5,6 +5,8 @@ package wcswidth
 import (
 	"testing"
 
+	"kitty/tools/utils"
+
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -114,3 +116,44 @@ func TestCellIterator(t *testing.T) {
 		t.Fatalf("switching to forward failed, %#v != %#v", "2", ci.Current())
 	}
 }
+
+func TestWidthCache(t *testing.T) {
+	c := new_width_cache(3)
+	keys := func() (ans []string) {
+		if c.head < 0 {
+			return
+		}
+		for i := c.head; ; {
+			ans = append(ans, c.entries[i].key)
+			if i = c.entries[i].next; i == c.head {
+				break
+			}
+		}
+		return
+	}
+	for i, k := range []string{"a", "b", "c"} {
+		c.set(k, i)
+	}
+	if diff := cmp.Diff([]string{"c", "b", "a"}, keys()); diff != "" {
+		t.Fatalf("Unexpected cache order:\n%s", diff)
+	}
+	if w, found := c.get("a"); !found || w != 0 {
+		t.Fatalf("Failed to get cached width: %d %v", w, found)
+	}
+	c.set("d", 3)
+	if diff := cmp.Diff([]string{"d", "a", "c"}, keys()); diff != "" {
+		t.Fatalf("Unexpected cache order after eviction:\n%s", diff)
+	}
+	if _, found := c.get("b"); found {
+		t.Fatalf("Least recently used entry was not evicted")
+	}
+	buf := []byte("cached✖")
+	key := utils.UnsafeBytesToString(buf)
+	if Stringwidth(key) != 7 {
+		t.Fatalf("Incorrect width for: %#v", key)
+	}
+	copy(buf, "xxxxxx")
+	if w, found := string_width_cache().get("cached✖"); !found || w != 7 {
+		t.Fatalf("Cached width not found after the key buffer was modified: %d %v", w, found)
+	}
+}